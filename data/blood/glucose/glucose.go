@@ -21,12 +21,30 @@ const (
 
 	MmolLToMgdLConversionFactor float64 = 18.01559
 	MmolLToMgdLPrecisionFactor  float64 = 100000.0
+
+	// Number of decimals displayed by devices for values and rates of change, per minute
+	MmolLDisplayPrecision     = 1
+	MmolLRateDisplayPrecision = 2
+	MgdLDisplayPrecision      = 0
+	MgdLRateDisplayPrecision  = 1
 )
 
 func Units() []string {
 	return []string{MmolL, Mmoll, MgdL, Mgdl}
 }
 
+func CanonicalUnits(units *string) *string {
+	if units != nil {
+		switch *units {
+		case MmolL, Mmoll:
+			return pointer.FromString(MmolL)
+		case MgdL, Mgdl:
+			return pointer.FromString(MgdL)
+		}
+	}
+	return units
+}
+
 func ValueRangeForUnits(units *string) (float64, float64) {
 	if units != nil {
 		switch *units {
@@ -60,3 +78,53 @@ func NormalizeValueForUnits(value *float64, units *string) *float64 {
 	}
 	return value
 }
+
+// ConvertValueToUnits converts the normalized value, in mmol/L, to the units and rounds it to the precision displayed
+// by devices for the units: the nearest integer for mg/dL and one decimal for mmol/L, halves away from zero. Since a
+// value normalized from mg/dL is within 0.00001 mmol/L of the original value, the original value is restored exactly.
+// The value is unchanged if the units are unknown. As the conversion is linear, it also applies to a difference
+// between two values, or to an insulin sensitivity per unit of insulin.
+func ConvertValueToUnits(value *float64, units *string) *float64 {
+	if value != nil && units != nil {
+		switch *units {
+		case MmolL, Mmoll:
+			return pointer.FromFloat64(RoundValueForUnits(*value, units))
+		case MgdL, Mgdl:
+			return pointer.FromFloat64(RoundValueForUnits(*value*MmolLToMgdLConversionFactor, units))
+		}
+	}
+	return value
+}
+
+// RoundValueForUnits rounds the value, in the units, to the precision displayed by devices for the units, as
+// ConvertValueToUnits does. The value is unchanged if the units are unknown.
+func RoundValueForUnits(value float64, units *string) float64 {
+	if units != nil {
+		switch *units {
+		case MmolL, Mmoll:
+			return round(value, MmolLDisplayPrecision)
+		case MgdL, Mgdl:
+			return round(value, MgdLDisplayPrecision)
+		}
+	}
+	return value
+}
+
+// ConvertRateToUnits converts the normalized rate of change, in mmol/L per minute, to the units per minute and rounds
+// it to the precision displayed by devices for the units: one decimal for mg/dL and two decimals for mmol/L
+func ConvertRateToUnits(rate *float64, units *string) *float64 {
+	if rate != nil && units != nil {
+		switch *units {
+		case MmolL, Mmoll:
+			return pointer.FromFloat64(round(*rate, MmolLRateDisplayPrecision))
+		case MgdL, Mgdl:
+			return pointer.FromFloat64(round(*rate*MmolLToMgdLConversionFactor, MgdLRateDisplayPrecision))
+		}
+	}
+	return rate
+}
+
+func round(value float64, precision int) float64 {
+	factor := math.Pow10(precision)
+	return math.Round(value*factor) / factor
+}
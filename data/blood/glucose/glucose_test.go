@@ -126,4 +126,62 @@ var _ = Describe("Glucose", func() {
 			}
 		})
 	})
+
+	DescribeTable("CanonicalUnits",
+		func(units *string, expectedUnits *string) {
+			Expect(glucose.CanonicalUnits(units)).To(Equal(expectedUnits))
+		},
+		Entry("returns nil for nil", nil, nil),
+		Entry("returns unchanged units for unknown units", pointer.FromString("unknown"), pointer.FromString("unknown")),
+		Entry("returns mmol/L for mmol/l", pointer.FromString("mmol/l"), pointer.FromString("mmol/L")),
+		Entry("returns mg/dL for mg/dl", pointer.FromString("mg/dl"), pointer.FromString("mg/dL")),
+	)
+
+	Context("ConvertValueToUnits", func() {
+		DescribeTable("given value and units",
+			func(value *float64, units *string, expectedValue *float64) {
+				Expect(glucose.ConvertValueToUnits(value, units)).To(Equal(expectedValue))
+			},
+			Entry("returns nil for nil value", nil, pointer.FromString("mg/dL"), nil),
+			Entry("returns unchanged value for nil units", pointer.FromFloat64(5.55075), nil, pointer.FromFloat64(5.55075)),
+			Entry("returns unchanged value for unknown units", pointer.FromFloat64(5.55075), pointer.FromString("unknown"), pointer.FromFloat64(5.55075)),
+			Entry("returns value rounded to one decimal for mmol/L units", pointer.FromFloat64(5.55075), pointer.FromString("mmol/L"), pointer.FromFloat64(5.6)),
+			Entry("returns value rounded half away from zero for mmol/l units", pointer.FromFloat64(5.25), pointer.FromString("mmol/l"), pointer.FromFloat64(5.3)),
+			Entry("returns value converted and rounded to an integer for mg/dL units", pointer.FromFloat64(5.55075), pointer.FromString("mg/dL"), pointer.FromFloat64(100)),
+			Entry("returns value converted and rounded to an integer for mg/dl units", pointer.FromFloat64(7.0), pointer.FromString("mg/dl"), pointer.FromFloat64(126)),
+		)
+
+		It("restores a range of normalized mg/dL values", func() {
+			for value := int(glucose.MgdLMinimum); value <= int(glucose.MgdLMaximum); value++ {
+				normalizedValue := glucose.NormalizeValueForUnits(pointer.FromFloat64(float64(value)), pointer.FromString("mg/dL"))
+				Expect(glucose.ConvertValueToUnits(normalizedValue, pointer.FromString("mg/dL"))).To(Equal(pointer.FromFloat64(float64(value))))
+			}
+		})
+
+		It("restores a range of mmol/L values", func() {
+			for value := 0; value <= int(glucose.MmolLMaximum*10); value++ {
+				Expect(glucose.ConvertValueToUnits(pointer.FromFloat64(float64(value)/10), pointer.FromString("mmol/L"))).To(Equal(pointer.FromFloat64(float64(value) / 10)))
+			}
+		})
+	})
+
+	DescribeTable("RoundValueForUnits",
+		func(value float64, units *string, expectedValue float64) {
+			Expect(glucose.RoundValueForUnits(value, units)).To(Equal(expectedValue))
+		},
+		Entry("returns unchanged value for nil units", 5.55, nil, 5.55),
+		Entry("returns value rounded to one decimal for mmol/L units", 5.55, pointer.FromString("mmol/L"), 5.6),
+		Entry("returns value rounded to an integer for mg/dL units", 99.5, pointer.FromString("mg/dL"), 100.0),
+		Entry("returns negative value rounded half away from zero for mg/dL units", -99.5, pointer.FromString("mg/dL"), -100.0),
+	)
+
+	DescribeTable("ConvertRateToUnits",
+		func(rate *float64, units *string, expectedRate *float64) {
+			Expect(glucose.ConvertRateToUnits(rate, units)).To(Equal(expectedRate))
+		},
+		Entry("returns nil for nil rate", nil, pointer.FromString("mg/dL"), nil),
+		Entry("returns unchanged rate for unknown units", pointer.FromFloat64(0.11102), pointer.FromString("unknown"), pointer.FromFloat64(0.11102)),
+		Entry("returns rate rounded to two decimals for mmol/L units", pointer.FromFloat64(0.11102), pointer.FromString("mmol/L"), pointer.FromFloat64(0.11)),
+		Entry("returns rate converted and rounded to one decimal for mg/dL units", pointer.FromFloat64(0.11102), pointer.FromString("mg/dL"), pointer.FromFloat64(2.0)),
+	)
 })
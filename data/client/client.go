@@ -2,9 +2,13 @@ package client
 
 import (
 	"context"
+	"encoding/json"
+	"io"
 	"net/http"
 
 	"github.com/tidepool-org/platform/data"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/data/summary"
 	"github.com/tidepool-org/platform/errors"
 	"github.com/tidepool-org/platform/page"
 	"github.com/tidepool-org/platform/platform"
@@ -22,6 +26,14 @@ type Client interface {
 	CreateDataSetsData(ctx context.Context, dataSetID string, datumArray []data.Datum) error
 
 	DestroyDataForUserByID(ctx context.Context, userID string) error
+
+	ListDeletedDataSets(ctx context.Context, filter *data.DeletedDataSetFilter, pagination *page.Pagination) (data.DataSets, error)
+	DestroyDeletedDataSetData(ctx context.Context, dataSetID string) error
+	DestroyDeletedData(ctx context.Context, filter *data.DeletedDataFilter, pagination *page.Pagination) (*data.DeletedDataDestroyResult, error)
+
+	RebuildPendingDailySummaries(ctx context.Context, filter *summary.DailySummaryRebuildFilter, pagination *page.Pagination) (*summary.DailySummaryRebuildResult, error)
+
+	ExportUserData(ctx context.Context, userID string, filter *dataStoreDEPRECATED.DataFilter, cursor *dataStoreDEPRECATED.DataCursor) (*DataIterator, error)
 }
 
 type ClientImpl struct {
@@ -189,3 +201,172 @@ func (c *ClientImpl) DestroyDataForUserByID(ctx context.Context, userID string)
 	url := c.client.ConstructURL("v1", "users", userID, "data")
 	return c.client.RequestData(ctx, http.MethodDelete, url, nil, nil, nil)
 }
+
+func (c *ClientImpl) ListDeletedDataSets(ctx context.Context, filter *data.DeletedDataSetFilter, pagination *page.Pagination) (data.DataSets, error) {
+	if ctx == nil {
+		return nil, errors.New("context is missing")
+	}
+	if filter == nil {
+		filter = data.NewDeletedDataSetFilter()
+	} else if err := structureValidator.New().Validate(filter); err != nil {
+		return nil, errors.Wrap(err, "filter is invalid")
+	}
+	if pagination == nil {
+		pagination = page.NewPagination()
+	} else if err := structureValidator.New().Validate(pagination); err != nil {
+		return nil, errors.Wrap(err, "pagination is invalid")
+	}
+
+	url := c.client.ConstructURL("v1", "deleted_data_sets")
+	dataSets := data.DataSets{}
+	if err := c.client.RequestData(ctx, http.MethodGet, url, []request.RequestMutator{filter, pagination}, nil, &dataSets); err != nil {
+		return nil, err
+	}
+
+	return dataSets, nil
+}
+
+func (c *ClientImpl) DestroyDeletedDataSetData(ctx context.Context, dataSetID string) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if dataSetID == "" {
+		return errors.New("data set id is missing")
+	}
+
+	url := c.client.ConstructURL("v1", "deleted_data_sets", dataSetID)
+	return c.client.RequestData(ctx, http.MethodDelete, url, nil, nil, nil)
+}
+
+func (c *ClientImpl) DestroyDeletedData(ctx context.Context, filter *data.DeletedDataFilter, pagination *page.Pagination) (*data.DeletedDataDestroyResult, error) {
+	if ctx == nil {
+		return nil, errors.New("context is missing")
+	}
+	if filter == nil {
+		return nil, errors.New("filter is missing")
+	} else if err := structureValidator.New().Validate(filter); err != nil {
+		return nil, errors.Wrap(err, "filter is invalid")
+	}
+	if pagination == nil {
+		pagination = page.NewPagination()
+	} else if err := structureValidator.New().Validate(pagination); err != nil {
+		return nil, errors.Wrap(err, "pagination is invalid")
+	}
+
+	url := c.client.ConstructURL("v1", "deleted_data")
+	result := &data.DeletedDataDestroyResult{}
+	if err := c.client.RequestData(ctx, http.MethodDelete, url, []request.RequestMutator{filter, pagination}, nil, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (c *ClientImpl) RebuildPendingDailySummaries(ctx context.Context, filter *summary.DailySummaryRebuildFilter, pagination *page.Pagination) (*summary.DailySummaryRebuildResult, error) {
+	if ctx == nil {
+		return nil, errors.New("context is missing")
+	}
+	if filter == nil {
+		filter = summary.NewDailySummaryRebuildFilter()
+	} else if err := structureValidator.New().Validate(filter); err != nil {
+		return nil, errors.Wrap(err, "filter is invalid")
+	}
+	if pagination == nil {
+		pagination = page.NewPagination()
+	} else if err := structureValidator.New().Validate(pagination); err != nil {
+		return nil, errors.Wrap(err, "pagination is invalid")
+	}
+
+	url := c.client.ConstructURL("v1", "daily_summaries", "rebuild")
+	result := &summary.DailySummaryRebuildResult{}
+	if err := c.client.RequestData(ctx, http.MethodPost, url, []request.RequestMutator{filter, pagination}, nil, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (c *ClientImpl) ExportUserData(ctx context.Context, userID string, filter *dataStoreDEPRECATED.DataFilter, cursor *dataStoreDEPRECATED.DataCursor) (*DataIterator, error) {
+	if ctx == nil {
+		return nil, errors.New("context is missing")
+	}
+	if userID == "" {
+		return nil, errors.New("user id is missing")
+	}
+	if filter == nil {
+		filter = dataStoreDEPRECATED.NewDataFilter()
+	} else if err := structureValidator.New().Validate(filter); err != nil {
+		return nil, errors.Wrap(err, "filter is invalid")
+	}
+
+	mutators := []request.RequestMutator{filter}
+	if cursor != nil {
+		mutators = append(mutators, cursor)
+	}
+
+	url := c.client.ConstructURL("v1", "users", userID, "data", "export")
+	body, err := c.client.RequestStream(ctx, http.MethodGet, url, mutators, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDataIterator(body, cursor), nil
+}
+
+// DataIterator decodes a data export one datum at a time, as it is received. If the
+// export is interrupted, a new one can be started from the cursor of the iterator.
+type DataIterator struct {
+	reader  io.ReadCloser
+	decoder *json.Decoder
+	datum   map[string]interface{}
+	cursor  *dataStoreDEPRECATED.DataCursor
+	err     error
+}
+
+func NewDataIterator(reader io.ReadCloser, cursor *dataStoreDEPRECATED.DataCursor) *DataIterator {
+	return &DataIterator{
+		reader:  reader,
+		decoder: json.NewDecoder(reader),
+		cursor:  cursor,
+	}
+}
+
+func (d *DataIterator) Next() bool {
+	d.datum = nil
+	if d.err != nil {
+		return false
+	}
+
+	datum := map[string]interface{}{}
+	if err := d.decoder.Decode(&datum); err != nil {
+		if err != io.EOF {
+			d.err = errors.Wrap(err, "unable to decode datum")
+		}
+		return false
+	}
+
+	if time, ok := datum["time"].(string); ok {
+		if id, ok := datum["id"].(string); ok {
+			d.cursor = dataStoreDEPRECATED.NewDataCursor(time, id)
+		}
+	}
+
+	d.datum = datum
+	return true
+}
+
+func (d *DataIterator) Datum() map[string]interface{} {
+	return d.datum
+}
+
+func (d *DataIterator) Cursor() *dataStoreDEPRECATED.DataCursor {
+	return d.cursor
+}
+
+func (d *DataIterator) Error() error {
+	return d.err
+}
+
+func (d *DataIterator) Close() error {
+	return d.reader.Close()
+}
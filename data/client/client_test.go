@@ -4,17 +4,23 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/ghttp"
 
 	"github.com/tidepool-org/platform/auth"
+	"github.com/tidepool-org/platform/data"
 	dataClient "github.com/tidepool-org/platform/data/client"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/data/summary"
 	dataTest "github.com/tidepool-org/platform/data/test"
 	"github.com/tidepool-org/platform/log"
 	logNull "github.com/tidepool-org/platform/log/null"
 	"github.com/tidepool-org/platform/platform"
+	"github.com/tidepool-org/platform/pointer"
 	testHttp "github.com/tidepool-org/platform/test/http"
 	userTest "github.com/tidepool-org/platform/user/test"
 )
@@ -165,5 +171,410 @@ var _ = Describe("Client", func() {
 				})
 			})
 		})
+
+		Context("ListDeletedDataSets", func() {
+			It("returns error if context is missing", func() {
+				dataSets, err := clnt.ListDeletedDataSets(nil, nil, nil)
+				Expect(err).To(MatchError("context is missing"))
+				Expect(dataSets).To(BeNil())
+				Expect(server.ReceivedRequests()).To(BeEmpty())
+			})
+
+			It("returns error if filter is invalid", func() {
+				filter := data.NewDeletedDataSetFilter()
+				filter.DeletedBefore = pointer.FromTime(time.Time{})
+				dataSets, err := clnt.ListDeletedDataSets(ctx, filter, nil)
+				Expect(err).To(MatchError("filter is invalid; value is empty"))
+				Expect(dataSets).To(BeNil())
+				Expect(server.ReceivedRequests()).To(BeEmpty())
+			})
+
+			Context("with server token", func() {
+				var token string
+				var deletedBefore time.Time
+				var filter *data.DeletedDataSetFilter
+
+				BeforeEach(func() {
+					token = dataTest.NewSessionToken()
+					ctx = auth.NewContextWithServerSessionToken(ctx, token)
+					deletedBefore = time.Now().Add(-time.Hour).UTC()
+					filter = data.NewDeletedDataSetFilter()
+					filter.DeletedBefore = pointer.FromTime(deletedBefore)
+				})
+
+				Context("with a forbidden response", func() {
+					BeforeEach(func() {
+						server.AppendHandlers(
+							CombineHandlers(
+								VerifyRequest("GET", "/v1/deleted_data_sets", fmt.Sprintf("deletedBefore=%s", url.QueryEscape(deletedBefore.Format(time.RFC3339Nano)))),
+								VerifyHeaderKV("User-Agent", userAgent),
+								VerifyHeaderKV("X-Tidepool-Session-Token", token),
+								VerifyBody(nil),
+								RespondWith(http.StatusForbidden, nil)),
+						)
+					})
+
+					It("returns an error", func() {
+						dataSets, err := clnt.ListDeletedDataSets(ctx, filter, nil)
+						Expect(err).To(MatchError("authentication token is not authorized for requested action"))
+						Expect(dataSets).To(BeNil())
+						Expect(server.ReceivedRequests()).To(HaveLen(1))
+					})
+				})
+
+				Context("with a successful response", func() {
+					var dataSetID string
+
+					BeforeEach(func() {
+						dataSetID = dataTest.RandomSetID()
+						server.AppendHandlers(
+							CombineHandlers(
+								VerifyRequest("GET", "/v1/deleted_data_sets", fmt.Sprintf("deletedBefore=%s", url.QueryEscape(deletedBefore.Format(time.RFC3339Nano)))),
+								VerifyHeaderKV("User-Agent", userAgent),
+								VerifyHeaderKV("X-Tidepool-Session-Token", token),
+								VerifyBody(nil),
+								RespondWith(http.StatusOK, fmt.Sprintf(`[{"uploadId": "%s"}]`, dataSetID), http.Header{"Content-Type": []string{"application/json"}})),
+						)
+					})
+
+					It("returns the deleted data sets", func() {
+						dataSets, err := clnt.ListDeletedDataSets(ctx, filter, nil)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(dataSets).To(HaveLen(1))
+						Expect(dataSets[0].UploadID).To(Equal(pointer.FromString(dataSetID)))
+						Expect(server.ReceivedRequests()).To(HaveLen(1))
+					})
+				})
+			})
+		})
+
+		Context("DestroyDeletedDataSetData", func() {
+			var dataSetID string
+
+			BeforeEach(func() {
+				dataSetID = dataTest.RandomSetID()
+			})
+
+			It("returns error if context is missing", func() {
+				Expect(clnt.DestroyDeletedDataSetData(nil, dataSetID)).To(MatchError("context is missing"))
+				Expect(server.ReceivedRequests()).To(BeEmpty())
+			})
+
+			It("returns error if data set id is missing", func() {
+				Expect(clnt.DestroyDeletedDataSetData(ctx, "")).To(MatchError("data set id is missing"))
+				Expect(server.ReceivedRequests()).To(BeEmpty())
+			})
+
+			Context("with server token", func() {
+				var token string
+
+				BeforeEach(func() {
+					token = dataTest.NewSessionToken()
+					ctx = auth.NewContextWithServerSessionToken(ctx, token)
+				})
+
+				Context("with a forbidden response", func() {
+					BeforeEach(func() {
+						server.AppendHandlers(
+							CombineHandlers(
+								VerifyRequest("DELETE", fmt.Sprintf("/v1/deleted_data_sets/%s", dataSetID)),
+								VerifyHeaderKV("User-Agent", userAgent),
+								VerifyHeaderKV("X-Tidepool-Session-Token", token),
+								VerifyBody(nil),
+								RespondWith(http.StatusForbidden, nil)),
+						)
+					})
+
+					It("returns an error", func() {
+						err := clnt.DestroyDeletedDataSetData(ctx, dataSetID)
+						Expect(err).To(MatchError("authentication token is not authorized for requested action"))
+						Expect(server.ReceivedRequests()).To(HaveLen(1))
+					})
+				})
+
+				Context("with a successful response", func() {
+					BeforeEach(func() {
+						server.AppendHandlers(
+							CombineHandlers(
+								VerifyRequest("DELETE", fmt.Sprintf("/v1/deleted_data_sets/%s", dataSetID)),
+								VerifyHeaderKV("User-Agent", userAgent),
+								VerifyHeaderKV("X-Tidepool-Session-Token", token),
+								VerifyBody(nil),
+								RespondWith(http.StatusOK, nil)),
+						)
+					})
+
+					It("returns success", func() {
+						Expect(clnt.DestroyDeletedDataSetData(ctx, dataSetID)).To(Succeed())
+						Expect(server.ReceivedRequests()).To(HaveLen(1))
+					})
+				})
+			})
+		})
+
+		Context("DestroyDeletedData", func() {
+			It("returns error if context is missing", func() {
+				result, err := clnt.DestroyDeletedData(nil, data.NewDeletedDataFilter(), nil)
+				Expect(err).To(MatchError("context is missing"))
+				Expect(result).To(BeNil())
+				Expect(server.ReceivedRequests()).To(BeEmpty())
+			})
+
+			It("returns error if filter is missing", func() {
+				result, err := clnt.DestroyDeletedData(ctx, nil, nil)
+				Expect(err).To(MatchError("filter is missing"))
+				Expect(result).To(BeNil())
+				Expect(server.ReceivedRequests()).To(BeEmpty())
+			})
+
+			It("returns error if filter is invalid", func() {
+				result, err := clnt.DestroyDeletedData(ctx, data.NewDeletedDataFilter(), nil)
+				Expect(err).To(MatchError("filter is invalid; value does not exist"))
+				Expect(result).To(BeNil())
+				Expect(server.ReceivedRequests()).To(BeEmpty())
+			})
+
+			Context("with server token", func() {
+				var token string
+				var deletedBefore time.Time
+				var filter *data.DeletedDataFilter
+
+				BeforeEach(func() {
+					token = dataTest.NewSessionToken()
+					ctx = auth.NewContextWithServerSessionToken(ctx, token)
+					deletedBefore = time.Now().UTC()
+					filter = data.NewDeletedDataFilter()
+					filter.DeletedBefore = pointer.FromTime(deletedBefore)
+				})
+
+				Context("with a forbidden response", func() {
+					BeforeEach(func() {
+						server.AppendHandlers(
+							CombineHandlers(
+								VerifyRequest("DELETE", "/v1/deleted_data", fmt.Sprintf("deletedBefore=%s", url.QueryEscape(deletedBefore.Format(time.RFC3339Nano)))),
+								VerifyHeaderKV("User-Agent", userAgent),
+								VerifyHeaderKV("X-Tidepool-Session-Token", token),
+								VerifyBody(nil),
+								RespondWith(http.StatusForbidden, nil)),
+						)
+					})
+
+					It("returns an error", func() {
+						result, err := clnt.DestroyDeletedData(ctx, filter, nil)
+						Expect(err).To(MatchError("authentication token is not authorized for requested action"))
+						Expect(result).To(BeNil())
+						Expect(server.ReceivedRequests()).To(HaveLen(1))
+					})
+				})
+
+				Context("with a successful response", func() {
+					BeforeEach(func() {
+						server.AppendHandlers(
+							CombineHandlers(
+								VerifyRequest("DELETE", "/v1/deleted_data", fmt.Sprintf("deletedBefore=%s", url.QueryEscape(deletedBefore.Format(time.RFC3339Nano)))),
+								VerifyHeaderKV("User-Agent", userAgent),
+								VerifyHeaderKV("X-Tidepool-Session-Token", token),
+								VerifyBody(nil),
+								RespondWith(http.StatusOK, `{"destroyed": 3}`, http.Header{"Content-Type": []string{"application/json"}})),
+						)
+					})
+
+					It("returns the result", func() {
+						result, err := clnt.DestroyDeletedData(ctx, filter, nil)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(result).To(Equal(&data.DeletedDataDestroyResult{Destroyed: 3}))
+						Expect(server.ReceivedRequests()).To(HaveLen(1))
+					})
+				})
+			})
+		})
+
+		Context("RebuildPendingDailySummaries", func() {
+			It("returns error if context is missing", func() {
+				result, err := clnt.RebuildPendingDailySummaries(nil, nil, nil)
+				Expect(err).To(MatchError("context is missing"))
+				Expect(result).To(BeNil())
+				Expect(server.ReceivedRequests()).To(BeEmpty())
+			})
+
+			It("returns error if filter is invalid", func() {
+				filter := summary.NewDailySummaryRebuildFilter()
+				filter.RequestedBefore = pointer.FromTime(time.Time{})
+				result, err := clnt.RebuildPendingDailySummaries(ctx, filter, nil)
+				Expect(err).To(MatchError("filter is invalid; value is empty"))
+				Expect(result).To(BeNil())
+				Expect(server.ReceivedRequests()).To(BeEmpty())
+			})
+
+			Context("with server token", func() {
+				var token string
+				var requestedBefore time.Time
+				var filter *summary.DailySummaryRebuildFilter
+
+				BeforeEach(func() {
+					token = dataTest.NewSessionToken()
+					ctx = auth.NewContextWithServerSessionToken(ctx, token)
+					requestedBefore = time.Now().UTC()
+					filter = summary.NewDailySummaryRebuildFilter()
+					filter.RequestedBefore = pointer.FromTime(requestedBefore)
+				})
+
+				Context("with a forbidden response", func() {
+					BeforeEach(func() {
+						server.AppendHandlers(
+							CombineHandlers(
+								VerifyRequest("POST", "/v1/daily_summaries/rebuild", fmt.Sprintf("requestedBefore=%s", url.QueryEscape(requestedBefore.Format(time.RFC3339Nano)))),
+								VerifyHeaderKV("User-Agent", userAgent),
+								VerifyHeaderKV("X-Tidepool-Session-Token", token),
+								VerifyBody(nil),
+								RespondWith(http.StatusForbidden, nil)),
+						)
+					})
+
+					It("returns an error", func() {
+						result, err := clnt.RebuildPendingDailySummaries(ctx, filter, nil)
+						Expect(err).To(MatchError("authentication token is not authorized for requested action"))
+						Expect(result).To(BeNil())
+						Expect(server.ReceivedRequests()).To(HaveLen(1))
+					})
+				})
+
+				Context("with a successful response", func() {
+					BeforeEach(func() {
+						server.AppendHandlers(
+							CombineHandlers(
+								VerifyRequest("POST", "/v1/daily_summaries/rebuild", fmt.Sprintf("requestedBefore=%s", url.QueryEscape(requestedBefore.Format(time.RFC3339Nano)))),
+								VerifyHeaderKV("User-Agent", userAgent),
+								VerifyHeaderKV("X-Tidepool-Session-Token", token),
+								VerifyBody(nil),
+								RespondWith(http.StatusOK, `{"rebuilt": 2, "failed": 1}`, http.Header{"Content-Type": []string{"application/json"}})),
+						)
+					})
+
+					It("returns the result", func() {
+						result, err := clnt.RebuildPendingDailySummaries(ctx, filter, nil)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(result).To(Equal(&summary.DailySummaryRebuildResult{Rebuilt: 2, Failed: 1}))
+						Expect(server.ReceivedRequests()).To(HaveLen(1))
+					})
+				})
+			})
+		})
+
+		Context("ExportUserData", func() {
+			var userID string
+
+			BeforeEach(func() {
+				userID = userTest.RandomID()
+			})
+
+			It("returns error if context is missing", func() {
+				iterator, err := clnt.ExportUserData(nil, userID, nil, nil)
+				Expect(err).To(MatchError("context is missing"))
+				Expect(iterator).To(BeNil())
+				Expect(server.ReceivedRequests()).To(BeEmpty())
+			})
+
+			It("returns error if user id is missing", func() {
+				iterator, err := clnt.ExportUserData(ctx, "", nil, nil)
+				Expect(err).To(MatchError("user id is missing"))
+				Expect(iterator).To(BeNil())
+				Expect(server.ReceivedRequests()).To(BeEmpty())
+			})
+
+			It("returns error if filter is invalid", func() {
+				filter := dataStoreDEPRECATED.NewDataFilter()
+				filter.DeviceID = pointer.FromString("")
+				iterator, err := clnt.ExportUserData(ctx, userID, filter, nil)
+				Expect(err).To(MatchError("filter is invalid; value is empty"))
+				Expect(iterator).To(BeNil())
+				Expect(server.ReceivedRequests()).To(BeEmpty())
+			})
+
+			Context("with server token", func() {
+				var token string
+
+				BeforeEach(func() {
+					token = dataTest.NewSessionToken()
+					ctx = auth.NewContextWithServerSessionToken(ctx, token)
+				})
+
+				Context("with an unauthorized response", func() {
+					BeforeEach(func() {
+						server.AppendHandlers(
+							CombineHandlers(
+								VerifyRequest("GET", fmt.Sprintf("/v1/users/%s/data/export", userID)),
+								VerifyHeaderKV("User-Agent", userAgent),
+								VerifyHeaderKV("X-Tidepool-Session-Token", token),
+								VerifyBody(nil),
+								RespondWith(http.StatusUnauthorized, nil)),
+						)
+					})
+
+					It("returns an error", func() {
+						iterator, err := clnt.ExportUserData(ctx, userID, nil, nil)
+						Expect(err).To(MatchError("authentication token is invalid"))
+						Expect(iterator).To(BeNil())
+						Expect(server.ReceivedRequests()).To(HaveLen(1))
+					})
+				})
+
+				Context("with a successful response", func() {
+					var cursor *dataStoreDEPRECATED.DataCursor
+
+					BeforeEach(func() {
+						cursor = dataStoreDEPRECATED.NewDataCursor("2020-01-01T00:00:00Z", "first")
+						server.AppendHandlers(
+							CombineHandlers(
+								VerifyRequest("GET", fmt.Sprintf("/v1/users/%s/data/export", userID), "type=cbg&cursor="+cursor.Token()),
+								VerifyHeaderKV("User-Agent", userAgent),
+								VerifyHeaderKV("X-Tidepool-Session-Token", token),
+								VerifyBody(nil),
+								RespondWith(http.StatusOK, "{\"id\":\"second\",\"time\":\"2020-01-01T00:05:00Z\",\"type\":\"cbg\"}\n{\"id\":\"third\",\"time\":\"2020-01-01T00:10:00Z\",\"type\":\"cbg\"}\n")),
+						)
+					})
+
+					It("returns each datum with its cursor", func() {
+						filter := dataStoreDEPRECATED.NewDataFilter()
+						filter.Type = pointer.FromStringArray([]string{"cbg"})
+						iterator, err := clnt.ExportUserData(ctx, userID, filter, cursor)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(iterator).ToNot(BeNil())
+						Expect(iterator.Cursor()).To(Equal(cursor))
+						Expect(iterator.Next()).To(BeTrue())
+						Expect(iterator.Datum()).To(HaveKeyWithValue("id", "second"))
+						Expect(iterator.Cursor()).To(Equal(dataStoreDEPRECATED.NewDataCursor("2020-01-01T00:05:00Z", "second")))
+						Expect(iterator.Next()).To(BeTrue())
+						Expect(iterator.Datum()).To(HaveKeyWithValue("id", "third"))
+						Expect(iterator.Cursor()).To(Equal(dataStoreDEPRECATED.NewDataCursor("2020-01-01T00:10:00Z", "third")))
+						Expect(iterator.Next()).To(BeFalse())
+						Expect(iterator.Datum()).To(BeNil())
+						Expect(iterator.Error()).ToNot(HaveOccurred())
+						Expect(iterator.Close()).To(Succeed())
+						Expect(server.ReceivedRequests()).To(HaveLen(1))
+					})
+				})
+
+				Context("with a truncated response", func() {
+					BeforeEach(func() {
+						server.AppendHandlers(
+							CombineHandlers(
+								VerifyRequest("GET", fmt.Sprintf("/v1/users/%s/data/export", userID)),
+								RespondWith(http.StatusOK, "{\"id\":\"first\",\"time\":\"2020-01-01T00:00:00Z\"}\n{\"id\":\"sec")),
+						)
+					})
+
+					It("returns an error with the cursor of the last datum", func() {
+						iterator, err := clnt.ExportUserData(ctx, userID, nil, nil)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(iterator.Next()).To(BeTrue())
+						Expect(iterator.Next()).To(BeFalse())
+						Expect(iterator.Error()).To(HaveOccurred())
+						Expect(iterator.Cursor()).To(Equal(dataStoreDEPRECATED.NewDataCursor("2020-01-01T00:00:00Z", "first")))
+						Expect(iterator.Close()).To(Succeed())
+					})
+				})
+			})
+		})
 	})
 })
@@ -0,0 +1,431 @@
+package test
+
+import (
+	"context"
+
+	"github.com/tidepool-org/platform/data"
+	dataClient "github.com/tidepool-org/platform/data/client"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/data/summary"
+	"github.com/tidepool-org/platform/page"
+)
+
+type ListUserDataSetsInput struct {
+	Context    context.Context
+	UserID     string
+	Filter     *data.DataSetFilter
+	Pagination *page.Pagination
+}
+
+type ListUserDataSetsOutput struct {
+	DataSets data.DataSets
+	Error    error
+}
+
+type CreateUserDataSetInput struct {
+	Context context.Context
+	UserID  string
+	Create  *data.DataSetCreate
+}
+
+type CreateUserDataSetOutput struct {
+	DataSet *data.DataSet
+	Error   error
+}
+
+type GetDataSetInput struct {
+	Context context.Context
+	ID      string
+}
+
+type GetDataSetOutput struct {
+	DataSet *data.DataSet
+	Error   error
+}
+
+type UpdateDataSetInput struct {
+	Context context.Context
+	ID      string
+	Update  *data.DataSetUpdate
+}
+
+type UpdateDataSetOutput struct {
+	DataSet *data.DataSet
+	Error   error
+}
+
+type DeleteDataSetInput struct {
+	Context context.Context
+	ID      string
+}
+
+type CreateDataSetsDataInput struct {
+	Context    context.Context
+	DataSetID  string
+	DatumArray []data.Datum
+}
+
+type DestroyDataForUserByIDInput struct {
+	Context context.Context
+	UserID  string
+}
+
+type ExportUserDataInput struct {
+	Context context.Context
+	UserID  string
+	Filter  *dataStoreDEPRECATED.DataFilter
+	Cursor  *dataStoreDEPRECATED.DataCursor
+}
+
+type ExportUserDataOutput struct {
+	DataIterator *dataClient.DataIterator
+	Error        error
+}
+
+type ListDeletedDataSetsInput struct {
+	Context    context.Context
+	Filter     *data.DeletedDataSetFilter
+	Pagination *page.Pagination
+}
+
+type ListDeletedDataSetsOutput struct {
+	DataSets data.DataSets
+	Error    error
+}
+
+type DestroyDeletedDataSetDataInput struct {
+	Context   context.Context
+	DataSetID string
+}
+
+type DestroyDeletedDataInput struct {
+	Context    context.Context
+	Filter     *data.DeletedDataFilter
+	Pagination *page.Pagination
+}
+
+type DestroyDeletedDataOutput struct {
+	Result *data.DeletedDataDestroyResult
+	Error  error
+}
+
+type RebuildPendingDailySummariesInput struct {
+	Context    context.Context
+	Filter     *summary.DailySummaryRebuildFilter
+	Pagination *page.Pagination
+}
+
+type RebuildPendingDailySummariesOutput struct {
+	Result *summary.DailySummaryRebuildResult
+	Error  error
+}
+
+type Client struct {
+	ListUserDataSetsInvocations             int
+	ListUserDataSetsInputs                  []ListUserDataSetsInput
+	ListUserDataSetsStub                    func(ctx context.Context, userID string, filter *data.DataSetFilter, pagination *page.Pagination) (data.DataSets, error)
+	ListUserDataSetsOutputs                 []ListUserDataSetsOutput
+	ListUserDataSetsOutput                  *ListUserDataSetsOutput
+	CreateUserDataSetInvocations            int
+	CreateUserDataSetInputs                 []CreateUserDataSetInput
+	CreateUserDataSetStub                   func(ctx context.Context, userID string, create *data.DataSetCreate) (*data.DataSet, error)
+	CreateUserDataSetOutputs                []CreateUserDataSetOutput
+	CreateUserDataSetOutput                 *CreateUserDataSetOutput
+	GetDataSetInvocations                   int
+	GetDataSetInputs                        []GetDataSetInput
+	GetDataSetStub                          func(ctx context.Context, id string) (*data.DataSet, error)
+	GetDataSetOutputs                       []GetDataSetOutput
+	GetDataSetOutput                        *GetDataSetOutput
+	UpdateDataSetInvocations                int
+	UpdateDataSetInputs                     []UpdateDataSetInput
+	UpdateDataSetStub                       func(ctx context.Context, id string, update *data.DataSetUpdate) (*data.DataSet, error)
+	UpdateDataSetOutputs                    []UpdateDataSetOutput
+	UpdateDataSetOutput                     *UpdateDataSetOutput
+	DeleteDataSetInvocations                int
+	DeleteDataSetInputs                     []DeleteDataSetInput
+	DeleteDataSetStub                       func(ctx context.Context, id string) error
+	DeleteDataSetOutputs                    []error
+	DeleteDataSetOutput                     *error
+	CreateDataSetsDataInvocations           int
+	CreateDataSetsDataInputs                []CreateDataSetsDataInput
+	CreateDataSetsDataStub                  func(ctx context.Context, dataSetID string, datumArray []data.Datum) error
+	CreateDataSetsDataOutputs               []error
+	CreateDataSetsDataOutput                *error
+	DestroyDataForUserByIDInvocations       int
+	DestroyDataForUserByIDInputs            []DestroyDataForUserByIDInput
+	DestroyDataForUserByIDStub              func(ctx context.Context, userID string) error
+	DestroyDataForUserByIDOutputs           []error
+	DestroyDataForUserByIDOutput            *error
+	ExportUserDataInvocations               int
+	ExportUserDataInputs                    []ExportUserDataInput
+	ExportUserDataStub                      func(ctx context.Context, userID string, filter *dataStoreDEPRECATED.DataFilter, cursor *dataStoreDEPRECATED.DataCursor) (*dataClient.DataIterator, error)
+	ExportUserDataOutputs                   []ExportUserDataOutput
+	ExportUserDataOutput                    *ExportUserDataOutput
+	ListDeletedDataSetsInvocations          int
+	ListDeletedDataSetsInputs               []ListDeletedDataSetsInput
+	ListDeletedDataSetsStub                 func(ctx context.Context, filter *data.DeletedDataSetFilter, pagination *page.Pagination) (data.DataSets, error)
+	ListDeletedDataSetsOutputs              []ListDeletedDataSetsOutput
+	ListDeletedDataSetsOutput               *ListDeletedDataSetsOutput
+	DestroyDeletedDataSetDataInvocations    int
+	DestroyDeletedDataSetDataInputs         []DestroyDeletedDataSetDataInput
+	DestroyDeletedDataSetDataStub           func(ctx context.Context, dataSetID string) error
+	DestroyDeletedDataSetDataOutputs        []error
+	DestroyDeletedDataSetDataOutput         *error
+	DestroyDeletedDataInvocations           int
+	DestroyDeletedDataInputs                []DestroyDeletedDataInput
+	DestroyDeletedDataStub                  func(ctx context.Context, filter *data.DeletedDataFilter, pagination *page.Pagination) (*data.DeletedDataDestroyResult, error)
+	DestroyDeletedDataOutputs               []DestroyDeletedDataOutput
+	DestroyDeletedDataOutput                *DestroyDeletedDataOutput
+	RebuildPendingDailySummariesInvocations int
+	RebuildPendingDailySummariesInputs      []RebuildPendingDailySummariesInput
+	RebuildPendingDailySummariesStub        func(ctx context.Context, filter *summary.DailySummaryRebuildFilter, pagination *page.Pagination) (*summary.DailySummaryRebuildResult, error)
+	RebuildPendingDailySummariesOutputs     []RebuildPendingDailySummariesOutput
+	RebuildPendingDailySummariesOutput      *RebuildPendingDailySummariesOutput
+}
+
+func NewClient() *Client {
+	return &Client{}
+}
+
+func (c *Client) ListUserDataSets(ctx context.Context, userID string, filter *data.DataSetFilter, pagination *page.Pagination) (data.DataSets, error) {
+	c.ListUserDataSetsInvocations++
+	c.ListUserDataSetsInputs = append(c.ListUserDataSetsInputs, ListUserDataSetsInput{Context: ctx, UserID: userID, Filter: filter, Pagination: pagination})
+	if c.ListUserDataSetsStub != nil {
+		return c.ListUserDataSetsStub(ctx, userID, filter, pagination)
+	}
+	if len(c.ListUserDataSetsOutputs) > 0 {
+		output := c.ListUserDataSetsOutputs[0]
+		c.ListUserDataSetsOutputs = c.ListUserDataSetsOutputs[1:]
+		return output.DataSets, output.Error
+	}
+	if c.ListUserDataSetsOutput != nil {
+		return c.ListUserDataSetsOutput.DataSets, c.ListUserDataSetsOutput.Error
+	}
+	panic("ListUserDataSets has no output")
+}
+
+func (c *Client) CreateUserDataSet(ctx context.Context, userID string, create *data.DataSetCreate) (*data.DataSet, error) {
+	c.CreateUserDataSetInvocations++
+	c.CreateUserDataSetInputs = append(c.CreateUserDataSetInputs, CreateUserDataSetInput{Context: ctx, UserID: userID, Create: create})
+	if c.CreateUserDataSetStub != nil {
+		return c.CreateUserDataSetStub(ctx, userID, create)
+	}
+	if len(c.CreateUserDataSetOutputs) > 0 {
+		output := c.CreateUserDataSetOutputs[0]
+		c.CreateUserDataSetOutputs = c.CreateUserDataSetOutputs[1:]
+		return output.DataSet, output.Error
+	}
+	if c.CreateUserDataSetOutput != nil {
+		return c.CreateUserDataSetOutput.DataSet, c.CreateUserDataSetOutput.Error
+	}
+	panic("CreateUserDataSet has no output")
+}
+
+func (c *Client) GetDataSet(ctx context.Context, id string) (*data.DataSet, error) {
+	c.GetDataSetInvocations++
+	c.GetDataSetInputs = append(c.GetDataSetInputs, GetDataSetInput{Context: ctx, ID: id})
+	if c.GetDataSetStub != nil {
+		return c.GetDataSetStub(ctx, id)
+	}
+	if len(c.GetDataSetOutputs) > 0 {
+		output := c.GetDataSetOutputs[0]
+		c.GetDataSetOutputs = c.GetDataSetOutputs[1:]
+		return output.DataSet, output.Error
+	}
+	if c.GetDataSetOutput != nil {
+		return c.GetDataSetOutput.DataSet, c.GetDataSetOutput.Error
+	}
+	panic("GetDataSet has no output")
+}
+
+func (c *Client) UpdateDataSet(ctx context.Context, id string, update *data.DataSetUpdate) (*data.DataSet, error) {
+	c.UpdateDataSetInvocations++
+	c.UpdateDataSetInputs = append(c.UpdateDataSetInputs, UpdateDataSetInput{Context: ctx, ID: id, Update: update})
+	if c.UpdateDataSetStub != nil {
+		return c.UpdateDataSetStub(ctx, id, update)
+	}
+	if len(c.UpdateDataSetOutputs) > 0 {
+		output := c.UpdateDataSetOutputs[0]
+		c.UpdateDataSetOutputs = c.UpdateDataSetOutputs[1:]
+		return output.DataSet, output.Error
+	}
+	if c.UpdateDataSetOutput != nil {
+		return c.UpdateDataSetOutput.DataSet, c.UpdateDataSetOutput.Error
+	}
+	panic("UpdateDataSet has no output")
+}
+
+func (c *Client) DeleteDataSet(ctx context.Context, id string) error {
+	c.DeleteDataSetInvocations++
+	c.DeleteDataSetInputs = append(c.DeleteDataSetInputs, DeleteDataSetInput{Context: ctx, ID: id})
+	if c.DeleteDataSetStub != nil {
+		return c.DeleteDataSetStub(ctx, id)
+	}
+	if len(c.DeleteDataSetOutputs) > 0 {
+		output := c.DeleteDataSetOutputs[0]
+		c.DeleteDataSetOutputs = c.DeleteDataSetOutputs[1:]
+		return output
+	}
+	if c.DeleteDataSetOutput != nil {
+		return *c.DeleteDataSetOutput
+	}
+	panic("DeleteDataSet has no output")
+}
+
+func (c *Client) CreateDataSetsData(ctx context.Context, dataSetID string, datumArray []data.Datum) error {
+	c.CreateDataSetsDataInvocations++
+	c.CreateDataSetsDataInputs = append(c.CreateDataSetsDataInputs, CreateDataSetsDataInput{Context: ctx, DataSetID: dataSetID, DatumArray: datumArray})
+	if c.CreateDataSetsDataStub != nil {
+		return c.CreateDataSetsDataStub(ctx, dataSetID, datumArray)
+	}
+	if len(c.CreateDataSetsDataOutputs) > 0 {
+		output := c.CreateDataSetsDataOutputs[0]
+		c.CreateDataSetsDataOutputs = c.CreateDataSetsDataOutputs[1:]
+		return output
+	}
+	if c.CreateDataSetsDataOutput != nil {
+		return *c.CreateDataSetsDataOutput
+	}
+	panic("CreateDataSetsData has no output")
+}
+
+func (c *Client) DestroyDataForUserByID(ctx context.Context, userID string) error {
+	c.DestroyDataForUserByIDInvocations++
+	c.DestroyDataForUserByIDInputs = append(c.DestroyDataForUserByIDInputs, DestroyDataForUserByIDInput{Context: ctx, UserID: userID})
+	if c.DestroyDataForUserByIDStub != nil {
+		return c.DestroyDataForUserByIDStub(ctx, userID)
+	}
+	if len(c.DestroyDataForUserByIDOutputs) > 0 {
+		output := c.DestroyDataForUserByIDOutputs[0]
+		c.DestroyDataForUserByIDOutputs = c.DestroyDataForUserByIDOutputs[1:]
+		return output
+	}
+	if c.DestroyDataForUserByIDOutput != nil {
+		return *c.DestroyDataForUserByIDOutput
+	}
+	panic("DestroyDataForUserByID has no output")
+}
+
+func (c *Client) ExportUserData(ctx context.Context, userID string, filter *dataStoreDEPRECATED.DataFilter, cursor *dataStoreDEPRECATED.DataCursor) (*dataClient.DataIterator, error) {
+	c.ExportUserDataInvocations++
+	c.ExportUserDataInputs = append(c.ExportUserDataInputs, ExportUserDataInput{Context: ctx, UserID: userID, Filter: filter, Cursor: cursor})
+	if c.ExportUserDataStub != nil {
+		return c.ExportUserDataStub(ctx, userID, filter, cursor)
+	}
+	if len(c.ExportUserDataOutputs) > 0 {
+		output := c.ExportUserDataOutputs[0]
+		c.ExportUserDataOutputs = c.ExportUserDataOutputs[1:]
+		return output.DataIterator, output.Error
+	}
+	if c.ExportUserDataOutput != nil {
+		return c.ExportUserDataOutput.DataIterator, c.ExportUserDataOutput.Error
+	}
+	panic("ExportUserData has no output")
+}
+
+func (c *Client) ListDeletedDataSets(ctx context.Context, filter *data.DeletedDataSetFilter, pagination *page.Pagination) (data.DataSets, error) {
+	c.ListDeletedDataSetsInvocations++
+	c.ListDeletedDataSetsInputs = append(c.ListDeletedDataSetsInputs, ListDeletedDataSetsInput{Context: ctx, Filter: filter, Pagination: pagination})
+	if c.ListDeletedDataSetsStub != nil {
+		return c.ListDeletedDataSetsStub(ctx, filter, pagination)
+	}
+	if len(c.ListDeletedDataSetsOutputs) > 0 {
+		output := c.ListDeletedDataSetsOutputs[0]
+		c.ListDeletedDataSetsOutputs = c.ListDeletedDataSetsOutputs[1:]
+		return output.DataSets, output.Error
+	}
+	if c.ListDeletedDataSetsOutput != nil {
+		return c.ListDeletedDataSetsOutput.DataSets, c.ListDeletedDataSetsOutput.Error
+	}
+	panic("ListDeletedDataSets has no output")
+}
+
+func (c *Client) DestroyDeletedDataSetData(ctx context.Context, dataSetID string) error {
+	c.DestroyDeletedDataSetDataInvocations++
+	c.DestroyDeletedDataSetDataInputs = append(c.DestroyDeletedDataSetDataInputs, DestroyDeletedDataSetDataInput{Context: ctx, DataSetID: dataSetID})
+	if c.DestroyDeletedDataSetDataStub != nil {
+		return c.DestroyDeletedDataSetDataStub(ctx, dataSetID)
+	}
+	if len(c.DestroyDeletedDataSetDataOutputs) > 0 {
+		output := c.DestroyDeletedDataSetDataOutputs[0]
+		c.DestroyDeletedDataSetDataOutputs = c.DestroyDeletedDataSetDataOutputs[1:]
+		return output
+	}
+	if c.DestroyDeletedDataSetDataOutput != nil {
+		return *c.DestroyDeletedDataSetDataOutput
+	}
+	panic("DestroyDeletedDataSetData has no output")
+}
+
+func (c *Client) DestroyDeletedData(ctx context.Context, filter *data.DeletedDataFilter, pagination *page.Pagination) (*data.DeletedDataDestroyResult, error) {
+	c.DestroyDeletedDataInvocations++
+	c.DestroyDeletedDataInputs = append(c.DestroyDeletedDataInputs, DestroyDeletedDataInput{Context: ctx, Filter: filter, Pagination: pagination})
+	if c.DestroyDeletedDataStub != nil {
+		return c.DestroyDeletedDataStub(ctx, filter, pagination)
+	}
+	if len(c.DestroyDeletedDataOutputs) > 0 {
+		output := c.DestroyDeletedDataOutputs[0]
+		c.DestroyDeletedDataOutputs = c.DestroyDeletedDataOutputs[1:]
+		return output.Result, output.Error
+	}
+	if c.DestroyDeletedDataOutput != nil {
+		return c.DestroyDeletedDataOutput.Result, c.DestroyDeletedDataOutput.Error
+	}
+	panic("DestroyDeletedData has no output")
+}
+
+func (c *Client) RebuildPendingDailySummaries(ctx context.Context, filter *summary.DailySummaryRebuildFilter, pagination *page.Pagination) (*summary.DailySummaryRebuildResult, error) {
+	c.RebuildPendingDailySummariesInvocations++
+	c.RebuildPendingDailySummariesInputs = append(c.RebuildPendingDailySummariesInputs, RebuildPendingDailySummariesInput{Context: ctx, Filter: filter, Pagination: pagination})
+	if c.RebuildPendingDailySummariesStub != nil {
+		return c.RebuildPendingDailySummariesStub(ctx, filter, pagination)
+	}
+	if len(c.RebuildPendingDailySummariesOutputs) > 0 {
+		output := c.RebuildPendingDailySummariesOutputs[0]
+		c.RebuildPendingDailySummariesOutputs = c.RebuildPendingDailySummariesOutputs[1:]
+		return output.Result, output.Error
+	}
+	if c.RebuildPendingDailySummariesOutput != nil {
+		return c.RebuildPendingDailySummariesOutput.Result, c.RebuildPendingDailySummariesOutput.Error
+	}
+	panic("RebuildPendingDailySummaries has no output")
+}
+
+func (c *Client) AssertOutputsEmpty() {
+	if len(c.ListUserDataSetsOutputs) > 0 {
+		panic("ListUserDataSetsOutputs is not empty")
+	}
+	if len(c.CreateUserDataSetOutputs) > 0 {
+		panic("CreateUserDataSetOutputs is not empty")
+	}
+	if len(c.GetDataSetOutputs) > 0 {
+		panic("GetDataSetOutputs is not empty")
+	}
+	if len(c.UpdateDataSetOutputs) > 0 {
+		panic("UpdateDataSetOutputs is not empty")
+	}
+	if len(c.DeleteDataSetOutputs) > 0 {
+		panic("DeleteDataSetOutputs is not empty")
+	}
+	if len(c.CreateDataSetsDataOutputs) > 0 {
+		panic("CreateDataSetsDataOutputs is not empty")
+	}
+	if len(c.DestroyDataForUserByIDOutputs) > 0 {
+		panic("DestroyDataForUserByIDOutputs is not empty")
+	}
+	if len(c.ExportUserDataOutputs) > 0 {
+		panic("ExportUserDataOutputs is not empty")
+	}
+	if len(c.ListDeletedDataSetsOutputs) > 0 {
+		panic("ListDeletedDataSetsOutputs is not empty")
+	}
+	if len(c.DestroyDeletedDataSetDataOutputs) > 0 {
+		panic("DestroyDeletedDataSetDataOutputs is not empty")
+	}
+	if len(c.DestroyDeletedDataOutputs) > 0 {
+		panic("DestroyDeletedDataOutputs is not empty")
+	}
+	if len(c.RebuildPendingDailySummariesOutputs) > 0 {
+		panic("RebuildPendingDailySummariesOutputs is not empty")
+	}
+}
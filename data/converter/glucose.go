@@ -0,0 +1,235 @@
+package converter
+
+import (
+	"github.com/tidepool-org/platform/data"
+	dataBloodGlucose "github.com/tidepool-org/platform/data/blood/glucose"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	dataTypesBloodGlucoseSelfMonitored "github.com/tidepool-org/platform/data/types/blood/glucose/selfmonitored"
+	dataTypesCalculator "github.com/tidepool-org/platform/data/types/calculator"
+	dataTypesDeviceCalibration "github.com/tidepool-org/platform/data/types/device/calibration"
+	dataTypesSettingsCgm "github.com/tidepool-org/platform/data/types/settings/cgm"
+	dataTypesSettingsPump "github.com/tidepool-org/platform/data/types/settings/pump"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/structure"
+)
+
+// GlucoseOptions are the units, if any, the glucose values of the data read are converted to. Once normalized, the
+// units are canonical.
+type GlucoseOptions struct {
+	Units *string `json:"units,omitempty"`
+}
+
+func NewGlucoseOptions() *GlucoseOptions {
+	return &GlucoseOptions{}
+}
+
+func (g *GlucoseOptions) Parse(parser structure.ObjectParser) {
+	g.Units = parser.String("units")
+}
+
+func (g *GlucoseOptions) Validate(validator structure.Validator) {
+	validator.String("units", g.Units).OneOf(dataBloodGlucose.Units()...)
+}
+
+func (g *GlucoseOptions) Normalize(normalizer structure.Normalizer) {
+	g.Units = dataBloodGlucose.CanonicalUnits(g.Units)
+}
+
+// ConvertGlucoseData converts, in place, the glucose values of the datums to the units, as ConvertGlucoseDatum does
+func ConvertGlucoseData(datums data.Data, units string) {
+	for _, datum := range datums {
+		ConvertGlucoseDatum(datum, units)
+	}
+}
+
+// ConvertGlucoseDatum converts, in place, the glucose values of the datum to the units, rounded to the precision
+// displayed by devices (see dataBloodGlucose.ConvertValueToUnits), and updates the units of the datum accordingly.
+// The glucose values are the continuous and self-monitored glucose values, the calibration values, the bolus
+// calculator glucose input, target and insulin sensitivity, the pump settings glucose targets and insulin
+// sensitivities, and the CGM settings alert levels and rates. Any other datum is unchanged.
+func ConvertGlucoseDatum(datum data.Datum, units string) {
+	switch units = *dataBloodGlucose.CanonicalUnits(&units); units {
+	case dataBloodGlucose.MmolL, dataBloodGlucose.MgdL:
+	default:
+		return
+	}
+
+	switch typed := datum.(type) {
+	case *dataTypesBloodGlucoseContinuous.Continuous:
+		typed.Value, typed.Units = convertValue(typed.Value, typed.Units, units)
+	case *dataTypesBloodGlucoseSelfMonitored.SelfMonitored:
+		typed.Value, typed.Units = convertValue(typed.Value, typed.Units, units)
+	case *dataTypesDeviceCalibration.Calibration:
+		typed.Value, typed.Units = convertValue(typed.Value, typed.Units, units)
+	case *dataTypesCalculator.Calculator:
+		convertCalculator(typed, units)
+	case *dataTypesSettingsPump.Pump:
+		convertPump(typed, units)
+	case *dataTypesSettingsCgm.CGM:
+		convertCGM(typed, units)
+	}
+}
+
+// convertValue converts the value, normalized to the units, to the target units. The value is unchanged if not
+// normalized, that is if the units are unknown.
+func convertValue(value *float64, units *string, targetUnits string) (*float64, *string) {
+	if !isNormalized(units) {
+		return value, units
+	}
+	return dataBloodGlucose.ConvertValueToUnits(value, &targetUnits), pointer.FromString(targetUnits)
+}
+
+func isNormalized(units *string) bool {
+	return units != nil && *units == dataBloodGlucose.MmolL
+}
+
+func convertTarget(target *dataBloodGlucose.Target, units string) {
+	if target != nil {
+		target.High = dataBloodGlucose.ConvertValueToUnits(target.High, &units)
+		target.Low = dataBloodGlucose.ConvertValueToUnits(target.Low, &units)
+		target.Range = dataBloodGlucose.ConvertValueToUnits(target.Range, &units)
+		target.Target = dataBloodGlucose.ConvertValueToUnits(target.Target, &units)
+	}
+}
+
+func convertCalculator(calculator *dataTypesCalculator.Calculator, units string) {
+	if !isNormalized(calculator.Units) {
+		return
+	}
+	calculator.BloodGlucoseInput = dataBloodGlucose.ConvertValueToUnits(calculator.BloodGlucoseInput, &units)
+	convertTarget(calculator.BloodGlucoseTarget, units)
+	calculator.InsulinSensitivity = dataBloodGlucose.ConvertValueToUnits(calculator.InsulinSensitivity, &units)
+	calculator.Units = pointer.FromString(units)
+}
+
+func convertPump(pump *dataTypesSettingsPump.Pump, units string) {
+	if pump.Units == nil || !isNormalized(pump.Units.BloodGlucose) {
+		return
+	}
+	convertBloodGlucoseTargetStartArray(pump.BloodGlucoseTargetSchedule, units)
+	if pump.BloodGlucoseTargetSchedules != nil {
+		for _, array := range *pump.BloodGlucoseTargetSchedules {
+			convertBloodGlucoseTargetStartArray(array, units)
+		}
+	}
+	convertInsulinSensitivityStartArray(pump.InsulinSensitivitySchedule, units)
+	if pump.InsulinSensitivitySchedules != nil {
+		for _, array := range *pump.InsulinSensitivitySchedules {
+			convertInsulinSensitivityStartArray(array, units)
+		}
+	}
+	pump.Units.BloodGlucose = pointer.FromString(units)
+}
+
+func convertBloodGlucoseTargetStartArray(array *dataTypesSettingsPump.BloodGlucoseTargetStartArray, units string) {
+	if array != nil {
+		for _, start := range *array {
+			if start != nil {
+				convertTarget(&start.Target, units)
+			}
+		}
+	}
+}
+
+func convertInsulinSensitivityStartArray(array *dataTypesSettingsPump.InsulinSensitivityStartArray, units string) {
+	if array != nil {
+		for _, start := range *array {
+			if start != nil {
+				start.Amount = dataBloodGlucose.ConvertValueToUnits(start.Amount, &units)
+			}
+		}
+	}
+}
+
+func convertCGM(cgm *dataTypesSettingsCgm.CGM, units string) {
+	// The deprecated alerts are normalized using the units of the CGM settings
+	if isNormalized(cgm.Units) {
+		if cgm.HighLevelAlert != nil {
+			cgm.HighLevelAlert.Level = dataBloodGlucose.ConvertValueToUnits(cgm.HighLevelAlert.Level, &units)
+		}
+		if cgm.LowLevelAlert != nil {
+			cgm.LowLevelAlert.Level = dataBloodGlucose.ConvertValueToUnits(cgm.LowLevelAlert.Level, &units)
+		}
+		if cgm.RateAlerts != nil {
+			if cgm.RateAlerts.FallRateAlert != nil {
+				cgm.RateAlerts.FallRateAlert.Rate = dataBloodGlucose.ConvertRateToUnits(cgm.RateAlerts.FallRateAlert.Rate, &units)
+			}
+			if cgm.RateAlerts.RiseRateAlert != nil {
+				cgm.RateAlerts.RiseRateAlert.Rate = dataBloodGlucose.ConvertRateToUnits(cgm.RateAlerts.RiseRateAlert.Rate, &units)
+			}
+		}
+		cgm.Units = pointer.FromString(units)
+	}
+
+	// The alerts are not normalized, but each has its own units
+	convertAlerts(cgm.DefaultAlerts, units)
+	if cgm.ScheduledAlerts != nil {
+		for _, scheduledAlert := range *cgm.ScheduledAlerts {
+			if scheduledAlert != nil {
+				convertAlerts(scheduledAlert.Alerts, units)
+			}
+		}
+	}
+}
+
+func convertAlerts(alerts *dataTypesSettingsCgm.Alerts, units string) {
+	if alerts == nil {
+		return
+	}
+	if alerts.UrgentLow != nil {
+		convertLevelAlert(&alerts.UrgentLow.LevelAlert, units)
+	}
+	if alerts.UrgentLowPredicted != nil {
+		convertLevelAlert(&alerts.UrgentLowPredicted.LevelAlert, units)
+	}
+	if alerts.Low != nil {
+		convertLevelAlert(&alerts.Low.LevelAlert, units)
+	}
+	if alerts.LowPredicted != nil {
+		convertLevelAlert(&alerts.LowPredicted.LevelAlert, units)
+	}
+	if alerts.High != nil {
+		convertLevelAlert(&alerts.High.LevelAlert, units)
+	}
+	if alerts.HighPredicted != nil {
+		convertLevelAlert(&alerts.HighPredicted.LevelAlert, units)
+	}
+	if alerts.Fall != nil {
+		convertRateAlert(&alerts.Fall.RateAlert, units)
+	}
+	if alerts.Rise != nil {
+		convertRateAlert(&alerts.Rise.RateAlert, units)
+	}
+}
+
+func convertLevelAlert(levelAlert *dataTypesSettingsCgm.LevelAlert, units string) {
+	if levelAlert.Level == nil || levelAlert.Units == nil {
+		return
+	}
+	switch *levelAlert.Units {
+	case dataTypesSettingsCgm.LevelAlertUnitsMgdL, dataTypesSettingsCgm.LevelAlertUnitsMmolL:
+		levelAlert.Level = dataBloodGlucose.ConvertValueToUnits(dataBloodGlucose.NormalizeValueForUnits(levelAlert.Level, levelAlert.Units), &units)
+		levelAlert.Units = pointer.FromString(units)
+	}
+}
+
+func convertRateAlert(rateAlert *dataTypesSettingsCgm.RateAlert, units string) {
+	if rateAlert.Rate == nil || rateAlert.Units == nil {
+		return
+	}
+	var rateUnits *string
+	switch *rateAlert.Units {
+	case dataTypesSettingsCgm.RateAlertUnitsMgdLMinute:
+		rateUnits = pointer.FromString(dataBloodGlucose.MgdL)
+	case dataTypesSettingsCgm.RateAlertUnitsMmolLMinute:
+		rateUnits = pointer.FromString(dataBloodGlucose.MmolL)
+	default:
+		return
+	}
+	rateAlert.Rate = dataBloodGlucose.ConvertRateToUnits(dataBloodGlucose.NormalizeValueForUnits(rateAlert.Rate, rateUnits), &units)
+	if units == dataBloodGlucose.MgdL {
+		rateAlert.Units = pointer.FromString(dataTypesSettingsCgm.RateAlertUnitsMgdLMinute)
+	} else {
+		rateAlert.Units = pointer.FromString(dataTypesSettingsCgm.RateAlertUnitsMmolLMinute)
+	}
+}
@@ -0,0 +1,145 @@
+package converter_test
+
+import (
+	"net/http"
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	dataBloodGlucose "github.com/tidepool-org/platform/data/blood/glucose"
+	"github.com/tidepool-org/platform/data/converter"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	dataTypesBloodGlucoseSelfMonitored "github.com/tidepool-org/platform/data/types/blood/glucose/selfmonitored"
+	dataTypesBloodKetone "github.com/tidepool-org/platform/data/types/blood/ketone"
+	dataTypesCalculator "github.com/tidepool-org/platform/data/types/calculator"
+	dataTypesSettingsCgm "github.com/tidepool-org/platform/data/types/settings/cgm"
+	dataTypesSettingsPump "github.com/tidepool-org/platform/data/types/settings/pump"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/request"
+)
+
+func DecodeGlucoseOptions(query string) (*converter.GlucoseOptions, error) {
+	options := converter.NewGlucoseOptions()
+	req := &http.Request{URL: &url.URL{RawQuery: query}}
+	return options, request.DecodeRequestQuery(req, options)
+}
+
+var _ = Describe("Glucose", func() {
+	Context("GlucoseOptions", func() {
+		It("leaves the units unset by default", func() {
+			options, err := DecodeGlucoseOptions("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(options.Units).To(BeNil())
+		})
+
+		It("returns the canonical units", func() {
+			options, err := DecodeGlucoseOptions("units=mg/dl")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(options.Units).To(Equal(pointer.FromString("mg/dL")))
+		})
+
+		It("rejects unknown units", func() {
+			_, err := DecodeGlucoseOptions("units=mmol")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("ConvertGlucoseData", func() {
+		var continuous *dataTypesBloodGlucoseContinuous.Continuous
+		var selfMonitored *dataTypesBloodGlucoseSelfMonitored.SelfMonitored
+		var ketone *dataTypesBloodKetone.Ketone
+
+		BeforeEach(func() {
+			continuous = dataTypesBloodGlucoseContinuous.New()
+			continuous.Units = pointer.FromString(dataBloodGlucose.MmolL)
+			continuous.Value = dataBloodGlucose.NormalizeValueForUnits(pointer.FromFloat64(100), pointer.FromString(dataBloodGlucose.MgdL))
+			selfMonitored = dataTypesBloodGlucoseSelfMonitored.New()
+			selfMonitored.Units = pointer.FromString(dataBloodGlucose.MmolL)
+			selfMonitored.Value = pointer.FromFloat64(7.04)
+			ketone = dataTypesBloodKetone.New()
+			ketone.Units = pointer.FromString(dataBloodGlucose.MmolL)
+			ketone.Value = pointer.FromFloat64(1.25)
+		})
+
+		It("converts the glucose values to mg/dL, rounded to an integer", func() {
+			converter.ConvertGlucoseData(data.Data{continuous, selfMonitored, ketone}, "mg/dL")
+			Expect(*continuous.Value).To(Equal(100.0))
+			Expect(*continuous.Units).To(Equal("mg/dL"))
+			Expect(*selfMonitored.Value).To(Equal(127.0))
+			Expect(*selfMonitored.Units).To(Equal("mg/dL"))
+			Expect(*ketone.Value).To(Equal(1.25))
+			Expect(*ketone.Units).To(Equal("mmol/L"))
+		})
+
+		It("rounds the glucose values in mmol/L to one decimal", func() {
+			converter.ConvertGlucoseData(data.Data{continuous, selfMonitored}, "mmol/l")
+			Expect(*continuous.Value).To(Equal(5.6))
+			Expect(*continuous.Units).To(Equal("mmol/L"))
+			Expect(*selfMonitored.Value).To(Equal(7.0))
+		})
+
+		It("leaves the data unchanged for unknown units", func() {
+			converter.ConvertGlucoseData(data.Data{selfMonitored}, "unknown")
+			Expect(*selfMonitored.Value).To(Equal(7.04))
+			Expect(*selfMonitored.Units).To(Equal("mmol/L"))
+		})
+
+		It("converts the glucose input, target and insulin sensitivity of the bolus calculator", func() {
+			calculator := dataTypesCalculator.New()
+			calculator.Units = pointer.FromString(dataBloodGlucose.MmolL)
+			calculator.BloodGlucoseInput = pointer.FromFloat64(8.32612)
+			calculator.BloodGlucoseTarget = &dataBloodGlucose.Target{Low: pointer.FromFloat64(4.44058), High: pointer.FromFloat64(7.77105)}
+			calculator.InsulinSensitivity = pointer.FromFloat64(2.77537)
+			calculator.CarbohydrateInput = pointer.FromFloat64(45)
+			converter.ConvertGlucoseDatum(calculator, "mg/dL")
+			Expect(*calculator.BloodGlucoseInput).To(Equal(150.0))
+			Expect(calculator.BloodGlucoseTarget).To(Equal(&dataBloodGlucose.Target{Low: pointer.FromFloat64(80), High: pointer.FromFloat64(140)}))
+			Expect(*calculator.InsulinSensitivity).To(Equal(50.0))
+			Expect(*calculator.CarbohydrateInput).To(Equal(45.0))
+			Expect(*calculator.Units).To(Equal("mg/dL"))
+		})
+
+		It("converts the glucose targets and insulin sensitivities of the pump settings", func() {
+			pump := dataTypesSettingsPump.New()
+			pump.Units = &dataTypesSettingsPump.Units{BloodGlucose: pointer.FromString(dataBloodGlucose.MmolL), Carbohydrate: pointer.FromString("grams")}
+			pump.BloodGlucoseTargetSchedule = &dataTypesSettingsPump.BloodGlucoseTargetStartArray{
+				{Target: dataBloodGlucose.Target{Target: pointer.FromFloat64(5.55075), Range: pointer.FromFloat64(0.55507)}, Start: pointer.FromInt(0)},
+			}
+			pump.InsulinSensitivitySchedules = &dataTypesSettingsPump.InsulinSensitivityStartArrayMap{
+				"weekday": &dataTypesSettingsPump.InsulinSensitivityStartArray{{Amount: pointer.FromFloat64(2.22030), Start: pointer.FromInt(0)}},
+			}
+			converter.ConvertGlucoseDatum(pump, "mg/dL")
+			Expect((*pump.BloodGlucoseTargetSchedule)[0].Target).To(Equal(dataBloodGlucose.Target{Target: pointer.FromFloat64(100), Range: pointer.FromFloat64(10)}))
+			Expect(*(*(*pump.InsulinSensitivitySchedules)["weekday"])[0].Amount).To(Equal(40.0))
+			Expect(*pump.Units.BloodGlucose).To(Equal("mg/dL"))
+			Expect(*pump.Units.Carbohydrate).To(Equal("grams"))
+		})
+
+		It("converts the alert levels and rates of the CGM settings", func() {
+			cgm := dataTypesSettingsCgm.New()
+			cgm.Units = pointer.FromString(dataBloodGlucose.MmolL)
+			cgm.HighLevelAlert = &dataTypesSettingsCgm.HighLevelAlertDEPRECATED{LevelAlertDEPRECATED: dataTypesSettingsCgm.LevelAlertDEPRECATED{Level: pointer.FromFloat64(9.99135)}}
+			cgm.RateAlerts = &dataTypesSettingsCgm.RateAlertsDEPRECATED{
+				FallRateAlert: &dataTypesSettingsCgm.FallRateAlertDEPRECATED{RateAlertDEPRECATED: dataTypesSettingsCgm.RateAlertDEPRECATED{Rate: pointer.FromFloat64(-0.16652)}},
+			}
+			cgm.DefaultAlerts = &dataTypesSettingsCgm.Alerts{
+				Low:  &dataTypesSettingsCgm.LowAlert{LevelAlert: dataTypesSettingsCgm.LevelAlert{Level: pointer.FromFloat64(4.4), Units: pointer.FromString("mmol/L")}},
+				High: &dataTypesSettingsCgm.HighAlert{LevelAlert: dataTypesSettingsCgm.LevelAlert{Level: pointer.FromFloat64(250), Units: pointer.FromString("mg/dL")}},
+				Rise: &dataTypesSettingsCgm.RiseAlert{RateAlert: dataTypesSettingsCgm.RateAlert{Rate: pointer.FromFloat64(0.17), Units: pointer.FromString("mmol/L/minute")}},
+			}
+			cgm.ScheduledAlerts = &dataTypesSettingsCgm.ScheduledAlerts{
+				{Alerts: &dataTypesSettingsCgm.Alerts{UrgentLow: &dataTypesSettingsCgm.UrgentLowAlert{LevelAlert: dataTypesSettingsCgm.LevelAlert{Level: pointer.FromFloat64(55), Units: pointer.FromString("mg/dL")}}}},
+			}
+			converter.ConvertGlucoseDatum(cgm, "mg/dL")
+			Expect(*cgm.Units).To(Equal("mg/dL"))
+			Expect(*cgm.HighLevelAlert.Level).To(Equal(180.0))
+			Expect(*cgm.RateAlerts.FallRateAlert.Rate).To(Equal(-3.0))
+			Expect(cgm.DefaultAlerts.Low.LevelAlert).To(Equal(dataTypesSettingsCgm.LevelAlert{Level: pointer.FromFloat64(79), Units: pointer.FromString("mg/dL")}))
+			Expect(cgm.DefaultAlerts.High.LevelAlert).To(Equal(dataTypesSettingsCgm.LevelAlert{Level: pointer.FromFloat64(250), Units: pointer.FromString("mg/dL")}))
+			Expect(cgm.DefaultAlerts.Rise.RateAlert).To(Equal(dataTypesSettingsCgm.RateAlert{Rate: pointer.FromFloat64(3.1), Units: pointer.FromString("mg/dL/minute")}))
+			Expect(*(*cgm.ScheduledAlerts)[0].Alerts.UrgentLow.Level).To(Equal(55.0))
+		})
+	})
+})
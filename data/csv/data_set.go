@@ -0,0 +1,40 @@
+package csv
+
+import (
+	"time"
+
+	"github.com/tidepool-org/platform/data"
+	dataDeduplicatorDeduplicator "github.com/tidepool-org/platform/data/deduplicator/deduplicator"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	"github.com/tidepool-org/platform/pointer"
+)
+
+const (
+	ClientName    = "org.tidepool.data.csv"
+	ClientVersion = "1.0.0"
+)
+
+// NewDataSet returns a new data set for the data of the import, deduplicated with their hashes, so that data imported
+// again from an overlapping export of the same device replaces the previously imported data
+func (p *Profile) NewDataSet(result *Import, timeZoneName *string) *dataTypesUpload.Upload {
+	dataSet := dataTypesUpload.New()
+	dataSet.Client = &dataTypesUpload.Client{
+		Name:    pointer.FromString(ClientName),
+		Version: pointer.FromString(ClientVersion),
+	}
+	dataSet.DataSetType = pointer.FromString(dataTypesUpload.DataSetTypeNormal)
+	dataSet.Deduplicator = &data.DeduplicatorDescriptor{Name: pointer.FromString(dataDeduplicatorDeduplicator.DeviceDeactivateHashName)}
+	dataSet.DeviceID = pointer.FromString(p.DeviceID(result.SerialNumber))
+	dataSet.DeviceManufacturers = pointer.CloneStringArray(&p.DeviceManufacturers)
+	dataSet.DeviceModel = pointer.FromString(p.DeviceModel)
+	dataSet.DeviceSerialNumber = pointer.CloneString(result.SerialNumber)
+	dataSet.DeviceTags = pointer.CloneStringArray(&p.DeviceTags)
+	dataSet.Time = pointer.FromString(time.Now().UTC().Format(data.TimeFormat))
+	if p.Time.Local {
+		dataSet.TimeProcessing = pointer.FromString(dataTypesUpload.TimeProcessingAcrossTheBoardTimeZone)
+		dataSet.TimeZoneName = pointer.CloneString(timeZoneName)
+	} else {
+		dataSet.TimeProcessing = pointer.FromString(dataTypesUpload.TimeProcessingNone)
+	}
+	return dataSet
+}
@@ -0,0 +1,38 @@
+package csv_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	dataCSV "github.com/tidepool-org/platform/data/csv"
+	dataDeduplicatorDeduplicator "github.com/tidepool-org/platform/data/deduplicator/deduplicator"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	"github.com/tidepool-org/platform/pointer"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
+)
+
+var _ = Describe("NewDataSet", func() {
+	It("returns a valid data set of the device, deduplicated with the hashes", func() {
+		profile := dataCSV.LibreViewProfile()
+		dataSet := profile.NewDataSet(&dataCSV.Import{SerialNumber: pointer.FromString("0A1B2C3D")}, pointer.FromString("America/Los_Angeles"))
+		Expect(structureValidator.New().Validate(dataSet)).To(Succeed())
+		Expect(dataSet.HasDeduplicatorNameMatch(dataDeduplicatorDeduplicator.DeviceDeactivateHashName)).To(BeTrue())
+		Expect(*dataSet.DeviceID).To(Equal("AbbottLibreView_0A1B2C3D"))
+		Expect(*dataSet.DeviceManufacturers).To(Equal([]string{"Abbott"}))
+		Expect(*dataSet.DeviceModel).To(Equal("LibreView"))
+		Expect(*dataSet.DeviceSerialNumber).To(Equal("0A1B2C3D"))
+		Expect(*dataSet.DeviceTags).To(Equal([]string{dataTypesUpload.DeviceTagBGM, dataTypesUpload.DeviceTagCGM}))
+		Expect(*dataSet.TimeProcessing).To(Equal(dataTypesUpload.TimeProcessingAcrossTheBoardTimeZone))
+		Expect(*dataSet.TimeZoneName).To(Equal("America/Los_Angeles"))
+	})
+
+	It("returns a data set without time zone if the times are not local", func() {
+		profile := dataCSV.ClarityProfile()
+		profile.Time.Local = false
+		dataSet := profile.NewDataSet(&dataCSV.Import{}, nil)
+		Expect(*dataSet.DeviceID).To(Equal("DexcomClarity"))
+		Expect(dataSet.DeviceSerialNumber).To(BeNil())
+		Expect(*dataSet.TimeProcessing).To(Equal(dataTypesUpload.TimeProcessingNone))
+		Expect(dataSet.TimeZoneName).To(BeNil())
+	})
+})
@@ -0,0 +1,266 @@
+package csv
+
+import (
+	stdlibCSV "encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidepool-org/platform/data"
+	dataNormalizer "github.com/tidepool-org/platform/data/normalizer"
+	dataTypesFactory "github.com/tidepool-org/platform/data/types/factory"
+	"github.com/tidepool-org/platform/errors"
+	"github.com/tidepool-org/platform/structure"
+	structureBase "github.com/tidepool-org/platform/structure/base"
+	structureParser "github.com/tidepool-org/platform/structure/parser"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
+)
+
+// Import is the outcome of the import of a CSV export. The rows are the records numbered from one, including the header
+// row and the rows before it, but not the empty lines. The rows with errors are not imported, but do not prevent the
+// others.
+type Import struct {
+	Data         data.Data   `json:"-"`
+	SerialNumber *string     `json:"-"`
+	Rows         int         `json:"rows"`
+	Imported     int         `json:"imported"`
+	Skipped      int         `json:"skipped"`
+	RowErrors    []*RowError `json:"rowErrors,omitempty"`
+}
+
+// RowError is the error of a row, whose source pointer is the column, if known
+type RowError struct {
+	Row   int                  `json:"row"`
+	Error *errors.Serializable `json:"error"`
+}
+
+// DeviceID returns the device id of the data imported from the device with the serial number, if known
+func (p *Profile) DeviceID(serialNumber *string) string {
+	if serialNumber == nil {
+		return p.DeviceIDPrefix
+	}
+	return p.DeviceIDPrefix + "_" + *serialNumber
+}
+
+// Import parses the rows of the CSV export into data, through the data types factory, then validates and normalizes
+// them as any other data. The time zone name is required if the times are local. An error is returned only if the
+// export cannot be read or does not match the profile.
+func (p *Profile) Import(reader io.Reader, timeZoneName *string) (*Import, error) {
+	location := time.UTC
+	if p.Time.Local {
+		if timeZoneName == nil {
+			return nil, errors.New("time zone name is missing")
+		}
+		var err error
+		if location, err = time.LoadLocation(*timeZoneName); err != nil || *timeZoneName == "" {
+			return nil, errors.Newf("time zone name %q is invalid", *timeZoneName)
+		}
+	}
+
+	csvReader := stdlibCSV.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read csv")
+	}
+	if len(records) <= p.HeaderRow {
+		return nil, errors.New("header row is missing")
+	}
+
+	columns := map[string]int{}
+	for index, column := range records[p.HeaderRow] {
+		if index == 0 {
+			column = strings.TrimPrefix(column, "\ufeff")
+		}
+		columns[strings.TrimSpace(column)] = index
+	}
+
+	importer := &importer{
+		profile:      p,
+		columns:      columns,
+		location:     location,
+		timeZoneName: timeZoneName,
+		valueColumns: map[*Mapping]*ValueColumn{},
+	}
+	if _, ok := columns[p.Time.Column]; !ok {
+		return nil, errors.Newf("time column %q is missing", p.Time.Column)
+	}
+	for _, mapping := range p.Mappings {
+		for _, valueColumn := range mapping.ValueColumns {
+			if _, ok := columns[valueColumn.Column]; ok {
+				importer.valueColumns[mapping] = valueColumn
+				break
+			}
+		}
+		if importer.valueColumns[mapping] == nil {
+			return nil, errors.Newf("value column %q is missing", mapping.ValueColumns[0].Column)
+		}
+	}
+
+	result := &Import{Data: data.Data{}}
+	for index := p.HeaderRow + 1; index < len(records); index++ {
+		record := records[index]
+		if isEmpty(record) {
+			continue
+		}
+
+		result.Rows++
+		rowData, err := importer.importRecord(record)
+		if err != nil {
+			result.RowErrors = append(result.RowErrors, &RowError{Row: index + 1, Error: errors.NewSerializable(err)})
+		} else if len(rowData) == 0 {
+			result.Skipped++
+		} else {
+			result.Imported++
+			result.Data = append(result.Data, rowData...)
+			if result.SerialNumber == nil && p.SerialNumberColumn != "" {
+				if serialNumber := importer.cell(record, p.SerialNumberColumn); serialNumber != "" {
+					result.SerialNumber = &serialNumber
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+type importer struct {
+	profile      *Profile
+	columns      map[string]int
+	location     *time.Location
+	timeZoneName *string
+	valueColumns map[*Mapping]*ValueColumn
+}
+
+func (i *importer) cell(record []string, column string) string {
+	if index, ok := i.columns[column]; ok && index < len(record) {
+		return strings.TrimSpace(record[index])
+	}
+	return ""
+}
+
+func (i *importer) importRecord(record []string) (data.Data, error) {
+	var mapping *Mapping
+	for _, profileMapping := range i.profile.Mappings {
+		if profileMapping.Condition == nil || profileMapping.Condition.Match(i.cell(record, profileMapping.Condition.Column)) {
+			mapping = profileMapping
+			break
+		}
+	}
+	if mapping == nil {
+		return nil, nil
+	}
+
+	valueColumn := i.valueColumns[mapping]
+	value := i.cell(record, valueColumn.Column)
+	if value == "" {
+		return nil, nil
+	}
+
+	source := &columnSource{pointers: map[string]string{
+		"/time":           i.profile.Time.Column,
+		"/timezoneOffset": i.profile.Time.Column,
+		"/deviceTime":     i.profile.Time.Column,
+		"/value":          valueColumn.Column,
+		"/units":          valueColumn.Column,
+		"/annotations":    valueColumn.Column,
+	}}
+	if valueColumn.UnitsColumn != "" {
+		source.pointers["/units"] = valueColumn.UnitsColumn
+	}
+	if mapping.Condition != nil {
+		source.pointers["/type"] = mapping.Condition.Column
+	}
+
+	object := map[string]interface{}{
+		"type": mapping.Type,
+	}
+	if mapping.SubType != "" {
+		object["subType"] = mapping.SubType
+	}
+
+	rowTime, err := time.ParseInLocation(i.profile.Time.Layout, i.cell(record, i.profile.Time.Column), i.location)
+	if err != nil {
+		return nil, errors.WithSource(structureParser.ErrorValueTimeNotParsable(i.cell(record, i.profile.Time.Column), i.profile.Time.Layout), source.WithReference("time"))
+	}
+	object["time"] = rowTime.Format(data.TimeFormat)
+	if i.profile.Time.Local {
+		object["timezone"] = *i.timeZoneName
+	}
+
+	if valueColumn.Units != "" {
+		object["units"] = valueColumn.Units
+	} else {
+		object["units"] = i.cell(record, valueColumn.UnitsColumn)
+	}
+
+	if outOfRange := valueColumn.OutOfRange(value); outOfRange != nil {
+		object["value"] = outOfRange.Value
+		object["annotations"] = []interface{}{outOfRange.Annotation()}
+	} else if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+		object["value"] = floatValue
+	} else {
+		return nil, errors.WithSource(structureParser.ErrorTypeNotFloat64(value), source.WithReference("value"))
+	}
+
+	parser := structureParser.NewObjectParser(structureBase.New().WithSource(source), &object)
+	datum := dataTypesFactory.ParseDatum(parser)
+	parser.NotParsed()
+	if err := parser.Error(); err != nil {
+		return nil, err
+	} else if datum == nil || *datum == nil {
+		return nil, errors.WithSource(structureValidator.ErrorValueNotExists(), source.WithReference("type"))
+	}
+
+	validator := structureValidator.NewValidator(structureBase.New().WithSource(source))
+	if err := validator.Validate(*datum); err != nil {
+		return nil, err
+	}
+
+	normalizer := dataNormalizer.New()
+	(*datum).Normalize(normalizer.WithSource(source))
+	if err := normalizer.Error(); err != nil {
+		return nil, err
+	}
+
+	return append(data.Data{*datum}, normalizer.Data()...), nil
+}
+
+func isEmpty(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// columnSource is the source of the datum translated from a row, whose pointers are those of the columns the datum
+// values were translated from, or of the row itself if unknown
+type columnSource struct {
+	pointer  string
+	pointers map[string]string
+}
+
+func (c *columnSource) Parameter() string {
+	return ""
+}
+
+func (c *columnSource) Pointer() string {
+	for pointer := c.pointer; pointer != ""; pointer = pointer[:strings.LastIndex(pointer, "/")] {
+		if column, ok := c.pointers[pointer]; ok {
+			return "/" + structure.EncodePointerReference(column)
+		}
+	}
+	return ""
+}
+
+func (c *columnSource) WithReference(reference string) structure.Source {
+	return &columnSource{
+		pointer:  c.pointer + "/" + structure.EncodePointerReference(reference),
+		pointers: c.pointers,
+	}
+}
@@ -0,0 +1,138 @@
+package csv_test
+
+import (
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	dataBloodGlucose "github.com/tidepool-org/platform/data/blood/glucose"
+	dataCSV "github.com/tidepool-org/platform/data/csv"
+	"github.com/tidepool-org/platform/data/types"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	dataTypesBloodGlucoseSelfMonitored "github.com/tidepool-org/platform/data/types/blood/glucose/selfmonitored"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/pointer"
+	structureParser "github.com/tidepool-org/platform/structure/parser"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
+)
+
+func ImportFixture(profileName string, fixture string, timeZoneName *string) (*dataCSV.Import, error) {
+	file, err := os.Open(fixture)
+	Expect(err).ToNot(HaveOccurred())
+	defer file.Close()
+	return dataCSV.GetProfile(profileName).Import(file, timeZoneName)
+}
+
+var _ = Describe("Import", func() {
+	It("imports the LibreView export historic glucose as continuous glucose and strip glucose as self-monitored glucose", func() {
+		result, err := ImportFixture(dataCSV.ProfileNameLibreView, "testdata/libreview.csv", pointer.FromString("America/Los_Angeles"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Rows).To(Equal(7))
+		Expect(result.Imported).To(Equal(3))
+		Expect(result.Skipped).To(Equal(2))
+		Expect(*result.SerialNumber).To(Equal("0A1B2C3D-4E5F-6789-ABCD-EF0123456789"))
+		Expect(result.Data).To(HaveLen(3))
+
+		cbg, ok := result.Data[0].(*dataTypesBloodGlucoseContinuous.Continuous)
+		Expect(ok).To(BeTrue())
+		Expect(*cbg.Time).To(Equal("2021-03-01T16:05:00Z"))
+		Expect(*cbg.DeviceTime).To(Equal("2021-03-01T08:05:00"))
+		Expect(*cbg.TimeZoneName).To(Equal("America/Los_Angeles"))
+		Expect(*cbg.TimeZoneOffset).To(Equal(-480))
+		Expect(*cbg.Units).To(Equal(dataBloodGlucose.MmolL))
+		Expect(*cbg.Value).To(BeNumerically("~", 6.2168, 0.0001))
+
+		smbg, ok := result.Data[2].(*dataTypesBloodGlucoseSelfMonitored.SelfMonitored)
+		Expect(ok).To(BeTrue())
+		Expect(*smbg.Time).To(Equal("2021-03-01T20:42:00Z"))
+		Expect(*smbg.Value).To(BeNumerically("~", 5.4397, 0.0001))
+
+		Expect(result.RowErrors).To(HaveLen(2))
+		Expect(result.RowErrors[0].Row).To(Equal(6))
+		errorsTest.ExpectEqual(result.RowErrors[0].Error.Error, errorsTest.WithPointerSourceAndMeta(structureValidator.ErrorValueNotInRange(1200.0, dataBloodGlucose.MgdLMinimum, dataBloodGlucose.MgdLMaximum), "/Historic Glucose mg~1dL", &types.Meta{Type: dataTypesBloodGlucoseContinuous.Type}))
+		Expect(result.RowErrors[1].Row).To(Equal(9))
+		errorsTest.ExpectEqual(result.RowErrors[1].Error.Error, errorsTest.WithPointerSource(structureParser.ErrorValueTimeNotParsable("03/01/2021 13:05", "01-02-2006 03:04 PM"), "/Device Timestamp"))
+	})
+
+	It("imports the Clarity export estimated glucose values as continuous glucose, with the out of range values annotated", func() {
+		result, err := ImportFixture(dataCSV.ProfileNameClarity, "testdata/clarity.csv", pointer.FromString("Europe/Paris"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Rows).To(Equal(9))
+		Expect(result.Imported).To(Equal(3))
+		Expect(result.Skipped).To(Equal(5))
+		Expect(*result.SerialNumber).To(Equal("8GK9XY"))
+		Expect(result.Data).To(HaveLen(3))
+
+		cbg, ok := result.Data[0].(*dataTypesBloodGlucoseContinuous.Continuous)
+		Expect(ok).To(BeTrue())
+		Expect(*cbg.Time).To(Equal("2021-07-15T21:58:12Z"))
+		Expect(*cbg.TimeZoneOffset).To(Equal(120))
+		Expect(*cbg.Value).To(Equal(6.4))
+		Expect(cbg.Annotations).To(BeNil())
+
+		low, ok := result.Data[1].(*dataTypesBloodGlucoseContinuous.Continuous)
+		Expect(ok).To(BeTrue())
+		Expect(*low.Value).To(Equal(2.1))
+		Expect(*low.Annotations).To(HaveLen(1))
+		Expect((*low.Annotations)[0].Get("code")).To(Equal(dataCSV.OutOfRangeAnnotationCode))
+		Expect((*low.Annotations)[0].Get("value")).To(Equal(dataCSV.OutOfRangeLow))
+		Expect((*low.Annotations)[0].Get("threshold")).To(Equal(2.2))
+
+		high, ok := result.Data[2].(*dataTypesBloodGlucoseContinuous.Continuous)
+		Expect(ok).To(BeTrue())
+		Expect(*high.Value).To(Equal(22.3))
+		Expect((*high.Annotations)[0].Get("value")).To(Equal(dataCSV.OutOfRangeHigh))
+
+		Expect(result.RowErrors).To(HaveLen(1))
+		Expect(result.RowErrors[0].Row).To(Equal(10))
+		errorsTest.ExpectEqual(result.RowErrors[0].Error.Error, errorsTest.WithPointerSource(structureParser.ErrorTypeNotFloat64("n/a"), "/Glucose Value (mmol~1L)"))
+	})
+
+	It("imports times with time zone and units from a units column without time zone name", func() {
+		profile := &dataCSV.Profile{
+			Name:                "meter",
+			DeviceIDPrefix:      "Meter",
+			DeviceManufacturers: []string{"Manufacturer"},
+			DeviceModel:         "Meter",
+			DeviceTags:          []string{"bgm"},
+			Time:                &dataCSV.Time{Column: "time", Layout: "2006-01-02T15:04:05Z07:00"},
+			Mappings: []*dataCSV.Mapping{
+				{Type: dataTypesBloodGlucoseSelfMonitored.Type, ValueColumns: []*dataCSV.ValueColumn{{Column: "value", UnitsColumn: "units"}}},
+			},
+		}
+		result, err := profile.Import(strings.NewReader("time,value,units\n2021-03-01T08:05:00-05:00,101,mg/dL\n2021-03-01T09:05:00-05:00,5.5,mg/kg\n"), nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Imported).To(Equal(1))
+		Expect(result.SerialNumber).To(BeNil())
+		Expect(profile.DeviceID(result.SerialNumber)).To(Equal("Meter"))
+		smbg, ok := result.Data[0].(*dataTypesBloodGlucoseSelfMonitored.SelfMonitored)
+		Expect(ok).To(BeTrue())
+		Expect(*smbg.Time).To(Equal("2021-03-01T13:05:00Z"))
+		Expect(*smbg.TimeZoneOffset).To(Equal(-300))
+		Expect(result.RowErrors).To(HaveLen(1))
+		Expect(result.RowErrors[0].Row).To(Equal(3))
+		errorsTest.ExpectEqual(result.RowErrors[0].Error.Error, errorsTest.WithPointerSourceAndMeta(structureValidator.ErrorValueStringNotOneOf("mg/kg", dataBloodGlucose.Units()), "/units", &types.Meta{Type: dataTypesBloodGlucoseSelfMonitored.Type}))
+	})
+
+	It("returns an error if the times are local and the time zone name is missing", func() {
+		_, err := ImportFixture(dataCSV.ProfileNameLibreView, "testdata/libreview.csv", nil)
+		Expect(err).To(MatchError("time zone name is missing"))
+	})
+
+	It("returns an error if the time zone name is invalid", func() {
+		_, err := ImportFixture(dataCSV.ProfileNameLibreView, "testdata/libreview.csv", pointer.FromString("Mars/Olympus_Mons"))
+		Expect(err).To(MatchError(`time zone name "Mars/Olympus_Mons" is invalid`))
+	})
+
+	It("returns an error if the export does not match the profile", func() {
+		_, err := ImportFixture(dataCSV.ProfileNameClarity, "testdata/libreview.csv", pointer.FromString("Europe/Paris"))
+		Expect(err).To(MatchError(`time column "Timestamp (YYYY-MM-DDThh:mm:ss)" is missing`))
+	})
+
+	It("returns an error if the header row is missing", func() {
+		_, err := dataCSV.GetProfile(dataCSV.ProfileNameLibreView).Import(strings.NewReader("Glucose Data,Generated on\n"), pointer.FromString("UTC"))
+		Expect(err).To(MatchError("header row is missing"))
+	})
+})
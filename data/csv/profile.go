@@ -0,0 +1,214 @@
+package csv
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tidepool-org/platform/errors"
+	"github.com/tidepool-org/platform/structure"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
+)
+
+// Profile maps the columns of a vendor CSV export to data. The rows before the header row are skipped. Each other row is
+// translated by the first mapping whose condition it matches, and skipped if there is none or if its value is empty.
+type Profile struct {
+	Name                string     `json:"name"`
+	DeviceIDPrefix      string     `json:"deviceIdPrefix"`
+	DeviceManufacturers []string   `json:"deviceManufacturers"`
+	DeviceModel         string     `json:"deviceModel"`
+	DeviceTags          []string   `json:"deviceTags"`
+	HeaderRow           int        `json:"headerRow"`
+	SerialNumberColumn  string     `json:"serialNumberColumn,omitempty"`
+	Time                *Time      `json:"time"`
+	Mappings            []*Mapping `json:"mappings"`
+}
+
+func (p *Profile) Validate(validator structure.Validator) {
+	validator.String("name", &p.Name).NotEmpty()
+	validator.String("deviceIdPrefix", &p.DeviceIDPrefix).NotEmpty()
+	validator.StringArray("deviceManufacturers", &p.DeviceManufacturers).NotEmpty().EachNotEmpty().EachUnique()
+	validator.String("deviceModel", &p.DeviceModel).NotEmpty()
+	validator.StringArray("deviceTags", &p.DeviceTags).NotEmpty().EachNotEmpty().EachUnique()
+	validator.Int("headerRow", &p.HeaderRow).GreaterThanOrEqualTo(0)
+	if p.Time != nil {
+		p.Time.Validate(validator.WithReference("time"))
+	} else {
+		validator.WithReference("time").ReportError(structureValidator.ErrorValueNotExists())
+	}
+	mappingsValidator := validator.WithReference("mappings")
+	if len(p.Mappings) == 0 {
+		mappingsValidator.ReportError(structureValidator.ErrorValueEmpty())
+	}
+	for index, mapping := range p.Mappings {
+		if mappingValidator := mappingsValidator.WithReference(strconv.Itoa(index)); mapping != nil {
+			mapping.Validate(mappingValidator)
+		} else {
+			mappingValidator.ReportError(structureValidator.ErrorValueNotExists())
+		}
+	}
+}
+
+// Time is the time column. If local, the times are without time zone, which must then be specified on import.
+type Time struct {
+	Column string `json:"column"`
+	Layout string `json:"layout"`
+	Local  bool   `json:"local"`
+}
+
+func (t *Time) Validate(validator structure.Validator) {
+	validator.String("column", &t.Column).NotEmpty()
+	validator.String("layout", &t.Layout).NotEmpty()
+}
+
+// Mapping translates the rows matching the condition, if any, to data of the type, with the value of the first of the
+// value columns present in the header
+type Mapping struct {
+	Type         string         `json:"type"`
+	SubType      string         `json:"subType,omitempty"`
+	Condition    *Condition     `json:"condition,omitempty"`
+	ValueColumns []*ValueColumn `json:"valueColumns"`
+}
+
+func (m *Mapping) Validate(validator structure.Validator) {
+	validator.String("type", &m.Type).NotEmpty()
+	if m.Condition != nil {
+		m.Condition.Validate(validator.WithReference("condition"))
+	}
+	valueColumnsValidator := validator.WithReference("valueColumns")
+	if len(m.ValueColumns) == 0 {
+		valueColumnsValidator.ReportError(structureValidator.ErrorValueEmpty())
+	}
+	for index, valueColumn := range m.ValueColumns {
+		if valueColumnValidator := valueColumnsValidator.WithReference(strconv.Itoa(index)); valueColumn != nil {
+			valueColumn.Validate(valueColumnValidator)
+		} else {
+			valueColumnValidator.ReportError(structureValidator.ErrorValueNotExists())
+		}
+	}
+}
+
+// Condition matches a row whose column value is one of the values
+type Condition struct {
+	Column string   `json:"column"`
+	Values []string `json:"values"`
+}
+
+func (c *Condition) Validate(validator structure.Validator) {
+	validator.String("column", &c.Column).NotEmpty()
+	validator.StringArray("values", &c.Values).NotEmpty().EachUnique()
+}
+
+func (c *Condition) Match(value string) bool {
+	for _, conditionValue := range c.Values {
+		if value == conditionValue {
+			return true
+		}
+	}
+	return false
+}
+
+// ValueColumn is a value column, whose values are in the units, or, if empty, in the units of the units column. Values
+// out of the range of the device, such as "Low" or "High", are translated to a value beyond the threshold with an
+// out-of-range annotation, as by the uploader.
+type ValueColumn struct {
+	Column      string        `json:"column"`
+	Units       string        `json:"units,omitempty"`
+	UnitsColumn string        `json:"unitsColumn,omitempty"`
+	OutOfRanges []*OutOfRange `json:"outOfRanges,omitempty"`
+}
+
+func (v *ValueColumn) Validate(validator structure.Validator) {
+	validator.String("column", &v.Column).NotEmpty()
+	if v.Units != "" {
+		validator.String("unitsColumn", &v.UnitsColumn).Empty()
+	} else {
+		validator.String("unitsColumn", &v.UnitsColumn).NotEmpty()
+	}
+	outOfRangesValidator := validator.WithReference("outOfRanges")
+	for index, outOfRange := range v.OutOfRanges {
+		if outOfRangeValidator := outOfRangesValidator.WithReference(strconv.Itoa(index)); outOfRange != nil {
+			outOfRange.Validate(outOfRangeValidator)
+		} else {
+			outOfRangeValidator.ReportError(structureValidator.ErrorValueNotExists())
+		}
+	}
+}
+
+func (v *ValueColumn) OutOfRange(value string) *OutOfRange {
+	for _, outOfRange := range v.OutOfRanges {
+		if strings.EqualFold(value, outOfRange.Text) {
+			return outOfRange
+		}
+	}
+	return nil
+}
+
+const (
+	OutOfRangeAnnotationCode = "bg/out-of-range"
+
+	OutOfRangeHigh = "high"
+	OutOfRangeLow  = "low"
+)
+
+func OutOfRanges() []string {
+	return []string{
+		OutOfRangeHigh,
+		OutOfRangeLow,
+	}
+}
+
+// OutOfRange is the text of an out-of-range value, and its translation
+type OutOfRange struct {
+	Text      string  `json:"text"`
+	Range     string  `json:"range"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+}
+
+func (o *OutOfRange) Validate(validator structure.Validator) {
+	validator.String("text", &o.Text).NotEmpty()
+	validator.String("range", &o.Range).OneOf(OutOfRanges()...)
+	if o.Range == OutOfRangeLow {
+		validator.Float64("value", &o.Value).LessThan(o.Threshold)
+	} else if o.Range == OutOfRangeHigh {
+		validator.Float64("value", &o.Value).GreaterThan(o.Threshold)
+	}
+}
+
+func (o *OutOfRange) Annotation() map[string]interface{} {
+	return map[string]interface{}{
+		"code":      OutOfRangeAnnotationCode,
+		"value":     o.Range,
+		"threshold": o.Threshold,
+	}
+}
+
+var profiles = map[string]*Profile{}
+
+// RegisterProfile registers the profile, so that it can be used to import CSV exports by name
+func RegisterProfile(profile *Profile) error {
+	if profile == nil {
+		return errors.New("profile is missing")
+	} else if err := structureValidator.New().Validate(profile); err != nil {
+		return errors.Wrap(err, "profile is invalid")
+	} else if _, ok := profiles[profile.Name]; ok {
+		return errors.Newf("profile %q already registered", profile.Name)
+	}
+
+	profiles[profile.Name] = profile
+	return nil
+}
+
+func GetProfile(name string) *Profile {
+	return profiles[name]
+}
+
+func ProfileNames() []string {
+	names := []string{}
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
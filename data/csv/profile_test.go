@@ -0,0 +1,35 @@
+package csv_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	dataCSV "github.com/tidepool-org/platform/data/csv"
+)
+
+var _ = Describe("Profile", func() {
+	It("registers the Clarity and LibreView profiles", func() {
+		Expect(dataCSV.ProfileNames()).To(Equal([]string{dataCSV.ProfileNameClarity, dataCSV.ProfileNameLibreView}))
+		Expect(dataCSV.GetProfile(dataCSV.ProfileNameClarity)).To(Equal(dataCSV.ClarityProfile()))
+		Expect(dataCSV.GetProfile(dataCSV.ProfileNameLibreView)).To(Equal(dataCSV.LibreViewProfile()))
+		Expect(dataCSV.GetProfile("unknown")).To(BeNil())
+	})
+
+	Context("RegisterProfile", func() {
+		It("returns an error if the profile is missing", func() {
+			Expect(dataCSV.RegisterProfile(nil)).To(MatchError("profile is missing"))
+		})
+
+		It("returns an error if the profile is invalid", func() {
+			profile := dataCSV.ClarityProfile()
+			profile.Name = "invalid"
+			profile.Mappings[0].ValueColumns[0].OutOfRanges[0].Value = 41
+			Expect(dataCSV.RegisterProfile(profile)).To(MatchError("profile is invalid; value 41 is not less than 40"))
+			Expect(dataCSV.GetProfile("invalid")).To(BeNil())
+		})
+
+		It("returns an error if the profile is already registered", func() {
+			Expect(dataCSV.RegisterProfile(dataCSV.ClarityProfile())).To(MatchError(`profile "clarity" already registered`))
+		})
+	})
+})
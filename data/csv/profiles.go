@@ -0,0 +1,104 @@
+package csv
+
+import (
+	dataBloodGlucose "github.com/tidepool-org/platform/data/blood/glucose"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	dataTypesBloodGlucoseSelfMonitored "github.com/tidepool-org/platform/data/types/blood/glucose/selfmonitored"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+)
+
+const (
+	ProfileNameClarity   = "clarity"
+	ProfileNameLibreView = "libreview"
+)
+
+// ClarityProfile is the profile of the Dexcom Clarity export, whose first rows hold the patient and device information,
+// with the estimated glucose values (EGV) as continuous glucose. The EGV out of the range of the sensor are exported
+// as "Low" and "High".
+func ClarityProfile() *Profile {
+	return &Profile{
+		Name:                ProfileNameClarity,
+		DeviceIDPrefix:      "DexcomClarity",
+		DeviceManufacturers: []string{"Dexcom"},
+		DeviceModel:         "Clarity",
+		DeviceTags:          []string{dataTypesUpload.DeviceTagCGM},
+		HeaderRow:           0,
+		SerialNumberColumn:  "Transmitter ID",
+		Time: &Time{
+			Column: "Timestamp (YYYY-MM-DDThh:mm:ss)",
+			Layout: "2006-01-02T15:04:05",
+			Local:  true,
+		},
+		Mappings: []*Mapping{
+			{
+				Type:      dataTypesBloodGlucoseContinuous.Type,
+				Condition: &Condition{Column: "Event Type", Values: []string{"EGV"}},
+				ValueColumns: []*ValueColumn{
+					{
+						Column: "Glucose Value (mg/dL)",
+						Units:  dataBloodGlucose.MgdL,
+						OutOfRanges: []*OutOfRange{
+							{Text: "Low", Range: OutOfRangeLow, Value: 39, Threshold: 40},
+							{Text: "High", Range: OutOfRangeHigh, Value: 401, Threshold: 400},
+						},
+					},
+					{
+						Column: "Glucose Value (mmol/L)",
+						Units:  dataBloodGlucose.MmolL,
+						OutOfRanges: []*OutOfRange{
+							{Text: "Low", Range: OutOfRangeLow, Value: 2.1, Threshold: 2.2},
+							{Text: "High", Range: OutOfRangeHigh, Value: 22.3, Threshold: 22.2},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// LibreViewProfile is the profile of the Abbott LibreView glucose export, whose first row holds the report information,
+// with the historic glucose (record type 0) as continuous glucose, and the strip glucose (record type 2) as
+// self-monitored glucose. The scans (record type 1) are not imported, as the historic glucose already covers the sensor
+// readings.
+func LibreViewProfile() *Profile {
+	return &Profile{
+		Name:                ProfileNameLibreView,
+		DeviceIDPrefix:      "AbbottLibreView",
+		DeviceManufacturers: []string{"Abbott"},
+		DeviceModel:         "LibreView",
+		DeviceTags:          []string{dataTypesUpload.DeviceTagBGM, dataTypesUpload.DeviceTagCGM},
+		HeaderRow:           1,
+		SerialNumberColumn:  "Serial Number",
+		Time: &Time{
+			Column: "Device Timestamp",
+			Layout: "01-02-2006 03:04 PM",
+			Local:  true,
+		},
+		Mappings: []*Mapping{
+			{
+				Type:      dataTypesBloodGlucoseContinuous.Type,
+				Condition: &Condition{Column: "Record Type", Values: []string{"0"}},
+				ValueColumns: []*ValueColumn{
+					{Column: "Historic Glucose mg/dL", Units: dataBloodGlucose.MgdL},
+					{Column: "Historic Glucose mmol/L", Units: dataBloodGlucose.MmolL},
+				},
+			},
+			{
+				Type:      dataTypesBloodGlucoseSelfMonitored.Type,
+				Condition: &Condition{Column: "Record Type", Values: []string{"2"}},
+				ValueColumns: []*ValueColumn{
+					{Column: "Strip Glucose mg/dL", Units: dataBloodGlucose.MgdL},
+					{Column: "Strip Glucose mmol/L", Units: dataBloodGlucose.MmolL},
+				},
+			},
+		},
+	}
+}
+
+func init() {
+	for _, profile := range []*Profile{ClarityProfile(), LibreViewProfile()} {
+		if err := RegisterProfile(profile); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -161,6 +161,62 @@ func (d *DataSetFilter) MutateRequest(req *http.Request) error {
 	return request.NewParametersMutator(parameters).MutateRequest(req)
 }
 
+// DeletedDataSetFilter selects the deleted data sets whose deleted data is not yet purged
+type DeletedDataSetFilter struct {
+	DeletedBefore *time.Time
+}
+
+func NewDeletedDataSetFilter() *DeletedDataSetFilter {
+	return &DeletedDataSetFilter{}
+}
+
+func (d *DeletedDataSetFilter) Parse(parser structure.ObjectParser) {
+	d.DeletedBefore = parser.Time("deletedBefore", time.RFC3339Nano)
+}
+
+func (d *DeletedDataSetFilter) Validate(validator structure.Validator) {
+	validator.Time("deletedBefore", d.DeletedBefore).NotZero()
+}
+
+func (d *DeletedDataSetFilter) MutateRequest(req *http.Request) error {
+	parameters := map[string]string{}
+	if d.DeletedBefore != nil {
+		parameters["deletedBefore"] = d.DeletedBefore.Format(time.RFC3339Nano)
+	}
+	return request.NewParametersMutator(parameters).MutateRequest(req)
+}
+
+// DeletedDataFilter selects the deleted data not yet purged, whether deleted on its own from a data set or with a
+// deleted data set
+type DeletedDataFilter struct {
+	DeletedBefore *time.Time
+}
+
+func NewDeletedDataFilter() *DeletedDataFilter {
+	return &DeletedDataFilter{}
+}
+
+func (d *DeletedDataFilter) Parse(parser structure.ObjectParser) {
+	d.DeletedBefore = parser.Time("deletedBefore", time.RFC3339Nano)
+}
+
+func (d *DeletedDataFilter) Validate(validator structure.Validator) {
+	validator.Time("deletedBefore", d.DeletedBefore).Exists().NotZero()
+}
+
+func (d *DeletedDataFilter) MutateRequest(req *http.Request) error {
+	parameters := map[string]string{}
+	if d.DeletedBefore != nil {
+		parameters["deletedBefore"] = d.DeletedBefore.Format(time.RFC3339Nano)
+	}
+	return request.NewParametersMutator(parameters).MutateRequest(req)
+}
+
+// DeletedDataDestroyResult is the count of the deleted data destroyed
+type DeletedDataDestroyResult struct {
+	Destroyed int `json:"destroyed"`
+}
+
 type DataSetCreate struct {
 	Client              *DataSetClient          `json:"client,omitempty"`
 	DataSetType         *string                 `json:"dataSetType,omitempty" enums:"continuous,normal"`
@@ -11,12 +11,24 @@ import (
 type Factory interface {
 	New(dataSet *dataTypesUpload.Upload) (Deduplicator, error)
 	Get(dataSet *dataTypesUpload.Upload) (Deduplicator, error)
+	GetByName(name string) (Deduplicator, error)
 }
 
 type Deduplicator interface {
+	Name() string
+	Version() string
+
 	Open(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) (*dataTypesUpload.Upload, error)
 	AddData(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload, dataSetData data.Data) error
 	DeleteData(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload, selectors *data.Selectors) error
 	Close(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) error
 	Delete(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload, doPurge bool) error
+	Restore(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) error
+	Unarchive(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) error
+}
+
+// DuplicateReporter is implemented by the deduplicators that can report, before the data is added, which of the data
+// duplicates data already stored, and so is deduplicated when the data set is closed
+type DuplicateReporter interface {
+	Duplicates(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload, dataSetData data.Data) ([]bool, error)
 }
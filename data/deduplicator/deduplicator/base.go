@@ -34,6 +34,14 @@ func NewBase(name string, version string) (*Base, error) {
 	}, nil
 }
 
+func (b *Base) Name() string {
+	return b.name
+}
+
+func (b *Base) Version() string {
+	return b.version
+}
+
 func (b *Base) New(dataSet *dataTypesUpload.Upload) (bool, error) {
 	return b.Get(dataSet)
 }
@@ -132,3 +140,39 @@ func (b *Base) Delete(ctx context.Context, session dataStoreDEPRECATED.DataSessi
 
 	return session.DeleteDataSet(ctx, dataSet, doPurge)
 }
+
+func (b *Base) Restore(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if session == nil {
+		return errors.New("session is missing")
+	}
+	if dataSet == nil {
+		return errors.New("data set is missing")
+	}
+
+	if err := session.RestoreDataSet(ctx, dataSet); err != nil {
+		return err
+	}
+
+	if !dataSet.Active {
+		return nil
+	}
+
+	return session.ActivateDataSetData(ctx, dataSet, nil)
+}
+
+func (b *Base) Unarchive(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if session == nil {
+		return errors.New("session is missing")
+	}
+	if dataSet == nil {
+		return errors.New("data set is missing")
+	}
+
+	return session.UnarchiveDeviceDataArchivedByDataSet(ctx, dataSet)
+}
@@ -75,6 +75,18 @@ var _ = Describe("Base", func() {
 			dataSet.Deduplicator.Name = pointer.FromString(name)
 		})
 
+		Context("Name", func() {
+			It("returns the name", func() {
+				Expect(deduplicator.Name()).To(Equal(name))
+			})
+		})
+
+		Context("Version", func() {
+			It("returns the version", func() {
+				Expect(deduplicator.Version()).To(Equal(version))
+			})
+		})
+
 		Context("New", func() {
 			It("returns an error when the data set is missing", func() {
 				found, err := deduplicator.New(nil)
@@ -401,6 +413,91 @@ var _ = Describe("Base", func() {
 					})
 				})
 			})
+
+			Context("Restore", func() {
+				It("returns an error when the context is missing", func() {
+					Expect(deduplicator.Restore(nil, session, dataSet)).To(MatchError("context is missing"))
+				})
+
+				It("returns an error when the session is missing", func() {
+					Expect(deduplicator.Restore(ctx, nil, dataSet)).To(MatchError("session is missing"))
+				})
+
+				It("returns an error when the data set is missing", func() {
+					Expect(deduplicator.Restore(ctx, session, nil)).To(MatchError("data set is missing"))
+				})
+
+				When("restore data set is invoked", func() {
+					AfterEach(func() {
+						Expect(session.RestoreDataSetInputs).To(Equal([]dataStoreDEPRECATEDTest.RestoreDataSetInput{{Context: ctx, DataSet: dataSet}}))
+					})
+
+					It("returns an error when restore data set returns an error", func() {
+						responseErr := errorsTest.RandomError()
+						session.RestoreDataSetOutputs = []error{responseErr}
+						Expect(deduplicator.Restore(ctx, session, dataSet)).To(Equal(responseErr))
+					})
+
+					It("returns successfully without activating data set data when the data set is not active", func() {
+						dataSet.Active = false
+						session.RestoreDataSetOutputs = []error{nil}
+						Expect(deduplicator.Restore(ctx, session, dataSet)).To(Succeed())
+					})
+
+					When("activate data set data is invoked", func() {
+						BeforeEach(func() {
+							dataSet.Active = true
+							session.RestoreDataSetOutputs = []error{nil}
+						})
+
+						AfterEach(func() {
+							Expect(session.ActivateDataSetDataInputs).To(Equal([]dataStoreDEPRECATEDTest.ActivateDataSetDataInput{{Context: ctx, DataSet: dataSet, Selectors: nil}}))
+						})
+
+						It("returns an error when activate data set data returns an error", func() {
+							responseErr := errorsTest.RandomError()
+							session.ActivateDataSetDataOutputs = []error{responseErr}
+							Expect(deduplicator.Restore(ctx, session, dataSet)).To(Equal(responseErr))
+						})
+
+						It("returns successfully when activate data set data returns successfully", func() {
+							session.ActivateDataSetDataOutputs = []error{nil}
+							Expect(deduplicator.Restore(ctx, session, dataSet)).To(Succeed())
+						})
+					})
+				})
+			})
+
+			Context("Unarchive", func() {
+				It("returns an error when the context is missing", func() {
+					Expect(deduplicator.Unarchive(nil, session, dataSet)).To(MatchError("context is missing"))
+				})
+
+				It("returns an error when the session is missing", func() {
+					Expect(deduplicator.Unarchive(ctx, nil, dataSet)).To(MatchError("session is missing"))
+				})
+
+				It("returns an error when the data set is missing", func() {
+					Expect(deduplicator.Unarchive(ctx, session, nil)).To(MatchError("data set is missing"))
+				})
+
+				When("unarchive device data archived by data set is invoked", func() {
+					AfterEach(func() {
+						Expect(session.UnarchiveDeviceDataArchivedByDataSetInputs).To(Equal([]dataStoreDEPRECATEDTest.UnarchiveDeviceDataArchivedByDataSetInput{{Context: ctx, DataSet: dataSet}}))
+					})
+
+					It("returns an error when unarchive device data archived by data set returns an error", func() {
+						responseErr := errorsTest.RandomError()
+						session.UnarchiveDeviceDataArchivedByDataSetOutputs = []error{responseErr}
+						Expect(deduplicator.Unarchive(ctx, session, dataSet)).To(Equal(responseErr))
+					})
+
+					It("returns successfully when unarchive device data archived by data set returns successfully", func() {
+						session.UnarchiveDeviceDataArchivedByDataSetOutputs = []error{nil}
+						Expect(deduplicator.Unarchive(ctx, session, dataSet)).To(Succeed())
+					})
+				})
+			})
 		})
 	})
 })
@@ -0,0 +1,93 @@
+package deduplicator
+
+import (
+	"encoding/json"
+
+	"github.com/tidepool-org/platform/config"
+	"github.com/tidepool-org/platform/errors"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
+)
+
+// Config holds the device rules of the deduplicators selected by device, each loaded from a JSON array of
+// device rules, for example [{"deviceManufacturer":"Abbott","deviceModels":["FreeStyle Libre"]}], and the rule
+// of the near duplicate deduplicator, loaded from a JSON object, for example {"timeWindow":10,"valueTolerance":0.1}
+type Config struct {
+	DeviceDeactivateHashDeviceRules  DeviceRules
+	DeviceTruncateDataSetDeviceRules DeviceRules
+	DeviceNearDuplicateDeviceRules   DeviceRules
+	DeviceNearDuplicateRule          *NearDuplicateRule
+}
+
+func NewConfig() *Config {
+	return &Config{
+		DeviceDeactivateHashDeviceRules:  DeviceDeactivateHashDeviceRules(),
+		DeviceTruncateDataSetDeviceRules: DeviceTruncateDataSetDeviceRules(),
+		DeviceNearDuplicateDeviceRules:   DeviceRules{},
+		DeviceNearDuplicateRule:          NewNearDuplicateRule(),
+	}
+}
+
+func (c *Config) Load(configReporter config.Reporter) error {
+	if configReporter == nil {
+		return errors.New("config reporter is missing")
+	}
+
+	if err := loadDeviceRules(configReporter, "device_deactivate_hash_device_rules", &c.DeviceDeactivateHashDeviceRules); err != nil {
+		return err
+	}
+	if err := loadDeviceRules(configReporter, "device_truncate_data_set_device_rules", &c.DeviceTruncateDataSetDeviceRules); err != nil {
+		return err
+	}
+	if err := loadDeviceRules(configReporter, "device_near_duplicate_device_rules", &c.DeviceNearDuplicateDeviceRules); err != nil {
+		return err
+	}
+	if value := configReporter.GetWithDefault("device_near_duplicate_rule", ""); value != "" {
+		rule := NewNearDuplicateRule()
+		if err := json.Unmarshal([]byte(value), rule); err != nil {
+			return errors.Wrap(err, "unable to parse device_near_duplicate_rule")
+		}
+		c.DeviceNearDuplicateRule = rule
+	}
+
+	return nil
+}
+
+func (c *Config) Validate() error {
+	if c.DeviceDeactivateHashDeviceRules == nil {
+		return errors.New("device deactivate hash device rules is missing")
+	} else if err := structureValidator.New().Validate(c.DeviceDeactivateHashDeviceRules); err != nil {
+		return errors.Wrap(err, "device deactivate hash device rules is invalid")
+	}
+	if c.DeviceTruncateDataSetDeviceRules == nil {
+		return errors.New("device truncate data set device rules is missing")
+	} else if err := structureValidator.New().Validate(c.DeviceTruncateDataSetDeviceRules); err != nil {
+		return errors.Wrap(err, "device truncate data set device rules is invalid")
+	}
+	if c.DeviceNearDuplicateDeviceRules == nil {
+		return errors.New("device near duplicate device rules is missing")
+	} else if err := structureValidator.New().Validate(c.DeviceNearDuplicateDeviceRules); err != nil {
+		return errors.Wrap(err, "device near duplicate device rules is invalid")
+	}
+	if c.DeviceNearDuplicateRule == nil {
+		return errors.New("device near duplicate rule is missing")
+	} else if err := structureValidator.New().Validate(c.DeviceNearDuplicateRule); err != nil {
+		return errors.Wrap(err, "device near duplicate rule is invalid")
+	}
+
+	return nil
+}
+
+func loadDeviceRules(configReporter config.Reporter, key string, deviceRules *DeviceRules) error {
+	value := configReporter.GetWithDefault(key, "")
+	if value == "" {
+		return nil
+	}
+
+	loadedDeviceRules := DeviceRules{}
+	if err := json.Unmarshal([]byte(value), &loadedDeviceRules); err != nil {
+		return errors.Wrapf(err, "unable to parse %s", key)
+	}
+	*deviceRules = loadedDeviceRules
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package deduplicator_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	configTest "github.com/tidepool-org/platform/config/test"
+	dataDeduplicatorDeduplicator "github.com/tidepool-org/platform/data/deduplicator/deduplicator"
+)
+
+var _ = Describe("Config", func() {
+	Context("NewConfig", func() {
+		It("returns the default device rules", func() {
+			cfg := dataDeduplicatorDeduplicator.NewConfig()
+			Expect(cfg).ToNot(BeNil())
+			Expect(cfg.DeviceDeactivateHashDeviceRules).To(Equal(dataDeduplicatorDeduplicator.DeviceDeactivateHashDeviceRules()))
+			Expect(cfg.DeviceTruncateDataSetDeviceRules).To(Equal(dataDeduplicatorDeduplicator.DeviceTruncateDataSetDeviceRules()))
+			Expect(cfg.DeviceNearDuplicateDeviceRules).To(Equal(dataDeduplicatorDeduplicator.DeviceRules{}))
+			Expect(cfg.DeviceNearDuplicateRule).To(Equal(dataDeduplicatorDeduplicator.NewNearDuplicateRule()))
+		})
+	})
+
+	Context("with new config", func() {
+		var configReporter *configTest.Reporter
+		var cfg *dataDeduplicatorDeduplicator.Config
+
+		BeforeEach(func() {
+			configReporter = configTest.NewReporter()
+			cfg = dataDeduplicatorDeduplicator.NewConfig()
+		})
+
+		Context("Load", func() {
+			It("returns an error if the config reporter is missing", func() {
+				Expect(cfg.Load(nil)).To(MatchError("config reporter is missing"))
+			})
+
+			It("keeps the default device rules if not configured", func() {
+				Expect(cfg.Load(configReporter)).To(Succeed())
+				Expect(cfg.DeviceDeactivateHashDeviceRules).To(Equal(dataDeduplicatorDeduplicator.DeviceDeactivateHashDeviceRules()))
+				Expect(cfg.DeviceTruncateDataSetDeviceRules).To(Equal(dataDeduplicatorDeduplicator.DeviceTruncateDataSetDeviceRules()))
+			})
+
+			It("returns an error if the device deactivate hash device rules cannot be parsed", func() {
+				configReporter.Config["device_deactivate_hash_device_rules"] = "{"
+				Expect(cfg.Load(configReporter)).To(MatchError(HavePrefix("unable to parse device_deactivate_hash_device_rules")))
+			})
+
+			It("returns an error if the device truncate data set device rules cannot be parsed", func() {
+				configReporter.Config["device_truncate_data_set_device_rules"] = "{}"
+				Expect(cfg.Load(configReporter)).To(MatchError(HavePrefix("unable to parse device_truncate_data_set_device_rules")))
+			})
+
+			It("returns an error if the device near duplicate device rules cannot be parsed", func() {
+				configReporter.Config["device_near_duplicate_device_rules"] = "["
+				Expect(cfg.Load(configReporter)).To(MatchError(HavePrefix("unable to parse device_near_duplicate_device_rules")))
+			})
+
+			It("returns an error if the device near duplicate rule cannot be parsed", func() {
+				configReporter.Config["device_near_duplicate_rule"] = "[]"
+				Expect(cfg.Load(configReporter)).To(MatchError(HavePrefix("unable to parse device_near_duplicate_rule")))
+			})
+
+			It("loads the configured device near duplicate rule, keeping the defaults not configured", func() {
+				configReporter.Config["device_near_duplicate_device_rules"] = `[{"deviceManufacturer":"Dexcom"}]`
+				configReporter.Config["device_near_duplicate_rule"] = `{"timeWindow":30}`
+				Expect(cfg.Load(configReporter)).To(Succeed())
+				Expect(cfg.DeviceNearDuplicateDeviceRules).To(Equal(dataDeduplicatorDeduplicator.DeviceRules{{DeviceManufacturer: "Dexcom"}}))
+				expectedRule := dataDeduplicatorDeduplicator.NewNearDuplicateRule()
+				expectedRule.TimeWindow = 30
+				Expect(cfg.DeviceNearDuplicateRule).To(Equal(expectedRule))
+			})
+
+			It("loads the configured device rules", func() {
+				configReporter.Config["device_deactivate_hash_device_rules"] = `[{"deviceManufacturer":"Abbott","deviceModels":["FreeStyle Libre","FreeStyle Libre 2"]}]`
+				configReporter.Config["device_truncate_data_set_device_rules"] = `[]`
+				Expect(cfg.Load(configReporter)).To(Succeed())
+				Expect(cfg.DeviceDeactivateHashDeviceRules).To(Equal(dataDeduplicatorDeduplicator.DeviceRules{
+					{DeviceManufacturer: "Abbott", DeviceModels: []string{"FreeStyle Libre", "FreeStyle Libre 2"}},
+				}))
+				Expect(cfg.DeviceTruncateDataSetDeviceRules).To(Equal(dataDeduplicatorDeduplicator.DeviceRules{}))
+			})
+		})
+
+		Context("Validate", func() {
+			It("returns an error if the device deactivate hash device rules is missing", func() {
+				cfg.DeviceDeactivateHashDeviceRules = nil
+				Expect(cfg.Validate()).To(MatchError("device deactivate hash device rules is missing"))
+			})
+
+			It("returns an error if the device deactivate hash device rules is invalid", func() {
+				cfg.DeviceDeactivateHashDeviceRules = dataDeduplicatorDeduplicator.DeviceRules{{}}
+				Expect(cfg.Validate()).To(MatchError("device deactivate hash device rules is invalid; value is empty"))
+			})
+
+			It("returns an error if the device truncate data set device rules is missing", func() {
+				cfg.DeviceTruncateDataSetDeviceRules = nil
+				Expect(cfg.Validate()).To(MatchError("device truncate data set device rules is missing"))
+			})
+
+			It("returns an error if the device truncate data set device rules is invalid", func() {
+				cfg.DeviceTruncateDataSetDeviceRules = dataDeduplicatorDeduplicator.DeviceRules{nil}
+				Expect(cfg.Validate()).To(MatchError("device truncate data set device rules is invalid; value does not exist"))
+			})
+
+			It("returns an error if the device near duplicate device rules is missing", func() {
+				cfg.DeviceNearDuplicateDeviceRules = nil
+				Expect(cfg.Validate()).To(MatchError("device near duplicate device rules is missing"))
+			})
+
+			It("returns an error if the device near duplicate device rules is invalid", func() {
+				cfg.DeviceNearDuplicateDeviceRules = dataDeduplicatorDeduplicator.DeviceRules{{}}
+				Expect(cfg.Validate()).To(MatchError("device near duplicate device rules is invalid; value is empty"))
+			})
+
+			It("returns an error if the device near duplicate rule is missing", func() {
+				cfg.DeviceNearDuplicateRule = nil
+				Expect(cfg.Validate()).To(MatchError("device near duplicate rule is missing"))
+			})
+
+			It("returns an error if the device near duplicate rule is invalid", func() {
+				cfg.DeviceNearDuplicateRule.TimeWindow = -1
+				Expect(cfg.Validate()).To(MatchError("device near duplicate rule is invalid; value -1 is not between 0 and 3600"))
+			})
+
+			It("returns successfully", func() {
+				Expect(cfg.Validate()).To(Succeed())
+			})
+		})
+	})
+})
@@ -7,6 +7,7 @@ import (
 	"github.com/tidepool-org/platform/data/storeDEPRECATED"
 	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
 	"github.com/tidepool-org/platform/errors"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
 )
 
 const DeviceDeactivateHashName = "org.tidepool.deduplicator.device.deactivate.hash"
@@ -21,19 +22,40 @@ var DeviceDeactivateHashDeviceManufacturerDeviceModels = map[string][]string{
 
 type DeviceDeactivateHash struct {
 	*Base
+	deviceRules DeviceRules
 }
 
 func NewDeviceDeactivateHash() (*DeviceDeactivateHash, error) {
+	return NewDeviceDeactivateHashWithDeviceRules(DeviceDeactivateHashDeviceRules())
+}
+
+func NewDeviceDeactivateHashWithDeviceRules(deviceRules DeviceRules) (*DeviceDeactivateHash, error) {
+	if deviceRules == nil {
+		return nil, errors.New("device rules is missing")
+	} else if err := structureValidator.New().Validate(deviceRules); err != nil {
+		return nil, errors.Wrap(err, "device rules is invalid")
+	}
+
 	base, err := NewBase(DeviceDeactivateHashName, "1.1.0")
 	if err != nil {
 		return nil, err
 	}
 
 	return &DeviceDeactivateHash{
-		Base: base,
+		Base:        base,
+		deviceRules: deviceRules,
 	}, nil
 }
 
+// DeviceDeactivateHashDeviceRules returns the default device rules, used when none are configured
+func DeviceDeactivateHashDeviceRules() DeviceRules {
+	return NewDeviceRulesFromDeviceManufacturerDeviceModels(DeviceDeactivateHashDeviceManufacturerDeviceModels)
+}
+
+func (d *DeviceDeactivateHash) DeviceRules() DeviceRules {
+	return d.deviceRules
+}
+
 func (d *DeviceDeactivateHash) New(dataSet *dataTypesUpload.Upload) (bool, error) {
 	if dataSet == nil {
 		return false, errors.New("data set is missing")
@@ -50,21 +72,7 @@ func (d *DeviceDeactivateHash) New(dataSet *dataTypesUpload.Upload) (bool, error
 		return d.Get(dataSet)
 	}
 
-	if dataSet.DeviceManufacturers == nil || dataSet.DeviceModel == nil {
-		return false, nil
-	}
-
-	for _, deviceManufacturer := range *dataSet.DeviceManufacturers {
-		if allowedDeviceModels, found := DeviceDeactivateHashDeviceManufacturerDeviceModels[deviceManufacturer]; found {
-			for _, allowedDeviceModel := range allowedDeviceModels {
-				if allowedDeviceModel == *dataSet.DeviceModel {
-					return true, nil
-				}
-			}
-		}
-	}
-
-	return false, nil
+	return d.deviceRules.Match(dataSet), nil
 }
 
 func (d *DeviceDeactivateHash) Get(dataSet *dataTypesUpload.Upload) (bool, error) {
@@ -96,6 +104,50 @@ func (d *DeviceDeactivateHash) AddData(ctx context.Context, session storeDEPRECA
 	return d.Base.AddData(ctx, session, dataSet, dataSetData)
 }
 
+// Duplicates returns, for each datum, true if its identity hash is assigned to active data of the data set device in
+// other data sets, which is archived when the data set is closed
+func (d *DeviceDeactivateHash) Duplicates(ctx context.Context, session storeDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload, dataSetData data.Data) ([]bool, error) {
+	if ctx == nil {
+		return nil, errors.New("context is missing")
+	}
+	if session == nil {
+		return nil, errors.New("session is missing")
+	}
+	if dataSet == nil {
+		return nil, errors.New("data set is missing")
+	}
+	if dataSetData == nil {
+		return nil, errors.New("data set data is missing")
+	}
+
+	hashes := make([]string, len(dataSetData))
+	for index, dataSetDatum := range dataSetData {
+		fields, err := dataSetDatum.IdentityFields()
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to gather identity fields for datum")
+		}
+		if hashes[index], err = GenerateIdentityHash(fields); err != nil {
+			return nil, errors.Wrap(err, "unable to generate identity hash for datum")
+		}
+	}
+
+	existingHashes, err := session.GetDeviceDataHashes(ctx, dataSet, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	duplicateHashes := map[string]bool{}
+	for _, existingHash := range existingHashes {
+		duplicateHashes[existingHash] = true
+	}
+
+	duplicates := make([]bool, len(hashes))
+	for index, hash := range hashes {
+		duplicates[index] = duplicateHashes[hash]
+	}
+	return duplicates, nil
+}
+
 func (d *DeviceDeactivateHash) Close(ctx context.Context, session storeDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) error {
 	if ctx == nil {
 		return errors.New("context is missing")
@@ -131,3 +183,45 @@ func (d *DeviceDeactivateHash) Delete(ctx context.Context, session storeDEPRECAT
 
 	return d.Base.Delete(ctx, session, dataSet, doPurge)
 }
+
+func (d *DeviceDeactivateHash) Restore(ctx context.Context, session storeDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if session == nil {
+		return errors.New("session is missing")
+	}
+	if dataSet == nil {
+		return errors.New("data set is missing")
+	}
+
+	if err := session.RestoreDataSet(ctx, dataSet); err != nil {
+		return err
+	}
+
+	if !dataSet.Active {
+		return nil
+	}
+
+	// Closing again archives the device data with the same hashes, which was unarchived when the data set was deleted
+	return d.Close(ctx, session, dataSet)
+}
+
+func (d *DeviceDeactivateHash) Unarchive(ctx context.Context, session storeDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if session == nil {
+		return errors.New("session is missing")
+	}
+	if dataSet == nil {
+		return errors.New("data set is missing")
+	}
+
+	if err := d.Base.Unarchive(ctx, session, dataSet); err != nil {
+		return err
+	}
+
+	// Only one datum with each hash may be active, so archive the data set data duplicating the unarchived data
+	return session.ArchiveDataSetDataUsingHashesFromDevice(ctx, dataSet)
+}
@@ -32,6 +32,28 @@ var _ = Describe("DeviceDeactivateHash", func() {
 		})
 	})
 
+	Context("NewDeviceDeactivateHashWithDeviceRules", func() {
+		It("returns an error when the device rules is missing", func() {
+			deduplicator, err := dataDeduplicatorDeduplicator.NewDeviceDeactivateHashWithDeviceRules(nil)
+			Expect(err).To(MatchError("device rules is missing"))
+			Expect(deduplicator).To(BeNil())
+		})
+
+		It("returns an error when the device rules is invalid", func() {
+			deduplicator, err := dataDeduplicatorDeduplicator.NewDeviceDeactivateHashWithDeviceRules(dataDeduplicatorDeduplicator.DeviceRules{{}})
+			Expect(err).To(MatchError("device rules is invalid; value is empty"))
+			Expect(deduplicator).To(BeNil())
+		})
+
+		It("returns succesfully", func() {
+			deviceRules := dataDeduplicatorDeduplicator.DeviceRules{{DeviceManufacturer: "Abbott"}}
+			deduplicator, err := dataDeduplicatorDeduplicator.NewDeviceDeactivateHashWithDeviceRules(deviceRules)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deduplicator).ToNot(BeNil())
+			Expect(deduplicator.DeviceRules()).To(Equal(deviceRules))
+		})
+	})
+
 	Context("with new deduplicator", func() {
 		var deduplicator *dataDeduplicatorDeduplicator.DeviceDeactivateHash
 		var dataSet *dataTypesUpload.Upload
@@ -381,6 +403,77 @@ var _ = Describe("DeviceDeactivateHash", func() {
 				})
 			})
 
+			Context("Duplicates", func() {
+				var dataSetData data.Data
+				var hashes []string
+
+				BeforeEach(func() {
+					dataSetData = make(data.Data, test.RandomIntFromRange(2, 4))
+					hashes = make([]string, len(dataSetData))
+					for index := range dataSetData {
+						base := dataTypesTest.NewBase()
+						base.Deduplicator.Hash = nil
+						dataSetData[index] = base
+						fields, err := base.IdentityFields()
+						Expect(err).ToNot(HaveOccurred())
+						hashes[index], err = dataDeduplicatorDeduplicator.GenerateIdentityHash(fields)
+						Expect(err).ToNot(HaveOccurred())
+					}
+				})
+
+				It("returns an error when the context is missing", func() {
+					duplicates, err := deduplicator.Duplicates(nil, session, dataSet, dataSetData)
+					Expect(err).To(MatchError("context is missing"))
+					Expect(duplicates).To(BeNil())
+				})
+
+				It("returns an error when the session is missing", func() {
+					duplicates, err := deduplicator.Duplicates(ctx, nil, dataSet, dataSetData)
+					Expect(err).To(MatchError("session is missing"))
+					Expect(duplicates).To(BeNil())
+				})
+
+				It("returns an error when the data set is missing", func() {
+					duplicates, err := deduplicator.Duplicates(ctx, session, nil, dataSetData)
+					Expect(err).To(MatchError("data set is missing"))
+					Expect(duplicates).To(BeNil())
+				})
+
+				It("returns an error when the data set data is missing", func() {
+					duplicates, err := deduplicator.Duplicates(ctx, session, dataSet, nil)
+					Expect(err).To(MatchError("data set data is missing"))
+					Expect(duplicates).To(BeNil())
+				})
+
+				When("get device data hashes is invoked", func() {
+					AfterEach(func() {
+						Expect(session.GetDeviceDataHashesInputs).To(Equal([]dataStoreDEPRECATEDTest.GetDeviceDataHashesInput{{Context: ctx, DataSet: dataSet, Hashes: hashes}}))
+						for _, datum := range dataSetData {
+							base, ok := datum.(*dataTypes.Base)
+							Expect(ok).To(BeTrue())
+							Expect(base.Deduplicator.Hash).To(BeNil())
+						}
+					})
+
+					It("returns an error when get device data hashes returns an error", func() {
+						responseErr := errorsTest.RandomError()
+						session.GetDeviceDataHashesOutputs = []dataStoreDEPRECATEDTest.GetDeviceDataHashesOutput{{Error: responseErr}}
+						duplicates, err := deduplicator.Duplicates(ctx, session, dataSet, dataSetData)
+						Expect(err).To(Equal(responseErr))
+						Expect(duplicates).To(BeNil())
+					})
+
+					It("returns the data with hashes of the device data as duplicates", func() {
+						session.GetDeviceDataHashesOutputs = []dataStoreDEPRECATEDTest.GetDeviceDataHashesOutput{{Hashes: hashes[1:]}}
+						expected := make([]bool, len(hashes))
+						for index := range expected {
+							expected[index] = index > 0
+						}
+						Expect(deduplicator.Duplicates(ctx, session, dataSet, dataSetData)).To(Equal(expected))
+					})
+				})
+			})
+
 			Context("DeleteData", func() {
 				var selectors *data.Selectors
 
@@ -531,6 +624,111 @@ var _ = Describe("DeviceDeactivateHash", func() {
 					})
 				})
 			})
+
+			Context("Restore", func() {
+				It("returns an error when the context is missing", func() {
+					Expect(deduplicator.Restore(nil, session, dataSet)).To(MatchError("context is missing"))
+				})
+
+				It("returns an error when the session is missing", func() {
+					Expect(deduplicator.Restore(ctx, nil, dataSet)).To(MatchError("session is missing"))
+				})
+
+				It("returns an error when the data set is missing", func() {
+					Expect(deduplicator.Restore(ctx, session, nil)).To(MatchError("data set is missing"))
+				})
+
+				When("restore data set is invoked", func() {
+					AfterEach(func() {
+						Expect(session.RestoreDataSetInputs).To(Equal([]dataStoreDEPRECATEDTest.RestoreDataSetInput{{Context: ctx, DataSet: dataSet}}))
+					})
+
+					It("returns an error when restore data set returns an error", func() {
+						responseErr := errorsTest.RandomError()
+						session.RestoreDataSetOutputs = []error{responseErr}
+						Expect(deduplicator.Restore(ctx, session, dataSet)).To(Equal(responseErr))
+					})
+
+					It("returns successfully without closing when the data set is not active", func() {
+						dataSet.Active = false
+						session.RestoreDataSetOutputs = []error{nil}
+						Expect(deduplicator.Restore(ctx, session, dataSet)).To(Succeed())
+					})
+
+					When("archive device data using hashes from data set is invoked", func() {
+						BeforeEach(func() {
+							dataSet.Active = true
+							session.RestoreDataSetOutputs = []error{nil}
+						})
+
+						AfterEach(func() {
+							Expect(session.ArchiveDeviceDataUsingHashesFromDataSetInputs).To(Equal([]dataStoreDEPRECATEDTest.ArchiveDeviceDataUsingHashesFromDataSetInput{{Context: ctx, DataSet: dataSet}}))
+						})
+
+						It("returns an error when archive device data using hashes from data set returns an error", func() {
+							responseErr := errorsTest.RandomError()
+							session.ArchiveDeviceDataUsingHashesFromDataSetOutputs = []error{responseErr}
+							Expect(deduplicator.Restore(ctx, session, dataSet)).To(Equal(responseErr))
+						})
+
+						It("returns successfully when the data set is closed again", func() {
+							session.ArchiveDeviceDataUsingHashesFromDataSetOutputs = []error{nil}
+							session.UpdateDataSetOutputs = []dataStoreDEPRECATEDTest.UpdateDataSetOutput{{DataSet: dataSet, Error: nil}}
+							session.ActivateDataSetDataOutputs = []error{nil}
+							Expect(deduplicator.Restore(ctx, session, dataSet)).To(Succeed())
+							Expect(session.UpdateDataSetInputs).To(Equal([]dataStoreDEPRECATEDTest.UpdateDataSetInput{{Context: ctx, ID: *dataSet.UploadID, Update: &data.DataSetUpdate{Active: pointer.FromBool(true)}}}))
+							Expect(session.ActivateDataSetDataInputs).To(Equal([]dataStoreDEPRECATEDTest.ActivateDataSetDataInput{{Context: ctx, DataSet: dataSet, Selectors: nil}}))
+						})
+					})
+				})
+			})
+
+			Context("Unarchive", func() {
+				It("returns an error when the context is missing", func() {
+					Expect(deduplicator.Unarchive(nil, session, dataSet)).To(MatchError("context is missing"))
+				})
+
+				It("returns an error when the session is missing", func() {
+					Expect(deduplicator.Unarchive(ctx, nil, dataSet)).To(MatchError("session is missing"))
+				})
+
+				It("returns an error when the data set is missing", func() {
+					Expect(deduplicator.Unarchive(ctx, session, nil)).To(MatchError("data set is missing"))
+				})
+
+				When("unarchive device data archived by data set is invoked", func() {
+					AfterEach(func() {
+						Expect(session.UnarchiveDeviceDataArchivedByDataSetInputs).To(Equal([]dataStoreDEPRECATEDTest.UnarchiveDeviceDataArchivedByDataSetInput{{Context: ctx, DataSet: dataSet}}))
+					})
+
+					It("returns an error when unarchive device data archived by data set returns an error", func() {
+						responseErr := errorsTest.RandomError()
+						session.UnarchiveDeviceDataArchivedByDataSetOutputs = []error{responseErr}
+						Expect(deduplicator.Unarchive(ctx, session, dataSet)).To(Equal(responseErr))
+					})
+
+					When("archive data set data using hashes from device is invoked", func() {
+						BeforeEach(func() {
+							session.UnarchiveDeviceDataArchivedByDataSetOutputs = []error{nil}
+						})
+
+						AfterEach(func() {
+							Expect(session.ArchiveDataSetDataUsingHashesFromDeviceInputs).To(Equal([]dataStoreDEPRECATEDTest.ArchiveDataSetDataUsingHashesFromDeviceInput{{Context: ctx, DataSet: dataSet}}))
+						})
+
+						It("returns an error when archive data set data using hashes from device returns an error", func() {
+							responseErr := errorsTest.RandomError()
+							session.ArchiveDataSetDataUsingHashesFromDeviceOutputs = []error{responseErr}
+							Expect(deduplicator.Unarchive(ctx, session, dataSet)).To(Equal(responseErr))
+						})
+
+						It("returns successfully when archive data set data using hashes from device returns successfully", func() {
+							session.ArchiveDataSetDataUsingHashesFromDeviceOutputs = []error{nil}
+							Expect(deduplicator.Unarchive(ctx, session, dataSet)).To(Succeed())
+						})
+					})
+				})
+			})
 		})
 	})
 })
@@ -0,0 +1,397 @@
+package deduplicator
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/tidepool-org/platform/data"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	dataTypesBloodGlucoseSelfMonitored "github.com/tidepool-org/platform/data/types/blood/glucose/selfmonitored"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	"github.com/tidepool-org/platform/errors"
+	"github.com/tidepool-org/platform/origin"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/structure"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
+)
+
+const DeviceNearDuplicateName = "org.tidepool.deduplicator.device.near.duplicate"
+
+const (
+	NearDuplicateRuleTimeWindowDefault     = 10   // seconds
+	NearDuplicateRuleTimeWindowMaximum     = 3600 // seconds
+	NearDuplicateRuleTimeWindowMinimum     = 0    // seconds
+	NearDuplicateRuleValueToleranceDefault = 0.1  // in the stored units, mmol/L for blood glucose
+	NearDuplicateRuleValueToleranceMinimum = 0
+)
+
+// NearDuplicateRule matches data of the same value-bearing type within the time window and the value tolerance; of
+// matching data, the data with the first origin type in origin types is kept, and the others archived
+type NearDuplicateRule struct {
+	TimeWindow     int      `json:"timeWindow"`
+	ValueTolerance float64  `json:"valueTolerance"`
+	OriginTypes    []string `json:"originTypes"`
+}
+
+func NewNearDuplicateRule() *NearDuplicateRule {
+	return &NearDuplicateRule{
+		TimeWindow:     NearDuplicateRuleTimeWindowDefault,
+		ValueTolerance: NearDuplicateRuleValueToleranceDefault,
+		OriginTypes:    []string{origin.TypeDevice, origin.TypeService, origin.TypeManual},
+	}
+}
+
+// NearDuplicateRuleTypes returns the types of the data the rule matches, only those whose value alone identifies the
+// datum, as data of other types (eg. boluses or food) may differ in fields not compared
+func NearDuplicateRuleTypes() []string {
+	return []string{
+		dataTypesBloodGlucoseContinuous.Type,
+		dataTypesBloodGlucoseSelfMonitored.Type,
+	}
+}
+
+func (n *NearDuplicateRule) Validate(validator structure.Validator) {
+	validator.Int("timeWindow", &n.TimeWindow).InRange(NearDuplicateRuleTimeWindowMinimum, NearDuplicateRuleTimeWindowMaximum)
+	validator.Float64("valueTolerance", &n.ValueTolerance).GreaterThanOrEqualTo(NearDuplicateRuleValueToleranceMinimum)
+	validator.StringArray("originTypes", &n.OriginTypes).EachOneOf(origin.Types()...).EachUnique()
+}
+
+func (n *NearDuplicateRule) Duration() time.Duration {
+	return time.Duration(n.TimeWindow) * time.Second
+}
+
+// Matches returns true if the rule matches data of the type
+func (n *NearDuplicateRule) Matches(typ string) bool {
+	for _, ruleType := range NearDuplicateRuleTypes() {
+		if ruleType == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns true if the samples are of the same matched type, both with a value, and within the time window and
+// the value tolerance
+func (n *NearDuplicateRule) Match(sample *dataStoreDEPRECATED.DataSample, sampleTime time.Time, otherSample *dataStoreDEPRECATED.DataSample, otherSampleTime time.Time) bool {
+	if sample.Type != otherSample.Type || !n.Matches(sample.Type) {
+		return false
+	}
+	if difference := sampleTime.Sub(otherSampleTime); difference > n.Duration() || difference < -n.Duration() {
+		return false
+	}
+	if sample.Value == nil || otherSample.Value == nil {
+		return false
+	}
+	return math.Abs(*sample.Value-*otherSample.Value) <= n.ValueTolerance
+}
+
+// Rank returns the preference of the sample by origin type, lower is preferred
+func (n *NearDuplicateRule) Rank(sample *dataStoreDEPRECATED.DataSample) int {
+	originType := sample.OriginType()
+	for index, preferredOriginType := range n.OriginTypes {
+		if preferredOriginType == originType {
+			return index
+		}
+	}
+	return len(n.OriginTypes)
+}
+
+type DeviceNearDuplicate struct {
+	*Base
+	deviceRules DeviceRules
+	rule        *NearDuplicateRule
+}
+
+// NewDeviceNearDuplicate returns a deduplicator that is never selected by device, only by name
+func NewDeviceNearDuplicate() (*DeviceNearDuplicate, error) {
+	return NewDeviceNearDuplicateWithDeviceRules(DeviceRules{}, NewNearDuplicateRule())
+}
+
+func NewDeviceNearDuplicateWithDeviceRules(deviceRules DeviceRules, rule *NearDuplicateRule) (*DeviceNearDuplicate, error) {
+	if deviceRules == nil {
+		return nil, errors.New("device rules is missing")
+	} else if err := structureValidator.New().Validate(deviceRules); err != nil {
+		return nil, errors.Wrap(err, "device rules is invalid")
+	}
+	if rule == nil {
+		return nil, errors.New("rule is missing")
+	} else if err := structureValidator.New().Validate(rule); err != nil {
+		return nil, errors.Wrap(err, "rule is invalid")
+	}
+
+	base, err := NewBase(DeviceNearDuplicateName, "1.0.0")
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeviceNearDuplicate{
+		Base:        base,
+		deviceRules: deviceRules,
+		rule:        rule,
+	}, nil
+}
+
+func (d *DeviceNearDuplicate) DeviceRules() DeviceRules {
+	return d.deviceRules
+}
+
+func (d *DeviceNearDuplicate) Rule() *NearDuplicateRule {
+	return d.rule
+}
+
+func (d *DeviceNearDuplicate) New(dataSet *dataTypesUpload.Upload) (bool, error) {
+	if dataSet == nil {
+		return false, errors.New("data set is missing")
+	}
+
+	if !dataSet.HasDataSetTypeNormal() {
+		return false, nil
+	}
+	if dataSet.DeviceID == nil {
+		return false, nil
+	}
+
+	if dataSet.HasDeduplicatorName() {
+		return d.Get(dataSet)
+	}
+
+	return d.deviceRules.Match(dataSet), nil
+}
+
+func (d *DeviceNearDuplicate) Close(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if session == nil {
+		return errors.New("session is missing")
+	}
+	if dataSet == nil {
+		return errors.New("data set is missing")
+	}
+
+	ids, err := d.nearDuplicateIDs(ctx, session, dataSet)
+	if err != nil {
+		return err
+	}
+
+	// The data set data is only active once closed, and only active data is archived, so archive after closing. If
+	// archiving fails, the near duplicates remain until the data set is uploaded again.
+	if err = d.Base.Close(ctx, session, dataSet); err != nil {
+		return err
+	}
+
+	return session.ArchiveDeviceDataByIDs(ctx, dataSet, ids)
+}
+
+func (d *DeviceNearDuplicate) Delete(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload, doPurge bool) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if session == nil {
+		return errors.New("session is missing")
+	}
+	if dataSet == nil {
+		return errors.New("data set is missing")
+	}
+
+	if err := session.UnarchiveDeviceDataArchivedByDataSet(ctx, dataSet); err != nil {
+		return err
+	}
+
+	return d.Base.Delete(ctx, session, dataSet, doPurge)
+}
+
+func (d *DeviceNearDuplicate) Restore(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if session == nil {
+		return errors.New("session is missing")
+	}
+	if dataSet == nil {
+		return errors.New("data set is missing")
+	}
+
+	if err := session.RestoreDataSet(ctx, dataSet); err != nil {
+		return err
+	}
+
+	if !dataSet.Active {
+		return nil
+	}
+
+	// Closing again archives the near duplicates, which were unarchived when the data set was deleted
+	return d.Close(ctx, session, dataSet)
+}
+
+func (d *DeviceNearDuplicate) Unarchive(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if session == nil {
+		return errors.New("session is missing")
+	}
+	if dataSet == nil {
+		return errors.New("data set is missing")
+	}
+
+	if err := d.Base.Unarchive(ctx, session, dataSet); err != nil {
+		return err
+	}
+
+	// Keep the unarchived data, so archive the data set data near duplicating it
+	samples, otherSamples, sampleTimes, err := d.getDataSamples(ctx, session, dataSet)
+	if err != nil || len(samples) == 0 {
+		return err
+	}
+
+	selectors := data.NewSelectors()
+	for _, sample := range samples {
+		matched := false
+		d.forEachMatch(sample, otherSamples, sampleTimes, func(otherSample *dataStoreDEPRECATED.DataSample) {
+			matched = true
+		})
+		if matched {
+			*selectors = append(*selectors, &data.Selector{ID: pointer.FromString(sample.ID)})
+		}
+	}
+	if len(*selectors) == 0 {
+		return nil
+	}
+
+	return session.ArchiveDataSetData(ctx, dataSet, selectors)
+}
+
+// nearDuplicateIDs returns the ids of the data, both of the data set and of the other data of the device, to archive
+func (d *DeviceNearDuplicate) nearDuplicateIDs(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) ([]string, error) {
+	samples, otherSamples, sampleTimes, err := d.getDataSamples(ctx, session, dataSet)
+	if err != nil || len(samples) == 0 {
+		return nil, err
+	}
+
+	archived := map[*dataStoreDEPRECATED.DataSample]bool{}
+	for _, sample := range samples {
+		keep := sample
+		matches := []*dataStoreDEPRECATED.DataSample{sample}
+		d.forEachMatch(sample, otherSamples, sampleTimes, func(otherSample *dataStoreDEPRECATED.DataSample) {
+			if archived[otherSample] {
+				return
+			}
+			matches = append(matches, otherSample)
+
+			// Prefer by origin type, otherwise keep the data already stored, the earliest created first
+			if rank, keepRank := d.rule.Rank(otherSample), d.rule.Rank(keep); rank < keepRank || (rank == keepRank && (keep == sample || otherSample.CreatedTime < keep.CreatedTime)) {
+				keep = otherSample
+			}
+		})
+
+		for _, match := range matches {
+			if match != keep {
+				archived[match] = true
+			}
+		}
+	}
+
+	ids := []string{}
+	for _, sample := range append(samples, otherSamples...) {
+		if archived[sample] {
+			ids = append(ids, sample.ID)
+		}
+	}
+	return ids, nil
+}
+
+// getDataSamples returns the data set data samples of the matched types with valid times, the active data samples of
+// the same device in other data sets within the time window of them sorted by time, and the parsed time of each
+func (d *DeviceNearDuplicate) getDataSamples(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) ([]*dataStoreDEPRECATED.DataSample, []*dataStoreDEPRECATED.DataSample, map[*dataStoreDEPRECATED.DataSample]time.Time, error) {
+	samples, err := session.GetDataSetDataSamples(ctx, dataSet)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	sampleTimes := map[*dataStoreDEPRECATED.DataSample]time.Time{}
+	samples = parseDataSampleTimes(samples, sampleTimes)
+	if len(samples) == 0 {
+		return nil, nil, nil, nil
+	}
+
+	typesMap := map[string]bool{}
+	var startTime time.Time
+	var endTime time.Time
+	matchedSamples := []*dataStoreDEPRECATED.DataSample{}
+	for _, sample := range samples {
+		if !d.rule.Matches(sample.Type) {
+			continue
+		}
+		matchedSamples = append(matchedSamples, sample)
+		sampleTime := sampleTimes[sample]
+		typesMap[sample.Type] = true
+		if startTime.IsZero() || sampleTime.Before(startTime) {
+			startTime = sampleTime
+		}
+		if endTime.IsZero() || sampleTime.After(endTime) {
+			endTime = sampleTime
+		}
+	}
+	if samples = matchedSamples; len(samples) == 0 {
+		return nil, nil, nil, nil
+	}
+	types := []string{}
+	for typ := range typesMap {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+
+	// Widen the range by a second, as the stored times are compared as strings and may not share the same precision
+	startTime = startTime.Add(-d.rule.Duration() - time.Second)
+	endTime = endTime.Add(d.rule.Duration() + time.Second)
+
+	otherSamples, err := session.GetDeviceDataSamples(ctx, dataSet, types, startTime, endTime)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	otherSamples = parseDataSampleTimes(otherSamples, sampleTimes)
+	sort.SliceStable(otherSamples, func(i int, j int) bool {
+		return sampleTimes[otherSamples[i]].Before(sampleTimes[otherSamples[j]])
+	})
+
+	return samples, otherSamples, sampleTimes, nil
+}
+
+// forEachMatch calls the function with each of the other samples, sorted by time, the sample matches
+func (d *DeviceNearDuplicate) forEachMatch(sample *dataStoreDEPRECATED.DataSample, otherSamples []*dataStoreDEPRECATED.DataSample, sampleTimes map[*dataStoreDEPRECATED.DataSample]time.Time, function func(otherSample *dataStoreDEPRECATED.DataSample)) {
+	sampleTime := sampleTimes[sample]
+	index := sort.Search(len(otherSamples), func(i int) bool {
+		return !sampleTimes[otherSamples[i]].Before(sampleTime.Add(-d.rule.Duration()))
+	})
+	for ; index < len(otherSamples); index++ {
+		otherSample := otherSamples[index]
+		otherSampleTime := sampleTimes[otherSample]
+		if otherSampleTime.After(sampleTime.Add(d.rule.Duration())) {
+			break
+		}
+		if d.rule.Match(sample, sampleTime, otherSample, otherSampleTime) {
+			function(otherSample)
+		}
+	}
+}
+
+// parseDataSampleTimes adds the parsed time of each sample to the sample times and returns only those samples with a
+// valid id and time
+func parseDataSampleTimes(samples []*dataStoreDEPRECATED.DataSample, sampleTimes map[*dataStoreDEPRECATED.DataSample]time.Time) []*dataStoreDEPRECATED.DataSample {
+	parsedSamples := []*dataStoreDEPRECATED.DataSample{}
+	for _, sample := range samples {
+		if sample == nil || sample.ID == "" {
+			continue
+		}
+		if sampleTime, err := time.Parse(data.TimeFormat, sample.Time); err == nil {
+			sampleTimes[sample] = sampleTime
+			parsedSamples = append(parsedSamples, sample)
+		}
+	}
+	return parsedSamples
+}
@@ -0,0 +1,496 @@
+package deduplicator_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	dataDeduplicatorDeduplicator "github.com/tidepool-org/platform/data/deduplicator/deduplicator"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	dataStoreDEPRECATEDTest "github.com/tidepool-org/platform/data/storeDEPRECATED/test"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	dataTypesUploadTest "github.com/tidepool-org/platform/data/types/upload/test"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/origin"
+	"github.com/tidepool-org/platform/pointer"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
+)
+
+func newDataSample(id string, typ string, tm time.Time, value *float64, originType *string) *dataStoreDEPRECATED.DataSample {
+	sample := &dataStoreDEPRECATED.DataSample{
+		ID:          id,
+		Type:        typ,
+		Time:        tm.Format(data.TimeFormat),
+		Value:       value,
+		CreatedTime: "2020-01-01T00:00:00Z",
+	}
+	if originType != nil {
+		sample.Origin = &origin.Origin{Type: originType}
+	}
+	return sample
+}
+
+var _ = Describe("DeviceNearDuplicate", func() {
+	It("DeviceNearDuplicateName is expected", func() {
+		Expect(dataDeduplicatorDeduplicator.DeviceNearDuplicateName).To(Equal("org.tidepool.deduplicator.device.near.duplicate"))
+	})
+
+	Context("NearDuplicateRule", func() {
+		var rule *dataDeduplicatorDeduplicator.NearDuplicateRule
+		var sampleTime time.Time
+
+		BeforeEach(func() {
+			rule = dataDeduplicatorDeduplicator.NewNearDuplicateRule()
+			sampleTime = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		})
+
+		It("returns the default rule", func() {
+			Expect(rule).To(Equal(&dataDeduplicatorDeduplicator.NearDuplicateRule{
+				TimeWindow:     10,
+				ValueTolerance: 0.1,
+				OriginTypes:    []string{"device", "service", "manual"},
+			}))
+			Expect(rule.Duration()).To(Equal(10 * time.Second))
+		})
+
+		Context("Validate", func() {
+			It("reports an error if the time window is out of range", func() {
+				rule.TimeWindow = 3601
+				Expect(structureValidator.New().Validate(rule)).To(MatchError("value 3601 is not between 0 and 3600"))
+			})
+
+			It("reports an error if the value tolerance is negative", func() {
+				rule.ValueTolerance = -0.1
+				Expect(structureValidator.New().Validate(rule)).To(MatchError("value -0.1 is not greater than or equal to 0"))
+			})
+
+			It("reports an error if an origin type is invalid", func() {
+				rule.OriginTypes = []string{"device", "invalid"}
+				Expect(structureValidator.New().Validate(rule)).To(HaveOccurred())
+			})
+
+			It("reports an error if an origin type is duplicated", func() {
+				rule.OriginTypes = []string{"device", "device"}
+				Expect(structureValidator.New().Validate(rule)).To(MatchError("value is a duplicate"))
+			})
+
+			It("returns successfully", func() {
+				Expect(structureValidator.New().Validate(rule)).To(Succeed())
+			})
+		})
+
+		Context("Match", func() {
+			var sample *dataStoreDEPRECATED.DataSample
+
+			BeforeEach(func() {
+				sample = newDataSample("a", "cbg", sampleTime, pointer.FromFloat64(5.5), nil)
+			})
+
+			It("returns false if the types differ", func() {
+				other := newDataSample("b", "smbg", sampleTime, pointer.FromFloat64(5.5), nil)
+				Expect(rule.Match(sample, sampleTime, other, sampleTime)).To(BeFalse())
+			})
+
+			It("returns false if the times differ by more than the time window", func() {
+				otherTime := sampleTime.Add(-11 * time.Second)
+				other := newDataSample("b", "cbg", otherTime, pointer.FromFloat64(5.5), nil)
+				Expect(rule.Match(sample, sampleTime, other, otherTime)).To(BeFalse())
+			})
+
+			It("returns false if the values differ by more than the value tolerance", func() {
+				other := newDataSample("b", "cbg", sampleTime, pointer.FromFloat64(5.7), nil)
+				Expect(rule.Match(sample, sampleTime, other, sampleTime)).To(BeFalse())
+			})
+
+			It("returns false if only one has a value", func() {
+				other := newDataSample("b", "cbg", sampleTime, nil, nil)
+				Expect(rule.Match(sample, sampleTime, other, sampleTime)).To(BeFalse())
+			})
+
+			It("returns false if neither has a value", func() {
+				sample.Value = nil
+				other := newDataSample("b", "cbg", sampleTime, nil, nil)
+				Expect(rule.Match(sample, sampleTime, other, sampleTime)).To(BeFalse())
+			})
+
+			It("returns false if the type is not matched", func() {
+				sample = newDataSample("a", "bolus", sampleTime, nil, nil)
+				other := newDataSample("b", "bolus", sampleTime.Add(time.Second), nil, nil)
+				Expect(rule.Match(sample, sampleTime, other, sampleTime.Add(time.Second))).To(BeFalse())
+			})
+
+			It("returns true if within the time window and the value tolerance", func() {
+				otherTime := sampleTime.Add(10 * time.Second)
+				other := newDataSample("b", "cbg", otherTime, pointer.FromFloat64(5.45), nil)
+				Expect(rule.Match(sample, sampleTime, other, otherTime)).To(BeTrue())
+			})
+		})
+
+		Context("Matches", func() {
+			It("returns true for the value-bearing types", func() {
+				Expect(dataDeduplicatorDeduplicator.NearDuplicateRuleTypes()).To(Equal([]string{"cbg", "smbg"}))
+				Expect(rule.Matches("cbg")).To(BeTrue())
+				Expect(rule.Matches("smbg")).To(BeTrue())
+			})
+
+			It("returns false for the other types", func() {
+				Expect(rule.Matches("bolus")).To(BeFalse())
+				Expect(rule.Matches("food")).To(BeFalse())
+			})
+		})
+
+		Context("Rank", func() {
+			It("returns the index of the origin type", func() {
+				Expect(rule.Rank(newDataSample("a", "cbg", sampleTime, nil, pointer.FromString("service")))).To(Equal(1))
+			})
+
+			It("returns after all origin types if the origin type is missing", func() {
+				Expect(rule.Rank(newDataSample("a", "cbg", sampleTime, nil, nil))).To(Equal(3))
+			})
+		})
+	})
+
+	Context("NewDeviceNearDuplicate", func() {
+		It("returns successfully without device rules", func() {
+			deduplicator, err := dataDeduplicatorDeduplicator.NewDeviceNearDuplicate()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deduplicator).ToNot(BeNil())
+			Expect(deduplicator.DeviceRules()).To(BeEmpty())
+			Expect(deduplicator.Rule()).To(Equal(dataDeduplicatorDeduplicator.NewNearDuplicateRule()))
+		})
+	})
+
+	Context("NewDeviceNearDuplicateWithDeviceRules", func() {
+		It("returns an error when the device rules is missing", func() {
+			deduplicator, err := dataDeduplicatorDeduplicator.NewDeviceNearDuplicateWithDeviceRules(nil, dataDeduplicatorDeduplicator.NewNearDuplicateRule())
+			Expect(err).To(MatchError("device rules is missing"))
+			Expect(deduplicator).To(BeNil())
+		})
+
+		It("returns an error when the device rules is invalid", func() {
+			deduplicator, err := dataDeduplicatorDeduplicator.NewDeviceNearDuplicateWithDeviceRules(dataDeduplicatorDeduplicator.DeviceRules{{}}, dataDeduplicatorDeduplicator.NewNearDuplicateRule())
+			Expect(err).To(MatchError("device rules is invalid; value is empty"))
+			Expect(deduplicator).To(BeNil())
+		})
+
+		It("returns an error when the rule is missing", func() {
+			deduplicator, err := dataDeduplicatorDeduplicator.NewDeviceNearDuplicateWithDeviceRules(dataDeduplicatorDeduplicator.DeviceRules{}, nil)
+			Expect(err).To(MatchError("rule is missing"))
+			Expect(deduplicator).To(BeNil())
+		})
+
+		It("returns an error when the rule is invalid", func() {
+			rule := dataDeduplicatorDeduplicator.NewNearDuplicateRule()
+			rule.TimeWindow = -1
+			deduplicator, err := dataDeduplicatorDeduplicator.NewDeviceNearDuplicateWithDeviceRules(dataDeduplicatorDeduplicator.DeviceRules{}, rule)
+			Expect(err).To(MatchError("rule is invalid; value -1 is not between 0 and 3600"))
+			Expect(deduplicator).To(BeNil())
+		})
+
+		It("returns succesfully", func() {
+			deviceRules := dataDeduplicatorDeduplicator.DeviceRules{{DeviceManufacturer: "Dexcom"}}
+			rule := dataDeduplicatorDeduplicator.NewNearDuplicateRule()
+			deduplicator, err := dataDeduplicatorDeduplicator.NewDeviceNearDuplicateWithDeviceRules(deviceRules, rule)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deduplicator).ToNot(BeNil())
+			Expect(deduplicator.DeviceRules()).To(Equal(deviceRules))
+			Expect(deduplicator.Rule()).To(Equal(rule))
+		})
+	})
+
+	Context("with new deduplicator", func() {
+		var deduplicator *dataDeduplicatorDeduplicator.DeviceNearDuplicate
+		var dataSet *dataTypesUpload.Upload
+
+		BeforeEach(func() {
+			var err error
+			deduplicator, err = dataDeduplicatorDeduplicator.NewDeviceNearDuplicateWithDeviceRules(dataDeduplicatorDeduplicator.DeviceRules{{DeviceManufacturer: "Dexcom"}}, dataDeduplicatorDeduplicator.NewNearDuplicateRule())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deduplicator).ToNot(BeNil())
+			dataSet = dataTypesUploadTest.RandomUpload()
+			dataSet.DataSetType = pointer.FromString("normal")
+			dataSet.Deduplicator = nil
+			dataSet.DeviceManufacturers = pointer.FromStringArray([]string{"Dexcom"})
+		})
+
+		Context("New", func() {
+			It("returns an error when the data set is missing", func() {
+				found, err := deduplicator.New(nil)
+				Expect(err).To(MatchError("data set is missing"))
+				Expect(found).To(BeFalse())
+			})
+
+			It("returns false when the data set type is not normal", func() {
+				dataSet.DataSetType = pointer.FromString("continuous")
+				Expect(deduplicator.New(dataSet)).To(BeFalse())
+			})
+
+			It("returns false when the device id is missing", func() {
+				dataSet.DeviceID = nil
+				Expect(deduplicator.New(dataSet)).To(BeFalse())
+			})
+
+			It("returns false when the deduplicator name does not match", func() {
+				dataSet.Deduplicator = &data.DeduplicatorDescriptor{Name: pointer.FromString("org.tidepool.deduplicator.none")}
+				Expect(deduplicator.New(dataSet)).To(BeFalse())
+			})
+
+			It("returns true when the deduplicator name matches", func() {
+				dataSet.Deduplicator = &data.DeduplicatorDescriptor{Name: pointer.FromString("org.tidepool.deduplicator.device.near.duplicate")}
+				Expect(deduplicator.New(dataSet)).To(BeTrue())
+			})
+
+			It("returns false when the device rules do not match", func() {
+				dataSet.DeviceManufacturers = pointer.FromStringArray([]string{"Abbott"})
+				Expect(deduplicator.New(dataSet)).To(BeFalse())
+			})
+
+			It("returns true when the device rules match", func() {
+				Expect(deduplicator.New(dataSet)).To(BeTrue())
+			})
+		})
+
+		Context("with context and session", func() {
+			var ctx context.Context
+			var session *dataStoreDEPRECATEDTest.DataSession
+			var sampleTime time.Time
+
+			BeforeEach(func() {
+				ctx = context.Background()
+				session = dataStoreDEPRECATEDTest.NewDataSession()
+				sampleTime = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+			})
+
+			AfterEach(func() {
+				session.AssertOutputsEmpty()
+			})
+
+			Context("Close", func() {
+				It("returns an error when the context is missing", func() {
+					Expect(deduplicator.Close(nil, session, dataSet)).To(MatchError("context is missing"))
+				})
+
+				It("returns an error when the session is missing", func() {
+					Expect(deduplicator.Close(ctx, nil, dataSet)).To(MatchError("session is missing"))
+				})
+
+				It("returns an error when the data set is missing", func() {
+					Expect(deduplicator.Close(ctx, session, nil)).To(MatchError("data set is missing"))
+				})
+
+				It("returns an error when get data set data samples returns an error", func() {
+					responseErr := errorsTest.RandomError()
+					session.GetDataSetDataSamplesOutputs = []dataStoreDEPRECATEDTest.GetDataSetDataSamplesOutput{{Error: responseErr}}
+					Expect(deduplicator.Close(ctx, session, dataSet)).To(Equal(responseErr))
+				})
+
+				It("returns an error when get device data samples returns an error", func() {
+					responseErr := errorsTest.RandomError()
+					session.GetDataSetDataSamplesOutputs = []dataStoreDEPRECATEDTest.GetDataSetDataSamplesOutput{{Samples: []*dataStoreDEPRECATED.DataSample{newDataSample("a", "cbg", sampleTime, pointer.FromFloat64(5.5), nil)}}}
+					session.GetDeviceDataSamplesOutputs = []dataStoreDEPRECATEDTest.GetDeviceDataSamplesOutput{{Error: responseErr}}
+					Expect(deduplicator.Close(ctx, session, dataSet)).To(Equal(responseErr))
+				})
+
+				It("archives nothing when the data set has only data of other types", func() {
+					session.GetDataSetDataSamplesOutputs = []dataStoreDEPRECATEDTest.GetDataSetDataSamplesOutput{{Samples: []*dataStoreDEPRECATED.DataSample{
+						newDataSample("bolus-first", "bolus", sampleTime, nil, nil),
+						newDataSample("bolus-second", "bolus", sampleTime.Add(5*time.Second), nil, nil),
+					}}}
+					session.UpdateDataSetOutputs = []dataStoreDEPRECATEDTest.UpdateDataSetOutput{{DataSet: dataSet}}
+					session.ActivateDataSetDataOutputs = []error{nil}
+					session.ArchiveDeviceDataByIDsOutputs = []error{nil}
+					Expect(deduplicator.Close(ctx, session, dataSet)).To(Succeed())
+					Expect(session.GetDeviceDataSamplesInputs).To(BeEmpty())
+					Expect(session.ArchiveDeviceDataByIDsInputs).To(Equal([]dataStoreDEPRECATEDTest.ArchiveDeviceDataByIDsInput{{Context: ctx, DataSet: dataSet, IDs: nil}}))
+				})
+
+				When("the data set data samples and device data samples are returned", func() {
+					var samples []*dataStoreDEPRECATED.DataSample
+					var otherSamples []*dataStoreDEPRECATED.DataSample
+
+					BeforeEach(func() {
+						samples = []*dataStoreDEPRECATED.DataSample{
+							newDataSample("new-device", "cbg", sampleTime, pointer.FromFloat64(5.5), pointer.FromString(origin.TypeDevice)),
+							newDataSample("new-service", "cbg", sampleTime.Add(5*time.Minute), pointer.FromFloat64(6.5), pointer.FromString(origin.TypeService)),
+							newDataSample("new-unmatched", "cbg", sampleTime.Add(10*time.Minute), pointer.FromFloat64(7.5), nil),
+							newDataSample("new-same", "smbg", sampleTime.Add(15*time.Minute), pointer.FromFloat64(8.5), nil),
+						}
+						otherSamples = []*dataStoreDEPRECATED.DataSample{
+							newDataSample("old-service", "cbg", sampleTime.Add(3*time.Second), pointer.FromFloat64(5.45), pointer.FromString(origin.TypeService)),
+							newDataSample("old-device", "cbg", sampleTime.Add(5*time.Minute-4*time.Second), pointer.FromFloat64(6.5), pointer.FromString(origin.TypeDevice)),
+							newDataSample("old-far", "cbg", sampleTime.Add(10*time.Minute+11*time.Second), pointer.FromFloat64(7.5), nil),
+							newDataSample("old-same", "smbg", sampleTime.Add(15*time.Minute+1*time.Second), pointer.FromFloat64(8.5), nil),
+						}
+						session.GetDataSetDataSamplesOutputs = []dataStoreDEPRECATEDTest.GetDataSetDataSamplesOutput{{Samples: samples}}
+						session.GetDeviceDataSamplesOutputs = []dataStoreDEPRECATEDTest.GetDeviceDataSamplesOutput{{Samples: otherSamples}}
+						session.UpdateDataSetOutputs = []dataStoreDEPRECATEDTest.UpdateDataSetOutput{{DataSet: dataSet}}
+						session.ActivateDataSetDataOutputs = []error{nil}
+					})
+
+					AfterEach(func() {
+						Expect(session.GetDataSetDataSamplesInputs).To(Equal([]dataStoreDEPRECATEDTest.GetDataSetDataSamplesInput{{Context: ctx, DataSet: dataSet}}))
+						Expect(session.GetDeviceDataSamplesInputs).To(Equal([]dataStoreDEPRECATEDTest.GetDeviceDataSamplesInput{{
+							Context:   ctx,
+							DataSet:   dataSet,
+							Types:     []string{"cbg", "smbg"},
+							StartTime: sampleTime.Add(-11 * time.Second),
+							EndTime:   sampleTime.Add(15*time.Minute + 11*time.Second),
+						}}))
+						Expect(session.UpdateDataSetInputs).To(Equal([]dataStoreDEPRECATEDTest.UpdateDataSetInput{{Context: ctx, ID: *dataSet.UploadID, Update: &data.DataSetUpdate{Active: pointer.FromBool(true)}}}))
+						Expect(session.ActivateDataSetDataInputs).To(Equal([]dataStoreDEPRECATEDTest.ActivateDataSetDataInput{{Context: ctx, DataSet: dataSet, Selectors: nil}}))
+					})
+
+					It("returns an error when archive device data by ids returns an error", func() {
+						responseErr := errorsTest.RandomError()
+						session.ArchiveDeviceDataByIDsOutputs = []error{responseErr}
+						Expect(deduplicator.Close(ctx, session, dataSet)).To(Equal(responseErr))
+					})
+
+					It("archives the near duplicates not preferred by origin type, otherwise the data set data", func() {
+						session.ArchiveDeviceDataByIDsOutputs = []error{nil}
+						Expect(deduplicator.Close(ctx, session, dataSet)).To(Succeed())
+						Expect(session.ArchiveDeviceDataByIDsInputs).To(Equal([]dataStoreDEPRECATEDTest.ArchiveDeviceDataByIDsInput{{
+							Context: ctx,
+							DataSet: dataSet,
+							IDs:     []string{"new-service", "new-same", "old-service"},
+						}}))
+					})
+				})
+			})
+
+			Context("Delete", func() {
+				It("returns an error when the context is missing", func() {
+					Expect(deduplicator.Delete(nil, session, dataSet, false)).To(MatchError("context is missing"))
+				})
+
+				It("returns an error when the session is missing", func() {
+					Expect(deduplicator.Delete(ctx, nil, dataSet, false)).To(MatchError("session is missing"))
+				})
+
+				It("returns an error when the data set is missing", func() {
+					Expect(deduplicator.Delete(ctx, session, nil, false)).To(MatchError("data set is missing"))
+				})
+
+				It("returns an error when unarchive device data archived by data set returns an error", func() {
+					responseErr := errorsTest.RandomError()
+					session.UnarchiveDeviceDataArchivedByDataSetOutputs = []error{responseErr}
+					Expect(deduplicator.Delete(ctx, session, dataSet, false)).To(Equal(responseErr))
+					Expect(session.UnarchiveDeviceDataArchivedByDataSetInputs).To(Equal([]dataStoreDEPRECATEDTest.UnarchiveDeviceDataArchivedByDataSetInput{{Context: ctx, DataSet: dataSet}}))
+				})
+
+				It("returns successfully when delete data set returns successfully", func() {
+					session.UnarchiveDeviceDataArchivedByDataSetOutputs = []error{nil}
+					session.DeleteDataSetOutputs = []error{nil}
+					Expect(deduplicator.Delete(ctx, session, dataSet, false)).To(Succeed())
+					Expect(session.DeleteDataSetInputs).To(Equal([]dataStoreDEPRECATEDTest.DeleteDataSetInput{{Context: ctx, DataSet: dataSet}}))
+				})
+			})
+
+			Context("Restore", func() {
+				It("returns an error when the context is missing", func() {
+					Expect(deduplicator.Restore(nil, session, dataSet)).To(MatchError("context is missing"))
+				})
+
+				It("returns an error when the session is missing", func() {
+					Expect(deduplicator.Restore(ctx, nil, dataSet)).To(MatchError("session is missing"))
+				})
+
+				It("returns an error when the data set is missing", func() {
+					Expect(deduplicator.Restore(ctx, session, nil)).To(MatchError("data set is missing"))
+				})
+
+				When("restore data set is invoked", func() {
+					AfterEach(func() {
+						Expect(session.RestoreDataSetInputs).To(Equal([]dataStoreDEPRECATEDTest.RestoreDataSetInput{{Context: ctx, DataSet: dataSet}}))
+					})
+
+					It("returns an error when restore data set returns an error", func() {
+						responseErr := errorsTest.RandomError()
+						session.RestoreDataSetOutputs = []error{responseErr}
+						Expect(deduplicator.Restore(ctx, session, dataSet)).To(Equal(responseErr))
+					})
+
+					It("returns successfully without closing when the data set is not active", func() {
+						dataSet.Active = false
+						session.RestoreDataSetOutputs = []error{nil}
+						Expect(deduplicator.Restore(ctx, session, dataSet)).To(Succeed())
+					})
+
+					It("returns successfully when the data set is closed again", func() {
+						dataSet.Active = true
+						session.RestoreDataSetOutputs = []error{nil}
+						session.GetDataSetDataSamplesOutputs = []dataStoreDEPRECATEDTest.GetDataSetDataSamplesOutput{{Samples: nil}}
+						session.UpdateDataSetOutputs = []dataStoreDEPRECATEDTest.UpdateDataSetOutput{{DataSet: dataSet}}
+						session.ActivateDataSetDataOutputs = []error{nil}
+						session.ArchiveDeviceDataByIDsOutputs = []error{nil}
+						Expect(deduplicator.Restore(ctx, session, dataSet)).To(Succeed())
+						Expect(session.ArchiveDeviceDataByIDsInputs).To(Equal([]dataStoreDEPRECATEDTest.ArchiveDeviceDataByIDsInput{{Context: ctx, DataSet: dataSet, IDs: nil}}))
+					})
+				})
+			})
+
+			Context("Unarchive", func() {
+				It("returns an error when the context is missing", func() {
+					Expect(deduplicator.Unarchive(nil, session, dataSet)).To(MatchError("context is missing"))
+				})
+
+				It("returns an error when the session is missing", func() {
+					Expect(deduplicator.Unarchive(ctx, nil, dataSet)).To(MatchError("session is missing"))
+				})
+
+				It("returns an error when the data set is missing", func() {
+					Expect(deduplicator.Unarchive(ctx, session, nil)).To(MatchError("data set is missing"))
+				})
+
+				It("returns an error when unarchive device data archived by data set returns an error", func() {
+					responseErr := errorsTest.RandomError()
+					session.UnarchiveDeviceDataArchivedByDataSetOutputs = []error{responseErr}
+					Expect(deduplicator.Unarchive(ctx, session, dataSet)).To(Equal(responseErr))
+				})
+
+				When("unarchive device data archived by data set returns successfully", func() {
+					BeforeEach(func() {
+						session.UnarchiveDeviceDataArchivedByDataSetOutputs = []error{nil}
+					})
+
+					AfterEach(func() {
+						Expect(session.UnarchiveDeviceDataArchivedByDataSetInputs).To(Equal([]dataStoreDEPRECATEDTest.UnarchiveDeviceDataArchivedByDataSetInput{{Context: ctx, DataSet: dataSet}}))
+					})
+
+					It("returns an error when get data set data samples returns an error", func() {
+						responseErr := errorsTest.RandomError()
+						session.GetDataSetDataSamplesOutputs = []dataStoreDEPRECATEDTest.GetDataSetDataSamplesOutput{{Error: responseErr}}
+						Expect(deduplicator.Unarchive(ctx, session, dataSet)).To(Equal(responseErr))
+					})
+
+					It("returns successfully when there are no data set data samples", func() {
+						session.GetDataSetDataSamplesOutputs = []dataStoreDEPRECATEDTest.GetDataSetDataSamplesOutput{{Samples: nil}}
+						Expect(deduplicator.Unarchive(ctx, session, dataSet)).To(Succeed())
+					})
+
+					It("returns successfully without archiving when no data set data near duplicates other data", func() {
+						session.GetDataSetDataSamplesOutputs = []dataStoreDEPRECATEDTest.GetDataSetDataSamplesOutput{{Samples: []*dataStoreDEPRECATED.DataSample{newDataSample("new", "cbg", sampleTime, pointer.FromFloat64(5.5), nil)}}}
+						session.GetDeviceDataSamplesOutputs = []dataStoreDEPRECATEDTest.GetDeviceDataSamplesOutput{{Samples: []*dataStoreDEPRECATED.DataSample{newDataSample("old", "cbg", sampleTime.Add(time.Minute), pointer.FromFloat64(5.5), nil)}}}
+						Expect(deduplicator.Unarchive(ctx, session, dataSet)).To(Succeed())
+					})
+
+					It("archives the data set data near duplicating other data", func() {
+						session.GetDataSetDataSamplesOutputs = []dataStoreDEPRECATEDTest.GetDataSetDataSamplesOutput{{Samples: []*dataStoreDEPRECATED.DataSample{
+							newDataSample("new-matched", "cbg", sampleTime, pointer.FromFloat64(5.5), pointer.FromString(origin.TypeDevice)),
+							newDataSample("new-unmatched", "cbg", sampleTime.Add(time.Minute), pointer.FromFloat64(6.5), pointer.FromString(origin.TypeDevice)),
+						}}}
+						session.GetDeviceDataSamplesOutputs = []dataStoreDEPRECATEDTest.GetDeviceDataSamplesOutput{{Samples: []*dataStoreDEPRECATED.DataSample{
+							newDataSample("old", "cbg", sampleTime.Add(5*time.Second), pointer.FromFloat64(5.5), pointer.FromString(origin.TypeManual)),
+						}}}
+						session.ArchiveDataSetDataOutputs = []error{nil}
+						Expect(deduplicator.Unarchive(ctx, session, dataSet)).To(Succeed())
+						Expect(session.ArchiveDataSetDataInputs).To(Equal([]dataStoreDEPRECATEDTest.ArchiveDataSetDataInput{{Context: ctx, DataSet: dataSet, Selectors: &data.Selectors{{ID: pointer.FromString("new-matched")}}}}))
+					})
+				})
+			})
+		})
+	})
+})
@@ -0,0 +1,86 @@
+package deduplicator
+
+import (
+	"sort"
+	"strconv"
+
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	"github.com/tidepool-org/platform/structure"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
+)
+
+// DeviceRule matches a data set with the device manufacturer and, if device models are specified, one of the device models
+type DeviceRule struct {
+	DeviceManufacturer string   `json:"deviceManufacturer"`
+	DeviceModels       []string `json:"deviceModels,omitempty"`
+}
+
+func (d *DeviceRule) Validate(validator structure.Validator) {
+	validator.String("deviceManufacturer", &d.DeviceManufacturer).NotEmpty()
+	validator.StringArray("deviceModels", &d.DeviceModels).EachNotEmpty().EachUnique()
+}
+
+func (d *DeviceRule) Match(dataSet *dataTypesUpload.Upload) bool {
+	if dataSet == nil || dataSet.DeviceManufacturers == nil {
+		return false
+	}
+
+	for _, deviceManufacturer := range *dataSet.DeviceManufacturers {
+		if deviceManufacturer == d.DeviceManufacturer {
+			if len(d.DeviceModels) == 0 {
+				return true
+			}
+			if dataSet.DeviceModel == nil {
+				return false
+			}
+			for _, deviceModel := range d.DeviceModels {
+				if deviceModel == *dataSet.DeviceModel {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	return false
+}
+
+type DeviceRules []*DeviceRule
+
+func (d DeviceRules) Validate(validator structure.Validator) {
+	for index, deviceRule := range d {
+		if deviceRuleValidator := validator.WithReference(strconv.Itoa(index)); deviceRule != nil {
+			deviceRule.Validate(deviceRuleValidator)
+		} else {
+			deviceRuleValidator.ReportError(structureValidator.ErrorValueNotExists())
+		}
+	}
+}
+
+func (d DeviceRules) Match(dataSet *dataTypesUpload.Upload) bool {
+	for _, deviceRule := range d {
+		if deviceRule.Match(dataSet) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewDeviceRulesFromDeviceManufacturerDeviceModels returns device rules, ordered by device manufacturer, matching the device models of each device manufacturer
+func NewDeviceRulesFromDeviceManufacturerDeviceModels(deviceManufacturerDeviceModels map[string][]string) DeviceRules {
+	deviceRules := DeviceRules{}
+	for deviceManufacturer, deviceModels := range deviceManufacturerDeviceModels {
+		deviceRules = append(deviceRules, &DeviceRule{DeviceManufacturer: deviceManufacturer, DeviceModels: append([]string{}, deviceModels...)})
+	}
+	sort.Slice(deviceRules, func(i int, j int) bool { return deviceRules[i].DeviceManufacturer < deviceRules[j].DeviceManufacturer })
+	return deviceRules
+}
+
+// NewDeviceRulesFromDeviceManufacturers returns device rules matching any device model of each device manufacturer
+func NewDeviceRulesFromDeviceManufacturers(deviceManufacturers []string) DeviceRules {
+	deviceRules := DeviceRules{}
+	for _, deviceManufacturer := range deviceManufacturers {
+		deviceRules = append(deviceRules, &DeviceRule{DeviceManufacturer: deviceManufacturer})
+	}
+	return deviceRules
+}
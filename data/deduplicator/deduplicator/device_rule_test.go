@@ -0,0 +1,145 @@
+package deduplicator_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	dataDeduplicatorDeduplicator "github.com/tidepool-org/platform/data/deduplicator/deduplicator"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	dataTypesUploadTest "github.com/tidepool-org/platform/data/types/upload/test"
+	"github.com/tidepool-org/platform/pointer"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
+)
+
+var _ = Describe("DeviceRule", func() {
+	var dataSet *dataTypesUpload.Upload
+
+	BeforeEach(func() {
+		dataSet = dataTypesUploadTest.RandomUpload()
+		dataSet.DeviceManufacturers = pointer.FromStringArray([]string{"Other", "Abbott"})
+		dataSet.DeviceModel = pointer.FromString("FreeStyle Libre")
+	})
+
+	Context("DeviceRule", func() {
+		Context("Validate", func() {
+			It("reports an error if the device manufacturer is empty", func() {
+				deviceRule := &dataDeduplicatorDeduplicator.DeviceRule{}
+				Expect(structureValidator.New().Validate(deviceRule)).To(MatchError("value is empty"))
+			})
+
+			It("reports an error if a device model is empty", func() {
+				deviceRule := &dataDeduplicatorDeduplicator.DeviceRule{DeviceManufacturer: "Abbott", DeviceModels: []string{""}}
+				Expect(structureValidator.New().Validate(deviceRule)).To(MatchError("value is empty"))
+			})
+
+			It("reports an error if a device model is duplicated", func() {
+				deviceRule := &dataDeduplicatorDeduplicator.DeviceRule{DeviceManufacturer: "Abbott", DeviceModels: []string{"FreeStyle Libre", "FreeStyle Libre"}}
+				Expect(structureValidator.New().Validate(deviceRule)).To(MatchError("value is a duplicate"))
+			})
+
+			It("succeeds without device models", func() {
+				deviceRule := &dataDeduplicatorDeduplicator.DeviceRule{DeviceManufacturer: "Abbott"}
+				Expect(structureValidator.New().Validate(deviceRule)).To(Succeed())
+			})
+
+			It("succeeds with device models", func() {
+				deviceRule := &dataDeduplicatorDeduplicator.DeviceRule{DeviceManufacturer: "Abbott", DeviceModels: []string{"FreeStyle Libre"}}
+				Expect(structureValidator.New().Validate(deviceRule)).To(Succeed())
+			})
+		})
+
+		Context("Match", func() {
+			It("returns false if the data set is missing", func() {
+				deviceRule := &dataDeduplicatorDeduplicator.DeviceRule{DeviceManufacturer: "Abbott"}
+				Expect(deviceRule.Match(nil)).To(BeFalse())
+			})
+
+			It("returns false if the data set device manufacturers is missing", func() {
+				dataSet.DeviceManufacturers = nil
+				deviceRule := &dataDeduplicatorDeduplicator.DeviceRule{DeviceManufacturer: "Abbott"}
+				Expect(deviceRule.Match(dataSet)).To(BeFalse())
+			})
+
+			It("returns false if the device manufacturer does not match", func() {
+				deviceRule := &dataDeduplicatorDeduplicator.DeviceRule{DeviceManufacturer: "LifeScan"}
+				Expect(deviceRule.Match(dataSet)).To(BeFalse())
+			})
+
+			It("returns true if the device manufacturer matches without device models", func() {
+				dataSet.DeviceModel = nil
+				deviceRule := &dataDeduplicatorDeduplicator.DeviceRule{DeviceManufacturer: "Abbott"}
+				Expect(deviceRule.Match(dataSet)).To(BeTrue())
+			})
+
+			It("returns false if the data set device model is missing with device models", func() {
+				dataSet.DeviceModel = nil
+				deviceRule := &dataDeduplicatorDeduplicator.DeviceRule{DeviceManufacturer: "Abbott", DeviceModels: []string{"FreeStyle Libre"}}
+				Expect(deviceRule.Match(dataSet)).To(BeFalse())
+			})
+
+			It("returns false if the device model does not match", func() {
+				deviceRule := &dataDeduplicatorDeduplicator.DeviceRule{DeviceManufacturer: "Abbott", DeviceModels: []string{"FreeStyle Libre 2"}}
+				Expect(deviceRule.Match(dataSet)).To(BeFalse())
+			})
+
+			It("returns true if the device model matches", func() {
+				deviceRule := &dataDeduplicatorDeduplicator.DeviceRule{DeviceManufacturer: "Abbott", DeviceModels: []string{"FreeStyle Libre 2", "FreeStyle Libre"}}
+				Expect(deviceRule.Match(dataSet)).To(BeTrue())
+			})
+		})
+	})
+
+	Context("DeviceRules", func() {
+		Context("Validate", func() {
+			It("reports an error if a device rule is missing", func() {
+				deviceRules := dataDeduplicatorDeduplicator.DeviceRules{nil}
+				Expect(structureValidator.New().Validate(deviceRules)).To(MatchError("value does not exist"))
+			})
+
+			It("reports an error if a device rule is invalid", func() {
+				deviceRules := dataDeduplicatorDeduplicator.DeviceRules{{DeviceManufacturer: "Abbott"}, {}}
+				Expect(structureValidator.New().Validate(deviceRules)).To(MatchError("value is empty"))
+			})
+
+			It("succeeds if empty", func() {
+				Expect(structureValidator.New().Validate(dataDeduplicatorDeduplicator.DeviceRules{})).To(Succeed())
+			})
+		})
+
+		Context("Match", func() {
+			It("returns false if empty", func() {
+				Expect(dataDeduplicatorDeduplicator.DeviceRules{}.Match(dataSet)).To(BeFalse())
+			})
+
+			It("returns false if no device rule matches", func() {
+				deviceRules := dataDeduplicatorDeduplicator.DeviceRules{{DeviceManufacturer: "LifeScan"}, {DeviceManufacturer: "Abbott", DeviceModels: []string{"FreeStyle Libre 2"}}}
+				Expect(deviceRules.Match(dataSet)).To(BeFalse())
+			})
+
+			It("returns true if any device rule matches", func() {
+				deviceRules := dataDeduplicatorDeduplicator.DeviceRules{{DeviceManufacturer: "LifeScan"}, {DeviceManufacturer: "Abbott", DeviceModels: []string{"FreeStyle Libre"}}}
+				Expect(deviceRules.Match(dataSet)).To(BeTrue())
+			})
+		})
+	})
+
+	Context("NewDeviceRulesFromDeviceManufacturerDeviceModels", func() {
+		It("returns device rules ordered by device manufacturer", func() {
+			Expect(dataDeduplicatorDeduplicator.NewDeviceRulesFromDeviceManufacturerDeviceModels(map[string][]string{
+				"LifeScan": {"Verio"},
+				"Abbott":   {"FreeStyle Libre"},
+			})).To(Equal(dataDeduplicatorDeduplicator.DeviceRules{
+				{DeviceManufacturer: "Abbott", DeviceModels: []string{"FreeStyle Libre"}},
+				{DeviceManufacturer: "LifeScan", DeviceModels: []string{"Verio"}},
+			}))
+		})
+	})
+
+	Context("NewDeviceRulesFromDeviceManufacturers", func() {
+		It("returns device rules without device models", func() {
+			Expect(dataDeduplicatorDeduplicator.NewDeviceRulesFromDeviceManufacturers([]string{"Animas"})).To(Equal(dataDeduplicatorDeduplicator.DeviceRules{
+				{DeviceManufacturer: "Animas"},
+			}))
+		})
+	})
+})
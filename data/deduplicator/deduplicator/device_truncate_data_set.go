@@ -6,6 +6,7 @@ import (
 	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
 	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
 	"github.com/tidepool-org/platform/errors"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
 )
 
 const DeviceTruncateDataSetName = "org.tidepool.deduplicator.device.truncate.dataset"
@@ -14,19 +15,40 @@ var DeviceTruncateDataSetDeviceManufacturers = []string{"Animas"}
 
 type DeviceTruncateDataSet struct {
 	*Base
+	deviceRules DeviceRules
 }
 
 func NewDeviceTruncateDataSet() (*DeviceTruncateDataSet, error) {
+	return NewDeviceTruncateDataSetWithDeviceRules(DeviceTruncateDataSetDeviceRules())
+}
+
+func NewDeviceTruncateDataSetWithDeviceRules(deviceRules DeviceRules) (*DeviceTruncateDataSet, error) {
+	if deviceRules == nil {
+		return nil, errors.New("device rules is missing")
+	} else if err := structureValidator.New().Validate(deviceRules); err != nil {
+		return nil, errors.Wrap(err, "device rules is invalid")
+	}
+
 	base, err := NewBase(DeviceTruncateDataSetName, "1.1.0")
 	if err != nil {
 		return nil, err
 	}
 
 	return &DeviceTruncateDataSet{
-		Base: base,
+		Base:        base,
+		deviceRules: deviceRules,
 	}, nil
 }
 
+// DeviceTruncateDataSetDeviceRules returns the default device rules, used when none are configured
+func DeviceTruncateDataSetDeviceRules() DeviceRules {
+	return NewDeviceRulesFromDeviceManufacturers(DeviceTruncateDataSetDeviceManufacturers)
+}
+
+func (t *DeviceTruncateDataSet) DeviceRules() DeviceRules {
+	return t.deviceRules
+}
+
 func (t *DeviceTruncateDataSet) New(dataSet *dataTypesUpload.Upload) (bool, error) {
 	if dataSet == nil {
 		return false, errors.New("data set is missing")
@@ -43,19 +65,7 @@ func (t *DeviceTruncateDataSet) New(dataSet *dataTypesUpload.Upload) (bool, erro
 		return t.Get(dataSet)
 	}
 
-	if dataSet.DeviceManufacturers == nil {
-		return false, nil
-	}
-
-	for _, deviceManufacturer := range *dataSet.DeviceManufacturers {
-		for _, allowedDeviceManufacturer := range DeviceTruncateDataSetDeviceManufacturers {
-			if allowedDeviceManufacturer == deviceManufacturer {
-				return true, nil
-			}
-		}
-	}
-
-	return false, nil
+	return t.deviceRules.Match(dataSet), nil
 }
 
 func (t *DeviceTruncateDataSet) Get(dataSet *dataTypesUpload.Upload) (bool, error) {
@@ -30,6 +30,28 @@ var _ = Describe("DeviceTruncateDataSet", func() {
 		})
 	})
 
+	Context("NewDeviceTruncateDataSetWithDeviceRules", func() {
+		It("returns an error when the device rules is missing", func() {
+			deduplicator, err := dataDeduplicatorDeduplicator.NewDeviceTruncateDataSetWithDeviceRules(nil)
+			Expect(err).To(MatchError("device rules is missing"))
+			Expect(deduplicator).To(BeNil())
+		})
+
+		It("returns an error when the device rules is invalid", func() {
+			deduplicator, err := dataDeduplicatorDeduplicator.NewDeviceTruncateDataSetWithDeviceRules(dataDeduplicatorDeduplicator.DeviceRules{{}})
+			Expect(err).To(MatchError("device rules is invalid; value is empty"))
+			Expect(deduplicator).To(BeNil())
+		})
+
+		It("returns succesfully", func() {
+			deviceRules := dataDeduplicatorDeduplicator.DeviceRules{{DeviceManufacturer: "Animas"}}
+			deduplicator, err := dataDeduplicatorDeduplicator.NewDeviceTruncateDataSetWithDeviceRules(deviceRules)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deduplicator).ToNot(BeNil())
+			Expect(deduplicator.DeviceRules()).To(Equal(deviceRules))
+		})
+	})
+
 	Context("with new deduplicator", func() {
 		var deduplicator *dataDeduplicatorDeduplicator.DeviceTruncateDataSet
 		var dataSet *dataTypesUpload.Upload
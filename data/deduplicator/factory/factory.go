@@ -4,6 +4,7 @@ import (
 	dataDeduplicator "github.com/tidepool-org/platform/data/deduplicator"
 	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
 	"github.com/tidepool-org/platform/errors"
+	"github.com/tidepool-org/platform/request"
 	"github.com/tidepool-org/platform/structure"
 	structureValidator "github.com/tidepool-org/platform/structure/validator"
 )
@@ -48,7 +49,7 @@ func (f *Factory) New(dataSet *dataTypesUpload.Upload) (dataDeduplicator.Dedupli
 		}
 	}
 
-	return nil, errors.New("deduplicator not found")
+	return nil, errorDeduplicatorNotFound()
 }
 
 func (f *Factory) Get(dataSet *dataTypesUpload.Upload) (dataDeduplicator.Deduplicator, error) {
@@ -65,6 +66,20 @@ func (f *Factory) Get(dataSet *dataTypesUpload.Upload) (dataDeduplicator.Dedupli
 	return nil, nil
 }
 
+func (f *Factory) GetByName(name string) (dataDeduplicator.Deduplicator, error) {
+	if name == "" {
+		return nil, errors.New("name is missing")
+	}
+
+	for _, deduplicator := range f.deduplicators {
+		if deduplicator.Name() == name {
+			return deduplicator, nil
+		}
+	}
+
+	return nil, nil
+}
+
 func (f *Factory) get(dataSet *dataTypesUpload.Upload) (dataDeduplicator.Deduplicator, error) {
 	for _, deduplicator := range f.deduplicators {
 		if found, err := deduplicator.Get(dataSet); err != nil {
@@ -74,5 +89,9 @@ func (f *Factory) get(dataSet *dataTypesUpload.Upload) (dataDeduplicator.Dedupli
 		}
 	}
 
-	return nil, errors.New("deduplicator not found")
+	return nil, errorDeduplicatorNotFound()
+}
+
+func errorDeduplicatorNotFound() error {
+	return errors.Prepared(request.ErrorCodeResourceNotFound, "resource not found", "deduplicator not found")
 }
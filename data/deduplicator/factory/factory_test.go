@@ -12,6 +12,7 @@ import (
 	errorsTest "github.com/tidepool-org/platform/errors/test"
 	netTest "github.com/tidepool-org/platform/net/test"
 	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/request"
 )
 
 var _ = Describe("Factory", func() {
@@ -104,6 +105,7 @@ var _ = Describe("Factory", func() {
 					secondDeduplicator.GetOutputs = []dataDeduplicatorFactoryTest.GetOutput{{Found: false, Error: nil}}
 					deduplicator, err := factory.New(dataSet)
 					Expect(err).To(MatchError("deduplicator not found"))
+					Expect(request.IsErrorResourceNotFound(err)).To(BeTrue())
 					Expect(deduplicator).To(BeNil())
 				})
 			})
@@ -134,6 +136,7 @@ var _ = Describe("Factory", func() {
 					secondDeduplicator.NewOutputs = []dataDeduplicatorFactoryTest.NewOutput{{Found: false, Error: nil}}
 					deduplicator, err := factory.New(dataSet)
 					Expect(err).To(MatchError("deduplicator not found"))
+					Expect(request.IsErrorResourceNotFound(err)).To(BeTrue())
 					Expect(deduplicator).To(BeNil())
 				})
 			}
@@ -195,6 +198,7 @@ var _ = Describe("Factory", func() {
 					secondDeduplicator.GetOutputs = []dataDeduplicatorFactoryTest.GetOutput{{Found: false, Error: nil}}
 					deduplicator, err := factory.Get(dataSet)
 					Expect(err).To(MatchError("deduplicator not found"))
+					Expect(request.IsErrorResourceNotFound(err)).To(BeTrue())
 					Expect(deduplicator).To(BeNil())
 				})
 			})
@@ -209,5 +213,31 @@ var _ = Describe("Factory", func() {
 				Expect(factory.Get(dataSet)).To(BeNil())
 			})
 		})
+
+		Context("GetByName", func() {
+			var firstName string
+			var secondName string
+
+			BeforeEach(func() {
+				firstName = netTest.RandomReverseDomain()
+				secondName = netTest.RandomReverseDomain()
+				firstDeduplicator.NameOutput = pointer.FromString(firstName)
+				secondDeduplicator.NameOutput = pointer.FromString(secondName)
+			})
+
+			It("returns an error when the name is missing", func() {
+				deduplicator, err := factory.GetByName("")
+				Expect(err).To(MatchError("name is missing"))
+				Expect(deduplicator).To(BeNil())
+			})
+
+			It("returns successfully when a deduplicator has the name", func() {
+				Expect(factory.GetByName(secondName)).To(Equal(secondDeduplicator))
+			})
+
+			It("returns successfully without a deduplicator when no deduplicator has the name", func() {
+				Expect(factory.GetByName(netTest.RandomReverseDomain())).To(BeNil())
+			})
+		})
 	})
 })
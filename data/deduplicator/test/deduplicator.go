@@ -46,7 +46,39 @@ type DeleteInput struct {
 	doPurge bool
 }
 
+type RestoreInput struct {
+	Context context.Context
+	Session dataStoreDEPRECATED.DataSession
+	DataSet *dataTypesUpload.Upload
+}
+
+type UnarchiveInput struct {
+	Context context.Context
+	Session dataStoreDEPRECATED.DataSession
+	DataSet *dataTypesUpload.Upload
+}
+
+type DuplicatesInput struct {
+	Context     context.Context
+	Session     dataStoreDEPRECATED.DataSession
+	DataSet     *dataTypesUpload.Upload
+	DataSetData data.Data
+}
+
+type DuplicatesOutput struct {
+	Duplicates []bool
+	Error      error
+}
+
 type Deduplicator struct {
+	NameInvocations       int
+	NameStub              func() string
+	NameOutputs           []string
+	NameOutput            *string
+	VersionInvocations    int
+	VersionStub           func() string
+	VersionOutputs        []string
+	VersionOutput         *string
 	OpenInvocations       int
 	OpenInputs            []OpenInput
 	OpenStub              func(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) (*dataTypesUpload.Upload, error)
@@ -72,12 +104,54 @@ type Deduplicator struct {
 	DeleteStub            func(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload, doPurge bool) error
 	DeleteOutputs         []error
 	DeleteOutput          *error
+	RestoreInvocations    int
+	RestoreInputs         []RestoreInput
+	RestoreStub           func(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) error
+	RestoreOutputs        []error
+	RestoreOutput         *error
+	UnarchiveInvocations  int
+	UnarchiveInputs       []UnarchiveInput
+	UnarchiveStub         func(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) error
+	UnarchiveOutputs      []error
+	UnarchiveOutput       *error
 }
 
 func NewDeduplicator() *Deduplicator {
 	return &Deduplicator{}
 }
 
+func (d *Deduplicator) Name() string {
+	d.NameInvocations++
+	if d.NameStub != nil {
+		return d.NameStub()
+	}
+	if len(d.NameOutputs) > 0 {
+		output := d.NameOutputs[0]
+		d.NameOutputs = d.NameOutputs[1:]
+		return output
+	}
+	if d.NameOutput != nil {
+		return *d.NameOutput
+	}
+	panic("Name has no output")
+}
+
+func (d *Deduplicator) Version() string {
+	d.VersionInvocations++
+	if d.VersionStub != nil {
+		return d.VersionStub()
+	}
+	if len(d.VersionOutputs) > 0 {
+		output := d.VersionOutputs[0]
+		d.VersionOutputs = d.VersionOutputs[1:]
+		return output
+	}
+	if d.VersionOutput != nil {
+		return *d.VersionOutput
+	}
+	panic("Version has no output")
+}
+
 func (d *Deduplicator) Open(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) (*dataTypesUpload.Upload, error) {
 	d.OpenInvocations++
 	d.OpenInputs = append(d.OpenInputs, OpenInput{Context: ctx, Session: session, DataSet: dataSet})
@@ -163,7 +237,47 @@ func (d *Deduplicator) Delete(ctx context.Context, session dataStoreDEPRECATED.D
 	panic("Delete has no output")
 }
 
+func (d *Deduplicator) Restore(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) error {
+	d.RestoreInvocations++
+	d.RestoreInputs = append(d.RestoreInputs, RestoreInput{Context: ctx, Session: session, DataSet: dataSet})
+	if d.RestoreStub != nil {
+		return d.RestoreStub(ctx, session, dataSet)
+	}
+	if len(d.RestoreOutputs) > 0 {
+		output := d.RestoreOutputs[0]
+		d.RestoreOutputs = d.RestoreOutputs[1:]
+		return output
+	}
+	if d.RestoreOutput != nil {
+		return *d.RestoreOutput
+	}
+	panic("Restore has no output")
+}
+
+func (d *Deduplicator) Unarchive(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) error {
+	d.UnarchiveInvocations++
+	d.UnarchiveInputs = append(d.UnarchiveInputs, UnarchiveInput{Context: ctx, Session: session, DataSet: dataSet})
+	if d.UnarchiveStub != nil {
+		return d.UnarchiveStub(ctx, session, dataSet)
+	}
+	if len(d.UnarchiveOutputs) > 0 {
+		output := d.UnarchiveOutputs[0]
+		d.UnarchiveOutputs = d.UnarchiveOutputs[1:]
+		return output
+	}
+	if d.UnarchiveOutput != nil {
+		return *d.UnarchiveOutput
+	}
+	panic("Unarchive has no output")
+}
+
 func (d *Deduplicator) AssertOutputsEmpty() {
+	if len(d.NameOutputs) > 0 {
+		panic("NameOutputs is not empty")
+	}
+	if len(d.VersionOutputs) > 0 {
+		panic("VersionOutputs is not empty")
+	}
 	if len(d.OpenOutputs) > 0 {
 		panic("OpenOutputs is not empty")
 	}
@@ -179,4 +293,49 @@ func (d *Deduplicator) AssertOutputsEmpty() {
 	if len(d.DeleteOutputs) > 0 {
 		panic("DeleteOutputs is not empty")
 	}
+	if len(d.RestoreOutputs) > 0 {
+		panic("RestoreOutputs is not empty")
+	}
+	if len(d.UnarchiveOutputs) > 0 {
+		panic("UnarchiveOutputs is not empty")
+	}
+}
+
+type DuplicateReporter struct {
+	*Deduplicator
+	DuplicatesInvocations int
+	DuplicatesInputs      []DuplicatesInput
+	DuplicatesStub        func(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload, dataSetData data.Data) ([]bool, error)
+	DuplicatesOutputs     []DuplicatesOutput
+	DuplicatesOutput      *DuplicatesOutput
+}
+
+func NewDuplicateReporter() *DuplicateReporter {
+	return &DuplicateReporter{
+		Deduplicator: NewDeduplicator(),
+	}
+}
+
+func (d *DuplicateReporter) Duplicates(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload, dataSetData data.Data) ([]bool, error) {
+	d.DuplicatesInvocations++
+	d.DuplicatesInputs = append(d.DuplicatesInputs, DuplicatesInput{Context: ctx, Session: session, DataSet: dataSet, DataSetData: dataSetData})
+	if d.DuplicatesStub != nil {
+		return d.DuplicatesStub(ctx, session, dataSet, dataSetData)
+	}
+	if len(d.DuplicatesOutputs) > 0 {
+		output := d.DuplicatesOutputs[0]
+		d.DuplicatesOutputs = d.DuplicatesOutputs[1:]
+		return output.Duplicates, output.Error
+	}
+	if d.DuplicatesOutput != nil {
+		return d.DuplicatesOutput.Duplicates, d.DuplicatesOutput.Error
+	}
+	panic("Duplicates has no output")
+}
+
+func (d *DuplicateReporter) AssertOutputsEmpty() {
+	d.Deduplicator.AssertOutputsEmpty()
+	if len(d.DuplicatesOutputs) > 0 {
+		panic("DuplicatesOutputs is not empty")
+	}
 }
@@ -15,17 +15,27 @@ type GetOutput struct {
 	Error        error
 }
 
+type GetByNameOutput struct {
+	Deduplicator dataDeduplicator.Deduplicator
+	Error        error
+}
+
 type Factory struct {
-	NewInvocations int
-	NewInputs      []*dataTypesUpload.Upload
-	NewStub        func(dataSet *dataTypesUpload.Upload) (dataDeduplicator.Deduplicator, error)
-	NewOutputs     []NewOutput
-	NewOutput      *NewOutput
-	GetInvocations int
-	GetInputs      []*dataTypesUpload.Upload
-	GetStub        func(dataSet *dataTypesUpload.Upload) (dataDeduplicator.Deduplicator, error)
-	GetOutputs     []GetOutput
-	GetOutput      *GetOutput
+	NewInvocations       int
+	NewInputs            []*dataTypesUpload.Upload
+	NewStub              func(dataSet *dataTypesUpload.Upload) (dataDeduplicator.Deduplicator, error)
+	NewOutputs           []NewOutput
+	NewOutput            *NewOutput
+	GetInvocations       int
+	GetInputs            []*dataTypesUpload.Upload
+	GetStub              func(dataSet *dataTypesUpload.Upload) (dataDeduplicator.Deduplicator, error)
+	GetOutputs           []GetOutput
+	GetOutput            *GetOutput
+	GetByNameInvocations int
+	GetByNameInputs      []string
+	GetByNameStub        func(name string) (dataDeduplicator.Deduplicator, error)
+	GetByNameOutputs     []GetByNameOutput
+	GetByNameOutput      *GetByNameOutput
 }
 
 func NewFactory() *Factory {
@@ -66,6 +76,23 @@ func (f *Factory) Get(dataSet *dataTypesUpload.Upload) (dataDeduplicator.Dedupli
 	panic("Get has no output")
 }
 
+func (f *Factory) GetByName(name string) (dataDeduplicator.Deduplicator, error) {
+	f.GetByNameInvocations++
+	f.GetByNameInputs = append(f.GetByNameInputs, name)
+	if f.GetByNameStub != nil {
+		return f.GetByNameStub(name)
+	}
+	if len(f.GetByNameOutputs) > 0 {
+		output := f.GetByNameOutputs[0]
+		f.GetByNameOutputs = f.GetByNameOutputs[1:]
+		return output.Deduplicator, output.Error
+	}
+	if f.GetByNameOutput != nil {
+		return f.GetByNameOutput.Deduplicator, f.GetByNameOutput.Error
+	}
+	panic("GetByName has no output")
+}
+
 func (f *Factory) AssertOutputsEmpty() {
 	if len(f.NewOutputs) > 0 {
 		panic("NewOutputs is not empty")
@@ -73,4 +100,7 @@ func (f *Factory) AssertOutputsEmpty() {
 	if len(f.GetOutputs) > 0 {
 		panic("GetOutputs is not empty")
 	}
+	if len(f.GetByNameOutputs) > 0 {
+		panic("GetByNameOutputs is not empty")
+	}
 }
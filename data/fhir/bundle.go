@@ -0,0 +1,257 @@
+package fhir
+
+import (
+	"strings"
+	"time"
+
+	"github.com/tidepool-org/platform/data"
+	dataBloodGlucose "github.com/tidepool-org/platform/data/blood/glucose"
+	"github.com/tidepool-org/platform/data/types"
+	dataTypesBasalAutomated "github.com/tidepool-org/platform/data/types/basal/automated"
+	dataTypesBasalScheduled "github.com/tidepool-org/platform/data/types/basal/scheduled"
+	dataTypesBasalSuspend "github.com/tidepool-org/platform/data/types/basal/suspend"
+	dataTypesBasalTemporary "github.com/tidepool-org/platform/data/types/basal/temporary"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	dataTypesBloodGlucoseSelfMonitored "github.com/tidepool-org/platform/data/types/blood/glucose/selfmonitored"
+	dataTypesBolusBiphasic "github.com/tidepool-org/platform/data/types/bolus/biphasic"
+	dataTypesBolusCombination "github.com/tidepool-org/platform/data/types/bolus/combination"
+	dataTypesBolusExtended "github.com/tidepool-org/platform/data/types/bolus/extended"
+	dataTypesBolusNormal "github.com/tidepool-org/platform/data/types/bolus/normal"
+	dataTypesBolusPen "github.com/tidepool-org/platform/data/types/bolus/pen"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	"github.com/tidepool-org/platform/pointer"
+)
+
+const (
+	LOINCGlucoseMassInInterstitialFluid         = "99504-3"
+	LOINCGlucoseMolesInInterstitialFluid        = "105272-9"
+	LOINCGlucoseMassInCapillaryBloodGlucometer  = "41653-7"
+	LOINCGlucoseMolesInCapillaryBloodGlucometer = "14743-9"
+
+	RxNormInsulin = "5856"
+
+	CategoryBasal = "basal"
+	CategoryBolus = "bolus"
+)
+
+var glucoseCodings = map[string]map[string]*Coding{
+	dataTypesBloodGlucoseContinuous.Type: {
+		dataBloodGlucose.MgdL:  {System: SystemLOINC, Code: LOINCGlucoseMassInInterstitialFluid, Display: "Glucose [Mass/volume] in Interstitial fluid"},
+		dataBloodGlucose.MmolL: {System: SystemLOINC, Code: LOINCGlucoseMolesInInterstitialFluid, Display: "Glucose [Moles/volume] in Interstitial fluid"},
+	},
+	dataTypesBloodGlucoseSelfMonitored.Type: {
+		dataBloodGlucose.MgdL:  {System: SystemLOINC, Code: LOINCGlucoseMassInCapillaryBloodGlucometer, Display: "Glucose [Mass/volume] in Capillary blood by Glucometer"},
+		dataBloodGlucose.MmolL: {System: SystemLOINC, Code: LOINCGlucoseMolesInCapillaryBloodGlucometer, Display: "Glucose [Moles/volume] in Capillary blood by Glucometer"},
+	},
+}
+
+// NewBundle returns the collection bundle of the resources mapped from the datums of the user, preceded by the devices
+// mapped from the data sets the resources refer to, in the order of the data sets. The datums not mapped are ignored.
+func NewBundle(userID string, dataSets []*dataTypesUpload.Upload, datums data.Data) *Bundle {
+	bundle := &Bundle{
+		ResourceType: ResourceTypeBundle,
+		Type:         BundleTypeCollection,
+		Entry:        []*BundleEntry{},
+	}
+
+	resources := []interface{}{}
+	deviceReferences := map[string]bool{}
+	for _, datum := range datums {
+		if resource := NewResource(userID, datum); resource != nil {
+			resources = append(resources, resource)
+			if device := resourceDeviceReference(resource); device != nil {
+				deviceReferences[device.Reference] = true
+			}
+		}
+	}
+
+	for _, dataSet := range dataSets {
+		if dataSet == nil || dataSet.UploadID == nil {
+			continue
+		}
+		if reference := newDeviceReference(&dataSet.Base).Reference; deviceReferences[reference] {
+			bundle.Entry = append(bundle.Entry, &BundleEntry{Resource: NewDevice(userID, dataSet)})
+			delete(deviceReferences, reference)
+		}
+	}
+	for _, resource := range resources {
+		bundle.Entry = append(bundle.Entry, &BundleEntry{Resource: resource})
+	}
+	return bundle
+}
+
+// NewResource returns the resource mapped from the datum of the user: an observation for a continuous or
+// self-monitored glucose datum, a medication administration for a bolus or basal datum, otherwise nil
+func NewResource(userID string, datum data.Datum) interface{} {
+	switch typed := datum.(type) {
+	case *dataTypesBloodGlucoseContinuous.Continuous:
+		if observation := newGlucoseObservation(userID, &typed.Base, typed.Value, typed.Units); observation != nil {
+			return observation
+		}
+	case *dataTypesBloodGlucoseSelfMonitored.SelfMonitored:
+		if observation := newGlucoseObservation(userID, &typed.Base, typed.Value, typed.Units); observation != nil {
+			return observation
+		}
+	case *dataTypesBolusNormal.Normal:
+		return newBolusMedicationAdministration(userID, &typed.Base, typed.Normal, typed.NormalExpected, nil, nil, nil)
+	case *dataTypesBolusBiphasic.Biphasic:
+		return newBolusMedicationAdministration(userID, &typed.Base, typed.Normal.Normal, typed.NormalExpected, nil, nil, nil)
+	case *dataTypesBolusPen.Pen:
+		return newBolusMedicationAdministration(userID, &typed.Base, typed.Normal, nil, nil, nil, nil)
+	case *dataTypesBolusExtended.Extended:
+		return newBolusMedicationAdministration(userID, &typed.Base, nil, nil, typed.Extended, typed.ExtendedExpected, typed.Duration)
+	case *dataTypesBolusCombination.Combination:
+		return newBolusMedicationAdministration(userID, &typed.Base, typed.Normal, typed.NormalExpected, typed.Extended, typed.ExtendedExpected, typed.Duration)
+	case *dataTypesBasalScheduled.Scheduled:
+		return newBasalMedicationAdministration(userID, &typed.Base, typed.Rate, typed.Duration)
+	case *dataTypesBasalTemporary.Temporary:
+		return newBasalMedicationAdministration(userID, &typed.Base, typed.Rate, typed.Duration)
+	case *dataTypesBasalAutomated.Automated:
+		return newBasalMedicationAdministration(userID, &typed.Base, typed.Rate, typed.Duration)
+	case *dataTypesBasalSuspend.Suspend:
+		medicationAdministration := newBasalMedicationAdministration(userID, &typed.Base, nil, typed.Duration)
+		medicationAdministration.Status = MedicationAdministrationStatusNotDone
+		return medicationAdministration
+	}
+	return nil
+}
+
+// NewDevice returns the device mapped from the data set of the user, identified by the upload id of the data set
+func NewDevice(userID string, dataSet *dataTypesUpload.Upload) *Device {
+	device := &Device{
+		ResourceType: ResourceTypeDevice,
+		ID:           pointer.CloneString(dataSet.UploadID),
+		ModelNumber:  pointer.CloneString(dataSet.DeviceModel),
+		SerialNumber: pointer.CloneString(dataSet.DeviceSerialNumber),
+		Patient:      newPatientReference(userID),
+	}
+	if dataSet.DeviceManufacturers != nil && len(*dataSet.DeviceManufacturers) > 0 {
+		device.Manufacturer = pointer.FromString(strings.Join(*dataSet.DeviceManufacturers, ", "))
+	}
+	if dataSet.DeviceModel != nil {
+		device.DeviceName = []*DeviceName{{Name: *dataSet.DeviceModel, Type: DeviceNameTypeModelName}}
+	}
+	return device
+}
+
+// newGlucoseObservation returns the observation of the glucose value, coded by the units of the datum, either mg/dL,
+// once converted, or mmol/L, once normalized. The observation is nil if the units are unknown.
+func newGlucoseObservation(userID string, base *types.Base, value *float64, units *string) *Observation {
+	if value == nil || units == nil {
+		return nil
+	}
+	canonicalUnits := *dataBloodGlucose.CanonicalUnits(units)
+	coding, ok := glucoseCodings[base.Type][canonicalUnits]
+	if !ok {
+		return nil
+	}
+	return &Observation{
+		ResourceType: ResourceTypeObservation,
+		ID:           pointer.CloneString(base.ID),
+		Status:       ObservationStatusFinal,
+		Category: []*CodeableConcept{{
+			Coding: []*Coding{{System: SystemObservationCategory, Code: "laboratory", Display: "Laboratory"}},
+		}},
+		Code:              &CodeableConcept{Coding: []*Coding{coding}},
+		Subject:           newPatientReference(userID),
+		EffectiveDateTime: pointer.CloneString(base.Time),
+		ValueQuantity:     &Quantity{Value: *value, Unit: canonicalUnits, System: SystemUCUM, Code: canonicalUnits},
+		Device:            newDeviceReference(base),
+	}
+}
+
+// newBolusMedicationAdministration returns the medication administration of the normal and extended amounts of the
+// bolus, stopped if either is less than expected. The administration of an extended bolus spans its duration.
+func newBolusMedicationAdministration(userID string, base *types.Base, normal *float64, normalExpected *float64, extended *float64, extendedExpected *float64, duration *int) *MedicationAdministration {
+	medicationAdministration := newMedicationAdministration(userID, base, CategoryBolus)
+	if isInterrupted(normal, normalExpected) || isInterrupted(extended, extendedExpected) {
+		medicationAdministration.Status = MedicationAdministrationStatusStopped
+	}
+	if extended != nil {
+		medicationAdministration.EffectiveDateTime = nil
+		medicationAdministration.EffectivePeriod = newPeriod(base, duration)
+	}
+	if normal != nil || extended != nil {
+		medicationAdministration.Dosage = &MedicationAdministrationDosage{
+			Dose: newInsulinQuantity(pointer.ToFloat64(normal) + pointer.ToFloat64(extended)),
+		}
+	}
+	return medicationAdministration
+}
+
+// newBasalMedicationAdministration returns the medication administration of the basal rate over its duration, with
+// the dose delivered if the duration is known
+func newBasalMedicationAdministration(userID string, base *types.Base, rate *float64, duration *int) *MedicationAdministration {
+	medicationAdministration := newMedicationAdministration(userID, base, CategoryBasal)
+	medicationAdministration.EffectiveDateTime = nil
+	medicationAdministration.EffectivePeriod = newPeriod(base, duration)
+	if rate != nil {
+		medicationAdministration.Dosage = &MedicationAdministrationDosage{
+			RateQuantity: &Quantity{Value: *rate, Unit: "U/h", System: SystemUCUM, Code: "[iU]/h"},
+		}
+		if duration != nil {
+			medicationAdministration.Dosage.Dose = newInsulinQuantity(*rate * float64(*duration) / float64(time.Hour/time.Millisecond))
+		}
+	}
+	return medicationAdministration
+}
+
+func newMedicationAdministration(userID string, base *types.Base, category string) *MedicationAdministration {
+	medicationAdministration := &MedicationAdministration{
+		ResourceType: ResourceTypeMedicationAdministration,
+		ID:           pointer.CloneString(base.ID),
+		Status:       MedicationAdministrationStatusCompleted,
+		Category:     &CodeableConcept{Text: pointer.FromString(category)},
+		MedicationCodeableConcept: &CodeableConcept{
+			Coding: []*Coding{{System: SystemRxNorm, Code: RxNormInsulin, Display: "insulin"}},
+		},
+		Subject:           newPatientReference(userID),
+		EffectiveDateTime: pointer.CloneString(base.Time),
+	}
+	if device := newDeviceReference(base); device != nil {
+		medicationAdministration.Device = []*Reference{device}
+	}
+	return medicationAdministration
+}
+
+func newInsulinQuantity(value float64) *Quantity {
+	return &Quantity{Value: value, Unit: "U", System: SystemUCUM, Code: "[iU]"}
+}
+
+// newPeriod returns the period starting at the time of the datum, ending after the duration, in milliseconds, if any
+func newPeriod(base *types.Base, duration *int) *Period {
+	period := &Period{Start: pointer.CloneString(base.Time)}
+	if base.Time != nil && duration != nil {
+		if tm, err := time.Parse(time.RFC3339Nano, *base.Time); err == nil {
+			period.End = pointer.FromString(tm.Add(time.Duration(*duration) * time.Millisecond).Format(time.RFC3339Nano))
+		}
+	}
+	return period
+}
+
+func newPatientReference(userID string) *Reference {
+	return &Reference{Reference: ResourceTypePatient + "/" + userID}
+}
+
+func newDeviceReference(base *types.Base) *Reference {
+	if base.UploadID == nil {
+		return nil
+	}
+	return &Reference{Reference: ResourceTypeDevice + "/" + *base.UploadID}
+}
+
+func isInterrupted(delivered *float64, expected *float64) bool {
+	return delivered != nil && expected != nil && *delivered < *expected
+}
+
+func resourceDeviceReference(resource interface{}) *Reference {
+	switch typed := resource.(type) {
+	case *Observation:
+		return typed.Device
+	case *MedicationAdministration:
+		if len(typed.Device) > 0 {
+			return typed.Device[0]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package fhir_test
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	dataBloodGlucose "github.com/tidepool-org/platform/data/blood/glucose"
+	"github.com/tidepool-org/platform/data/fhir"
+	"github.com/tidepool-org/platform/data/types"
+	dataTypesBasalScheduled "github.com/tidepool-org/platform/data/types/basal/scheduled"
+	dataTypesBasalSuspend "github.com/tidepool-org/platform/data/types/basal/suspend"
+	dataTypesBasalTest "github.com/tidepool-org/platform/data/types/basal/test"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	dataTypesBloodGlucoseSelfMonitored "github.com/tidepool-org/platform/data/types/blood/glucose/selfmonitored"
+	dataTypesBloodGlucoseTest "github.com/tidepool-org/platform/data/types/blood/glucose/test"
+	dataTypesBolusCombinationTest "github.com/tidepool-org/platform/data/types/bolus/combination/test"
+	dataTypesBolusNormalTest "github.com/tidepool-org/platform/data/types/bolus/normal/test"
+	dataTypesFood "github.com/tidepool-org/platform/data/types/food"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	dataTypesUploadTest "github.com/tidepool-org/platform/data/types/upload/test"
+	"github.com/tidepool-org/platform/pointer"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+const userID = "0123456789"
+
+// The fixtures are random, so the fields mapped are set to known values
+
+func pinBase(base *types.Base, id string, tm string, uploadID string) {
+	base.ID = pointer.FromString(id)
+	base.Time = pointer.FromString(tm)
+	base.UploadID = pointer.FromString(uploadID)
+}
+
+func NewUpload(uploadID string, manufacturer string, model string, serialNumber string) *dataTypesUpload.Upload {
+	datum := dataTypesUploadTest.RandomUpload()
+	datum.UploadID = pointer.FromString(uploadID)
+	datum.DeviceManufacturers = pointer.FromStringArray([]string{manufacturer})
+	datum.DeviceModel = pointer.FromString(model)
+	datum.DeviceSerialNumber = pointer.FromString(serialNumber)
+	return datum
+}
+
+func NewContinuous(id string, tm string, uploadID string, value float64, units string) *dataTypesBloodGlucoseContinuous.Continuous {
+	datum := dataTypesBloodGlucoseContinuous.New()
+	datum.Glucose = *dataTypesBloodGlucoseTest.NewGlucose(pointer.FromString(units))
+	datum.Type = dataTypesBloodGlucoseContinuous.Type
+	datum.Value = pointer.FromFloat64(value)
+	pinBase(&datum.Base, id, tm, uploadID)
+	return datum
+}
+
+func NewSelfMonitored(id string, tm string, uploadID string, value float64, units string) *dataTypesBloodGlucoseSelfMonitored.SelfMonitored {
+	datum := dataTypesBloodGlucoseSelfMonitored.New()
+	datum.Glucose = *dataTypesBloodGlucoseTest.NewGlucose(pointer.FromString(units))
+	datum.Type = dataTypesBloodGlucoseSelfMonitored.Type
+	datum.Value = pointer.FromFloat64(value)
+	pinBase(&datum.Base, id, tm, uploadID)
+	return datum
+}
+
+func NewBundleData() data.Data {
+	normal := dataTypesBolusNormalTest.NewNormal()
+	normal.Normal = pointer.FromFloat64(2.5)
+	normal.NormalExpected = pointer.FromFloat64(3)
+	pinBase(&normal.Base, "bolus0001", "2020-03-01T12:00:00Z", "upload0002")
+
+	combination := dataTypesBolusCombinationTest.NewCombination()
+	combination.Normal = pointer.FromFloat64(1)
+	combination.Extended = pointer.FromFloat64(2)
+	combination.ExtendedExpected = pointer.FromFloat64(2)
+	combination.Duration = pointer.FromInt(7200000)
+	pinBase(&combination.Base, "bolus0002", "2020-03-01T18:00:00Z", "upload0002")
+
+	scheduled := dataTypesBasalScheduled.New()
+	scheduled.Basal = *dataTypesBasalTest.NewBasal()
+	scheduled.DeliveryType = dataTypesBasalScheduled.DeliveryType
+	scheduled.Rate = pointer.FromFloat64(0.8)
+	scheduled.Duration = pointer.FromInt(5400000)
+	pinBase(&scheduled.Base, "basal0001", "2020-03-01T00:00:00Z", "upload0002")
+
+	suspend := dataTypesBasalSuspend.New()
+	suspend.Basal = *dataTypesBasalTest.NewBasal()
+	suspend.DeliveryType = dataTypesBasalSuspend.DeliveryType
+	suspend.Duration = pointer.FromInt(1800000)
+	pinBase(&suspend.Base, "basal0002", "2020-03-01T01:30:00Z", "upload0002")
+
+	food := dataTypesFood.New()
+	pinBase(&food.Base, "food0001", "2020-03-01T12:00:00Z", "upload0003")
+
+	return data.Data{
+		NewContinuous("cbg0001", "2020-03-01T08:00:00Z", "upload0001", 5.5, dataBloodGlucose.MmolL),
+		NewSelfMonitored("smbg0001", "2020-03-01T08:05:00Z", "upload0002", 112, dataBloodGlucose.MgdL),
+		normal,
+		combination,
+		scheduled,
+		suspend,
+		food,
+	}
+}
+
+func ExpectGolden(name string, actual interface{}) {
+	actualJSON, err := json.MarshalIndent(actual, "", "  ")
+	Expect(err).ToNot(HaveOccurred())
+	path := filepath.Join("testdata", name)
+	if *update {
+		Expect(ioutil.WriteFile(path, append(actualJSON, '\n'), 0644)).To(Succeed())
+	}
+	expectedJSON, err := ioutil.ReadFile(path)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(actualJSON).To(MatchJSON(expectedJSON))
+}
+
+var _ = Describe("Bundle", func() {
+	var dataSets []*dataTypesUpload.Upload
+
+	BeforeEach(func() {
+		dataSets = []*dataTypesUpload.Upload{
+			NewUpload("upload0001", "Dexcom", "G6", "SM12345678"),
+			NewUpload("upload0002", "Medtronic", "MiniMed 780G", "NG1234567H"),
+			NewUpload("upload0004", "Abbott", "FreeStyle Libre", "MAAA1234"),
+		}
+	})
+
+	It("maps the data sets and the data to a bundle", func() {
+		ExpectGolden("bundle.json", fhir.NewBundle(userID, dataSets, NewBundleData()))
+	})
+
+	It("maps no data to an empty bundle", func() {
+		ExpectGolden("bundle_empty.json", fhir.NewBundle(userID, dataSets, nil))
+	})
+
+	It("maps a glucose value with unknown units to no resource", func() {
+		Expect(fhir.NewResource(userID, NewContinuous("cbg0001", "2020-03-01T08:00:00Z", "upload0001", 5.5, "unknown"))).To(BeNil())
+	})
+
+	It("maps a datum without data set to a resource without device", func() {
+		datum := NewContinuous("cbg0001", "2020-03-01T08:00:00Z", "upload0001", 5.5, dataBloodGlucose.MmolL)
+		datum.UploadID = nil
+		observation, ok := fhir.NewResource(userID, datum).(*fhir.Observation)
+		Expect(ok).To(BeTrue())
+		Expect(observation.Device).To(BeNil())
+	})
+})
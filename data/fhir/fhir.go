@@ -0,0 +1,108 @@
+package fhir
+
+// The subset of the FHIR R4 resources and data types (see https://hl7.org/fhir/R4/) used to export the data
+
+const (
+	ResourceTypeBundle                   = "Bundle"
+	ResourceTypeDevice                   = "Device"
+	ResourceTypeMedicationAdministration = "MedicationAdministration"
+	ResourceTypeObservation              = "Observation"
+	ResourceTypePatient                  = "Patient"
+
+	BundleTypeCollection = "collection"
+
+	DeviceNameTypeModelName = "model-name"
+
+	MedicationAdministrationStatusCompleted = "completed"
+	MedicationAdministrationStatusNotDone   = "not-done"
+	MedicationAdministrationStatusStopped   = "stopped"
+
+	ObservationStatusFinal = "final"
+
+	SystemLOINC               = "http://loinc.org"
+	SystemObservationCategory = "http://terminology.hl7.org/CodeSystem/observation-category"
+	SystemRxNorm              = "http://www.nlm.nih.gov/research/umls/rxnorm"
+	SystemUCUM                = "http://unitsofmeasure.org"
+)
+
+type Bundle struct {
+	ResourceType string         `json:"resourceType"`
+	Type         string         `json:"type"`
+	Entry        []*BundleEntry `json:"entry"`
+}
+
+type BundleEntry struct {
+	Resource interface{} `json:"resource"`
+}
+
+type Observation struct {
+	ResourceType      string             `json:"resourceType"`
+	ID                *string            `json:"id,omitempty"`
+	Status            string             `json:"status"`
+	Category          []*CodeableConcept `json:"category,omitempty"`
+	Code              *CodeableConcept   `json:"code"`
+	Subject           *Reference         `json:"subject,omitempty"`
+	EffectiveDateTime *string            `json:"effectiveDateTime,omitempty"`
+	ValueQuantity     *Quantity          `json:"valueQuantity,omitempty"`
+	Device            *Reference         `json:"device,omitempty"`
+}
+
+type MedicationAdministration struct {
+	ResourceType              string                          `json:"resourceType"`
+	ID                        *string                         `json:"id,omitempty"`
+	Status                    string                          `json:"status"`
+	Category                  *CodeableConcept                `json:"category,omitempty"`
+	MedicationCodeableConcept *CodeableConcept                `json:"medicationCodeableConcept"`
+	Subject                   *Reference                      `json:"subject"`
+	EffectiveDateTime         *string                         `json:"effectiveDateTime,omitempty"`
+	EffectivePeriod           *Period                         `json:"effectivePeriod,omitempty"`
+	Device                    []*Reference                    `json:"device,omitempty"`
+	Dosage                    *MedicationAdministrationDosage `json:"dosage,omitempty"`
+}
+
+type MedicationAdministrationDosage struct {
+	Dose         *Quantity `json:"dose,omitempty"`
+	RateQuantity *Quantity `json:"rateQuantity,omitempty"`
+}
+
+type Device struct {
+	ResourceType string        `json:"resourceType"`
+	ID           *string       `json:"id,omitempty"`
+	Manufacturer *string       `json:"manufacturer,omitempty"`
+	DeviceName   []*DeviceName `json:"deviceName,omitempty"`
+	ModelNumber  *string       `json:"modelNumber,omitempty"`
+	SerialNumber *string       `json:"serialNumber,omitempty"`
+	Patient      *Reference    `json:"patient,omitempty"`
+}
+
+type DeviceName struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type CodeableConcept struct {
+	Coding []*Coding `json:"coding,omitempty"`
+	Text   *string   `json:"text,omitempty"`
+}
+
+type Coding struct {
+	System  string `json:"system"`
+	Code    string `json:"code"`
+	Display string `json:"display,omitempty"`
+}
+
+type Quantity struct {
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit"`
+	System string  `json:"system"`
+	Code   string  `json:"code"`
+}
+
+type Reference struct {
+	Reference string `json:"reference"`
+}
+
+type Period struct {
+	Start *string `json:"start,omitempty"`
+	End   *string `json:"end,omitempty"`
+}
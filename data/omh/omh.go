@@ -0,0 +1,261 @@
+package omh
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/tidepool-org/platform/data"
+	dataNormalizer "github.com/tidepool-org/platform/data/normalizer"
+	dataTypesFactory "github.com/tidepool-org/platform/data/types/factory"
+	"github.com/tidepool-org/platform/errors"
+	"github.com/tidepool-org/platform/origin"
+	"github.com/tidepool-org/platform/structure"
+	structureBase "github.com/tidepool-org/platform/structure/base"
+	structureParser "github.com/tidepool-org/platform/structure/parser"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
+)
+
+// Open mHealth datapoints (see https://www.openmhealth.org/documentation/#/schema-docs/schema-library), including
+// the IEEE 1752.1 schemas, are translated to raw datums, then parsed, validated and normalized as any other datum.
+// The errors are reported with the pointers of the source datapoint values.
+
+const (
+	ModalitySelfReported = "self-reported"
+	ModalitySensed       = "sensed"
+
+	NamespaceIEEE = "ieee"
+	NamespaceOMH  = "omh"
+)
+
+func Namespaces() []string {
+	return []string{
+		NamespaceIEEE,
+		NamespaceOMH,
+	}
+}
+
+func Modalities() []string {
+	return []string{
+		ModalitySelfReported,
+		ModalitySensed,
+	}
+}
+
+type SchemaID struct {
+	Namespace *string `json:"namespace,omitempty"`
+	Name      *string `json:"name,omitempty"`
+	Version   *string `json:"version,omitempty"`
+}
+
+func ParseSchemaID(parser structure.ObjectParser) *SchemaID {
+	if !parser.Exists() {
+		return nil
+	}
+	datum := &SchemaID{}
+	parser.Parse(datum)
+	return datum
+}
+
+func (s *SchemaID) Parse(parser structure.ObjectParser) {
+	s.Namespace = parser.String("namespace")
+	s.Name = parser.String("name")
+	s.Version = parser.String("version")
+}
+
+func (s *SchemaID) Validate(validator structure.Validator) {
+	validator.String("namespace", s.Namespace).Exists().OneOf(Namespaces()...)
+	validator.String("name", s.Name).Exists().OneOf(SchemaNames()...)
+	validator.String("version", s.Version).Exists().NotEmpty()
+}
+
+type AcquisitionProvenance struct {
+	SourceName *string `json:"source_name,omitempty"`
+	Modality   *string `json:"modality,omitempty"`
+}
+
+func ParseAcquisitionProvenance(parser structure.ObjectParser) *AcquisitionProvenance {
+	if !parser.Exists() {
+		return nil
+	}
+	datum := &AcquisitionProvenance{}
+	parser.Parse(datum)
+	return datum
+}
+
+func (a *AcquisitionProvenance) Parse(parser structure.ObjectParser) {
+	a.SourceName = parser.String("source_name")
+	a.Modality = parser.String("modality")
+}
+
+func (a *AcquisitionProvenance) Validate(validator structure.Validator) {
+	validator.String("source_name", a.SourceName).NotEmpty()
+	validator.String("modality", a.Modality).OneOf(Modalities()...)
+}
+
+type Header struct {
+	ID                    *string                `json:"id,omitempty"`
+	CreationDateTime      *string                `json:"creation_date_time,omitempty"`
+	SchemaID              *SchemaID              `json:"schema_id,omitempty"`
+	AcquisitionProvenance *AcquisitionProvenance `json:"acquisition_provenance,omitempty"`
+}
+
+func ParseHeader(parser structure.ObjectParser) *Header {
+	if !parser.Exists() {
+		return nil
+	}
+	datum := &Header{}
+	parser.Parse(datum)
+	return datum
+}
+
+func (h *Header) Parse(parser structure.ObjectParser) {
+	h.ID = parser.String("id")
+	h.CreationDateTime = parser.String("creation_date_time")
+	h.SchemaID = ParseSchemaID(parser.WithReferenceObjectParser("schema_id"))
+	h.AcquisitionProvenance = ParseAcquisitionProvenance(parser.WithReferenceObjectParser("acquisition_provenance"))
+}
+
+func (h *Header) Validate(validator structure.Validator) {
+	validator = validator.WithReference("header")
+	validator.String("id", h.ID).Exists().NotEmpty()
+	if h.SchemaID != nil {
+		h.SchemaID.Validate(validator.WithReference("schema_id"))
+	} else {
+		validator.WithReference("schema_id").ReportError(structureValidator.ErrorValueNotExists())
+	}
+	if h.AcquisitionProvenance != nil {
+		h.AcquisitionProvenance.Validate(validator.WithReference("acquisition_provenance"))
+	}
+}
+
+// Origin returns the origin of the datum translated from the datapoint: the datapoint id and creation time, the
+// source name, and the type, manual if self-reported, otherwise service, as the datapoint is sent by an application
+func (h *Header) Origin() map[string]interface{} {
+	object := map[string]interface{}{
+		"id":   *h.ID,
+		"type": origin.TypeService,
+	}
+	if h.CreationDateTime != nil {
+		object["time"] = *h.CreationDateTime
+	}
+	if h.AcquisitionProvenance != nil {
+		if h.AcquisitionProvenance.SourceName != nil {
+			object["name"] = *h.AcquisitionProvenance.SourceName
+		}
+		if h.AcquisitionProvenance.Modality != nil && *h.AcquisitionProvenance.Modality == ModalitySelfReported {
+			object["type"] = origin.TypeManual
+		}
+	}
+	return object
+}
+
+// IsSelfReported returns true if the datapoint was reported by the user, rather than sensed by a device
+func (h *Header) IsSelfReported() bool {
+	return h.AcquisitionProvenance != nil && h.AcquisitionProvenance.Modality != nil && *h.AcquisitionProvenance.Modality == ModalitySelfReported
+}
+
+// ParseDatapoints translates the datapoints to datums, then parses, validates and normalizes them. The datums are
+// returned, including those added by the normalizer, only if there is no error. The pointers of the errors are those
+// of the datapoints.
+func ParseDatapoints(rawDatapoints []interface{}) (data.Data, error) {
+	var errs []error
+	datums := data.Data{}
+	for index, rawDatapoint := range rawDatapoints {
+		datapointDatums, err := parseDatapoint(rawDatapoint, structure.NewPointerSource().WithReference(strconv.Itoa(index)))
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			datums = append(datums, datapointDatums...)
+		}
+	}
+	if err := errors.Append(errs...); err != nil {
+		return nil, err
+	}
+	return datums, nil
+}
+
+func parseDatapoint(rawDatapoint interface{}, datapointSource structure.Source) (data.Data, error) {
+	datapoint, ok := rawDatapoint.(map[string]interface{})
+	if !ok {
+		return nil, errors.WithSource(structureParser.ErrorTypeNotObject(rawDatapoint), datapointSource)
+	}
+
+	datapointParser := structureParser.NewObjectParser(structureBase.New().WithSource(datapointSource), &datapoint)
+	header := ParseHeader(datapointParser.WithReferenceObjectParser("header"))
+	if header == nil {
+		datapointParser.WithReferenceErrorReporter("header").ReportError(structureValidator.ErrorValueNotExists())
+	}
+	body := datapointParser.Object("body")
+	if body == nil {
+		datapointParser.WithReferenceErrorReporter("body").ReportError(structureValidator.ErrorValueNotExists())
+	}
+	if err := datapointParser.Error(); err != nil {
+		return nil, err
+	}
+
+	headerValidator := structureValidator.NewValidator(structureBase.New().WithSource(datapointSource))
+	if err := headerValidator.Validate(header); err != nil {
+		return nil, err
+	}
+
+	translation := NewTranslation()
+	translation.Set("origin", header.Origin(), "/header")
+	translation.Pointers["/origin/id"] = "/header/id"
+	translation.Pointers["/origin/time"] = "/header/creation_date_time"
+	translation.Pointers["/origin/name"] = "/header/acquisition_provenance/source_name"
+	translation.Pointers["/origin/type"] = "/header/acquisition_provenance/modality"
+	translation.Pointers["/type"] = "/header/schema_id/name"
+	translators[*header.SchemaID.Name](translation, header, *body)
+
+	source := &translationSource{datapointPointer: datapointSource.Pointer(), pointers: translation.Pointers}
+	parser := structureParser.NewObjectParser(structureBase.New().WithSource(source), &translation.Object)
+	datum := dataTypesFactory.ParseDatum(parser)
+	parser.NotParsed()
+	if err := parser.Error(); err != nil {
+		return nil, err
+	} else if datum == nil || *datum == nil {
+		return nil, errors.WithSource(structureValidator.ErrorValueNotExists(), source.WithReference("type"))
+	}
+
+	validator := structureValidator.NewValidator(structureBase.New().WithSource(source))
+	if err := validator.Validate(*datum); err != nil {
+		return nil, err
+	}
+
+	normalizer := dataNormalizer.New()
+	(*datum).Normalize(normalizer.WithSource(source))
+	if err := normalizer.Error(); err != nil {
+		return nil, err
+	}
+
+	return append(data.Data{*datum}, normalizer.Data()...), nil
+}
+
+// translationSource is the source of the translated datum, whose pointers are those of the datapoint values the
+// datum values were translated from, or of the datapoint itself if unknown
+type translationSource struct {
+	datapointPointer string
+	pointer          string
+	pointers         map[string]string
+}
+
+func (t *translationSource) Parameter() string {
+	return ""
+}
+
+func (t *translationSource) Pointer() string {
+	for pointer := t.pointer; pointer != ""; pointer = pointer[:strings.LastIndex(pointer, "/")] {
+		if datapointPointer, ok := t.pointers[pointer]; ok {
+			return t.datapointPointer + datapointPointer
+		}
+	}
+	return t.datapointPointer
+}
+
+func (t *translationSource) WithReference(reference string) structure.Source {
+	return &translationSource{
+		datapointPointer: t.datapointPointer,
+		pointer:          t.pointer + "/" + structure.EncodePointerReference(reference),
+		pointers:         t.pointers,
+	}
+}
@@ -0,0 +1,157 @@
+package omh_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data/omh"
+	"github.com/tidepool-org/platform/data/types"
+	dataTypesActivityPhysical "github.com/tidepool-org/platform/data/types/activity/physical"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	dataTypesBloodGlucoseSelfMonitored "github.com/tidepool-org/platform/data/types/blood/glucose/selfmonitored"
+	dataTypesFood "github.com/tidepool-org/platform/data/types/food"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/origin"
+	structureParser "github.com/tidepool-org/platform/structure/parser"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
+)
+
+func NewDatapoint(name string, version string, modality string, body map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"header": map[string]interface{}{
+			"id":                 "a5ad97c7-8e2f-4a4e-8f3d-0c1b3d5e7a90",
+			"creation_date_time": "2020-03-01T09:00:00-08:00",
+			"schema_id":          map[string]interface{}{"namespace": "omh", "name": name, "version": version},
+			"acquisition_provenance": map[string]interface{}{
+				"source_name": "Wellness App",
+				"modality":    modality,
+			},
+		},
+		"body": body,
+	}
+}
+
+func NewBloodGlucoseBody(value interface{}, unit interface{}, specimenSource string) map[string]interface{} {
+	body := map[string]interface{}{
+		"blood_glucose":        map[string]interface{}{"value": value, "unit": unit},
+		"effective_time_frame": map[string]interface{}{"date_time": "2020-03-01T08:00:00-08:00"},
+	}
+	if specimenSource != "" {
+		body["specimen_source"] = specimenSource
+	}
+	return body
+}
+
+var _ = Describe("OMH", func() {
+	Context("ParseDatapoints", func() {
+		It("translates a blood glucose measured in the interstitial fluid to a continuous glucose datum", func() {
+			datums, err := omh.ParseDatapoints([]interface{}{NewDatapoint("blood-glucose", "3.0", "sensed", NewBloodGlucoseBody(180.0, "mg/dL", "interstitial fluid"))})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(datums).To(HaveLen(1))
+			datum, ok := datums[0].(*dataTypesBloodGlucoseContinuous.Continuous)
+			Expect(ok).To(BeTrue())
+			Expect(*datum.Time).To(Equal("2020-03-01T16:00:00Z"))
+			Expect(*datum.TimeZoneOffset).To(Equal(-480))
+			Expect(*datum.Units).To(Equal("mmol/L"))
+			Expect(*datum.Value).To(BeNumerically("~", 9.99, 0.01))
+			Expect(*datum.Origin.ID).To(Equal("a5ad97c7-8e2f-4a4e-8f3d-0c1b3d5e7a90"))
+			Expect(*datum.Origin.Name).To(Equal("Wellness App"))
+			Expect(*datum.Origin.Time).To(Equal("2020-03-01T09:00:00-08:00"))
+			Expect(*datum.Origin.Type).To(Equal(origin.TypeService))
+		})
+
+		It("translates a self-reported blood glucose to a manual self-monitored glucose datum", func() {
+			datums, err := omh.ParseDatapoints([]interface{}{NewDatapoint("blood-glucose", "1.0", "self-reported", NewBloodGlucoseBody(6.2, "mmol/L", "capillary blood"))})
+			Expect(err).ToNot(HaveOccurred())
+			datum, ok := datums[0].(*dataTypesBloodGlucoseSelfMonitored.SelfMonitored)
+			Expect(ok).To(BeTrue())
+			Expect(*datum.SubType).To(Equal(dataTypesBloodGlucoseSelfMonitored.SubTypeManual))
+			Expect(*datum.Value).To(Equal(6.2))
+			Expect(*datum.Origin.Type).To(Equal(origin.TypeManual))
+		})
+
+		It("translates a physical activity to a physical activity datum", func() {
+			datums, err := omh.ParseDatapoints([]interface{}{NewDatapoint("physical-activity", "1.2", "sensed", map[string]interface{}{
+				"activity_name": "walking",
+				"effective_time_frame": map[string]interface{}{
+					"time_interval": map[string]interface{}{"start_date_time": "2020-03-01T07:00:00Z", "end_date_time": "2020-03-01T07:45:00Z"},
+				},
+				"distance":                    map[string]interface{}{"value": 3.5, "unit": "km"},
+				"kcal_burned":                 map[string]interface{}{"value": 160.0, "unit": "kcal"},
+				"reported_activity_intensity": "moderate",
+			})})
+			Expect(err).ToNot(HaveOccurred())
+			datum, ok := datums[0].(*dataTypesActivityPhysical.Physical)
+			Expect(ok).To(BeTrue())
+			Expect(*datum.Name).To(Equal("walking"))
+			Expect(*datum.Time).To(Equal("2020-03-01T07:00:00Z"))
+			Expect(*datum.Duration.Value).To(Equal(2700.0))
+			Expect(*datum.Duration.Units).To(Equal("seconds"))
+			Expect(*datum.Distance.Units).To(Equal("kilometers"))
+			Expect(*datum.Energy.Value).To(Equal(160.0))
+			Expect(*datum.Energy.Units).To(Equal("kilocalories"))
+			Expect(*datum.ReportedIntensity).To(Equal("medium"))
+		})
+
+		It("translates a step count, as a unit value, to a physical activity datum", func() {
+			datums, err := omh.ParseDatapoints([]interface{}{NewDatapoint("step-count", "2.0", "sensed", map[string]interface{}{
+				"step_count": map[string]interface{}{"value": 7939.0, "unit": "steps"},
+				"effective_time_frame": map[string]interface{}{
+					"time_interval": map[string]interface{}{"start_date_time": "2020-03-01T00:00:00Z", "duration": map[string]interface{}{"value": 1.0, "unit": "h"}},
+				},
+			})})
+			Expect(err).ToNot(HaveOccurred())
+			datum, ok := datums[0].(*dataTypesActivityPhysical.Physical)
+			Expect(ok).To(BeTrue())
+			Expect(*datum.Step.Count).To(Equal(7939))
+			Expect(*datum.Duration.Value).To(Equal(1.0))
+			Expect(*datum.Duration.Units).To(Equal("hours"))
+		})
+
+		It("translates a total carbohydrate consumed to a food datum with the net carbohydrate", func() {
+			datums, err := omh.ParseDatapoints([]interface{}{NewDatapoint("total-carbohydrate-consumed", "1.0", "self-reported", map[string]interface{}{
+				"total_carbohydrate_consumed": map[string]interface{}{"value": 45.0, "unit": "g"},
+				"effective_time_frame":        map[string]interface{}{"date_time": "2020-03-01T12:30:00+01:00"},
+			})})
+			Expect(err).ToNot(HaveOccurred())
+			datum, ok := datums[0].(*dataTypesFood.Food)
+			Expect(ok).To(BeTrue())
+			Expect(*datum.Time).To(Equal("2020-03-01T11:30:00Z"))
+			Expect(*datum.TimeZoneOffset).To(Equal(60))
+			Expect(*datum.Nutrition.Carbohydrate.Net).To(Equal(45.0))
+			Expect(*datum.Nutrition.Carbohydrate.Units).To(Equal("grams"))
+			Expect(*datum.Origin.Type).To(Equal(origin.TypeManual))
+		})
+
+		It("reports the errors with the pointers of the datapoints", func() {
+			stepCount := NewDatapoint("step-count", "2.0", "sensed", map[string]interface{}{
+				"step_count": 100.0,
+				"effective_time_frame": map[string]interface{}{
+					"time_interval": map[string]interface{}{"start_date_time": "2020-03-01T00:00:00Z", "duration": map[string]interface{}{"value": 2.0, "unit": "wk"}},
+				},
+			})
+			withoutTime := NewBloodGlucoseBody(6.2, "mmol/L", "")
+			delete(withoutTime, "effective_time_frame")
+			datums, err := omh.ParseDatapoints([]interface{}{
+				NewDatapoint("blood-glucose", "3.0", "sensed", NewBloodGlucoseBody(6.2, "mg/kg", "")),
+				NewDatapoint("body-weight", "1.0", "sensed", map[string]interface{}{}),
+				stepCount,
+				NewDatapoint("blood-glucose", "3.0", "sensed", withoutTime),
+				"datapoint",
+				NewDatapoint("total-carbohydrate-consumed", "1.0", "self-reported", map[string]interface{}{
+					"total_carbohydrate_consumed": map[string]interface{}{"value": 45.0, "unit": "oz"},
+					"effective_time_frame":        map[string]interface{}{"date_time": "2020-03-01T12:30:00Z"},
+				}),
+			})
+			Expect(datums).To(BeNil())
+			errorsTest.ExpectEqual(err,
+				errorsTest.WithPointerSourceAndMeta(structureValidator.ErrorValueStringNotOneOf("mg/kg", []string{"mmol/L", "mmol/l", "mg/dL", "mg/dl"}), "/0/body/blood_glucose/unit", &types.Meta{Type: "smbg"}),
+				errorsTest.WithPointerSource(structureValidator.ErrorValueStringNotOneOf("body-weight", omh.SchemaNames()), "/1/header/schema_id/name"),
+				errorsTest.WithPointerSourceAndMeta(structureValidator.ErrorValueStringNotOneOf("wk", []string{"hours", "minutes", "seconds"}), "/2/body/effective_time_frame/time_interval/duration/unit", &types.Meta{Type: "physicalActivity"}),
+				errorsTest.WithPointerSourceAndMeta(structureValidator.ErrorValueNotExists(), "/3/body/effective_time_frame", &types.Meta{Type: "smbg"}),
+				errorsTest.WithPointerSource(structureParser.ErrorTypeNotObject("datapoint"), "/4"),
+				errorsTest.WithPointerSourceAndMeta(structureValidator.ErrorValueStringNotOneOf("oz", []string{"grams"}), "/5/body/total_carbohydrate_consumed/unit", &types.Meta{Type: "food"}),
+			)
+		})
+	})
+})
@@ -0,0 +1,251 @@
+package omh
+
+import (
+	"sort"
+	"time"
+
+	dataTypesActivityPhysical "github.com/tidepool-org/platform/data/types/activity/physical"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	dataTypesBloodGlucoseSelfMonitored "github.com/tidepool-org/platform/data/types/blood/glucose/selfmonitored"
+	dataTypesCommon "github.com/tidepool-org/platform/data/types/common"
+	dataTypesFood "github.com/tidepool-org/platform/data/types/food"
+)
+
+const (
+	SchemaNameBloodGlucose     = "blood-glucose"
+	SchemaNameCaloriesBurned   = "calories-burned"
+	SchemaNamePhysicalActivity = "physical-activity"
+	SchemaNameStepCount        = "step-count"
+
+	SchemaNameTotalCarbohydrateConsumed = "total-carbohydrate-consumed"
+
+	SpecimenSourceInterstitialFluid = "interstitial fluid"
+)
+
+// Translator translates the body of a datapoint to the translation of the datum
+type Translator func(translation *Translation, header *Header, body map[string]interface{})
+
+var translators = map[string]Translator{
+	SchemaNameBloodGlucose:     translateBloodGlucose,
+	SchemaNameCaloriesBurned:   translateCaloriesBurned,
+	SchemaNamePhysicalActivity: translatePhysicalActivity,
+	SchemaNameStepCount:        translateStepCount,
+
+	SchemaNameTotalCarbohydrateConsumed: translateTotalCarbohydrateConsumed,
+}
+
+// SchemaNames returns the names of the schemas translated, whatever the namespace and version
+func SchemaNames() []string {
+	names := []string{}
+	for name := range translators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var carbohydrateUnits = map[string]string{
+	"g": dataTypesFood.CarbohydrateUnitsGrams,
+}
+
+var distanceUnits = map[string]string{
+	"ft": dataTypesActivityPhysical.DistanceUnitsFeet,
+	"km": dataTypesActivityPhysical.DistanceUnitsKilometers,
+	"m":  dataTypesActivityPhysical.DistanceUnitsMeters,
+	"mi": dataTypesActivityPhysical.DistanceUnitsMiles,
+	"yd": dataTypesActivityPhysical.DistanceUnitsYards,
+}
+
+var durationUnits = map[string]string{
+	"h":   dataTypesCommon.DurationUnitsHours,
+	"min": dataTypesCommon.DurationUnitsMinutes,
+	"sec": dataTypesCommon.DurationUnitsSeconds,
+}
+
+var energyUnits = map[string]string{
+	"kcal": dataTypesActivityPhysical.EnergyUnitsKilocalories,
+}
+
+var reportedIntensities = map[string]string{
+	"light":    dataTypesActivityPhysical.ReportedIntensityLow,
+	"moderate": dataTypesActivityPhysical.ReportedIntensityMedium,
+	"vigorous": dataTypesActivityPhysical.ReportedIntensityHigh,
+}
+
+// Translation is the raw datum translated from a datapoint, with the pointers of the datum values, relative to the
+// datum, to the datapoint values they are translated from, relative to the datapoint
+type Translation struct {
+	Object   map[string]interface{}
+	Pointers map[string]string
+}
+
+func NewTranslation() *Translation {
+	return &Translation{
+		Object:   map[string]interface{}{},
+		Pointers: map[string]string{},
+	}
+}
+
+// Set sets the datum value at the reference to the value, if any, translated from the datapoint value at the pointer
+func (t *Translation) Set(reference string, value interface{}, pointer string) {
+	if value != nil {
+		t.Object[reference] = value
+	}
+	t.Pointers["/"+reference] = pointer
+}
+
+// SetUnitValue sets the datum value and units object at the reference from the datapoint unit value, if any, at the
+// pointer, with the units translated. Units not translated are kept as is, so they are reported as invalid.
+func (t *Translation) SetUnitValue(reference string, unitValue interface{}, pointer string, units map[string]string) {
+	t.Pointers["/"+reference+"/value"] = pointer + "/value"
+	t.Pointers["/"+reference+"/units"] = pointer + "/unit"
+	unitValueObject, ok := unitValue.(map[string]interface{})
+	if !ok {
+		t.Set(reference, unitValue, pointer)
+		return
+	}
+	object := map[string]interface{}{}
+	if value, exists := unitValueObject["value"]; exists {
+		object["value"] = value
+	}
+	if unit, exists := unitValueObject["unit"]; exists {
+		object["units"] = translateString(unit, units)
+	}
+	t.Set(reference, object, pointer)
+}
+
+// SetEffectiveTimeFrame sets the time, in UTC, and the time zone offset from the date time of the effective time
+// frame, or from the start date time of its time interval. With a duration, the duration is set from that of the
+// time interval, or from the difference between its end and start date times.
+func (t *Translation) SetEffectiveTimeFrame(body map[string]interface{}, withDuration bool) {
+	t.Pointers["/time"] = "/body/effective_time_frame"
+	effectiveTimeFrame, ok := body["effective_time_frame"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	if dateTime, exists := effectiveTimeFrame["date_time"]; exists {
+		t.setDateTime(dateTime, "/body/effective_time_frame/date_time")
+		return
+	}
+	timeInterval, ok := effectiveTimeFrame["time_interval"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	t.setDateTime(timeInterval["start_date_time"], "/body/effective_time_frame/time_interval/start_date_time")
+	if !withDuration {
+		return
+	}
+	if duration, exists := timeInterval["duration"]; exists {
+		t.SetUnitValue("duration", duration, "/body/effective_time_frame/time_interval/duration", durationUnits)
+	} else if startTime, endTime, ok := parseTimeInterval(timeInterval); ok {
+		duration := map[string]interface{}{"value": endTime.Sub(startTime).Seconds(), "units": dataTypesCommon.DurationUnitsSeconds}
+		t.Set("duration", duration, "/body/effective_time_frame/time_interval/end_date_time")
+	}
+}
+
+func (t *Translation) setDateTime(dateTime interface{}, pointer string) {
+	if dateTimeString, ok := dateTime.(string); ok {
+		if tm, err := time.Parse(time.RFC3339Nano, dateTimeString); err == nil {
+			_, offset := tm.Zone()
+			dateTime = tm.UTC().Format(time.RFC3339Nano)
+			t.Set("timezoneOffset", offset/60, pointer)
+		}
+	}
+	t.Set("time", dateTime, pointer)
+}
+
+func parseTimeInterval(timeInterval map[string]interface{}) (time.Time, time.Time, bool) {
+	startDateTime, startOK := timeInterval["start_date_time"].(string)
+	endDateTime, endOK := timeInterval["end_date_time"].(string)
+	if !startOK || !endOK {
+		return time.Time{}, time.Time{}, false
+	}
+	startTime, startErr := time.Parse(time.RFC3339Nano, startDateTime)
+	endTime, endErr := time.Parse(time.RFC3339Nano, endDateTime)
+	return startTime, endTime, startErr == nil && endErr == nil
+}
+
+func translateString(value interface{}, translations map[string]string) interface{} {
+	if valueString, ok := value.(string); ok {
+		if translation, ok := translations[valueString]; ok {
+			return translation
+		}
+	}
+	return value
+}
+
+// translateBloodGlucose translates a blood glucose datapoint to a continuous glucose datum if measured in the
+// interstitial fluid, otherwise to a self-monitored glucose datum, manual if self-reported
+func translateBloodGlucose(translation *Translation, header *Header, body map[string]interface{}) {
+	if body["specimen_source"] == SpecimenSourceInterstitialFluid {
+		translation.Set("type", dataTypesBloodGlucoseContinuous.Type, "/body/specimen_source")
+	} else {
+		translation.Set("type", dataTypesBloodGlucoseSelfMonitored.Type, "/header/schema_id/name")
+		if header.IsSelfReported() {
+			translation.Set("subType", dataTypesBloodGlucoseSelfMonitored.SubTypeManual, "/header/acquisition_provenance/modality")
+		}
+	}
+	translation.Pointers["/value"] = "/body/blood_glucose/value"
+	translation.Pointers["/units"] = "/body/blood_glucose/unit"
+	if bloodGlucose, ok := body["blood_glucose"].(map[string]interface{}); ok {
+		translation.Set("value", bloodGlucose["value"], "/body/blood_glucose/value")
+		translation.Set("units", bloodGlucose["unit"], "/body/blood_glucose/unit")
+	}
+	translation.SetEffectiveTimeFrame(body, false)
+}
+
+// translatePhysicalActivity translates a physical activity datapoint to a physical activity datum
+func translatePhysicalActivity(translation *Translation, header *Header, body map[string]interface{}) {
+	translation.Set("type", dataTypesActivityPhysical.Type, "/header/schema_id/name")
+	translation.Set("name", body["activity_name"], "/body/activity_name")
+	translation.Set("reportedIntensity", translateString(body["reported_activity_intensity"], reportedIntensities), "/body/reported_activity_intensity")
+	if distance, exists := body["distance"]; exists {
+		translation.SetUnitValue("distance", distance, "/body/distance", distanceUnits)
+	}
+	if kcalBurned, exists := body["kcal_burned"]; exists {
+		translation.SetUnitValue("energy", kcalBurned, "/body/kcal_burned", energyUnits)
+	}
+	translation.SetEffectiveTimeFrame(body, true)
+}
+
+// translateStepCount translates a step count datapoint, with the count as a number or a unit value, to a physical
+// activity datum
+func translateStepCount(translation *Translation, header *Header, body map[string]interface{}) {
+	translation.Set("type", dataTypesActivityPhysical.Type, "/header/schema_id/name")
+	stepCount := body["step_count"]
+	pointer := "/body/step_count"
+	if unitValue, ok := stepCount.(map[string]interface{}); ok {
+		stepCount = unitValue["value"]
+		pointer += "/value"
+	}
+	translation.Set("step", map[string]interface{}{"count": stepCount}, pointer)
+	translation.Pointers["/step/count"] = pointer
+	translation.SetEffectiveTimeFrame(body, true)
+}
+
+// translateCaloriesBurned translates a calories burned datapoint to a physical activity datum
+func translateCaloriesBurned(translation *Translation, header *Header, body map[string]interface{}) {
+	translation.Set("type", dataTypesActivityPhysical.Type, "/header/schema_id/name")
+	translation.Set("name", body["activity_name"], "/body/activity_name")
+	translation.SetUnitValue("energy", body["kcal_burned"], "/body/kcal_burned", energyUnits)
+	translation.SetEffectiveTimeFrame(body, true)
+}
+
+// translateTotalCarbohydrateConsumed translates a total carbohydrate consumed datapoint to a food datum with the net
+// carbohydrate
+func translateTotalCarbohydrateConsumed(translation *Translation, header *Header, body map[string]interface{}) {
+	translation.Set("type", dataTypesFood.Type, "/header/schema_id/name")
+	translation.Pointers["/nutrition/carbohydrate/net"] = "/body/total_carbohydrate_consumed/value"
+	translation.Pointers["/nutrition/carbohydrate/units"] = "/body/total_carbohydrate_consumed/unit"
+	carbohydrate := map[string]interface{}{}
+	if totalCarbohydrateConsumed, ok := body["total_carbohydrate_consumed"].(map[string]interface{}); ok {
+		if value, exists := totalCarbohydrateConsumed["value"]; exists {
+			carbohydrate["net"] = value
+		}
+		if unit, exists := totalCarbohydrateConsumed["unit"]; exists {
+			carbohydrate["units"] = translateString(unit, carbohydrateUnits)
+		}
+	}
+	translation.Set("nutrition", map[string]interface{}{"carbohydrate": carbohydrate}, "/body/total_carbohydrate_consumed")
+	translation.SetEffectiveTimeFrame(body, false)
+}
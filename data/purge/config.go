@@ -0,0 +1,92 @@
+package purge
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/tidepool-org/platform/config"
+	"github.com/tidepool-org/platform/errors"
+	"github.com/tidepool-org/platform/page"
+)
+
+const (
+	RetentionPeriodDefault = 90 * 24 * time.Hour
+	BatchSizeDefault       = 100
+	BatchDelayDefault      = 5 * time.Second
+	IntervalDefault        = 24 * time.Hour
+)
+
+// Config of the purge of deleted data sets. The retention period is the time a deleted data set stays restorable.
+type Config struct {
+	RetentionPeriod time.Duration
+	BatchSize       int
+	BatchDelay      time.Duration
+	Interval        time.Duration
+}
+
+func NewConfig() *Config {
+	return &Config{
+		RetentionPeriod: RetentionPeriodDefault,
+		BatchSize:       BatchSizeDefault,
+		BatchDelay:      BatchDelayDefault,
+		Interval:        IntervalDefault,
+	}
+}
+
+func (c *Config) Load(configReporter config.Reporter) error {
+	if configReporter == nil {
+		return errors.New("config reporter is missing")
+	}
+
+	if retentionDaysString, err := configReporter.Get("retention_days"); err == nil {
+		var retentionDays int64
+		retentionDays, err = strconv.ParseInt(retentionDaysString, 10, 0)
+		if err != nil {
+			return errors.New("retention days is invalid")
+		}
+		c.RetentionPeriod = time.Duration(retentionDays) * 24 * time.Hour
+	}
+	if batchSizeString, err := configReporter.Get("batch_size"); err == nil {
+		var batchSize int64
+		batchSize, err = strconv.ParseInt(batchSizeString, 10, 0)
+		if err != nil {
+			return errors.New("batch size is invalid")
+		}
+		c.BatchSize = int(batchSize)
+	}
+	if batchDelayString, err := configReporter.Get("batch_delay"); err == nil {
+		var batchDelay int64
+		batchDelay, err = strconv.ParseInt(batchDelayString, 10, 0)
+		if err != nil {
+			return errors.New("batch delay is invalid")
+		}
+		c.BatchDelay = time.Duration(batchDelay) * time.Second
+	}
+	if intervalString, err := configReporter.Get("interval"); err == nil {
+		var interval int64
+		interval, err = strconv.ParseInt(intervalString, 10, 0)
+		if err != nil {
+			return errors.New("interval is invalid")
+		}
+		c.Interval = time.Duration(interval) * time.Second
+	}
+
+	return nil
+}
+
+func (c *Config) Validate() error {
+	if c.RetentionPeriod <= 0 {
+		return errors.New("retention period is invalid")
+	}
+	if c.BatchSize < page.PaginationSizeMinimum || c.BatchSize > page.PaginationSizeMaximum {
+		return errors.New("batch size is invalid")
+	}
+	if c.BatchDelay < 0 {
+		return errors.New("batch delay is invalid")
+	}
+	if c.Interval <= 0 {
+		return errors.New("interval is invalid")
+	}
+
+	return nil
+}
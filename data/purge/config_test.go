@@ -0,0 +1,105 @@
+package purge_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	configTest "github.com/tidepool-org/platform/config/test"
+	dataPurge "github.com/tidepool-org/platform/data/purge"
+)
+
+var _ = Describe("Config", func() {
+	Context("NewConfig", func() {
+		It("returns the defaults", func() {
+			cfg := dataPurge.NewConfig()
+			Expect(cfg).ToNot(BeNil())
+			Expect(cfg.RetentionPeriod).To(Equal(dataPurge.RetentionPeriodDefault))
+			Expect(cfg.BatchSize).To(Equal(dataPurge.BatchSizeDefault))
+			Expect(cfg.BatchDelay).To(Equal(dataPurge.BatchDelayDefault))
+			Expect(cfg.Interval).To(Equal(dataPurge.IntervalDefault))
+			Expect(cfg.Validate()).To(Succeed())
+		})
+	})
+
+	Context("with new config", func() {
+		var configReporter *configTest.Reporter
+		var cfg *dataPurge.Config
+
+		BeforeEach(func() {
+			configReporter = configTest.NewReporter()
+			cfg = dataPurge.NewConfig()
+		})
+
+		Context("Load", func() {
+			It("returns an error if the config reporter is missing", func() {
+				Expect(cfg.Load(nil)).To(MatchError("config reporter is missing"))
+			})
+
+			It("keeps the defaults if not configured", func() {
+				Expect(cfg.Load(configReporter)).To(Succeed())
+				Expect(cfg).To(Equal(dataPurge.NewConfig()))
+			})
+
+			It("returns an error if the retention days is invalid", func() {
+				configReporter.Config["retention_days"] = "abc"
+				Expect(cfg.Load(configReporter)).To(MatchError("retention days is invalid"))
+			})
+
+			It("returns an error if the batch size is invalid", func() {
+				configReporter.Config["batch_size"] = "abc"
+				Expect(cfg.Load(configReporter)).To(MatchError("batch size is invalid"))
+			})
+
+			It("returns an error if the batch delay is invalid", func() {
+				configReporter.Config["batch_delay"] = "abc"
+				Expect(cfg.Load(configReporter)).To(MatchError("batch delay is invalid"))
+			})
+
+			It("returns an error if the interval is invalid", func() {
+				configReporter.Config["interval"] = "abc"
+				Expect(cfg.Load(configReporter)).To(MatchError("interval is invalid"))
+			})
+
+			It("loads the configuration", func() {
+				configReporter.Config["retention_days"] = "30"
+				configReporter.Config["batch_size"] = "50"
+				configReporter.Config["batch_delay"] = "2"
+				configReporter.Config["interval"] = "3600"
+				Expect(cfg.Load(configReporter)).To(Succeed())
+				Expect(cfg.RetentionPeriod).To(Equal(30 * 24 * time.Hour))
+				Expect(cfg.BatchSize).To(Equal(50))
+				Expect(cfg.BatchDelay).To(Equal(2 * time.Second))
+				Expect(cfg.Interval).To(Equal(time.Hour))
+			})
+		})
+
+		Context("Validate", func() {
+			It("returns an error if the retention period is not positive", func() {
+				cfg.RetentionPeriod = 0
+				Expect(cfg.Validate()).To(MatchError("retention period is invalid"))
+			})
+
+			It("returns an error if the batch size is less than the minimum", func() {
+				cfg.BatchSize = 0
+				Expect(cfg.Validate()).To(MatchError("batch size is invalid"))
+			})
+
+			It("returns an error if the batch size is greater than the maximum", func() {
+				cfg.BatchSize = 1001
+				Expect(cfg.Validate()).To(MatchError("batch size is invalid"))
+			})
+
+			It("returns an error if the batch delay is negative", func() {
+				cfg.BatchDelay = -time.Second
+				Expect(cfg.Validate()).To(MatchError("batch delay is invalid"))
+			})
+
+			It("returns an error if the interval is not positive", func() {
+				cfg.Interval = 0
+				Expect(cfg.Validate()).To(MatchError("interval is invalid"))
+			})
+		})
+	})
+})
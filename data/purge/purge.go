@@ -0,0 +1,3 @@
+package purge
+
+const Type = "org.tidepool.data.purge"
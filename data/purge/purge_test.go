@@ -0,0 +1,14 @@
+package purge_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	dataPurge "github.com/tidepool-org/platform/data/purge"
+)
+
+var _ = Describe("Purge", func() {
+	It("Type is expected", func() {
+		Expect(dataPurge.Type).To(Equal("org.tidepool.data.purge"))
+	})
+})
@@ -0,0 +1,278 @@
+package purge
+
+import (
+	"context"
+	"time"
+
+	"github.com/tidepool-org/platform/auth"
+	"github.com/tidepool-org/platform/data"
+	dataClient "github.com/tidepool-org/platform/data/client"
+	"github.com/tidepool-org/platform/errors"
+	"github.com/tidepool-org/platform/log"
+	"github.com/tidepool-org/platform/page"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/task"
+)
+
+const TaskDurationMaximum = 30 * time.Minute
+
+type Runner struct {
+	config     *Config
+	logger     log.Logger
+	authClient auth.Client
+	dataClient dataClient.Client
+}
+
+func NewRunner(cfg *Config, logger log.Logger, authClient auth.Client, dataClient dataClient.Client) (*Runner, error) {
+	if cfg == nil {
+		return nil, errors.New("config is missing")
+	} else if err := cfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "config is invalid")
+	}
+	if logger == nil {
+		return nil, errors.New("logger is missing")
+	}
+	if authClient == nil {
+		return nil, errors.New("auth client is missing")
+	}
+	if dataClient == nil {
+		return nil, errors.New("data client is missing")
+	}
+
+	return &Runner{
+		config:     cfg,
+		logger:     logger,
+		authClient: authClient,
+		dataClient: dataClient,
+	}, nil
+}
+
+func (r *Runner) Config() *Config {
+	return r.config
+}
+
+func (r *Runner) Logger() log.Logger {
+	return r.logger
+}
+
+func (r *Runner) AuthClient() auth.Client {
+	return r.authClient
+}
+
+func (r *Runner) DataClient() dataClient.Client {
+	return r.dataClient
+}
+
+func (r *Runner) CanRunTask(tsk *task.Task) bool {
+	return tsk != nil && tsk.Type == Type
+}
+
+func (r *Runner) Run(ctx context.Context, tsk *task.Task) {
+	now := time.Now()
+
+	ctx = log.NewContextWithLogger(ctx, r.Logger())
+
+	tsk.ClearError()
+
+	summary := NewSummary(now, now.Add(-r.Config().RetentionPeriod))
+
+	if serverSessionToken, sErr := r.AuthClient().ServerSessionToken(); sErr != nil {
+		tsk.AppendError(errors.Wrap(sErr, "unable to get server session token"))
+	} else {
+		ctx = auth.NewContextWithServerSessionToken(ctx, serverSessionToken)
+
+		if taskRunner, tErr := NewTaskRunner(r, tsk); tErr != nil {
+			tsk.AppendError(errors.Wrap(tErr, "unable to create task runner"))
+		} else if tErr = taskRunner.Run(ctx, summary); tErr != nil {
+			tsk.AppendError(errors.Wrap(tErr, "unable to run task runner"))
+		}
+	}
+
+	summary.Duration = time.Since(now)
+
+	if tsk.Data == nil {
+		tsk.Data = map[string]interface{}{}
+	}
+	tsk.Data["lastRun"] = summary.AsMap()
+
+	if !tsk.IsFailed() {
+		tsk.RepeatAvailableAfter(r.Config().Interval)
+	}
+
+	r.Logger().WithField("summary", summary).Info("Purged deleted data sets")
+
+	if summary.Duration > TaskDurationMaximum+r.Config().BatchDelay {
+		r.Logger().WithField("taskDuration", summary.Duration.Truncate(time.Millisecond).Seconds()).Warn("Task duration exceeds maximum")
+	}
+}
+
+// Summary of a single run of the purge task, recorded in the task data. A data set that fails to purge is counted once
+// as failed, however often it is retried.
+type Summary struct {
+	StartTime     time.Time     `json:"startTime"`
+	DeletedBefore time.Time     `json:"deletedBefore"`
+	Duration      time.Duration `json:"duration"`
+	Batches       int           `json:"batches"`
+	Purged        int           `json:"purged"`
+	Failed        int           `json:"failed"`
+	PurgedData    int           `json:"purgedData"`
+	Complete      bool          `json:"complete"`
+}
+
+func NewSummary(startTime time.Time, deletedBefore time.Time) *Summary {
+	return &Summary{
+		StartTime:     startTime,
+		DeletedBefore: deletedBefore,
+	}
+}
+
+func (s *Summary) AsMap() map[string]interface{} {
+	return map[string]interface{}{
+		"startTime":     s.StartTime.Truncate(time.Millisecond).Format(time.RFC3339Nano),
+		"deletedBefore": s.DeletedBefore.Truncate(time.Millisecond).Format(time.RFC3339Nano),
+		"duration":      s.Duration.Truncate(time.Millisecond).Seconds(),
+		"batches":       s.Batches,
+		"purged":        s.Purged,
+		"failed":        s.Failed,
+		"purgedData":    s.PurgedData,
+		"complete":      s.Complete,
+	}
+}
+
+type TaskRunner struct {
+	*Runner
+	task *task.Task
+}
+
+func NewTaskRunner(rnnr *Runner, tsk *task.Task) (*TaskRunner, error) {
+	if rnnr == nil {
+		return nil, errors.New("runner is missing")
+	}
+	if tsk == nil {
+		return nil, errors.New("task is missing")
+	}
+
+	return &TaskRunner{
+		Runner: rnnr,
+		task:   tsk,
+	}, nil
+}
+
+// Run purges the deleted data sets and then the deleted data left, that is the data deleted on its own from data sets
+// that are not deleted, until none remain or the task duration maximum is reached. The run is complete only once both
+// are purged.
+func (t *TaskRunner) Run(ctx context.Context, summary *Summary) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if summary == nil {
+		return errors.New("summary is missing")
+	}
+
+	complete, err := t.purgeDeletedDataSets(ctx, summary)
+	if err != nil || !complete {
+		return err
+	}
+
+	complete, err = t.purgeDeletedData(ctx, summary)
+	summary.Complete = complete
+	return err
+}
+
+// purgeDeletedDataSets purges the deleted data sets in batches, oldest deleted first. A purged data set is no longer
+// listed, so each batch is always the first page. A data set that fails to purge is listed again in the next batch, so
+// the purge stops once an entire batch fails.
+func (t *TaskRunner) purgeDeletedDataSets(ctx context.Context, summary *Summary) (bool, error) {
+	logger := log.LoggerFromContext(ctx)
+
+	filter := data.NewDeletedDataSetFilter()
+	filter.DeletedBefore = pointer.FromTime(summary.DeletedBefore)
+	pagination := page.NewPagination()
+	pagination.Size = t.Config().BatchSize
+
+	failedDataSetIDs := map[string]bool{}
+	for time.Since(summary.StartTime) < TaskDurationMaximum {
+		dataSets, err := t.DataClient().ListDeletedDataSets(ctx, filter, pagination)
+		if err != nil {
+			return false, errors.Wrap(err, "unable to list deleted data sets")
+		}
+		if len(dataSets) == 0 {
+			return true, nil
+		}
+
+		summary.Batches++
+
+		var failed int
+		for _, dataSet := range dataSets {
+			if dataSet.UploadID == nil {
+				failed++
+				continue
+			}
+			if err = t.DataClient().DestroyDeletedDataSetData(ctx, *dataSet.UploadID); err != nil {
+				logger.WithError(err).WithField("dataSetId", *dataSet.UploadID).Warn("Unable to destroy deleted data set data")
+				failed++
+				if !failedDataSetIDs[*dataSet.UploadID] {
+					failedDataSetIDs[*dataSet.UploadID] = true
+					summary.Failed++
+				}
+			} else {
+				summary.Purged++
+			}
+		}
+
+		if failed == len(dataSets) {
+			return false, errors.New("unable to purge any deleted data set in batch")
+		} else if len(dataSets) < pagination.Size && failed == 0 {
+			return true, nil
+		}
+
+		if !t.waitBatchDelay(ctx) {
+			return false, nil
+		}
+	}
+
+	return false, nil
+}
+
+// purgeDeletedData purges the deleted data left in batches, oldest deleted first, with the same retention period as the
+// data sets. Destroyed data is no longer selected, so each batch is always the first page.
+func (t *TaskRunner) purgeDeletedData(ctx context.Context, summary *Summary) (bool, error) {
+	filter := data.NewDeletedDataFilter()
+	filter.DeletedBefore = pointer.FromTime(summary.DeletedBefore)
+	pagination := page.NewPagination()
+	pagination.Size = t.Config().BatchSize
+
+	for time.Since(summary.StartTime) < TaskDurationMaximum {
+		result, err := t.DataClient().DestroyDeletedData(ctx, filter, pagination)
+		if err != nil {
+			return false, errors.Wrap(err, "unable to destroy deleted data")
+		}
+
+		if result.Destroyed == 0 {
+			return true, nil
+		}
+
+		summary.Batches++
+		summary.PurgedData += result.Destroyed
+
+		if result.Destroyed < pagination.Size {
+			return true, nil
+		}
+
+		if !t.waitBatchDelay(ctx) {
+			return false, nil
+		}
+	}
+
+	return false, nil
+}
+
+// waitBatchDelay waits the batch delay, and returns false if the context is done first
+func (t *TaskRunner) waitBatchDelay(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(t.Config().BatchDelay):
+		return true
+	}
+}
@@ -0,0 +1,271 @@
+package purge_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/auth"
+	authTest "github.com/tidepool-org/platform/auth/test"
+	"github.com/tidepool-org/platform/data"
+	dataClientTest "github.com/tidepool-org/platform/data/client/test"
+	dataPurge "github.com/tidepool-org/platform/data/purge"
+	dataTest "github.com/tidepool-org/platform/data/test"
+	"github.com/tidepool-org/platform/errors"
+	logTest "github.com/tidepool-org/platform/log/test"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/task"
+)
+
+var _ = Describe("Runner", func() {
+	var cfg *dataPurge.Config
+	var logger *logTest.Logger
+	var authClient *authTest.Client
+	var dataClient *dataClientTest.Client
+
+	BeforeEach(func() {
+		cfg = dataPurge.NewConfig()
+		cfg.BatchSize = 2
+		cfg.BatchDelay = 0
+		logger = logTest.NewLogger()
+		authClient = authTest.NewClient()
+		dataClient = dataClientTest.NewClient()
+	})
+
+	AfterEach(func() {
+		dataClient.AssertOutputsEmpty()
+		authClient.AssertOutputsEmpty()
+	})
+
+	Context("NewRunner", func() {
+		It("returns an error if the config is missing", func() {
+			rnnr, err := dataPurge.NewRunner(nil, logger, authClient, dataClient)
+			Expect(err).To(MatchError("config is missing"))
+			Expect(rnnr).To(BeNil())
+		})
+
+		It("returns an error if the config is invalid", func() {
+			cfg.BatchSize = 0
+			rnnr, err := dataPurge.NewRunner(cfg, logger, authClient, dataClient)
+			Expect(err).To(MatchError("config is invalid; batch size is invalid"))
+			Expect(rnnr).To(BeNil())
+		})
+
+		It("returns an error if the logger is missing", func() {
+			rnnr, err := dataPurge.NewRunner(cfg, nil, authClient, dataClient)
+			Expect(err).To(MatchError("logger is missing"))
+			Expect(rnnr).To(BeNil())
+		})
+
+		It("returns an error if the auth client is missing", func() {
+			rnnr, err := dataPurge.NewRunner(cfg, logger, nil, dataClient)
+			Expect(err).To(MatchError("auth client is missing"))
+			Expect(rnnr).To(BeNil())
+		})
+
+		It("returns an error if the data client is missing", func() {
+			rnnr, err := dataPurge.NewRunner(cfg, logger, authClient, nil)
+			Expect(err).To(MatchError("data client is missing"))
+			Expect(rnnr).To(BeNil())
+		})
+
+		It("returns successfully", func() {
+			rnnr, err := dataPurge.NewRunner(cfg, logger, authClient, dataClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rnnr).ToNot(BeNil())
+			Expect(rnnr.Config()).To(Equal(cfg))
+			Expect(rnnr.Logger()).To(Equal(logger))
+			Expect(rnnr.AuthClient()).To(Equal(authClient))
+			Expect(rnnr.DataClient()).To(Equal(dataClient))
+		})
+	})
+
+	Context("with new runner", func() {
+		var rnnr *dataPurge.Runner
+		var tsk *task.Task
+
+		BeforeEach(func() {
+			var err error
+			rnnr, err = dataPurge.NewRunner(cfg, logger, authClient, dataClient)
+			Expect(err).ToNot(HaveOccurred())
+			tsk, err = task.NewTask(dataPurge.NewTaskCreate())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Context("CanRunTask", func() {
+			It("returns false if the task is missing", func() {
+				Expect(rnnr.CanRunTask(nil)).To(BeFalse())
+			})
+
+			It("returns false if the task type is different", func() {
+				tsk.Type = "org.tidepool.other"
+				Expect(rnnr.CanRunTask(tsk)).To(BeFalse())
+			})
+
+			It("returns true if the task type is purge", func() {
+				Expect(rnnr.CanRunTask(tsk)).To(BeTrue())
+			})
+		})
+
+		Context("Run", func() {
+			var ctx context.Context
+			var token string
+
+			newDataSets := func(count int) data.DataSets {
+				dataSets := data.DataSets{}
+				for index := 0; index < count; index++ {
+					dataSets = append(dataSets, &data.DataSet{UploadID: pointer.FromString(dataTest.RandomSetID())})
+				}
+				return dataSets
+			}
+
+			BeforeEach(func() {
+				ctx = context.Background()
+				token = dataTest.NewSessionToken()
+			})
+
+			It("records the error if the server session token cannot be obtained", func() {
+				authClient.ServerSessionTokenOutputs = []authTest.ServerSessionTokenOutput{{Error: errors.New("test error")}}
+				rnnr.Run(ctx, tsk)
+				Expect(tsk.HasError()).To(BeTrue())
+				Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("purged", 0))
+				Expect(tsk.AvailableTime).ToNot(BeNil())
+			})
+
+			Context("with server session token", func() {
+				BeforeEach(func() {
+					authClient.ServerSessionTokenOutputs = []authTest.ServerSessionTokenOutput{{Token: token}}
+				})
+
+				It("lists deleted data sets deleted before the retention period with the server session token", func() {
+					dataClient.ListDeletedDataSetsOutputs = []dataClientTest.ListDeletedDataSetsOutput{{DataSets: data.DataSets{}}}
+					dataClient.DestroyDeletedDataOutputs = []dataClientTest.DestroyDeletedDataOutput{{Result: &data.DeletedDataDestroyResult{}}}
+					startTime := time.Now()
+					rnnr.Run(ctx, tsk)
+					Expect(tsk.HasError()).To(BeFalse())
+					Expect(dataClient.ListDeletedDataSetsInputs).To(HaveLen(1))
+					input := dataClient.ListDeletedDataSetsInputs[0]
+					Expect(auth.ServerSessionTokenFromContext(input.Context)).To(Equal(token))
+					Expect(*input.Filter.DeletedBefore).To(BeTemporally("~", startTime.Add(-cfg.RetentionPeriod), time.Second))
+					Expect(input.Pagination.Page).To(Equal(0))
+					Expect(input.Pagination.Size).To(Equal(cfg.BatchSize))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("complete", true))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("batches", 0))
+					Expect(*tsk.AvailableTime).To(BeTemporally("~", startTime.Add(cfg.Interval), time.Second))
+				})
+
+				It("purges the deleted data sets in batches until none remain", func() {
+					firstDataSets := newDataSets(2)
+					secondDataSets := newDataSets(1)
+					dataClient.ListDeletedDataSetsOutputs = []dataClientTest.ListDeletedDataSetsOutput{{DataSets: firstDataSets}, {DataSets: secondDataSets}}
+					dataClient.DestroyDeletedDataSetDataOutputs = []error{nil, nil, nil}
+					dataClient.DestroyDeletedDataOutputs = []dataClientTest.DestroyDeletedDataOutput{{Result: &data.DeletedDataDestroyResult{}}}
+					rnnr.Run(ctx, tsk)
+					Expect(tsk.HasError()).To(BeFalse())
+					Expect(dataClient.DestroyDeletedDataSetDataInputs).To(Equal([]dataClientTest.DestroyDeletedDataSetDataInput{
+						{Context: dataClient.DestroyDeletedDataSetDataInputs[0].Context, DataSetID: *firstDataSets[0].UploadID},
+						{Context: dataClient.DestroyDeletedDataSetDataInputs[1].Context, DataSetID: *firstDataSets[1].UploadID},
+						{Context: dataClient.DestroyDeletedDataSetDataInputs[2].Context, DataSetID: *secondDataSets[0].UploadID},
+					}))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("batches", 2))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("purged", 3))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("failed", 0))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("complete", true))
+				})
+
+				It("continues past a data set that fails to purge", func() {
+					firstDataSets := newDataSets(2)
+					dataClient.ListDeletedDataSetsOutputs = []dataClientTest.ListDeletedDataSetsOutput{{DataSets: firstDataSets}, {DataSets: firstDataSets[:1]}}
+					dataClient.DestroyDeletedDataSetDataOutputs = []error{errors.New("test error"), nil, nil}
+					dataClient.DestroyDeletedDataOutputs = []dataClientTest.DestroyDeletedDataOutput{{Result: &data.DeletedDataDestroyResult{}}}
+					rnnr.Run(ctx, tsk)
+					Expect(tsk.HasError()).To(BeFalse())
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("batches", 2))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("purged", 2))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("failed", 1))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("complete", true))
+				})
+
+				It("counts a data set that fails to purge again once", func() {
+					firstDataSets := newDataSets(2)
+					secondDataSets := append(firstDataSets[:1:1], newDataSets(1)...)
+					dataClient.ListDeletedDataSetsOutputs = []dataClientTest.ListDeletedDataSetsOutput{{DataSets: firstDataSets}, {DataSets: secondDataSets}, {DataSets: firstDataSets[:1]}}
+					dataClient.DestroyDeletedDataSetDataOutputs = []error{errors.New("test error"), nil, errors.New("test error"), nil, errors.New("test error")}
+					rnnr.Run(ctx, tsk)
+					Expect(tsk.HasError()).To(BeTrue())
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("batches", 3))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("purged", 2))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("failed", 1))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("complete", false))
+				})
+
+				It("stops and records the error once an entire batch fails to purge", func() {
+					dataClient.ListDeletedDataSetsOutputs = []dataClientTest.ListDeletedDataSetsOutput{{DataSets: newDataSets(2)}}
+					dataClient.DestroyDeletedDataSetDataOutputs = []error{errors.New("test error"), errors.New("test error")}
+					rnnr.Run(ctx, tsk)
+					Expect(tsk.HasError()).To(BeTrue())
+					Expect(tsk.IsFailed()).To(BeFalse())
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("failed", 2))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("complete", false))
+					Expect(tsk.AvailableTime).ToNot(BeNil())
+				})
+
+				It("stops and records the error if the deleted data sets cannot be listed", func() {
+					dataClient.ListDeletedDataSetsOutputs = []dataClientTest.ListDeletedDataSetsOutput{{Error: errors.New("test error")}}
+					rnnr.Run(ctx, tsk)
+					Expect(tsk.HasError()).To(BeTrue())
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("complete", false))
+				})
+
+				It("purges the deleted data left in batches after the deleted data sets until a batch is not full", func() {
+					startTime := time.Now()
+					dataClient.ListDeletedDataSetsOutputs = []dataClientTest.ListDeletedDataSetsOutput{{DataSets: data.DataSets{}}}
+					dataClient.DestroyDeletedDataOutputs = []dataClientTest.DestroyDeletedDataOutput{{Result: &data.DeletedDataDestroyResult{Destroyed: 2}}, {Result: &data.DeletedDataDestroyResult{Destroyed: 1}}}
+					rnnr.Run(ctx, tsk)
+					Expect(tsk.HasError()).To(BeFalse())
+					Expect(dataClient.DestroyDeletedDataInputs).To(HaveLen(2))
+					for _, input := range dataClient.DestroyDeletedDataInputs {
+						Expect(auth.ServerSessionTokenFromContext(input.Context)).To(Equal(token))
+						Expect(*input.Filter.DeletedBefore).To(BeTemporally("~", startTime.Add(-cfg.RetentionPeriod), time.Second))
+						Expect(input.Pagination.Page).To(Equal(0))
+						Expect(input.Pagination.Size).To(Equal(cfg.BatchSize))
+					}
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("batches", 2))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("purgedData", 3))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("complete", true))
+				})
+
+				It("stops and records the error if the deleted data cannot be destroyed", func() {
+					dataClient.ListDeletedDataSetsOutputs = []dataClientTest.ListDeletedDataSetsOutput{{DataSets: data.DataSets{}}}
+					dataClient.DestroyDeletedDataOutputs = []dataClientTest.DestroyDeletedDataOutput{{Error: errors.New("test error")}}
+					rnnr.Run(ctx, tsk)
+					Expect(tsk.HasError()).To(BeTrue())
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("purgedData", 0))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("complete", false))
+				})
+
+				It("does not purge the deleted data left until the deleted data sets are purged", func() {
+					dataClient.ListDeletedDataSetsOutputs = []dataClientTest.ListDeletedDataSetsOutput{{DataSets: newDataSets(1)}}
+					dataClient.DestroyDeletedDataSetDataOutputs = []error{errors.New("test error")}
+					rnnr.Run(ctx, tsk)
+					Expect(tsk.HasError()).To(BeTrue())
+					Expect(dataClient.DestroyDeletedDataInvocations).To(Equal(0))
+				})
+
+				It("stops between batches when the context is done", func() {
+					cfg.BatchDelay = time.Hour
+					cancelCtx, cancel := context.WithCancel(ctx)
+					cancel()
+					dataClient.ListDeletedDataSetsOutputs = []dataClientTest.ListDeletedDataSetsOutput{{DataSets: newDataSets(2)}}
+					dataClient.DestroyDeletedDataSetDataOutputs = []error{nil, nil}
+					rnnr.Run(cancelCtx, tsk)
+					Expect(tsk.HasError()).To(BeFalse())
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("purged", 2))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("complete", false))
+				})
+			})
+		})
+	})
+})
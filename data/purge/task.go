@@ -0,0 +1,17 @@
+package purge
+
+import (
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/task"
+)
+
+// There is only ever one purge task, so the task name is the type
+const TaskName = Type
+
+func NewTaskCreate() *task.TaskCreate {
+	return &task.TaskCreate{
+		Name: pointer.FromString(TaskName),
+		Type: Type,
+		Data: map[string]interface{}{},
+	}
+}
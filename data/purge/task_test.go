@@ -0,0 +1,23 @@
+package purge_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	dataPurge "github.com/tidepool-org/platform/data/purge"
+	"github.com/tidepool-org/platform/pointer"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
+)
+
+var _ = Describe("Task", func() {
+	Context("NewTaskCreate", func() {
+		It("returns a valid task create", func() {
+			taskCreate := dataPurge.NewTaskCreate()
+			Expect(taskCreate).ToNot(BeNil())
+			Expect(taskCreate.Name).To(Equal(pointer.FromString(dataPurge.TaskName)))
+			Expect(taskCreate.Type).To(Equal(dataPurge.Type))
+			Expect(taskCreate.Data).To(BeEmpty())
+			Expect(structureValidator.New().Validate(taskCreate)).To(Succeed())
+		})
+	})
+})
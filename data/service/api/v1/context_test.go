@@ -0,0 +1,165 @@
+package v1_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	. "github.com/onsi/gomega"
+
+	dataClient "github.com/tidepool-org/platform/data/client"
+	dataClientTest "github.com/tidepool-org/platform/data/client/test"
+	dataDeduplicator "github.com/tidepool-org/platform/data/deduplicator"
+	dataDeduplicatorTest "github.com/tidepool-org/platform/data/deduplicator/test"
+	dataService "github.com/tidepool-org/platform/data/service"
+	dataSource "github.com/tidepool-org/platform/data/source"
+	dataSourceTest "github.com/tidepool-org/platform/data/source/test"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	dataStoreDEPRECATEDTest "github.com/tidepool-org/platform/data/storeDEPRECATED/test"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	"github.com/tidepool-org/platform/log"
+	logTest "github.com/tidepool-org/platform/log/test"
+	"github.com/tidepool-org/platform/permission"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+	testRest "github.com/tidepool-org/platform/test/rest"
+)
+
+type GetUserPermissionsOutput struct {
+	Permissions bool
+	Error       error
+}
+
+type TestPermissionClient struct {
+	permission.Client
+	GetUserPermissionsInputs  []string
+	GetUserPermissionsOutputs []GetUserPermissionsOutput
+}
+
+func (p *TestPermissionClient) GetUserPermissions(req *rest.Request, targetUserID string) (bool, error) {
+	p.GetUserPermissionsInputs = append(p.GetUserPermissionsInputs, targetUserID)
+	Expect(p.GetUserPermissionsOutputs).ToNot(BeEmpty())
+	output := p.GetUserPermissionsOutputs[0]
+	p.GetUserPermissionsOutputs = p.GetUserPermissionsOutputs[1:]
+	return output.Permissions, output.Error
+}
+
+// TestDryRunDataSession is a dry run data session reading through the data session mock, with an empty report
+type TestDryRunDataSession struct {
+	*dataStoreDEPRECATEDTest.DataSession
+}
+
+func (d *TestDryRunDataSession) DryRunReport(ctx context.Context, dataSet *dataTypesUpload.Upload) (dataStoreDEPRECATED.DryRunReport, error) {
+	return dataStoreDEPRECATED.NewDryRunReport(), nil
+}
+
+// TestContext is a data service context for the handler tests, recording the responses instead of writing them,
+// except for those written directly to the response by a responder
+type TestContext struct {
+	dataService.Context
+	request                 *rest.Request
+	response                *testRest.ResponseWriter
+	dataSession             *dataStoreDEPRECATEDTest.DataSession
+	dataClient              *dataClientTest.Client
+	dataDeduplicatorFactory *dataDeduplicatorTest.Factory
+	permissionClient        *TestPermissionClient
+	dataSourceClient        *dataSourceTest.Client
+	errors                  []*service.Error
+	failures                []string
+	statusCode              int
+	data                    interface{}
+	body                    bytes.Buffer
+}
+
+func NewTestContext() *TestContext {
+	response := testRest.NewResponseWriter()
+	response.HeaderOutput = &http.Header{}
+	context := &TestContext{
+		response:                response,
+		dataSession:             dataStoreDEPRECATEDTest.NewDataSession(),
+		dataClient:              dataClientTest.NewClient(),
+		dataDeduplicatorFactory: dataDeduplicatorTest.NewFactory(),
+		permissionClient:        &TestPermissionClient{},
+		dataSourceClient:        dataSourceTest.NewClient(),
+	}
+	response.WriteStub = context.body.Write
+	return context
+}
+
+// SetRequest sets the request, with the body, if any, as JSON, a test logger, and the request details, if any
+func (c *TestContext) SetRequest(method string, target string, body interface{}, pathParams map[string]string, details request.Details) {
+	var reader io.Reader
+	if body != nil {
+		bites, err := json.Marshal(body)
+		Expect(err).ToNot(HaveOccurred())
+		reader = bytes.NewReader(bites)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	req = req.WithContext(log.NewContextWithLogger(req.Context(), logTest.NewLogger()))
+	if details != nil {
+		req = req.WithContext(request.NewContextWithDetails(req.Context(), details))
+	}
+	c.request = &rest.Request{Request: req, PathParams: pathParams}
+}
+
+// ResponseStatusCode returns the status code written to the response by a responder, if any
+func (c *TestContext) ResponseStatusCode() int {
+	if len(c.response.WriteHeaderInputs) == 0 {
+		return 0
+	}
+	return c.response.WriteHeaderInputs[len(c.response.WriteHeaderInputs)-1]
+}
+
+// ResponseBody returns the body written to the response by a responder, if any
+func (c *TestContext) ResponseBody() string {
+	return c.body.String()
+}
+
+func (c *TestContext) Request() *rest.Request {
+	return c.request
+}
+
+func (c *TestContext) Response() rest.ResponseWriter {
+	return c.response
+}
+
+func (c *TestContext) RespondWithError(err *service.Error) {
+	c.errors = append(c.errors, err)
+}
+
+func (c *TestContext) RespondWithInternalServerFailure(message string, failure ...interface{}) {
+	c.failures = append(c.failures, message)
+}
+
+func (c *TestContext) RespondWithStatusAndData(statusCode int, data interface{}) {
+	c.statusCode = statusCode
+	c.data = data
+}
+
+func (c *TestContext) PermissionClient() permission.Client {
+	return c.permissionClient
+}
+
+func (c *TestContext) DataDeduplicatorFactory() dataDeduplicator.Factory {
+	return c.dataDeduplicatorFactory
+}
+
+func (c *TestContext) DataSession() dataStoreDEPRECATED.DataSession {
+	return c.dataSession
+}
+
+func (c *TestContext) DataDryRunSession() dataStoreDEPRECATED.DryRunDataSession {
+	return &TestDryRunDataSession{DataSession: c.dataSession}
+}
+
+func (c *TestContext) DataClient() dataClient.Client {
+	return c.dataClient
+}
+
+func (c *TestContext) DataSourceClient() dataSource.Client {
+	return c.dataSourceClient
+}
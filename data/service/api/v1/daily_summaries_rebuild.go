@@ -0,0 +1,54 @@
+package v1
+
+import (
+	"net/http"
+
+	dataService "github.com/tidepool-org/platform/data/service"
+	"github.com/tidepool-org/platform/data/summary"
+	"github.com/tidepool-org/platform/page"
+	"github.com/tidepool-org/platform/request"
+)
+
+// DailySummariesRebuild godoc
+// @Summary Rebuild the pending daily summaries
+// @Description Rebuild a page of the daily summaries whose rebuild was requested when their data changed, oldest
+// @Description requested first. Only services (eg. not users) can rebuild the pending daily summaries
+// @ID platform-data-api-DailySummariesRebuild
+// @Produce json
+// @Param requestedBefore query string false "Only the rebuilds requested before this time (RFC3339Nano)"
+// @Param page query int false "When using pagination, page number" default(0)
+// @Param size query int false "When using pagination, number of elements by page, 1<size<1000" minimum(1) maximum(1000) default(100)
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Success 200 {object} summary.DailySummaryRebuildResult "Count of the rebuilt and failed daily summary rebuilds"
+// @Failure 400 {object} service.Error "Bad request (invalid query parameter)"
+// @Failure 403 {object} service.Error "Forbidden: caller is not a service"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/daily_summaries/rebuild [post]
+func DailySummariesRebuild(dataServiceContext dataService.Context) {
+	res := dataServiceContext.Response()
+	req := dataServiceContext.Request()
+	dataClient := dataServiceContext.DataClient()
+
+	responder := request.MustNewResponder(res, req)
+
+	if details := request.DetailsFromContext(req.Context()); !details.IsService() {
+		responder.Error(http.StatusForbidden, request.ErrorUnauthorized())
+		return
+	}
+
+	filter := summary.NewDailySummaryRebuildFilter()
+	pagination := page.NewPagination()
+	if err := request.DecodeRequestQuery(req.Request, filter, pagination); err != nil {
+		responder.Error(http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := dataClient.RebuildPendingDailySummaries(req.Context(), filter, pagination)
+	if err != nil {
+		responder.Error(http.StatusInternalServerError, err)
+		return
+	}
+
+	responder.Data(http.StatusOK, result)
+}
@@ -0,0 +1,68 @@
+package v1_test
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	dataClientTest "github.com/tidepool-org/platform/data/client/test"
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	"github.com/tidepool-org/platform/data/summary"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/request"
+	userTest "github.com/tidepool-org/platform/user/test"
+)
+
+var _ = Describe("DailySummariesRebuild", func() {
+	var context *TestContext
+
+	setRequest := func(query string, details request.Details) {
+		context.SetRequest(http.MethodPost, "/v1/daily_summaries/rebuild"+query, nil, nil, details)
+	}
+
+	BeforeEach(func() {
+		context = NewTestContext()
+	})
+
+	AfterEach(func() {
+		context.dataClient.AssertOutputsEmpty()
+	})
+
+	It("responds with forbidden if the caller is not a service", func() {
+		setRequest("", request.NewDetails(request.MethodSessionToken, userTest.RandomID(), "token"))
+		dataServiceApiV1.DailySummariesRebuild(context)
+		Expect(context.ResponseStatusCode()).To(Equal(http.StatusForbidden))
+		Expect(context.dataClient.RebuildPendingDailySummariesInvocations).To(Equal(0))
+	})
+
+	Context("as a service", func() {
+		serviceDetails := request.NewDetails(request.MethodServiceSecret, "", "")
+
+		It("responds with bad request if the requested before time is not valid", func() {
+			setRequest("?requestedBefore=invalid", serviceDetails)
+			dataServiceApiV1.DailySummariesRebuild(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataClient.RebuildPendingDailySummariesInvocations).To(Equal(0))
+		})
+
+		It("responds with failure if the pending daily summaries cannot be rebuilt", func() {
+			setRequest("", serviceDetails)
+			context.dataClient.RebuildPendingDailySummariesOutputs = []dataClientTest.RebuildPendingDailySummariesOutput{{Error: errorsTest.RandomError()}}
+			dataServiceApiV1.DailySummariesRebuild(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusInternalServerError))
+		})
+
+		It("responds with the count of the rebuilt and failed pending daily summaries", func() {
+			setRequest("?requestedBefore=2020-03-01T08:00:00Z&size=10", serviceDetails)
+			context.dataClient.RebuildPendingDailySummariesOutputs = []dataClientTest.RebuildPendingDailySummariesOutput{{Result: &summary.DailySummaryRebuildResult{Rebuilt: 3, Failed: 1}}}
+			dataServiceApiV1.DailySummariesRebuild(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusOK))
+			Expect(context.ResponseBody()).To(MatchJSON(`{"rebuilt": 3, "failed": 1}`))
+			Expect(context.dataClient.RebuildPendingDailySummariesInputs).To(HaveLen(1))
+			Expect(*context.dataClient.RebuildPendingDailySummariesInputs[0].Filter.RequestedBefore).To(BeTemporally("==", time.Date(2020, 3, 1, 8, 0, 0, 0, time.UTC)))
+			Expect(context.dataClient.RebuildPendingDailySummariesInputs[0].Pagination.Size).To(Equal(10))
+		})
+	})
+})
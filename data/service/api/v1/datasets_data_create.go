@@ -1,13 +1,19 @@
 package v1
 
 import (
+	"encoding/json"
 	"net/http"
 	"strconv"
 
 	"github.com/tidepool-org/platform/data"
+	dataDeduplicator "github.com/tidepool-org/platform/data/deduplicator"
 	dataNormalizer "github.com/tidepool-org/platform/data/normalizer"
 	dataService "github.com/tidepool-org/platform/data/service"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/data/timeprocessing"
 	dataTypesFactory "github.com/tidepool-org/platform/data/types/factory"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	"github.com/tidepool-org/platform/errors"
 	"github.com/tidepool-org/platform/request"
 	"github.com/tidepool-org/platform/service"
 	structureParser "github.com/tidepool-org/platform/structure/parser"
@@ -16,6 +22,20 @@ import (
 
 type EmptyBody struct{}
 
+const (
+	DatumResultStatusAccepted     = "accepted"
+	DatumResultStatusRejected     = "rejected"
+	DatumResultStatusDeduplicated = "deduplicated"
+)
+
+// DatumResult is the outcome for the datum at the same index of the request body when data is added with results. A
+// deduplicated datum is added as an accepted datum is, but duplicates data already stored, which the deduplicator of
+// the data set deduplicates when the data set is closed.
+type DatumResult struct {
+	Status string               `json:"status"`
+	Error  *errors.Serializable `json:"error,omitempty"`
+}
+
 // DataSetsDataCreate godoc
 // @Summary Add data to a DataSets
 // @ID platform-data-api-DataSetsDataCreate
@@ -23,15 +43,20 @@ type EmptyBody struct{}
 // @Produce json
 // @Param dataSetID path string true "dataSet ID"
 // @Param data body []types.Base true "Array of data, of one type only"
+// @Param results query bool false "True to store the valid data and return a result for each datum instead of rejecting the whole array"
+// @Param dryRun query bool false "True to report what the deduplicator would do to the existing data when adding the data and closing the data set, without adding any data; results is then ignored"
+// @Param deduplicator query string false "With dryRun, the name of the deduplicator to dry run instead of the one of the data set"
 // @Security TidepoolSessionToken
 // @Security TidepoolServiceSecret
 // @Security TidepoolAuthorization
 // @Security TidepoolRestrictedToken
 // @Success 200 {object} EmptyBody "Operation is a success"
-// @Failure 400 {object} service.Error "Data set id is missing"
+// @Success 200 {array} DatumResult "Operation is a success, with results requested"
+// @Success 200 {object} DataSetDryRun "Operation is a success, with dry run requested"
+// @Failure 400 {object} service.Error "Data set id is missing, or results, dryRun or deduplicator parameter is invalid"
 // @Failure 403 {object} service.Error "Auth token is not authorized for requested action"
 // @Failure 404 {object} service.Error "Data set with specified id not found"
-// @Failure 409 {object} service.Error "Data set with specified id is closed"
+// @Failure 409 {object} service.Error "Data set with specified id is closed, or has no valid time zone name for its time processing of data with only a device time"
 // @Failure 500 {object} service.Error "Unable to perform the operation"
 // @Router /v1/datasets/:dataSetId/data [post]
 func DataSetsDataCreate(dataServiceContext dataService.Context) {
@@ -44,6 +69,21 @@ func DataSetsDataCreate(dataServiceContext dataService.Context) {
 		return
 	}
 
+	results := false
+	if value := req.URL.Query().Get("results"); value != "" {
+		var parseErr error
+		if results, parseErr = strconv.ParseBool(value); parseErr != nil {
+			request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, request.ErrorParameterInvalid("results"))
+			return
+		}
+	}
+
+	dryRunQuery, err := parseDryRunQuery(req)
+	if err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+
 	dataSet, err := dataServiceContext.DataSession().GetDataSetByID(ctx, dataSetID)
 	if err != nil {
 		dataServiceContext.RespondWithInternalServerFailure("Unable to get data set by id", err)
@@ -79,17 +119,40 @@ func DataSetsDataCreate(dataServiceContext dataService.Context) {
 		return
 	}
 
+	// The raw data whose time processing is deferred are only processed here to be validated, and are stored as sent
+	var deferredRawDatumArray []interface{}
+	if !dryRunQuery.DryRun && timeprocessing.Deferred(dataSet, rawDatumArray) {
+		if deferredRawDatumArray, err = copyRawDatumArray(rawDatumArray); err != nil {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to copy raw data", err)
+			return
+		}
+	}
+
+	if err = timeprocessing.Process(dataSet, rawDatumArray); err != nil {
+		dataServiceContext.RespondWithError(ErrorDataSetTimeZoneNameNotValid(dataSetID))
+		return
+	}
+
+	if results && !dryRunQuery.DryRun {
+		dataSetsDataCreateWithResults(dataServiceContext, dataSet, rawDatumArray, deferredRawDatumArray)
+		return
+	}
+
 	parser := structureParser.NewArray(&rawDatumArray)
 	validator := structureValidator.New()
 	normalizer := dataNormalizer.New()
 
 	datumArray := []data.Datum{}
+	validRawDatumArray := []interface{}{}
 	for _, reference := range parser.References() {
 		if datum := dataTypesFactory.ParseDatum(parser.WithReferenceObjectParser(reference)); datum != nil && *datum != nil {
 			(*datum).Validate(validator.WithReference(strconv.Itoa(reference)))
 			if (*datum).IsValid(validator.WithReference(strconv.Itoa(reference))) {
 				(*datum).Normalize(normalizer.WithReference(strconv.Itoa(reference)))
 				datumArray = append(datumArray, *datum)
+				if deferredRawDatumArray != nil {
+					validRawDatumArray = append(validRawDatumArray, deferredRawDatumArray[reference])
+				}
 			} else {
 				// reset Warning
 				validator.ResetWarning()
@@ -118,7 +181,24 @@ func DataSetsDataCreate(dataServiceContext dataService.Context) {
 		datum.SetDataSetID(dataSet.UploadID)
 	}
 
-	if deduplicator, getErr := dataServiceContext.DataDeduplicatorFactory().Get(dataSet); getErr != nil {
+	if dryRunQuery.DryRun {
+		dataSetDryRun(dataServiceContext, dataSet, dryRunQuery, func(deduplicator dataDeduplicator.Deduplicator, session dataStoreDEPRECATED.DataSession) error {
+			// Deduplicators that compare hashes only act on close, so report what closing after adding would do
+			if err := deduplicator.AddData(ctx, session, dataSet, datumArray); err != nil {
+				return err
+			}
+			return deduplicator.Close(ctx, session, dataSet)
+		})
+		return
+	}
+
+	// Only the valid raw data are stored, as are only the valid data otherwise
+	if deferredRawDatumArray != nil {
+		if err = dataServiceContext.DataSession().CreateTimeProcessingBatch(ctx, dataSet, validRawDatumArray); err != nil {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to create time processing batch", err)
+			return
+		}
+	} else if deduplicator, getErr := dataServiceContext.DataDeduplicatorFactory().Get(dataSet); getErr != nil {
 		dataServiceContext.RespondWithInternalServerFailure("Unable to get deduplicator", getErr)
 		return
 	} else if deduplicator == nil {
@@ -131,3 +211,127 @@ func DataSetsDataCreate(dataServiceContext dataService.Context) {
 
 	dataServiceContext.RespondWithStatusAndData(http.StatusOK, []struct{}{})
 }
+
+// dataSetsDataCreateWithResults adds the valid data exactly as without results, and reports a datum as deduplicated only
+// if the deduplicator of the data set reports it as a duplicate; the data whose time processing is deferred until the
+// data set is closed are only reported as accepted, as their times, and so their identities, are not yet known
+func dataSetsDataCreateWithResults(dataServiceContext dataService.Context, dataSet *dataTypesUpload.Upload, rawDatumArray []interface{}, deferredRawDatumArray []interface{}) {
+	ctx := dataServiceContext.Request().Context()
+
+	datumResults := make([]DatumResult, len(rawDatumArray))
+	datumReferences := []int{}
+	datumArray := data.Data{}
+	duplicateDatumArray := data.Data{}
+	acceptedRawDatumArray := []interface{}{}
+	for reference := range rawDatumArray {
+		referenceDatumArray, err := parseDatumArrayWithReference(rawDatumArray, reference)
+		if err != nil {
+			datumResults[reference] = DatumResult{Status: DatumResultStatusRejected, Error: errors.NewSerializable(err)}
+			continue
+		}
+		for _, datum := range referenceDatumArray {
+			datum.SetUserID(dataSet.UserID)
+			datum.SetDataSetID(dataSet.UploadID)
+		}
+		datumResults[reference] = DatumResult{Status: DatumResultStatusAccepted}
+		datumReferences = append(datumReferences, reference)
+		datumArray = append(datumArray, referenceDatumArray...)
+		duplicateDatumArray = append(duplicateDatumArray, referenceDatumArray[0])
+		if deferredRawDatumArray != nil {
+			acceptedRawDatumArray = append(acceptedRawDatumArray, deferredRawDatumArray[reference])
+		}
+	}
+
+	if deferredRawDatumArray != nil {
+		if len(acceptedRawDatumArray) > 0 {
+			if err := dataServiceContext.DataSession().CreateTimeProcessingBatch(ctx, dataSet, acceptedRawDatumArray); err != nil {
+				dataServiceContext.RespondWithInternalServerFailure("Unable to create time processing batch", err)
+				return
+			}
+		}
+	} else if len(datumArray) > 0 {
+		deduplicator, err := dataServiceContext.DataDeduplicatorFactory().Get(dataSet)
+		if err != nil {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get deduplicator", err)
+			return
+		} else if deduplicator == nil {
+			dataServiceContext.RespondWithInternalServerFailure("Deduplicator not found")
+			return
+		}
+
+		if duplicateReporter, ok := deduplicator.(dataDeduplicator.DuplicateReporter); ok {
+			duplicates, err := duplicateReporter.Duplicates(ctx, dataServiceContext.DataSession(), dataSet, duplicateDatumArray)
+			if err != nil {
+				dataServiceContext.RespondWithInternalServerFailure("Unable to get duplicates", err)
+				return
+			}
+			for index, duplicate := range duplicates {
+				if duplicate && index < len(datumReferences) {
+					datumResults[datumReferences[index]] = DatumResult{Status: DatumResultStatusDeduplicated}
+				}
+			}
+		}
+
+		if err = deduplicator.AddData(ctx, dataServiceContext.DataSession(), dataSet, datumArray); err != nil {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to add data", err)
+			return
+		}
+	}
+
+	dataServiceContext.RespondWithStatusAndData(http.StatusOK, datumResults)
+}
+
+// parseDatumArrayWithReference parses, validates and normalizes the datum at reference on its own, so that
+// any error only concerns that datum; a datum that is not valid only because of a warning is rejected with the warning
+// as the error. The error source pointers still include the reference
+func parseDatumArrayWithReference(rawDatumArray []interface{}, reference int) (data.Data, error) {
+	parser := structureParser.NewArray(&rawDatumArray)
+	validator := structureValidator.New()
+	normalizer := dataNormalizer.New()
+
+	datum := dataTypesFactory.ParseDatum(parser.WithReferenceObjectParser(reference))
+	if datum == nil || *datum == nil {
+		if err := parser.Error(); err != nil {
+			return nil, err
+		}
+		parser.WithReferenceErrorReporter(reference).ReportError(structureValidator.ErrorValueNotExists())
+		return nil, parser.Error()
+	}
+	if err := parser.Error(); err != nil {
+		return nil, err
+	}
+
+	(*datum).Validate(validator.WithReference(strconv.Itoa(reference)))
+	if err := validator.Error(); err != nil {
+		return nil, err
+	}
+
+	// As without results, only a valid datum is added, which for some types also excludes a datum with a warning
+	if !(*datum).IsValid(validator.WithReference(strconv.Itoa(reference))) {
+		if err := validator.Warning(); err != nil {
+			return nil, err
+		}
+		validator.WithReference(strconv.Itoa(reference)).ReportError(errors.Prepared(structureValidator.ErrorCodeValueNotValid, "value is not valid", "value is not valid"))
+		return nil, validator.Error()
+	}
+
+	(*datum).Normalize(normalizer.WithReference(strconv.Itoa(reference)))
+	if err := normalizer.Error(); err != nil {
+		return nil, err
+	}
+
+	return append(data.Data{*datum}, normalizer.Data()...), nil
+}
+
+// copyRawDatumArray returns a deep copy of the raw data, as decoded from JSON
+func copyRawDatumArray(rawDatumArray []interface{}) ([]interface{}, error) {
+	bytes, err := json.Marshal(rawDatumArray)
+	if err != nil {
+		return nil, err
+	}
+	var copied []interface{}
+	if err = json.Unmarshal(bytes, &copied); err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
@@ -1,8 +1,259 @@
 package v1_test
 
 import (
+	"net/http"
+
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	dataDeduplicatorTest "github.com/tidepool-org/platform/data/deduplicator/test"
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataStoreDEPRECATEDTest "github.com/tidepool-org/platform/data/storeDEPRECATED/test"
+	dataTest "github.com/tidepool-org/platform/data/test"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/pointer"
+	userTest "github.com/tidepool-org/platform/user/test"
 )
 
 var _ = Describe("DataSetsDataCreate", func() {
+	Context("with results", func() {
+		var dataSet *dataTypesUpload.Upload
+		var rawDatumArray []interface{}
+		var context *TestContext
+
+		newRawDatum := func(value float64) map[string]interface{} {
+			return map[string]interface{}{
+				"type":     "cbg",
+				"deviceId": *dataSet.DeviceID,
+				"time":     "2020-01-01T00:00:00Z",
+				"units":    "mmol/L",
+				"value":    value,
+			}
+		}
+
+		BeforeEach(func() {
+			dataSet = dataTypesUpload.New()
+			dataSet.UserID = pointer.FromString(userTest.RandomID())
+			dataSet.UploadID = pointer.FromString(dataTest.RandomSetID())
+			dataSet.DeviceID = pointer.FromString(dataTest.NewDeviceID())
+			dataSet.State = pointer.FromString("open")
+			rawDatumArray = []interface{}{newRawDatum(5.5), map[string]interface{}{"type": "cbg"}, newRawDatum(5.5), newRawDatum(6.5)}
+			context = NewTestContext()
+			context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: true}}
+			context.dataSession.GetDataSetByIDOutputs = []dataStoreDEPRECATEDTest.GetDataSetByIDOutput{{DataSet: dataSet}}
+		})
+
+		JustBeforeEach(func() {
+			context.SetRequest(http.MethodPost, "/v1/datasets/"+*dataSet.UploadID+"/data?results=true", rawDatumArray, map[string]string{"dataSetId": *dataSet.UploadID}, nil)
+		})
+
+		AfterEach(func() {
+			context.dataSession.Expectations()
+		})
+
+		It("adds all the valid data, including duplicates, if the deduplicator does not report duplicates", func() {
+			deduplicator := dataDeduplicatorTest.NewDeduplicator()
+			deduplicator.AddDataOutputs = []error{nil}
+			context.dataDeduplicatorFactory.GetOutputs = []dataDeduplicatorTest.GetOutput{{Deduplicator: deduplicator}}
+			dataServiceApiV1.DataSetsDataCreate(context)
+			Expect(context.errors).To(BeEmpty())
+			Expect(context.failures).To(BeEmpty())
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			Expect(context.data).To(HaveLen(4))
+			datumResults := context.data.([]dataServiceApiV1.DatumResult)
+			Expect(datumResults[0].Status).To(Equal(dataServiceApiV1.DatumResultStatusAccepted))
+			Expect(datumResults[1].Status).To(Equal(dataServiceApiV1.DatumResultStatusRejected))
+			Expect(datumResults[1].Error).ToNot(BeNil())
+			Expect(datumResults[2].Status).To(Equal(dataServiceApiV1.DatumResultStatusAccepted))
+			Expect(datumResults[3].Status).To(Equal(dataServiceApiV1.DatumResultStatusAccepted))
+			Expect(deduplicator.AddDataInputs).To(HaveLen(1))
+			Expect(deduplicator.AddDataInputs[0].DataSetData).To(HaveLen(3))
+			deduplicator.AssertOutputsEmpty()
+		})
+
+		It("reports the data the deduplicator reports as duplicates as deduplicated, and still adds them", func() {
+			deduplicator := dataDeduplicatorTest.NewDuplicateReporter()
+			deduplicator.DuplicatesOutputs = []dataDeduplicatorTest.DuplicatesOutput{{Duplicates: []bool{false, true, false}}}
+			deduplicator.AddDataOutputs = []error{nil}
+			context.dataDeduplicatorFactory.GetOutputs = []dataDeduplicatorTest.GetOutput{{Deduplicator: deduplicator}}
+			dataServiceApiV1.DataSetsDataCreate(context)
+			Expect(context.failures).To(BeEmpty())
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			datumResults := context.data.([]dataServiceApiV1.DatumResult)
+			Expect(datumResults[0].Status).To(Equal(dataServiceApiV1.DatumResultStatusAccepted))
+			Expect(datumResults[1].Status).To(Equal(dataServiceApiV1.DatumResultStatusRejected))
+			Expect(datumResults[2].Status).To(Equal(dataServiceApiV1.DatumResultStatusDeduplicated))
+			Expect(datumResults[3].Status).To(Equal(dataServiceApiV1.DatumResultStatusAccepted))
+			Expect(deduplicator.DuplicatesInputs).To(HaveLen(1))
+			Expect(deduplicator.DuplicatesInputs[0].DataSetData).To(HaveLen(3))
+			Expect(deduplicator.AddDataInputs).To(HaveLen(1))
+			Expect(deduplicator.AddDataInputs[0].DataSetData).To(Equal(deduplicator.DuplicatesInputs[0].DataSetData))
+			deduplicator.AssertOutputsEmpty()
+		})
+
+		It("responds with failure if the deduplicator fails to report duplicates", func() {
+			deduplicator := dataDeduplicatorTest.NewDuplicateReporter()
+			deduplicator.DuplicatesOutputs = []dataDeduplicatorTest.DuplicatesOutput{{Error: errorsTest.RandomError()}}
+			context.dataDeduplicatorFactory.GetOutputs = []dataDeduplicatorTest.GetOutput{{Deduplicator: deduplicator}}
+			dataServiceApiV1.DataSetsDataCreate(context)
+			Expect(context.failures).To(Equal([]string{"Unable to get duplicates"}))
+			Expect(deduplicator.AddDataInputs).To(BeEmpty())
+		})
+
+		It("responds with failure if the deduplicator fails to add the data", func() {
+			deduplicator := dataDeduplicatorTest.NewDeduplicator()
+			deduplicator.AddDataOutputs = []error{errorsTest.RandomError()}
+			context.dataDeduplicatorFactory.GetOutputs = []dataDeduplicatorTest.GetOutput{{Deduplicator: deduplicator}}
+			dataServiceApiV1.DataSetsDataCreate(context)
+			Expect(context.failures).To(Equal([]string{"Unable to add data"}))
+		})
+
+		When("a datum is not valid only because of a warning", func() {
+			BeforeEach(func() {
+				rawDatumArray = []interface{}{
+					map[string]interface{}{
+						"type":         "basal",
+						"deliveryType": "scheduled",
+						"deviceId":     *dataSet.DeviceID,
+						"time":         "2020-01-01T00:00:00Z",
+						"duration":     -1,
+						"rate":         1.0,
+					},
+					newRawDatum(5.5),
+				}
+			})
+
+			It("rejects the datum with the warning, as it is not added without results either", func() {
+				deduplicator := dataDeduplicatorTest.NewDeduplicator()
+				deduplicator.AddDataOutputs = []error{nil}
+				context.dataDeduplicatorFactory.GetOutputs = []dataDeduplicatorTest.GetOutput{{Deduplicator: deduplicator}}
+				dataServiceApiV1.DataSetsDataCreate(context)
+				Expect(context.errors).To(BeEmpty())
+				Expect(context.failures).To(BeEmpty())
+				Expect(context.statusCode).To(Equal(http.StatusOK))
+				datumResults := context.data.([]dataServiceApiV1.DatumResult)
+				Expect(datumResults).To(HaveLen(2))
+				Expect(datumResults[0].Status).To(Equal(dataServiceApiV1.DatumResultStatusRejected))
+				Expect(datumResults[0].Error).ToNot(BeNil())
+				Expect(datumResults[0].Error.Error).To(MatchError(ContainSubstring("value -1 is not between 0 and 604800000")))
+				Expect(datumResults[1].Status).To(Equal(dataServiceApiV1.DatumResultStatusAccepted))
+				Expect(deduplicator.AddDataInputs).To(HaveLen(1))
+				Expect(deduplicator.AddDataInputs[0].DataSetData).To(HaveLen(1))
+				deduplicator.AssertOutputsEmpty()
+			})
+		})
+
+		When("no datum is valid", func() {
+			BeforeEach(func() {
+				rawDatumArray = []interface{}{map[string]interface{}{"type": "cbg"}}
+			})
+
+			It("adds no data", func() {
+				dataServiceApiV1.DataSetsDataCreate(context)
+				Expect(context.failures).To(BeEmpty())
+				Expect(context.statusCode).To(Equal(http.StatusOK))
+				datumResults := context.data.([]dataServiceApiV1.DatumResult)
+				Expect(datumResults).To(HaveLen(1))
+				Expect(datumResults[0].Status).To(Equal(dataServiceApiV1.DatumResultStatusRejected))
+				Expect(context.dataDeduplicatorFactory.GetInvocations).To(Equal(0))
+			})
+		})
+
+		When("the time processing is deferred until the data set is closed", func() {
+			BeforeEach(func() {
+				dataSet.TimeProcessing = pointer.FromString(dataTypesUpload.TimeProcessingUTCBootstrapping)
+				dataSet.DeviceTime = pointer.FromString("2020-01-01T10:00:00")
+				dataSet.ComputerTime = pointer.FromString("2020-01-01T10:00:00")
+				dataSet.TimeZoneOffset = pointer.FromInt(0)
+				dataSet.TimeZoneName = pointer.FromString("UTC")
+				rawDatumArray = []interface{}{newRawDatum(5.5), map[string]interface{}{"type": "cbg"}, newRawDatum(6.5)}
+				for _, rawDatum := range rawDatumArray {
+					object := rawDatum.(map[string]interface{})
+					if _, ok := object["time"]; ok {
+						delete(object, "time")
+						object["deviceTime"] = "2020-01-01T09:00:00"
+					}
+				}
+			})
+
+			It("stores the valid data as sent, and reports them as accepted", func() {
+				context.dataSession.CreateTimeProcessingBatchOutputs = []error{nil}
+				dataServiceApiV1.DataSetsDataCreate(context)
+				Expect(context.errors).To(BeEmpty())
+				Expect(context.failures).To(BeEmpty())
+				Expect(context.statusCode).To(Equal(http.StatusOK))
+				datumResults := context.data.([]dataServiceApiV1.DatumResult)
+				Expect(datumResults[0].Status).To(Equal(dataServiceApiV1.DatumResultStatusAccepted))
+				Expect(datumResults[1].Status).To(Equal(dataServiceApiV1.DatumResultStatusRejected))
+				Expect(datumResults[2].Status).To(Equal(dataServiceApiV1.DatumResultStatusAccepted))
+				Expect(context.dataSession.CreateTimeProcessingBatchInputs).To(HaveLen(1))
+				Expect(context.dataSession.CreateTimeProcessingBatchInputs[0].RawData).To(ConsistOf(
+					HaveKeyWithValue("deviceTime", "2020-01-01T09:00:00"),
+					HaveKeyWithValue("deviceTime", "2020-01-01T09:00:00"),
+				))
+				Expect(context.dataDeduplicatorFactory.GetInvocations).To(Equal(0))
+			})
+		})
+	})
+
+	Context("without results", func() {
+		var dataSet *dataTypesUpload.Upload
+		var rawDatumArray []interface{}
+		var context *TestContext
+
+		BeforeEach(func() {
+			dataSet = dataTypesUpload.New()
+			dataSet.UserID = pointer.FromString(userTest.RandomID())
+			dataSet.UploadID = pointer.FromString(dataTest.RandomSetID())
+			dataSet.DeviceID = pointer.FromString(dataTest.NewDeviceID())
+			dataSet.State = pointer.FromString("open")
+			dataSet.TimeProcessing = pointer.FromString(dataTypesUpload.TimeProcessingUTCBootstrapping)
+			dataSet.DeviceTime = pointer.FromString("2020-01-01T10:00:00")
+			dataSet.ComputerTime = pointer.FromString("2020-01-01T10:00:00")
+			dataSet.TimeZoneOffset = pointer.FromInt(0)
+			dataSet.TimeZoneName = pointer.FromString("UTC")
+			rawDatumArray = []interface{}{
+				map[string]interface{}{
+					"type":         "basal",
+					"deliveryType": "scheduled",
+					"deviceId":     *dataSet.DeviceID,
+					"deviceTime":   "2020-01-01T08:00:00",
+					"duration":     -1,
+					"rate":         1.0,
+				},
+				map[string]interface{}{
+					"type":       "cbg",
+					"deviceId":   *dataSet.DeviceID,
+					"deviceTime": "2020-01-01T09:00:00",
+					"units":      "mmol/L",
+					"value":      5.5,
+				},
+			}
+			context = NewTestContext()
+			context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: true}}
+			context.dataSession.GetDataSetByIDOutputs = []dataStoreDEPRECATEDTest.GetDataSetByIDOutput{{DataSet: dataSet}}
+		})
+
+		JustBeforeEach(func() {
+			context.SetRequest(http.MethodPost, "/v1/datasets/"+*dataSet.UploadID+"/data", rawDatumArray, map[string]string{"dataSetId": *dataSet.UploadID}, nil)
+		})
+
+		AfterEach(func() {
+			context.dataSession.Expectations()
+		})
+
+		It("stores only the valid raw data as sent when the time processing is deferred", func() {
+			context.dataSession.CreateTimeProcessingBatchOutputs = []error{nil}
+			dataServiceApiV1.DataSetsDataCreate(context)
+			Expect(context.errors).To(BeEmpty())
+			Expect(context.failures).To(BeEmpty())
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			Expect(context.dataSession.CreateTimeProcessingBatchInputs).To(HaveLen(1))
+			Expect(context.dataSession.CreateTimeProcessingBatchInputs[0].RawData).To(ConsistOf(
+				SatisfyAll(HaveKeyWithValue("type", "cbg"), HaveKeyWithValue("deviceTime", "2020-01-01T09:00:00"), Not(HaveKey("time"))),
+			))
+			Expect(context.dataDeduplicatorFactory.GetInvocations).To(Equal(0))
+		})
+	})
 })
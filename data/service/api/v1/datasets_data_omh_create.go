@@ -0,0 +1,102 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/tidepool-org/platform/data/omh"
+	dataService "github.com/tidepool-org/platform/data/service"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+)
+
+// DataSetsDataOMHCreate godoc
+// @Summary Add Open mHealth datapoints to a DataSets
+// @Description Translates the Open mHealth (or IEEE 1752.1) datapoints to data, blood-glucose to cbg or smbg, and
+// @Description physical-activity, step-count and calories-burned to physicalActivity, and total-carbohydrate-consumed to
+// @Description food, with the origin from the header.
+// @Description The error source pointers are those of the datapoints.
+// @ID platform-data-api-DataSetsDataOMHCreate
+// @Accept json
+// @Produce json
+// @Param dataSetID path string true "dataSet ID"
+// @Param datapoints body []object true "Array of Open mHealth datapoints, each with header and body"
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Security TidepoolAuthorization
+// @Security TidepoolRestrictedToken
+// @Success 200 {object} EmptyBody "Operation is a success"
+// @Failure 400 {object} service.Error "Data set id is missing, or a datapoint is invalid or of an unsupported schema"
+// @Failure 403 {object} service.Error "Auth token is not authorized for requested action"
+// @Failure 404 {object} service.Error "Data set with specified id not found"
+// @Failure 409 {object} service.Error "Data set with specified id is closed"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/datasets/:dataSetId/data/omh [post]
+func DataSetsDataOMHCreate(dataServiceContext dataService.Context) {
+	req := dataServiceContext.Request()
+	ctx := req.Context()
+
+	dataSetID := req.PathParam("dataSetId")
+	if dataSetID == "" {
+		dataServiceContext.RespondWithError(ErrorDataSetIDMissing())
+		return
+	}
+
+	dataSet, err := dataServiceContext.DataSession().GetDataSetByID(ctx, dataSetID)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get data set by id", err)
+		return
+	}
+	if dataSet == nil {
+		dataServiceContext.RespondWithError(ErrorDataSetIDNotFound(dataSetID))
+		return
+	}
+
+	permissions, err := dataServiceContext.PermissionClient().GetUserPermissions(req, *dataSet.UserID)
+	if err != nil {
+		if request.IsErrorUnauthorized(err) {
+			dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		} else {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get user permissions", err)
+		}
+		return
+	}
+	if !permissions {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	if (dataSet.State != nil && *dataSet.State == "closed") || (dataSet.DataState != nil && *dataSet.DataState == "closed") { // TODO: Deprecated DataState (after data migration)
+		dataServiceContext.RespondWithError(ErrorDataSetClosed(dataSetID))
+		return
+	}
+
+	var rawDatapointArray []interface{}
+	if err = req.DecodeJsonPayload(&rawDatapointArray); err != nil {
+		dataServiceContext.RespondWithError(service.ErrorJSONMalformed())
+		return
+	}
+
+	datumArray, err := omh.ParseDatapoints(rawDatapointArray)
+	if err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+
+	for _, datum := range datumArray {
+		datum.SetUserID(dataSet.UserID)
+		datum.SetDataSetID(dataSet.UploadID)
+	}
+
+	if deduplicator, getErr := dataServiceContext.DataDeduplicatorFactory().Get(dataSet); getErr != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get deduplicator", getErr)
+		return
+	} else if deduplicator == nil {
+		dataServiceContext.RespondWithInternalServerFailure("Deduplicator not found")
+		return
+	} else if err = deduplicator.AddData(ctx, dataServiceContext.DataSession(), dataSet, datumArray); err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to add data", err)
+		return
+	}
+
+	dataServiceContext.RespondWithStatusAndData(http.StatusOK, []struct{}{})
+}
@@ -0,0 +1,112 @@
+package v1_test
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	dataDeduplicatorTest "github.com/tidepool-org/platform/data/deduplicator/test"
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataStoreDEPRECATEDTest "github.com/tidepool-org/platform/data/storeDEPRECATED/test"
+	dataTest "github.com/tidepool-org/platform/data/test"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/service"
+	userTest "github.com/tidepool-org/platform/user/test"
+)
+
+var _ = Describe("DataSetsDataOMHCreate", func() {
+	var dataSet *dataTypesUpload.Upload
+	var rawDatapointArray []interface{}
+	var context *TestContext
+
+	newDatapoint := func(value interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"header": map[string]interface{}{
+				"id":                 "a5ad97c7-8e2f-4a4e-8f3d-0c1b3d5e7a90",
+				"creation_date_time": "2020-03-01T09:00:00-08:00",
+				"schema_id":          map[string]interface{}{"namespace": "omh", "name": "blood-glucose", "version": "3.0"},
+				"acquisition_provenance": map[string]interface{}{
+					"source_name": "Wellness App",
+					"modality":    "sensed",
+				},
+			},
+			"body": map[string]interface{}{
+				"blood_glucose":        map[string]interface{}{"value": value, "unit": "mg/dL"},
+				"effective_time_frame": map[string]interface{}{"date_time": "2020-03-01T08:00:00-08:00"},
+				"specimen_source":      "interstitial fluid",
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		dataSet = dataTypesUpload.New()
+		dataSet.UserID = pointer.FromString(userTest.RandomID())
+		dataSet.UploadID = pointer.FromString(dataTest.RandomSetID())
+		dataSet.State = pointer.FromString("open")
+		rawDatapointArray = []interface{}{newDatapoint(180.0)}
+		context = NewTestContext()
+		context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: true}}
+		context.dataSession.GetDataSetByIDOutputs = []dataStoreDEPRECATEDTest.GetDataSetByIDOutput{{DataSet: dataSet}}
+	})
+
+	JustBeforeEach(func() {
+		context.SetRequest(http.MethodPost, "/v1/datasets/"+*dataSet.UploadID+"/data/omh", rawDatapointArray, map[string]string{"dataSetId": *dataSet.UploadID}, nil)
+	})
+
+	AfterEach(func() {
+		context.dataSession.Expectations()
+	})
+
+	It("adds the data translated from the datapoints to the data set", func() {
+		deduplicator := dataDeduplicatorTest.NewDeduplicator()
+		deduplicator.AddDataOutputs = []error{nil}
+		context.dataDeduplicatorFactory.GetOutputs = []dataDeduplicatorTest.GetOutput{{Deduplicator: deduplicator}}
+		dataServiceApiV1.DataSetsDataOMHCreate(context)
+		Expect(context.errors).To(BeEmpty())
+		Expect(context.failures).To(BeEmpty())
+		Expect(context.statusCode).To(Equal(http.StatusOK))
+		Expect(deduplicator.AddDataInputs).To(HaveLen(1))
+		Expect(deduplicator.AddDataInputs[0].DataSetData).To(HaveLen(1))
+		datum, ok := deduplicator.AddDataInputs[0].DataSetData[0].(*dataTypesBloodGlucoseContinuous.Continuous)
+		Expect(ok).To(BeTrue())
+		Expect(*datum.Time).To(Equal("2020-03-01T16:00:00Z"))
+		Expect(datum.UserID).To(Equal(dataSet.UserID))
+		Expect(datum.UploadID).To(Equal(dataSet.UploadID))
+	})
+
+	It("responds with failure if the deduplicator fails to add the data", func() {
+		deduplicator := dataDeduplicatorTest.NewDeduplicator()
+		deduplicator.AddDataOutputs = []error{errorsTest.RandomError()}
+		context.dataDeduplicatorFactory.GetOutputs = []dataDeduplicatorTest.GetOutput{{Deduplicator: deduplicator}}
+		dataServiceApiV1.DataSetsDataOMHCreate(context)
+		Expect(context.failures).To(Equal([]string{"Unable to add data"}))
+	})
+
+	When("a datapoint is not valid", func() {
+		BeforeEach(func() {
+			rawDatapointArray = append(rawDatapointArray, newDatapoint("invalid"))
+		})
+
+		It("responds with bad request and adds no data", func() {
+			dataServiceApiV1.DataSetsDataOMHCreate(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataDeduplicatorFactory.GetInvocations).To(Equal(0))
+		})
+	})
+
+	When("the data set is closed", func() {
+		BeforeEach(func() {
+			dataSet.State = pointer.FromString("closed")
+		})
+
+		It("responds with conflict and adds no data", func() {
+			dataServiceApiV1.DataSetsDataOMHCreate(context)
+			Expect(context.errors).To(Equal([]*service.Error{dataServiceApiV1.ErrorDataSetClosed(*dataSet.UploadID)}))
+			Expect(context.dataDeduplicatorFactory.GetInvocations).To(Equal(0))
+		})
+	})
+})
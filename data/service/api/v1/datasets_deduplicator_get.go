@@ -0,0 +1,73 @@
+package v1
+
+import (
+	"net/http"
+
+	dataService "github.com/tidepool-org/platform/data/service"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+)
+
+// DataSetDeduplicator describes the deduplicator a data set resolves to
+type DataSetDeduplicator struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Assigned bool   `json:"assigned"`
+}
+
+// DataSetsDeduplicatorGet godoc
+// @Summary Get the deduplicator of a data set
+// @Description Get the deduplicator a data set resolves to, either the one already assigned to the data set
+// @Description or the one that the configured device rules would assign to it.
+// @Description Caller must be a service.
+// @ID platform-data-api-DataSetsDeduplicatorGet
+// @Produce json
+// @Param dataSetId path string true "dataSet ID"
+// @Security TidepoolServiceSecret
+// @Success 200 {object} DataSetDeduplicator "Operation is a success"
+// @Failure 400 {object} service.Error "Data set id is missing"
+// @Failure 403 {object} service.Error "Auth token is not authorized for requested action"
+// @Failure 404 {object} service.Error "Data set with specified id not found, or no deduplicator for the data set"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/data_sets/:dataSetId/deduplicator [get]
+func DataSetsDeduplicatorGet(dataServiceContext dataService.Context) {
+	req := dataServiceContext.Request()
+	ctx := req.Context()
+
+	dataSetID := req.PathParam("dataSetId")
+	if dataSetID == "" {
+		dataServiceContext.RespondWithError(ErrorDataSetIDMissing())
+		return
+	}
+
+	if details := request.DetailsFromContext(ctx); !details.IsService() {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	dataSet, err := dataServiceContext.DataSession().GetDataSetByID(ctx, dataSetID)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get data set by id", err)
+		return
+	}
+	if dataSet == nil {
+		dataServiceContext.RespondWithError(ErrorDataSetIDNotFound(dataSetID))
+		return
+	}
+
+	deduplicator, err := dataServiceContext.DataDeduplicatorFactory().New(dataSet)
+	if err != nil {
+		if request.IsErrorResourceNotFound(err) {
+			request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusNotFound, err)
+		} else {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to resolve deduplicator", err)
+		}
+		return
+	}
+
+	dataServiceContext.RespondWithStatusAndData(http.StatusOK, &DataSetDeduplicator{
+		Name:     deduplicator.Name(),
+		Version:  deduplicator.Version(),
+		Assigned: dataSet.HasDeduplicatorName(),
+	})
+}
@@ -0,0 +1,97 @@
+package v1_test
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	dataDeduplicatorTest "github.com/tidepool-org/platform/data/deduplicator/test"
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataStoreDEPRECATEDTest "github.com/tidepool-org/platform/data/storeDEPRECATED/test"
+	dataTest "github.com/tidepool-org/platform/data/test"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+	userTest "github.com/tidepool-org/platform/user/test"
+)
+
+var _ = Describe("DataSetsDeduplicatorGet", func() {
+	var dataSet *dataTypesUpload.Upload
+	var context *TestContext
+	var deduplicator *dataDeduplicatorTest.Deduplicator
+
+	setRequest := func(details request.Details) {
+		context.SetRequest(http.MethodGet, "/v1/data_sets/"+*dataSet.UploadID+"/deduplicator", nil, map[string]string{"dataSetId": *dataSet.UploadID}, details)
+	}
+
+	BeforeEach(func() {
+		dataSet = dataTypesUpload.New()
+		dataSet.UserID = pointer.FromString(userTest.RandomID())
+		dataSet.UploadID = pointer.FromString(dataTest.RandomSetID())
+		deduplicator = dataDeduplicatorTest.NewDeduplicator()
+		context = NewTestContext()
+		setRequest(request.NewDetails(request.MethodServiceSecret, "", ""))
+	})
+
+	AfterEach(func() {
+		context.dataSession.Expectations()
+		context.dataDeduplicatorFactory.AssertOutputsEmpty()
+		deduplicator.AssertOutputsEmpty()
+	})
+
+	It("responds with unauthorized if the caller is not a service", func() {
+		setRequest(request.NewDetails(request.MethodSessionToken, *dataSet.UserID, "token"))
+		dataServiceApiV1.DataSetsDeduplicatorGet(context)
+		Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+		Expect(context.dataSession.GetDataSetByIDInputs).To(BeEmpty())
+	})
+
+	It("responds with not found if the data set does not exist", func() {
+		context.dataSession.GetDataSetByIDOutputs = []dataStoreDEPRECATEDTest.GetDataSetByIDOutput{{DataSet: nil}}
+		dataServiceApiV1.DataSetsDeduplicatorGet(context)
+		Expect(context.errors).To(Equal([]*service.Error{dataServiceApiV1.ErrorDataSetIDNotFound(*dataSet.UploadID)}))
+	})
+
+	Context("with a data set", func() {
+		BeforeEach(func() {
+			context.dataSession.GetDataSetByIDOutputs = []dataStoreDEPRECATEDTest.GetDataSetByIDOutput{{DataSet: dataSet}}
+		})
+
+		It("responds with not found if there is no deduplicator for the data set", func() {
+			context.dataDeduplicatorFactory.NewOutputs = []dataDeduplicatorTest.NewOutput{{Error: request.ErrorResourceNotFound()}}
+			dataServiceApiV1.DataSetsDeduplicatorGet(context)
+			Expect(context.failures).To(BeEmpty())
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusNotFound))
+		})
+
+		It("responds with failure if the deduplicator cannot be resolved", func() {
+			context.dataDeduplicatorFactory.NewOutputs = []dataDeduplicatorTest.NewOutput{{Error: errorsTest.RandomError()}}
+			dataServiceApiV1.DataSetsDeduplicatorGet(context)
+			Expect(context.failures).To(Equal([]string{"Unable to resolve deduplicator"}))
+		})
+
+		It("responds with the deduplicator the data set resolves to", func() {
+			context.dataDeduplicatorFactory.NewOutputs = []dataDeduplicatorTest.NewOutput{{Deduplicator: deduplicator}}
+			deduplicator.NameOutputs = []string{"org.tidepool.deduplicator.device.deactivate.hash"}
+			deduplicator.VersionOutputs = []string{"1.1.0"}
+			dataServiceApiV1.DataSetsDeduplicatorGet(context)
+			Expect(context.errors).To(BeEmpty())
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			Expect(context.data).To(Equal(&dataServiceApiV1.DataSetDeduplicator{Name: "org.tidepool.deduplicator.device.deactivate.hash", Version: "1.1.0", Assigned: false}))
+			Expect(context.dataDeduplicatorFactory.NewInputs).To(Equal([]*dataTypesUpload.Upload{dataSet}))
+		})
+
+		It("responds with the deduplicator assigned to the data set", func() {
+			dataSet.Deduplicator = dataTest.RandomDeduplicatorDescriptor()
+			context.dataDeduplicatorFactory.NewOutputs = []dataDeduplicatorTest.NewOutput{{Deduplicator: deduplicator}}
+			deduplicator.NameOutputs = []string{*dataSet.Deduplicator.Name}
+			deduplicator.VersionOutputs = []string{*dataSet.Deduplicator.Version}
+			dataServiceApiV1.DataSetsDeduplicatorGet(context)
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			Expect(context.data).To(Equal(&dataServiceApiV1.DataSetDeduplicator{Name: *dataSet.Deduplicator.Name, Version: *dataSet.Deduplicator.Version, Assigned: true}))
+		})
+	})
+})
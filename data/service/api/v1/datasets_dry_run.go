@@ -0,0 +1,88 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ant0ine/go-json-rest/rest"
+
+	dataDeduplicator "github.com/tidepool-org/platform/data/deduplicator"
+	dataService "github.com/tidepool-org/platform/data/service"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	"github.com/tidepool-org/platform/request"
+)
+
+// DataSetDryRun reports, by datum type, what the deduplicator would have done to the existing data
+type DataSetDryRun struct {
+	Deduplicator *DataSetDeduplicator             `json:"deduplicator"`
+	Report       dataStoreDEPRECATED.DryRunReport `json:"report"`
+}
+
+// DryRunQuery holds the dry run query parameters; without a deduplicator name, the data set deduplicator is used
+type DryRunQuery struct {
+	DryRun           bool
+	DeduplicatorName string
+}
+
+func parseDryRunQuery(req *rest.Request) (*DryRunQuery, error) {
+	query := &DryRunQuery{}
+	if value := req.URL.Query().Get("dryRun"); value != "" {
+		var err error
+		if query.DryRun, err = strconv.ParseBool(value); err != nil {
+			return nil, request.ErrorParameterInvalid("dryRun")
+		}
+	}
+	if query.DeduplicatorName = req.URL.Query().Get("deduplicator"); query.DeduplicatorName != "" && !query.DryRun {
+		return nil, request.ErrorParameterInvalid("deduplicator")
+	}
+	return query, nil
+}
+
+// dataSetDryRun runs the deduplicator operation against a dry run data session, so that no data is modified, and
+// responds with what the operation would have done
+func dataSetDryRun(dataServiceContext dataService.Context, dataSet *dataTypesUpload.Upload, query *DryRunQuery, operation func(deduplicator dataDeduplicator.Deduplicator, session dataStoreDEPRECATED.DataSession) error) {
+	req := dataServiceContext.Request()
+	ctx := req.Context()
+
+	var deduplicator dataDeduplicator.Deduplicator
+	var err error
+	if query.DeduplicatorName != "" {
+		if deduplicator, err = dataServiceContext.DataDeduplicatorFactory().GetByName(query.DeduplicatorName); err != nil {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get deduplicator by name", err)
+			return
+		} else if deduplicator == nil {
+			request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, request.ErrorParameterInvalid("deduplicator"))
+			return
+		}
+	} else {
+		if deduplicator, err = dataServiceContext.DataDeduplicatorFactory().Get(dataSet); err != nil {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get deduplicator", err)
+			return
+		} else if deduplicator == nil {
+			dataServiceContext.RespondWithInternalServerFailure("Deduplicator not found")
+			return
+		}
+	}
+
+	session := dataServiceContext.DataDryRunSession()
+	if err = operation(deduplicator, session); err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to dry run deduplicator", err)
+		return
+	}
+
+	report, err := session.DryRunReport(ctx, dataSet)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get dry run report", err)
+		return
+	}
+
+	dataServiceContext.RespondWithStatusAndData(http.StatusOK, &DataSetDryRun{
+		Deduplicator: &DataSetDeduplicator{
+			Name:     deduplicator.Name(),
+			Version:  deduplicator.Version(),
+			Assigned: dataSet.HasDeduplicatorNameMatch(deduplicator.Name()),
+		},
+		Report: report,
+	})
+}
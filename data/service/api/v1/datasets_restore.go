@@ -0,0 +1,100 @@
+package v1
+
+import (
+	"net/http"
+
+	dataService "github.com/tidepool-org/platform/data/service"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+)
+
+// DataSetsRestore godoc
+// @Summary Restore a deleted DataSet
+// @Description Restore a deleted data set along with the data deleted with it, through the data set deduplicator.
+// @Description A data set cannot be restored once its deleted data is purged.
+// @ID platform-data-api-DataSetsRestore
+// @Produce json
+// @Param dataSetId path string true "dataSet ID"
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Security TidepoolAuthorization
+// @Security TidepoolRestrictedToken
+// @Success 200 {object} upload.Upload "Operation is a success"
+// @Failure 400 {object} service.Error "Data set id is missing"
+// @Failure 403 {object} service.Error "Auth token is not authorized for requested action"
+// @Failure 404 {object} service.Error "Data set with specified id not found"
+// @Failure 409 {object} service.Error "Data set with specified id is not deleted or is purged"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/data_sets/:dataSetId/restore [post]
+func DataSetsRestore(dataServiceContext dataService.Context) {
+	req := dataServiceContext.Request()
+	ctx := req.Context()
+
+	dataSetID := req.PathParam("dataSetId")
+	if dataSetID == "" {
+		dataServiceContext.RespondWithError(ErrorDataSetIDMissing())
+		return
+	}
+
+	dataSet, err := dataServiceContext.DataSession().GetDataSetByID(ctx, dataSetID)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get data set by id", err)
+		return
+	}
+	if dataSet == nil {
+		dataServiceContext.RespondWithError(ErrorDataSetIDNotFound(dataSetID))
+		return
+	}
+
+	targetUserID := dataSet.UserID
+	if targetUserID == nil || *targetUserID == "" {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get user id from data set")
+		return
+	}
+
+	permissions, err := dataServiceContext.PermissionClient().GetUserPermissions(req, *targetUserID)
+	if err != nil {
+		if request.IsErrorUnauthorized(err) {
+			dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		} else {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get user permissions", err)
+		}
+		return
+	}
+	if !permissions {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	if dataSet.IsPurged() {
+		dataServiceContext.RespondWithError(ErrorDataSetPurged(dataSetID))
+		return
+	}
+	if !dataSet.IsDeleted() {
+		dataServiceContext.RespondWithError(ErrorDataSetNotDeleted(dataSetID))
+		return
+	}
+
+	if deduplicator, getErr := dataServiceContext.DataDeduplicatorFactory().Get(dataSet); getErr != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get deduplicator", getErr)
+		return
+	} else if deduplicator == nil {
+		if err = dataServiceContext.DataSession().RestoreDataSet(ctx, dataSet); err != nil {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to restore data set", err)
+			return
+		}
+		if dataSet.Active {
+			if err = dataServiceContext.DataSession().ActivateDataSetData(ctx, dataSet, nil); err != nil {
+				dataServiceContext.RespondWithInternalServerFailure("Unable to activate data set data", err)
+				return
+			}
+		}
+	} else {
+		if err = deduplicator.Restore(ctx, dataServiceContext.DataSession(), dataSet); err != nil {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to restore", err)
+			return
+		}
+	}
+
+	dataServiceContext.RespondWithStatusAndData(http.StatusOK, dataSet)
+}
@@ -0,0 +1,115 @@
+package v1_test
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	dataDeduplicatorTest "github.com/tidepool-org/platform/data/deduplicator/test"
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataStoreDEPRECATEDTest "github.com/tidepool-org/platform/data/storeDEPRECATED/test"
+	dataTest "github.com/tidepool-org/platform/data/test"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/service"
+	userTest "github.com/tidepool-org/platform/user/test"
+)
+
+var _ = Describe("DataSetsRestore", func() {
+	var dataSet *dataTypesUpload.Upload
+	var context *TestContext
+	var deduplicator *dataDeduplicatorTest.Deduplicator
+
+	BeforeEach(func() {
+		dataSet = dataTypesUpload.New()
+		dataSet.UserID = pointer.FromString(userTest.RandomID())
+		dataSet.UploadID = pointer.FromString(dataTest.RandomSetID())
+		dataSet.DeletedTime = pointer.FromString(time.Now().Format(time.RFC3339Nano))
+		deduplicator = dataDeduplicatorTest.NewDeduplicator()
+		context = NewTestContext()
+		context.SetRequest(http.MethodPost, "/v1/data_sets/"+*dataSet.UploadID+"/restore", nil, map[string]string{"dataSetId": *dataSet.UploadID}, nil)
+		context.dataSession.GetDataSetByIDOutputs = []dataStoreDEPRECATEDTest.GetDataSetByIDOutput{{DataSet: dataSet}}
+	})
+
+	AfterEach(func() {
+		context.dataSession.Expectations()
+		context.dataDeduplicatorFactory.AssertOutputsEmpty()
+		deduplicator.AssertOutputsEmpty()
+		Expect(context.permissionClient.GetUserPermissionsOutputs).To(BeEmpty())
+	})
+
+	It("responds with not found if the data set does not exist", func() {
+		context.dataSession.GetDataSetByIDOutputs = []dataStoreDEPRECATEDTest.GetDataSetByIDOutput{{DataSet: nil}}
+		dataServiceApiV1.DataSetsRestore(context)
+		Expect(context.errors).To(Equal([]*service.Error{dataServiceApiV1.ErrorDataSetIDNotFound(*dataSet.UploadID)}))
+	})
+
+	It("responds with unauthorized if the caller has no permissions", func() {
+		context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: false}}
+		dataServiceApiV1.DataSetsRestore(context)
+		Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+		Expect(context.permissionClient.GetUserPermissionsInputs).To(Equal([]string{*dataSet.UserID}))
+		Expect(context.dataDeduplicatorFactory.GetInvocations).To(Equal(0))
+	})
+
+	It("responds with failure if the permissions cannot be retrieved", func() {
+		context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Error: errorsTest.RandomError()}}
+		dataServiceApiV1.DataSetsRestore(context)
+		Expect(context.failures).To(Equal([]string{"Unable to get user permissions"}))
+	})
+
+	Context("with permissions", func() {
+		BeforeEach(func() {
+			context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: true}}
+		})
+
+		It("responds with conflict if the data set is purged", func() {
+			dataSet.PurgedTime = pointer.FromString(time.Now().Format(time.RFC3339Nano))
+			dataServiceApiV1.DataSetsRestore(context)
+			Expect(context.errors).To(Equal([]*service.Error{dataServiceApiV1.ErrorDataSetPurged(*dataSet.UploadID)}))
+			Expect(context.dataDeduplicatorFactory.GetInvocations).To(Equal(0))
+		})
+
+		It("responds with conflict if the data set is not deleted", func() {
+			dataSet.DeletedTime = nil
+			dataServiceApiV1.DataSetsRestore(context)
+			Expect(context.errors).To(Equal([]*service.Error{dataServiceApiV1.ErrorDataSetNotDeleted(*dataSet.UploadID)}))
+			Expect(context.dataDeduplicatorFactory.GetInvocations).To(Equal(0))
+		})
+
+		It("restores the data set through the deduplicator", func() {
+			context.dataDeduplicatorFactory.GetOutputs = []dataDeduplicatorTest.GetOutput{{Deduplicator: deduplicator}}
+			deduplicator.RestoreOutputs = []error{nil}
+			dataServiceApiV1.DataSetsRestore(context)
+			Expect(context.errors).To(BeEmpty())
+			Expect(context.failures).To(BeEmpty())
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			Expect(context.data).To(Equal(dataSet))
+			Expect(deduplicator.RestoreInputs).To(HaveLen(1))
+			Expect(deduplicator.RestoreInputs[0].DataSet).To(Equal(dataSet))
+		})
+
+		It("responds with failure if the deduplicator cannot restore the data set", func() {
+			context.dataDeduplicatorFactory.GetOutputs = []dataDeduplicatorTest.GetOutput{{Deduplicator: deduplicator}}
+			deduplicator.RestoreOutputs = []error{errorsTest.RandomError()}
+			dataServiceApiV1.DataSetsRestore(context)
+			Expect(context.failures).To(Equal([]string{"Unable to restore"}))
+		})
+
+		It("restores and activates the data of an active data set without deduplicator", func() {
+			dataSet.Active = true
+			context.dataDeduplicatorFactory.GetOutputs = []dataDeduplicatorTest.GetOutput{{Deduplicator: nil}}
+			context.dataSession.RestoreDataSetOutputs = []error{nil}
+			context.dataSession.ActivateDataSetDataOutputs = []error{nil}
+			dataServiceApiV1.DataSetsRestore(context)
+			Expect(context.errors).To(BeEmpty())
+			Expect(context.failures).To(BeEmpty())
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			Expect(context.dataSession.RestoreDataSetInputs).To(HaveLen(1))
+			Expect(context.dataSession.ActivateDataSetDataInputs).To(HaveLen(1))
+		})
+	})
+})
@@ -0,0 +1,157 @@
+package v1
+
+import (
+	"context"
+
+	"github.com/tidepool-org/platform/data"
+	dataDeduplicator "github.com/tidepool-org/platform/data/deduplicator"
+	dataService "github.com/tidepool-org/platform/data/service"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/data/timeprocessing"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	"github.com/tidepool-org/platform/errors"
+	"github.com/tidepool-org/platform/log"
+)
+
+// processTimeProcessingBatches adds the data of the time processing batches of the data set. The raw data were
+// validated when sent, but a raw datum may no longer be valid once processed with the time changes of the later
+// batches, so all the batches are first processed without adding any data, and if any raw datum is rejected, no data
+// is added and the number of the rejected raw data is returned. A batch already processed by a previous close that
+// failed is only processed again for its time changes, so that its data is not added twice.
+func processTimeProcessingBatches(ctx context.Context, dataServiceContext dataService.Context, dataSet *dataTypesUpload.Upload) (int, error) {
+	session := dataServiceContext.DataSession()
+
+	ids, err := session.ListTimeProcessingBatchIDs(ctx, dataSet)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to list time processing batch ids")
+	} else if len(ids) == 0 {
+		return 0, nil
+	}
+
+	rejected, err := forEachTimeProcessingBatch(ctx, session, dataSet, ids, func(batch *dataStoreDEPRECATED.TimeProcessingBatch, datumArray data.Data) error {
+		return nil
+	})
+	if err != nil || rejected > 0 {
+		return rejected, err
+	}
+
+	deduplicator, err := dataServiceContext.DataDeduplicatorFactory().Get(dataSet)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to get deduplicator")
+	} else if deduplicator == nil {
+		return 0, errors.New("deduplicator not found")
+	}
+
+	if _, err = forEachTimeProcessingBatch(ctx, session, dataSet, ids, func(batch *dataStoreDEPRECATED.TimeProcessingBatch, datumArray data.Data) error {
+		if len(datumArray) > 0 {
+			if err := deduplicator.AddData(ctx, session, dataSet, datumArray); err != nil {
+				return errors.Wrap(err, "unable to add data")
+			}
+		}
+		if err := session.SetTimeProcessingBatchProcessed(ctx, batch.ID); err != nil {
+			return errors.Wrap(err, "unable to set time processing batch processed")
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	return 0, session.DestroyTimeProcessingBatches(ctx, dataSet)
+}
+
+// rejectedTimeProcessingBatchesData processes all the time processing batches of the data set without adding any data
+// and returns the number of the rejected raw data, so that a dry run of the close reports the rejection as the close
+// would.
+func rejectedTimeProcessingBatchesData(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload) (int, error) {
+	ids, err := session.ListTimeProcessingBatchIDs(ctx, dataSet)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to list time processing batch ids")
+	} else if len(ids) == 0 {
+		return 0, nil
+	}
+
+	return forEachTimeProcessingBatch(ctx, session, dataSet, ids, func(batch *dataStoreDEPRECATED.TimeProcessingBatch, datumArray data.Data) error {
+		return nil
+	})
+}
+
+// dryRunTimeProcessingBatches adds the data of the time processing batches of the data set to the dry run session,
+// without marking the batches processed, so that the dry run of the close considers the data the close would add. The
+// raw data must have been checked with rejectedTimeProcessingBatchesData first.
+func dryRunTimeProcessingBatches(ctx context.Context, session dataStoreDEPRECATED.DataSession, deduplicator dataDeduplicator.Deduplicator, dataSet *dataTypesUpload.Upload) error {
+	ids, err := session.ListTimeProcessingBatchIDs(ctx, dataSet)
+	if err != nil {
+		return errors.Wrap(err, "unable to list time processing batch ids")
+	} else if len(ids) == 0 {
+		return nil
+	}
+
+	rejected, err := forEachTimeProcessingBatch(ctx, session, dataSet, ids, func(batch *dataStoreDEPRECATED.TimeProcessingBatch, datumArray data.Data) error {
+		if len(datumArray) > 0 {
+			if err := deduplicator.AddData(ctx, session, dataSet, datumArray); err != nil {
+				return errors.Wrap(err, "unable to add data")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	} else if rejected > 0 {
+		return errors.Newf("%d time processing batch data not valid", rejected)
+	}
+	return nil
+}
+
+// forEachTimeProcessingBatch calls the function with each time processing batch of the data set not yet processed
+// and its data, processed together from the last batch to the first, so that the time changes of a batch correct the
+// data of the previous batches. A raw datum not valid once processed is logged and rejected, and the function is not
+// called for its batch. It returns the number of the rejected raw data.
+func forEachTimeProcessingBatch(ctx context.Context, session dataStoreDEPRECATED.DataSession, dataSet *dataTypesUpload.Upload, ids []string, function func(batch *dataStoreDEPRECATED.TimeProcessingBatch, datumArray data.Data) error) (int, error) {
+	logger := log.LoggerFromContext(ctx)
+	processor := timeprocessing.NewProcessor(dataSet)
+
+	rejected := 0
+	for index := len(ids) - 1; index >= 0; index-- {
+		batch, err := session.GetTimeProcessingBatch(ctx, ids[index])
+		if err != nil {
+			return rejected, errors.Wrap(err, "unable to get time processing batch")
+		} else if batch == nil {
+			continue
+		}
+
+		if processor != nil {
+			if err = processor.Process(batch.RawData); err != nil {
+				return rejected, errors.Wrap(err, "unable to process time processing batch")
+			}
+		}
+		if batch.Processed {
+			continue
+		}
+
+		batchRejected := 0
+		datumArray := data.Data{}
+		for reference := range batch.RawData {
+			referenceDatumArray, parseErr := parseDatumArrayWithReference(batch.RawData, reference)
+			if parseErr != nil {
+				logger.WithFields(log.Fields{"id": batch.ID, "reference": reference}).WithError(parseErr).Error("Processed raw datum is not valid")
+				batchRejected++
+				continue
+			}
+			for _, datum := range referenceDatumArray {
+				datum.SetUserID(dataSet.UserID)
+				datum.SetDataSetID(dataSet.UploadID)
+			}
+			datumArray = append(datumArray, referenceDatumArray...)
+		}
+
+		if batchRejected > 0 {
+			rejected += batchRejected
+			continue
+		}
+		if err = function(batch, datumArray); err != nil {
+			return rejected, err
+		}
+	}
+
+	return rejected, nil
+}
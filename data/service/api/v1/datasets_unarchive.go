@@ -0,0 +1,90 @@
+package v1
+
+import (
+	"net/http"
+
+	dataService "github.com/tidepool-org/platform/data/service"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+)
+
+// DataSetsUnarchive godoc
+// @Summary Unarchive the data archived by a DataSet
+// @Description Unarchive all the data of other data sets archived by the data set, through the data set deduplicator,
+// @Description which then archives any data set data duplicating the unarchived data.
+// @Description The data archived by a data set cannot be unarchived once the data set is purged.
+// @ID platform-data-api-DataSetsUnarchive
+// @Param dataSetId path string true "dataSet ID"
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Security TidepoolAuthorization
+// @Security TidepoolRestrictedToken
+// @Success 200 {object} EmptyBody "Operation is a success"
+// @Failure 400 {object} service.Error "Data set id is missing"
+// @Failure 403 {object} service.Error "Auth token is not authorized for requested action"
+// @Failure 404 {object} service.Error "Data set with specified id not found"
+// @Failure 409 {object} service.Error "Data set with specified id is purged"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/data_sets/:dataSetId/unarchive [post]
+func DataSetsUnarchive(dataServiceContext dataService.Context) {
+	req := dataServiceContext.Request()
+	ctx := req.Context()
+
+	dataSetID := req.PathParam("dataSetId")
+	if dataSetID == "" {
+		dataServiceContext.RespondWithError(ErrorDataSetIDMissing())
+		return
+	}
+
+	dataSet, err := dataServiceContext.DataSession().GetDataSetByID(ctx, dataSetID)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get data set by id", err)
+		return
+	}
+	if dataSet == nil {
+		dataServiceContext.RespondWithError(ErrorDataSetIDNotFound(dataSetID))
+		return
+	}
+
+	targetUserID := dataSet.UserID
+	if targetUserID == nil || *targetUserID == "" {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get user id from data set")
+		return
+	}
+
+	permissions, err := dataServiceContext.PermissionClient().GetUserPermissions(req, *targetUserID)
+	if err != nil {
+		if request.IsErrorUnauthorized(err) {
+			dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		} else {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get user permissions", err)
+		}
+		return
+	}
+	if !permissions {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	if dataSet.IsPurged() {
+		dataServiceContext.RespondWithError(ErrorDataSetPurged(dataSetID))
+		return
+	}
+
+	if deduplicator, getErr := dataServiceContext.DataDeduplicatorFactory().Get(dataSet); getErr != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get deduplicator", getErr)
+		return
+	} else if deduplicator == nil {
+		if err = dataServiceContext.DataSession().UnarchiveDeviceDataArchivedByDataSet(ctx, dataSet); err != nil {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to unarchive device data archived by data set", err)
+			return
+		}
+	} else {
+		if err = deduplicator.Unarchive(ctx, dataServiceContext.DataSession(), dataSet); err != nil {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to unarchive", err)
+			return
+		}
+	}
+
+	dataServiceContext.RespondWithStatusAndData(http.StatusOK, struct{}{})
+}
@@ -4,7 +4,9 @@ import (
 	"net/http"
 
 	"github.com/tidepool-org/platform/data"
+	dataDeduplicator "github.com/tidepool-org/platform/data/deduplicator"
 	dataService "github.com/tidepool-org/platform/data/service"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
 	"github.com/tidepool-org/platform/data/types/upload"
 	"github.com/tidepool-org/platform/pointer"
 	"github.com/tidepool-org/platform/request"
@@ -13,20 +15,26 @@ import (
 
 // DataSetsUpdate godoc
 // @Summary Update a data sets
+// @Description Closing a data set first adds its data sent with only a device time under utc-bootstrapping time
+// @Description processing, whose time processing is deferred until then so that all its time changes apply. Should any
+// @Description of that data not be valid once time processed, none of it is added and the data set is not closed
 // @ID platform-data-api-DataSetsUpdate
 // @Accept json
 // @Produce json
 // @Param dataSetID path string true "dataSet ID"
 // @Param dataSetUpdate body data.DataSetUpdate true "The dataSet to update"
+// @Param dryRun query bool false "True to report what the deduplicator would do to the existing data when closing, without updating the data set"
+// @Param deduplicator query string false "With dryRun, the name of the deduplicator to dry run instead of the one of the data set"
 // @Security TidepoolSessionToken
 // @Security TidepoolServiceSecret
 // @Security TidepoolAuthorization
 // @Security TidepoolRestrictedToken
 // @Success 200 {object} upload.Upload "Operation is a success"
-// @Failure 400 {object} service.Error "Data set id is missing"
+// @Success 200 {object} DataSetDryRun "Operation is a success, with dry run requested"
+// @Failure 400 {object} service.Error "Data set id is missing, or dryRun or deduplicator parameter is invalid"
 // @Failure 403 {object} service.Error "Auth token is not authorized for requested action"
 // @Failure 404 {object} service.Error "Data set with specified id not found"
-// @Failure 409 {object} service.Error "Data set with specified id is closed for new data"
+// @Failure 409 {object} service.Error "Data set with specified id is closed for new data, or has data not valid once time processed"
 // @Failure 500 {object} service.Error "Unable to perform the operation"
 // @Router /v1/datasets/:dataSetId [put]
 func DataSetsUpdate(dataServiceContext dataService.Context) {
@@ -40,6 +48,12 @@ func DataSetsUpdate(dataServiceContext dataService.Context) {
 		return
 	}
 
+	dryRunQuery, err := parseDryRunQuery(req)
+	if err != nil {
+		request.MustNewResponder(res, req).Error(http.StatusBadRequest, err)
+		return
+	}
+
 	dataSet, err := dataServiceContext.DataSession().GetDataSetByID(ctx, dataSetID)
 	if err != nil {
 		dataServiceContext.RespondWithInternalServerFailure("Unable to get data set by id", err)
@@ -84,6 +98,40 @@ func DataSetsUpdate(dataServiceContext dataService.Context) {
 		update.State = pointer.FromString(data.DataSetStateClosed)
 	}
 
+	if dryRunQuery.DryRun {
+		if update.State != nil && *update.State == "closed" {
+			if rejected, processErr := rejectedTimeProcessingBatchesData(ctx, dataServiceContext.DataSession(), dataSet); processErr != nil {
+				dataServiceContext.RespondWithInternalServerFailure("Unable to process time processing batches", processErr)
+				return
+			} else if rejected > 0 {
+				dataServiceContext.RespondWithError(ErrorDataSetTimeProcessingDataNotValid(dataSetID, rejected))
+				return
+			}
+		}
+		dataSetDryRun(dataServiceContext, dataSet, dryRunQuery, func(deduplicator dataDeduplicator.Deduplicator, session dataStoreDEPRECATED.DataSession) error {
+			if update.State != nil && *update.State == "closed" {
+				if err := dryRunTimeProcessingBatches(ctx, session, deduplicator, dataSet); err != nil {
+					return err
+				}
+				return deduplicator.Close(ctx, session, dataSet)
+			}
+			return nil
+		})
+		return
+	}
+
+	// The data whose time processing is deferred are added before the data set is closed, so that the data set is
+	// still open should it fail
+	if update.State != nil && *update.State == "closed" {
+		if rejected, processErr := processTimeProcessingBatches(ctx, dataServiceContext, dataSet); processErr != nil {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to process time processing batches", processErr)
+			return
+		} else if rejected > 0 {
+			dataServiceContext.RespondWithError(ErrorDataSetTimeProcessingDataNotValid(dataSetID, rejected))
+			return
+		}
+	}
+
 	dataSet, err = dataServiceContext.DataSession().UpdateDataSet(ctx, dataSetID, update)
 	if err != nil {
 		dataServiceContext.RespondWithInternalServerFailure("Unable to update data set", err)
@@ -1,8 +1,146 @@
 package v1_test
 
 import (
+	"net/http"
+
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	dataDeduplicatorTest "github.com/tidepool-org/platform/data/deduplicator/test"
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	dataStoreDEPRECATEDTest "github.com/tidepool-org/platform/data/storeDEPRECATED/test"
+	dataTest "github.com/tidepool-org/platform/data/test"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/service"
+	userTest "github.com/tidepool-org/platform/user/test"
 )
 
 var _ = Describe("DataSetsUpdate", func() {
+	Context("with time processing batches", func() {
+		var dataSet *dataTypesUpload.Upload
+		var context *TestContext
+		var deduplicator *dataDeduplicatorTest.Deduplicator
+		var secondRawData []interface{}
+
+		newRawDatum := func(deviceTime string) map[string]interface{} {
+			return map[string]interface{}{
+				"type":       "cbg",
+				"deviceId":   *dataSet.DeviceID,
+				"deviceTime": deviceTime,
+				"units":      "mmol/L",
+				"value":      5.5,
+			}
+		}
+
+		// Each get returns a new batch, as the store does, since processing changes the raw data
+		newBatchOutputs := func() []dataStoreDEPRECATEDTest.GetTimeProcessingBatchOutput {
+			return []dataStoreDEPRECATEDTest.GetTimeProcessingBatchOutput{
+				{Batch: &dataStoreDEPRECATED.TimeProcessingBatch{ID: "second", RawData: secondRawData}},
+				{Batch: &dataStoreDEPRECATED.TimeProcessingBatch{ID: "first", RawData: []interface{}{newRawDatum("2020-01-01T08:00:00")}}},
+			}
+		}
+
+		BeforeEach(func() {
+			dataSet = dataTypesUpload.New()
+			dataSet.UserID = pointer.FromString(userTest.RandomID())
+			dataSet.UploadID = pointer.FromString(dataTest.RandomSetID())
+			dataSet.DeviceID = pointer.FromString(dataTest.NewDeviceID())
+			dataSet.State = pointer.FromString("open")
+			dataSet.TimeProcessing = pointer.FromString(dataTypesUpload.TimeProcessingUTCBootstrapping)
+			dataSet.DeviceTime = pointer.FromString("2020-01-01T10:00:00")
+			dataSet.ComputerTime = pointer.FromString("2020-01-01T10:00:00")
+			dataSet.TimeZoneOffset = pointer.FromInt(0)
+			dataSet.TimeZoneName = pointer.FromString("UTC")
+			secondRawData = []interface{}{newRawDatum("2020-01-01T09:00:00")}
+			deduplicator = dataDeduplicatorTest.NewDeduplicator()
+			context = NewTestContext()
+			context.SetRequest(http.MethodPut, "/v1/datasets/"+*dataSet.UploadID, nil, map[string]string{"dataSetId": *dataSet.UploadID}, nil)
+			context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: true}}
+			context.dataSession.GetDataSetByIDOutputs = []dataStoreDEPRECATEDTest.GetDataSetByIDOutput{{DataSet: dataSet}}
+			context.dataSession.ListTimeProcessingBatchIDsOutputs = []dataStoreDEPRECATEDTest.ListTimeProcessingBatchIDsOutput{{IDs: []string{"first", "second"}}}
+			context.dataSession.GetTimeProcessingBatchOutputs = newBatchOutputs()
+		})
+
+		AfterEach(func() {
+			context.dataSession.Expectations()
+			deduplicator.AssertOutputsEmpty()
+		})
+
+		It("adds the data of the batches, from the last batch to the first, then closes the data set", func() {
+			context.dataSession.GetTimeProcessingBatchOutputs = append(context.dataSession.GetTimeProcessingBatchOutputs, newBatchOutputs()...)
+			context.dataSession.SetTimeProcessingBatchProcessedOutputs = []error{nil, nil}
+			context.dataSession.DestroyTimeProcessingBatchesOutputs = []error{nil}
+			context.dataSession.UpdateDataSetOutputs = []dataStoreDEPRECATEDTest.UpdateDataSetOutput{{DataSet: dataSet}}
+			context.dataDeduplicatorFactory.GetOutputs = []dataDeduplicatorTest.GetOutput{{Deduplicator: deduplicator}, {Deduplicator: deduplicator}}
+			deduplicator.AddDataOutputs = []error{nil, nil}
+			deduplicator.CloseOutputs = []error{nil}
+			dataServiceApiV1.DataSetsUpdate(context)
+			Expect(context.errors).To(BeEmpty())
+			Expect(context.failures).To(BeEmpty())
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			Expect(deduplicator.AddDataInputs).To(HaveLen(2))
+			Expect(deduplicator.AddDataInputs[0].DataSetData).To(HaveLen(1))
+			Expect(*deduplicator.AddDataInputs[0].DataSetData[0].(*dataTypesBloodGlucoseContinuous.Continuous).Time).To(Equal("2020-01-01T09:00:00Z"))
+			Expect(deduplicator.AddDataInputs[1].DataSetData).To(HaveLen(1))
+			Expect(*deduplicator.AddDataInputs[1].DataSetData[0].(*dataTypesBloodGlucoseContinuous.Continuous).Time).To(Equal("2020-01-01T08:00:00Z"))
+			Expect(context.dataSession.SetTimeProcessingBatchProcessedInputs).To(HaveLen(2))
+			Expect(context.dataSession.SetTimeProcessingBatchProcessedInputs[0].ID).To(Equal("second"))
+			Expect(context.dataSession.SetTimeProcessingBatchProcessedInputs[1].ID).To(Equal("first"))
+			Expect(deduplicator.CloseInputs).To(HaveLen(1))
+		})
+
+		When("a raw datum is not valid once processed", func() {
+			BeforeEach(func() {
+				invalidRawDatum := newRawDatum("2020-01-01T09:30:00")
+				delete(invalidRawDatum, "value")
+				secondRawData = append(secondRawData, invalidRawDatum)
+				context.dataSession.GetTimeProcessingBatchOutputs = newBatchOutputs()
+			})
+
+			It("adds no data and does not close the data set", func() {
+				dataServiceApiV1.DataSetsUpdate(context)
+				Expect(context.failures).To(BeEmpty())
+				Expect(context.errors).To(Equal([]*service.Error{dataServiceApiV1.ErrorDataSetTimeProcessingDataNotValid(*dataSet.UploadID, 1)}))
+				Expect(context.dataDeduplicatorFactory.GetInvocations).To(Equal(0))
+				Expect(context.dataSession.UpdateDataSetInputs).To(BeEmpty())
+			})
+
+			It("reports the rejection of a dry run as the close would", func() {
+				context.SetRequest(http.MethodPut, "/v1/datasets/"+*dataSet.UploadID+"?dryRun=true", nil, map[string]string{"dataSetId": *dataSet.UploadID}, nil)
+				dataServiceApiV1.DataSetsUpdate(context)
+				Expect(context.failures).To(BeEmpty())
+				Expect(context.errors).To(Equal([]*service.Error{dataServiceApiV1.ErrorDataSetTimeProcessingDataNotValid(*dataSet.UploadID, 1)}))
+				Expect(context.dataDeduplicatorFactory.GetInvocations).To(Equal(0))
+			})
+		})
+
+		When("the close is a dry run", func() {
+			BeforeEach(func() {
+				context.SetRequest(http.MethodPut, "/v1/datasets/"+*dataSet.UploadID+"?dryRun=true", nil, map[string]string{"dataSetId": *dataSet.UploadID}, nil)
+			})
+
+			It("adds the data of the batches to the dry run session before the close, without processing the batches", func() {
+				context.dataSession.ListTimeProcessingBatchIDsOutputs = append(context.dataSession.ListTimeProcessingBatchIDsOutputs, dataStoreDEPRECATEDTest.ListTimeProcessingBatchIDsOutput{IDs: []string{"first", "second"}})
+				context.dataSession.GetTimeProcessingBatchOutputs = append(context.dataSession.GetTimeProcessingBatchOutputs, newBatchOutputs()...)
+				context.dataDeduplicatorFactory.GetOutputs = []dataDeduplicatorTest.GetOutput{{Deduplicator: deduplicator}}
+				deduplicator.NameOutputs = []string{"org.tidepool.deduplicator.none", "org.tidepool.deduplicator.none"}
+				deduplicator.VersionOutputs = []string{"1.0.0"}
+				deduplicator.AddDataOutputs = []error{nil, nil}
+				deduplicator.CloseOutputs = []error{nil}
+				dataServiceApiV1.DataSetsUpdate(context)
+				Expect(context.errors).To(BeEmpty())
+				Expect(context.failures).To(BeEmpty())
+				Expect(context.statusCode).To(Equal(http.StatusOK))
+				Expect(deduplicator.AddDataInputs).To(HaveLen(2))
+				Expect(*deduplicator.AddDataInputs[0].DataSetData[0].(*dataTypesBloodGlucoseContinuous.Continuous).Time).To(Equal("2020-01-01T09:00:00Z"))
+				Expect(*deduplicator.AddDataInputs[1].DataSetData[0].(*dataTypesBloodGlucoseContinuous.Continuous).Time).To(Equal("2020-01-01T08:00:00Z"))
+				Expect(deduplicator.CloseInputs).To(HaveLen(1))
+				Expect(context.dataSession.SetTimeProcessingBatchProcessedInputs).To(BeEmpty())
+				Expect(context.dataSession.UpdateDataSetInputs).To(BeEmpty())
+			})
+		})
+	})
 })
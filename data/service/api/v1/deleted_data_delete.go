@@ -0,0 +1,55 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/tidepool-org/platform/data"
+	dataService "github.com/tidepool-org/platform/data/service"
+	"github.com/tidepool-org/platform/page"
+	"github.com/tidepool-org/platform/request"
+)
+
+// DeletedDataDelete godoc
+// @Summary Purge deleted data
+// @Description Destroy a page of the data deleted before the given time, oldest deleted first, whether deleted on its own
+// @Description from a data set or with a deleted data set. A deleted data set whose data is destroyed is marked as purged,
+// @Description as it can no longer be restored. Only services (eg. not users) can purge deleted data
+// @ID platform-data-api-DeletedDataDelete
+// @Produce json
+// @Param deletedBefore query string true "Only the data deleted before this time (RFC3339Nano)"
+// @Param page query int false "When using pagination, page number" default(0)
+// @Param size query int false "When using pagination, number of elements by page, 1<size<1000" minimum(1) maximum(1000) default(100)
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Success 200 {object} data.DeletedDataDestroyResult "Count of the destroyed deleted data"
+// @Failure 400 {object} service.Error "Bad request (missing or invalid query parameter)"
+// @Failure 403 {object} service.Error "Forbidden: caller is not a service"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/deleted_data [delete]
+func DeletedDataDelete(dataServiceContext dataService.Context) {
+	res := dataServiceContext.Response()
+	req := dataServiceContext.Request()
+	dataClient := dataServiceContext.DataClient()
+
+	responder := request.MustNewResponder(res, req)
+
+	if details := request.DetailsFromContext(req.Context()); !details.IsService() {
+		responder.Error(http.StatusForbidden, request.ErrorUnauthorized())
+		return
+	}
+
+	filter := data.NewDeletedDataFilter()
+	pagination := page.NewPagination()
+	if err := request.DecodeRequestQuery(req.Request, filter, pagination); err != nil {
+		responder.Error(http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := dataClient.DestroyDeletedData(req.Context(), filter, pagination)
+	if err != nil {
+		responder.Error(http.StatusInternalServerError, err)
+		return
+	}
+
+	responder.Data(http.StatusOK, result)
+}
@@ -0,0 +1,68 @@
+package v1_test
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	dataClientTest "github.com/tidepool-org/platform/data/client/test"
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/request"
+	userTest "github.com/tidepool-org/platform/user/test"
+)
+
+var _ = Describe("DeletedDataDelete", func() {
+	var context *TestContext
+
+	setRequest := func(query string, details request.Details) {
+		context.SetRequest(http.MethodDelete, "/v1/deleted_data"+query, nil, nil, details)
+	}
+
+	BeforeEach(func() {
+		context = NewTestContext()
+	})
+
+	AfterEach(func() {
+		context.dataClient.AssertOutputsEmpty()
+	})
+
+	It("responds with forbidden if the caller is not a service", func() {
+		setRequest("?deletedBefore=2020-01-01T00:00:00Z", request.NewDetails(request.MethodSessionToken, userTest.RandomID(), "token"))
+		dataServiceApiV1.DeletedDataDelete(context)
+		Expect(context.ResponseStatusCode()).To(Equal(http.StatusForbidden))
+		Expect(context.dataClient.DestroyDeletedDataInvocations).To(Equal(0))
+	})
+
+	Context("as a service", func() {
+		serviceDetails := request.NewDetails(request.MethodServiceSecret, "", "")
+
+		It("responds with bad request if the deleted before time is missing", func() {
+			setRequest("", serviceDetails)
+			dataServiceApiV1.DeletedDataDelete(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataClient.DestroyDeletedDataInvocations).To(Equal(0))
+		})
+
+		It("responds with failure if the deleted data cannot be destroyed", func() {
+			setRequest("?deletedBefore=2020-01-01T00:00:00Z", serviceDetails)
+			context.dataClient.DestroyDeletedDataOutputs = []dataClientTest.DestroyDeletedDataOutput{{Error: errorsTest.RandomError()}}
+			dataServiceApiV1.DeletedDataDelete(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusInternalServerError))
+		})
+
+		It("responds with the count of the destroyed deleted data", func() {
+			setRequest("?deletedBefore=2020-01-01T00:00:00Z&size=10", serviceDetails)
+			context.dataClient.DestroyDeletedDataOutputs = []dataClientTest.DestroyDeletedDataOutput{{Result: &data.DeletedDataDestroyResult{Destroyed: 3}}}
+			dataServiceApiV1.DeletedDataDelete(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusOK))
+			Expect(context.ResponseBody()).To(MatchJSON(`{"destroyed": 3}`))
+			Expect(context.dataClient.DestroyDeletedDataInputs).To(HaveLen(1))
+			Expect(*context.dataClient.DestroyDeletedDataInputs[0].Filter.DeletedBefore).To(BeTemporally("==", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+			Expect(context.dataClient.DestroyDeletedDataInputs[0].Pagination.Size).To(Equal(10))
+		})
+	})
+})
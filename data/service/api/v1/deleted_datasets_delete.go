@@ -0,0 +1,65 @@
+package v1
+
+import (
+	"net/http"
+
+	dataService "github.com/tidepool-org/platform/data/service"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+)
+
+// DeletedDataSetsDelete godoc
+// @Summary Purge a deleted DataSet
+// @Description Destroy all the data deleted with a deleted data set, after which the data set can no longer be restored.
+// @Description Only services (eg. not users) can purge a deleted data set
+// @ID platform-data-api-DeletedDataSetsDelete
+// @Produce json
+// @Param dataSetId path string true "dataSet ID"
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Success 200 {object} upload.Upload "Operation is a success"
+// @Failure 400 {object} service.Error "Data set id is missing"
+// @Failure 403 {object} service.Error "Forbidden: caller is not a service"
+// @Failure 404 {object} service.Error "Data set with specified id not found"
+// @Failure 409 {object} service.Error "Data set with specified id is not deleted"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/deleted_data_sets/:dataSetId [delete]
+func DeletedDataSetsDelete(dataServiceContext dataService.Context) {
+	req := dataServiceContext.Request()
+	ctx := req.Context()
+
+	dataSetID := req.PathParam("dataSetId")
+	if dataSetID == "" {
+		dataServiceContext.RespondWithError(ErrorDataSetIDMissing())
+		return
+	}
+
+	if details := request.DetailsFromContext(ctx); !details.IsService() {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	dataSet, err := dataServiceContext.DataSession().GetDataSetByID(ctx, dataSetID)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get data set by id", err)
+		return
+	}
+	if dataSet == nil {
+		dataServiceContext.RespondWithError(ErrorDataSetIDNotFound(dataSetID))
+		return
+	}
+
+	if !dataSet.IsDeleted() {
+		dataServiceContext.RespondWithError(ErrorDataSetNotDeleted(dataSetID))
+		return
+	}
+
+	if !dataSet.IsPurged() {
+		if err = dataServiceContext.DataSession().DestroyDeletedDataSetData(ctx, dataSet, nil); err != nil {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to destroy deleted data set data", err)
+			return
+		}
+	}
+
+	dataServiceContext.RespondWithStatusAndData(http.StatusOK, dataSet)
+}
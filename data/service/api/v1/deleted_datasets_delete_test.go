@@ -0,0 +1,90 @@
+package v1_test
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataStoreDEPRECATEDTest "github.com/tidepool-org/platform/data/storeDEPRECATED/test"
+	dataTest "github.com/tidepool-org/platform/data/test"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+	userTest "github.com/tidepool-org/platform/user/test"
+)
+
+var _ = Describe("DeletedDataSetsDelete", func() {
+	var dataSet *dataTypesUpload.Upload
+	var context *TestContext
+
+	setRequest := func(details request.Details) {
+		context.SetRequest(http.MethodDelete, "/v1/deleted_data_sets/"+*dataSet.UploadID, nil, map[string]string{"dataSetId": *dataSet.UploadID}, details)
+	}
+
+	BeforeEach(func() {
+		dataSet = dataTypesUpload.New()
+		dataSet.UserID = pointer.FromString(userTest.RandomID())
+		dataSet.UploadID = pointer.FromString(dataTest.RandomSetID())
+		dataSet.DeletedTime = pointer.FromString(time.Now().Format(time.RFC3339Nano))
+		context = NewTestContext()
+		setRequest(request.NewDetails(request.MethodServiceSecret, "", ""))
+	})
+
+	AfterEach(func() {
+		context.dataSession.Expectations()
+	})
+
+	It("responds with unauthorized if the caller is not a service", func() {
+		setRequest(request.NewDetails(request.MethodSessionToken, *dataSet.UserID, "token"))
+		dataServiceApiV1.DeletedDataSetsDelete(context)
+		Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+		Expect(context.dataSession.GetDataSetByIDInputs).To(BeEmpty())
+	})
+
+	It("responds with not found if the data set does not exist", func() {
+		context.dataSession.GetDataSetByIDOutputs = []dataStoreDEPRECATEDTest.GetDataSetByIDOutput{{DataSet: nil}}
+		dataServiceApiV1.DeletedDataSetsDelete(context)
+		Expect(context.errors).To(Equal([]*service.Error{dataServiceApiV1.ErrorDataSetIDNotFound(*dataSet.UploadID)}))
+	})
+
+	Context("with a data set", func() {
+		BeforeEach(func() {
+			context.dataSession.GetDataSetByIDOutputs = []dataStoreDEPRECATEDTest.GetDataSetByIDOutput{{DataSet: dataSet}}
+		})
+
+		It("responds with conflict if the data set is not deleted", func() {
+			dataSet.DeletedTime = nil
+			dataServiceApiV1.DeletedDataSetsDelete(context)
+			Expect(context.errors).To(Equal([]*service.Error{dataServiceApiV1.ErrorDataSetNotDeleted(*dataSet.UploadID)}))
+			Expect(context.dataSession.DestroyDeletedDataSetDataInputs).To(BeEmpty())
+		})
+
+		It("responds with failure if the deleted data set data cannot be destroyed", func() {
+			context.dataSession.DestroyDeletedDataSetDataOutputs = []error{errorsTest.RandomError()}
+			dataServiceApiV1.DeletedDataSetsDelete(context)
+			Expect(context.failures).To(Equal([]string{"Unable to destroy deleted data set data"}))
+		})
+
+		It("destroys all the deleted data set data", func() {
+			context.dataSession.DestroyDeletedDataSetDataOutputs = []error{nil}
+			dataServiceApiV1.DeletedDataSetsDelete(context)
+			Expect(context.errors).To(BeEmpty())
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			Expect(context.data).To(Equal(dataSet))
+			Expect(context.dataSession.DestroyDeletedDataSetDataInputs).To(HaveLen(1))
+			Expect(context.dataSession.DestroyDeletedDataSetDataInputs[0].Selectors).To(BeNil())
+		})
+
+		It("does not destroy the data of a data set already purged", func() {
+			dataSet.PurgedTime = pointer.FromString(time.Now().Format(time.RFC3339Nano))
+			dataServiceApiV1.DeletedDataSetsDelete(context)
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			Expect(context.dataSession.DestroyDeletedDataSetDataInputs).To(BeEmpty())
+		})
+	})
+})
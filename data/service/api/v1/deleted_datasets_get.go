@@ -0,0 +1,54 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/tidepool-org/platform/data"
+	dataService "github.com/tidepool-org/platform/data/service"
+	"github.com/tidepool-org/platform/page"
+	"github.com/tidepool-org/platform/request"
+)
+
+// DeletedDataSetsGet godoc
+// @Summary List the deleted datasets
+// @Description List the deleted data sets whose deleted data is not yet purged, oldest deleted first.
+// @Description Only services (eg. not users) can list the deleted data sets
+// @ID platform-data-api-DeletedDataSetsGet
+// @Produce json
+// @Param deletedBefore query string false "Only the data sets deleted before this time (RFC3339Nano)"
+// @Param page query int false "When using pagination, page number" default(0)
+// @Param size query int false "When using pagination, number of elements by page, 1<size<1000" minimum(1) maximum(1000) default(100)
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Success 200 {array} data.DataSet "Array of data sets"
+// @Failure 400 {object} service.Error "Bad request (invalid query parameter)"
+// @Failure 403 {object} service.Error "Forbidden: caller is not a service"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/deleted_data_sets [get]
+func DeletedDataSetsGet(dataServiceContext dataService.Context) {
+	res := dataServiceContext.Response()
+	req := dataServiceContext.Request()
+	dataClient := dataServiceContext.DataClient()
+
+	responder := request.MustNewResponder(res, req)
+
+	if details := request.DetailsFromContext(req.Context()); !details.IsService() {
+		responder.Error(http.StatusForbidden, request.ErrorUnauthorized())
+		return
+	}
+
+	filter := data.NewDeletedDataSetFilter()
+	pagination := page.NewPagination()
+	if err := request.DecodeRequestQuery(req.Request, filter, pagination); err != nil {
+		responder.Error(http.StatusBadRequest, err)
+		return
+	}
+
+	dataSets, err := dataClient.ListDeletedDataSets(req.Context(), filter, pagination)
+	if err != nil {
+		responder.Error(http.StatusInternalServerError, err)
+		return
+	}
+
+	responder.Data(http.StatusOK, dataSets)
+}
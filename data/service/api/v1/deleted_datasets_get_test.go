@@ -0,0 +1,71 @@
+package v1_test
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	dataClientTest "github.com/tidepool-org/platform/data/client/test"
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataTest "github.com/tidepool-org/platform/data/test"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/request"
+	userTest "github.com/tidepool-org/platform/user/test"
+)
+
+var _ = Describe("DeletedDataSetsGet", func() {
+	var context *TestContext
+
+	setRequest := func(query string, details request.Details) {
+		context.SetRequest(http.MethodGet, "/v1/deleted_data_sets"+query, nil, nil, details)
+	}
+
+	BeforeEach(func() {
+		context = NewTestContext()
+	})
+
+	AfterEach(func() {
+		context.dataClient.AssertOutputsEmpty()
+	})
+
+	It("responds with forbidden if the caller is not a service", func() {
+		setRequest("", request.NewDetails(request.MethodSessionToken, userTest.RandomID(), "token"))
+		dataServiceApiV1.DeletedDataSetsGet(context)
+		Expect(context.ResponseStatusCode()).To(Equal(http.StatusForbidden))
+		Expect(context.dataClient.ListDeletedDataSetsInvocations).To(Equal(0))
+	})
+
+	Context("as a service", func() {
+		serviceDetails := request.NewDetails(request.MethodServiceSecret, "", "")
+
+		It("responds with bad request if the deleted before time is not valid", func() {
+			setRequest("?deletedBefore=invalid", serviceDetails)
+			dataServiceApiV1.DeletedDataSetsGet(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataClient.ListDeletedDataSetsInvocations).To(Equal(0))
+		})
+
+		It("responds with failure if the deleted data sets cannot be listed", func() {
+			setRequest("", serviceDetails)
+			context.dataClient.ListDeletedDataSetsOutputs = []dataClientTest.ListDeletedDataSetsOutput{{Error: errorsTest.RandomError()}}
+			dataServiceApiV1.DeletedDataSetsGet(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusInternalServerError))
+		})
+
+		It("responds with the deleted data sets", func() {
+			setRequest("?deletedBefore=2020-01-01T00:00:00Z&page=2", serviceDetails)
+			dataSetID := dataTest.RandomSetID()
+			context.dataClient.ListDeletedDataSetsOutputs = []dataClientTest.ListDeletedDataSetsOutput{{DataSets: data.DataSets{{UploadID: pointer.FromString(dataSetID)}}}}
+			dataServiceApiV1.DeletedDataSetsGet(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusOK))
+			Expect(context.ResponseBody()).To(ContainSubstring(dataSetID))
+			Expect(context.dataClient.ListDeletedDataSetsInputs).To(HaveLen(1))
+			Expect(*context.dataClient.ListDeletedDataSetsInputs[0].Filter.DeletedBefore).To(BeTemporally("==", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+			Expect(context.dataClient.ListDeletedDataSetsInputs[0].Pagination.Page).To(Equal(2))
+		})
+	})
+})
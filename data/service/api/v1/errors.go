@@ -51,3 +51,39 @@ func ErrorDataSetClosed(dataSetID string) *service.Error {
 		Detail: fmt.Sprintf("Data set with id %s is closed for new data", dataSetID),
 	}
 }
+
+func ErrorDataSetNotDeleted(dataSetID string) *service.Error {
+	return &service.Error{
+		Code:   "data-set-not-deleted",
+		Status: http.StatusConflict,
+		Title:  "data set with specified id is not deleted",
+		Detail: fmt.Sprintf("Data set with id %s is not deleted", dataSetID),
+	}
+}
+
+func ErrorDataSetPurged(dataSetID string) *service.Error {
+	return &service.Error{
+		Code:   "data-set-purged",
+		Status: http.StatusConflict,
+		Title:  "data set with specified id is purged",
+		Detail: fmt.Sprintf("Data set with id %s is purged", dataSetID),
+	}
+}
+
+func ErrorDataSetTimeProcessingDataNotValid(dataSetID string, count int) *service.Error {
+	return &service.Error{
+		Code:   "data-set-time-processing-data-not-valid",
+		Status: http.StatusConflict,
+		Title:  "data set with specified id has data not valid once time processed",
+		Detail: fmt.Sprintf("Data set with id %s has %d data not valid once time processed", dataSetID, count),
+	}
+}
+
+func ErrorDataSetTimeZoneNameNotValid(dataSetID string) *service.Error {
+	return &service.Error{
+		Code:   "data-set-time-zone-name-not-valid",
+		Status: http.StatusConflict,
+		Title:  "data set with specified id has no valid time zone name for its time processing",
+		Detail: fmt.Sprintf("Data set with id %s has no valid time zone name for its time processing", dataSetID),
+	}
+}
@@ -68,4 +68,40 @@ var _ = Describe("Errors", func() {
 				}))
 		})
 	})
+
+	Context("ErrorDataSetNotDeleted", func() {
+		It("matches the expected error", func() {
+			Expect(dataServiceApiV1.ErrorDataSetNotDeleted("1234567890abcdef")).To(Equal(
+				&service.Error{
+					Code:   "data-set-not-deleted",
+					Status: 409,
+					Title:  "data set with specified id is not deleted",
+					Detail: "Data set with id 1234567890abcdef is not deleted",
+				}))
+		})
+	})
+
+	Context("ErrorDataSetPurged", func() {
+		It("matches the expected error", func() {
+			Expect(dataServiceApiV1.ErrorDataSetPurged("1234567890abcdef")).To(Equal(
+				&service.Error{
+					Code:   "data-set-purged",
+					Status: 409,
+					Title:  "data set with specified id is purged",
+					Detail: "Data set with id 1234567890abcdef is purged",
+				}))
+		})
+	})
+
+	Context("ErrorDataSetTimeZoneNameNotValid", func() {
+		It("matches the expected error", func() {
+			Expect(dataServiceApiV1.ErrorDataSetTimeZoneNameNotValid("1234567890abcdef")).To(Equal(
+				&service.Error{
+					Code:   "data-set-time-zone-name-not-valid",
+					Status: 409,
+					Title:  "data set with specified id has no valid time zone name for its time processing",
+					Detail: "Data set with id 1234567890abcdef has no valid time zone name for its time processing",
+				}))
+		})
+	})
 })
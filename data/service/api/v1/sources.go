@@ -0,0 +1,333 @@
+package v1
+
+import (
+	"net/http"
+
+	dataService "github.com/tidepool-org/platform/data/service"
+	dataSource "github.com/tidepool-org/platform/data/source"
+	"github.com/tidepool-org/platform/page"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+	"github.com/tidepool-org/platform/user"
+)
+
+func SourcesRoutes() []dataService.Route {
+	return []dataService.Route{
+		dataService.MakeRoute("GET", "/v1/users/:userId/data_sources", Authenticate(ListUserSources)),
+		dataService.MakeRoute("POST", "/v1/users/:userId/data_sources", Authenticate(CreateUserSource)),
+		dataService.MakeRoute("DELETE", "/v1/users/:userId/data_sources", Authenticate(DeleteAllUserSources)),
+		dataService.MakeRoute("GET", "/v1/data_sources/:id", Authenticate(GetSource)),
+		dataService.MakeRoute("PUT", "/v1/data_sources/:id", Authenticate(UpdateSource)),
+		dataService.MakeRoute("DELETE", "/v1/data_sources/:id", Authenticate(DeleteSource)),
+	}
+}
+
+// ListUserSources godoc
+// @Summary List the user's data sources
+// @Description Caller must be a service, the owner, or have the authorizations to do it in behalf of the user.
+// @ID platform-data-api-ListUserSources
+// @Produce json
+// @Param userId path string true "user ID"
+// @Param page query int false "When using pagination, page number" default(0)
+// @Param size query int false "When using pagination, number of elements by page, 1<size<1000" minimum(1) maximum(1000) default(100)
+// @Param providerType query string false "Filter on the provider type, comma separated"
+// @Param providerName query string false "Filter on the provider name, comma separated"
+// @Param providerSessionId query string false "Filter on the provider session id, comma separated"
+// @Param state query string false "Filter on the state, comma separated"
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Security TidepoolAuthorization
+// @Security TidepoolRestrictedToken
+// @Success 200 {array} source.Source "Array of data sources"
+// @Failure 400 {object} service.Error "Bad request (userId is missing or query is malformed)"
+// @Failure 401 {object} service.Error "Not authenticated"
+// @Failure 403 {object} service.Error "Forbidden"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/users/:userId/data_sources [get]
+func ListUserSources(dataServiceContext dataService.Context) {
+	res := dataServiceContext.Response()
+	req := dataServiceContext.Request()
+
+	responder := request.MustNewResponder(res, req)
+
+	userID, err := request.DecodeRequestPathParameter(req, "userId", user.IsValidID)
+	if err != nil {
+		responder.Error(http.StatusBadRequest, err)
+		return
+	}
+
+	permissions, err := dataServiceContext.PermissionClient().GetUserPermissions(req, userID)
+	if err != nil {
+		if request.IsErrorUnauthorized(err) {
+			dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		} else {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get user permissions", err)
+		}
+		return
+	}
+	if !permissions {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	filter := dataSource.NewFilter()
+	pagination := page.NewPagination()
+	if err = request.DecodeRequestQuery(req.Request, filter, pagination); err != nil {
+		responder.Error(http.StatusBadRequest, err)
+		return
+	}
+
+	sources, err := dataServiceContext.DataSourceClient().List(req.Context(), userID, filter, pagination)
+	if err != nil {
+		responder.Error(request.StatusCodeForError(err), err)
+		return
+	}
+
+	responder.Data(http.StatusOK, sources)
+}
+
+// CreateUserSource godoc
+// @Summary Create a data source for the user
+// @Description Caller must be a service.
+// @ID platform-data-api-CreateUserSource
+// @Accept json
+// @Produce json
+// @Param userId path string true "user ID"
+// @Param source body source.Create true "The data source to create"
+// @Security TidepoolServiceSecret
+// @Success 201 {object} source.Source "The created data source"
+// @Failure 400 {object} service.Error "Bad request (userId is missing or body is malformed)"
+// @Failure 401 {object} service.Error "Not authenticated"
+// @Failure 403 {object} service.Error "Forbidden"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/users/:userId/data_sources [post]
+func CreateUserSource(dataServiceContext dataService.Context) {
+	res := dataServiceContext.Response()
+	req := dataServiceContext.Request()
+
+	responder := request.MustNewResponder(res, req)
+
+	userID, err := request.DecodeRequestPathParameter(req, "userId", user.IsValidID)
+	if err != nil {
+		responder.Error(http.StatusBadRequest, err)
+		return
+	}
+
+	if details := request.DetailsFromContext(req.Context()); !details.IsService() {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	create := dataSource.NewCreate()
+	if err = request.DecodeRequestBody(req.Request, create); err != nil {
+		responder.Error(http.StatusBadRequest, err)
+		return
+	}
+
+	source, err := dataServiceContext.DataSourceClient().Create(req.Context(), userID, create)
+	if err != nil {
+		responder.Error(request.StatusCodeForError(err), err)
+		return
+	}
+
+	responder.Data(http.StatusCreated, source)
+}
+
+// DeleteAllUserSources godoc
+// @Summary Delete all the data sources of the user
+// @Description Caller must be a service.
+// @ID platform-data-api-DeleteAllUserSources
+// @Param userId path string true "user ID"
+// @Security TidepoolServiceSecret
+// @Success 204 "Operation is a success"
+// @Failure 400 {object} service.Error "Bad request (userId is missing)"
+// @Failure 401 {object} service.Error "Not authenticated"
+// @Failure 403 {object} service.Error "Forbidden"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/users/:userId/data_sources [delete]
+func DeleteAllUserSources(dataServiceContext dataService.Context) {
+	res := dataServiceContext.Response()
+	req := dataServiceContext.Request()
+
+	responder := request.MustNewResponder(res, req)
+
+	userID, err := request.DecodeRequestPathParameter(req, "userId", user.IsValidID)
+	if err != nil {
+		responder.Error(http.StatusBadRequest, err)
+		return
+	}
+
+	if details := request.DetailsFromContext(req.Context()); !details.IsService() {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	if err = dataServiceContext.DataSourceClient().DeleteAll(req.Context(), userID); err != nil {
+		responder.Error(request.StatusCodeForError(err), err)
+		return
+	}
+
+	responder.Empty(http.StatusNoContent)
+}
+
+// GetSource godoc
+// @Summary Get one data source
+// @Description Caller must be a service, the owner, or have the authorizations to do it in behalf of the user of the data source.
+// @ID platform-data-api-GetSource
+// @Produce json
+// @Param id path string true "data source ID"
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Security TidepoolAuthorization
+// @Security TidepoolRestrictedToken
+// @Success 200 {object} source.Source "The requested data source"
+// @Failure 400 {object} service.Error "Bad request (id is missing)"
+// @Failure 401 {object} service.Error "Not authenticated"
+// @Failure 403 {object} service.Error "Forbidden"
+// @Failure 404 {object} service.Error "Data source not found"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/data_sources/:id [get]
+func GetSource(dataServiceContext dataService.Context) {
+	res := dataServiceContext.Response()
+	req := dataServiceContext.Request()
+
+	responder := request.MustNewResponder(res, req)
+
+	id, err := request.DecodeRequestPathParameter(req, "id", dataSource.IsValidID)
+	if err != nil {
+		responder.Error(http.StatusBadRequest, err)
+		return
+	}
+
+	source, err := dataServiceContext.DataSourceClient().Get(req.Context(), id)
+	if err != nil {
+		responder.Error(request.StatusCodeForError(err), err)
+		return
+	} else if source == nil {
+		responder.Error(http.StatusNotFound, request.ErrorResourceNotFoundWithID(id))
+		return
+	}
+
+	permissions, err := dataServiceContext.PermissionClient().GetUserPermissions(req, *source.UserID)
+	if err != nil {
+		if request.IsErrorUnauthorized(err) {
+			dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		} else {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get user permissions", err)
+		}
+		return
+	}
+	if !permissions {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	responder.Data(http.StatusOK, source)
+}
+
+// UpdateSource godoc
+// @Summary Update one data source
+// @Description Caller must be a service. When a revision is given, the data source is only updated if its revision matches.
+// @ID platform-data-api-UpdateSource
+// @Accept json
+// @Produce json
+// @Param id path string true "data source ID"
+// @Param revision query int false "Only update the data source if its revision matches"
+// @Param source body source.Update true "The data source fields to update"
+// @Security TidepoolServiceSecret
+// @Success 200 {object} source.Source "The updated data source"
+// @Failure 400 {object} service.Error "Bad request (id is missing, query or body is malformed)"
+// @Failure 401 {object} service.Error "Not authenticated"
+// @Failure 403 {object} service.Error "Forbidden"
+// @Failure 404 {object} service.Error "Data source not found or revision does not match"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/data_sources/:id [put]
+func UpdateSource(dataServiceContext dataService.Context) {
+	res := dataServiceContext.Response()
+	req := dataServiceContext.Request()
+
+	responder := request.MustNewResponder(res, req)
+
+	id, err := request.DecodeRequestPathParameter(req, "id", dataSource.IsValidID)
+	if err != nil {
+		responder.Error(http.StatusBadRequest, err)
+		return
+	}
+
+	if details := request.DetailsFromContext(req.Context()); !details.IsService() {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	condition := request.NewCondition()
+	if err = request.DecodeRequestQuery(req.Request, condition); err != nil {
+		responder.Error(http.StatusBadRequest, err)
+		return
+	}
+
+	update := dataSource.NewUpdate()
+	if err = request.DecodeRequestBody(req.Request, update); err != nil {
+		responder.Error(http.StatusBadRequest, err)
+		return
+	}
+
+	source, err := dataServiceContext.DataSourceClient().Update(req.Context(), id, condition, update)
+	if err != nil {
+		responder.Error(request.StatusCodeForError(err), err)
+		return
+	} else if source == nil {
+		responder.Error(http.StatusNotFound, request.ErrorResourceNotFoundWithIDAndOptionalRevision(id, condition.Revision))
+		return
+	}
+
+	responder.Data(http.StatusOK, source)
+}
+
+// DeleteSource godoc
+// @Summary Delete one data source
+// @Description Caller must be a service. When a revision is given, the data source is only deleted if its revision matches.
+// @ID platform-data-api-DeleteSource
+// @Param id path string true "data source ID"
+// @Param revision query int false "Only delete the data source if its revision matches"
+// @Security TidepoolServiceSecret
+// @Success 204 "Operation is a success"
+// @Failure 400 {object} service.Error "Bad request (id is missing or query is malformed)"
+// @Failure 401 {object} service.Error "Not authenticated"
+// @Failure 403 {object} service.Error "Forbidden"
+// @Failure 404 {object} service.Error "Data source not found or revision does not match"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/data_sources/:id [delete]
+func DeleteSource(dataServiceContext dataService.Context) {
+	res := dataServiceContext.Response()
+	req := dataServiceContext.Request()
+
+	responder := request.MustNewResponder(res, req)
+
+	id, err := request.DecodeRequestPathParameter(req, "id", dataSource.IsValidID)
+	if err != nil {
+		responder.Error(http.StatusBadRequest, err)
+		return
+	}
+
+	if details := request.DetailsFromContext(req.Context()); !details.IsService() {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	condition := request.NewCondition()
+	if err = request.DecodeRequestQuery(req.Request, condition); err != nil {
+		responder.Error(http.StatusBadRequest, err)
+		return
+	}
+
+	deleted, err := dataServiceContext.DataSourceClient().Delete(req.Context(), id, condition)
+	if err != nil {
+		responder.Error(request.StatusCodeForError(err), err)
+		return
+	} else if !deleted {
+		responder.Error(http.StatusNotFound, request.ErrorResourceNotFoundWithIDAndOptionalRevision(id, condition.Revision))
+		return
+	}
+
+	responder.Empty(http.StatusNoContent)
+}
@@ -0,0 +1,243 @@
+package v1_test
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataSource "github.com/tidepool-org/platform/data/source"
+	dataSourceTest "github.com/tidepool-org/platform/data/source/test"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+	userTest "github.com/tidepool-org/platform/user/test"
+)
+
+var _ = Describe("Sources", func() {
+	var context *TestContext
+	var userID string
+	var serviceDetails request.Details
+	var userDetails request.Details
+
+	BeforeEach(func() {
+		context = NewTestContext()
+		userID = userTest.RandomID()
+		serviceDetails = request.NewDetails(request.MethodServiceSecret, "", "")
+		userDetails = request.NewDetails(request.MethodSessionToken, userTest.RandomID(), "token")
+	})
+
+	AfterEach(func() {
+		context.dataSourceClient.AssertOutputsEmpty()
+		Expect(context.permissionClient.GetUserPermissionsOutputs).To(BeEmpty())
+	})
+
+	It("SourcesRoutes returns the routes", func() {
+		Expect(dataServiceApiV1.SourcesRoutes()).To(HaveLen(6))
+	})
+
+	Context("ListUserSources", func() {
+		setRequest := func(query string, details request.Details) {
+			context.SetRequest(http.MethodGet, "/v1/users/"+userID+"/data_sources"+query, nil, map[string]string{"userId": userID}, details)
+		}
+
+		It("responds with bad request if the user id is not valid", func() {
+			userID = "invalid"
+			setRequest("", userDetails)
+			dataServiceApiV1.ListUserSources(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+		})
+
+		It("responds with unauthorized if the caller has no permissions", func() {
+			setRequest("", userDetails)
+			context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: false}}
+			dataServiceApiV1.ListUserSources(context)
+			Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+			Expect(context.permissionClient.GetUserPermissionsInputs).To(Equal([]string{userID}))
+			Expect(context.dataSourceClient.ListInvocations).To(Equal(0))
+		})
+
+		It("responds with failure if the permissions cannot be retrieved", func() {
+			setRequest("", userDetails)
+			context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Error: errorsTest.RandomError()}}
+			dataServiceApiV1.ListUserSources(context)
+			Expect(context.failures).To(Equal([]string{"Unable to get user permissions"}))
+		})
+
+		It("responds with bad request if the query is malformed", func() {
+			setRequest("?page=invalid", userDetails)
+			context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: true}}
+			dataServiceApiV1.ListUserSources(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataSourceClient.ListInvocations).To(Equal(0))
+		})
+
+		It("responds with the data sources", func() {
+			setRequest("?state=connected", userDetails)
+			context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: true}}
+			source := dataSourceTest.RandomSource()
+			context.dataSourceClient.ListOutputs = []dataSourceTest.ListOutput{{SourceArray: dataSource.SourceArray{source}}}
+			dataServiceApiV1.ListUserSources(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusOK))
+			Expect(context.ResponseBody()).To(ContainSubstring(*source.ID))
+			Expect(context.dataSourceClient.ListInputs).To(HaveLen(1))
+			Expect(context.dataSourceClient.ListInputs[0].UserID).To(Equal(userID))
+			Expect(context.dataSourceClient.ListInputs[0].Filter.State).To(Equal(pointer.FromStringArray([]string{"connected"})))
+		})
+	})
+
+	Context("CreateUserSource", func() {
+		var create *dataSource.Create
+
+		BeforeEach(func() {
+			create = dataSourceTest.RandomCreate()
+		})
+
+		setRequest := func(details request.Details) {
+			context.SetRequest(http.MethodPost, "/v1/users/"+userID+"/data_sources", create, map[string]string{"userId": userID}, details)
+		}
+
+		It("responds with unauthorized if the caller is not a service", func() {
+			setRequest(userDetails)
+			dataServiceApiV1.CreateUserSource(context)
+			Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+			Expect(context.dataSourceClient.CreateInvocations).To(Equal(0))
+		})
+
+		It("responds with the created data source", func() {
+			setRequest(serviceDetails)
+			source := dataSourceTest.RandomSource()
+			context.dataSourceClient.CreateOutputs = []dataSourceTest.CreateOutput{{Source: source}}
+			dataServiceApiV1.CreateUserSource(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusCreated))
+			Expect(context.dataSourceClient.CreateInputs).To(HaveLen(1))
+			Expect(context.dataSourceClient.CreateInputs[0].UserID).To(Equal(userID))
+			Expect(context.dataSourceClient.CreateInputs[0].Create).To(Equal(create))
+		})
+	})
+
+	Context("DeleteAllUserSources", func() {
+		It("responds with unauthorized if the caller is not a service", func() {
+			context.SetRequest(http.MethodDelete, "/v1/users/"+userID+"/data_sources", nil, map[string]string{"userId": userID}, userDetails)
+			dataServiceApiV1.DeleteAllUserSources(context)
+			Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+			Expect(context.dataSourceClient.DeleteAllInvocations).To(Equal(0))
+		})
+
+		It("deletes all the data sources of the user", func() {
+			context.SetRequest(http.MethodDelete, "/v1/users/"+userID+"/data_sources", nil, map[string]string{"userId": userID}, serviceDetails)
+			context.dataSourceClient.DeleteAllOutputs = []error{nil}
+			dataServiceApiV1.DeleteAllUserSources(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusNoContent))
+			Expect(context.dataSourceClient.DeleteAllInputs).To(Equal([]string{userID}))
+		})
+	})
+
+	Context("with a data source", func() {
+		var source *dataSource.Source
+
+		BeforeEach(func() {
+			source = dataSourceTest.RandomSource()
+			source.UserID = pointer.FromString(userID)
+		})
+
+		Context("GetSource", func() {
+			BeforeEach(func() {
+				context.SetRequest(http.MethodGet, "/v1/data_sources/"+*source.ID, nil, map[string]string{"id": *source.ID}, userDetails)
+			})
+
+			It("responds with not found if the data source does not exist", func() {
+				context.dataSourceClient.GetOutputs = []dataSourceTest.GetOutput{{Source: nil}}
+				dataServiceApiV1.GetSource(context)
+				Expect(context.ResponseStatusCode()).To(Equal(http.StatusNotFound))
+			})
+
+			It("responds with unauthorized if the caller has no permissions on the user of the data source", func() {
+				context.dataSourceClient.GetOutputs = []dataSourceTest.GetOutput{{Source: source}}
+				context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: false}}
+				dataServiceApiV1.GetSource(context)
+				Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+				Expect(context.permissionClient.GetUserPermissionsInputs).To(Equal([]string{userID}))
+				Expect(context.response.WriteInputs).To(BeEmpty())
+			})
+
+			It("responds with the data source", func() {
+				context.dataSourceClient.GetOutputs = []dataSourceTest.GetOutput{{Source: source}}
+				context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: true}}
+				dataServiceApiV1.GetSource(context)
+				Expect(context.ResponseStatusCode()).To(Equal(http.StatusOK))
+				Expect(context.ResponseBody()).To(ContainSubstring(*source.ID))
+				Expect(context.dataSourceClient.GetInputs).To(Equal([]string{*source.ID}))
+			})
+		})
+
+		Context("UpdateSource", func() {
+			setRequest := func(query string, details request.Details) {
+				context.SetRequest(http.MethodPut, "/v1/data_sources/"+*source.ID+query, map[string]interface{}{"state": "disconnected"}, map[string]string{"id": *source.ID}, details)
+			}
+
+			It("responds with unauthorized if the caller is not a service", func() {
+				setRequest("", userDetails)
+				dataServiceApiV1.UpdateSource(context)
+				Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+				Expect(context.dataSourceClient.UpdateInvocations).To(Equal(0))
+			})
+
+			It("responds with bad request if the revision is not valid", func() {
+				setRequest("?revision=invalid", serviceDetails)
+				dataServiceApiV1.UpdateSource(context)
+				Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+				Expect(context.dataSourceClient.UpdateInvocations).To(Equal(0))
+			})
+
+			It("responds with not found if the revision does not match", func() {
+				setRequest("?revision=2", serviceDetails)
+				context.dataSourceClient.UpdateOutputs = []dataSourceTest.UpdateOutput{{Source: nil}}
+				dataServiceApiV1.UpdateSource(context)
+				Expect(context.ResponseStatusCode()).To(Equal(http.StatusNotFound))
+				Expect(context.dataSourceClient.UpdateInputs).To(HaveLen(1))
+				Expect(context.dataSourceClient.UpdateInputs[0].Condition).To(Equal(&request.Condition{Revision: pointer.FromInt(2)}))
+			})
+
+			It("responds with the updated data source", func() {
+				setRequest("", serviceDetails)
+				context.dataSourceClient.UpdateOutputs = []dataSourceTest.UpdateOutput{{Source: source}}
+				dataServiceApiV1.UpdateSource(context)
+				Expect(context.ResponseStatusCode()).To(Equal(http.StatusOK))
+				Expect(context.dataSourceClient.UpdateInputs).To(HaveLen(1))
+				Expect(*context.dataSourceClient.UpdateInputs[0].Update.State).To(Equal("disconnected"))
+			})
+		})
+
+		Context("DeleteSource", func() {
+			setRequest := func(query string, details request.Details) {
+				context.SetRequest(http.MethodDelete, "/v1/data_sources/"+*source.ID+query, nil, map[string]string{"id": *source.ID}, details)
+			}
+
+			It("responds with unauthorized if the caller is not a service", func() {
+				setRequest("", userDetails)
+				dataServiceApiV1.DeleteSource(context)
+				Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+				Expect(context.dataSourceClient.DeleteInvocations).To(Equal(0))
+			})
+
+			It("responds with not found if the data source is not deleted", func() {
+				setRequest("?revision=2", serviceDetails)
+				context.dataSourceClient.DeleteOutputs = []dataSourceTest.DeleteOutput{{Deleted: false}}
+				dataServiceApiV1.DeleteSource(context)
+				Expect(context.ResponseStatusCode()).To(Equal(http.StatusNotFound))
+			})
+
+			It("deletes the data source", func() {
+				setRequest("", serviceDetails)
+				context.dataSourceClient.DeleteOutputs = []dataSourceTest.DeleteOutput{{Deleted: true}}
+				dataServiceApiV1.DeleteSource(context)
+				Expect(context.ResponseStatusCode()).To(Equal(http.StatusNoContent))
+				Expect(context.dataSourceClient.DeleteInputs).To(HaveLen(1))
+				Expect(context.dataSourceClient.DeleteInputs[0].ID).To(Equal(*source.ID))
+			})
+		})
+	})
+})
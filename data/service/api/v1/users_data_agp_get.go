@@ -0,0 +1,87 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/tidepool-org/platform/data/converter"
+	dataService "github.com/tidepool-org/platform/data/service"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+)
+
+// UsersDataAGPGet godoc
+// @Summary Get the ambulatory glucose profile
+// @Description Get the 5th, 25th, 50th, 75th and 95th percentiles of the continuous glucose data of a user, in mmol/L unless
+// @Description units specified, for each 15 minutes of the day, using the local time of the data.
+// @Description The range defaults to the 14 days before the end date, which defaults to now.
+// @Description The range is at most 366 days.
+// @Description Caller must be a service, the owner, or have the authorizations to do it in behalf of the user.
+// @ID platform-data-api-UsersDataAGPGet
+// @Produce json
+// @Param userId path string true "user ID"
+// @Param startDate query string false "Only data with a time after or equal to this date (RFC3339)"
+// @Param endDate query string false "Only data with a time before or equal to this date (RFC3339)"
+// @Param deviceId query string false "Filter on the deviceId"
+// @Param uploadId query string false "Filter on the uploadId"
+// @Param units query string false "Units of the glucose values, rounded as displayed by devices, otherwise mmol/L unrounded" Enums(mmol/L, mmol/l, mg/dL, mg/dl)
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Security TidepoolAuthorization
+// @Security TidepoolRestrictedToken
+// @Success 200 {object} summary.AGPReport "Operation is a success"
+// @Failure 400 {object} service.Error "User id is missing or query is malformed"
+// @Failure 403 {object} service.Error "Forbiden: caller is not authorized"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/users/:userId/data/agp [get]
+func UsersDataAGPGet(dataServiceContext dataService.Context) {
+	req := dataServiceContext.Request()
+
+	targetUserID := req.PathParam("userId")
+	if targetUserID == "" {
+		dataServiceContext.RespondWithError(ErrorUserIDMissing())
+		return
+	}
+
+	permissions, err := dataServiceContext.PermissionClient().GetUserPermissions(req, targetUserID)
+	if err != nil {
+		if request.IsErrorUnauthorized(err) {
+			dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		} else {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get user permissions", err)
+		}
+		return
+	}
+	if !permissions {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	filter := dataStoreDEPRECATED.NewDataFilter()
+	glucoseOptions := converter.NewGlucoseOptions()
+	if err = request.DecodeRequestQuery(req.Request, filter, glucoseOptions); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+	if err = defaultDataFilterDates(filter, summary.AGPRangeDefault); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+	filter.Type = &[]string{dataTypesBloodGlucoseContinuous.Type}
+	filter.SubType = nil
+
+	continuousData, err := iterateDataForUserByID(dataServiceContext, targetUserID, filter)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get data for user", err)
+		return
+	}
+
+	report := summary.CalculateAGP(continuousData, *filter.StartDate, *filter.EndDate)
+	if glucoseOptions.Units != nil {
+		report.ConvertGlucoseUnits(*glucoseOptions.Units)
+	}
+
+	dataServiceContext.RespondWithStatusAndData(http.StatusOK, report)
+}
@@ -0,0 +1,101 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/tidepool-org/platform/data/converter"
+	dataService "github.com/tidepool-org/platform/data/service"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesDevice "github.com/tidepool-org/platform/data/types/device"
+	dataTypesDeviceAlarm "github.com/tidepool-org/platform/data/types/device/alarm"
+	dataTypesSettingsCgm "github.com/tidepool-org/platform/data/types/settings/cgm"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+)
+
+// UsersDataAlarmsGet godoc
+// @Summary Get the alarms report
+// @Description Get the device alarms of a user counted by alarm type per local day and per local hour of the day, the
+// @Description alarm bursts, series of at least 3 alarms each within 30 minutes of the previous, and the level alert
+// @Description configuration, in the CGM settings active at the time, of the alarms with the alarm codes of the CGM
+// @Description level alerts. Alert levels are in mmol/L, unless units specified, and snoozes in minutes.
+// @Description The range defaults to the 14 days before the end date, which defaults to now.
+// @Description The range is at most 366 days.
+// @Description Caller must be a service, the owner, or have the authorizations to do it in behalf of the user.
+// @ID platform-data-api-UsersDataAlarmsGet
+// @Produce json
+// @Param userId path string true "user ID"
+// @Param startDate query string false "Only data with a time after or equal to this date (RFC3339)"
+// @Param endDate query string false "Only data with a time before or equal to this date (RFC3339)"
+// @Param deviceId query string false "Filter on the deviceId"
+// @Param uploadId query string false "Filter on the uploadId"
+// @Param highAlarmCode query string false "Comma-separated alarm codes of the CGM high alert"
+// @Param lowAlarmCode query string false "Comma-separated alarm codes of the CGM low alert"
+// @Param urgentLowAlarmCode query string false "Comma-separated alarm codes of the CGM urgent low alert"
+// @Param units query string false "Units of the alert levels, rounded as displayed by devices, otherwise mmol/L unrounded" Enums(mmol/L, mmol/l, mg/dL, mg/dl)
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Security TidepoolAuthorization
+// @Security TidepoolRestrictedToken
+// @Success 200 {object} summary.AlarmsReport "Operation is a success"
+// @Failure 400 {object} service.Error "User id is missing or query is malformed"
+// @Failure 403 {object} service.Error "Forbiden: caller is not authorized"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/users/:userId/data/alarms [get]
+func UsersDataAlarmsGet(dataServiceContext dataService.Context) {
+	req := dataServiceContext.Request()
+
+	targetUserID := req.PathParam("userId")
+	if targetUserID == "" {
+		dataServiceContext.RespondWithError(ErrorUserIDMissing())
+		return
+	}
+
+	permissions, err := dataServiceContext.PermissionClient().GetUserPermissions(req, targetUserID)
+	if err != nil {
+		if request.IsErrorUnauthorized(err) {
+			dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		} else {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get user permissions", err)
+		}
+		return
+	}
+	if !permissions {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	filter := dataStoreDEPRECATED.NewDataFilter()
+	alarmsOptions := summary.NewAlarmsOptions()
+	glucoseOptions := converter.NewGlucoseOptions()
+	if err = request.DecodeRequestQuery(req.Request, filter, alarmsOptions, glucoseOptions); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+	if err = defaultDataFilterDates(filter, summary.AlarmsRangeDefault); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+	filter.Type = &[]string{dataTypesDevice.Type}
+	filter.SubType = &[]string{dataTypesDeviceAlarm.SubType}
+
+	alarmData, err := iterateDataForUserByID(dataServiceContext, targetUserID, filter)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get data for user", err)
+		return
+	}
+
+	cgmSettingsData, err := settingsForUserByID(dataServiceContext, targetUserID, filter, dataTypesSettingsCgm.Type)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get data for user", err)
+		return
+	}
+
+	report := summary.CalculateAlarms(append(alarmData, cgmSettingsData...), alarmsOptions, *filter.StartDate, *filter.EndDate)
+	if glucoseOptions.Units != nil {
+		report.ConvertGlucoseUnits(*glucoseOptions.Units)
+	}
+
+	dataServiceContext.RespondWithStatusAndData(http.StatusOK, report)
+}
@@ -0,0 +1,141 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/tidepool-org/platform/data"
+	dataCSV "github.com/tidepool-org/platform/data/csv"
+	dataNormalizer "github.com/tidepool-org/platform/data/normalizer"
+	dataService "github.com/tidepool-org/platform/data/service"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+)
+
+// CSVImport is the outcome of the import of a CSV export, with the data set created, only if any data is imported
+type CSVImport struct {
+	*dataCSV.Import
+	DataSet *dataTypesUpload.Upload `json:"dataSet,omitempty"`
+}
+
+// UsersDataCSVCreate godoc
+// @Summary Import a CSV export
+// @Description Import a vendor CSV export, such as a Clarity or LibreView export, with the profile mapping its columns to data.
+// @Description The data are added to a new data set, closed once imported, with the hash deduplicator of the device.
+// @Description The rows with errors are reported, with the column as error source pointer, and do not prevent the others.
+// @Description Caller must be a service, the owner, or have the authorizations to do it in behalf of the user.
+// @ID platform-data-api-UsersDataCSVCreate
+// @Accept text/csv
+// @Produce json
+// @Param userId path string true "user ID"
+// @Param profile query string true "Name of the profile of the CSV export" Enums(clarity, libreview)
+// @Param timeZoneName query string false "Time zone name of the times of the CSV export, required if local to the device"
+// @Param export body string true "CSV export"
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Security TidepoolAuthorization
+// @Security TidepoolRestrictedToken
+// @Success 200 {object} CSVImport "Operation is a success"
+// @Failure 400 {object} service.Error "User id is missing, profile or timeZoneName parameter is invalid, or the CSV export does not match the profile"
+// @Failure 403 {object} service.Error "Forbiden: caller is not authorized"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/users/:userId/data/csv [post]
+func UsersDataCSVCreate(dataServiceContext dataService.Context) {
+	req := dataServiceContext.Request()
+	ctx := req.Context()
+
+	targetUserID := req.PathParam("userId")
+	if targetUserID == "" {
+		dataServiceContext.RespondWithError(ErrorUserIDMissing())
+		return
+	}
+
+	permissions, err := dataServiceContext.PermissionClient().GetUserPermissions(req, targetUserID)
+	if err != nil {
+		if request.IsErrorUnauthorized(err) {
+			dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		} else {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get user permissions", err)
+		}
+		return
+	}
+	if !permissions {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	profileName := req.URL.Query().Get("profile")
+	if profileName == "" {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, request.ErrorParameterMissing("profile"))
+		return
+	}
+	profile := dataCSV.GetProfile(profileName)
+	if profile == nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, request.ErrorParameterInvalid("profile"))
+		return
+	}
+
+	var timeZoneName *string
+	if value := req.URL.Query().Get("timeZoneName"); value != "" {
+		timeZoneName = pointer.FromString(value)
+	}
+
+	csvImport, err := profile.Import(req.Body, timeZoneName)
+	if err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+
+	if len(csvImport.Data) == 0 {
+		dataServiceContext.RespondWithStatusAndData(http.StatusOK, &CSVImport{Import: csvImport})
+		return
+	}
+
+	dataSet := profile.NewDataSet(csvImport, timeZoneName)
+	dataSet.SetUserID(&targetUserID)
+	dataSet.Normalize(dataNormalizer.New())
+
+	dataSet.DataState = pointer.FromString("open") // TODO: Deprecated DataState (after data migration)
+	dataSet.State = pointer.FromString("open")
+
+	if err = dataServiceContext.DataSession().CreateDataSet(ctx, dataSet); err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to insert data set", err)
+		return
+	}
+
+	deduplicator, err := dataServiceContext.DataDeduplicatorFactory().New(dataSet)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get deduplicator", err)
+		return
+	} else if deduplicator == nil {
+		dataServiceContext.RespondWithInternalServerFailure("Deduplicator not found")
+		return
+	} else if dataSet, err = deduplicator.Open(ctx, dataServiceContext.DataSession(), dataSet); err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to open", err)
+		return
+	}
+
+	for _, datum := range csvImport.Data {
+		datum.SetUserID(dataSet.UserID)
+		datum.SetDataSetID(dataSet.UploadID)
+		datum.SetDeviceID(dataSet.DeviceID)
+	}
+
+	if err = deduplicator.AddData(ctx, dataServiceContext.DataSession(), dataSet, csvImport.Data); err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to add data", err)
+		return
+	}
+
+	update := data.NewDataSetUpdate()
+	update.State = pointer.FromString(data.DataSetStateClosed)
+	if dataSet, err = dataServiceContext.DataSession().UpdateDataSet(ctx, *dataSet.UploadID, update); err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to update data set", err)
+		return
+	} else if err = deduplicator.Close(ctx, dataServiceContext.DataSession(), dataSet); err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to close", err)
+		return
+	}
+
+	dataServiceContext.RespondWithStatusAndData(http.StatusOK, &CSVImport{Import: csvImport, DataSet: dataSet})
+}
@@ -0,0 +1,144 @@
+package v1_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	dataCSV "github.com/tidepool-org/platform/data/csv"
+	dataDeduplicatorTest "github.com/tidepool-org/platform/data/deduplicator/test"
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataStoreDEPRECATEDTest "github.com/tidepool-org/platform/data/storeDEPRECATED/test"
+	dataTest "github.com/tidepool-org/platform/data/test"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/service"
+	userTest "github.com/tidepool-org/platform/user/test"
+)
+
+const clarityHeader = "Index,Timestamp (YYYY-MM-DDThh:mm:ss),Event Type,Event Subtype,Patient Info,Device Info,Source Device ID,Glucose Value (mmol/L),Insulin Value (u),Carb Value (grams),Duration (hh:mm:ss),Glucose Rate of Change (mmol/L/min),Transmitter Time (Long Integer),Transmitter ID\n"
+
+var _ = Describe("UsersDataCSVCreate", func() {
+	var userID string
+	var context *TestContext
+
+	setRequest := func(query string, export string) {
+		context.SetRequest(http.MethodPost, "/v1/users/"+userID+"/data/csv"+query, nil, map[string]string{"userId": userID}, nil)
+		context.request.Body = ioutil.NopCloser(strings.NewReader(export))
+	}
+
+	BeforeEach(func() {
+		userID = userTest.RandomID()
+		context = NewTestContext()
+		setRequest("?profile="+dataCSV.ProfileNameClarity+"&timeZoneName=Europe/Paris", clarityHeader+"1,2021-07-15T23:58:12,EGV,,,,Android G6,6.4,,,,0.1,4235212,8GK9XY\n")
+	})
+
+	AfterEach(func() {
+		context.dataSession.Expectations()
+		Expect(context.permissionClient.GetUserPermissionsOutputs).To(BeEmpty())
+	})
+
+	It("responds with unauthorized if the caller has no permissions", func() {
+		context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: false}}
+		dataServiceApiV1.UsersDataCSVCreate(context)
+		Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+		Expect(context.dataSession.CreateDataSetInputs).To(BeEmpty())
+	})
+
+	Context("with permissions", func() {
+		BeforeEach(func() {
+			context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: true}}
+		})
+
+		It("responds with bad request if the profile is missing", func() {
+			setRequest("", clarityHeader)
+			dataServiceApiV1.UsersDataCSVCreate(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataSession.CreateDataSetInputs).To(BeEmpty())
+		})
+
+		It("responds with bad request if the profile is not registered", func() {
+			setRequest("?profile=unknown", clarityHeader)
+			dataServiceApiV1.UsersDataCSVCreate(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataSession.CreateDataSetInputs).To(BeEmpty())
+		})
+
+		It("responds with bad request if the export does not match the profile", func() {
+			setRequest("?profile="+dataCSV.ProfileNameClarity, "Unexpected,Columns\n")
+			dataServiceApiV1.UsersDataCSVCreate(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataSession.CreateDataSetInputs).To(BeEmpty())
+		})
+
+		It("responds with the import without creating a data set if no data is imported", func() {
+			setRequest("?profile="+dataCSV.ProfileNameClarity+"&timeZoneName=Europe/Paris", clarityHeader)
+			dataServiceApiV1.UsersDataCSVCreate(context)
+			Expect(context.errors).To(BeEmpty())
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			csvImport, ok := context.data.(*dataServiceApiV1.CSVImport)
+			Expect(ok).To(BeTrue())
+			Expect(csvImport.Imported).To(Equal(0))
+			Expect(csvImport.DataSet).To(BeNil())
+			Expect(context.dataSession.CreateDataSetInputs).To(BeEmpty())
+		})
+
+		Context("with a data set created", func() {
+			var dataSet *dataTypesUpload.Upload
+			var deduplicator *dataDeduplicatorTest.Deduplicator
+
+			BeforeEach(func() {
+				dataSet = dataTypesUpload.New()
+				dataSet.UserID = pointer.FromString(userID)
+				dataSet.UploadID = pointer.FromString(dataTest.RandomSetID())
+				dataSet.DeviceID = pointer.FromString("DexcomClarity")
+				context.dataSession.CreateDataSetOutputs = []error{nil}
+				deduplicator = dataDeduplicatorTest.NewDeduplicator()
+				deduplicator.OpenOutputs = []dataDeduplicatorTest.OpenOutput{{DataSet: dataSet}}
+				context.dataDeduplicatorFactory.NewOutputs = []dataDeduplicatorTest.NewOutput{{Deduplicator: deduplicator}}
+			})
+
+			AfterEach(func() {
+				deduplicator.AssertOutputsEmpty()
+			})
+
+			It("adds the imported data to the data set, then closes it", func() {
+				deduplicator.AddDataOutputs = []error{nil}
+				deduplicator.CloseOutputs = []error{nil}
+				context.dataSession.UpdateDataSetOutputs = []dataStoreDEPRECATEDTest.UpdateDataSetOutput{{DataSet: dataSet}}
+				dataServiceApiV1.UsersDataCSVCreate(context)
+				Expect(context.errors).To(BeEmpty())
+				Expect(context.failures).To(BeEmpty())
+				Expect(context.statusCode).To(Equal(http.StatusOK))
+				Expect(context.dataSession.CreateDataSetInputs).To(HaveLen(1))
+				Expect(*context.dataSession.CreateDataSetInputs[0].DataSet.UserID).To(Equal(userID))
+				Expect(*context.dataSession.CreateDataSetInputs[0].DataSet.State).To(Equal("open"))
+				Expect(deduplicator.AddDataInputs).To(HaveLen(1))
+				Expect(deduplicator.AddDataInputs[0].DataSetData).To(HaveLen(1))
+				cbg, ok := deduplicator.AddDataInputs[0].DataSetData[0].(*dataTypesBloodGlucoseContinuous.Continuous)
+				Expect(ok).To(BeTrue())
+				Expect(*cbg.Time).To(Equal("2021-07-15T21:58:12Z"))
+				Expect(cbg.UploadID).To(Equal(dataSet.UploadID))
+				Expect(cbg.DeviceID).To(Equal(dataSet.DeviceID))
+				Expect(context.dataSession.UpdateDataSetInputs).To(HaveLen(1))
+				Expect(*context.dataSession.UpdateDataSetInputs[0].Update.State).To(Equal("closed"))
+				csvImport, ok := context.data.(*dataServiceApiV1.CSVImport)
+				Expect(ok).To(BeTrue())
+				Expect(csvImport.Imported).To(Equal(1))
+				Expect(csvImport.DataSet).To(Equal(dataSet))
+			})
+
+			It("responds with failure and does not close the data set if the data cannot be added", func() {
+				deduplicator.AddDataOutputs = []error{errorsTest.RandomError()}
+				dataServiceApiV1.UsersDataCSVCreate(context)
+				Expect(context.failures).To(Equal([]string{"Unable to add data"}))
+				Expect(context.dataSession.UpdateDataSetInputs).To(BeEmpty())
+			})
+		})
+	})
+})
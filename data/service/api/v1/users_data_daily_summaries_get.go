@@ -0,0 +1,78 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/tidepool-org/platform/data/converter"
+	dataService "github.com/tidepool-org/platform/data/service"
+	"github.com/tidepool-org/platform/data/summary"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+)
+
+// UsersDataDailySummariesGet godoc
+// @Summary Get the daily summaries
+// @Description Get the rollups of the data of a user for each local day with data, ordered by date: the glucose stats,
+// @Description the carbohydrate from food and bolus calculator, the insulin totals, the physical activity minutes and
+// @Description the alarm count. The daily summaries are updated whenever the data of the user changes.
+// @Description Caller must be a service, the owner, or have the authorizations to do it in behalf of the user.
+// @ID platform-data-api-UsersDataDailySummariesGet
+// @Produce json
+// @Param userId path string true "user ID"
+// @Param startDate query string false "Only daily summaries of a local date after or equal to this date (YYYY-MM-DD)"
+// @Param endDate query string false "Only daily summaries of a local date before or equal to this date (YYYY-MM-DD)"
+// @Param units query string false "Units of the glucose values, rounded as displayed by devices, otherwise mmol/L unrounded" Enums(mmol/L, mmol/l, mg/dL, mg/dl)
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Security TidepoolAuthorization
+// @Security TidepoolRestrictedToken
+// @Success 200 {array} summary.DailySummary "Operation is a success"
+// @Failure 400 {object} service.Error "User id is missing or query is malformed"
+// @Failure 403 {object} service.Error "Forbiden: caller is not authorized"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/users/:userId/data/daily_summaries [get]
+func UsersDataDailySummariesGet(dataServiceContext dataService.Context) {
+	req := dataServiceContext.Request()
+	ctx := req.Context()
+
+	targetUserID := req.PathParam("userId")
+	if targetUserID == "" {
+		dataServiceContext.RespondWithError(ErrorUserIDMissing())
+		return
+	}
+
+	permissions, err := dataServiceContext.PermissionClient().GetUserPermissions(req, targetUserID)
+	if err != nil {
+		if request.IsErrorUnauthorized(err) {
+			dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		} else {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get user permissions", err)
+		}
+		return
+	}
+	if !permissions {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	filter := summary.NewDailySummaryFilter()
+	glucoseOptions := converter.NewGlucoseOptions()
+	if err = request.DecodeRequestQuery(req.Request, filter, glucoseOptions); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+
+	dailySummaries, err := dataServiceContext.DataSession().GetDailySummaries(ctx, targetUserID, filter)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get daily summaries", err)
+		return
+	}
+
+	if glucoseOptions.Units != nil {
+		for _, dailySummary := range dailySummaries {
+			dailySummary.ConvertGlucoseUnits(*glucoseOptions.Units)
+		}
+	}
+
+	dataServiceContext.RespondWithStatusAndData(http.StatusOK, dailySummaries)
+}
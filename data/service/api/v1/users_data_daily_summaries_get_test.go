@@ -0,0 +1,76 @@
+package v1_test
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataStoreDEPRECATEDTest "github.com/tidepool-org/platform/data/storeDEPRECATED/test"
+	"github.com/tidepool-org/platform/data/summary"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/service"
+	userTest "github.com/tidepool-org/platform/user/test"
+)
+
+var _ = Describe("UsersDataDailySummariesGet", func() {
+	var userID string
+	var context *TestContext
+
+	setRequest := func(query string) {
+		context.SetRequest(http.MethodGet, "/v1/users/"+userID+"/data/daily_summaries"+query, nil, map[string]string{"userId": userID}, nil)
+	}
+
+	BeforeEach(func() {
+		userID = userTest.RandomID()
+		context = NewTestContext()
+		setRequest("?startDate=2020-03-01&endDate=2020-03-02")
+	})
+
+	AfterEach(func() {
+		context.dataSession.Expectations()
+		Expect(context.permissionClient.GetUserPermissionsOutputs).To(BeEmpty())
+	})
+
+	It("responds with unauthorized if the caller has no permissions", func() {
+		context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: false}}
+		dataServiceApiV1.UsersDataDailySummariesGet(context)
+		Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+		Expect(context.dataSession.GetDailySummariesInvocations).To(Equal(0))
+	})
+
+	Context("with permissions", func() {
+		BeforeEach(func() {
+			context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: true}}
+		})
+
+		It("responds with bad request if the end date is before the start date", func() {
+			setRequest("?startDate=2020-03-02&endDate=2020-03-01")
+			dataServiceApiV1.UsersDataDailySummariesGet(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataSession.GetDailySummariesInvocations).To(Equal(0))
+		})
+
+		It("responds with failure if the daily summaries cannot be fetched", func() {
+			context.dataSession.GetDailySummariesOutputs = []dataStoreDEPRECATEDTest.GetDailySummariesOutput{{Error: errorsTest.RandomError()}}
+			dataServiceApiV1.UsersDataDailySummariesGet(context)
+			Expect(context.failures).To(Equal([]string{"Unable to get daily summaries"}))
+		})
+
+		It("responds with the daily summaries of the dates, with the glucose in the units", func() {
+			setRequest("?startDate=2020-03-01&endDate=2020-03-02&units=mg/dL")
+			dailySummaries := []*summary.DailySummary{{UserID: userID, Date: "2020-03-01", Glucose: &summary.GlucoseStats{Mean: pointer.FromFloat64(5.5)}}}
+			context.dataSession.GetDailySummariesOutputs = []dataStoreDEPRECATEDTest.GetDailySummariesOutput{{DailySummaries: dailySummaries}}
+			dataServiceApiV1.UsersDataDailySummariesGet(context)
+			Expect(context.errors).To(BeEmpty())
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			Expect(context.data).To(Equal(dailySummaries))
+			Expect(*dailySummaries[0].Glucose.Mean).To(BeNumerically("~", 99, 0.5))
+			Expect(context.dataSession.GetDailySummariesInputs).To(HaveLen(1))
+			Expect(*context.dataSession.GetDailySummariesInputs[0].Filter.StartDate).To(Equal("2020-03-01"))
+			Expect(*context.dataSession.GetDailySummariesInputs[0].Filter.EndDate).To(Equal("2020-03-02"))
+		})
+	})
+})
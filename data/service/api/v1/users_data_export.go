@@ -0,0 +1,123 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/tidepool-org/platform/data/converter"
+	dataService "github.com/tidepool-org/platform/data/service"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/log"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+)
+
+const (
+	// ContentTypeNDJSON is the media type of a newline delimited JSON stream
+	ContentTypeNDJSON = "application/x-ndjson"
+
+	exportFlushInterval = 1000
+)
+
+// UsersDataExport godoc
+// @Summary Export data
+// @Description Stream all the active device data of a user, in chronological order, one JSON datum per line.
+// @Description An interrupted export can be resumed with the cursor built from the last datum received.
+// @Description Caller must be a service, the owner, or have the authorizations to do it in behalf of the user.
+// @ID platform-data-api-UsersDataExport
+// @Produce application/x-ndjson
+// @Param userId path string true "user ID"
+// @Param startDate query string false "Only data with a time after or equal to this date (RFC3339)"
+// @Param endDate query string false "Only data with a time before or equal to this date (RFC3339)"
+// @Param type query string false "Filter on the type, comma separated"
+// @Param subType query string false "Filter on the subType, comma separated"
+// @Param deviceId query string false "Filter on the deviceId"
+// @Param uploadId query string false "Filter on the uploadId"
+// @Param cursor query string false "Resume the export after the datum identified by this cursor"
+// @Param units query string false "Units of the glucose values, rounded as displayed by devices, otherwise mmol/L unrounded" Enums(mmol/L, mmol/l, mg/dL, mg/dl)
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Security TidepoolAuthorization
+// @Security TidepoolRestrictedToken
+// @Success 200 {array} types.Base "Operation is a success"
+// @Failure 400 {object} service.Error "User id is missing or query is malformed"
+// @Failure 403 {object} service.Error "Forbiden: caller is not authorized"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/users/:userId/data/export [get]
+func UsersDataExport(dataServiceContext dataService.Context) {
+	req := dataServiceContext.Request()
+	ctx := req.Context()
+
+	targetUserID := req.PathParam("userId")
+	if targetUserID == "" {
+		dataServiceContext.RespondWithError(ErrorUserIDMissing())
+		return
+	}
+
+	permissions, err := dataServiceContext.PermissionClient().GetUserPermissions(req, targetUserID)
+	if err != nil {
+		if request.IsErrorUnauthorized(err) {
+			dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		} else {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get user permissions", err)
+		}
+		return
+	}
+	if !permissions {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	filter := dataStoreDEPRECATED.NewDataFilter()
+	cursor := &dataStoreDEPRECATED.DataCursor{}
+	glucoseOptions := converter.NewGlucoseOptions()
+	if err := request.DecodeRequestQuery(req.Request, filter, cursor, glucoseOptions); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+	if cursor.ID == "" {
+		cursor = nil
+	}
+
+	iterator, err := dataServiceContext.DataSession().IterateDataForUserByID(ctx, targetUserID, filter, cursor)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to iterate data for user", err)
+		return
+	}
+	defer iterator.Close()
+
+	res := dataServiceContext.Response()
+	writer, ok := res.(http.ResponseWriter)
+	if !ok {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to stream response")
+		return
+	}
+	flusher, _ := res.(http.Flusher)
+
+	res.Header().Set("Content-Type", ContentTypeNDJSON)
+	res.WriteHeader(http.StatusOK)
+
+	// Once the status is written, errors can no longer be reported to the caller; the export
+	// is simply truncated and the caller resumes it with the cursor of the last datum received
+	logger := log.LoggerFromContext(ctx).WithField("userId", targetUserID)
+	encoder := json.NewEncoder(writer)
+	count := 0
+	for iterator.Next(ctx) {
+		datum := iterator.Datum()
+		if glucoseOptions.Units != nil {
+			converter.ConvertGlucoseDatum(datum, *glucoseOptions.Units)
+		}
+		if err = encoder.Encode(datum); err != nil {
+			logger.WithError(err).Warn("Unable to write datum to export")
+			return
+		}
+		if count++; flusher != nil && count%exportFlushInterval == 0 {
+			flusher.Flush()
+		}
+	}
+	if err = iterator.Error(); err != nil {
+		logger.WithError(err).Error("Unable to complete export")
+	}
+
+	logger.WithField("count", count).Debug("UsersDataExport")
+}
@@ -0,0 +1,114 @@
+package v1_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	dataStoreDEPRECATEDTest "github.com/tidepool-org/platform/data/storeDEPRECATED/test"
+	dataTest "github.com/tidepool-org/platform/data/test"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/service"
+	userTest "github.com/tidepool-org/platform/user/test"
+)
+
+var _ = Describe("UsersDataExport", func() {
+	var userID string
+	var context *TestContext
+
+	setRequest := func(query string) {
+		context.SetRequest(http.MethodGet, "/v1/users/"+userID+"/data/export"+query, nil, map[string]string{"userId": userID}, nil)
+	}
+
+	newDatum := func(value float64) *dataTypesBloodGlucoseContinuous.Continuous {
+		datum := dataTypesBloodGlucoseContinuous.New()
+		datum.ID = pointer.FromString(dataTest.RandomID())
+		datum.Units = pointer.FromString("mmol/L")
+		datum.Value = pointer.FromFloat64(value)
+		return datum
+	}
+
+	responseLines := func() []string {
+		return strings.Split(strings.TrimSuffix(context.ResponseBody(), "\n"), "\n")
+	}
+
+	BeforeEach(func() {
+		userID = userTest.RandomID()
+		context = NewTestContext()
+		setRequest("")
+	})
+
+	AfterEach(func() {
+		context.dataSession.Expectations()
+		Expect(context.permissionClient.GetUserPermissionsOutputs).To(BeEmpty())
+	})
+
+	It("responds with unauthorized if the caller has no permissions", func() {
+		context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: false}}
+		dataServiceApiV1.UsersDataExport(context)
+		Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+		Expect(context.permissionClient.GetUserPermissionsInputs).To(Equal([]string{userID}))
+		Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+	})
+
+	Context("with permissions", func() {
+		BeforeEach(func() {
+			context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: true}}
+		})
+
+		It("responds with bad request if the cursor is not valid", func() {
+			setRequest("?cursor=invalid")
+			dataServiceApiV1.UsersDataExport(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+		})
+
+		It("responds with failure if the data cannot be iterated", func() {
+			context.dataSession.IterateDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.IterateDataForUserByIDOutput{{Error: errorsTest.RandomError()}}
+			dataServiceApiV1.UsersDataExport(context)
+			Expect(context.failures).To(Equal([]string{"Unable to iterate data for user"}))
+		})
+
+		It("streams the data, one datum per line, from the cursor", func() {
+			cursor := dataStoreDEPRECATED.NewDataCursor("2020-01-01T00:00:00Z", dataTest.RandomID())
+			setRequest("?cursor=" + cursor.Token() + "&units=mg/dL")
+			iterator := dataStoreDEPRECATEDTest.NewDataIterator(data.Data{newDatum(5.5), newDatum(11.1)}, nil)
+			context.dataSession.IterateDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.IterateDataForUserByIDOutput{{DataIterator: iterator}}
+			dataServiceApiV1.UsersDataExport(context)
+			Expect(context.failures).To(BeEmpty())
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusOK))
+			Expect(context.response.HeaderOutput.Get("Content-Type")).To(Equal(dataServiceApiV1.ContentTypeNDJSON))
+			Expect(context.dataSession.IterateDataForUserByIDInputs).To(HaveLen(1))
+			Expect(context.dataSession.IterateDataForUserByIDInputs[0].UserID).To(Equal(userID))
+			Expect(context.dataSession.IterateDataForUserByIDInputs[0].Cursor).To(Equal(cursor))
+			lines := responseLines()
+			Expect(lines).To(HaveLen(2))
+			for index, value := range []float64{99, 200} {
+				var object map[string]interface{}
+				Expect(json.Unmarshal([]byte(lines[index]), &object)).To(Succeed())
+				Expect(object["units"]).To(Equal("mg/dL"))
+				Expect(object["value"]).To(Equal(value))
+			}
+			Expect(iterator.CloseInvocations).To(Equal(1))
+		})
+
+		It("truncates the export if the iteration fails", func() {
+			iterator := dataStoreDEPRECATEDTest.NewDataIterator(data.Data{newDatum(5.5)}, errorsTest.RandomError())
+			context.dataSession.IterateDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.IterateDataForUserByIDOutput{{DataIterator: iterator}}
+			dataServiceApiV1.UsersDataExport(context)
+			Expect(context.failures).To(BeEmpty())
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusOK))
+			Expect(responseLines()).To(HaveLen(1))
+			Expect(context.dataSession.IterateDataForUserByIDInputs[0].Cursor).To(BeNil())
+			Expect(iterator.CloseInvocations).To(Equal(1))
+		})
+	})
+})
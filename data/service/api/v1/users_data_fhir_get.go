@@ -0,0 +1,138 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/tidepool-org/platform/data/converter"
+	"github.com/tidepool-org/platform/data/fhir"
+	dataService "github.com/tidepool-org/platform/data/service"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	dataTypesBasal "github.com/tidepool-org/platform/data/types/basal"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	dataTypesBloodGlucoseSelfMonitored "github.com/tidepool-org/platform/data/types/blood/glucose/selfmonitored"
+	dataTypesBolus "github.com/tidepool-org/platform/data/types/bolus"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	"github.com/tidepool-org/platform/log"
+	"github.com/tidepool-org/platform/page"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+)
+
+const (
+	// ContentTypeFHIRJSON is the media type of a FHIR resource in JSON
+	ContentTypeFHIRJSON = "application/fhir+json"
+
+	fhirRangeDefault = 14 * 24 * time.Hour
+)
+
+// UsersDataFHIRGet godoc
+// @Summary Get the data as a FHIR bundle
+// @Description Get the glucose and insulin data of a user as a FHIR R4 collection bundle: continuous and self-monitored
+// @Description glucose values as LOINC coded observations, boluses and basals as insulin medication administrations,
+// @Description and the data sets they come from as devices. Glucose values are in mmol/L, unless units specified.
+// @Description The range defaults to the 14 days before the end date, which defaults to now.
+// @Description The range is at most 366 days.
+// @Description Caller must be a service, the owner, or have the authorizations to do it in behalf of the user.
+// @ID platform-data-api-UsersDataFHIRGet
+// @Produce application/fhir+json
+// @Param userId path string true "user ID"
+// @Param startDate query string false "Only data with a time after or equal to this date (RFC3339)"
+// @Param endDate query string false "Only data with a time before or equal to this date (RFC3339)"
+// @Param deviceId query string false "Filter on the deviceId"
+// @Param uploadId query string false "Filter on the uploadId"
+// @Param units query string false "Units of the glucose values, rounded as displayed by devices, otherwise mmol/L unrounded" Enums(mmol/L, mmol/l, mg/dL, mg/dl)
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Security TidepoolAuthorization
+// @Security TidepoolRestrictedToken
+// @Success 200 {object} fhir.Bundle "Operation is a success"
+// @Failure 400 {object} service.Error "User id is missing or query is malformed"
+// @Failure 403 {object} service.Error "Forbiden: caller is not authorized"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/users/:userId/data/fhir [get]
+func UsersDataFHIRGet(dataServiceContext dataService.Context) {
+	req := dataServiceContext.Request()
+
+	targetUserID := req.PathParam("userId")
+	if targetUserID == "" {
+		dataServiceContext.RespondWithError(ErrorUserIDMissing())
+		return
+	}
+
+	permissions, err := dataServiceContext.PermissionClient().GetUserPermissions(req, targetUserID)
+	if err != nil {
+		if request.IsErrorUnauthorized(err) {
+			dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		} else {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get user permissions", err)
+		}
+		return
+	}
+	if !permissions {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	filter := dataStoreDEPRECATED.NewDataFilter()
+	glucoseOptions := converter.NewGlucoseOptions()
+	if err = request.DecodeRequestQuery(req.Request, filter, glucoseOptions); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+	if err = defaultDataFilterDates(filter, fhirRangeDefault); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+	filter.Type = &[]string{dataTypesBloodGlucoseContinuous.Type, dataTypesBloodGlucoseSelfMonitored.Type, dataTypesBolus.Type, dataTypesBasal.Type}
+	filter.SubType = nil
+
+	datums, err := iterateDataForUserByID(dataServiceContext, targetUserID, filter)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get data for user", err)
+		return
+	}
+	if glucoseOptions.Units != nil {
+		converter.ConvertGlucoseData(datums, *glucoseOptions.Units)
+	}
+
+	dataSets, err := dataSetsForUserByID(dataServiceContext, targetUserID)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get data sets for user", err)
+		return
+	}
+
+	res := dataServiceContext.Response()
+	writer, ok := res.(http.ResponseWriter)
+	if !ok {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to write response")
+		return
+	}
+
+	res.Header().Set("Content-Type", ContentTypeFHIRJSON)
+	res.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(writer).Encode(fhir.NewBundle(targetUserID, dataSets, datums)); err != nil {
+		log.LoggerFromContext(req.Context()).WithField("userId", targetUserID).WithError(err).Warn("Unable to write bundle")
+	}
+}
+
+// dataSetsForUserByID returns all the data sets of the user, page by page
+func dataSetsForUserByID(dataServiceContext dataService.Context, userID string) ([]*dataTypesUpload.Upload, error) {
+	ctx := dataServiceContext.Request().Context()
+
+	result := []*dataTypesUpload.Upload{}
+	pagination := page.NewPagination()
+	pagination.Size = page.PaginationSizeMaximum
+	for {
+		dataSets, err := dataServiceContext.DataSession().GetDataSetsForUserByID(ctx, userID, dataStoreDEPRECATED.NewFilter(), pagination)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, dataSets...)
+		if len(dataSets) < pagination.Size {
+			return result, nil
+		}
+		pagination.Page++
+	}
+}
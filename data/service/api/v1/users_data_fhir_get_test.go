@@ -0,0 +1,102 @@
+package v1_test
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataStoreDEPRECATEDTest "github.com/tidepool-org/platform/data/storeDEPRECATED/test"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/service"
+	userTest "github.com/tidepool-org/platform/user/test"
+)
+
+var _ = Describe("UsersDataFHIRGet", func() {
+	var userID string
+	var context *TestContext
+
+	setRequest := func(query string) {
+		context.SetRequest(http.MethodGet, "/v1/users/"+userID+"/data/fhir"+query, nil, map[string]string{"userId": userID}, nil)
+	}
+
+	BeforeEach(func() {
+		userID = userTest.RandomID()
+		context = NewTestContext()
+		setRequest("?startDate=2020-03-01T00:00:00Z&endDate=2020-03-15T00:00:00Z")
+	})
+
+	AfterEach(func() {
+		context.dataSession.Expectations()
+		Expect(context.permissionClient.GetUserPermissionsOutputs).To(BeEmpty())
+	})
+
+	It("responds with unauthorized if the caller has no permissions", func() {
+		context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: false}}
+		dataServiceApiV1.UsersDataFHIRGet(context)
+		Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+		Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+	})
+
+	Context("with permissions", func() {
+		BeforeEach(func() {
+			context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: true}}
+		})
+
+		It("responds with bad request if the query is not valid", func() {
+			setRequest("?units=invalid")
+			dataServiceApiV1.UsersDataFHIRGet(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+		})
+
+		It("responds with bad request if the range is longer than the maximum", func() {
+			setRequest("?startDate=2018-01-01T00:00:00Z&endDate=2020-03-15T00:00:00Z")
+			dataServiceApiV1.UsersDataFHIRGet(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+		})
+
+		It("responds with failure if the data cannot be iterated", func() {
+			context.dataSession.IterateDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.IterateDataForUserByIDOutput{{Error: errorsTest.RandomError()}}
+			dataServiceApiV1.UsersDataFHIRGet(context)
+			Expect(context.failures).To(Equal([]string{"Unable to get data for user"}))
+		})
+
+		Context("with data", func() {
+			BeforeEach(func() {
+				datum := dataTypesBloodGlucoseContinuous.New()
+				datum.ID = pointer.FromString("continuous")
+				datum.Time = pointer.FromString("2020-03-02T00:00:00Z")
+				datum.Units = pointer.FromString("mmol/L")
+				datum.Value = pointer.FromFloat64(5.5)
+				context.dataSession.IterateDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.IterateDataForUserByIDOutput{{DataIterator: dataStoreDEPRECATEDTest.NewDataIterator(data.Data{datum}, nil)}}
+			})
+
+			It("responds with failure if the data sets cannot be listed", func() {
+				context.dataSession.GetDataSetsForUserByIDOutputs = []dataStoreDEPRECATEDTest.GetDataSetsForUserByIDOutput{{Error: errorsTest.RandomError()}}
+				dataServiceApiV1.UsersDataFHIRGet(context)
+				Expect(context.failures).To(Equal([]string{"Unable to get data sets for user"}))
+			})
+
+			It("responds with the bundle of the data of the range", func() {
+				context.dataSession.GetDataSetsForUserByIDOutputs = []dataStoreDEPRECATEDTest.GetDataSetsForUserByIDOutput{{DataSets: []*dataTypesUpload.Upload{}}}
+				dataServiceApiV1.UsersDataFHIRGet(context)
+				Expect(context.failures).To(BeEmpty())
+				Expect(context.ResponseStatusCode()).To(Equal(http.StatusOK))
+				Expect(context.response.HeaderOutput.Get("Content-Type")).To(Equal(dataServiceApiV1.ContentTypeFHIRJSON))
+				Expect(context.ResponseBody()).To(ContainSubstring(`"resourceType":"Bundle"`))
+				Expect(context.ResponseBody()).To(ContainSubstring("continuous"))
+				filter := context.dataSession.IterateDataForUserByIDInputs[0].Filter
+				Expect(*filter.StartDate).To(BeTemporally("==", time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)))
+				Expect(*filter.EndDate).To(BeTemporally("==", time.Date(2020, 3, 15, 0, 0, 0, 0, time.UTC)))
+			})
+		})
+	})
+})
@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/tidepool-org/platform/data/converter"
+	dataService "github.com/tidepool-org/platform/data/service"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/page"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+)
+
+// UsersDataGet godoc
+// @Summary Get data
+// @Description Get the active device data of a user, most recent first
+// @Description Caller must be a service, the owner, or have the authorizations to do it in behalf of the user.
+// @ID platform-data-api-UsersDataGet
+// @Produce json
+// @Param userId path string true "user ID"
+// @Param page query int false "When using pagination, page number" default(0)
+// @Param size query int false "When using pagination, number of elements by page, 1<size<1000" minimum(1) maximum(1000) default(100)
+// @Param startDate query string false "Only data with a time after or equal to this date (RFC3339)"
+// @Param endDate query string false "Only data with a time before or equal to this date (RFC3339)"
+// @Param type query string false "Filter on the type, comma separated"
+// @Param subType query string false "Filter on the subType, comma separated"
+// @Param deviceId query string false "Filter on the deviceId"
+// @Param uploadId query string false "Filter on the uploadId"
+// @Param units query string false "Units of the glucose values, rounded as displayed by devices, otherwise mmol/L unrounded" Enums(mmol/L, mmol/l, mg/dL, mg/dl)
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Security TidepoolAuthorization
+// @Security TidepoolRestrictedToken
+// @Success 200 {array} types.Base "Operation is a success"
+// @Failure 400 {object} service.Error "User id is missing or query is malformed"
+// @Failure 403 {object} service.Error "Forbiden: caller is not authorized"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/users/:userId/data [get]
+func UsersDataGet(dataServiceContext dataService.Context) {
+	req := dataServiceContext.Request()
+	ctx := req.Context()
+
+	targetUserID := req.PathParam("userId")
+	if targetUserID == "" {
+		dataServiceContext.RespondWithError(ErrorUserIDMissing())
+		return
+	}
+
+	permissions, err := dataServiceContext.PermissionClient().GetUserPermissions(req, targetUserID)
+	if err != nil {
+		if request.IsErrorUnauthorized(err) {
+			dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		} else {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get user permissions", err)
+		}
+		return
+	}
+	if !permissions {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	filter := dataStoreDEPRECATED.NewDataFilter()
+	pagination := page.NewPagination()
+	glucoseOptions := converter.NewGlucoseOptions()
+	if err := request.DecodeRequestQuery(req.Request, filter, pagination, glucoseOptions); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+
+	dataSetData, err := dataServiceContext.DataSession().GetDataForUserByID(ctx, targetUserID, filter, pagination)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get data for user", err)
+		return
+	}
+
+	if glucoseOptions.Units != nil {
+		converter.ConvertGlucoseData(dataSetData, *glucoseOptions.Units)
+	}
+
+	dataServiceContext.RespondWithStatusAndData(http.StatusOK, dataSetData)
+}
@@ -0,0 +1,110 @@
+package v1_test
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataStoreDEPRECATEDTest "github.com/tidepool-org/platform/data/storeDEPRECATED/test"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/service"
+	userTest "github.com/tidepool-org/platform/user/test"
+)
+
+var _ = Describe("UsersDataGet", func() {
+	var userID string
+	var context *TestContext
+
+	setRequest := func(query string) {
+		context.SetRequest(http.MethodGet, "/v1/users/"+userID+"/data"+query, nil, map[string]string{"userId": userID}, nil)
+	}
+
+	BeforeEach(func() {
+		userID = userTest.RandomID()
+		context = NewTestContext()
+		setRequest("")
+	})
+
+	AfterEach(func() {
+		context.dataSession.Expectations()
+		Expect(context.permissionClient.GetUserPermissionsOutputs).To(BeEmpty())
+	})
+
+	It("responds with unauthorized if the caller has no permissions", func() {
+		context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: false}}
+		dataServiceApiV1.UsersDataGet(context)
+		Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+		Expect(context.permissionClient.GetUserPermissionsInputs).To(Equal([]string{userID}))
+		Expect(context.dataSession.GetDataForUserByIDInvocations).To(Equal(0))
+	})
+
+	It("responds with failure if the permissions cannot be retrieved", func() {
+		context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Error: errorsTest.RandomError()}}
+		dataServiceApiV1.UsersDataGet(context)
+		Expect(context.failures).To(Equal([]string{"Unable to get user permissions"}))
+	})
+
+	Context("with permissions", func() {
+		BeforeEach(func() {
+			context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: true}}
+		})
+
+		DescribeTable("responds with bad request if the query is malformed",
+			func(query string) {
+				setRequest(query)
+				dataServiceApiV1.UsersDataGet(context)
+				Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+				Expect(context.dataSession.GetDataForUserByIDInvocations).To(Equal(0))
+			},
+			Entry("start date is not a time", "?startDate=invalid"),
+			Entry("end date is before the start date", "?startDate=2020-01-02T00:00:00Z&endDate=2020-01-01T00:00:00Z"),
+			Entry("type is empty", "?type="),
+			Entry("units are not valid", "?units=invalid"),
+			Entry("size is not valid", "?size=0"),
+		)
+
+		It("responds with failure if the data cannot be retrieved", func() {
+			context.dataSession.GetDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.GetDataForUserByIDOutput{{Error: errorsTest.RandomError()}}
+			dataServiceApiV1.UsersDataGet(context)
+			Expect(context.failures).To(Equal([]string{"Unable to get data for user"}))
+		})
+
+		It("responds with the data filtered by the query", func() {
+			setRequest("?startDate=2020-01-01T00:00:00Z&endDate=2020-01-02T00:00:00Z&type=cbg,smbg&deviceId=device&page=1&size=10")
+			dataSetData := data.Data{dataTypesBloodGlucoseContinuous.New()}
+			context.dataSession.GetDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.GetDataForUserByIDOutput{{Data: dataSetData}}
+			dataServiceApiV1.UsersDataGet(context)
+			Expect(context.errors).To(BeEmpty())
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			Expect(context.data).To(Equal(dataSetData))
+			Expect(context.dataSession.GetDataForUserByIDInputs).To(HaveLen(1))
+			input := context.dataSession.GetDataForUserByIDInputs[0]
+			Expect(input.UserID).To(Equal(userID))
+			Expect(*input.Filter.StartDate).To(BeTemporally("==", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+			Expect(*input.Filter.EndDate).To(BeTemporally("==", time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)))
+			Expect(input.Filter.Type).To(Equal(pointer.FromStringArray([]string{"cbg", "smbg"})))
+			Expect(input.Filter.DeviceID).To(Equal(pointer.FromString("device")))
+			Expect(input.Pagination.Page).To(Equal(1))
+			Expect(input.Pagination.Size).To(Equal(10))
+		})
+
+		It("responds with the glucose values converted to the units", func() {
+			setRequest("?units=mg/dL")
+			datum := dataTypesBloodGlucoseContinuous.New()
+			datum.Units = pointer.FromString("mmol/L")
+			datum.Value = pointer.FromFloat64(5.5)
+			context.dataSession.GetDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.GetDataForUserByIDOutput{{Data: data.Data{datum}}}
+			dataServiceApiV1.UsersDataGet(context)
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			Expect(datum.Units).To(Equal(pointer.FromString("mg/dL")))
+			Expect(*datum.Value).To(Equal(99.0))
+		})
+	})
+})
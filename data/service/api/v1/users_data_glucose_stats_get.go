@@ -0,0 +1,132 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/tidepool-org/platform/data/converter"
+	dataService "github.com/tidepool-org/platform/data/service"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+	"github.com/tidepool-org/platform/structure"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
+)
+
+const (
+	// GlucoseStatsRangeDefault is the range of the glucose stats when the start date is not specified
+	GlucoseStatsRangeDefault = 14 * 24 * time.Hour
+
+	// DataFilterRangeMaximum is the longest range of the data read at once to calculate a report
+	DataFilterRangeMaximum = 366 * 24 * time.Hour
+)
+
+// UsersDataGlucoseStatsGet godoc
+// @Summary Get continuous glucose stats
+// @Description Get the time in range, time below and above range, mean glucose, GMI, coefficient of variation and sensor wear
+// @Description of the continuous glucose data of a user, for the whole range and for each local day with data.
+// @Description With segmentBy=reportedState, also for the local days during which each reported state was active, and
+// @Description for the local days without any, to compare for example the illness days with the normal days.
+// @Description Glucose values are in mmol/L, unless units specified. The range defaults to the 14 days before the end date, which defaults to now.
+// @Description The range is at most 366 days.
+// @Description Caller must be a service, the owner, or have the authorizations to do it in behalf of the user.
+// @ID platform-data-api-UsersDataGlucoseStatsGet
+// @Produce json
+// @Param userId path string true "user ID"
+// @Param startDate query string false "Only data with a time after or equal to this date (RFC3339)"
+// @Param endDate query string false "Only data with a time before or equal to this date (RFC3339)"
+// @Param deviceId query string false "Filter on the deviceId"
+// @Param uploadId query string false "Filter on the uploadId"
+// @Param units query string false "Units of the targets, required with any target, and of the glucose values, rounded as displayed by devices, otherwise mmol/L unrounded" Enums(mmol/L, mmol/l, mg/dL, mg/dl)
+// @Param veryLow query number false "Very low target" default(3.0)
+// @Param low query number false "Low target, the bottom of the range" default(3.9)
+// @Param high query number false "High target, the top of the range" default(10.0)
+// @Param veryHigh query number false "Very high target" default(13.9)
+// @Param sampleInterval query int false "Expected minutes between two readings, for the sensor wear" minimum(1) maximum(60) default(5)
+// @Param segmentBy query string false "Also segment the local days by the reported states active during the day" Enums(reportedState)
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Security TidepoolAuthorization
+// @Security TidepoolRestrictedToken
+// @Success 200 {object} summary.GlucoseStatsReport "Operation is a success"
+// @Failure 400 {object} service.Error "User id is missing or query is malformed"
+// @Failure 403 {object} service.Error "Forbiden: caller is not authorized"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/users/:userId/data/glucose_stats [get]
+func UsersDataGlucoseStatsGet(dataServiceContext dataService.Context) {
+	req := dataServiceContext.Request()
+
+	targetUserID := req.PathParam("userId")
+	if targetUserID == "" {
+		dataServiceContext.RespondWithError(ErrorUserIDMissing())
+		return
+	}
+
+	permissions, err := dataServiceContext.PermissionClient().GetUserPermissions(req, targetUserID)
+	if err != nil {
+		if request.IsErrorUnauthorized(err) {
+			dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		} else {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get user permissions", err)
+		}
+		return
+	}
+	if !permissions {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	filter := dataStoreDEPRECATED.NewDataFilter()
+	options := summary.NewGlucoseStatsOptions()
+	segmentOptions := summary.NewSegmentOptions()
+	glucoseOptions := converter.NewGlucoseOptions()
+	if err = request.DecodeRequestQuery(req.Request, filter, options, segmentOptions, glucoseOptions); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+	if err = defaultDataFilterDates(filter, GlucoseStatsRangeDefault); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+	filter.Type = &[]string{dataTypesBloodGlucoseContinuous.Type}
+	filter.SubType = nil
+
+	continuousData, err := iterateDataForUserByID(dataServiceContext, targetUserID, filter)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get data for user", err)
+		return
+	}
+
+	report := summary.CalculateGlucoseStats(continuousData, options, *filter.StartDate, *filter.EndDate)
+	if segmentOptions.SegmentByReportedState() {
+		periods, err := reportedStatePeriodsForUserByID(dataServiceContext, targetUserID, filter)
+		if err != nil {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get data for user", err)
+			return
+		}
+		report.Segments = summary.CalculateGlucoseStatsSegments(continuousData, options, periods, *filter.StartDate, *filter.EndDate)
+	}
+	if glucoseOptions.Units != nil {
+		report.ConvertGlucoseUnits(*glucoseOptions.Units)
+	}
+
+	dataServiceContext.RespondWithStatusAndData(http.StatusOK, report)
+}
+
+// defaultDataFilterDates defaults the end date of the filter to now and the start date to the range before the end
+// date, and returns an error if the range is longer than the maximum, as all the data of the range is read at once
+func defaultDataFilterDates(filter *dataStoreDEPRECATED.DataFilter, rangeDefault time.Duration) error {
+	if filter.EndDate == nil {
+		filter.EndDate = pointer.FromTime(time.Now().UTC())
+	}
+	if filter.StartDate == nil {
+		filter.StartDate = pointer.FromTime(filter.EndDate.Add(-rangeDefault))
+	}
+
+	validator := structureValidator.New().WithSource(structure.NewParameterSource())
+	validator.Time("startDate", filter.StartDate).After(filter.EndDate.Add(-DataFilterRangeMaximum))
+	return validator.Error()
+}
@@ -0,0 +1,93 @@
+package v1_test
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataStoreDEPRECATEDTest "github.com/tidepool-org/platform/data/storeDEPRECATED/test"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/service"
+	userTest "github.com/tidepool-org/platform/user/test"
+)
+
+var _ = Describe("UsersDataGlucoseStatsGet", func() {
+	var userID string
+	var context *TestContext
+
+	setRequest := func(query string) {
+		context.SetRequest(http.MethodGet, "/v1/users/"+userID+"/data/glucose_stats"+query, nil, map[string]string{"userId": userID}, nil)
+	}
+
+	BeforeEach(func() {
+		userID = userTest.RandomID()
+		context = NewTestContext()
+		setRequest("?endDate=2020-03-15T00:00:00Z")
+	})
+
+	AfterEach(func() {
+		context.dataSession.Expectations()
+		Expect(context.permissionClient.GetUserPermissionsOutputs).To(BeEmpty())
+	})
+
+	It("responds with unauthorized if the caller has no permissions", func() {
+		context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: false}}
+		dataServiceApiV1.UsersDataGlucoseStatsGet(context)
+		Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+		Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+	})
+
+	Context("with permissions", func() {
+		BeforeEach(func() {
+			context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: true}}
+		})
+
+		It("responds with bad request if the query is not valid", func() {
+			setRequest("?segmentBy=invalid")
+			dataServiceApiV1.UsersDataGlucoseStatsGet(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+		})
+
+		It("responds with bad request if the range is longer than the maximum", func() {
+			setRequest("?startDate=2018-01-01T00:00:00Z&endDate=2020-03-15T00:00:00Z")
+			dataServiceApiV1.UsersDataGlucoseStatsGet(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.ResponseBody()).To(ContainSubstring("startDate"))
+			Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+		})
+
+		It("responds with bad request if the range from the start date to now is longer than the maximum", func() {
+			setRequest("?startDate=2018-01-01T00:00:00Z")
+			dataServiceApiV1.UsersDataGlucoseStatsGet(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+		})
+
+		It("responds with failure if the data cannot be iterated", func() {
+			context.dataSession.IterateDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.IterateDataForUserByIDOutput{{Error: errorsTest.RandomError()}}
+			dataServiceApiV1.UsersDataGlucoseStatsGet(context)
+			Expect(context.failures).To(Equal([]string{"Unable to get data for user"}))
+		})
+
+		It("responds with the glucose stats of the continuous data of the default range", func() {
+			context.dataSession.IterateDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.IterateDataForUserByIDOutput{{DataIterator: dataStoreDEPRECATEDTest.NewDataIterator(data.Data{}, nil)}}
+			dataServiceApiV1.UsersDataGlucoseStatsGet(context)
+			Expect(context.errors).To(BeEmpty())
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			_, ok := context.data.(*summary.GlucoseStatsReport)
+			Expect(ok).To(BeTrue())
+			Expect(context.dataSession.IterateDataForUserByIDInputs).To(HaveLen(1))
+			filter := context.dataSession.IterateDataForUserByIDInputs[0].Filter
+			Expect(*filter.Type).To(Equal([]string{dataTypesBloodGlucoseContinuous.Type}))
+			Expect(*filter.StartDate).To(BeTemporally("==", time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)))
+			Expect(*filter.EndDate).To(BeTemporally("==", time.Date(2020, 3, 15, 0, 0, 0, 0, time.UTC)))
+		})
+	})
+})
@@ -0,0 +1,102 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/tidepool-org/platform/data/converter"
+	dataService "github.com/tidepool-org/platform/data/service"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	dataTypesSettingsCgm "github.com/tidepool-org/platform/data/types/settings/cgm"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+)
+
+// UsersDataGlycemicEventsGet godoc
+// @Summary Get the glycemic events
+// @Description Get the lows, very lows and prolonged highs detected in the continuous glucose data of a user, with their
+// @Description start and end times and their nadir or peak. A threshold not specified is that of the enabled level alert
+// @Description of the latest CGM settings, if any, or else the consensus default. Glucose values are in mmol/L,
+// @Description unless units specified.
+// @Description The range defaults to the 14 days before the end date, which defaults to now.
+// @Description The range is at most 366 days.
+// @Description Caller must be a service, the owner, or have the authorizations to do it in behalf of the user.
+// @ID platform-data-api-UsersDataGlycemicEventsGet
+// @Produce json
+// @Param userId path string true "user ID"
+// @Param startDate query string false "Only data with a time after or equal to this date (RFC3339)"
+// @Param endDate query string false "Only data with a time before or equal to this date (RFC3339)"
+// @Param deviceId query string false "Filter on the deviceId"
+// @Param uploadId query string false "Filter on the uploadId"
+// @Param units query string false "Units of the thresholds, required with any threshold, and of the glucose values, rounded as displayed by devices, otherwise mmol/L unrounded" Enums(mmol/L, mmol/l, mg/dL, mg/dl)
+// @Param low query number false "Low threshold" default(3.9)
+// @Param veryLow query number false "Very low threshold" default(3.0)
+// @Param high query number false "High threshold" default(13.9)
+// @Param lowDuration query int false "Minimum minutes of a low" minimum(5) maximum(1440) default(15)
+// @Param highDuration query int false "Minimum minutes of a high" minimum(5) maximum(1440) default(120)
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Security TidepoolAuthorization
+// @Security TidepoolRestrictedToken
+// @Success 200 {object} summary.GlycemicEventsReport "Operation is a success"
+// @Failure 400 {object} service.Error "User id is missing or query is malformed"
+// @Failure 403 {object} service.Error "Forbiden: caller is not authorized"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/users/:userId/data/glycemic_events [get]
+func UsersDataGlycemicEventsGet(dataServiceContext dataService.Context) {
+	req := dataServiceContext.Request()
+
+	targetUserID := req.PathParam("userId")
+	if targetUserID == "" {
+		dataServiceContext.RespondWithError(ErrorUserIDMissing())
+		return
+	}
+
+	permissions, err := dataServiceContext.PermissionClient().GetUserPermissions(req, targetUserID)
+	if err != nil {
+		if request.IsErrorUnauthorized(err) {
+			dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		} else {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get user permissions", err)
+		}
+		return
+	}
+	if !permissions {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	filter := dataStoreDEPRECATED.NewDataFilter()
+	options := summary.NewGlycemicEventsOptions()
+	glucoseOptions := converter.NewGlucoseOptions()
+	if err = request.DecodeRequestQuery(req.Request, filter, options, glucoseOptions); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+	if err = defaultDataFilterDates(filter, summary.GlycemicEventsRangeDefault); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+	filter.Type = &[]string{dataTypesBloodGlucoseContinuous.Type}
+	filter.SubType = nil
+
+	continuousData, err := iterateDataForUserByID(dataServiceContext, targetUserID, filter)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get data for user", err)
+		return
+	}
+
+	cgmSettingsData, err := settingsForUserByID(dataServiceContext, targetUserID, filter, dataTypesSettingsCgm.Type)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get data for user", err)
+		return
+	}
+
+	report := summary.CalculateGlycemicEvents(append(continuousData, cgmSettingsData...), options, *filter.StartDate, *filter.EndDate)
+	if glucoseOptions.Units != nil {
+		report.ConvertGlucoseUnits(*glucoseOptions.Units)
+	}
+
+	dataServiceContext.RespondWithStatusAndData(http.StatusOK, report)
+}
@@ -0,0 +1,108 @@
+package v1_test
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataStoreDEPRECATEDTest "github.com/tidepool-org/platform/data/storeDEPRECATED/test"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	dataTypesSettingsCgm "github.com/tidepool-org/platform/data/types/settings/cgm"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/service"
+	userTest "github.com/tidepool-org/platform/user/test"
+)
+
+var _ = Describe("UsersDataGlycemicEventsGet", func() {
+	var userID string
+	var context *TestContext
+	var startTime time.Time
+	var endTime time.Time
+
+	setRequest := func(query string) {
+		context.SetRequest(http.MethodGet, "/v1/users/"+userID+"/data/glycemic_events"+query, nil, map[string]string{"userId": userID}, nil)
+	}
+
+	BeforeEach(func() {
+		userID = userTest.RandomID()
+		startTime = time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+		endTime = time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC)
+		context = NewTestContext()
+		setRequest("?startDate=2020-03-01T00:00:00Z&endDate=2020-03-02T00:00:00Z")
+	})
+
+	AfterEach(func() {
+		context.dataSession.Expectations()
+		Expect(context.permissionClient.GetUserPermissionsOutputs).To(BeEmpty())
+	})
+
+	It("responds with unauthorized if the caller has no permissions", func() {
+		context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: false}}
+		dataServiceApiV1.UsersDataGlycemicEventsGet(context)
+		Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+		Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+	})
+
+	Context("with permissions", func() {
+		BeforeEach(func() {
+			context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: true}}
+		})
+
+		It("responds with bad request if the query is not valid", func() {
+			setRequest("?startDate=invalid")
+			dataServiceApiV1.UsersDataGlycemicEventsGet(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+		})
+
+		It("responds with bad request if the range is longer than the maximum", func() {
+			setRequest("?startDate=2018-01-01T00:00:00Z&endDate=2020-03-02T00:00:00Z")
+			dataServiceApiV1.UsersDataGlycemicEventsGet(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+		})
+
+		It("responds with failure if the data cannot be iterated", func() {
+			context.dataSession.IterateDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.IterateDataForUserByIDOutput{{Error: errorsTest.RandomError()}}
+			dataServiceApiV1.UsersDataGlycemicEventsGet(context)
+			Expect(context.failures).To(Equal([]string{"Unable to get data for user"}))
+		})
+
+		It("responds with failure if the latest CGM settings cannot be fetched", func() {
+			context.dataSession.IterateDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.IterateDataForUserByIDOutput{{DataIterator: dataStoreDEPRECATEDTest.NewDataIterator(data.Data{}, nil)}}
+			context.dataSession.GetDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.GetDataForUserByIDOutput{{Error: errorsTest.RandomError()}}
+			dataServiceApiV1.UsersDataGlycemicEventsGet(context)
+			Expect(context.failures).To(Equal([]string{"Unable to get data for user"}))
+		})
+
+		It("fetches only the latest CGM settings before the range and those within it", func() {
+			context.dataSession.IterateDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.IterateDataForUserByIDOutput{
+				{DataIterator: dataStoreDEPRECATEDTest.NewDataIterator(data.Data{}, nil)},
+				{DataIterator: dataStoreDEPRECATEDTest.NewDataIterator(data.Data{}, nil)},
+			}
+			context.dataSession.GetDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.GetDataForUserByIDOutput{{Data: data.Data{}}}
+			dataServiceApiV1.UsersDataGlycemicEventsGet(context)
+			Expect(context.errors).To(BeEmpty())
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			_, ok := context.data.(*summary.GlycemicEventsReport)
+			Expect(ok).To(BeTrue())
+
+			Expect(context.dataSession.IterateDataForUserByIDInputs).To(HaveLen(2))
+			Expect(*context.dataSession.IterateDataForUserByIDInputs[0].Filter.Type).To(Equal([]string{dataTypesBloodGlucoseContinuous.Type}))
+			Expect(context.dataSession.GetDataForUserByIDInputs).To(HaveLen(1))
+			latestFilter := context.dataSession.GetDataForUserByIDInputs[0].Filter
+			Expect(*latestFilter.Type).To(Equal([]string{dataTypesSettingsCgm.Type}))
+			Expect(*latestFilter.EndDate).To(BeTemporally("<", startTime))
+			Expect(context.dataSession.GetDataForUserByIDInputs[0].Pagination.Size).To(Equal(1))
+			rangeFilter := context.dataSession.IterateDataForUserByIDInputs[1].Filter
+			Expect(*rangeFilter.Type).To(Equal([]string{dataTypesSettingsCgm.Type}))
+			Expect(*rangeFilter.StartDate).To(BeTemporally("==", startTime))
+			Expect(*rangeFilter.EndDate).To(BeTemporally("==", endTime))
+		})
+	})
+})
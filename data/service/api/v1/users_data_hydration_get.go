@@ -0,0 +1,93 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/tidepool-org/platform/data"
+	dataService "github.com/tidepool-org/platform/data/service"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesWater "github.com/tidepool-org/platform/data/types/water"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+)
+
+// UsersDataHydrationGet godoc
+// @Summary Get daily hydration totals
+// @Description Get the total water intake of a user, in milliliters, for each local day with water data
+// @Description Caller must be a service, the owner, or have the authorizations to do it in behalf of the user.
+// @ID platform-data-api-UsersDataHydrationGet
+// @Produce json
+// @Param userId path string true "user ID"
+// @Param startDate query string false "Only data with a time after or equal to this date (RFC3339)"
+// @Param endDate query string false "Only data with a time before or equal to this date (RFC3339)"
+// @Param deviceId query string false "Filter on the deviceId"
+// @Param uploadId query string false "Filter on the uploadId"
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Security TidepoolAuthorization
+// @Security TidepoolRestrictedToken
+// @Success 200 {array} summary.HydrationDailyTotal "Operation is a success"
+// @Failure 400 {object} service.Error "User id is missing or query is malformed"
+// @Failure 403 {object} service.Error "Forbiden: caller is not authorized"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/users/:userId/data/hydration [get]
+func UsersDataHydrationGet(dataServiceContext dataService.Context) {
+	req := dataServiceContext.Request()
+
+	targetUserID := req.PathParam("userId")
+	if targetUserID == "" {
+		dataServiceContext.RespondWithError(ErrorUserIDMissing())
+		return
+	}
+
+	permissions, err := dataServiceContext.PermissionClient().GetUserPermissions(req, targetUserID)
+	if err != nil {
+		if request.IsErrorUnauthorized(err) {
+			dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		} else {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get user permissions", err)
+		}
+		return
+	}
+	if !permissions {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	filter := dataStoreDEPRECATED.NewDataFilter()
+	if err = request.DecodeRequestQuery(req.Request, filter); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+	filter.Type = &[]string{dataTypesWater.Type}
+	filter.SubType = nil
+
+	waterData, err := iterateDataForUserByID(dataServiceContext, targetUserID, filter)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get data for user", err)
+		return
+	}
+
+	dataServiceContext.RespondWithStatusAndData(http.StatusOK, summary.HydrationDailyTotals(waterData))
+}
+
+// iterateDataForUserByID reads all the data for the user matching the filter, in chronological order
+func iterateDataForUserByID(dataServiceContext dataService.Context, userID string, filter *dataStoreDEPRECATED.DataFilter) (data.Data, error) {
+	ctx := dataServiceContext.Request().Context()
+
+	iterator, err := dataServiceContext.DataSession().IterateDataForUserByID(ctx, userID, filter, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	result := data.Data{}
+	for iterator.Next(ctx) {
+		result = append(result, iterator.Datum())
+	}
+	if err = iterator.Error(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,91 @@
+package v1_test
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataStoreDEPRECATEDTest "github.com/tidepool-org/platform/data/storeDEPRECATED/test"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesWater "github.com/tidepool-org/platform/data/types/water"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/service"
+	userTest "github.com/tidepool-org/platform/user/test"
+)
+
+func NewWater(tm string, units string, value float64) *dataTypesWater.Water {
+	datum := dataTypesWater.New()
+	datum.Time = pointer.FromString(tm)
+	datum.Amount = &dataTypesWater.Amount{Units: pointer.FromString(units), Value: pointer.FromFloat64(value)}
+	return datum
+}
+
+var _ = Describe("UsersDataHydrationGet", func() {
+	var userID string
+	var context *TestContext
+
+	setRequest := func(query string) {
+		context.SetRequest(http.MethodGet, "/v1/users/"+userID+"/data/hydration"+query, nil, map[string]string{"userId": userID}, nil)
+	}
+
+	BeforeEach(func() {
+		userID = userTest.RandomID()
+		context = NewTestContext()
+		setRequest("?startDate=2020-03-01T00:00:00Z&endDate=2020-03-03T00:00:00Z")
+	})
+
+	AfterEach(func() {
+		context.dataSession.Expectations()
+		Expect(context.permissionClient.GetUserPermissionsOutputs).To(BeEmpty())
+	})
+
+	It("responds with unauthorized if the caller has no permissions", func() {
+		context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: false}}
+		dataServiceApiV1.UsersDataHydrationGet(context)
+		Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+		Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+	})
+
+	Context("with permissions", func() {
+		BeforeEach(func() {
+			context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: true}}
+		})
+
+		It("responds with bad request if the query is not valid", func() {
+			setRequest("?startDate=invalid")
+			dataServiceApiV1.UsersDataHydrationGet(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+		})
+
+		It("responds with failure if the data cannot be iterated", func() {
+			context.dataSession.IterateDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.IterateDataForUserByIDOutput{{Error: errorsTest.RandomError()}}
+			dataServiceApiV1.UsersDataHydrationGet(context)
+			Expect(context.failures).To(Equal([]string{"Unable to get data for user"}))
+		})
+
+		It("responds with the daily totals of the water data only", func() {
+			context.dataSession.IterateDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.IterateDataForUserByIDOutput{{DataIterator: dataStoreDEPRECATEDTest.NewDataIterator(data.Data{
+				NewWater("2020-03-01T08:00:00Z", dataTypesWater.AmountUnitsMilliliters, 250),
+				NewWater("2020-03-01T12:00:00Z", dataTypesWater.AmountUnitsLiters, 0.5),
+				NewWater("2020-03-02T08:00:00Z", dataTypesWater.AmountUnitsMilliliters, 300),
+			}, nil)}}
+			dataServiceApiV1.UsersDataHydrationGet(context)
+			Expect(context.errors).To(BeEmpty())
+			Expect(context.failures).To(BeEmpty())
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			Expect(context.data).To(Equal([]*summary.HydrationDailyTotal{
+				{Date: "2020-03-01", Count: 2, Total: 750, Units: dataTypesWater.AmountUnitsMilliliters},
+				{Date: "2020-03-02", Count: 1, Total: 300, Units: dataTypesWater.AmountUnitsMilliliters},
+			}))
+			Expect(context.dataSession.IterateDataForUserByIDInputs).To(HaveLen(1))
+			filter := context.dataSession.IterateDataForUserByIDInputs[0].Filter
+			Expect(*filter.Type).To(Equal([]string{dataTypesWater.Type}))
+			Expect(filter.SubType).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,140 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/tidepool-org/platform/data"
+	dataService "github.com/tidepool-org/platform/data/service"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesBasal "github.com/tidepool-org/platform/data/types/basal"
+	dataTypesBolus "github.com/tidepool-org/platform/data/types/bolus"
+	dataTypesSettingsPump "github.com/tidepool-org/platform/data/types/settings/pump"
+	"github.com/tidepool-org/platform/page"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+)
+
+// UsersDataInsulinGet godoc
+// @Summary Get the insulin delivery
+// @Description Get the total, basal and bolus insulin delivered each local day, in units, and the insulin on board every
+// @Description 15 minutes, derived from the boluses using the insulin action duration of the latest pump settings at or
+// @Description before the end date, including the boluses delivered within that duration before the start date.
+// @Description With segmentBy=reportedState, also the mean daily insulin of the local days during which each reported state
+// @Description was active, and of the local days without any.
+// @Description The range defaults to the 14 days before the end date, which defaults to now.
+// @Description The range is at most 366 days.
+// @Description Caller must be a service, the owner, or have the authorizations to do it in behalf of the user.
+// @ID platform-data-api-UsersDataInsulinGet
+// @Produce json
+// @Param userId path string true "user ID"
+// @Param startDate query string false "Only data with a time after or equal to this date (RFC3339)"
+// @Param endDate query string false "Only data with a time before or equal to this date (RFC3339)"
+// @Param deviceId query string false "Filter on the deviceId"
+// @Param uploadId query string false "Filter on the uploadId"
+// @Param segmentBy query string false "Also segment the local days by the reported states active during the day" Enums(reportedState)
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Security TidepoolAuthorization
+// @Security TidepoolRestrictedToken
+// @Success 200 {object} summary.InsulinReport "Operation is a success"
+// @Failure 400 {object} service.Error "User id is missing or query is malformed"
+// @Failure 403 {object} service.Error "Forbiden: caller is not authorized"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/users/:userId/data/insulin [get]
+func UsersDataInsulinGet(dataServiceContext dataService.Context) {
+	req := dataServiceContext.Request()
+
+	targetUserID := req.PathParam("userId")
+	if targetUserID == "" {
+		dataServiceContext.RespondWithError(ErrorUserIDMissing())
+		return
+	}
+
+	permissions, err := dataServiceContext.PermissionClient().GetUserPermissions(req, targetUserID)
+	if err != nil {
+		if request.IsErrorUnauthorized(err) {
+			dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		} else {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get user permissions", err)
+		}
+		return
+	}
+	if !permissions {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	filter := dataStoreDEPRECATED.NewDataFilter()
+	segmentOptions := summary.NewSegmentOptions()
+	if err = request.DecodeRequestQuery(req.Request, filter, segmentOptions); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+	if err = defaultDataFilterDates(filter, summary.InsulinRangeDefault); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+
+	pumpSettingsData, err := settingsForUserByID(dataServiceContext, targetUserID, filter, dataTypesSettingsPump.Type)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get data for user", err)
+		return
+	}
+
+	// The boluses delivered before the range, within the insulin action duration, are still on board during the range
+	insulinFilter := *filter
+	insulinFilter.StartDate = pointer.FromTime(filter.StartDate.Add(-summary.InsulinActionDuration(pumpSettingsData, *filter.EndDate)))
+	insulinFilter.Type = &[]string{dataTypesBasal.Type, dataTypesBolus.Type}
+	insulinFilter.SubType = nil
+
+	insulinData, err := iterateDataForUserByID(dataServiceContext, targetUserID, &insulinFilter)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get data for user", err)
+		return
+	}
+
+	report := summary.CalculateInsulin(append(insulinData, pumpSettingsData...), *filter.StartDate, *filter.EndDate)
+	if segmentOptions.SegmentByReportedState() {
+		periods, err := reportedStatePeriodsForUserByID(dataServiceContext, targetUserID, filter)
+		if err != nil {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get data for user", err)
+			return
+		}
+		report.Segments = summary.CalculateInsulinSegments(report.Daily, periods)
+	}
+
+	dataServiceContext.RespondWithStatusAndData(http.StatusOK, report)
+}
+
+// settingsForUserByID returns the settings datums of the type in effect during the filter dates, with the filter
+// device: the latest before the start date, usually older than the range, and those within the range
+func settingsForUserByID(dataServiceContext dataService.Context, userID string, filter *dataStoreDEPRECATED.DataFilter, settingsType string) (data.Data, error) {
+	ctx := dataServiceContext.Request().Context()
+
+	latestFilter := dataStoreDEPRECATED.NewDataFilter()
+	latestFilter.EndDate = pointer.FromTime(filter.StartDate.Add(-time.Nanosecond))
+	latestFilter.DeviceID = filter.DeviceID
+	latestFilter.Type = &[]string{settingsType}
+	latestPagination := page.NewPagination()
+	latestPagination.Size = 1
+
+	latestData, err := dataServiceContext.DataSession().GetDataForUserByID(ctx, userID, latestFilter, latestPagination)
+	if err != nil {
+		return nil, err
+	}
+
+	rangeFilter := dataStoreDEPRECATED.NewDataFilter()
+	rangeFilter.StartDate = filter.StartDate
+	rangeFilter.EndDate = filter.EndDate
+	rangeFilter.DeviceID = filter.DeviceID
+	rangeFilter.Type = &[]string{settingsType}
+
+	rangeData, err := iterateDataForUserByID(dataServiceContext, userID, rangeFilter)
+	if err != nil {
+		return nil, err
+	}
+	return append(latestData, rangeData...), nil
+}
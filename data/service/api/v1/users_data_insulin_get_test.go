@@ -0,0 +1,129 @@
+package v1_test
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataStoreDEPRECATEDTest "github.com/tidepool-org/platform/data/storeDEPRECATED/test"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesBasal "github.com/tidepool-org/platform/data/types/basal"
+	dataTypesBolus "github.com/tidepool-org/platform/data/types/bolus"
+	dataTypesSettingsPump "github.com/tidepool-org/platform/data/types/settings/pump"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/service"
+	userTest "github.com/tidepool-org/platform/user/test"
+)
+
+var _ = Describe("UsersDataInsulinGet", func() {
+	var userID string
+	var context *TestContext
+	var startTime time.Time
+	var endTime time.Time
+
+	setRequest := func(query string) {
+		context.SetRequest(http.MethodGet, "/v1/users/"+userID+"/data/insulin"+query, nil, map[string]string{"userId": userID}, nil)
+	}
+
+	newPumpSettings := func(tm string, durationHours float64) *dataTypesSettingsPump.Pump {
+		datum := dataTypesSettingsPump.New()
+		datum.Time = pointer.FromString(tm)
+		datum.Bolus = &dataTypesSettingsPump.Bolus{Calculator: &dataTypesSettingsPump.BolusCalculator{Insulin: &dataTypesSettingsPump.BolusCalculatorInsulin{
+			Duration: pointer.FromFloat64(durationHours),
+			Units:    pointer.FromString(dataTypesSettingsPump.BolusCalculatorInsulinUnitsHours),
+		}}}
+		return datum
+	}
+
+	BeforeEach(func() {
+		userID = userTest.RandomID()
+		startTime = time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+		endTime = time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC)
+		context = NewTestContext()
+		setRequest("?startDate=2020-03-01T00:00:00Z&endDate=2020-03-02T00:00:00Z")
+	})
+
+	AfterEach(func() {
+		context.dataSession.Expectations()
+		Expect(context.permissionClient.GetUserPermissionsOutputs).To(BeEmpty())
+	})
+
+	It("responds with unauthorized if the caller has no permissions", func() {
+		context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: false}}
+		dataServiceApiV1.UsersDataInsulinGet(context)
+		Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+		Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+	})
+
+	Context("with permissions", func() {
+		BeforeEach(func() {
+			context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: true}}
+		})
+
+		It("responds with bad request if the query is not valid", func() {
+			setRequest("?segmentBy=invalid")
+			dataServiceApiV1.UsersDataInsulinGet(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+		})
+
+		It("responds with bad request if the range is longer than the maximum", func() {
+			setRequest("?startDate=2018-01-01T00:00:00Z&endDate=2020-03-02T00:00:00Z")
+			dataServiceApiV1.UsersDataInsulinGet(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataSession.GetDataForUserByIDInvocations).To(Equal(0))
+		})
+
+		It("responds with failure if the latest pump settings cannot be fetched", func() {
+			context.dataSession.GetDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.GetDataForUserByIDOutput{{Error: errorsTest.RandomError()}}
+			dataServiceApiV1.UsersDataInsulinGet(context)
+			Expect(context.failures).To(Equal([]string{"Unable to get data for user"}))
+			Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+		})
+
+		It("responds with failure if the data cannot be iterated", func() {
+			context.dataSession.GetDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.GetDataForUserByIDOutput{{Data: data.Data{}}}
+			context.dataSession.IterateDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.IterateDataForUserByIDOutput{
+				{DataIterator: dataStoreDEPRECATEDTest.NewDataIterator(data.Data{}, nil)},
+				{Error: errorsTest.RandomError()},
+			}
+			dataServiceApiV1.UsersDataInsulinGet(context)
+			Expect(context.failures).To(Equal([]string{"Unable to get data for user"}))
+		})
+
+		It("fetches only the pump settings in effect during the range and the deliveries still on board at its start", func() {
+			context.dataSession.IterateDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.IterateDataForUserByIDOutput{
+				{DataIterator: dataStoreDEPRECATEDTest.NewDataIterator(data.Data{newPumpSettings("2020-03-01T12:00:00Z", 3)}, nil)},
+				{DataIterator: dataStoreDEPRECATEDTest.NewDataIterator(data.Data{}, nil)},
+			}
+			context.dataSession.GetDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.GetDataForUserByIDOutput{{Data: data.Data{newPumpSettings("2020-02-01T00:00:00Z", 5)}}}
+			dataServiceApiV1.UsersDataInsulinGet(context)
+			Expect(context.errors).To(BeEmpty())
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			report, ok := context.data.(*summary.InsulinReport)
+			Expect(ok).To(BeTrue())
+			Expect(report.InsulinActionDuration).To(Equal(180))
+
+			Expect(context.dataSession.IterateDataForUserByIDInputs).To(HaveLen(2))
+			Expect(context.dataSession.GetDataForUserByIDInputs).To(HaveLen(1))
+			latestFilter := context.dataSession.GetDataForUserByIDInputs[0].Filter
+			Expect(*latestFilter.Type).To(Equal([]string{dataTypesSettingsPump.Type}))
+			Expect(latestFilter.StartDate).To(BeNil())
+			Expect(*latestFilter.EndDate).To(BeTemporally("<", startTime))
+			Expect(context.dataSession.GetDataForUserByIDInputs[0].Pagination.Size).To(Equal(1))
+			rangeFilter := context.dataSession.IterateDataForUserByIDInputs[0].Filter
+			Expect(*rangeFilter.Type).To(Equal([]string{dataTypesSettingsPump.Type}))
+			Expect(*rangeFilter.StartDate).To(BeTemporally("==", startTime))
+			Expect(*rangeFilter.EndDate).To(BeTemporally("==", endTime))
+			insulinFilter := context.dataSession.IterateDataForUserByIDInputs[1].Filter
+			Expect(*insulinFilter.Type).To(Equal([]string{dataTypesBasal.Type, dataTypesBolus.Type}))
+			Expect(*insulinFilter.StartDate).To(BeTemporally("==", startTime.Add(-3*time.Hour)))
+			Expect(*insulinFilter.EndDate).To(BeTemporally("==", endTime))
+		})
+	})
+})
@@ -0,0 +1,73 @@
+package v1
+
+import (
+	"net/http"
+
+	dataService "github.com/tidepool-org/platform/data/service"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/data/summary"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+)
+
+// UsersDataMealsAssociationsCreate godoc
+// @Summary Record the meal associations
+// @Description Correlate the meals of a user, as UsersDataMealsGet does, and record the datum associations of each meal
+// @Description on its food or bolus calculator datum, in place of the meal associations recorded before. The other
+// @Description associations of the datum are kept. Glucose values are in mmol/L. The daily summaries rebuilds record
+// @Description the meal associations for the modified data, so this is only needed to record them again over a range.
+// @Description The range defaults to the 14 days before the end date, which defaults to now.
+// @Description The range is at most 366 days.
+// @Description Only services (eg. not users) can record the meal associations
+// @ID platform-data-api-UsersDataMealsAssociationsCreate
+// @Produce json
+// @Param userId path string true "user ID"
+// @Param startDate query string false "Only meals with a time after or equal to this date (RFC3339)"
+// @Param endDate query string false "Only meals with a time before or equal to this date (RFC3339)"
+// @Param deviceId query string false "Filter on the deviceId"
+// @Param uploadId query string false "Filter on the uploadId"
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Success 200 {object} summary.MealsReport "Operation is a success"
+// @Failure 400 {object} service.Error "User id is missing or query is malformed"
+// @Failure 403 {object} service.Error "Forbiden: caller is not a service"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/users/:userId/data/meals/associations [post]
+func UsersDataMealsAssociationsCreate(dataServiceContext dataService.Context) {
+	req := dataServiceContext.Request()
+	ctx := req.Context()
+
+	targetUserID := req.PathParam("userId")
+	if targetUserID == "" {
+		dataServiceContext.RespondWithError(ErrorUserIDMissing())
+		return
+	}
+
+	if details := request.DetailsFromContext(ctx); !details.IsService() {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	filter := dataStoreDEPRECATED.NewDataFilter()
+	if err := request.DecodeRequestQuery(req.Request, filter); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+	if err := defaultDataFilterDates(filter, summary.MealsRangeDefault); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+
+	mealsData, report, err := calculateMealsForUserByID(dataServiceContext, targetUserID, filter)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get data for user", err)
+		return
+	}
+
+	if err = dataServiceContext.DataSession().UpdateDataAssociations(ctx, targetUserID, summary.MealDataAssociations(mealsData, report)); err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to update data associations", err)
+		return
+	}
+
+	dataServiceContext.RespondWithStatusAndData(http.StatusOK, report)
+}
@@ -0,0 +1,85 @@
+package v1_test
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataStoreDEPRECATEDTest "github.com/tidepool-org/platform/data/storeDEPRECATED/test"
+	dataTypesBolusNormal "github.com/tidepool-org/platform/data/types/bolus/normal"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+	userTest "github.com/tidepool-org/platform/user/test"
+)
+
+var _ = Describe("UsersDataMealsAssociationsCreate", func() {
+	var userID string
+	var context *TestContext
+
+	setRequest := func(details request.Details) {
+		context.SetRequest(http.MethodPost, "/v1/users/"+userID+"/data/meals/associations?startDate=2020-03-01T00:00:00Z&endDate=2020-03-02T00:00:00Z", nil, map[string]string{"userId": userID}, details)
+	}
+
+	BeforeEach(func() {
+		userID = userTest.RandomID()
+		context = NewTestContext()
+		setRequest(request.NewDetails(request.MethodServiceSecret, "", ""))
+	})
+
+	AfterEach(func() {
+		context.dataSession.Expectations()
+	})
+
+	It("responds with unauthorized if the caller is not a service", func() {
+		setRequest(request.NewDetails(request.MethodSessionToken, userID, "token"))
+		dataServiceApiV1.UsersDataMealsAssociationsCreate(context)
+		Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+		Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+	})
+
+	It("responds with bad request if the range is longer than the maximum", func() {
+		context.SetRequest(http.MethodPost, "/v1/users/"+userID+"/data/meals/associations?startDate=2018-01-01T00:00:00Z&endDate=2020-03-02T00:00:00Z", nil, map[string]string{"userId": userID}, request.NewDetails(request.MethodServiceSecret, "", ""))
+		dataServiceApiV1.UsersDataMealsAssociationsCreate(context)
+		Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+		Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+	})
+
+	Context("with the meal data", func() {
+		BeforeEach(func() {
+			bolus := dataTypesBolusNormal.New()
+			bolus.ID = pointer.FromString("bolus")
+			bolus.Time = pointer.FromString("2020-03-01T12:10:00Z")
+			bolus.Normal = pointer.FromFloat64(3)
+			datums := data.Data{NewMealsFood("food", "2020-03-01T12:00:00Z", 30), bolus}
+			context.dataSession.IterateDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.IterateDataForUserByIDOutput{{DataIterator: dataStoreDEPRECATEDTest.NewDataIterator(datums, nil)}}
+		})
+
+		It("responds with failure if the data associations cannot be updated", func() {
+			context.dataSession.UpdateDataAssociationsOutputs = []error{errorsTest.RandomError()}
+			dataServiceApiV1.UsersDataMealsAssociationsCreate(context)
+			Expect(context.failures).To(Equal([]string{"Unable to update data associations"}))
+		})
+
+		It("records the associations of the meal datums", func() {
+			context.dataSession.UpdateDataAssociationsOutputs = []error{nil}
+			dataServiceApiV1.UsersDataMealsAssociationsCreate(context)
+			Expect(context.errors).To(BeEmpty())
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			Expect(context.dataSession.UpdateDataAssociationsInputs).To(HaveLen(1))
+			Expect(context.dataSession.UpdateDataAssociationsInputs[0].UserID).To(Equal(userID))
+			associations := context.dataSession.UpdateDataAssociationsInputs[0].Associations
+			Expect(associations).To(HaveKey("food"))
+			Expect(associations["food"]).ToNot(BeNil())
+			ids := []string{}
+			for _, datumAssociation := range *associations["food"] {
+				ids = append(ids, pointer.ToString(datumAssociation.ID))
+			}
+			Expect(ids).To(ContainElement("bolus"))
+		})
+	})
+})
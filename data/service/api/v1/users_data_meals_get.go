@@ -0,0 +1,107 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/tidepool-org/platform/data"
+	"github.com/tidepool-org/platform/data/converter"
+	dataService "github.com/tidepool-org/platform/data/service"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/data/summary"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+)
+
+// UsersDataMealsGet godoc
+// @Summary Get the meal correlations
+// @Description Get the meals of a user, from the food and bolus calculator data, each with the associated bolus, the
+// @Description pre-meal glucose, the 2-hour post-prandial peak and delta, and the time to return to range. The datums
+// @Description related to each meal are referenced by datum associations, which the daily summaries rebuilds record on
+// @Description the meal datums for the modified data, as UsersDataMealsAssociationsCreate does. Glucose values are in
+// @Description mmol/L, unless units specified.
+// @Description The range defaults to the 14 days before the end date, which defaults to now.
+// @Description The range is at most 366 days.
+// @Description Caller must be a service, the owner, or have the authorizations to do it in behalf of the user.
+// @ID platform-data-api-UsersDataMealsGet
+// @Produce json
+// @Param userId path string true "user ID"
+// @Param startDate query string false "Only meals with a time after or equal to this date (RFC3339)"
+// @Param endDate query string false "Only meals with a time before or equal to this date (RFC3339)"
+// @Param deviceId query string false "Filter on the deviceId"
+// @Param uploadId query string false "Filter on the uploadId"
+// @Param units query string false "Units of the glucose values, rounded as displayed by devices, otherwise mmol/L unrounded" Enums(mmol/L, mmol/l, mg/dL, mg/dl)
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Security TidepoolAuthorization
+// @Security TidepoolRestrictedToken
+// @Success 200 {object} summary.MealsReport "Operation is a success"
+// @Failure 400 {object} service.Error "User id is missing or query is malformed"
+// @Failure 403 {object} service.Error "Forbiden: caller is not authorized"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/users/:userId/data/meals [get]
+func UsersDataMealsGet(dataServiceContext dataService.Context) {
+	req := dataServiceContext.Request()
+
+	targetUserID := req.PathParam("userId")
+	if targetUserID == "" {
+		dataServiceContext.RespondWithError(ErrorUserIDMissing())
+		return
+	}
+
+	permissions, err := dataServiceContext.PermissionClient().GetUserPermissions(req, targetUserID)
+	if err != nil {
+		if request.IsErrorUnauthorized(err) {
+			dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		} else {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get user permissions", err)
+		}
+		return
+	}
+	if !permissions {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	filter := dataStoreDEPRECATED.NewDataFilter()
+	glucoseOptions := converter.NewGlucoseOptions()
+	if err = request.DecodeRequestQuery(req.Request, filter, glucoseOptions); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+	if err = defaultDataFilterDates(filter, summary.MealsRangeDefault); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+
+	_, report, err := calculateMealsForUserByID(dataServiceContext, targetUserID, filter)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get data for user", err)
+		return
+	}
+	if glucoseOptions.Units != nil {
+		report.ConvertGlucoseUnits(*glucoseOptions.Units)
+	}
+
+	dataServiceContext.RespondWithStatusAndData(http.StatusOK, report)
+}
+
+// calculateMealsForUserByID returns the meal data of the user and the meals correlated from it within the filter
+// dates, already defaulted
+func calculateMealsForUserByID(dataServiceContext dataService.Context, userID string, filter *dataStoreDEPRECATED.DataFilter) (data.Data, *summary.MealsReport, error) {
+	startTime := *filter.StartDate
+	endTime := *filter.EndDate
+
+	mealsFilter := *filter
+	mealsStartTime, mealsEndTime := summary.MealsDataTimeRange(startTime, endTime)
+	mealsFilter.StartDate = pointer.FromTime(mealsStartTime)
+	mealsFilter.EndDate = pointer.FromTime(mealsEndTime)
+	mealsFilter.Type = pointer.FromStringArray(summary.MealsDataTypes())
+	mealsFilter.SubType = nil
+
+	mealsData, err := iterateDataForUserByID(dataServiceContext, userID, &mealsFilter)
+	if err != nil {
+		return nil, nil, err
+	}
+	return mealsData, summary.CalculateMeals(mealsData, startTime, endTime), nil
+}
@@ -0,0 +1,98 @@
+package v1_test
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataStoreDEPRECATEDTest "github.com/tidepool-org/platform/data/storeDEPRECATED/test"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesFood "github.com/tidepool-org/platform/data/types/food"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/service"
+	userTest "github.com/tidepool-org/platform/user/test"
+)
+
+func NewMealsFood(id string, tm string, carbohydrate float64) *dataTypesFood.Food {
+	datum := dataTypesFood.New()
+	datum.ID = pointer.FromString(id)
+	datum.Time = pointer.FromString(tm)
+	datum.Nutrition = &dataTypesFood.Nutrition{Carbohydrate: &dataTypesFood.Carbohydrate{Net: pointer.FromFloat64(carbohydrate)}}
+	return datum
+}
+
+var _ = Describe("UsersDataMealsGet", func() {
+	var userID string
+	var context *TestContext
+
+	setRequest := func(query string) {
+		context.SetRequest(http.MethodGet, "/v1/users/"+userID+"/data/meals"+query, nil, map[string]string{"userId": userID}, nil)
+	}
+
+	BeforeEach(func() {
+		userID = userTest.RandomID()
+		context = NewTestContext()
+		setRequest("")
+	})
+
+	AfterEach(func() {
+		context.dataSession.Expectations()
+		Expect(context.permissionClient.GetUserPermissionsOutputs).To(BeEmpty())
+	})
+
+	It("responds with unauthorized if the caller has no permissions", func() {
+		context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: false}}
+		dataServiceApiV1.UsersDataMealsGet(context)
+		Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+		Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+	})
+
+	Context("with permissions", func() {
+		BeforeEach(func() {
+			context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: true}}
+		})
+
+		It("responds with bad request if the query is not valid", func() {
+			setRequest("?startDate=invalid")
+			dataServiceApiV1.UsersDataMealsGet(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+		})
+
+		It("responds with bad request if the range is longer than the maximum", func() {
+			setRequest("?startDate=2018-01-01T00:00:00Z&endDate=2020-03-02T00:00:00Z")
+			dataServiceApiV1.UsersDataMealsGet(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+		})
+
+		It("responds with failure if the data cannot be iterated", func() {
+			context.dataSession.IterateDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.IterateDataForUserByIDOutput{{Error: errorsTest.RandomError()}}
+			dataServiceApiV1.UsersDataMealsGet(context)
+			Expect(context.failures).To(Equal([]string{"Unable to get data for user"}))
+		})
+
+		It("responds with the meals, from the data of the meal types around the range", func() {
+			setRequest("?startDate=2020-03-01T00:00:00Z&endDate=2020-03-02T00:00:00Z")
+			datums := data.Data{NewMealsFood("food", "2020-03-01T12:00:00Z", 30)}
+			context.dataSession.IterateDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.IterateDataForUserByIDOutput{{DataIterator: dataStoreDEPRECATEDTest.NewDataIterator(datums, nil)}}
+			dataServiceApiV1.UsersDataMealsGet(context)
+			Expect(context.errors).To(BeEmpty())
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			report, ok := context.data.(*summary.MealsReport)
+			Expect(ok).To(BeTrue())
+			Expect(report.Meals).To(HaveLen(1))
+			Expect(report.Meals[0].ID).To(Equal("food"))
+			Expect(context.dataSession.IterateDataForUserByIDInputs).To(HaveLen(1))
+			filter := context.dataSession.IterateDataForUserByIDInputs[0].Filter
+			Expect(*filter.Type).To(Equal(summary.MealsDataTypes()))
+			Expect(*filter.StartDate).To(BeTemporally("==", time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC).Add(-summary.MealBolusWindow)))
+			Expect(*filter.EndDate).To(BeTemporally("==", time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC).Add(summary.MealReturnToRangeWindow)))
+		})
+	})
+})
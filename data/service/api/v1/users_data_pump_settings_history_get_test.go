@@ -0,0 +1,93 @@
+package v1_test
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataStoreDEPRECATEDTest "github.com/tidepool-org/platform/data/storeDEPRECATED/test"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesSettingsPump "github.com/tidepool-org/platform/data/types/settings/pump"
+	errorsTest "github.com/tidepool-org/platform/errors/test"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/service"
+	userTest "github.com/tidepool-org/platform/user/test"
+)
+
+var _ = Describe("UsersDataPumpSettingsHistoryGet", func() {
+	var userID string
+	var context *TestContext
+
+	setRequest := func(query string) {
+		context.SetRequest(http.MethodGet, "/v1/users/"+userID+"/data/pump_settings_history"+query, nil, map[string]string{"userId": userID}, nil)
+	}
+
+	newPumpSettings := func(tm string, deviceID string) *dataTypesSettingsPump.Pump {
+		datum := dataTypesSettingsPump.New()
+		datum.Time = pointer.FromString(tm)
+		datum.DeviceID = pointer.FromString(deviceID)
+		return datum
+	}
+
+	BeforeEach(func() {
+		userID = userTest.RandomID()
+		context = NewTestContext()
+		setRequest("?startDate=2020-03-01T00:00:00Z&endDate=2020-03-31T00:00:00Z")
+	})
+
+	AfterEach(func() {
+		context.dataSession.Expectations()
+		Expect(context.permissionClient.GetUserPermissionsOutputs).To(BeEmpty())
+	})
+
+	It("responds with unauthorized if the caller has no permissions", func() {
+		context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: false}}
+		dataServiceApiV1.UsersDataPumpSettingsHistoryGet(context)
+		Expect(context.errors).To(Equal([]*service.Error{service.ErrorUnauthorized()}))
+		Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+	})
+
+	Context("with permissions", func() {
+		BeforeEach(func() {
+			context.permissionClient.GetUserPermissionsOutputs = []GetUserPermissionsOutput{{Permissions: true}}
+		})
+
+		It("responds with bad request if the query is not valid", func() {
+			setRequest("?units=invalid")
+			dataServiceApiV1.UsersDataPumpSettingsHistoryGet(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+		})
+
+		It("responds with bad request if the range is longer than the maximum", func() {
+			setRequest("?startDate=2018-01-01T00:00:00Z&endDate=2020-03-31T00:00:00Z")
+			dataServiceApiV1.UsersDataPumpSettingsHistoryGet(context)
+			Expect(context.ResponseStatusCode()).To(Equal(http.StatusBadRequest))
+			Expect(context.dataSession.IterateDataForUserByIDInvocations).To(Equal(0))
+		})
+
+		It("responds with failure if the data cannot be iterated", func() {
+			context.dataSession.IterateDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.IterateDataForUserByIDOutput{{Error: errorsTest.RandomError()}}
+			dataServiceApiV1.UsersDataPumpSettingsHistoryGet(context)
+			Expect(context.failures).To(Equal([]string{"Unable to get data for user"}))
+		})
+
+		It("responds with the history of the pump settings of each device", func() {
+			datums := data.Data{newPumpSettings("2020-03-02T00:00:00Z", "pump-a"), newPumpSettings("2020-03-03T00:00:00Z", "pump-b")}
+			context.dataSession.IterateDataForUserByIDOutputs = []dataStoreDEPRECATEDTest.IterateDataForUserByIDOutput{{DataIterator: dataStoreDEPRECATEDTest.NewDataIterator(datums, nil)}}
+			dataServiceApiV1.UsersDataPumpSettingsHistoryGet(context)
+			Expect(context.errors).To(BeEmpty())
+			Expect(context.statusCode).To(Equal(http.StatusOK))
+			history, ok := context.data.(*summary.PumpSettingsHistory)
+			Expect(ok).To(BeTrue())
+			Expect(history.Snapshots).To(HaveLen(2))
+			Expect(history.Snapshots[0].Settings).ToNot(BeNil())
+			Expect(history.Snapshots[1].Settings).ToNot(BeNil())
+			Expect(context.dataSession.IterateDataForUserByIDInputs).To(HaveLen(1))
+			Expect(*context.dataSession.IterateDataForUserByIDInputs[0].Filter.Type).To(Equal([]string{dataTypesSettingsPump.Type}))
+		})
+	})
+})
@@ -0,0 +1,106 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/tidepool-org/platform/data"
+	dataService "github.com/tidepool-org/platform/data/service"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesStateReported "github.com/tidepool-org/platform/data/types/state/reported"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/service"
+)
+
+// UsersDataReportedStatePeriodsGet godoc
+// @Summary Get the reported state periods
+// @Description Get the periods during which each state reported by a user (illness, stress, alcohol, cycle...) was active,
+// @Description with the highest severity reported. A state is active from the time it is reported until the next report
+// @Description not including it, and for at most 24 hours after the last report including it.
+// @Description The range defaults to the 14 days before the end date, which defaults to now.
+// @Description The range is at most 366 days.
+// @Description Caller must be a service, the owner, or have the authorizations to do it in behalf of the user.
+// @ID platform-data-api-UsersDataReportedStatePeriodsGet
+// @Produce json
+// @Param userId path string true "user ID"
+// @Param startDate query string false "Only periods active after or at this date (RFC3339)"
+// @Param endDate query string false "Only periods active before or at this date (RFC3339)"
+// @Param deviceId query string false "Filter on the deviceId"
+// @Param uploadId query string false "Filter on the uploadId"
+// @Security TidepoolSessionToken
+// @Security TidepoolServiceSecret
+// @Security TidepoolAuthorization
+// @Security TidepoolRestrictedToken
+// @Success 200 {object} summary.ReportedStatePeriodsReport "Operation is a success"
+// @Failure 400 {object} service.Error "User id is missing or query is malformed"
+// @Failure 403 {object} service.Error "Forbiden: caller is not authorized"
+// @Failure 500 {object} service.Error "Unable to perform the operation"
+// @Router /v1/users/:userId/data/reported_state_periods [get]
+func UsersDataReportedStatePeriodsGet(dataServiceContext dataService.Context) {
+	req := dataServiceContext.Request()
+
+	targetUserID := req.PathParam("userId")
+	if targetUserID == "" {
+		dataServiceContext.RespondWithError(ErrorUserIDMissing())
+		return
+	}
+
+	permissions, err := dataServiceContext.PermissionClient().GetUserPermissions(req, targetUserID)
+	if err != nil {
+		if request.IsErrorUnauthorized(err) {
+			dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		} else {
+			dataServiceContext.RespondWithInternalServerFailure("Unable to get user permissions", err)
+		}
+		return
+	}
+	if !permissions {
+		dataServiceContext.RespondWithError(service.ErrorUnauthorized())
+		return
+	}
+
+	filter := dataStoreDEPRECATED.NewDataFilter()
+	if err = request.DecodeRequestQuery(req.Request, filter); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+	if err = defaultDataFilterDates(filter, summary.ReportedStateRangeDefault); err != nil {
+		request.MustNewResponder(dataServiceContext.Response(), req).Error(http.StatusBadRequest, err)
+		return
+	}
+
+	reportedStateData, err := iterateReportedStateDataForUserByID(dataServiceContext, targetUserID, filter)
+	if err != nil {
+		dataServiceContext.RespondWithInternalServerFailure("Unable to get data for user", err)
+		return
+	}
+
+	dataServiceContext.RespondWithStatusAndData(http.StatusOK, summary.CalculateReportedStatePeriods(reportedStateData, *filter.StartDate, *filter.EndDate))
+}
+
+// reportedStatePeriodsForUserByID returns the reported state periods overlapping the range of the filter. The states
+// are usually reported from another device than the data segmented, so the device and upload are not filtered on.
+func reportedStatePeriodsForUserByID(dataServiceContext dataService.Context, userID string, filter *dataStoreDEPRECATED.DataFilter) ([]*summary.ReportedStatePeriod, error) {
+	rangeFilter := dataStoreDEPRECATED.NewDataFilter()
+	rangeFilter.StartDate = filter.StartDate
+	rangeFilter.EndDate = filter.EndDate
+
+	reportedStateData, err := iterateReportedStateDataForUserByID(dataServiceContext, userID, rangeFilter)
+	if err != nil {
+		return nil, err
+	}
+	return summary.CalculateReportedStatePeriods(reportedStateData, *filter.StartDate, *filter.EndDate).Periods, nil
+}
+
+// iterateReportedStateDataForUserByID returns the reported state data of the range of the filter, including the
+// states reported before the range and possibly still active at its start
+func iterateReportedStateDataForUserByID(dataServiceContext dataService.Context, userID string, filter *dataStoreDEPRECATED.DataFilter) (data.Data, error) {
+	reportedStateFilter := dataStoreDEPRECATED.NewDataFilter()
+	reportedStateFilter.StartDate = pointer.FromTime(filter.StartDate.Add(-summary.ReportedStateDuration))
+	reportedStateFilter.EndDate = filter.EndDate
+	reportedStateFilter.DeviceID = filter.DeviceID
+	reportedStateFilter.UploadID = filter.UploadID
+	reportedStateFilter.Type = &[]string{dataTypesStateReported.Type}
+	return iterateDataForUserByID(dataServiceContext, userID, reportedStateFilter)
+}
@@ -5,9 +5,28 @@ import "github.com/tidepool-org/platform/data/service"
 func Routes() []service.Route {
 	routes := []service.Route{
 		service.MakeRoute("POST", "/v1/datasets/:dataSetId/data", Authenticate(DataSetsDataCreate)),
+		service.MakeRoute("POST", "/v1/datasets/:dataSetId/data/omh", Authenticate(DataSetsDataOMHCreate)),
 		service.MakeRoute("DELETE", "/v1/datasets/:dataSetId", Authenticate(DataSetsDelete)),
 		service.MakeRoute("PUT", "/v1/datasets/:dataSetId", Authenticate(DataSetsUpdate)),
+		service.MakeRoute("POST", "/v1/datasets/:dataSetId/restore", Authenticate(DataSetsRestore)),
+		service.MakeRoute("POST", "/v1/datasets/:dataSetId/unarchive", Authenticate(DataSetsUnarchive)),
+		service.MakeRoute("GET", "/v1/datasets/:dataSetId/deduplicator", Authenticate(DataSetsDeduplicatorGet)),
 		service.MakeRoute("DELETE", "/v1/users/:userId/data", Authenticate(UsersDataDelete)),
+		service.MakeRoute("GET", "/v1/users/:userId/data", Authenticate(UsersDataGet)),
+		service.MakeRoute("POST", "/v1/users/:userId/data/csv", Authenticate(UsersDataCSVCreate)),
+		service.MakeRoute("GET", "/v1/users/:userId/data/export", Authenticate(UsersDataExport)),
+		service.MakeRoute("GET", "/v1/users/:userId/data/fhir", Authenticate(UsersDataFHIRGet)),
+		service.MakeRoute("GET", "/v1/users/:userId/data/hydration", Authenticate(UsersDataHydrationGet)),
+		service.MakeRoute("GET", "/v1/users/:userId/data/agp", Authenticate(UsersDataAGPGet)),
+		service.MakeRoute("GET", "/v1/users/:userId/data/glucose_stats", Authenticate(UsersDataGlucoseStatsGet)),
+		service.MakeRoute("GET", "/v1/users/:userId/data/glycemic_events", Authenticate(UsersDataGlycemicEventsGet)),
+		service.MakeRoute("GET", "/v1/users/:userId/data/insulin", Authenticate(UsersDataInsulinGet)),
+		service.MakeRoute("GET", "/v1/users/:userId/data/meals", Authenticate(UsersDataMealsGet)),
+		service.MakeRoute("POST", "/v1/users/:userId/data/meals/associations", Authenticate(UsersDataMealsAssociationsCreate)),
+		service.MakeRoute("GET", "/v1/users/:userId/data/pump_settings_history", Authenticate(UsersDataPumpSettingsHistoryGet)),
+		service.MakeRoute("GET", "/v1/users/:userId/data/reported_state_periods", Authenticate(UsersDataReportedStatePeriodsGet)),
+		service.MakeRoute("GET", "/v1/users/:userId/data/daily_summaries", Authenticate(UsersDataDailySummariesGet)),
+		service.MakeRoute("GET", "/v1/users/:userId/data/alarms", Authenticate(UsersDataAlarmsGet)),
 		service.MakeRoute("POST", "/v1/users/:userId/datasets", Authenticate(UsersDataSetsCreate)),
 		service.MakeRoute("GET", "/v1/users/:userId/datasets", Authenticate(UsersDataSetsGet)),
 
@@ -15,8 +34,16 @@ func Routes() []service.Route {
 		service.MakeRoute("DELETE", "/v1/data_sets/:dataSetId/data", Authenticate(DataSetsDataDelete)),
 		service.MakeRoute("DELETE", "/v1/data_sets/:dataSetId", Authenticate(DataSetsDelete)),
 		service.MakeRoute("PUT", "/v1/data_sets/:dataSetId", Authenticate(DataSetsUpdate)),
+		service.MakeRoute("POST", "/v1/data_sets/:dataSetId/restore", Authenticate(DataSetsRestore)),
+		service.MakeRoute("POST", "/v1/data_sets/:dataSetId/unarchive", Authenticate(DataSetsUnarchive)),
+		service.MakeRoute("GET", "/v1/data_sets/:dataSetId/deduplicator", Authenticate(DataSetsDeduplicatorGet)),
+		service.MakeRoute("GET", "/v1/deleted_data_sets", Authenticate(DeletedDataSetsGet)),
+		service.MakeRoute("DELETE", "/v1/deleted_data_sets/:dataSetId", Authenticate(DeletedDataSetsDelete)),
+		service.MakeRoute("DELETE", "/v1/deleted_data", Authenticate(DeletedDataDelete)),
+		service.MakeRoute("POST", "/v1/daily_summaries/rebuild", Authenticate(DailySummariesRebuild)),
 		service.MakeRoute("GET", "/v1/time", TimeGet),
 		service.MakeRoute("POST", "/v1/users/:userId/data_sets", Authenticate(UsersDataSetsCreate)),
 	}
-	return append(routes, DataSetsRoutes()...)
+	routes = append(routes, DataSetsRoutes()...)
+	return append(routes, SourcesRoutes()...)
 }
@@ -9,6 +9,7 @@ import (
 	dataClient "github.com/tidepool-org/platform/data/client"
 	"github.com/tidepool-org/platform/data/deduplicator"
 	dataService "github.com/tidepool-org/platform/data/service"
+	dataSource "github.com/tidepool-org/platform/data/source"
 	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
 	"github.com/tidepool-org/platform/errors"
 	"github.com/tidepool-org/platform/permission"
@@ -23,17 +24,19 @@ type Standard struct {
 	dataDeduplicatorFactory deduplicator.Factory
 	dataStoreDEPRECATED     dataStoreDEPRECATED.Store
 	dataSession             dataStoreDEPRECATED.DataSession
+	dataDryRunSession       dataStoreDEPRECATED.DryRunDataSession
 	syncTaskStore           syncTaskStore.Store
 	syncTasksSession        syncTaskStore.SyncTaskSession
 	dataClient              dataClient.Client
+	dataSourceClient        dataSource.Client
 }
 
 func WithContext(authClient auth.Client, permissionClient permission.Client,
 	dataDeduplicatorFactory deduplicator.Factory,
-	dataStoreDEPRECATED dataStoreDEPRECATED.Store, syncTaskStore syncTaskStore.Store, dataClient dataClient.Client, handler dataService.HandlerFunc) rest.HandlerFunc {
+	dataStoreDEPRECATED dataStoreDEPRECATED.Store, syncTaskStore syncTaskStore.Store, dataClient dataClient.Client, dataSourceClient dataSource.Client, handler dataService.HandlerFunc) rest.HandlerFunc {
 	return func(response rest.ResponseWriter, request *rest.Request) {
 		standard, standardErr := NewStandard(response, request, authClient, permissionClient,
-			dataDeduplicatorFactory, dataStoreDEPRECATED, syncTaskStore, dataClient)
+			dataDeduplicatorFactory, dataStoreDEPRECATED, syncTaskStore, dataClient, dataSourceClient)
 		if standardErr != nil {
 			if responder, responderErr := serviceContext.NewResponder(response, request); responderErr != nil {
 				response.WriteHeader(http.StatusInternalServerError)
@@ -51,7 +54,7 @@ func WithContext(authClient auth.Client, permissionClient permission.Client,
 func NewStandard(response rest.ResponseWriter, request *rest.Request,
 	authClient auth.Client, permissionClient permission.Client,
 	dataDeduplicatorFactory deduplicator.Factory,
-	dataStoreDEPRECATED dataStoreDEPRECATED.Store, syncTaskStore syncTaskStore.Store, dataClient dataClient.Client) (*Standard, error) {
+	dataStoreDEPRECATED dataStoreDEPRECATED.Store, syncTaskStore syncTaskStore.Store, dataClient dataClient.Client, dataSourceClient dataSource.Client) (*Standard, error) {
 	if authClient == nil {
 		return nil, errors.New("auth client is missing")
 	}
@@ -70,6 +73,9 @@ func NewStandard(response rest.ResponseWriter, request *rest.Request,
 	if dataClient == nil {
 		return nil, errors.New("data client is missing")
 	}
+	if dataSourceClient == nil {
+		return nil, errors.New("data source client is missing")
+	}
 
 	responder, err := serviceContext.NewResponder(response, request)
 	if err != nil {
@@ -84,6 +90,7 @@ func NewStandard(response rest.ResponseWriter, request *rest.Request,
 		dataStoreDEPRECATED:     dataStoreDEPRECATED,
 		syncTaskStore:           syncTaskStore,
 		dataClient:              dataClient,
+		dataSourceClient:        dataSourceClient,
 	}, nil
 }
 
@@ -92,6 +99,10 @@ func (s *Standard) Close() {
 		s.syncTasksSession.Close()
 		s.syncTasksSession = nil
 	}
+	if s.dataDryRunSession != nil {
+		s.dataDryRunSession.Close()
+		s.dataDryRunSession = nil
+	}
 	if s.dataSession != nil {
 		s.dataSession.Close()
 		s.dataSession = nil
@@ -117,6 +128,13 @@ func (s *Standard) DataSession() dataStoreDEPRECATED.DataSession {
 	return s.dataSession
 }
 
+func (s *Standard) DataDryRunSession() dataStoreDEPRECATED.DryRunDataSession {
+	if s.dataDryRunSession == nil {
+		s.dataDryRunSession = s.dataStoreDEPRECATED.NewDryRunDataSession()
+	}
+	return s.dataDryRunSession
+}
+
 func (s *Standard) SyncTaskSession() syncTaskStore.SyncTaskSession {
 	if s.syncTasksSession == nil {
 		s.syncTasksSession = s.syncTaskStore.NewSyncTaskSession()
@@ -127,3 +145,7 @@ func (s *Standard) SyncTaskSession() syncTaskStore.SyncTaskSession {
 func (s *Standard) DataClient() dataClient.Client {
 	return s.dataClient
 }
+
+func (s *Standard) DataSourceClient() dataSource.Client {
+	return s.dataSourceClient
+}
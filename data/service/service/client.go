@@ -4,7 +4,9 @@ import (
 	"context"
 
 	"github.com/tidepool-org/platform/data"
+	dataClient "github.com/tidepool-org/platform/data/client"
 	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/data/summary"
 	"github.com/tidepool-org/platform/errors"
 	"github.com/tidepool-org/platform/page"
 )
@@ -56,3 +58,32 @@ func (c *Client) CreateDataSetsData(ctx context.Context, dataSetID string, datum
 func (c *Client) DestroyDataForUserByID(ctx context.Context, userID string) error {
 	panic("Not Implemented!")
 }
+
+func (c *Client) ListDeletedDataSets(ctx context.Context, filter *data.DeletedDataSetFilter, pagination *page.Pagination) (data.DataSets, error) {
+	ssn := c.dataStoreDEPRECATED.NewDataSession()
+	defer ssn.Close()
+
+	return ssn.ListDeletedDataSets(ctx, filter, pagination)
+}
+
+func (c *Client) DestroyDeletedDataSetData(ctx context.Context, dataSetID string) error {
+	panic("Not Implemented!")
+}
+
+func (c *Client) DestroyDeletedData(ctx context.Context, filter *data.DeletedDataFilter, pagination *page.Pagination) (*data.DeletedDataDestroyResult, error) {
+	ssn := c.dataStoreDEPRECATED.NewDataSession()
+	defer ssn.Close()
+
+	return ssn.DestroyDeletedData(ctx, filter, pagination)
+}
+
+func (c *Client) RebuildPendingDailySummaries(ctx context.Context, filter *summary.DailySummaryRebuildFilter, pagination *page.Pagination) (*summary.DailySummaryRebuildResult, error) {
+	ssn := c.dataStoreDEPRECATED.NewDataSession()
+	defer ssn.Close()
+
+	return ssn.RebuildPendingDailySummaries(ctx, filter, pagination)
+}
+
+func (c *Client) ExportUserData(ctx context.Context, userID string, filter *dataStoreDEPRECATED.DataFilter, cursor *dataStoreDEPRECATED.DataCursor) (*dataClient.DataIterator, error) {
+	panic("Not Implemented!")
+}
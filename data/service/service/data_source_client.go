@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+
+	dataSource "github.com/tidepool-org/platform/data/source"
+	dataSourceStoreStructured "github.com/tidepool-org/platform/data/source/store/structured"
+	"github.com/tidepool-org/platform/errors"
+	"github.com/tidepool-org/platform/page"
+	"github.com/tidepool-org/platform/request"
+)
+
+// DataSourceClient is the data source client of the data service. As with the data client, the handlers authorize
+// the requests with the same rules as the other data routes, so the client does not.
+type DataSourceClient struct {
+	dataSourceStructuredStore dataSourceStoreStructured.Store
+}
+
+func NewDataSourceClient(dataSourceStructuredStore dataSourceStoreStructured.Store) (*DataSourceClient, error) {
+	if dataSourceStructuredStore == nil {
+		return nil, errors.New("data source structured store is missing")
+	}
+
+	return &DataSourceClient{
+		dataSourceStructuredStore: dataSourceStructuredStore,
+	}, nil
+}
+
+func (d *DataSourceClient) List(ctx context.Context, userID string, filter *dataSource.Filter, pagination *page.Pagination) (dataSource.SourceArray, error) {
+	session := d.dataSourceStructuredStore.NewSession()
+	defer session.Close()
+
+	return session.List(ctx, userID, filter, pagination)
+}
+
+func (d *DataSourceClient) Create(ctx context.Context, userID string, create *dataSource.Create) (*dataSource.Source, error) {
+	session := d.dataSourceStructuredStore.NewSession()
+	defer session.Close()
+
+	return session.Create(ctx, userID, create)
+}
+
+func (d *DataSourceClient) DeleteAll(ctx context.Context, userID string) error {
+	session := d.dataSourceStructuredStore.NewSession()
+	defer session.Close()
+
+	_, err := session.DestroyAll(ctx, userID)
+	return err
+}
+
+func (d *DataSourceClient) Get(ctx context.Context, id string) (*dataSource.Source, error) {
+	session := d.dataSourceStructuredStore.NewSession()
+	defer session.Close()
+
+	return session.Get(ctx, id)
+}
+
+func (d *DataSourceClient) Update(ctx context.Context, id string, condition *request.Condition, update *dataSource.Update) (*dataSource.Source, error) {
+	session := d.dataSourceStructuredStore.NewSession()
+	defer session.Close()
+
+	return session.Update(ctx, id, condition, update)
+}
+
+func (d *DataSourceClient) Delete(ctx context.Context, id string, condition *request.Condition) (bool, error) {
+	session := d.dataSourceStructuredStore.NewSession()
+	defer session.Close()
+
+	return session.Destroy(ctx, id, condition)
+}
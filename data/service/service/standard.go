@@ -6,6 +6,7 @@ import (
 	dataDeduplicatorFactory "github.com/tidepool-org/platform/data/deduplicator/factory"
 	"github.com/tidepool-org/platform/data/service/api"
 	dataServiceApiV1 "github.com/tidepool-org/platform/data/service/api/v1"
+	dataSourceStoreStructuredMongo "github.com/tidepool-org/platform/data/source/store/structured/mongo"
 	dataStoreDEPRECATEDMongo "github.com/tidepool-org/platform/data/storeDEPRECATED/mongo"
 	"github.com/tidepool-org/platform/errors"
 	"github.com/tidepool-org/platform/permission"
@@ -19,13 +20,15 @@ import (
 
 type Standard struct {
 	*service.DEPRECATEDService
-	permissionClient        *permissionClient.Client
-	dataDeduplicatorFactory *dataDeduplicatorFactory.Factory
-	dataStoreDEPRECATED     *dataStoreDEPRECATEDMongo.Store
-	syncTaskStore           *syncTaskMongo.Store
-	dataClient              *Client
-	api                     *api.Standard
-	server                  *server.Standard
+	permissionClient          *permissionClient.Client
+	dataDeduplicatorFactory   *dataDeduplicatorFactory.Factory
+	dataStoreDEPRECATED       *dataStoreDEPRECATEDMongo.Store
+	syncTaskStore             *syncTaskMongo.Store
+	dataSourceStructuredStore *dataSourceStoreStructuredMongo.Store
+	dataClient                *Client
+	dataSourceClient          *DataSourceClient
+	api                       *api.Standard
+	server                    *server.Standard
 }
 
 func NewStandard() *Standard {
@@ -51,9 +54,15 @@ func (s *Standard) Initialize(provider application.Provider) error {
 	if err := s.initializeSyncTaskStore(); err != nil {
 		return err
 	}
+	if err := s.initializeDataSourceStructuredStore(); err != nil {
+		return err
+	}
 	if err := s.initializeDataClient(); err != nil {
 		return err
 	}
+	if err := s.initializeDataSourceClient(); err != nil {
+		return err
+	}
 	if err := s.initializeAPI(); err != nil {
 		return err
 	}
@@ -63,7 +72,12 @@ func (s *Standard) Initialize(provider application.Provider) error {
 func (s *Standard) Terminate() {
 	s.server = nil
 	s.api = nil
+	s.dataSourceClient = nil
 	s.dataClient = nil
+	if s.dataSourceStructuredStore != nil {
+		s.dataSourceStructuredStore.Close()
+		s.dataSourceStructuredStore = nil
+	}
 	if s.syncTaskStore != nil {
 		s.syncTaskStore.Close()
 		s.syncTaskStore = nil
@@ -111,16 +125,33 @@ func (s *Standard) initializePermissionClient() error {
 }
 
 func (s *Standard) initializeDataDeduplicatorFactory() error {
+	s.Logger().Debug("Loading data deduplicator config")
+
+	cfg := dataDeduplicatorDeduplicator.NewConfig()
+	if err := cfg.Load(s.ConfigReporter().WithScopes("deduplicator")); err != nil {
+		return errors.Wrap(err, "unable to load data deduplicator config")
+	}
+	if err := cfg.Validate(); err != nil {
+		return errors.Wrap(err, "data deduplicator config is invalid")
+	}
+
+	s.Logger().Debug("Creating device near duplicate deduplicator")
+
+	deviceNearDuplicateDeduplicator, err := dataDeduplicatorDeduplicator.NewDeviceNearDuplicateWithDeviceRules(cfg.DeviceNearDuplicateDeviceRules, cfg.DeviceNearDuplicateRule)
+	if err != nil {
+		return errors.Wrap(err, "unable to create device near duplicate deduplicator")
+	}
+
 	s.Logger().Debug("Creating device deactivate hash deduplicator")
 
-	deviceDeactivateHashDeduplicator, err := dataDeduplicatorDeduplicator.NewDeviceDeactivateHash()
+	deviceDeactivateHashDeduplicator, err := dataDeduplicatorDeduplicator.NewDeviceDeactivateHashWithDeviceRules(cfg.DeviceDeactivateHashDeviceRules)
 	if err != nil {
 		return errors.Wrap(err, "unable to create device deactivate hash deduplicator")
 	}
 
 	s.Logger().Debug("Creating device truncate data set deduplicator")
 
-	deviceTruncateDataSetDeduplicator, err := dataDeduplicatorDeduplicator.NewDeviceTruncateDataSet()
+	deviceTruncateDataSetDeduplicator, err := dataDeduplicatorDeduplicator.NewDeviceTruncateDataSetWithDeviceRules(cfg.DeviceTruncateDataSetDeviceRules)
 	if err != nil {
 		return errors.Wrap(err, "unable to create device truncate data set deduplicator")
 	}
@@ -142,6 +173,7 @@ func (s *Standard) initializeDataDeduplicatorFactory() error {
 	s.Logger().Debug("Creating data deduplicator factory")
 
 	deduplicators := []dataDeduplicatorFactory.Deduplicator{
+		deviceNearDuplicateDeduplicator,
 		deviceDeactivateHashDeduplicator,
 		deviceTruncateDataSetDeduplicator,
 		dataSetDeleteOriginDeduplicator,
@@ -195,6 +227,25 @@ func (s *Standard) initializeSyncTaskStore() error {
 	return nil
 }
 
+func (s *Standard) initializeDataSourceStructuredStore() error {
+	s.Logger().Debug("Loading data source structured store config")
+
+	cfg := storeStructuredMongo.NewConfig()
+	if err := cfg.Load(s.ConfigReporter().WithScopes("data_source", "store")); err != nil {
+		return errors.Wrap(err, "unable to load data source structured store config")
+	}
+
+	s.Logger().Debug("Creating data source structured store")
+
+	str, err := dataSourceStoreStructuredMongo.NewStore(cfg, s.Logger())
+	if err != nil {
+		return errors.Wrap(err, "unable to create data source structured store")
+	}
+	s.dataSourceStructuredStore = str
+
+	return nil
+}
+
 func (s *Standard) initializeDataClient() error {
 	s.Logger().Debug("Creating data client")
 
@@ -207,12 +258,24 @@ func (s *Standard) initializeDataClient() error {
 	return nil
 }
 
+func (s *Standard) initializeDataSourceClient() error {
+	s.Logger().Debug("Creating data source client")
+
+	clnt, err := NewDataSourceClient(s.dataSourceStructuredStore)
+	if err != nil {
+		return errors.Wrap(err, "unable to create data source client")
+	}
+	s.dataSourceClient = clnt
+
+	return nil
+}
+
 func (s *Standard) initializeAPI() error {
 	s.Logger().Debug("Creating api")
 
 	newAPI, err := api.NewStandard(s, s.permissionClient,
 		s.dataDeduplicatorFactory,
-		s.dataStoreDEPRECATED, s.syncTaskStore, s.dataClient)
+		s.dataStoreDEPRECATED, s.syncTaskStore, s.dataClient, s.dataSourceClient)
 	if err != nil {
 		return errors.Wrap(err, "unable to create api")
 	}
@@ -0,0 +1,376 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	mgo "github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"github.com/tidepool-org/platform/data"
+	"github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/data/summary"
+	"github.com/tidepool-org/platform/errors"
+	"github.com/tidepool-org/platform/log"
+	"github.com/tidepool-org/platform/page"
+	"github.com/tidepool-org/platform/pointer"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
+)
+
+const (
+	dailySummariesCollection       = "deviceDataDailySummaries"
+	dailySummaryRebuildsCollection = "deviceDataDailySummaryRebuilds"
+)
+
+func (d *DataSession) Close() error {
+	if d.timeProcessingBatchesSession != nil {
+		d.timeProcessingBatchesSession.Close()
+	}
+	if d.dailySummaryRebuildsSession != nil {
+		d.dailySummaryRebuildsSession.Close()
+	}
+	if d.dailySummariesSession != nil {
+		d.dailySummariesSession.Close()
+	}
+	return d.Session.Close()
+}
+
+func (d *DataSession) GetDailySummaries(ctx context.Context, userID string, filter *summary.DailySummaryFilter) ([]*summary.DailySummary, error) {
+	if ctx == nil {
+		return nil, errors.New("context is missing")
+	}
+	if userID == "" {
+		return nil, errors.New("user id is missing")
+	}
+	if filter == nil {
+		filter = summary.NewDailySummaryFilter()
+	} else if err := structureValidator.New().Validate(filter); err != nil {
+		return nil, errors.Wrap(err, "filter is invalid")
+	}
+
+	if d.IsClosed() {
+		return nil, errors.New("session closed")
+	}
+
+	now := time.Now()
+
+	selector := bson.M{
+		"userId": userID,
+	}
+	if filter.StartDate != nil || filter.EndDate != nil {
+		dateSelector := bson.M{}
+		if filter.StartDate != nil {
+			dateSelector["$gte"] = *filter.StartDate
+		}
+		if filter.EndDate != nil {
+			dateSelector["$lte"] = *filter.EndDate
+		}
+		selector["date"] = dateSelector
+	}
+
+	dailySummaries := []*summary.DailySummary{}
+	err := d.dailySummariesSession.C().Find(selector).Sort("date").All(&dailySummaries)
+
+	loggerFields := log.Fields{"userId": userID, "filter": filter, "count": len(dailySummaries), "duration": time.Since(now) / time.Microsecond}
+	log.LoggerFromContext(ctx).WithFields(loggerFields).WithError(err).Debug("GetDailySummaries")
+
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get daily summaries")
+	}
+	return dailySummaries, nil
+}
+
+// CalculateDailySummaries calculates the daily summaries of the user for the local dates between the start and end
+// dates, inclusive, from the active data, without storing them
+func (d *DataSession) CalculateDailySummaries(ctx context.Context, userID string, startDate string, endDate string) ([]*summary.DailySummary, error) {
+	if ctx == nil {
+		return nil, errors.New("context is missing")
+	}
+	if userID == "" {
+		return nil, errors.New("user id is missing")
+	}
+	startTime, endTime, err := summary.DailySummaryTimeRange(startDate, endDate)
+	if err != nil {
+		return nil, errors.Wrap(err, "dates are invalid")
+	} else if endDate < startDate {
+		return nil, errors.New("end date is before start date")
+	}
+
+	if d.IsClosed() {
+		return nil, errors.New("session closed")
+	}
+
+	filter := storeDEPRECATED.NewDataFilter()
+	filter.StartDate = &startTime
+	filter.EndDate = &endTime
+
+	iterator, err := d.IterateDataForUserByID(ctx, userID, filter, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to calculate daily summaries")
+	}
+	defer iterator.Close()
+
+	datums := data.Data{}
+	for iterator.Next(ctx) {
+		datums = append(datums, iterator.Datum())
+	}
+	if err = iterator.Error(); err != nil {
+		return nil, errors.Wrap(err, "unable to calculate daily summaries")
+	}
+
+	return summary.CalculateDailySummaries(userID, datums, startDate, endDate), nil
+}
+
+// RebuildDailySummaries calculates and stores the daily summaries of the user for the local dates between the start
+// and end dates, inclusive, removing the stored daily summaries of the dates without data. The dates are rebuilt by
+// windows of at most summary.DailySummaryWindowDays dates, so that the data of a long range is not read at once.
+func (d *DataSession) RebuildDailySummaries(ctx context.Context, userID string, startDate string, endDate string) error {
+	windows, err := summary.DailySummaryWindows(startDate, endDate)
+	if err != nil {
+		return errors.Wrap(err, "dates are invalid")
+	} else if endDate < startDate {
+		return errors.New("end date is before start date")
+	}
+
+	for _, window := range windows {
+		if err = d.rebuildDailySummaries(ctx, userID, window.StartDate, window.EndDate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebuildDailySummaries calculates and stores the daily summaries of the user for the local dates of a window
+func (d *DataSession) rebuildDailySummaries(ctx context.Context, userID string, startDate string, endDate string) error {
+	dailySummaries, err := d.CalculateDailySummaries(ctx, userID, startDate, endDate)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	timestamp := now.Truncate(time.Millisecond).Format(time.RFC3339Nano)
+
+	dates := make([]string, 0, len(dailySummaries))
+	for _, dailySummary := range dailySummaries {
+		dailySummary.UpdatedTime = timestamp
+		if _, err = d.dailySummariesSession.C().Upsert(bson.M{"userId": userID, "date": dailySummary.Date}, dailySummary); err != nil {
+			return errors.Wrap(err, "unable to store daily summary")
+		}
+		dates = append(dates, dailySummary.Date)
+	}
+
+	selector := bson.M{
+		"userId": userID,
+		"date":   bson.M{"$gte": startDate, "$lte": endDate, "$nin": dates},
+	}
+	removeInfo, err := d.dailySummariesSession.C().RemoveAll(selector)
+
+	loggerFields := log.Fields{"userId": userID, "startDate": startDate, "endDate": endDate, "count": len(dailySummaries), "removeInfo": removeInfo, "duration": time.Since(now) / time.Microsecond}
+	log.LoggerFromContext(ctx).WithFields(loggerFields).WithError(err).Debug("rebuildDailySummaries")
+
+	if err != nil {
+		return errors.Wrap(err, "unable to remove daily summaries")
+	}
+	return nil
+}
+
+// dataTimeRange returns the earliest and latest time of the data matching the selector, or nil if there is no data
+func (d *DataSession) dataTimeRange(selector bson.M) (*dataTimeRange, error) {
+	pipeline := []bson.M{
+		{"$match": selector},
+		{"$group": bson.M{"_id": nil, "startTime": bson.M{"$min": "$time"}, "endTime": bson.M{"$max": "$time"}}},
+	}
+	var results []struct {
+		StartTime string `bson:"startTime"`
+		EndTime   string `bson:"endTime"`
+	}
+	if err := d.C().Pipe(pipeline).All(&results); err != nil {
+		return nil, err
+	} else if len(results) == 0 {
+		return nil, nil
+	}
+
+	startTime, err := time.Parse(data.TimeFormat, results[0].StartTime)
+	if err != nil {
+		return nil, nil
+	}
+	endTime, err := time.Parse(data.TimeFormat, results[0].EndTime)
+	if err != nil {
+		return nil, nil
+	}
+	return &dataTimeRange{startTime: startTime, endTime: endTime}, nil
+}
+
+type dataTimeRange struct {
+	startTime time.Time
+	endTime   time.Time
+}
+
+// updateDailySummaries requests the rebuild of the daily summaries of the local dates of the data matching the
+// selector, which is evaluated after the data is modified
+func (d *DataSession) updateDailySummaries(ctx context.Context, userID *string, selector bson.M) {
+	timeRange, err := d.dataTimeRange(selector)
+	if err != nil {
+		log.LoggerFromContext(ctx).WithError(err).Error("Unable to get time range of data to update daily summaries")
+		return
+	}
+	d.updateDailySummariesForTimeRange(ctx, userID, timeRange)
+}
+
+// updateDailySummariesForTimeRange requests the rebuild of the daily summaries of the local dates of data within the
+// time range, merging it with any pending rebuild of the user, so that the data is not read again in the request. The
+// pending rebuilds are run by RebuildPendingDailySummaries. The data was already modified, so the failure to request
+// the rebuild is only logged.
+func (d *DataSession) updateDailySummariesForTimeRange(ctx context.Context, userID *string, timeRange *dataTimeRange) {
+	if userID == nil || timeRange == nil {
+		return
+	}
+	startDate, endDate := summary.DailySummaryDates(timeRange.startTime, timeRange.endTime)
+	update := bson.M{
+		"$min": bson.M{"startDate": startDate},
+		"$max": bson.M{"endDate": endDate},
+		"$set": bson.M{"requestedTime": time.Now().Truncate(time.Millisecond)},
+	}
+	if _, err := d.dailySummaryRebuildsSession.C().Upsert(bson.M{"userId": *userID}, update); err != nil {
+		loggerFields := log.Fields{"userId": *userID, "startDate": startDate, "endDate": endDate}
+		log.LoggerFromContext(ctx).WithFields(loggerFields).WithError(err).Error("Unable to request rebuild of daily summaries")
+	}
+}
+
+// dailySummaryRebuild is the pending rebuild of the daily summaries of a user for the local dates between the start and
+// end dates, inclusive
+type dailySummaryRebuild struct {
+	ID            bson.ObjectId `bson:"_id"`
+	UserID        string        `bson:"userId"`
+	StartDate     string        `bson:"startDate"`
+	EndDate       string        `bson:"endDate"`
+	RequestedTime time.Time     `bson:"requestedTime"`
+	FailedTime    *time.Time    `bson:"failedTime,omitempty"`
+}
+
+// RebuildPendingDailySummaries runs a page of the pending rebuilds of daily summaries requested before the filter time,
+// oldest requested first, each also recording the associations of the meals that may change with the rebuilt data. A
+// rebuild is removed once run, unless requested again meanwhile. A rebuild that fails is kept to be run again, but is
+// not selected again before the filter time, so that it is counted only once by a caller that runs the pending rebuilds
+// page by page until none remain.
+func (d *DataSession) RebuildPendingDailySummaries(ctx context.Context, filter *summary.DailySummaryRebuildFilter, pagination *page.Pagination) (*summary.DailySummaryRebuildResult, error) {
+	if ctx == nil {
+		return nil, errors.New("context is missing")
+	}
+	if filter == nil {
+		filter = summary.NewDailySummaryRebuildFilter()
+	} else if err := structureValidator.New().Validate(filter); err != nil {
+		return nil, errors.Wrap(err, "filter is invalid")
+	}
+	if pagination == nil {
+		pagination = page.NewPagination()
+	} else if err := structureValidator.New().Validate(pagination); err != nil {
+		return nil, errors.Wrap(err, "pagination is invalid")
+	}
+
+	if d.IsClosed() {
+		return nil, errors.New("session closed")
+	}
+
+	now := time.Now()
+	logger := log.LoggerFromContext(ctx)
+
+	requestedBefore := now
+	if filter.RequestedBefore != nil {
+		requestedBefore = *filter.RequestedBefore
+	}
+	selector := bson.M{
+		"requestedTime": bson.M{"$lt": requestedBefore},
+		"failedTime":    bson.M{"$not": bson.M{"$gte": requestedBefore}},
+	}
+	rebuilds := []*dailySummaryRebuild{}
+	if err := d.dailySummaryRebuildsSession.C().Find(selector).Sort("requestedTime").Skip(pagination.Page * pagination.Size).Limit(pagination.Size).All(&rebuilds); err != nil {
+		return nil, errors.Wrap(err, "unable to get pending daily summary rebuilds")
+	}
+
+	result := &summary.DailySummaryRebuildResult{}
+	for _, rebuild := range rebuilds {
+		rebuildSelector := bson.M{"_id": rebuild.ID, "requestedTime": rebuild.RequestedTime}
+		if err := d.rebuildPendingDailySummaries(ctx, rebuild); err != nil {
+			loggerFields := log.Fields{"userId": rebuild.UserID, "startDate": rebuild.StartDate, "endDate": rebuild.EndDate}
+			logger.WithFields(loggerFields).WithError(err).Warn("Unable to rebuild pending daily summaries")
+			if err = d.dailySummaryRebuildsSession.C().Update(rebuildSelector, bson.M{"$set": bson.M{"failedTime": time.Now().Truncate(time.Millisecond)}}); err != nil && err != mgo.ErrNotFound {
+				return nil, errors.Wrap(err, "unable to update pending daily summary rebuild")
+			}
+			result.Failed++
+		} else {
+			if err = d.dailySummaryRebuildsSession.C().Remove(rebuildSelector); err != nil && err != mgo.ErrNotFound {
+				return nil, errors.Wrap(err, "unable to remove pending daily summary rebuild")
+			}
+			result.Rebuilt++
+		}
+	}
+
+	loggerFields := log.Fields{"filter": filter, "rebuilt": result.Rebuilt, "failed": result.Failed, "duration": time.Since(now) / time.Microsecond}
+	logger.WithFields(loggerFields).Debug("RebuildPendingDailySummaries")
+
+	return result, nil
+}
+
+// rebuildPendingDailySummaries runs the pending rebuild of the daily summaries and records the associations of the meals
+// whose correlations may change with the data of the rebuild dates, as the data is modified, window by window so that
+// the data of a long range is not read at once
+func (d *DataSession) rebuildPendingDailySummaries(ctx context.Context, rebuild *dailySummaryRebuild) error {
+	windows, err := summary.DailySummaryWindows(rebuild.StartDate, rebuild.EndDate)
+	if err != nil {
+		return errors.Wrap(err, "dates are invalid")
+	}
+
+	for _, window := range windows {
+		if err = d.RebuildDailySummaries(ctx, rebuild.UserID, window.StartDate, window.EndDate); err != nil {
+			return err
+		}
+
+		var startTime, endTime time.Time
+		if startTime, endTime, err = summary.DailySummaryTimeRange(window.StartDate, window.EndDate); err != nil {
+			return errors.Wrap(err, "dates are invalid")
+		}
+		mealsStartTime, mealsEndTime := summary.MealsTimeRangeForData(startTime, endTime)
+		if err = d.updateMealAssociations(ctx, rebuild.UserID, mealsStartTime, mealsEndTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateMealAssociations correlates the meals of the user between the start and end times and records their datum
+// associations on the meal datums
+func (d *DataSession) updateMealAssociations(ctx context.Context, userID string, startTime time.Time, endTime time.Time) error {
+	filter := storeDEPRECATED.NewDataFilter()
+	dataStartTime, dataEndTime := summary.MealsDataTimeRange(startTime, endTime)
+	filter.StartDate = &dataStartTime
+	filter.EndDate = &dataEndTime
+	filter.Type = pointer.FromStringArray(summary.MealsDataTypes())
+
+	iterator, err := d.IterateDataForUserByID(ctx, userID, filter, nil)
+	if err != nil {
+		return errors.Wrap(err, "unable to update meal associations")
+	}
+	defer iterator.Close()
+
+	datums := data.Data{}
+	for iterator.Next(ctx) {
+		datums = append(datums, iterator.Datum())
+	}
+	if err = iterator.Error(); err != nil {
+		return errors.Wrap(err, "unable to update meal associations")
+	}
+
+	report := summary.CalculateMeals(datums, startTime, endTime)
+	return d.UpdateDataAssociations(ctx, userID, summary.MealDataAssociations(datums, report))
+}
+
+// timeRangeBeforeUpdate returns the time range of the data matching the selector, before the data is modified, only
+// logging any failure
+func (d *DataSession) timeRangeBeforeUpdate(ctx context.Context, selector bson.M) *dataTimeRange {
+	timeRange, err := d.dataTimeRange(selector)
+	if err != nil {
+		log.LoggerFromContext(ctx).WithError(err).Error("Unable to get time range of data to update daily summaries")
+	}
+	return timeRange
+}
@@ -0,0 +1,460 @@
+package mongo
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+
+	"github.com/tidepool-org/platform/association"
+	"github.com/tidepool-org/platform/data"
+	"github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/data/summary"
+	"github.com/tidepool-org/platform/data/types/upload"
+	"github.com/tidepool-org/platform/errors"
+	"github.com/tidepool-org/platform/log"
+	"github.com/tidepool-org/platform/page"
+)
+
+func (s *Store) NewDryRunDataSession() storeDEPRECATED.DryRunDataSession {
+	return &DryRunDataSession{
+		DataSession: &DataSession{
+			Session:                      s.Store.NewSession("deviceData"),
+			dailySummariesSession:        s.Store.NewSession(dailySummariesCollection),
+			dailySummaryRebuildsSession:  s.Store.NewSession(dailySummaryRebuildsCollection),
+			timeProcessingBatchesSession: s.Store.NewSession(timeProcessingBatchesCollection),
+		},
+		report:  storeDEPRECATED.NewDryRunReport(),
+		hashes:  map[string][]string{},
+		samples: map[string][]*storeDEPRECATED.DataSample{},
+	}
+}
+
+// DryRunDataSession reads through to the underlying data session, but replaces each write with a count, by datum
+// type, of the existing data the write would have modified
+type DryRunDataSession struct {
+	*DataSession
+	report  storeDEPRECATED.DryRunReport
+	hashes  map[string][]string
+	samples map[string][]*storeDEPRECATED.DataSample
+}
+
+func (d *DryRunDataSession) CreateDataSet(ctx context.Context, dataSet *upload.Upload) error {
+	return errors.New("create data set is not supported by dry run")
+}
+
+func (d *DryRunDataSession) UpdateDataSet(ctx context.Context, id string, update *data.DataSetUpdate) (*upload.Upload, error) {
+	if ctx == nil {
+		return nil, errors.New("context is missing")
+	}
+	if id == "" {
+		return nil, errors.New("id is missing")
+	}
+	if update == nil {
+		return nil, errors.New("update is missing")
+	}
+
+	return d.DataSession.GetDataSetByID(ctx, id)
+}
+
+func (d *DryRunDataSession) DeleteDataSet(ctx context.Context, dataSet *upload.Upload, doPurge bool) error {
+	return errors.New("delete data set is not supported by dry run")
+}
+
+func (d *DryRunDataSession) RestoreDataSet(ctx context.Context, dataSet *upload.Upload) error {
+	return errors.New("restore data set is not supported by dry run")
+}
+
+func (d *DryRunDataSession) CreateDataSetData(ctx context.Context, dataSet *upload.Upload, dataSetData []data.Datum) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if err := validateDataSet(dataSet); err != nil {
+		return err
+	}
+	if dataSetData == nil {
+		return errors.New("data set data is missing")
+	}
+
+	if d.IsClosed() {
+		return errors.New("session closed")
+	}
+
+	// Remember the hashes and samples of the data that would have been created, so that later operations on the data set consider them
+	for _, datum := range dataSetData {
+		if deduplicator := datum.DeduplicatorDescriptor(); deduplicator != nil && deduplicator.Hash != nil {
+			d.hashes[*dataSet.UploadID] = append(d.hashes[*dataSet.UploadID], *deduplicator.Hash)
+		}
+		sample, err := newDataSample(datum)
+		if err != nil {
+			return err
+		}
+		sample.UploadID = *dataSet.UploadID
+		d.samples[*dataSet.UploadID] = append(d.samples[*dataSet.UploadID], sample)
+	}
+
+	return nil
+}
+
+func (d *DryRunDataSession) ActivateDataSetData(ctx context.Context, dataSet *upload.Upload, selectors *data.Selectors) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if err := validateDataSet(dataSet); err != nil {
+		return err
+	}
+	if _, err := validateAndTranslateSelectors(selectors); err != nil {
+		return err
+	}
+
+	if d.IsClosed() {
+		return errors.New("session closed")
+	}
+
+	return nil
+}
+
+func (d *DryRunDataSession) ArchiveDataSetData(ctx context.Context, dataSet *upload.Upload, selectors *data.Selectors) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if err := validateDataSet(dataSet); err != nil {
+		return err
+	}
+	selector, err := validateAndTranslateSelectors(selectors)
+	if err != nil {
+		return err
+	}
+
+	if d.IsClosed() {
+		return errors.New("session closed")
+	}
+
+	selector["_userId"] = dataSet.UserID
+	selector["uploadId"] = dataSet.UploadID
+	selector["type"] = bson.M{"$ne": "upload"}
+	selector["_active"] = true
+	selector["deletedTime"] = bson.M{"$exists": false}
+	return d.tally(ctx, "ArchiveDataSetData", selector, func(counts *storeDEPRECATED.DryRunCounts, count int) {
+		counts.Archived += count
+	})
+}
+
+func (d *DryRunDataSession) DeleteDataSetData(ctx context.Context, dataSet *upload.Upload, selectors *data.Selectors) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if err := validateDataSet(dataSet); err != nil {
+		return err
+	}
+	selector, err := validateAndTranslateSelectors(selectors)
+	if err != nil {
+		return err
+	}
+
+	if d.IsClosed() {
+		return errors.New("session closed")
+	}
+
+	selector["_userId"] = dataSet.UserID
+	selector["uploadId"] = dataSet.UploadID
+	selector["type"] = bson.M{"$ne": "upload"}
+	selector["deletedTime"] = bson.M{"$exists": false}
+	return d.tally(ctx, "DeleteDataSetData", selector, func(counts *storeDEPRECATED.DryRunCounts, count int) {
+		counts.Deleted += count
+	})
+}
+
+// DestroyDeletedDataSetData only destroys data already deleted, which a dry run never deletes, so there is nothing to count
+func (d *DryRunDataSession) DestroyDeletedDataSetData(ctx context.Context, dataSet *upload.Upload, selectors *data.Selectors) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if err := validateDataSet(dataSet); err != nil {
+		return err
+	}
+	if _, err := validateAndTranslateSelectors(selectors); err != nil {
+		return err
+	}
+
+	if d.IsClosed() {
+		return errors.New("session closed")
+	}
+
+	return nil
+}
+
+func (d *DryRunDataSession) DestroyDataSetData(ctx context.Context, dataSet *upload.Upload, selectors *data.Selectors) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if err := validateDataSet(dataSet); err != nil {
+		return err
+	}
+	selector, err := validateAndTranslateSelectors(selectors)
+	if err != nil {
+		return err
+	}
+
+	if d.IsClosed() {
+		return errors.New("session closed")
+	}
+
+	selector["_userId"] = dataSet.UserID
+	selector["uploadId"] = dataSet.UploadID
+	selector["type"] = bson.M{"$ne": "upload"}
+	selector["deletedTime"] = bson.M{"$exists": false}
+	return d.tally(ctx, "DestroyDataSetData", selector, func(counts *storeDEPRECATED.DryRunCounts, count int) {
+		counts.Deleted += count
+	})
+}
+
+func (d *DryRunDataSession) ArchiveDeviceDataUsingHashesFromDataSet(ctx context.Context, dataSet *upload.Upload) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if err := validateDataSet(dataSet); err != nil {
+		return err
+	}
+	if dataSet.DeviceID == nil || *dataSet.DeviceID == "" {
+		return errors.New("data set device id is missing")
+	}
+
+	if d.IsClosed() {
+		return errors.New("session closed")
+	}
+
+	var hashes []string
+	selector := bson.M{
+		"_userId":  dataSet.UserID,
+		"uploadId": dataSet.UploadID,
+		"type":     bson.M{"$ne": "upload"},
+	}
+	if err := d.C().Find(selector).Distinct("_deduplicator.hash", &hashes); err != nil {
+		return errors.Wrap(err, "unable to archive device data using hashes from data set")
+	}
+	hashes = append(hashes, d.hashes[*dataSet.UploadID]...)
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	selector = bson.M{
+		"_userId":            dataSet.UserID,
+		"deviceId":           *dataSet.DeviceID,
+		"type":               bson.M{"$ne": "upload"},
+		"_active":            true,
+		"_deduplicator.hash": bson.M{"$in": hashes},
+	}
+	return d.tally(ctx, "ArchiveDeviceDataUsingHashesFromDataSet", selector, func(counts *storeDEPRECATED.DryRunCounts, count int) {
+		counts.Deactivated += count
+	})
+}
+
+func (d *DryRunDataSession) UnarchiveDeviceDataUsingHashesFromDataSet(ctx context.Context, dataSet *upload.Upload) error {
+	return errors.New("unarchive device data using hashes from data set is not supported by dry run")
+}
+
+func (d *DryRunDataSession) ArchiveDataSetDataUsingHashesFromDevice(ctx context.Context, dataSet *upload.Upload) error {
+	return errors.New("archive data set data using hashes from device is not supported by dry run")
+}
+
+func (d *DryRunDataSession) DeleteOtherDataSetData(ctx context.Context, dataSet *upload.Upload) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if err := validateDataSet(dataSet); err != nil {
+		return err
+	}
+	if dataSet.DeviceID == nil || *dataSet.DeviceID == "" {
+		return errors.New("data set device id is missing")
+	}
+
+	if d.IsClosed() {
+		return errors.New("session closed")
+	}
+
+	selector := bson.M{
+		"_userId":     dataSet.UserID,
+		"deviceId":    *dataSet.DeviceID,
+		"uploadId":    bson.M{"$ne": dataSet.UploadID},
+		"type":        bson.M{"$ne": "upload"},
+		"deletedTime": bson.M{"$exists": false},
+	}
+	return d.tally(ctx, "DeleteOtherDataSetData", selector, func(counts *storeDEPRECATED.DryRunCounts, count int) {
+		counts.Deleted += count
+	})
+}
+
+func (d *DryRunDataSession) GetDataSetDataSamples(ctx context.Context, dataSet *upload.Upload) ([]*storeDEPRECATED.DataSample, error) {
+	samples, err := d.DataSession.GetDataSetDataSamples(ctx, dataSet)
+	if err != nil {
+		return nil, err
+	}
+
+	samples = append(samples, d.samples[*dataSet.UploadID]...)
+	sort.SliceStable(samples, func(i int, j int) bool { return samples[i].Time < samples[j].Time })
+	return samples, nil
+}
+
+func (d *DryRunDataSession) ArchiveDeviceDataByIDs(ctx context.Context, dataSet *upload.Upload, ids []string) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if err := validateDataSet(dataSet); err != nil {
+		return err
+	}
+	if dataSet.DeviceID == nil || *dataSet.DeviceID == "" {
+		return errors.New("data set device id is missing")
+	}
+
+	if d.IsClosed() {
+		return errors.New("session closed")
+	}
+
+	// Data that would have been created is not stored, so only tally the existing data
+	if len(ids) == 0 {
+		return nil
+	}
+
+	selector := bson.M{
+		"_userId":  dataSet.UserID,
+		"deviceId": *dataSet.DeviceID,
+		"type":     bson.M{"$ne": "upload"},
+		"_active":  true,
+		"id":       bson.M{"$in": ids},
+	}
+	return d.tally(ctx, "ArchiveDeviceDataByIDs", selector, func(counts *storeDEPRECATED.DryRunCounts, count int) {
+		counts.Archived += count
+	})
+}
+
+func (d *DryRunDataSession) UnarchiveDeviceDataArchivedByDataSet(ctx context.Context, dataSet *upload.Upload) error {
+	return errors.New("unarchive device data archived by data set is not supported by dry run")
+}
+
+func (d *DryRunDataSession) DestroyDataForUserByID(ctx context.Context, userID string) error {
+	return errors.New("destroy data for user by id is not supported by dry run")
+}
+
+func (d *DryRunDataSession) RebuildDailySummaries(ctx context.Context, userID string, startDate string, endDate string) error {
+	return errors.New("rebuild daily summaries is not supported by dry run")
+}
+
+func (d *DryRunDataSession) CreateTimeProcessingBatch(ctx context.Context, dataSet *upload.Upload, rawData []interface{}) error {
+	return errors.New("create time processing batch is not supported by dry run")
+}
+
+func (d *DryRunDataSession) SetTimeProcessingBatchProcessed(ctx context.Context, id string) error {
+	return errors.New("set time processing batch processed is not supported by dry run")
+}
+
+func (d *DryRunDataSession) DestroyTimeProcessingBatches(ctx context.Context, dataSet *upload.Upload) error {
+	return errors.New("destroy time processing batches is not supported by dry run")
+}
+
+func (d *DryRunDataSession) UpdateDataAssociations(ctx context.Context, userID string, associations map[string]*association.AssociationArray) error {
+	return errors.New("update data associations is not supported by dry run")
+}
+
+func (d *DryRunDataSession) DestroyDeletedData(ctx context.Context, filter *data.DeletedDataFilter, pagination *page.Pagination) (*data.DeletedDataDestroyResult, error) {
+	return nil, errors.New("destroy deleted data is not supported by dry run")
+}
+
+func (d *DryRunDataSession) RebuildPendingDailySummaries(ctx context.Context, filter *summary.DailySummaryRebuildFilter, pagination *page.Pagination) (*summary.DailySummaryRebuildResult, error) {
+	return nil, errors.New("rebuild pending daily summaries is not supported by dry run")
+}
+
+// DryRunReport returns the counts tallied so far, along with the count of existing data left unchanged, where the
+// existing data is that of the data set device or, without a device, that of the data set itself
+func (d *DryRunDataSession) DryRunReport(ctx context.Context, dataSet *upload.Upload) (storeDEPRECATED.DryRunReport, error) {
+	if ctx == nil {
+		return nil, errors.New("context is missing")
+	}
+	if err := validateDataSet(dataSet); err != nil {
+		return nil, err
+	}
+
+	if d.IsClosed() {
+		return nil, errors.New("session closed")
+	}
+
+	selector := bson.M{
+		"_userId":     dataSet.UserID,
+		"type":        bson.M{"$ne": "upload"},
+		"deletedTime": bson.M{"$exists": false},
+	}
+	if dataSet.DeviceID != nil && *dataSet.DeviceID != "" {
+		selector["deviceId"] = *dataSet.DeviceID
+	} else {
+		selector["uploadId"] = dataSet.UploadID
+	}
+	totals, err := d.countByType(selector)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to count existing data")
+	}
+
+	report := storeDEPRECATED.NewDryRunReport()
+	for typ, counts := range d.report {
+		*report.Counts(typ) = *counts
+	}
+	for typ, total := range totals {
+		counts := report.Counts(typ)
+		if unchanged := total - counts.Modified(); unchanged > 0 {
+			counts.Unchanged = unchanged
+		}
+	}
+	return report, nil
+}
+
+func (d *DryRunDataSession) tally(ctx context.Context, operation string, selector bson.M, add func(counts *storeDEPRECATED.DryRunCounts, count int)) error {
+	now := time.Now()
+
+	countsByType, err := d.countByType(selector)
+
+	loggerFields := log.Fields{"selector": selector, "countsByType": countsByType, "duration": time.Since(now) / time.Microsecond}
+	log.LoggerFromContext(ctx).WithFields(loggerFields).WithError(err).Debugf("%s (dry run)", operation)
+
+	if err != nil {
+		return errors.Wrapf(err, "unable to count data for %s", operation)
+	}
+
+	for typ, count := range countsByType {
+		add(d.report.Counts(typ), count)
+	}
+	return nil
+}
+
+func newDataSample(datum data.Datum) (*storeDEPRECATED.DataSample, error) {
+	bites, err := bson.Marshal(datum)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to marshal datum")
+	}
+
+	sample := &storeDEPRECATED.DataSample{}
+	if err = bson.Unmarshal(bites, sample); err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal datum as sample")
+	}
+	return sample, nil
+}
+
+func (d *DryRunDataSession) countByType(selector bson.M) (map[string]int, error) {
+	pipeline := []bson.M{
+		{"$match": selector},
+		{"$group": bson.M{"_id": "$type", "count": bson.M{"$sum": 1}}},
+	}
+
+	var results []struct {
+		Type  string `bson:"_id"`
+		Count int    `bson:"count"`
+	}
+	if err := d.C().Pipe(pipeline).All(&results); err != nil {
+		return nil, err
+	}
+
+	countsByType := map[string]int{}
+	for _, result := range results {
+		countsByType[result.Type] = result.Count
+	}
+	return countsByType, nil
+}
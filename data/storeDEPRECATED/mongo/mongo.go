@@ -7,14 +7,18 @@ import (
 	mgo "github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 
+	"github.com/tidepool-org/platform/association"
 	"github.com/tidepool-org/platform/data"
 	"github.com/tidepool-org/platform/data/storeDEPRECATED"
+	dataTypesFactory "github.com/tidepool-org/platform/data/types/factory"
+	dataTypesSchema "github.com/tidepool-org/platform/data/types/schema"
 	"github.com/tidepool-org/platform/data/types/upload"
 	"github.com/tidepool-org/platform/errors"
 	"github.com/tidepool-org/platform/log"
 	"github.com/tidepool-org/platform/page"
 	"github.com/tidepool-org/platform/pointer"
 	storeStructuredMongo "github.com/tidepool-org/platform/store/structured/mongo"
+	structureParser "github.com/tidepool-org/platform/structure/parser"
 	structureValidator "github.com/tidepool-org/platform/structure/validator"
 )
 
@@ -26,6 +30,20 @@ var (
 			{Key: []string{"type", "uploadId"}, Background: true, Name: "typeUploadId"},
 			{Key: []string{"uploadId", "type", "-deletedTime", "_active"}, Background: true, Name: "UploadId"},
 			{Key: []string{"uploadId"}, Background: true, Unique: true, PartialFilter: bson.M{"type": "upload"}, Name: "UniqueUploadId"},
+			{Key: []string{"deletedTime"}, Background: true, PartialFilter: bson.M{"type": "upload", "deletedTime": bson.M{"$exists": true}}, Name: "DeletedUpload"},
+			{Key: []string{"deletedTime", "type"}, Background: true, PartialFilter: bson.M{"deletedTime": bson.M{"$exists": true}}, Name: "Deleted"},
+		},
+		dailySummariesCollection: {
+			{Key: []string{"userId", "date"}, Background: true, Unique: true, Name: "UniqueUserIdDate"},
+		},
+		dailySummaryRebuildsCollection: {
+			{Key: []string{"userId"}, Background: true, Unique: true, Name: "UniqueUserId"},
+			{Key: []string{"requestedTime"}, Background: true, Name: "RequestedTime"},
+		},
+		timeProcessingBatchesCollection: {
+			{Key: []string{"id"}, Background: true, Unique: true, Name: "UniqueId"},
+			{Key: []string{"uploadId", "createdTime"}, Background: true, Name: "UploadIdCreatedTime"},
+			{Key: []string{"userId"}, Background: true, Name: "UserId"},
 		},
 	}
 )
@@ -50,12 +68,20 @@ type Store struct {
 
 func (s *Store) NewDataSession() storeDEPRECATED.DataSession {
 	return &DataSession{
-		Session: s.Store.NewSession("deviceData"),
+		Session:                      s.Store.NewSession("deviceData"),
+		dailySummariesSession:        s.Store.NewSession(dailySummariesCollection),
+		dailySummaryRebuildsSession:  s.Store.NewSession(dailySummaryRebuildsCollection),
+		timeProcessingBatchesSession: s.Store.NewSession(timeProcessingBatchesCollection),
 	}
 }
 
+// DataSession requests the rebuild of the daily summaries of the local dates of the data modified by each write, so
+// that they reflect the active data once the pending rebuilds are run
 type DataSession struct {
 	*storeStructuredMongo.Session
+	dailySummariesSession        *storeStructuredMongo.Session
+	dailySummaryRebuildsSession  *storeStructuredMongo.Session
+	timeProcessingBatchesSession *storeStructuredMongo.Session
 }
 
 func (d *DataSession) GetDataSetsForUserByID(ctx context.Context, userID string, filter *storeDEPRECATED.Filter, pagination *page.Pagination) ([]*upload.Upload, error) {
@@ -274,34 +300,56 @@ func (d *DataSession) DeleteDataSet(ctx context.Context, dataSet *upload.Upload,
 	var selector bson.M
 	var removeInfo *mgo.ChangeInfo
 	var updateInfo *mgo.ChangeInfo
+	var timeRange *dataTimeRange
 
 	if doPurge {
+		timeRange = d.timeRangeBeforeUpdate(ctx, bson.M{"_userId": dataSet.UserID, "uploadId": dataSet.UploadID, "type": bson.M{"$ne": "upload"}, "_active": true})
 		selector = bson.M{
 			"_userId":  dataSet.UserID,
 			"uploadId": dataSet.UploadID,
 		}
-	} else {
-		selector = bson.M{
-			"_userId":  dataSet.UserID,
-			"uploadId": dataSet.UploadID,
-			"type":     bson.M{"$ne": "upload"},
+		removeInfo, err = d.C().RemoveAll(selector)
+		if err == nil {
+			_, err = d.timeProcessingBatchesSession.C().RemoveAll(bson.M{"uploadId": dataSet.UploadID})
 		}
-	}
-
-	removeInfo, err = d.C().RemoveAll(selector)
-	if err == nil && doPurge == false {
+	} else {
+		// The data set data is deleted with the same deleted time as the data set, so that it can later be restored
+		// along with the data set, until destroyed
 		selector = bson.M{
-			"_userId":       dataSet.UserID,
-			"uploadId":      dataSet.UploadID,
-			"type":          "upload",
-			"deletedTime":   bson.M{"$exists": false},
-			"deletedUserId": bson.M{"$exists": false},
+			"_userId":     dataSet.UserID,
+			"uploadId":    dataSet.UploadID,
+			"type":        bson.M{"$ne": "upload"},
+			"deletedTime": bson.M{"$exists": false},
 		}
 		set := bson.M{
-			"deletedTime": timestamp,
+			"_active":      false,
+			"archivedTime": timestamp,
+			"deletedTime":  timestamp,
+			"modifiedTime": timestamp,
+		}
+		unset := bson.M{
+			"archivedDatasetId": 1,
+			"deletedUserId":     1,
+			"modifiedUserId":    1,
 		}
-		unset := bson.M{}
 		updateInfo, err = d.C().UpdateAll(selector, d.ConstructUpdate(set, unset))
+		if err == nil {
+			timeRange, err = d.dataTimeRange(bson.M{"_userId": dataSet.UserID, "uploadId": dataSet.UploadID, "type": bson.M{"$ne": "upload"}, "deletedTime": timestamp})
+		}
+		if err == nil {
+			selector = bson.M{
+				"_userId":       dataSet.UserID,
+				"uploadId":      dataSet.UploadID,
+				"type":          "upload",
+				"deletedTime":   bson.M{"$exists": false},
+				"deletedUserId": bson.M{"$exists": false},
+			}
+			set = bson.M{
+				"deletedTime": timestamp,
+			}
+			unset = bson.M{}
+			_, err = d.C().UpdateAll(selector, d.ConstructUpdate(set, unset))
+		}
 	}
 
 	loggerFields := log.Fields{"dataSetId": dataSet.UploadID, "doPurge": doPurge, "removeInfo": removeInfo, "updateInfo": updateInfo, "duration": time.Since(now) / time.Microsecond}
@@ -311,10 +359,83 @@ func (d *DataSession) DeleteDataSet(ctx context.Context, dataSet *upload.Upload,
 		return errors.Wrap(err, "unable to delete data set")
 	}
 
+	d.updateDailySummariesForTimeRange(ctx, dataSet.UserID, timeRange)
+
 	dataSet.SetDeletedTime(&timestamp)
 	return nil
 }
 
+// RestoreDataSet restores a deleted, but not purged, data set along with the data deleted with it; the data remains
+// inactive until activated
+func (d *DataSession) RestoreDataSet(ctx context.Context, dataSet *upload.Upload) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if err := validateDataSet(dataSet); err != nil {
+		return err
+	}
+	if dataSet.DeletedTime == nil || *dataSet.DeletedTime == "" {
+		return errors.New("data set is not deleted")
+	}
+	if dataSet.PurgedTime != nil {
+		return errors.New("data set is purged")
+	}
+
+	if d.IsClosed() {
+		return errors.New("session closed")
+	}
+
+	now := time.Now()
+	timestamp := now.Truncate(time.Millisecond).Format(time.RFC3339Nano)
+
+	selector := bson.M{
+		"_userId":     dataSet.UserID,
+		"uploadId":    dataSet.UploadID,
+		"type":        "upload",
+		"deletedTime": *dataSet.DeletedTime,
+		"_purgedTime": bson.M{"$exists": false},
+	}
+	set := bson.M{
+		"modifiedTime": timestamp,
+	}
+	unset := bson.M{
+		"deletedTime":   1,
+		"deletedUserId": 1,
+	}
+	changeInfo, err := d.C().UpdateAll(selector, d.ConstructUpdate(set, unset))
+	if err == nil && changeInfo.Updated == 0 {
+		err = errors.New("data set is not deleted or is purged")
+	}
+
+	var updateInfo *mgo.ChangeInfo
+	if err == nil {
+		selector = bson.M{
+			"_userId":     dataSet.UserID,
+			"uploadId":    dataSet.UploadID,
+			"type":        bson.M{"$ne": "upload"},
+			"deletedTime": *dataSet.DeletedTime,
+		}
+		unset = bson.M{
+			"archivedTime":  1,
+			"deletedTime":   1,
+			"deletedUserId": 1,
+		}
+		updateInfo, err = d.C().UpdateAll(selector, d.ConstructUpdate(set, unset))
+	}
+
+	loggerFields := log.Fields{"dataSetId": dataSet.UploadID, "updateInfo": updateInfo, "duration": time.Since(now) / time.Microsecond}
+	log.LoggerFromContext(ctx).WithFields(loggerFields).WithError(err).Debug("RestoreDataSet")
+
+	if err != nil {
+		return errors.Wrap(err, "unable to restore data set")
+	}
+
+	dataSet.SetDeletedTime(nil)
+	dataSet.SetDeletedUserID(nil)
+	dataSet.SetModifiedTime(&timestamp)
+	return nil
+}
+
 func (d *DataSession) CreateDataSetData(ctx context.Context, dataSet *upload.Upload, dataSetData []data.Datum) error {
 	if ctx == nil {
 		return errors.New("context is missing")
@@ -357,6 +478,8 @@ func (d *DataSession) CreateDataSetData(ctx context.Context, dataSet *upload.Upl
 	if err != nil {
 		return errors.Wrap(err, "unable to create data set data")
 	}
+
+	d.updateDailySummaries(ctx, dataSet.UserID, bson.M{"_userId": dataSet.UserID, "uploadId": dataSet.UploadID, "_active": true, "createdTime": timestamp})
 	return nil
 }
 
@@ -401,6 +524,8 @@ func (d *DataSession) ActivateDataSetData(ctx context.Context, dataSet *upload.U
 	}
 
 	logger.WithFields(log.Fields{"changeInfo": changeInfo, "duration": time.Since(now) / time.Microsecond}).Debug("ActivateDataSetData")
+
+	d.updateDailySummaries(ctx, dataSet.UserID, bson.M{"_userId": dataSet.UserID, "uploadId": dataSet.UploadID, "_active": true, "modifiedTime": timestamp})
 	return nil
 }
 
@@ -445,6 +570,8 @@ func (d *DataSession) ArchiveDataSetData(ctx context.Context, dataSet *upload.Up
 	}
 
 	logger.WithFields(log.Fields{"changeInfo": changeInfo, "duration": time.Since(now) / time.Microsecond}).Debug("ArchiveDataSetData")
+
+	d.updateDailySummaries(ctx, dataSet.UserID, bson.M{"_userId": dataSet.UserID, "uploadId": dataSet.UploadID, "archivedTime": timestamp})
 	return nil
 }
 
@@ -490,6 +617,8 @@ func (d *DataSession) DeleteDataSetData(ctx context.Context, dataSet *upload.Upl
 	}
 
 	logger.WithFields(log.Fields{"changeInfo": changeInfo, "duration": time.Since(now) / time.Microsecond}).Debug("DeleteDataSetData")
+
+	d.updateDailySummaries(ctx, dataSet.UserID, bson.M{"_userId": dataSet.UserID, "uploadId": dataSet.UploadID, "deletedTime": timestamp})
 	return nil
 }
 
@@ -522,10 +651,149 @@ func (d *DataSession) DestroyDeletedDataSetData(ctx context.Context, dataSet *up
 		return errors.Wrap(err, "unable to destroy deleted data set data")
 	}
 
+	// Without selectors all deleted data set data is destroyed, so mark a deleted data set as purged, as it can no
+	// longer be restored
+	if selectors == nil {
+		if _, err = d.timeProcessingBatchesSession.C().RemoveAll(bson.M{"uploadId": dataSet.UploadID}); err != nil {
+			logger.WithError(err).Error("Unable to destroy deleted data set time processing batches")
+			return errors.Wrap(err, "unable to destroy deleted data set time processing batches")
+		}
+		timestamp := now.Truncate(time.Millisecond).Format(time.RFC3339Nano)
+		selector = bson.M{
+			"_userId":     dataSet.UserID,
+			"uploadId":    dataSet.UploadID,
+			"type":        "upload",
+			"deletedTime": bson.M{"$exists": true},
+			"_purgedTime": bson.M{"$exists": false},
+		}
+		set := bson.M{
+			"_purgedTime": timestamp,
+		}
+		unset := bson.M{}
+		if _, err = d.C().UpdateAll(selector, d.ConstructUpdate(set, unset)); err != nil {
+			logger.WithError(err).Error("Unable to mark deleted data set as purged")
+			return errors.Wrap(err, "unable to mark deleted data set as purged")
+		}
+		if dataSet.DeletedTime != nil {
+			dataSet.PurgedTime = pointer.FromString(timestamp)
+		}
+	}
+
 	logger.WithFields(log.Fields{"changeInfo": changeInfo, "duration": time.Since(now) / time.Microsecond}).Debug("DestroyDeletedDataSetData")
 	return nil
 }
 
+// DestroyDeletedData destroys a page of the data deleted before the filter time, oldest deleted first, whether deleted
+// on its own from a data set that is not deleted or with a deleted data set. The data deleted on its own is never
+// restored with its data set, so it can be destroyed once deleted long enough. Destroyed data is no longer selected, so
+// a caller destroys the deleted data page by page, always the first page, until a page is not full.
+func (d *DataSession) DestroyDeletedData(ctx context.Context, filter *data.DeletedDataFilter, pagination *page.Pagination) (*data.DeletedDataDestroyResult, error) {
+	if ctx == nil {
+		return nil, errors.New("context is missing")
+	}
+	if filter == nil {
+		return nil, errors.New("filter is missing")
+	} else if err := structureValidator.New().Validate(filter); err != nil {
+		return nil, errors.Wrap(err, "filter is invalid")
+	}
+	if pagination == nil {
+		pagination = page.NewPagination()
+	} else if err := structureValidator.New().Validate(pagination); err != nil {
+		return nil, errors.Wrap(err, "pagination is invalid")
+	}
+
+	if d.IsClosed() {
+		return nil, errors.New("session closed")
+	}
+
+	now := time.Now()
+	logger := log.LoggerFromContext(ctx).WithFields(log.Fields{"filter": filter, "pagination": pagination})
+
+	selector := bson.M{
+		"deletedTime": bson.M{"$lt": filter.DeletedBefore.Truncate(time.Millisecond).Format(time.RFC3339Nano)},
+		"type":        bson.M{"$ne": "upload"},
+	}
+	ids := []struct {
+		ID       interface{} `bson:"_id"`
+		UploadID string      `bson:"uploadId"`
+	}{}
+	if err := d.C().Find(selector).Select(bson.M{"_id": 1, "uploadId": 1}).Sort("deletedTime").Skip(pagination.Page * pagination.Size).Limit(pagination.Size).All(&ids); err != nil {
+		logger.WithError(err).Error("Unable to get deleted data")
+		return nil, errors.Wrap(err, "unable to get deleted data")
+	}
+
+	result := &data.DeletedDataDestroyResult{}
+	if len(ids) > 0 {
+		idValues := make([]interface{}, len(ids))
+		uploadIDSet := map[string]bool{}
+		uploadIDs := []string{}
+		for index, id := range ids {
+			idValues[index] = id.ID
+			if id.UploadID != "" && !uploadIDSet[id.UploadID] {
+				uploadIDSet[id.UploadID] = true
+				uploadIDs = append(uploadIDs, id.UploadID)
+			}
+		}
+		selector["_id"] = bson.M{"$in": idValues}
+		changeInfo, err := d.C().RemoveAll(selector)
+		if err != nil {
+			logger.WithError(err).Error("Unable to destroy deleted data")
+			return nil, errors.Wrap(err, "unable to destroy deleted data")
+		}
+		result.Destroyed = changeInfo.Removed
+
+		// The destroyed data may include data deleted with a data set not yet purged, which can then no longer be
+		// restored, so mark any such data set as purged, as when its deleted data is destroyed
+		if err = d.purgeDeletedDataSets(uploadIDs, now); err != nil {
+			logger.WithError(err).Error("Unable to mark deleted data sets as purged")
+			return nil, err
+		}
+	}
+
+	logger.WithFields(log.Fields{"destroyed": result.Destroyed, "duration": time.Since(now) / time.Microsecond}).Debug("DestroyDeletedData")
+	return result, nil
+}
+
+// purgeDeletedDataSets marks the deleted data sets with the upload ids, not yet purged, as purged, and destroys their
+// time processing batches
+func (d *DataSession) purgeDeletedDataSets(uploadIDs []string, now time.Time) error {
+	if len(uploadIDs) == 0 {
+		return nil
+	}
+
+	selector := bson.M{
+		"uploadId":    bson.M{"$in": uploadIDs},
+		"type":        "upload",
+		"deletedTime": bson.M{"$exists": true},
+		"_purgedTime": bson.M{"$exists": false},
+	}
+	dataSetIDs := []struct {
+		UploadID string `bson:"uploadId"`
+	}{}
+	if err := d.C().Find(selector).Select(bson.M{"uploadId": 1}).All(&dataSetIDs); err != nil {
+		return errors.Wrap(err, "unable to get deleted data sets")
+	} else if len(dataSetIDs) == 0 {
+		return nil
+	}
+
+	deletedUploadIDs := make([]string, len(dataSetIDs))
+	for index, dataSetID := range dataSetIDs {
+		deletedUploadIDs[index] = dataSetID.UploadID
+	}
+	if _, err := d.timeProcessingBatchesSession.C().RemoveAll(bson.M{"uploadId": bson.M{"$in": deletedUploadIDs}}); err != nil {
+		return errors.Wrap(err, "unable to destroy deleted data sets time processing batches")
+	}
+	selector["uploadId"] = bson.M{"$in": deletedUploadIDs}
+	set := bson.M{
+		"_purgedTime": now.Truncate(time.Millisecond).Format(time.RFC3339Nano),
+	}
+	if _, err := d.C().UpdateAll(selector, d.ConstructUpdate(set, bson.M{})); err != nil {
+		return errors.Wrap(err, "unable to mark deleted data sets as purged")
+	}
+
+	return nil
+}
+
 func (d *DataSession) DestroyDataSetData(ctx context.Context, dataSet *upload.Upload, selectors *data.Selectors) error {
 	if ctx == nil {
 		return errors.New("context is missing")
@@ -548,6 +816,11 @@ func (d *DataSession) DestroyDataSetData(ctx context.Context, dataSet *upload.Up
 	selector["_userId"] = dataSet.UserID
 	selector["uploadId"] = dataSet.UploadID
 	selector["type"] = bson.M{"$ne": "upload"}
+	activeSelector := bson.M{"_active": true}
+	for key, value := range selector {
+		activeSelector[key] = value
+	}
+	timeRange := d.timeRangeBeforeUpdate(ctx, activeSelector)
 	changeInfo, err := d.C().RemoveAll(selector)
 	if err != nil {
 		logger.WithError(err).Error("Unable to destroy data set data")
@@ -555,6 +828,8 @@ func (d *DataSession) DestroyDataSetData(ctx context.Context, dataSet *upload.Up
 	}
 
 	logger.WithFields(log.Fields{"changeInfo": changeInfo, "duration": time.Since(now) / time.Microsecond}).Debug("DestroyDataSetData")
+
+	d.updateDailySummariesForTimeRange(ctx, dataSet.UserID, timeRange)
 	return nil
 }
 
@@ -609,6 +884,8 @@ func (d *DataSession) ArchiveDeviceDataUsingHashesFromDataSet(ctx context.Contex
 	if err != nil {
 		return errors.Wrap(err, "unable to archive device data using hashes from data set")
 	}
+
+	d.updateDailySummaries(ctx, dataSet.UserID, bson.M{"_userId": dataSet.UserID, "archivedDatasetId": dataSet.UploadID, "archivedTime": timestamp})
 	return nil
 }
 
@@ -709,9 +986,125 @@ func (d *DataSession) UnarchiveDeviceDataUsingHashesFromDataSet(ctx context.Cont
 	loggerFields := log.Fields{"dataSetId": dataSet.UploadID, "updateInfo": overallUpdateInfo, "duration": time.Since(now) / time.Microsecond}
 	log.LoggerFromContext(ctx).WithFields(loggerFields).WithError(overallErr).Debug("UnarchiveDeviceDataUsingHashesFromDataSet")
 
+	d.updateDailySummaries(ctx, dataSet.UserID, bson.M{"_userId": dataSet.UserID, "deviceId": dataSet.DeviceID, "modifiedTime": timestamp})
 	return overallErr
 }
 
+// ArchiveDataSetDataUsingHashesFromDevice archives the active data of the data set with the same identity hash as
+// active data of the same device in other data sets
+func (d *DataSession) ArchiveDataSetDataUsingHashesFromDevice(ctx context.Context, dataSet *upload.Upload) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if err := validateDataSet(dataSet); err != nil {
+		return err
+	}
+	if dataSet.DeviceID == nil || *dataSet.DeviceID == "" {
+		return errors.New("data set device id is missing")
+	}
+
+	if d.IsClosed() {
+		return errors.New("session closed")
+	}
+
+	now := time.Now()
+	timestamp := now.Truncate(time.Millisecond).Format(time.RFC3339Nano)
+
+	var updateInfo *mgo.ChangeInfo
+
+	var hashes []string
+	selector := bson.M{
+		"_userId":  dataSet.UserID,
+		"uploadId": dataSet.UploadID,
+		"type":     bson.M{"$ne": "upload"},
+		"_active":  true,
+	}
+	err := d.C().Find(selector).Distinct("_deduplicator.hash", &hashes)
+	if err == nil && len(hashes) > 0 {
+		selector = bson.M{
+			"_userId":            dataSet.UserID,
+			"deviceId":           *dataSet.DeviceID,
+			"uploadId":           bson.M{"$ne": dataSet.UploadID},
+			"type":               bson.M{"$ne": "upload"},
+			"_active":            true,
+			"_deduplicator.hash": bson.M{"$in": hashes},
+		}
+		hashes = nil
+		err = d.C().Find(selector).Distinct("_deduplicator.hash", &hashes)
+	}
+	if err == nil && len(hashes) > 0 {
+		selector = bson.M{
+			"_userId":            dataSet.UserID,
+			"uploadId":           dataSet.UploadID,
+			"type":               bson.M{"$ne": "upload"},
+			"_active":            true,
+			"_deduplicator.hash": bson.M{"$in": hashes},
+		}
+		set := bson.M{
+			"_active":      false,
+			"archivedTime": timestamp,
+			"modifiedTime": timestamp,
+		}
+		unset := bson.M{
+			"archivedDatasetId": 1,
+		}
+		updateInfo, err = d.C().UpdateAll(selector, d.ConstructUpdate(set, unset))
+	}
+
+	loggerFields := log.Fields{"dataSetId": dataSet.UploadID, "deviceId": *dataSet.DeviceID, "updateInfo": updateInfo, "duration": time.Since(now) / time.Microsecond}
+	log.LoggerFromContext(ctx).WithFields(loggerFields).WithError(err).Debug("ArchiveDataSetDataUsingHashesFromDevice")
+
+	if err != nil {
+		return errors.Wrap(err, "unable to archive data set data using hashes from device")
+	}
+
+	d.updateDailySummaries(ctx, dataSet.UserID, bson.M{"_userId": dataSet.UserID, "uploadId": dataSet.UploadID, "archivedTime": timestamp})
+	return nil
+}
+
+// GetDeviceDataHashes returns those of the given identity hashes assigned to active data of the data set device in
+// other data sets, that is the data archived by the hashes of the data set when it is closed
+func (d *DataSession) GetDeviceDataHashes(ctx context.Context, dataSet *upload.Upload, hashes []string) ([]string, error) {
+	if ctx == nil {
+		return nil, errors.New("context is missing")
+	}
+	if err := validateDataSet(dataSet); err != nil {
+		return nil, err
+	}
+	if dataSet.DeviceID == nil || *dataSet.DeviceID == "" {
+		return nil, errors.New("data set device id is missing")
+	}
+
+	if d.IsClosed() {
+		return nil, errors.New("session closed")
+	}
+
+	existingHashes := []string{}
+	if len(hashes) == 0 {
+		return existingHashes, nil
+	}
+
+	now := time.Now()
+
+	selector := bson.M{
+		"_userId":            dataSet.UserID,
+		"deviceId":           *dataSet.DeviceID,
+		"uploadId":           bson.M{"$ne": dataSet.UploadID},
+		"type":               bson.M{"$ne": "upload"},
+		"_active":            true,
+		"_deduplicator.hash": bson.M{"$in": hashes},
+	}
+	err := d.C().Find(selector).Distinct("_deduplicator.hash", &existingHashes)
+
+	loggerFields := log.Fields{"userId": dataSet.UserID, "deviceId": *dataSet.DeviceID, "hashesCount": len(hashes), "existingHashesCount": len(existingHashes), "duration": time.Since(now) / time.Microsecond}
+	log.LoggerFromContext(ctx).WithFields(loggerFields).WithError(err).Debug("GetDeviceDataHashes")
+
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get device data hashes")
+	}
+	return existingHashes, nil
+}
+
 func (d *DataSession) DeleteOtherDataSetData(ctx context.Context, dataSet *upload.Upload) error {
 	if ctx == nil {
 		return errors.New("context is missing")
@@ -741,6 +1134,7 @@ func (d *DataSession) DeleteOtherDataSetData(ctx context.Context, dataSet *uploa
 		"uploadId": bson.M{"$ne": dataSet.UploadID},
 		"type":     bson.M{"$ne": "upload"},
 	}
+	timeRange := d.timeRangeBeforeUpdate(ctx, bson.M{"_userId": dataSet.UserID, "deviceId": *dataSet.DeviceID, "uploadId": bson.M{"$ne": dataSet.UploadID}, "type": bson.M{"$ne": "upload"}, "_active": true})
 	removeInfo, err = d.C().RemoveAll(selector)
 	if err == nil {
 		selector = bson.M{
@@ -764,70 +1158,318 @@ func (d *DataSession) DeleteOtherDataSetData(ctx context.Context, dataSet *uploa
 	if err != nil {
 		return errors.Wrap(err, "unable to remove other data set data")
 	}
+
+	d.updateDailySummariesForTimeRange(ctx, dataSet.UserID, timeRange)
 	return nil
 }
 
-func (d *DataSession) DestroyDataForUserByID(ctx context.Context, userID string) error {
+var dataSampleFields = bson.M{"id": 1, "type": 1, "time": 1, "value": 1, "origin": 1, "uploadId": 1, "createdTime": 1}
+
+// GetDataSetDataSamples returns a sample of each datum in the data set, ordered by time
+func (d *DataSession) GetDataSetDataSamples(ctx context.Context, dataSet *upload.Upload) ([]*storeDEPRECATED.DataSample, error) {
 	if ctx == nil {
-		return errors.New("context is missing")
+		return nil, errors.New("context is missing")
 	}
-	if userID == "" {
-		return errors.New("user id is missing")
+	if err := validateDataSet(dataSet); err != nil {
+		return nil, err
 	}
 
 	if d.IsClosed() {
-		return errors.New("session closed")
+		return nil, errors.New("session closed")
 	}
 
 	now := time.Now()
 
+	samples := []*storeDEPRECATED.DataSample{}
 	selector := bson.M{
-		"_userId": userID,
+		"_userId":     dataSet.UserID,
+		"uploadId":    dataSet.UploadID,
+		"type":        bson.M{"$ne": "upload"},
+		"deletedTime": bson.M{"$exists": false},
 	}
-	removeInfo, err := d.C().RemoveAll(selector)
+	err := d.C().Find(selector).Select(dataSampleFields).Sort("time").All(&samples)
 
-	loggerFields := log.Fields{"userId": userID, "removeInfo": removeInfo, "duration": time.Since(now) / time.Microsecond}
-	log.LoggerFromContext(ctx).WithFields(loggerFields).WithError(err).Debug("DestroyDataForUserByID")
+	loggerFields := log.Fields{"dataSetId": dataSet.UploadID, "samplesCount": len(samples), "duration": time.Since(now) / time.Microsecond}
+	log.LoggerFromContext(ctx).WithFields(loggerFields).WithError(err).Debug("GetDataSetDataSamples")
 
 	if err != nil {
-		return errors.Wrap(err, "unable to destroy data for user by id")
+		return nil, errors.Wrap(err, "unable to get data set data samples")
 	}
-
-	return nil
+	return samples, nil
 }
 
-func (d *DataSession) ListUserDataSets(ctx context.Context, userID string, filter *data.DataSetFilter, pagination *page.Pagination) (data.DataSets, error) {
+// GetDeviceDataSamples returns a sample of each active datum of the data set device, in other data sets, with one of
+// the types and a time within the range, ordered by time
+func (d *DataSession) GetDeviceDataSamples(ctx context.Context, dataSet *upload.Upload, types []string, startTime time.Time, endTime time.Time) ([]*storeDEPRECATED.DataSample, error) {
 	if ctx == nil {
 		return nil, errors.New("context is missing")
 	}
-	if userID == "" {
-		return nil, errors.New("user id is missing")
+	if err := validateDataSet(dataSet); err != nil {
+		return nil, err
 	}
-	if filter == nil {
-		filter = data.NewDataSetFilter()
-	} else if err := structureValidator.New().Validate(filter); err != nil {
-		return nil, errors.Wrap(err, "filter is invalid")
+	if dataSet.DeviceID == nil || *dataSet.DeviceID == "" {
+		return nil, errors.New("data set device id is missing")
 	}
-	if pagination == nil {
-		pagination = page.NewPagination()
-	} else if err := structureValidator.New().Validate(pagination); err != nil {
-		return nil, errors.Wrap(err, "pagination is invalid")
+	if startTime.IsZero() {
+		return nil, errors.New("start time is missing")
+	}
+	if endTime.IsZero() {
+		return nil, errors.New("end time is missing")
+	} else if endTime.Before(startTime) {
+		return nil, errors.New("end time is before start time")
 	}
 
 	if d.IsClosed() {
 		return nil, errors.New("session closed")
 	}
 
+	samples := []*storeDEPRECATED.DataSample{}
+	if len(types) == 0 {
+		return samples, nil
+	}
+
 	now := time.Now()
-	logger := log.LoggerFromContext(ctx).WithFields(log.Fields{"userId": userID, "filter": filter, "pagination": pagination})
 
-	dataSets := data.DataSets{}
 	selector := bson.M{
-		"_active": true,
-		"_userId": userID,
-		"type":    "upload",
+		"_userId":     dataSet.UserID,
+		"deviceId":    *dataSet.DeviceID,
+		"uploadId":    bson.M{"$ne": dataSet.UploadID},
+		"type":        bson.M{"$ne": "upload", "$in": types},
+		"_active":     true,
+		"deletedTime": bson.M{"$exists": false},
+		"time": bson.M{
+			"$gte": startTime.UTC().Format(data.TimeFormat),
+			"$lte": endTime.UTC().Format(data.TimeFormat),
+		},
 	}
-	if filter.ClientName != nil {
+	err := d.C().Find(selector).Select(dataSampleFields).Sort("time").All(&samples)
+
+	loggerFields := log.Fields{"dataSetId": dataSet.UploadID, "types": types, "startTime": startTime, "endTime": endTime, "samplesCount": len(samples), "duration": time.Since(now) / time.Microsecond}
+	log.LoggerFromContext(ctx).WithFields(loggerFields).WithError(err).Debug("GetDeviceDataSamples")
+
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get device data samples")
+	}
+	return samples, nil
+}
+
+// ArchiveDeviceDataByIDs archives the active data of the data set device with the ids, on behalf of the data set
+func (d *DataSession) ArchiveDeviceDataByIDs(ctx context.Context, dataSet *upload.Upload, ids []string) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if err := validateDataSet(dataSet); err != nil {
+		return err
+	}
+	if dataSet.DeviceID == nil || *dataSet.DeviceID == "" {
+		return errors.New("data set device id is missing")
+	}
+
+	if d.IsClosed() {
+		return errors.New("session closed")
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	timestamp := now.Truncate(time.Millisecond).Format(time.RFC3339Nano)
+
+	selector := bson.M{
+		"_userId":  dataSet.UserID,
+		"deviceId": *dataSet.DeviceID,
+		"type":     bson.M{"$ne": "upload"},
+		"_active":  true,
+		"id":       bson.M{"$in": ids},
+	}
+	set := bson.M{
+		"_active":           false,
+		"archivedDatasetId": dataSet.UploadID,
+		"archivedTime":      timestamp,
+		"modifiedTime":      timestamp,
+	}
+	unset := bson.M{}
+	updateInfo, err := d.C().UpdateAll(selector, d.ConstructUpdate(set, unset))
+
+	loggerFields := log.Fields{"dataSetId": dataSet.UploadID, "idsCount": len(ids), "updateInfo": updateInfo, "duration": time.Since(now) / time.Microsecond}
+	log.LoggerFromContext(ctx).WithFields(loggerFields).WithError(err).Debug("ArchiveDeviceDataByIDs")
+
+	if err != nil {
+		return errors.Wrap(err, "unable to archive device data by ids")
+	}
+
+	d.updateDailySummaries(ctx, dataSet.UserID, bson.M{"_userId": dataSet.UserID, "id": bson.M{"$in": ids}, "archivedTime": timestamp})
+	return nil
+}
+
+// UnarchiveDeviceDataArchivedByDataSet activates the data of other data sets the data set archived
+func (d *DataSession) UnarchiveDeviceDataArchivedByDataSet(ctx context.Context, dataSet *upload.Upload) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if err := validateDataSet(dataSet); err != nil {
+		return err
+	}
+
+	if d.IsClosed() {
+		return errors.New("session closed")
+	}
+
+	now := time.Now()
+	timestamp := now.Truncate(time.Millisecond).Format(time.RFC3339Nano)
+
+	selector := bson.M{
+		"_userId":           dataSet.UserID,
+		"uploadId":          bson.M{"$ne": dataSet.UploadID},
+		"type":              bson.M{"$ne": "upload"},
+		"archivedDatasetId": dataSet.UploadID,
+	}
+	set := bson.M{
+		"_active":      true,
+		"modifiedTime": timestamp,
+	}
+	unset := bson.M{
+		"archivedDatasetId": 1,
+		"archivedTime":      1,
+	}
+	updateInfo, err := d.C().UpdateAll(selector, d.ConstructUpdate(set, unset))
+
+	loggerFields := log.Fields{"dataSetId": dataSet.UploadID, "updateInfo": updateInfo, "duration": time.Since(now) / time.Microsecond}
+	log.LoggerFromContext(ctx).WithFields(loggerFields).WithError(err).Debug("UnarchiveDeviceDataArchivedByDataSet")
+
+	if err != nil {
+		return errors.Wrap(err, "unable to unarchive device data archived by data set")
+	}
+
+	d.updateDailySummaries(ctx, dataSet.UserID, bson.M{"_userId": dataSet.UserID, "uploadId": bson.M{"$ne": dataSet.UploadID}, "_active": true, "modifiedTime": timestamp})
+	return nil
+}
+
+// UpdateDataAssociations replaces the associations of the active data of the user, by datum id, removing them if nil
+func (d *DataSession) UpdateDataAssociations(ctx context.Context, userID string, associations map[string]*association.AssociationArray) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if userID == "" {
+		return errors.New("user id is missing")
+	}
+	if associations == nil {
+		return errors.New("associations is missing")
+	}
+
+	if d.IsClosed() {
+		return errors.New("session closed")
+	}
+
+	if len(associations) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	timestamp := now.Truncate(time.Millisecond).Format(time.RFC3339Nano)
+
+	bulk := d.C().Bulk()
+	bulk.Unordered()
+	for id, datumAssociations := range associations {
+		selector := bson.M{
+			"_userId": userID,
+			"id":      id,
+			"type":    bson.M{"$ne": "upload"},
+			"_active": true,
+		}
+		set := bson.M{
+			"modifiedTime": timestamp,
+		}
+		unset := bson.M{}
+		if datumAssociations != nil {
+			set["associations"] = datumAssociations
+		} else {
+			unset["associations"] = 1
+		}
+		bulk.Update(selector, d.ConstructUpdate(set, unset))
+	}
+	bulkResult, err := bulk.Run()
+
+	loggerFields := log.Fields{"userId": userID, "associationsCount": len(associations), "bulkResult": bulkResult, "duration": time.Since(now) / time.Microsecond}
+	log.LoggerFromContext(ctx).WithFields(loggerFields).WithError(err).Debug("UpdateDataAssociations")
+
+	if err != nil {
+		return errors.Wrap(err, "unable to update data associations")
+	}
+	return nil
+}
+
+func (d *DataSession) DestroyDataForUserByID(ctx context.Context, userID string) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if userID == "" {
+		return errors.New("user id is missing")
+	}
+
+	if d.IsClosed() {
+		return errors.New("session closed")
+	}
+
+	now := time.Now()
+
+	selector := bson.M{
+		"_userId": userID,
+	}
+	removeInfo, err := d.C().RemoveAll(selector)
+	if err == nil {
+		_, err = d.dailySummariesSession.C().RemoveAll(bson.M{"userId": userID})
+	}
+	if err == nil {
+		_, err = d.dailySummaryRebuildsSession.C().RemoveAll(bson.M{"userId": userID})
+	}
+	if err == nil {
+		_, err = d.timeProcessingBatchesSession.C().RemoveAll(bson.M{"userId": userID})
+	}
+
+	loggerFields := log.Fields{"userId": userID, "removeInfo": removeInfo, "duration": time.Since(now) / time.Microsecond}
+	log.LoggerFromContext(ctx).WithFields(loggerFields).WithError(err).Debug("DestroyDataForUserByID")
+
+	if err != nil {
+		return errors.Wrap(err, "unable to destroy data for user by id")
+	}
+
+	return nil
+}
+
+func (d *DataSession) ListUserDataSets(ctx context.Context, userID string, filter *data.DataSetFilter, pagination *page.Pagination) (data.DataSets, error) {
+	if ctx == nil {
+		return nil, errors.New("context is missing")
+	}
+	if userID == "" {
+		return nil, errors.New("user id is missing")
+	}
+	if filter == nil {
+		filter = data.NewDataSetFilter()
+	} else if err := structureValidator.New().Validate(filter); err != nil {
+		return nil, errors.Wrap(err, "filter is invalid")
+	}
+	if pagination == nil {
+		pagination = page.NewPagination()
+	} else if err := structureValidator.New().Validate(pagination); err != nil {
+		return nil, errors.Wrap(err, "pagination is invalid")
+	}
+
+	if d.IsClosed() {
+		return nil, errors.New("session closed")
+	}
+
+	now := time.Now()
+	logger := log.LoggerFromContext(ctx).WithFields(log.Fields{"userId": userID, "filter": filter, "pagination": pagination})
+
+	dataSets := data.DataSets{}
+	selector := bson.M{
+		"_active": true,
+		"_userId": userID,
+		"type":    "upload",
+	}
+	if filter.ClientName != nil {
 		selector["client.name"] = *filter.ClientName
 	}
 	if filter.Deleted == nil || !*filter.Deleted {
@@ -855,6 +1497,51 @@ func (d *DataSession) ListUserDataSets(ctx context.Context, userID string, filte
 	return dataSets, nil
 }
 
+func (d *DataSession) ListDeletedDataSets(ctx context.Context, filter *data.DeletedDataSetFilter, pagination *page.Pagination) (data.DataSets, error) {
+	if ctx == nil {
+		return nil, errors.New("context is missing")
+	}
+	if filter == nil {
+		filter = data.NewDeletedDataSetFilter()
+	} else if err := structureValidator.New().Validate(filter); err != nil {
+		return nil, errors.Wrap(err, "filter is invalid")
+	}
+	if pagination == nil {
+		pagination = page.NewPagination()
+	} else if err := structureValidator.New().Validate(pagination); err != nil {
+		return nil, errors.Wrap(err, "pagination is invalid")
+	}
+
+	if d.IsClosed() {
+		return nil, errors.New("session closed")
+	}
+
+	now := time.Now()
+	logger := log.LoggerFromContext(ctx).WithFields(log.Fields{"filter": filter, "pagination": pagination})
+
+	dataSets := data.DataSets{}
+	deletedTimeSelector := bson.M{"$exists": true}
+	if filter.DeletedBefore != nil {
+		deletedTimeSelector["$lt"] = filter.DeletedBefore.Truncate(time.Millisecond).Format(time.RFC3339Nano)
+	}
+	selector := bson.M{
+		"type":        "upload",
+		"deletedTime": deletedTimeSelector,
+		"_purgedTime": bson.M{"$exists": false},
+	}
+	err := d.C().Find(selector).Sort("deletedTime").Skip(pagination.Page * pagination.Size).Limit(pagination.Size).All(&dataSets)
+	logger.WithFields(log.Fields{"count": len(dataSets), "duration": time.Since(now) / time.Microsecond}).WithError(err).Debug("ListDeletedDataSets")
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list deleted data sets")
+	}
+
+	if dataSets == nil {
+		dataSets = data.DataSets{}
+	}
+
+	return dataSets, nil
+}
+
 func (d *DataSession) GetDataSet(ctx context.Context, id string) (*data.DataSet, error) {
 	if ctx == nil {
 		return nil, errors.New("context is missing")
@@ -892,6 +1579,205 @@ func (d *DataSession) GetDataSet(ctx context.Context, id string) (*data.DataSet,
 	}
 }
 
+func (d *DataSession) GetDataForUserByID(ctx context.Context, userID string, filter *storeDEPRECATED.DataFilter, pagination *page.Pagination) (data.Data, error) {
+	if ctx == nil {
+		return nil, errors.New("context is missing")
+	}
+	if userID == "" {
+		return nil, errors.New("user id is missing")
+	}
+	if filter == nil {
+		filter = storeDEPRECATED.NewDataFilter()
+	} else if err := structureValidator.New().Validate(filter); err != nil {
+		return nil, errors.Wrap(err, "filter is invalid")
+	}
+	if pagination == nil {
+		pagination = page.NewPagination()
+	} else if err := structureValidator.New().Validate(pagination); err != nil {
+		return nil, errors.Wrap(err, "pagination is invalid")
+	}
+
+	if d.IsClosed() {
+		return nil, errors.New("session closed")
+	}
+
+	now := time.Now()
+	logger := log.LoggerFromContext(ctx).WithFields(log.Fields{"userId": userID, "filter": filter, "pagination": pagination})
+
+	dataSetData := data.Data{}
+	iter := d.C().Find(dataSelector(userID, filter)).Sort("-time").Skip(pagination.Page * pagination.Size).Limit(pagination.Size).Iter()
+
+	var raw bson.Raw
+	for iter.Next(&raw) {
+		datum, err := decodeDatum(raw)
+		if err != nil {
+			logger.WithError(err).Warn("Unable to decode datum")
+			continue
+		}
+		dataSetData = append(dataSetData, datum)
+	}
+	err := iter.Close()
+
+	logger.WithFields(log.Fields{"count": len(dataSetData), "duration": time.Since(now) / time.Microsecond}).WithError(err).Debug("GetDataForUserByID")
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get data for user by id")
+	}
+
+	return dataSetData, nil
+}
+
+func (d *DataSession) IterateDataForUserByID(ctx context.Context, userID string, filter *storeDEPRECATED.DataFilter, cursor *storeDEPRECATED.DataCursor) (storeDEPRECATED.DataIterator, error) {
+	if ctx == nil {
+		return nil, errors.New("context is missing")
+	}
+	if userID == "" {
+		return nil, errors.New("user id is missing")
+	}
+	if filter == nil {
+		filter = storeDEPRECATED.NewDataFilter()
+	} else if err := structureValidator.New().Validate(filter); err != nil {
+		return nil, errors.Wrap(err, "filter is invalid")
+	}
+
+	if d.IsClosed() {
+		return nil, errors.New("session closed")
+	}
+
+	selector := dataSelector(userID, filter)
+	if cursor != nil {
+		selector["$or"] = []bson.M{
+			{"time": bson.M{"$gt": cursor.Time}},
+			{"time": cursor.Time, "id": bson.M{"$gt": cursor.ID}},
+		}
+	}
+
+	log.LoggerFromContext(ctx).WithFields(log.Fields{"userId": userID, "filter": filter, "cursor": cursor}).Debug("IterateDataForUserByID")
+
+	return &dataIterator{
+		iter: d.C().Find(selector).Sort("time", "id").Iter(),
+	}, nil
+}
+
+// dataIterator decodes the documents of a mongo cursor one at a time, skipping those not
+// supported by the datum factory, while keeping track of the position reached
+type dataIterator struct {
+	iter   *mgo.Iter
+	datum  data.Datum
+	cursor *storeDEPRECATED.DataCursor
+}
+
+func (d *dataIterator) Next(ctx context.Context) bool {
+	var raw bson.Raw
+	for d.iter.Next(&raw) {
+		position := struct {
+			Time string `bson:"time"`
+			ID   string `bson:"id"`
+		}{}
+		if err := raw.Unmarshal(&position); err == nil {
+			d.cursor = storeDEPRECATED.NewDataCursor(position.Time, position.ID)
+		}
+
+		datum, err := decodeDatum(raw)
+		if err != nil {
+			log.LoggerFromContext(ctx).WithError(err).Warn("Unable to decode datum")
+			continue
+		}
+
+		d.datum = datum
+		return true
+	}
+
+	d.datum = nil
+	return false
+}
+
+func (d *dataIterator) Datum() data.Datum {
+	return d.datum
+}
+
+func (d *dataIterator) Cursor() *storeDEPRECATED.DataCursor {
+	return d.cursor
+}
+
+func (d *dataIterator) Error() error {
+	if err := d.iter.Err(); err != nil {
+		return errors.Wrap(err, "unable to iterate data for user by id")
+	}
+	return nil
+}
+
+func (d *dataIterator) Close() error {
+	if err := d.iter.Close(); err != nil {
+		return errors.Wrap(err, "unable to close data iterator")
+	}
+	return nil
+}
+
+func dataSelector(userID string, filter *storeDEPRECATED.DataFilter) bson.M {
+	selector := bson.M{
+		"_userId": userID,
+		"_active": true,
+		"type":    bson.M{"$ne": "upload"},
+	}
+	if filter.Type != nil {
+		selector["type"] = bson.M{"$ne": "upload", "$in": *filter.Type}
+	}
+	if filter.SubType != nil {
+		selector["subType"] = bson.M{"$in": *filter.SubType}
+	}
+	if filter.DeviceID != nil {
+		selector["deviceId"] = *filter.DeviceID
+	}
+	if filter.UploadID != nil {
+		selector["uploadId"] = *filter.UploadID
+	}
+	if filter.StartDate != nil || filter.EndDate != nil {
+		timeSelector := bson.M{}
+		if filter.StartDate != nil {
+			timeSelector["$gte"] = filter.StartDate.UTC().Format(data.TimeFormat)
+		}
+		if filter.EndDate != nil {
+			timeSelector["$lte"] = filter.EndDate.UTC().Format(data.TimeFormat)
+		}
+		selector["time"] = timeSelector
+	}
+	return selector
+}
+
+// decodeDatum uses the datum factory to determine the concrete type of the stored document
+// and then decodes the full document, including the fields managed by the store, into it,
+// after upgrading the document from its schema version to the current one, if its type has upgrades
+func decodeDatum(raw bson.Raw) (data.Datum, error) {
+	object := map[string]interface{}{}
+	if err := raw.Unmarshal(&object); err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal datum")
+	}
+
+	if upgraded, err := dataTypesSchema.UpgradeDocument(object); err != nil {
+		return nil, errors.Wrap(err, "unable to upgrade datum")
+	} else if upgraded {
+		bytes, err := bson.Marshal(object)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to marshal datum")
+		}
+		raw = bson.Raw{Kind: 0x03, Data: bytes}
+	}
+
+	parser := structureParser.NewObject(&object)
+	datum := dataTypesFactory.NewDatum(parser)
+	if err := parser.Error(); err != nil {
+		return nil, errors.Wrap(err, "unable to create datum")
+	} else if datum == nil {
+		return nil, errors.New("unable to create datum")
+	}
+
+	if err := raw.Unmarshal(datum); err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal datum")
+	}
+
+	return datum, nil
+}
+
 func validateDataSet(dataSet *upload.Upload) error {
 	if dataSet == nil {
 		return errors.New("data set is missing")
@@ -3,12 +3,14 @@ package mongo_test
 import (
 	"context"
 	"math/rand"
+	"time"
 
 	mgo "github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"github.com/tidepool-org/platform/association"
 	"github.com/tidepool-org/platform/data"
 	"github.com/tidepool-org/platform/data/storeDEPRECATED"
 	"github.com/tidepool-org/platform/data/storeDEPRECATED/mongo"
@@ -118,6 +120,14 @@ func DataSetDatumAsInterface(dataSetDatum data.Datum) interface{} {
 	return dataSetDatumAsInterface
 }
 
+func DataSetDataIDs(dataSetData data.Data) []interface{} {
+	var ids []interface{}
+	for _, dataSetDatum := range dataSetData {
+		ids = append(ids, DataSetDatumAsInterface(dataSetDatum).(bson.M)["id"])
+	}
+	return ids
+}
+
 var _ = Describe("Mongo", func() {
 	var logger *logTest.Logger
 	var config *storeStructuredMongo.Config
@@ -642,6 +652,12 @@ var _ = Describe("Mongo", func() {
 							It("has the correct stored data set data", func() {
 								ValidateDataSetData(mgoCollection, bson.M{"uploadId": dataSet.UploadID}, bson.M{}, dataSetData)
 								Expect(session.DeleteDataSet(ctx, dataSet, false)).To(Succeed())
+								ValidateDataSetData(mgoCollection, bson.M{"uploadId": dataSet.UploadID, "deletedTime": bson.M{"$exists": false}}, bson.M{}, data.Data{})
+								Expect(mgoCollection.Find(bson.M{"uploadId": dataSet.UploadID, "type": bson.M{"$ne": "upload"}, "_active": false, "deletedTime": *dataSet.DeletedTime}).Count()).To(Equal(len(dataSetData)))
+							})
+
+							It("removes the data set data when purged", func() {
+								Expect(session.DeleteDataSet(ctx, dataSet, true)).To(Succeed())
 								ValidateDataSetData(mgoCollection, bson.M{"uploadId": dataSet.UploadID}, bson.M{}, data.Data{})
 							})
 
@@ -651,6 +667,99 @@ var _ = Describe("Mongo", func() {
 						})
 					})
 
+					Context("RestoreDataSet", func() {
+						It("returns an error if the data set is missing", func() {
+							Expect(session.RestoreDataSet(ctx, nil)).To(MatchError("data set is missing"))
+						})
+
+						It("returns an error if the data set is not deleted", func() {
+							dataSet.DeletedTime = nil
+							Expect(session.RestoreDataSet(ctx, dataSet)).To(MatchError("data set is not deleted"))
+						})
+
+						It("returns an error if the data set is purged", func() {
+							dataSet.DeletedTime = pointer.FromString(time.Now().Format(time.RFC3339Nano))
+							dataSet.PurgedTime = pointer.FromString(time.Now().Format(time.RFC3339Nano))
+							Expect(session.RestoreDataSet(ctx, dataSet)).To(MatchError("data set is purged"))
+						})
+
+						It("returns an error if the session is closed", func() {
+							dataSet.DeletedTime = pointer.FromString(time.Now().Format(time.RFC3339Nano))
+							session.Close()
+							Expect(session.RestoreDataSet(ctx, dataSet)).To(MatchError("session closed"))
+						})
+
+						Context("with database access", func() {
+							BeforeEach(func() {
+								preparePersistedDataSetsData()
+								Expect(session.CreateDataSetData(ctx, dataSet, dataSetData)).To(Succeed())
+								Expect(session.DeleteDataSet(ctx, dataSet, false)).To(Succeed())
+							})
+
+							It("restores the data set and the data deleted with it", func() {
+								Expect(session.RestoreDataSet(ctx, dataSet)).To(Succeed())
+								Expect(dataSet.DeletedTime).To(BeNil())
+								ValidateDataSet(mgoCollection, bson.M{"deletedTime": bson.M{"$exists": true}}, bson.M{})
+								Expect(mgoCollection.Find(bson.M{"uploadId": dataSet.UploadID, "type": bson.M{"$ne": "upload"}, "deletedTime": bson.M{"$exists": false}}).Count()).To(Equal(len(dataSetData)))
+							})
+
+							It("returns an error if the deleted data set data is destroyed", func() {
+								Expect(session.DestroyDeletedDataSetData(ctx, dataSet, nil)).To(Succeed())
+								Expect(dataSet.PurgedTime).ToNot(BeNil())
+								Expect(session.RestoreDataSet(ctx, dataSet)).To(MatchError("data set is purged"))
+							})
+						})
+					})
+
+					Context("ListDeletedDataSets", func() {
+						It("returns an error if the context is missing", func() {
+							dataSets, err := session.ListDeletedDataSets(nil, nil, nil)
+							Expect(err).To(MatchError("context is missing"))
+							Expect(dataSets).To(BeNil())
+						})
+
+						It("returns an error if the filter is invalid", func() {
+							filter := data.NewDeletedDataSetFilter()
+							filter.DeletedBefore = pointer.FromTime(time.Time{})
+							dataSets, err := session.ListDeletedDataSets(ctx, filter, nil)
+							Expect(err).To(MatchError("filter is invalid; value is empty"))
+							Expect(dataSets).To(BeNil())
+						})
+
+						It("returns an error if the session is closed", func() {
+							session.Close()
+							dataSets, err := session.ListDeletedDataSets(ctx, nil, nil)
+							Expect(err).To(MatchError("session closed"))
+							Expect(dataSets).To(BeNil())
+						})
+
+						Context("with database access", func() {
+							BeforeEach(func() {
+								preparePersistedDataSetsData()
+								Expect(session.CreateDataSetData(ctx, dataSet, dataSetData)).To(Succeed())
+								Expect(session.DeleteDataSet(ctx, dataSet, false)).To(Succeed())
+							})
+
+							It("lists the deleted data set", func() {
+								dataSets, err := session.ListDeletedDataSets(ctx, nil, nil)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(dataSets).To(HaveLen(1))
+								Expect(dataSets[0].UploadID).To(Equal(dataSet.UploadID))
+							})
+
+							It("does not list the data set deleted after the deleted before time", func() {
+								filter := data.NewDeletedDataSetFilter()
+								filter.DeletedBefore = pointer.FromTime(time.Now().Add(-time.Hour))
+								Expect(session.ListDeletedDataSets(ctx, filter, nil)).To(BeEmpty())
+							})
+
+							It("does not list the data set once its deleted data is destroyed", func() {
+								Expect(session.DestroyDeletedDataSetData(ctx, dataSet, nil)).To(Succeed())
+								Expect(session.ListDeletedDataSets(ctx, nil, nil)).To(BeEmpty())
+							})
+						})
+					})
+
 					Context("CreateDataSetData", func() {
 						It("returns an error if the data set is missing", func() {
 							Expect(session.CreateDataSetData(ctx, nil, dataSetData)).To(MatchError("data set is missing"))
@@ -741,6 +850,182 @@ var _ = Describe("Mongo", func() {
 						})
 					})
 
+					Context("GetDataForUserByID", func() {
+						var filter *storeDEPRECATED.DataFilter
+						var pagination *page.Pagination
+
+						BeforeEach(func() {
+							filter = storeDEPRECATED.NewDataFilter()
+							pagination = page.NewPagination()
+						})
+
+						It("returns an error if the user id is missing", func() {
+							resultData, err := session.GetDataForUserByID(ctx, "", filter, pagination)
+							Expect(err).To(MatchError("user id is missing"))
+							Expect(resultData).To(BeNil())
+						})
+
+						It("returns an error if the filter is invalid", func() {
+							filter.DeviceID = pointer.FromString("")
+							resultData, err := session.GetDataForUserByID(ctx, userID, filter, pagination)
+							Expect(err).To(MatchError("filter is invalid; value is empty"))
+							Expect(resultData).To(BeNil())
+						})
+
+						It("returns an error if the pagination size is less than minimum", func() {
+							pagination.Size = 0
+							resultData, err := session.GetDataForUserByID(ctx, userID, filter, pagination)
+							Expect(err).To(MatchError("pagination is invalid; value 0 is not between 1 and 1000"))
+							Expect(resultData).To(BeNil())
+						})
+
+						It("returns an error if the session is closed", func() {
+							session.Close()
+							resultData, err := session.GetDataForUserByID(ctx, userID, filter, pagination)
+							Expect(err).To(MatchError("session closed"))
+							Expect(resultData).To(BeNil())
+						})
+
+						Context("with database access", func() {
+							BeforeEach(func() {
+								for index, datum := range dataSetData {
+									baseDatum := datum.(*types.Base)
+									baseDatum.Active = true
+									baseDatum.DeletedTime = nil
+									baseDatum.Time = pointer.FromString(time.Date(2016, 9, 1, 12, index, 0, 0, time.UTC).Format(time.RFC3339Nano))
+									if index%2 == 0 {
+										baseDatum.Type = "food"
+									} else {
+										baseDatum.Type = "reportedState"
+									}
+								}
+								preparePersistedDataSetsData()
+								Expect(session.CreateDataSetData(ctx, dataSet, dataSetData)).To(Succeed())
+							})
+
+							It("succeeds if it successfully finds the user data", func() {
+								resultData, err := session.GetDataForUserByID(ctx, userID, filter, pagination)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(DataSetDataIDs(resultData)).To(ConsistOf(DataSetDataIDs(dataSetData)))
+							})
+
+							It("succeeds if the filter and pagination are not specified", func() {
+								resultData, err := session.GetDataForUserByID(ctx, userID, nil, nil)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(DataSetDataIDs(resultData)).To(ConsistOf(DataSetDataIDs(dataSetData)))
+							})
+
+							It("returns the most recent data first", func() {
+								pagination.Size = 1
+								resultData, err := session.GetDataForUserByID(ctx, userID, filter, pagination)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(DataSetDataIDs(resultData)).To(Equal(DataSetDataIDs(dataSetData[len(dataSetData)-1:])))
+							})
+
+							It("succeeds if it successfully finds the user data with a type filter", func() {
+								filter.Type = pointer.FromStringArray([]string{"food"})
+								resultData, err := session.GetDataForUserByID(ctx, userID, filter, pagination)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(resultData).ToNot(BeEmpty())
+								for _, datum := range resultData {
+									Expect(DataSetDatumAsInterface(datum)).To(HaveKeyWithValue("type", "food"))
+								}
+							})
+
+							It("succeeds if it successfully finds the user data with a time range filter", func() {
+								filter.StartDate = pointer.FromTime(time.Date(2016, 9, 1, 12, 1, 0, 0, time.UTC))
+								filter.EndDate = pointer.FromTime(time.Date(2016, 9, 1, 12, 2, 0, 0, time.UTC))
+								resultData, err := session.GetDataForUserByID(ctx, userID, filter, pagination)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(DataSetDataIDs(resultData)).To(ConsistOf(DataSetDataIDs(dataSetData[1:3])))
+							})
+
+							It("succeeds if it successfully finds the user data with an upload id filter", func() {
+								filter.UploadID = dataSetExistingOne.UploadID
+								resultData, err := session.GetDataForUserByID(ctx, userID, filter, pagination)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(resultData).To(BeEmpty())
+							})
+
+							It("succeeds if it successfully does not find another user data", func() {
+								resultData, err := session.GetDataForUserByID(ctx, userTest.RandomID(), filter, pagination)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(resultData).ToNot(BeNil())
+								Expect(resultData).To(BeEmpty())
+							})
+						})
+					})
+
+					Context("IterateDataForUserByID", func() {
+						var filter *storeDEPRECATED.DataFilter
+
+						BeforeEach(func() {
+							filter = storeDEPRECATED.NewDataFilter()
+						})
+
+						It("returns an error if the user id is missing", func() {
+							iterator, err := session.IterateDataForUserByID(ctx, "", filter, nil)
+							Expect(err).To(MatchError("user id is missing"))
+							Expect(iterator).To(BeNil())
+						})
+
+						It("returns an error if the filter is invalid", func() {
+							filter.DeviceID = pointer.FromString("")
+							iterator, err := session.IterateDataForUserByID(ctx, userID, filter, nil)
+							Expect(err).To(MatchError("filter is invalid; value is empty"))
+							Expect(iterator).To(BeNil())
+						})
+
+						It("returns an error if the session is closed", func() {
+							session.Close()
+							iterator, err := session.IterateDataForUserByID(ctx, userID, filter, nil)
+							Expect(err).To(MatchError("session closed"))
+							Expect(iterator).To(BeNil())
+						})
+
+						Context("with database access", func() {
+							iterate := func(iterator storeDEPRECATED.DataIterator) data.Data {
+								iteratedData := data.Data{}
+								for iterator.Next(ctx) {
+									iteratedData = append(iteratedData, iterator.Datum())
+								}
+								Expect(iterator.Error()).ToNot(HaveOccurred())
+								Expect(iterator.Close()).To(Succeed())
+								return iteratedData
+							}
+
+							BeforeEach(func() {
+								for index, datum := range dataSetData {
+									baseDatum := datum.(*types.Base)
+									baseDatum.Active = true
+									baseDatum.DeletedTime = nil
+									baseDatum.Time = pointer.FromString(time.Date(2016, 9, 1, 12, index, 0, 0, time.UTC).Format(time.RFC3339Nano))
+									baseDatum.Type = "food"
+								}
+								preparePersistedDataSetsData()
+								Expect(session.CreateDataSetData(ctx, dataSet, dataSetData)).To(Succeed())
+							})
+
+							It("iterates over all the user data in chronological order", func() {
+								iterator, err := session.IterateDataForUserByID(ctx, userID, filter, nil)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(DataSetDataIDs(iterate(iterator))).To(Equal(DataSetDataIDs(dataSetData)))
+							})
+
+							It("resumes after the cursor", func() {
+								iterator, err := session.IterateDataForUserByID(ctx, userID, filter, nil)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(iterator.Next(ctx)).To(BeTrue())
+								cursor := iterator.Cursor()
+								Expect(cursor).ToNot(BeNil())
+								Expect(iterator.Close()).To(Succeed())
+								iterator, err = session.IterateDataForUserByID(ctx, userID, filter, cursor)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(DataSetDataIDs(iterate(iterator))).To(Equal(DataSetDataIDs(dataSetData[1:])))
+							})
+						})
+					})
+
 					Context("with selected data set data", func() {
 						var selectors *data.Selectors
 						var selectedDataSetData data.Data
@@ -1551,6 +1836,240 @@ var _ = Describe("Mongo", func() {
 						})
 					})
 
+					Context("DestroyDeletedData", func() {
+						var filter *data.DeletedDataFilter
+
+						BeforeEach(func() {
+							filter = data.NewDeletedDataFilter()
+							filter.DeletedBefore = pointer.FromTime(time.Now().Add(time.Second))
+						})
+
+						It("returns an error if the filter is missing", func() {
+							result, err := session.DestroyDeletedData(ctx, nil, nil)
+							Expect(err).To(MatchError("filter is missing"))
+							Expect(result).To(BeNil())
+						})
+
+						It("returns an error if the filter is invalid", func() {
+							filter.DeletedBefore = nil
+							result, err := session.DestroyDeletedData(ctx, filter, nil)
+							Expect(err).To(MatchError("filter is invalid; value does not exist"))
+							Expect(result).To(BeNil())
+						})
+
+						It("returns an error if the session is closed", func() {
+							session.Close()
+							result, err := session.DestroyDeletedData(ctx, filter, nil)
+							Expect(err).To(MatchError("session closed"))
+							Expect(result).To(BeNil())
+						})
+
+						Context("with database access", func() {
+							BeforeEach(func() {
+								preparePersistedDataSetsData()
+								Expect(session.CreateDataSetData(ctx, dataSet, dataSetData)).To(Succeed())
+								Expect(session.DeleteDataSetData(ctx, dataSet, nil)).To(Succeed())
+							})
+
+							It("destroys no data deleted after the filter time", func() {
+								filter.DeletedBefore = pointer.FromTime(time.Now().Add(-time.Hour))
+								Expect(session.DestroyDeletedData(ctx, filter, nil)).To(Equal(&data.DeletedDataDestroyResult{}))
+								Expect(mgoCollection.Find(bson.M{"uploadId": dataSet.UploadID, "type": bson.M{"$ne": "upload"}}).Count()).To(Equal(len(dataSetData)))
+							})
+
+							It("destroys a page of the deleted data", func() {
+								pagination := page.NewPagination()
+								pagination.Size = 1
+								Expect(session.DestroyDeletedData(ctx, filter, pagination)).To(Equal(&data.DeletedDataDestroyResult{Destroyed: 1}))
+								Expect(mgoCollection.Find(bson.M{"uploadId": dataSet.UploadID, "type": bson.M{"$ne": "upload"}}).Count()).To(Equal(len(dataSetData) - 1))
+							})
+
+							It("destroys only the deleted data", func() {
+								Expect(session.DestroyDeletedData(ctx, filter, nil)).To(Equal(&data.DeletedDataDestroyResult{Destroyed: len(dataSetData)}))
+								Expect(mgoCollection.Find(bson.M{"uploadId": dataSet.UploadID, "type": bson.M{"$ne": "upload"}}).Count()).To(Equal(0))
+								Expect(mgoCollection.Find(bson.M{"uploadId": dataSet.UploadID, "type": "upload"}).Count()).To(Equal(1))
+								Expect(mgoCollection.Find(bson.M{"uploadId": dataSetExistingOne.UploadID, "type": bson.M{"$ne": "upload"}}).Count()).To(Equal(len(dataSetExistingOneData)))
+							})
+
+							It("does not mark the data set purged if it is not deleted", func() {
+								Expect(session.DestroyDeletedData(ctx, filter, nil)).To(Equal(&data.DeletedDataDestroyResult{Destroyed: len(dataSetData)}))
+								Expect(mgoCollection.Find(bson.M{"uploadId": dataSet.UploadID, "type": "upload", "_purgedTime": bson.M{"$exists": true}}).Count()).To(Equal(0))
+							})
+
+							It("marks the data set purged if it is deleted", func() {
+								Expect(session.DeleteDataSet(ctx, dataSet, false)).To(Succeed())
+								Expect(session.DestroyDeletedData(ctx, filter, nil)).To(Equal(&data.DeletedDataDestroyResult{Destroyed: len(dataSetData)}))
+								Expect(mgoCollection.Find(bson.M{"uploadId": dataSet.UploadID, "type": "upload", "_purgedTime": bson.M{"$exists": true}}).Count()).To(Equal(1))
+								Expect(session.ListDeletedDataSets(ctx, nil, nil)).To(BeEmpty())
+								purgedDataSet, err := session.GetDataSetByID(ctx, *dataSet.UploadID)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(session.RestoreDataSet(ctx, purgedDataSet)).To(MatchError("data set is purged"))
+							})
+						})
+					})
+
+					Context("GetDeviceDataHashes", func() {
+						var hashes []string
+
+						BeforeEach(func() {
+							hashes = []string{test.RandomStringFromRangeAndCharset(32, 32, test.CharsetHexidecimalLowercase)}
+							for _, dataSetDatum := range dataSetData {
+								hashes = append(hashes, *dataSetDatum.DeduplicatorDescriptor().Hash)
+							}
+						})
+
+						It("returns an error if the data set is missing", func() {
+							result, err := session.GetDeviceDataHashes(ctx, nil, hashes)
+							Expect(err).To(MatchError("data set is missing"))
+							Expect(result).To(BeNil())
+						})
+
+						It("returns an error if the user id is missing", func() {
+							dataSet.UserID = nil
+							result, err := session.GetDeviceDataHashes(ctx, dataSet, hashes)
+							Expect(err).To(MatchError("data set user id is missing"))
+							Expect(result).To(BeNil())
+						})
+
+						It("returns an error if the upload id is missing", func() {
+							dataSet.UploadID = nil
+							result, err := session.GetDeviceDataHashes(ctx, dataSet, hashes)
+							Expect(err).To(MatchError("data set upload id is missing"))
+							Expect(result).To(BeNil())
+						})
+
+						It("returns an error if the device id is missing", func() {
+							dataSet.DeviceID = nil
+							result, err := session.GetDeviceDataHashes(ctx, dataSet, hashes)
+							Expect(err).To(MatchError("data set device id is missing"))
+							Expect(result).To(BeNil())
+						})
+
+						It("returns an error if the session is closed", func() {
+							session.Close()
+							result, err := session.GetDeviceDataHashes(ctx, dataSet, hashes)
+							Expect(err).To(MatchError("session closed"))
+							Expect(result).To(BeNil())
+						})
+
+						Context("with database access", func() {
+							BeforeEach(func() {
+								preparePersistedDataSetsData()
+								Expect(session.CreateDataSetData(ctx, dataSetExistingOne, CloneDataSetData(dataSetData))).To(Succeed())
+								Expect(session.CreateDataSetData(ctx, dataSet, dataSetData)).To(Succeed())
+							})
+
+							It("returns no hashes if no hashes are given", func() {
+								Expect(session.GetDeviceDataHashes(ctx, dataSet, nil)).To(BeEmpty())
+							})
+
+							It("returns no hashes of inactive device data", func() {
+								Expect(session.GetDeviceDataHashes(ctx, dataSet, hashes)).To(BeEmpty())
+							})
+
+							It("returns only the hashes of the active device data in other data sets", func() {
+								Expect(session.ActivateDataSetData(ctx, dataSetExistingOne, nil)).To(Succeed())
+								Expect(session.ActivateDataSetData(ctx, dataSet, nil)).To(Succeed())
+								Expect(session.GetDeviceDataHashes(ctx, dataSet, hashes)).To(ConsistOf(hashes[1:]))
+							})
+						})
+					})
+
+					Context("DryRunDataSession", func() {
+						var dryRunSession storeDEPRECATED.DryRunDataSession
+						var dataSetExistingOneDataCloned data.Data
+
+						BeforeEach(func() {
+							preparePersistedDataSetsData()
+							dataSetExistingOneDataCloned = CloneDataSetData(dataSetData)
+							Expect(session.CreateDataSetData(ctx, dataSetExistingOne, dataSetExistingOneDataCloned)).To(Succeed())
+							Expect(session.ActivateDataSetData(ctx, dataSetExistingOne, nil)).To(Succeed())
+							dryRunSession = store.NewDryRunDataSession()
+							Expect(dryRunSession).ToNot(BeNil())
+						})
+
+						AfterEach(func() {
+							if dryRunSession != nil {
+								dryRunSession.Close()
+							}
+						})
+
+						countData := func(selector bson.M) int {
+							selector["type"] = bson.M{"$ne": "upload"}
+							count, err := mgoCollection.Find(selector).Count()
+							Expect(err).ToNot(HaveOccurred())
+							return count
+						}
+
+						expectedDryRunReport := func(modified data.Data, modify func(counts *storeDEPRECATED.DryRunCounts), unchanged data.Data) storeDEPRECATED.DryRunReport {
+							report := storeDEPRECATED.NewDryRunReport()
+							for _, dataSetDatum := range modified {
+								modify(report.Counts(dataSetDatum.(*types.Base).Type))
+							}
+							for _, dataSetDatum := range unchanged {
+								report.Counts(dataSetDatum.(*types.Base).Type).Unchanged++
+							}
+							return report
+						}
+
+						It("returns an error when creating a data set", func() {
+							Expect(dryRunSession.CreateDataSet(ctx, dataSet)).To(MatchError("create data set is not supported by dry run"))
+						})
+
+						It("returns an error when deleting a data set", func() {
+							Expect(dryRunSession.DeleteDataSet(ctx, dataSet, false)).To(MatchError("delete data set is not supported by dry run"))
+						})
+
+						It("returns an error if the session is closed", func() {
+							dryRunSession.Close()
+							Expect(dryRunSession.DeleteOtherDataSetData(ctx, dataSet)).To(MatchError("session closed"))
+						})
+
+						It("does not create data set data", func() {
+							Expect(dryRunSession.CreateDataSetData(ctx, dataSet, CloneDataSetData(dataSetData))).To(Succeed())
+							Expect(countData(bson.M{"uploadId": dataSet.UploadID})).To(Equal(0))
+						})
+
+						It("tallies the device data that would be deactivated using hashes from created data set data", func() {
+							Expect(dryRunSession.CreateDataSetData(ctx, dataSet, CloneDataSetData(dataSetData))).To(Succeed())
+							Expect(dryRunSession.ArchiveDeviceDataUsingHashesFromDataSet(ctx, dataSet)).To(Succeed())
+							Expect(countData(bson.M{"uploadId": dataSetExistingOne.UploadID, "_active": true})).To(Equal(len(dataSetExistingOneData) + len(dataSetExistingOneDataCloned)))
+							Expect(dryRunSession.DryRunReport(ctx, dataSet)).To(Equal(expectedDryRunReport(
+								dataSetExistingOneDataCloned,
+								func(counts *storeDEPRECATED.DryRunCounts) { counts.Deactivated++ },
+								append(append(data.Data{}, dataSetExistingOneData...), dataSetExistingTwoData...),
+							)))
+						})
+
+						It("tallies the other data set data that would be deleted", func() {
+							Expect(dryRunSession.DeleteOtherDataSetData(ctx, dataSet)).To(Succeed())
+							Expect(countData(bson.M{"uploadId": dataSetExistingOne.UploadID})).To(Equal(len(dataSetExistingOneData) + len(dataSetExistingOneDataCloned)))
+							Expect(dryRunSession.DryRunReport(ctx, dataSet)).To(Equal(expectedDryRunReport(
+								append(append(append(data.Data{}, dataSetExistingOneData...), dataSetExistingOneDataCloned...), dataSetExistingTwoData...),
+								func(counts *storeDEPRECATED.DryRunCounts) { counts.Deleted++ },
+								nil,
+							)))
+						})
+
+						It("tallies the data set data that would be archived", func() {
+							Expect(dryRunSession.ArchiveDataSetData(ctx, dataSetExistingOne, nil)).To(Succeed())
+							Expect(countData(bson.M{"uploadId": dataSetExistingOne.UploadID, "_active": true})).To(Equal(len(dataSetExistingOneData) + len(dataSetExistingOneDataCloned)))
+							Expect(dryRunSession.DryRunReport(ctx, dataSetExistingOne)).To(Equal(expectedDryRunReport(
+								append(append(data.Data{}, dataSetExistingOneData...), dataSetExistingOneDataCloned...),
+								func(counts *storeDEPRECATED.DryRunCounts) { counts.Archived++ },
+								dataSetExistingTwoData,
+							)))
+						})
+
+						It("reports all existing device data as unchanged without any tallies", func() {
+							Expect(dryRunSession.DryRunReport(ctx, dataSet)).To(Equal(expectedDryRunReport(
+								nil,
+								nil,
+								append(append(append(data.Data{}, dataSetExistingOneData...), dataSetExistingOneDataCloned...), dataSetExistingTwoData...),
+							)))
+						})
+					})
+
 					Context("UnarchiveDeviceDataUsingHashesFromDataSet", func() {
 						It("returns an error if the data set is missing", func() {
 							Expect(session.UnarchiveDeviceDataUsingHashesFromDataSet(ctx, nil)).To(MatchError("data set is missing"))
@@ -1794,6 +2313,63 @@ var _ = Describe("Mongo", func() {
 							})
 						})
 					})
+
+					Context("UpdateDataAssociations", func() {
+						var associations map[string]*association.AssociationArray
+
+						BeforeEach(func() {
+							associations = map[string]*association.AssociationArray{}
+						})
+
+						It("returns an error if the user id is missing", func() {
+							Expect(session.UpdateDataAssociations(ctx, "", associations)).To(MatchError("user id is missing"))
+						})
+
+						It("returns an error if the associations is missing", func() {
+							Expect(session.UpdateDataAssociations(ctx, *dataSet.UserID, nil)).To(MatchError("associations is missing"))
+						})
+
+						It("returns an error if the session is closed", func() {
+							session.Close()
+							Expect(session.UpdateDataAssociations(ctx, *dataSet.UserID, associations)).To(MatchError("session closed"))
+						})
+
+						Context("with database access", func() {
+							var updatedDatum *types.Base
+							var removedDatum *types.Base
+							var inactiveDatum *types.Base
+							var reason string
+
+							BeforeEach(func() {
+								preparePersistedDataSetsData()
+								Expect(session.CreateDataSetData(ctx, dataSet, dataSetData)).To(Succeed())
+								Expect(session.ActivateDataSetData(ctx, dataSet, nil)).To(Succeed())
+								updatedDatum = dataSetData[0].(*types.Base)
+								removedDatum = dataSetData[1].(*types.Base)
+								inactiveDatum = dataSetExistingOtherData[0].(*types.Base)
+								reason = test.RandomString()
+								datumAssociations := association.AssociationArray{{ID: pointer.FromString(*removedDatum.ID), Reason: pointer.FromString(reason), Type: pointer.FromString(association.TypeDatum)}}
+								associations[*updatedDatum.ID] = &datumAssociations
+								associations[*removedDatum.ID] = nil
+								associations[*inactiveDatum.ID] = &datumAssociations
+							})
+
+							It("replaces the associations of the active data", func() {
+								Expect(session.UpdateDataAssociations(ctx, *dataSet.UserID, associations)).To(Succeed())
+								Expect(mgoCollection.Find(bson.M{"id": *updatedDatum.ID, "associations": bson.M{"$size": 1}, "associations.reason": reason, "modifiedTime": bson.M{"$exists": true}}).Count()).To(Equal(1))
+							})
+
+							It("removes the associations if nil", func() {
+								Expect(session.UpdateDataAssociations(ctx, *dataSet.UserID, associations)).To(Succeed())
+								Expect(mgoCollection.Find(bson.M{"id": *removedDatum.ID, "associations": bson.M{"$exists": false}}).Count()).To(Equal(1))
+							})
+
+							It("does not update the inactive data", func() {
+								Expect(session.UpdateDataAssociations(ctx, *dataSet.UserID, associations)).To(Succeed())
+								Expect(mgoCollection.Find(bson.M{"id": *inactiveDatum.ID, "associations.reason": reason}).Count()).To(Equal(0))
+							})
+						})
+					})
 				})
 			})
 		})
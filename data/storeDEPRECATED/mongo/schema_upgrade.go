@@ -0,0 +1,271 @@
+package mongo
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	mgo "github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	dataTypes "github.com/tidepool-org/platform/data/types"
+	dataTypesSchema "github.com/tidepool-org/platform/data/types/schema"
+	"github.com/tidepool-org/platform/errors"
+	"github.com/tidepool-org/platform/log"
+	storeStructuredMongo "github.com/tidepool-org/platform/store/structured/mongo"
+)
+
+const (
+	schemaUpgradesCollection        = "deviceDataSchemaUpgrades"
+	schemaUpgradeFailuresCollection = "deviceDataSchemaUpgradeFailures"
+
+	SchemaUpgradeFailuresMaximum  = 1000
+	SchemaUpgradeProgressInterval = 1000
+)
+
+func (s *Store) NewSchemaUpgradeSession() *SchemaUpgradeSession {
+	return &SchemaUpgradeSession{
+		Session:         s.Store.NewSession("deviceData"),
+		progressSession: s.Store.NewSession(schemaUpgradesCollection),
+		failuresSession: s.Store.NewSession(schemaUpgradeFailuresCollection),
+	}
+}
+
+// SchemaUpgradeSession rewrites the stored data of the types with upgrades below the current schema version to the
+// current one, recording its progress so that an interrupted upgrade resumes where it stopped. As the upgraded data no
+// longer match the data to upgrade, only the data that failed to upgrade are recorded, each in its own document, so
+// that they are not retried on resume. The upgrade stops once the maximum number of failures is recorded, as the
+// failures then need to be investigated.
+type SchemaUpgradeSession struct {
+	*storeStructuredMongo.Session
+	progressSession *storeStructuredMongo.Session
+	failuresSession *storeStructuredMongo.Session
+}
+
+// SchemaUpgradeProgress is the progress of the upgrade to the schema version
+type SchemaUpgradeProgress struct {
+	Version       int     `json:"version" bson:"_id"`
+	Upgraded      int     `json:"upgraded" bson:"upgraded"`
+	Failed        int     `json:"failed" bson:"failed"`
+	StartedTime   string  `json:"startedTime" bson:"startedTime"`
+	UpdatedTime   string  `json:"updatedTime" bson:"updatedTime"`
+	CompletedTime *string `json:"completedTime,omitempty" bson:"completedTime,omitempty"`
+}
+
+// SchemaUpgradeFailure is a datum that failed to upgrade to the schema version
+type SchemaUpgradeFailure struct {
+	ID          interface{} `json:"id" bson:"_id"`
+	Version     int         `json:"version" bson:"version"`
+	Error       string      `json:"error" bson:"error"`
+	CreatedTime string      `json:"createdTime" bson:"createdTime"`
+}
+
+func (s *SchemaUpgradeSession) Close() error {
+	if s.failuresSession != nil {
+		s.failuresSession.Close()
+	}
+	if s.progressSession != nil {
+		s.progressSession.Close()
+	}
+	return s.Session.Close()
+}
+
+// GetProgress returns the progress of the upgrade to the current schema version, or nil if not yet started
+func (s *SchemaUpgradeSession) GetProgress(ctx context.Context) (*SchemaUpgradeProgress, error) {
+	if ctx == nil {
+		return nil, errors.New("context is missing")
+	}
+
+	if s.IsClosed() {
+		return nil, errors.New("session closed")
+	}
+
+	progress := &SchemaUpgradeProgress{}
+	if err := s.progressSession.C().FindId(dataTypes.SchemaVersionCurrent).One(progress); err == mgo.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "unable to get schema upgrade progress")
+	}
+	return progress, nil
+}
+
+// Upgrade upgrades at most the limit of stored data, if positive, otherwise all, to the current schema version,
+// resuming the recorded progress, if any, and returns the progress. If a dry run, the data are only counted, and the
+// progress is neither resumed nor recorded.
+func (s *SchemaUpgradeSession) Upgrade(ctx context.Context, limit int, dryRun bool) (*SchemaUpgradeProgress, error) {
+	if ctx == nil {
+		return nil, errors.New("context is missing")
+	}
+
+	if s.IsClosed() {
+		return nil, errors.New("session closed")
+	}
+
+	now := time.Now()
+	timestamp := now.Truncate(time.Millisecond).Format(time.RFC3339Nano)
+
+	var progress *SchemaUpgradeProgress
+	if !dryRun {
+		var err error
+		if progress, err = s.GetProgress(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if progress == nil {
+		progress = &SchemaUpgradeProgress{
+			Version:     dataTypes.SchemaVersionCurrent,
+			StartedTime: timestamp,
+		}
+	}
+
+	var failedIDs []interface{}
+	if !dryRun {
+		var err error
+		if failedIDs, err = s.failedIDs(); err != nil {
+			return nil, err
+		}
+	}
+
+	selector := bson.M{
+		"type":                             bson.M{"$in": dataTypesSchema.Types()},
+		dataTypesSchema.SchemaVersionField: bson.M{"$not": bson.M{"$gte": dataTypes.SchemaVersionCurrent}},
+	}
+	if len(failedIDs) > 0 {
+		selector["_id"] = bson.M{"$nin": failedIDs}
+	}
+
+	logger := log.LoggerFromContext(ctx)
+
+	var count int
+	var raw bson.Raw
+	iter := s.C().Find(selector).Iter()
+	for (limit <= 0 || count < limit) && len(failedIDs) < SchemaUpgradeFailuresMaximum && iter.Next(&raw) {
+		count++
+
+		id, err := s.upgradeDocument(raw, dryRun)
+		if err != nil {
+			logger.WithField("id", id).WithError(err).Warn("Unable to upgrade document")
+			if !dryRun {
+				if err = s.createFailure(id, err); err != nil {
+					iter.Close()
+					return nil, err
+				}
+			}
+			failedIDs = append(failedIDs, id)
+			progress.Failed++
+		} else {
+			progress.Upgraded++
+		}
+
+		if !dryRun && count%SchemaUpgradeProgressInterval == 0 {
+			if err = s.updateProgress(progress); err != nil {
+				iter.Close()
+				return nil, err
+			}
+		}
+	}
+	exhausted := (limit <= 0 || count < limit) && len(failedIDs) < SchemaUpgradeFailuresMaximum
+	if err := iter.Close(); err != nil {
+		return nil, errors.Wrap(err, "unable to iterate data")
+	}
+
+	if exhausted {
+		completedTime := time.Now().Truncate(time.Millisecond).Format(time.RFC3339Nano)
+		progress.CompletedTime = &completedTime
+	}
+	if !dryRun {
+		if err := s.updateProgress(progress); err != nil {
+			return nil, err
+		}
+	}
+	if len(failedIDs) >= SchemaUpgradeFailuresMaximum {
+		return progress, errors.Newf("maximum of %d failures reached", SchemaUpgradeFailuresMaximum)
+	}
+
+	loggerFields := log.Fields{"version": progress.Version, "count": count, "upgraded": progress.Upgraded, "failed": progress.Failed, "dryRun": dryRun, "duration": time.Since(now) / time.Microsecond}
+	logger.WithFields(loggerFields).Debug("Upgrade")
+
+	return progress, nil
+}
+
+// upgradeDocument upgrades the stored document, setting and unsetting only the upgraded fields, so that any concurrent
+// change of the other fields is preserved, and returns its id
+func (s *SchemaUpgradeSession) upgradeDocument(raw bson.Raw, dryRun bool) (interface{}, error) {
+	original := map[string]interface{}{}
+	if err := raw.Unmarshal(&original); err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal document")
+	}
+	id := original["_id"]
+
+	document := map[string]interface{}{}
+	if err := raw.Unmarshal(&document); err != nil {
+		return id, errors.Wrap(err, "unable to unmarshal document")
+	}
+	if _, err := dataTypesSchema.UpgradeDocument(document); err != nil {
+		return id, err
+	}
+	document[dataTypesSchema.SchemaVersionField] = dataTypes.SchemaVersionCurrent
+
+	set := bson.M{}
+	for key, value := range document {
+		if originalValue, ok := original[key]; !ok || !reflect.DeepEqual(originalValue, value) {
+			set[key] = value
+		}
+	}
+	unset := bson.M{}
+	for key := range original {
+		if _, ok := document[key]; !ok {
+			unset[key] = ""
+		}
+	}
+
+	if dryRun || (len(set) == 0 && len(unset) == 0) {
+		return id, nil
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if err := s.C().UpdateId(id, update); err != nil {
+		return id, errors.Wrap(err, "unable to update document")
+	}
+	return id, nil
+}
+
+// failedIDs returns the ids of the data that failed to upgrade to the current schema version
+func (s *SchemaUpgradeSession) failedIDs() ([]interface{}, error) {
+	var failures []*SchemaUpgradeFailure
+	if err := s.failuresSession.C().Find(bson.M{"version": dataTypes.SchemaVersionCurrent}).Select(bson.M{"_id": 1}).All(&failures); err != nil {
+		return nil, errors.Wrap(err, "unable to get schema upgrade failures")
+	}
+	ids := make([]interface{}, 0, len(failures))
+	for _, failure := range failures {
+		ids = append(ids, failure.ID)
+	}
+	return ids, nil
+}
+
+func (s *SchemaUpgradeSession) createFailure(id interface{}, failureErr error) error {
+	failure := &SchemaUpgradeFailure{
+		ID:          id,
+		Version:     dataTypes.SchemaVersionCurrent,
+		Error:       failureErr.Error(),
+		CreatedTime: time.Now().Truncate(time.Millisecond).Format(time.RFC3339Nano),
+	}
+	if _, err := s.failuresSession.C().UpsertId(id, failure); err != nil {
+		return errors.Wrap(err, "unable to create schema upgrade failure")
+	}
+	return nil
+}
+
+func (s *SchemaUpgradeSession) updateProgress(progress *SchemaUpgradeProgress) error {
+	progress.UpdatedTime = time.Now().Truncate(time.Millisecond).Format(time.RFC3339Nano)
+	if _, err := s.progressSession.C().UpsertId(progress.Version, progress); err != nil {
+		return errors.Wrap(err, "unable to update schema upgrade progress")
+	}
+	return nil
+}
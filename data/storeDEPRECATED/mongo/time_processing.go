@@ -0,0 +1,193 @@
+package mongo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	mgo "github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"github.com/tidepool-org/platform/data"
+	"github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/data/types/upload"
+	"github.com/tidepool-org/platform/errors"
+	"github.com/tidepool-org/platform/log"
+)
+
+const timeProcessingBatchesCollection = "deviceDataTimeProcessingBatches"
+
+// timeProcessingBatch is the stored batch, whose raw data is stored as sent, in JSON, so that it is parsed as if just
+// sent once processed
+type timeProcessingBatch struct {
+	ID          string    `bson:"id"`
+	UserID      string    `bson:"userId"`
+	DataSetID   string    `bson:"uploadId"`
+	RawData     string    `bson:"rawData"`
+	Processed   bool      `bson:"processed"`
+	CreatedTime time.Time `bson:"createdTime"`
+}
+
+func (d *DataSession) CreateTimeProcessingBatch(ctx context.Context, dataSet *upload.Upload, rawData []interface{}) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if err := validateDataSet(dataSet); err != nil {
+		return err
+	}
+	if rawData == nil {
+		return errors.New("raw data is missing")
+	}
+
+	if d.IsClosed() {
+		return errors.New("session closed")
+	}
+
+	now := time.Now()
+
+	bytes, err := json.Marshal(rawData)
+	if err != nil {
+		return errors.Wrap(err, "unable to encode raw data")
+	}
+
+	batch := &timeProcessingBatch{
+		ID:          data.NewID(),
+		UserID:      *dataSet.UserID,
+		DataSetID:   *dataSet.UploadID,
+		RawData:     string(bytes),
+		CreatedTime: now,
+	}
+	err = d.timeProcessingBatchesSession.C().Insert(batch)
+
+	loggerFields := log.Fields{"dataSetId": *dataSet.UploadID, "id": batch.ID, "count": len(rawData), "duration": time.Since(now) / time.Microsecond}
+	log.LoggerFromContext(ctx).WithFields(loggerFields).WithError(err).Debug("CreateTimeProcessingBatch")
+
+	if err != nil {
+		return errors.Wrap(err, "unable to create time processing batch")
+	}
+	return nil
+}
+
+// ListTimeProcessingBatchIDs returns the ids of the time processing batches of the data set, in the order created
+func (d *DataSession) ListTimeProcessingBatchIDs(ctx context.Context, dataSet *upload.Upload) ([]string, error) {
+	if ctx == nil {
+		return nil, errors.New("context is missing")
+	}
+	if err := validateDataSet(dataSet); err != nil {
+		return nil, err
+	}
+
+	if d.IsClosed() {
+		return nil, errors.New("session closed")
+	}
+
+	now := time.Now()
+
+	var batches []struct {
+		ID string `bson:"id"`
+	}
+	err := d.timeProcessingBatchesSession.C().Find(bson.M{"uploadId": *dataSet.UploadID}).Select(bson.M{"id": 1}).Sort("createdTime", "_id").All(&batches)
+
+	loggerFields := log.Fields{"dataSetId": *dataSet.UploadID, "count": len(batches), "duration": time.Since(now) / time.Microsecond}
+	log.LoggerFromContext(ctx).WithFields(loggerFields).WithError(err).Debug("ListTimeProcessingBatchIDs")
+
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list time processing batch ids")
+	}
+
+	ids := make([]string, 0, len(batches))
+	for _, batch := range batches {
+		ids = append(ids, batch.ID)
+	}
+	return ids, nil
+}
+
+func (d *DataSession) GetTimeProcessingBatch(ctx context.Context, id string) (*storeDEPRECATED.TimeProcessingBatch, error) {
+	if ctx == nil {
+		return nil, errors.New("context is missing")
+	}
+	if id == "" {
+		return nil, errors.New("id is missing")
+	}
+
+	if d.IsClosed() {
+		return nil, errors.New("session closed")
+	}
+
+	now := time.Now()
+
+	batch := &timeProcessingBatch{}
+	err := d.timeProcessingBatchesSession.C().Find(bson.M{"id": id}).One(batch)
+
+	loggerFields := log.Fields{"id": id, "duration": time.Since(now) / time.Microsecond}
+	log.LoggerFromContext(ctx).WithFields(loggerFields).WithError(err).Debug("GetTimeProcessingBatch")
+
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "unable to get time processing batch")
+	}
+
+	var rawData []interface{}
+	if err = json.Unmarshal([]byte(batch.RawData), &rawData); err != nil {
+		return nil, errors.Wrap(err, "unable to decode raw data")
+	}
+
+	return &storeDEPRECATED.TimeProcessingBatch{
+		ID:          batch.ID,
+		DataSetID:   batch.DataSetID,
+		RawData:     rawData,
+		Processed:   batch.Processed,
+		CreatedTime: batch.CreatedTime,
+	}, nil
+}
+
+func (d *DataSession) SetTimeProcessingBatchProcessed(ctx context.Context, id string) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if id == "" {
+		return errors.New("id is missing")
+	}
+
+	if d.IsClosed() {
+		return errors.New("session closed")
+	}
+
+	now := time.Now()
+
+	err := d.timeProcessingBatchesSession.C().Update(bson.M{"id": id}, bson.M{"$set": bson.M{"processed": true}})
+
+	loggerFields := log.Fields{"id": id, "duration": time.Since(now) / time.Microsecond}
+	log.LoggerFromContext(ctx).WithFields(loggerFields).WithError(err).Debug("SetTimeProcessingBatchProcessed")
+
+	if err != nil {
+		return errors.Wrap(err, "unable to set time processing batch processed")
+	}
+	return nil
+}
+
+func (d *DataSession) DestroyTimeProcessingBatches(ctx context.Context, dataSet *upload.Upload) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if err := validateDataSet(dataSet); err != nil {
+		return err
+	}
+
+	if d.IsClosed() {
+		return errors.New("session closed")
+	}
+
+	now := time.Now()
+
+	removeInfo, err := d.timeProcessingBatchesSession.C().RemoveAll(bson.M{"uploadId": *dataSet.UploadID})
+
+	loggerFields := log.Fields{"dataSetId": *dataSet.UploadID, "removeInfo": removeInfo, "duration": time.Since(now) / time.Microsecond}
+	log.LoggerFromContext(ctx).WithFields(loggerFields).WithError(err).Debug("DestroyTimeProcessingBatches")
+
+	if err != nil {
+		return errors.Wrap(err, "unable to destroy time processing batches")
+	}
+	return nil
+}
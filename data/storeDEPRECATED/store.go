@@ -2,18 +2,30 @@ package storeDEPRECATED
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"io"
+	"net/http"
+	"strings"
+	"time"
 
+	"github.com/tidepool-org/platform/association"
 	"github.com/tidepool-org/platform/data"
+	"github.com/tidepool-org/platform/data/summary"
 	"github.com/tidepool-org/platform/data/types/upload"
+	"github.com/tidepool-org/platform/errors"
+	"github.com/tidepool-org/platform/origin"
 	"github.com/tidepool-org/platform/page"
+	"github.com/tidepool-org/platform/request"
 	"github.com/tidepool-org/platform/structure"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
 )
 
 type Store interface {
 	Status() interface{}
 
 	NewDataSession() DataSession
+	NewDryRunDataSession() DryRunDataSession
 }
 
 type DataSession interface {
@@ -24,21 +36,123 @@ type DataSession interface {
 	CreateDataSet(ctx context.Context, dataSet *upload.Upload) error
 	UpdateDataSet(ctx context.Context, id string, update *data.DataSetUpdate) (*upload.Upload, error)
 	DeleteDataSet(ctx context.Context, dataSet *upload.Upload, doPurge bool) error
+	RestoreDataSet(ctx context.Context, dataSet *upload.Upload) error
 
 	CreateDataSetData(ctx context.Context, dataSet *upload.Upload, dataSetData []data.Datum) error
 	ActivateDataSetData(ctx context.Context, dataSet *upload.Upload, selectors *data.Selectors) error
 	ArchiveDataSetData(ctx context.Context, dataSet *upload.Upload, selectors *data.Selectors) error
 	DeleteDataSetData(ctx context.Context, dataSet *upload.Upload, selectors *data.Selectors) error
 	DestroyDeletedDataSetData(ctx context.Context, dataSet *upload.Upload, selectors *data.Selectors) error
+	DestroyDeletedData(ctx context.Context, filter *data.DeletedDataFilter, pagination *page.Pagination) (*data.DeletedDataDestroyResult, error)
 	DestroyDataSetData(ctx context.Context, dataSet *upload.Upload, selectors *data.Selectors) error
 
 	ArchiveDeviceDataUsingHashesFromDataSet(ctx context.Context, dataSet *upload.Upload) error
 	UnarchiveDeviceDataUsingHashesFromDataSet(ctx context.Context, dataSet *upload.Upload) error
+	ArchiveDataSetDataUsingHashesFromDevice(ctx context.Context, dataSet *upload.Upload) error
+	GetDeviceDataHashes(ctx context.Context, dataSet *upload.Upload, hashes []string) ([]string, error)
 	DeleteOtherDataSetData(ctx context.Context, dataSet *upload.Upload) error
+	GetDataSetDataSamples(ctx context.Context, dataSet *upload.Upload) ([]*DataSample, error)
+	GetDeviceDataSamples(ctx context.Context, dataSet *upload.Upload, types []string, startTime time.Time, endTime time.Time) ([]*DataSample, error)
+	ArchiveDeviceDataByIDs(ctx context.Context, dataSet *upload.Upload, ids []string) error
+	UnarchiveDeviceDataArchivedByDataSet(ctx context.Context, dataSet *upload.Upload) error
 	DestroyDataForUserByID(ctx context.Context, userID string) error
+	UpdateDataAssociations(ctx context.Context, userID string, associations map[string]*association.AssociationArray) error
 
 	ListUserDataSets(ctx context.Context, userID string, filter *data.DataSetFilter, pagination *page.Pagination) (data.DataSets, error)
+	ListDeletedDataSets(ctx context.Context, filter *data.DeletedDataSetFilter, pagination *page.Pagination) (data.DataSets, error)
 	GetDataSet(ctx context.Context, id string) (*data.DataSet, error)
+
+	GetDataForUserByID(ctx context.Context, userID string, filter *DataFilter, pagination *page.Pagination) (data.Data, error)
+	IterateDataForUserByID(ctx context.Context, userID string, filter *DataFilter, cursor *DataCursor) (DataIterator, error)
+
+	GetDailySummaries(ctx context.Context, userID string, filter *summary.DailySummaryFilter) ([]*summary.DailySummary, error)
+	CalculateDailySummaries(ctx context.Context, userID string, startDate string, endDate string) ([]*summary.DailySummary, error)
+	RebuildDailySummaries(ctx context.Context, userID string, startDate string, endDate string) error
+	RebuildPendingDailySummaries(ctx context.Context, filter *summary.DailySummaryRebuildFilter, pagination *page.Pagination) (*summary.DailySummaryRebuildResult, error)
+
+	CreateTimeProcessingBatch(ctx context.Context, dataSet *upload.Upload, rawData []interface{}) error
+	ListTimeProcessingBatchIDs(ctx context.Context, dataSet *upload.Upload) ([]string, error)
+	GetTimeProcessingBatch(ctx context.Context, id string) (*TimeProcessingBatch, error)
+	SetTimeProcessingBatchProcessed(ctx context.Context, id string) error
+	DestroyTimeProcessingBatches(ctx context.Context, dataSet *upload.Upload) error
+}
+
+// TimeProcessingBatch is a batch of the raw data of a data set, as sent, whose time processing is deferred until the
+// data set is closed. A batch is processed once its data is added to the data set, but is kept until all the batches
+// are processed, as its time changes apply to the data of the previous batches.
+type TimeProcessingBatch struct {
+	ID          string        `json:"id"`
+	DataSetID   string        `json:"uploadId"`
+	RawData     []interface{} `json:"rawData"`
+	Processed   bool          `json:"processed"`
+	CreatedTime time.Time     `json:"createdTime"`
+}
+
+// DataSample holds the few fields of a stored datum needed to compare it with other data
+type DataSample struct {
+	ID          string         `bson:"id"`
+	Type        string         `bson:"type"`
+	Time        string         `bson:"time"`
+	Value       *float64       `bson:"value,omitempty"`
+	Origin      *origin.Origin `bson:"origin,omitempty"`
+	UploadID    string         `bson:"uploadId"`
+	CreatedTime string         `bson:"createdTime"`
+}
+
+// OriginType returns the origin type of the datum, if any
+func (d *DataSample) OriginType() string {
+	if d.Origin == nil || d.Origin.Type == nil {
+		return ""
+	}
+	return *d.Origin.Type
+}
+
+// DryRunDataSession is a DataSession that never modifies existing data, but instead tallies the existing data that each
+// operation would have archived, deactivated or deleted
+type DryRunDataSession interface {
+	DataSession
+
+	DryRunReport(ctx context.Context, dataSet *upload.Upload) (DryRunReport, error)
+}
+
+// DryRunReport counts, by datum type, the existing data a dry run would have modified
+type DryRunReport map[string]*DryRunCounts
+
+// DryRunCounts counts the existing data of one datum type by the outcome a dry run would have applied
+type DryRunCounts struct {
+	Archived    int `json:"archived"`
+	Deactivated int `json:"deactivated"`
+	Deleted     int `json:"deleted"`
+	Unchanged   int `json:"unchanged"`
+}
+
+func NewDryRunReport() DryRunReport {
+	return DryRunReport{}
+}
+
+// Counts returns the counts for the datum type, adding them if not already present
+func (d DryRunReport) Counts(typ string) *DryRunCounts {
+	counts, ok := d[typ]
+	if !ok {
+		counts = &DryRunCounts{}
+		d[typ] = counts
+	}
+	return counts
+}
+
+// Modified returns the total count of data that would have been archived, deactivated or deleted
+func (d *DryRunCounts) Modified() int {
+	return d.Archived + d.Deactivated + d.Deleted
+}
+
+// DataIterator walks through the data of a user in chronological order, one datum at a time
+type DataIterator interface {
+	io.Closer
+
+	Next(ctx context.Context) bool
+	Datum() data.Datum
+	Cursor() *DataCursor
+	Error() error
 }
 
 // Filter available on HTTP query
@@ -75,3 +189,119 @@ func (f *Filter) Validate(validator structure.Validator) {
 		validator.String("dataSetType", f.DataSetType).OneOf(upload.DataSetTypes()...)
 	}
 }
+
+// DataFilter available on HTTP query when reading device data
+type DataFilter struct {
+	StartDate *time.Time
+	EndDate   *time.Time
+	Type      *[]string
+	SubType   *[]string
+	DeviceID  *string
+	UploadID  *string
+}
+
+// NewDataFilter for HTTP query URL
+func NewDataFilter() *DataFilter {
+	return &DataFilter{}
+}
+
+// Parse HTTP query URL parameters
+func (d *DataFilter) Parse(parser structure.ObjectParser) {
+	d.StartDate = parser.Time("startDate", time.RFC3339Nano)
+	d.EndDate = parser.Time("endDate", time.RFC3339Nano)
+	d.Type = parser.StringArray("type")
+	d.SubType = parser.StringArray("subType")
+	d.DeviceID = parser.String("deviceId")
+	d.UploadID = parser.String("uploadId")
+}
+
+// Validate HTTP query URL parameters
+func (d *DataFilter) Validate(validator structure.Validator) {
+	if d.StartDate != nil && d.EndDate != nil {
+		validator.Time("endDate", d.EndDate).After(*d.StartDate)
+	}
+	validator.StringArray("type", d.Type).NotEmpty().EachNotEmpty().EachUnique()
+	validator.StringArray("subType", d.SubType).NotEmpty().EachNotEmpty().EachUnique()
+	validator.String("deviceId", d.DeviceID).NotEmpty()
+	validator.String("uploadId", d.UploadID).Using(data.SetIDValidator)
+}
+
+// MutateRequest adds the filter to the HTTP query URL
+func (d *DataFilter) MutateRequest(req *http.Request) error {
+	parameters := map[string]string{}
+	if d.StartDate != nil {
+		parameters["startDate"] = d.StartDate.Format(time.RFC3339Nano)
+	}
+	if d.EndDate != nil {
+		parameters["endDate"] = d.EndDate.Format(time.RFC3339Nano)
+	}
+	if d.Type != nil {
+		parameters["type"] = strings.Join(*d.Type, ",")
+	}
+	if d.SubType != nil {
+		parameters["subType"] = strings.Join(*d.SubType, ",")
+	}
+	if d.DeviceID != nil {
+		parameters["deviceId"] = *d.DeviceID
+	}
+	if d.UploadID != nil {
+		parameters["uploadId"] = *d.UploadID
+	}
+	return request.NewParametersMutator(parameters).MutateRequest(req)
+}
+
+// DataCursor is the position of a datum in the chronological order of a user data,
+// used to resume an export right after the last datum received
+type DataCursor struct {
+	Time string `json:"time"`
+	ID   string `json:"id"`
+}
+
+// NewDataCursor for the datum with the given time and id
+func NewDataCursor(time string, id string) *DataCursor {
+	return &DataCursor{
+		Time: time,
+		ID:   id,
+	}
+}
+
+// ParseDataCursor from an opaque cursor token
+func ParseDataCursor(token string) (*DataCursor, error) {
+	bites, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("cursor is invalid")
+	}
+
+	cursor := &DataCursor{}
+	if err = json.Unmarshal(bites, cursor); err != nil || cursor.Time == "" || cursor.ID == "" {
+		return nil, errors.New("cursor is invalid")
+	}
+
+	return cursor, nil
+}
+
+// Token returns the opaque cursor token
+func (d *DataCursor) Token() string {
+	bites, _ := json.Marshal(d)
+	return base64.RawURLEncoding.EncodeToString(bites)
+}
+
+// Parse HTTP query URL parameters
+func (d *DataCursor) Parse(parser structure.ObjectParser) {
+	if token := parser.String("cursor"); token != nil {
+		if cursor, err := ParseDataCursor(*token); err != nil {
+			parser.WithReferenceErrorReporter("cursor").ReportError(ErrorValueStringAsCursorNotValid(*token))
+		} else {
+			*d = *cursor
+		}
+	}
+}
+
+// MutateRequest adds the cursor to the HTTP query URL
+func (d *DataCursor) MutateRequest(req *http.Request) error {
+	return request.NewParameterMutator("cursor", d.Token()).MutateRequest(req)
+}
+
+func ErrorValueStringAsCursorNotValid(value string) error {
+	return errors.Preparedf(structureValidator.ErrorCodeValueNotValid, "value is not valid", "value %q is not valid as cursor", value)
+}
@@ -1,8 +1,11 @@
 package storeDEPRECATED_test
 
 import (
+	"time"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
 
 	"github.com/tidepool-org/platform/data/storeDEPRECATED"
 	errorsTest "github.com/tidepool-org/platform/errors/test"
@@ -196,4 +199,213 @@ var _ = Describe("Store", func() {
 			})
 		})
 	})
+
+	Context("DataFilter", func() {
+		Context("NewDataFilter", func() {
+			It("successfully returns a new data filter", func() {
+				Expect(storeDEPRECATED.NewDataFilter()).ToNot(BeNil())
+			})
+		})
+
+		Context("with a new data filter", func() {
+			var filter *storeDEPRECATED.DataFilter
+
+			BeforeEach(func() {
+				filter = storeDEPRECATED.NewDataFilter()
+				Expect(filter).ToNot(BeNil())
+			})
+
+			It("use expected defaults", func() {
+				Expect(filter.StartDate).To(BeNil())
+				Expect(filter.EndDate).To(BeNil())
+				Expect(filter.Type).To(BeNil())
+				Expect(filter.SubType).To(BeNil())
+				Expect(filter.DeviceID).To(BeNil())
+				Expect(filter.UploadID).To(BeNil())
+			})
+
+			Context("Parse", func() {
+				It("parses missing values", func() {
+					object := map[string]interface{}{}
+					parser := structureParser.NewObject(&object)
+					filter.Parse(parser)
+					Expect(filter).To(Equal(storeDEPRECATED.NewDataFilter()))
+					Expect(parser.Error()).ToNot(HaveOccurred())
+				})
+
+				It("parses all values", func() {
+					object := map[string]interface{}{
+						"startDate": "2020-01-01T00:00:00Z",
+						"endDate":   "2020-01-02T00:00:00.5Z",
+						"type":      []interface{}{"cbg", "smbg"},
+						"subType":   []interface{}{"normal"},
+						"deviceId":  "device",
+						"uploadId":  "0123456789abcdef0123456789abcdef",
+					}
+					parser := structureParser.NewObject(&object)
+					filter.Parse(parser)
+					Expect(parser.Error()).ToNot(HaveOccurred())
+					Expect(filter.StartDate).To(PointTo(Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))))
+					Expect(filter.EndDate).To(PointTo(Equal(time.Date(2020, 1, 2, 0, 0, 0, 500000000, time.UTC))))
+					Expect(filter.Type).To(PointTo(Equal([]string{"cbg", "smbg"})))
+					Expect(filter.SubType).To(PointTo(Equal([]string{"normal"})))
+					Expect(filter.DeviceID).To(PointTo(Equal("device")))
+					Expect(filter.UploadID).To(PointTo(Equal("0123456789abcdef0123456789abcdef")))
+				})
+
+				It("reports an error if start date is not parsable", func() {
+					object := map[string]interface{}{"startDate": "invalid"}
+					parser := structureParser.NewObject(&object)
+					filter.Parse(parser)
+					Expect(filter.StartDate).To(BeNil())
+					Expect(parser.Error()).To(HaveOccurred())
+				})
+
+				It("reports an error if device id is not a string", func() {
+					object := map[string]interface{}{"deviceId": true}
+					parser := structureParser.NewObject(&object)
+					filter.Parse(parser)
+					Expect(filter.DeviceID).To(BeNil())
+					Expect(parser.Error()).To(HaveOccurred())
+				})
+			})
+
+			Context("Validate", func() {
+				var validator *structureValidator.Validator
+
+				BeforeEach(func() {
+					validator = structureValidator.New()
+				})
+
+				It("succeeds with default", func() {
+					filter.Validate(validator)
+					Expect(validator.Error()).ToNot(HaveOccurred())
+				})
+
+				It("succeeds with end date after start date", func() {
+					filter.StartDate = pointer.FromTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+					filter.EndDate = pointer.FromTime(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC))
+					filter.Validate(validator)
+					Expect(validator.Error()).ToNot(HaveOccurred())
+				})
+
+				It("fails with end date before start date", func() {
+					filter.StartDate = pointer.FromTime(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC))
+					filter.EndDate = pointer.FromTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+					filter.Validate(validator)
+					Expect(validator.Error()).To(HaveOccurred())
+				})
+
+				It("fails with type empty", func() {
+					filter.Type = pointer.FromStringArray([]string{})
+					filter.Validate(validator)
+					Expect(validator.Error()).To(HaveOccurred())
+				})
+
+				It("fails with type duplicated", func() {
+					filter.Type = pointer.FromStringArray([]string{"cbg", "cbg"})
+					filter.Validate(validator)
+					Expect(validator.Error()).To(HaveOccurred())
+				})
+
+				It("fails with sub type element empty", func() {
+					filter.SubType = pointer.FromStringArray([]string{""})
+					filter.Validate(validator)
+					Expect(validator.Error()).To(HaveOccurred())
+				})
+
+				It("fails with device id empty", func() {
+					filter.DeviceID = pointer.FromString("")
+					filter.Validate(validator)
+					Expect(validator.Error()).To(HaveOccurred())
+				})
+
+				It("fails with upload id invalid", func() {
+					filter.UploadID = pointer.FromString("invalid")
+					filter.Validate(validator)
+					Expect(validator.Error()).To(HaveOccurred())
+				})
+			})
+		})
+	})
+
+	Context("DataCursor", func() {
+		It("returns a new data cursor", func() {
+			cursor := storeDEPRECATED.NewDataCursor("2020-01-01T00:00:00Z", "id")
+			Expect(cursor).ToNot(BeNil())
+			Expect(cursor.Time).To(Equal("2020-01-01T00:00:00Z"))
+			Expect(cursor.ID).To(Equal("id"))
+		})
+
+		It("parses the token of a data cursor", func() {
+			cursor := storeDEPRECATED.NewDataCursor("2020-01-01T00:00:00Z", "id")
+			Expect(storeDEPRECATED.ParseDataCursor(cursor.Token())).To(Equal(cursor))
+		})
+
+		It("returns an error if the token is not base64", func() {
+			cursor, err := storeDEPRECATED.ParseDataCursor("!")
+			Expect(err).To(MatchError("cursor is invalid"))
+			Expect(cursor).To(BeNil())
+		})
+
+		It("returns an error if the token is incomplete", func() {
+			cursor, err := storeDEPRECATED.ParseDataCursor(storeDEPRECATED.NewDataCursor("", "id").Token())
+			Expect(err).To(MatchError("cursor is invalid"))
+			Expect(cursor).To(BeNil())
+		})
+
+		Context("Parse", func() {
+			It("parses cursor missing", func() {
+				cursor := &storeDEPRECATED.DataCursor{}
+				object := map[string]interface{}{}
+				parser := structureParser.NewObject(&object)
+				cursor.Parse(parser)
+				Expect(cursor.ID).To(BeEmpty())
+				Expect(parser.Error()).ToNot(HaveOccurred())
+			})
+
+			It("parses cursor", func() {
+				cursor := &storeDEPRECATED.DataCursor{}
+				object := map[string]interface{}{"cursor": storeDEPRECATED.NewDataCursor("2020-01-01T00:00:00Z", "id").Token()}
+				parser := structureParser.NewObject(&object)
+				cursor.Parse(parser)
+				Expect(cursor).To(Equal(storeDEPRECATED.NewDataCursor("2020-01-01T00:00:00Z", "id")))
+				Expect(parser.Error()).ToNot(HaveOccurred())
+			})
+
+			It("reports an error if cursor is invalid", func() {
+				cursor := &storeDEPRECATED.DataCursor{}
+				object := map[string]interface{}{"cursor": "invalid"}
+				parser := structureParser.NewObject(&object)
+				cursor.Parse(parser)
+				Expect(cursor.ID).To(BeEmpty())
+				Expect(parser.Error()).To(HaveOccurred())
+			})
+		})
+	})
+
+	Context("DryRunReport", func() {
+		It("successfully returns a new dry run report", func() {
+			Expect(storeDEPRECATED.NewDryRunReport()).To(BeEmpty())
+		})
+
+		It("adds counts for a datum type not already present", func() {
+			report := storeDEPRECATED.NewDryRunReport()
+			counts := report.Counts("cbg")
+			Expect(counts).To(Equal(&storeDEPRECATED.DryRunCounts{}))
+			Expect(report).To(HaveKeyWithValue("cbg", counts))
+		})
+
+		It("returns the counts for a datum type already present", func() {
+			report := storeDEPRECATED.NewDryRunReport()
+			report.Counts("cbg").Archived = 1
+			Expect(report.Counts("cbg")).To(Equal(&storeDEPRECATED.DryRunCounts{Archived: 1}))
+			Expect(report).To(HaveLen(1))
+		})
+
+		It("returns the modified count", func() {
+			counts := &storeDEPRECATED.DryRunCounts{Archived: 1, Deactivated: 2, Deleted: 3, Unchanged: 4}
+			Expect(counts.Modified()).To(Equal(6))
+		})
+	})
 })
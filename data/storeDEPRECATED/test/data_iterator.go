@@ -0,0 +1,56 @@
+package test
+
+import (
+	"context"
+
+	"github.com/tidepool-org/platform/data"
+	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/test"
+)
+
+// DataIterator iterates over the data, then reports the error, if any
+type DataIterator struct {
+	*test.Closer
+	Data            data.Data
+	ErrorOutput     error
+	NextInvocations int
+	index           int
+}
+
+func NewDataIterator(datumArray data.Data, err error) *DataIterator {
+	var closeOutput error
+	closer := test.NewCloser()
+	closer.CloseOutput = &closeOutput
+	return &DataIterator{
+		Closer:      closer,
+		Data:        datumArray,
+		ErrorOutput: err,
+		index:       -1,
+	}
+}
+
+func (d *DataIterator) Next(ctx context.Context) bool {
+	d.NextInvocations++
+	if d.index < len(d.Data) {
+		d.index++
+	}
+	return d.index < len(d.Data)
+}
+
+func (d *DataIterator) Datum() data.Datum {
+	if d.index < 0 || d.index >= len(d.Data) {
+		return nil
+	}
+	return d.Data[d.index]
+}
+
+func (d *DataIterator) Cursor() *dataStoreDEPRECATED.DataCursor {
+	return nil
+}
+
+func (d *DataIterator) Error() error {
+	if d.index < len(d.Data) {
+		return nil
+	}
+	return d.ErrorOutput
+}
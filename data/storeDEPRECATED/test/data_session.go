@@ -2,11 +2,14 @@ package test
 
 import (
 	"context"
+	"time"
 
 	"github.com/onsi/gomega"
 
+	"github.com/tidepool-org/platform/association"
 	"github.com/tidepool-org/platform/data"
 	dataStoreDEPRECATED "github.com/tidepool-org/platform/data/storeDEPRECATED"
+	"github.com/tidepool-org/platform/data/summary"
 	"github.com/tidepool-org/platform/data/types/upload"
 	"github.com/tidepool-org/platform/page"
 	"github.com/tidepool-org/platform/test"
@@ -56,6 +59,11 @@ type DeleteDataSetInput struct {
 	doPurge bool
 }
 
+type RestoreDataSetInput struct {
+	Context context.Context
+	DataSet *upload.Upload
+}
+
 type CreateDataSetDataInput struct {
 	Context     context.Context
 	DataSet     *upload.Upload
@@ -86,6 +94,17 @@ type DestroyDeletedDataSetDataInput struct {
 	Selectors *data.Selectors
 }
 
+type DestroyDeletedDataInput struct {
+	Context    context.Context
+	Filter     *data.DeletedDataFilter
+	Pagination *page.Pagination
+}
+
+type DestroyDeletedDataOutput struct {
+	Result *data.DeletedDataDestroyResult
+	Error  error
+}
+
 type DestroyDataSetDataInput struct {
 	Context   context.Context
 	DataSet   *upload.Upload
@@ -102,6 +121,56 @@ type UnarchiveDeviceDataUsingHashesFromDataSetInput struct {
 	DataSet *upload.Upload
 }
 
+type ArchiveDataSetDataUsingHashesFromDeviceInput struct {
+	Context context.Context
+	DataSet *upload.Upload
+}
+
+type GetDeviceDataHashesInput struct {
+	Context context.Context
+	DataSet *upload.Upload
+	Hashes  []string
+}
+
+type GetDeviceDataHashesOutput struct {
+	Hashes []string
+	Error  error
+}
+
+type GetDataSetDataSamplesInput struct {
+	Context context.Context
+	DataSet *upload.Upload
+}
+
+type GetDataSetDataSamplesOutput struct {
+	Samples []*dataStoreDEPRECATED.DataSample
+	Error   error
+}
+
+type GetDeviceDataSamplesInput struct {
+	Context   context.Context
+	DataSet   *upload.Upload
+	Types     []string
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+type GetDeviceDataSamplesOutput struct {
+	Samples []*dataStoreDEPRECATED.DataSample
+	Error   error
+}
+
+type ArchiveDeviceDataByIDsInput struct {
+	Context context.Context
+	DataSet *upload.Upload
+	IDs     []string
+}
+
+type UnarchiveDeviceDataArchivedByDataSetInput struct {
+	Context context.Context
+	DataSet *upload.Upload
+}
+
 type DeleteOtherDataSetDataInput struct {
 	Context context.Context
 	DataSet *upload.Upload
@@ -112,6 +181,12 @@ type DestroyDataForUserByIDInput struct {
 	UserID  string
 }
 
+type UpdateDataAssociationsInput struct {
+	Context      context.Context
+	UserID       string
+	Associations map[string]*association.AssociationArray
+}
+
 type GetDataSetInput struct {
 	Context context.Context
 	ID      string
@@ -134,6 +209,118 @@ type ListUserDataSetsOutput struct {
 	Error    error
 }
 
+type ListDeletedDataSetsInput struct {
+	Context    context.Context
+	Filter     *data.DeletedDataSetFilter
+	Pagination *page.Pagination
+}
+
+type ListDeletedDataSetsOutput struct {
+	DataSets data.DataSets
+	Error    error
+}
+
+type GetDataForUserByIDInput struct {
+	Context    context.Context
+	UserID     string
+	Filter     *dataStoreDEPRECATED.DataFilter
+	Pagination *page.Pagination
+}
+
+type GetDataForUserByIDOutput struct {
+	Data  data.Data
+	Error error
+}
+
+type IterateDataForUserByIDInput struct {
+	Context context.Context
+	UserID  string
+	Filter  *dataStoreDEPRECATED.DataFilter
+	Cursor  *dataStoreDEPRECATED.DataCursor
+}
+
+type IterateDataForUserByIDOutput struct {
+	DataIterator dataStoreDEPRECATED.DataIterator
+	Error        error
+}
+
+type GetDailySummariesInput struct {
+	Context context.Context
+	UserID  string
+	Filter  *summary.DailySummaryFilter
+}
+
+type GetDailySummariesOutput struct {
+	DailySummaries []*summary.DailySummary
+	Error          error
+}
+
+type CalculateDailySummariesInput struct {
+	Context   context.Context
+	UserID    string
+	StartDate string
+	EndDate   string
+}
+
+type CalculateDailySummariesOutput struct {
+	DailySummaries []*summary.DailySummary
+	Error          error
+}
+
+type RebuildDailySummariesInput struct {
+	Context   context.Context
+	UserID    string
+	StartDate string
+	EndDate   string
+}
+
+type RebuildPendingDailySummariesInput struct {
+	Context    context.Context
+	Filter     *summary.DailySummaryRebuildFilter
+	Pagination *page.Pagination
+}
+
+type RebuildPendingDailySummariesOutput struct {
+	Result *summary.DailySummaryRebuildResult
+	Error  error
+}
+
+type CreateTimeProcessingBatchInput struct {
+	Context context.Context
+	DataSet *upload.Upload
+	RawData []interface{}
+}
+
+type ListTimeProcessingBatchIDsInput struct {
+	Context context.Context
+	DataSet *upload.Upload
+}
+
+type ListTimeProcessingBatchIDsOutput struct {
+	IDs   []string
+	Error error
+}
+
+type GetTimeProcessingBatchInput struct {
+	Context context.Context
+	ID      string
+}
+
+type GetTimeProcessingBatchOutput struct {
+	Batch *dataStoreDEPRECATED.TimeProcessingBatch
+	Error error
+}
+
+type SetTimeProcessingBatchProcessedInput struct {
+	Context context.Context
+	ID      string
+}
+
+type DestroyTimeProcessingBatchesInput struct {
+	Context context.Context
+	DataSet *upload.Upload
+}
+
 type DataSession struct {
 	*test.Closer
 	GetDataSetsForUserByIDInvocations                    int
@@ -151,6 +338,9 @@ type DataSession struct {
 	DeleteDataSetInvocations                             int
 	DeleteDataSetInputs                                  []DeleteDataSetInput
 	DeleteDataSetOutputs                                 []error
+	RestoreDataSetInvocations                            int
+	RestoreDataSetInputs                                 []RestoreDataSetInput
+	RestoreDataSetOutputs                                []error
 	CreateDataSetDataInvocations                         int
 	CreateDataSetDataInputs                              []CreateDataSetDataInput
 	CreateDataSetDataOutputs                             []error
@@ -166,6 +356,9 @@ type DataSession struct {
 	DestroyDeletedDataSetDataInvocations                 int
 	DestroyDeletedDataSetDataInputs                      []DestroyDeletedDataSetDataInput
 	DestroyDeletedDataSetDataOutputs                     []error
+	DestroyDeletedDataInvocations                        int
+	DestroyDeletedDataInputs                             []DestroyDeletedDataInput
+	DestroyDeletedDataOutputs                            []DestroyDeletedDataOutput
 	DestroyDataSetDataInvocations                        int
 	DestroyDataSetDataInputs                             []DestroyDataSetDataInput
 	DestroyDataSetDataOutputs                            []error
@@ -175,18 +368,75 @@ type DataSession struct {
 	UnarchiveDeviceDataUsingHashesFromDataSetInvocations int
 	UnarchiveDeviceDataUsingHashesFromDataSetInputs      []UnarchiveDeviceDataUsingHashesFromDataSetInput
 	UnarchiveDeviceDataUsingHashesFromDataSetOutputs     []error
+	ArchiveDataSetDataUsingHashesFromDeviceInvocations   int
+	ArchiveDataSetDataUsingHashesFromDeviceInputs        []ArchiveDataSetDataUsingHashesFromDeviceInput
+	ArchiveDataSetDataUsingHashesFromDeviceOutputs       []error
+	GetDeviceDataHashesInvocations                       int
+	GetDeviceDataHashesInputs                            []GetDeviceDataHashesInput
+	GetDeviceDataHashesOutputs                           []GetDeviceDataHashesOutput
 	DeleteOtherDataSetDataInvocations                    int
 	DeleteOtherDataSetDataInputs                         []DeleteOtherDataSetDataInput
 	DeleteOtherDataSetDataOutputs                        []error
+	GetDataSetDataSamplesInvocations                     int
+	GetDataSetDataSamplesInputs                          []GetDataSetDataSamplesInput
+	GetDataSetDataSamplesOutputs                         []GetDataSetDataSamplesOutput
+	GetDeviceDataSamplesInvocations                      int
+	GetDeviceDataSamplesInputs                           []GetDeviceDataSamplesInput
+	GetDeviceDataSamplesOutputs                          []GetDeviceDataSamplesOutput
+	ArchiveDeviceDataByIDsInvocations                    int
+	ArchiveDeviceDataByIDsInputs                         []ArchiveDeviceDataByIDsInput
+	ArchiveDeviceDataByIDsOutputs                        []error
+	UnarchiveDeviceDataArchivedByDataSetInvocations      int
+	UnarchiveDeviceDataArchivedByDataSetInputs           []UnarchiveDeviceDataArchivedByDataSetInput
+	UnarchiveDeviceDataArchivedByDataSetOutputs          []error
 	DestroyDataForUserByIDInvocations                    int
 	DestroyDataForUserByIDInputs                         []DestroyDataForUserByIDInput
 	DestroyDataForUserByIDOutputs                        []error
+	UpdateDataAssociationsInvocations                    int
+	UpdateDataAssociationsInputs                         []UpdateDataAssociationsInput
+	UpdateDataAssociationsOutputs                        []error
 	ListUserDataSetsInvocations                          int
 	ListUserDataSetsInputs                               []ListUserDataSetsInput
 	ListUserDataSetsOutputs                              []ListUserDataSetsOutput
+	ListDeletedDataSetsInvocations                       int
+	ListDeletedDataSetsInputs                            []ListDeletedDataSetsInput
+	ListDeletedDataSetsOutputs                           []ListDeletedDataSetsOutput
 	GetDataSetInvocations                                int
 	GetDataSetInputs                                     []GetDataSetInput
 	GetDataSetOutputs                                    []GetDataSetOutput
+	GetDataForUserByIDInvocations                        int
+	GetDataForUserByIDInputs                             []GetDataForUserByIDInput
+	GetDataForUserByIDOutputs                            []GetDataForUserByIDOutput
+	IterateDataForUserByIDInvocations                    int
+	IterateDataForUserByIDInputs                         []IterateDataForUserByIDInput
+	IterateDataForUserByIDOutputs                        []IterateDataForUserByIDOutput
+	GetDailySummariesInvocations                         int
+	GetDailySummariesInputs                              []GetDailySummariesInput
+	GetDailySummariesOutputs                             []GetDailySummariesOutput
+	CalculateDailySummariesInvocations                   int
+	CalculateDailySummariesInputs                        []CalculateDailySummariesInput
+	CalculateDailySummariesOutputs                       []CalculateDailySummariesOutput
+	RebuildDailySummariesInvocations                     int
+	RebuildDailySummariesInputs                          []RebuildDailySummariesInput
+	RebuildDailySummariesOutputs                         []error
+	RebuildPendingDailySummariesInvocations              int
+	RebuildPendingDailySummariesInputs                   []RebuildPendingDailySummariesInput
+	RebuildPendingDailySummariesOutputs                  []RebuildPendingDailySummariesOutput
+	CreateTimeProcessingBatchInvocations                 int
+	CreateTimeProcessingBatchInputs                      []CreateTimeProcessingBatchInput
+	CreateTimeProcessingBatchOutputs                     []error
+	ListTimeProcessingBatchIDsInvocations                int
+	ListTimeProcessingBatchIDsInputs                     []ListTimeProcessingBatchIDsInput
+	ListTimeProcessingBatchIDsOutputs                    []ListTimeProcessingBatchIDsOutput
+	GetTimeProcessingBatchInvocations                    int
+	GetTimeProcessingBatchInputs                         []GetTimeProcessingBatchInput
+	GetTimeProcessingBatchOutputs                        []GetTimeProcessingBatchOutput
+	SetTimeProcessingBatchProcessedInvocations           int
+	SetTimeProcessingBatchProcessedInputs                []SetTimeProcessingBatchProcessedInput
+	SetTimeProcessingBatchProcessedOutputs               []error
+	DestroyTimeProcessingBatchesInvocations              int
+	DestroyTimeProcessingBatchesInputs                   []DestroyTimeProcessingBatchesInput
+	DestroyTimeProcessingBatchesOutputs                  []error
 }
 
 func NewDataSession() *DataSession {
@@ -255,6 +505,18 @@ func (d *DataSession) DeleteDataSet(ctx context.Context, dataSet *upload.Upload,
 	return output
 }
 
+func (d *DataSession) RestoreDataSet(ctx context.Context, dataSet *upload.Upload) error {
+	d.RestoreDataSetInvocations++
+
+	d.RestoreDataSetInputs = append(d.RestoreDataSetInputs, RestoreDataSetInput{Context: ctx, DataSet: dataSet})
+
+	gomega.Expect(d.RestoreDataSetOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.RestoreDataSetOutputs[0]
+	d.RestoreDataSetOutputs = d.RestoreDataSetOutputs[1:]
+	return output
+}
+
 func (d *DataSession) CreateDataSetData(ctx context.Context, dataSet *upload.Upload, dataSetData []data.Datum) error {
 	d.CreateDataSetDataInvocations++
 
@@ -315,6 +577,18 @@ func (d *DataSession) DestroyDeletedDataSetData(ctx context.Context, dataSet *up
 	return output
 }
 
+func (d *DataSession) DestroyDeletedData(ctx context.Context, filter *data.DeletedDataFilter, pagination *page.Pagination) (*data.DeletedDataDestroyResult, error) {
+	d.DestroyDeletedDataInvocations++
+
+	d.DestroyDeletedDataInputs = append(d.DestroyDeletedDataInputs, DestroyDeletedDataInput{Context: ctx, Filter: filter, Pagination: pagination})
+
+	gomega.Expect(d.DestroyDeletedDataOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.DestroyDeletedDataOutputs[0]
+	d.DestroyDeletedDataOutputs = d.DestroyDeletedDataOutputs[1:]
+	return output.Result, output.Error
+}
+
 func (d *DataSession) DestroyDataSetData(ctx context.Context, dataSet *upload.Upload, selectors *data.Selectors) error {
 	d.DestroyDataSetDataInvocations++
 
@@ -351,6 +625,78 @@ func (d *DataSession) UnarchiveDeviceDataUsingHashesFromDataSet(ctx context.Cont
 	return output
 }
 
+func (d *DataSession) ArchiveDataSetDataUsingHashesFromDevice(ctx context.Context, dataSet *upload.Upload) error {
+	d.ArchiveDataSetDataUsingHashesFromDeviceInvocations++
+
+	d.ArchiveDataSetDataUsingHashesFromDeviceInputs = append(d.ArchiveDataSetDataUsingHashesFromDeviceInputs, ArchiveDataSetDataUsingHashesFromDeviceInput{Context: ctx, DataSet: dataSet})
+
+	gomega.Expect(d.ArchiveDataSetDataUsingHashesFromDeviceOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.ArchiveDataSetDataUsingHashesFromDeviceOutputs[0]
+	d.ArchiveDataSetDataUsingHashesFromDeviceOutputs = d.ArchiveDataSetDataUsingHashesFromDeviceOutputs[1:]
+	return output
+}
+
+func (d *DataSession) GetDeviceDataHashes(ctx context.Context, dataSet *upload.Upload, hashes []string) ([]string, error) {
+	d.GetDeviceDataHashesInvocations++
+
+	d.GetDeviceDataHashesInputs = append(d.GetDeviceDataHashesInputs, GetDeviceDataHashesInput{Context: ctx, DataSet: dataSet, Hashes: hashes})
+
+	gomega.Expect(d.GetDeviceDataHashesOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.GetDeviceDataHashesOutputs[0]
+	d.GetDeviceDataHashesOutputs = d.GetDeviceDataHashesOutputs[1:]
+	return output.Hashes, output.Error
+}
+
+func (d *DataSession) GetDataSetDataSamples(ctx context.Context, dataSet *upload.Upload) ([]*dataStoreDEPRECATED.DataSample, error) {
+	d.GetDataSetDataSamplesInvocations++
+
+	d.GetDataSetDataSamplesInputs = append(d.GetDataSetDataSamplesInputs, GetDataSetDataSamplesInput{Context: ctx, DataSet: dataSet})
+
+	gomega.Expect(d.GetDataSetDataSamplesOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.GetDataSetDataSamplesOutputs[0]
+	d.GetDataSetDataSamplesOutputs = d.GetDataSetDataSamplesOutputs[1:]
+	return output.Samples, output.Error
+}
+
+func (d *DataSession) GetDeviceDataSamples(ctx context.Context, dataSet *upload.Upload, types []string, startTime time.Time, endTime time.Time) ([]*dataStoreDEPRECATED.DataSample, error) {
+	d.GetDeviceDataSamplesInvocations++
+
+	d.GetDeviceDataSamplesInputs = append(d.GetDeviceDataSamplesInputs, GetDeviceDataSamplesInput{Context: ctx, DataSet: dataSet, Types: types, StartTime: startTime, EndTime: endTime})
+
+	gomega.Expect(d.GetDeviceDataSamplesOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.GetDeviceDataSamplesOutputs[0]
+	d.GetDeviceDataSamplesOutputs = d.GetDeviceDataSamplesOutputs[1:]
+	return output.Samples, output.Error
+}
+
+func (d *DataSession) ArchiveDeviceDataByIDs(ctx context.Context, dataSet *upload.Upload, ids []string) error {
+	d.ArchiveDeviceDataByIDsInvocations++
+
+	d.ArchiveDeviceDataByIDsInputs = append(d.ArchiveDeviceDataByIDsInputs, ArchiveDeviceDataByIDsInput{Context: ctx, DataSet: dataSet, IDs: ids})
+
+	gomega.Expect(d.ArchiveDeviceDataByIDsOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.ArchiveDeviceDataByIDsOutputs[0]
+	d.ArchiveDeviceDataByIDsOutputs = d.ArchiveDeviceDataByIDsOutputs[1:]
+	return output
+}
+
+func (d *DataSession) UnarchiveDeviceDataArchivedByDataSet(ctx context.Context, dataSet *upload.Upload) error {
+	d.UnarchiveDeviceDataArchivedByDataSetInvocations++
+
+	d.UnarchiveDeviceDataArchivedByDataSetInputs = append(d.UnarchiveDeviceDataArchivedByDataSetInputs, UnarchiveDeviceDataArchivedByDataSetInput{Context: ctx, DataSet: dataSet})
+
+	gomega.Expect(d.UnarchiveDeviceDataArchivedByDataSetOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.UnarchiveDeviceDataArchivedByDataSetOutputs[0]
+	d.UnarchiveDeviceDataArchivedByDataSetOutputs = d.UnarchiveDeviceDataArchivedByDataSetOutputs[1:]
+	return output
+}
+
 func (d *DataSession) DeleteOtherDataSetData(ctx context.Context, dataSet *upload.Upload) error {
 	d.DeleteOtherDataSetDataInvocations++
 
@@ -375,6 +721,18 @@ func (d *DataSession) DestroyDataForUserByID(ctx context.Context, userID string)
 	return output
 }
 
+func (d *DataSession) UpdateDataAssociations(ctx context.Context, userID string, associations map[string]*association.AssociationArray) error {
+	d.UpdateDataAssociationsInvocations++
+
+	d.UpdateDataAssociationsInputs = append(d.UpdateDataAssociationsInputs, UpdateDataAssociationsInput{Context: ctx, UserID: userID, Associations: associations})
+
+	gomega.Expect(d.UpdateDataAssociationsOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.UpdateDataAssociationsOutputs[0]
+	d.UpdateDataAssociationsOutputs = d.UpdateDataAssociationsOutputs[1:]
+	return output
+}
+
 func (d *DataSession) ListUserDataSets(ctx context.Context, userID string, filter *data.DataSetFilter, pagination *page.Pagination) (data.DataSets, error) {
 	d.ListUserDataSetsInvocations++
 
@@ -387,6 +745,18 @@ func (d *DataSession) ListUserDataSets(ctx context.Context, userID string, filte
 	return output.DataSets, output.Error
 }
 
+func (d *DataSession) ListDeletedDataSets(ctx context.Context, filter *data.DeletedDataSetFilter, pagination *page.Pagination) (data.DataSets, error) {
+	d.ListDeletedDataSetsInvocations++
+
+	d.ListDeletedDataSetsInputs = append(d.ListDeletedDataSetsInputs, ListDeletedDataSetsInput{Context: ctx, Filter: filter, Pagination: pagination})
+
+	gomega.Expect(d.ListDeletedDataSetsOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.ListDeletedDataSetsOutputs[0]
+	d.ListDeletedDataSetsOutputs = d.ListDeletedDataSetsOutputs[1:]
+	return output.DataSets, output.Error
+}
+
 func (d *DataSession) GetDataSet(ctx context.Context, id string) (*data.DataSet, error) {
 	d.GetDataSetInvocations++
 
@@ -399,6 +769,138 @@ func (d *DataSession) GetDataSet(ctx context.Context, id string) (*data.DataSet,
 	return output.DataSet, output.Error
 }
 
+func (d *DataSession) GetDataForUserByID(ctx context.Context, userID string, filter *dataStoreDEPRECATED.DataFilter, pagination *page.Pagination) (data.Data, error) {
+	d.GetDataForUserByIDInvocations++
+
+	d.GetDataForUserByIDInputs = append(d.GetDataForUserByIDInputs, GetDataForUserByIDInput{Context: ctx, UserID: userID, Filter: filter, Pagination: pagination})
+
+	gomega.Expect(d.GetDataForUserByIDOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.GetDataForUserByIDOutputs[0]
+	d.GetDataForUserByIDOutputs = d.GetDataForUserByIDOutputs[1:]
+	return output.Data, output.Error
+}
+
+func (d *DataSession) IterateDataForUserByID(ctx context.Context, userID string, filter *dataStoreDEPRECATED.DataFilter, cursor *dataStoreDEPRECATED.DataCursor) (dataStoreDEPRECATED.DataIterator, error) {
+	d.IterateDataForUserByIDInvocations++
+
+	d.IterateDataForUserByIDInputs = append(d.IterateDataForUserByIDInputs, IterateDataForUserByIDInput{Context: ctx, UserID: userID, Filter: filter, Cursor: cursor})
+
+	gomega.Expect(d.IterateDataForUserByIDOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.IterateDataForUserByIDOutputs[0]
+	d.IterateDataForUserByIDOutputs = d.IterateDataForUserByIDOutputs[1:]
+	return output.DataIterator, output.Error
+}
+
+func (d *DataSession) GetDailySummaries(ctx context.Context, userID string, filter *summary.DailySummaryFilter) ([]*summary.DailySummary, error) {
+	d.GetDailySummariesInvocations++
+
+	d.GetDailySummariesInputs = append(d.GetDailySummariesInputs, GetDailySummariesInput{Context: ctx, UserID: userID, Filter: filter})
+
+	gomega.Expect(d.GetDailySummariesOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.GetDailySummariesOutputs[0]
+	d.GetDailySummariesOutputs = d.GetDailySummariesOutputs[1:]
+	return output.DailySummaries, output.Error
+}
+
+func (d *DataSession) CalculateDailySummaries(ctx context.Context, userID string, startDate string, endDate string) ([]*summary.DailySummary, error) {
+	d.CalculateDailySummariesInvocations++
+
+	d.CalculateDailySummariesInputs = append(d.CalculateDailySummariesInputs, CalculateDailySummariesInput{Context: ctx, UserID: userID, StartDate: startDate, EndDate: endDate})
+
+	gomega.Expect(d.CalculateDailySummariesOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.CalculateDailySummariesOutputs[0]
+	d.CalculateDailySummariesOutputs = d.CalculateDailySummariesOutputs[1:]
+	return output.DailySummaries, output.Error
+}
+
+func (d *DataSession) RebuildDailySummaries(ctx context.Context, userID string, startDate string, endDate string) error {
+	d.RebuildDailySummariesInvocations++
+
+	d.RebuildDailySummariesInputs = append(d.RebuildDailySummariesInputs, RebuildDailySummariesInput{Context: ctx, UserID: userID, StartDate: startDate, EndDate: endDate})
+
+	gomega.Expect(d.RebuildDailySummariesOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.RebuildDailySummariesOutputs[0]
+	d.RebuildDailySummariesOutputs = d.RebuildDailySummariesOutputs[1:]
+	return output
+}
+
+func (d *DataSession) RebuildPendingDailySummaries(ctx context.Context, filter *summary.DailySummaryRebuildFilter, pagination *page.Pagination) (*summary.DailySummaryRebuildResult, error) {
+	d.RebuildPendingDailySummariesInvocations++
+
+	d.RebuildPendingDailySummariesInputs = append(d.RebuildPendingDailySummariesInputs, RebuildPendingDailySummariesInput{Context: ctx, Filter: filter, Pagination: pagination})
+
+	gomega.Expect(d.RebuildPendingDailySummariesOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.RebuildPendingDailySummariesOutputs[0]
+	d.RebuildPendingDailySummariesOutputs = d.RebuildPendingDailySummariesOutputs[1:]
+	return output.Result, output.Error
+}
+
+func (d *DataSession) CreateTimeProcessingBatch(ctx context.Context, dataSet *upload.Upload, rawData []interface{}) error {
+	d.CreateTimeProcessingBatchInvocations++
+
+	d.CreateTimeProcessingBatchInputs = append(d.CreateTimeProcessingBatchInputs, CreateTimeProcessingBatchInput{Context: ctx, DataSet: dataSet, RawData: rawData})
+
+	gomega.Expect(d.CreateTimeProcessingBatchOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.CreateTimeProcessingBatchOutputs[0]
+	d.CreateTimeProcessingBatchOutputs = d.CreateTimeProcessingBatchOutputs[1:]
+	return output
+}
+
+func (d *DataSession) ListTimeProcessingBatchIDs(ctx context.Context, dataSet *upload.Upload) ([]string, error) {
+	d.ListTimeProcessingBatchIDsInvocations++
+
+	d.ListTimeProcessingBatchIDsInputs = append(d.ListTimeProcessingBatchIDsInputs, ListTimeProcessingBatchIDsInput{Context: ctx, DataSet: dataSet})
+
+	gomega.Expect(d.ListTimeProcessingBatchIDsOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.ListTimeProcessingBatchIDsOutputs[0]
+	d.ListTimeProcessingBatchIDsOutputs = d.ListTimeProcessingBatchIDsOutputs[1:]
+	return output.IDs, output.Error
+}
+
+func (d *DataSession) GetTimeProcessingBatch(ctx context.Context, id string) (*dataStoreDEPRECATED.TimeProcessingBatch, error) {
+	d.GetTimeProcessingBatchInvocations++
+
+	d.GetTimeProcessingBatchInputs = append(d.GetTimeProcessingBatchInputs, GetTimeProcessingBatchInput{Context: ctx, ID: id})
+
+	gomega.Expect(d.GetTimeProcessingBatchOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.GetTimeProcessingBatchOutputs[0]
+	d.GetTimeProcessingBatchOutputs = d.GetTimeProcessingBatchOutputs[1:]
+	return output.Batch, output.Error
+}
+
+func (d *DataSession) SetTimeProcessingBatchProcessed(ctx context.Context, id string) error {
+	d.SetTimeProcessingBatchProcessedInvocations++
+
+	d.SetTimeProcessingBatchProcessedInputs = append(d.SetTimeProcessingBatchProcessedInputs, SetTimeProcessingBatchProcessedInput{Context: ctx, ID: id})
+
+	gomega.Expect(d.SetTimeProcessingBatchProcessedOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.SetTimeProcessingBatchProcessedOutputs[0]
+	d.SetTimeProcessingBatchProcessedOutputs = d.SetTimeProcessingBatchProcessedOutputs[1:]
+	return output
+}
+
+func (d *DataSession) DestroyTimeProcessingBatches(ctx context.Context, dataSet *upload.Upload) error {
+	d.DestroyTimeProcessingBatchesInvocations++
+
+	d.DestroyTimeProcessingBatchesInputs = append(d.DestroyTimeProcessingBatchesInputs, DestroyTimeProcessingBatchesInput{Context: ctx, DataSet: dataSet})
+
+	gomega.Expect(d.DestroyTimeProcessingBatchesOutputs).ToNot(gomega.BeEmpty())
+
+	output := d.DestroyTimeProcessingBatchesOutputs[0]
+	d.DestroyTimeProcessingBatchesOutputs = d.DestroyTimeProcessingBatchesOutputs[1:]
+	return output
+}
+
 func (d *DataSession) Expectations() {
 	d.Closer.AssertOutputsEmpty()
 	gomega.Expect(d.GetDataSetsForUserByIDOutputs).To(gomega.BeEmpty())
@@ -406,16 +908,37 @@ func (d *DataSession) Expectations() {
 	gomega.Expect(d.CreateDataSetOutputs).To(gomega.BeEmpty())
 	gomega.Expect(d.UpdateDataSetOutputs).To(gomega.BeEmpty())
 	gomega.Expect(d.DeleteDataSetOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.RestoreDataSetOutputs).To(gomega.BeEmpty())
 	gomega.Expect(d.CreateDataSetDataOutputs).To(gomega.BeEmpty())
 	gomega.Expect(d.ActivateDataSetDataOutputs).To(gomega.BeEmpty())
 	gomega.Expect(d.ArchiveDataSetDataOutputs).To(gomega.BeEmpty())
 	gomega.Expect(d.DeleteDataSetDataOutputs).To(gomega.BeEmpty())
 	gomega.Expect(d.DestroyDeletedDataSetDataOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.DestroyDeletedDataOutputs).To(gomega.BeEmpty())
 	gomega.Expect(d.DestroyDataSetDataOutputs).To(gomega.BeEmpty())
 	gomega.Expect(d.ArchiveDeviceDataUsingHashesFromDataSetOutputs).To(gomega.BeEmpty())
 	gomega.Expect(d.UnarchiveDeviceDataUsingHashesFromDataSetOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.ArchiveDataSetDataUsingHashesFromDeviceOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.GetDeviceDataHashesOutputs).To(gomega.BeEmpty())
 	gomega.Expect(d.DeleteOtherDataSetDataOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.GetDataSetDataSamplesOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.GetDeviceDataSamplesOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.ArchiveDeviceDataByIDsOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.UnarchiveDeviceDataArchivedByDataSetOutputs).To(gomega.BeEmpty())
 	gomega.Expect(d.DestroyDataForUserByIDOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.UpdateDataAssociationsOutputs).To(gomega.BeEmpty())
 	gomega.Expect(d.ListUserDataSetsOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.ListDeletedDataSetsOutputs).To(gomega.BeEmpty())
 	gomega.Expect(d.GetDataSetOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.GetDataForUserByIDOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.IterateDataForUserByIDOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.GetDailySummariesOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.CalculateDailySummariesOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.RebuildDailySummariesOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.RebuildPendingDailySummariesOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.CreateTimeProcessingBatchOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.ListTimeProcessingBatchIDsOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.GetTimeProcessingBatchOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.SetTimeProcessingBatchProcessedOutputs).To(gomega.BeEmpty())
+	gomega.Expect(d.DestroyTimeProcessingBatchesOutputs).To(gomega.BeEmpty())
 }
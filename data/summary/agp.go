@@ -0,0 +1,96 @@
+package summary
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/tidepool-org/platform/data"
+	dataBloodGlucose "github.com/tidepool-org/platform/data/blood/glucose"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	"github.com/tidepool-org/platform/pointer"
+)
+
+const (
+	AGPBucketDuration = 15 * time.Minute
+	AGPBucketCount    = int(24 * time.Hour / AGPBucketDuration)
+	AGPRangeDefault   = 14 * 24 * time.Hour
+)
+
+// AGPBucket holds the glucose percentiles, in mmol/L, of the readings within the bucket of the day starting at the
+// local time
+type AGPBucket struct {
+	Time         string   `json:"time"`
+	Count        int      `json:"count"`
+	Percentile5  *float64 `json:"percentile5,omitempty"`
+	Percentile25 *float64 `json:"percentile25,omitempty"`
+	Percentile50 *float64 `json:"percentile50,omitempty"`
+	Percentile75 *float64 `json:"percentile75,omitempty"`
+	Percentile95 *float64 `json:"percentile95,omitempty"`
+}
+
+// AGPReport is the ambulatory glucose profile, with a bucket for each 15 minutes of the day, in order
+type AGPReport struct {
+	StartTime string       `json:"startTime"`
+	EndTime   string       `json:"endTime"`
+	Units     string       `json:"units"`
+	Buckets   []*AGPBucket `json:"buckets"`
+}
+
+// CalculateAGP calculates the ambulatory glucose profile of the continuous glucose datums between the start and end
+// times, ignoring any other datums. The readings are bucketed by the local time of the datum.
+func CalculateAGP(datums data.Data, startTime time.Time, endTime time.Time) *AGPReport {
+	values := make([][]float64, AGPBucketCount)
+	for _, datum := range datums {
+		continuous, ok := datum.(*dataTypesBloodGlucoseContinuous.Continuous)
+		if !ok {
+			continue
+		}
+		value := dataBloodGlucose.NormalizeValueForUnits(continuous.Value, continuous.Units)
+		if value == nil {
+			continue
+		}
+		tm, ok := LocalTime(&continuous.Base)
+		if !ok || tm.Before(startTime) || tm.After(endTime) {
+			continue
+		}
+
+		index := (tm.Hour()*60 + tm.Minute()) / int(AGPBucketDuration/time.Minute)
+		values[index] = append(values[index], *value)
+	}
+
+	buckets := make([]*AGPBucket, AGPBucketCount)
+	for index := range buckets {
+		minutes := index * int(AGPBucketDuration/time.Minute)
+		bucket := &AGPBucket{
+			Time:  fmt.Sprintf("%02d:%02d", minutes/60, minutes%60),
+			Count: len(values[index]),
+		}
+		if bucket.Count > 0 {
+			sort.Float64s(values[index])
+			bucket.Percentile5 = pointer.FromFloat64(Percentile(values[index], 5))
+			bucket.Percentile25 = pointer.FromFloat64(Percentile(values[index], 25))
+			bucket.Percentile50 = pointer.FromFloat64(Percentile(values[index], 50))
+			bucket.Percentile75 = pointer.FromFloat64(Percentile(values[index], 75))
+			bucket.Percentile95 = pointer.FromFloat64(Percentile(values[index], 95))
+		}
+		buckets[index] = bucket
+	}
+
+	return &AGPReport{
+		StartTime: startTime.Format(time.RFC3339Nano),
+		EndTime:   endTime.Format(time.RFC3339Nano),
+		Units:     dataBloodGlucose.MmolL,
+		Buckets:   buckets,
+	}
+}
+
+// Percentile returns the percentile, from 0 to 100, of the sorted values, interpolating linearly between the closest
+// ranks. The values must not be empty.
+func Percentile(sortedValues []float64, percentile float64) float64 {
+	rank := percentile / 100 * float64(len(sortedValues)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sortedValues[lower] + (rank-float64(lower))*(sortedValues[upper]-sortedValues[lower])
+}
@@ -0,0 +1,93 @@
+package summary_test
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesFood "github.com/tidepool-org/platform/data/types/food"
+	"github.com/tidepool-org/platform/pointer"
+)
+
+var _ = Describe("AGP", func() {
+	Context("Percentile", func() {
+		It("returns the single value", func() {
+			Expect(summary.Percentile([]float64{5}, 95)).To(Equal(5.0))
+		})
+
+		It("returns the minimum and maximum values", func() {
+			Expect(summary.Percentile([]float64{1, 2, 3}, 0)).To(Equal(1.0))
+			Expect(summary.Percentile([]float64{1, 2, 3}, 100)).To(Equal(3.0))
+		})
+
+		It("interpolates between the closest ranks", func() {
+			Expect(summary.Percentile([]float64{1, 2, 3, 4}, 50)).To(Equal(2.5))
+			Expect(summary.Percentile([]float64{1, 2, 3, 4, 5}, 25)).To(Equal(2.0))
+			Expect(summary.Percentile([]float64{10, 20}, 5)).To(BeNumerically("~", 10.5, 0.0001))
+		})
+	})
+
+	Context("CalculateAGP", func() {
+		var startTime time.Time
+		var endTime time.Time
+
+		BeforeEach(func() {
+			startTime = time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+			endTime = startTime.Add(summary.AGPRangeDefault)
+		})
+
+		It("returns a bucket for each 15 minutes of the day without data", func() {
+			report := summary.CalculateAGP(nil, startTime, endTime)
+			Expect(report.StartTime).To(Equal("2020-03-01T00:00:00Z"))
+			Expect(report.EndTime).To(Equal("2020-03-15T00:00:00Z"))
+			Expect(report.Units).To(Equal("mmol/L"))
+			Expect(report.Buckets).To(HaveLen(96))
+			Expect(report.Buckets[0]).To(Equal(&summary.AGPBucket{Time: "00:00"}))
+			Expect(report.Buckets[1].Time).To(Equal("00:15"))
+			Expect(report.Buckets[95].Time).To(Equal("23:45"))
+		})
+
+		It("buckets the readings of each day by local time", func() {
+			datums := data.Data{}
+			for day := 0; day < 14; day++ {
+				datums = append(datums, NewContinuous(fmt.Sprintf("2020-03-%02dT08:%02d:00Z", day+1, day), "mmol/L", float64(day+1)))
+			}
+			offset := NewContinuous("2020-03-05T12:20:00Z", "mg/dL", 180.1559)
+			offset.TimeZoneOffset = pointer.FromInt(-240)
+			named := NewContinuous("2020-03-05T12:20:00Z", "mmol/L", 12)
+			named.TimeZoneName = pointer.FromString("Europe/Paris")
+			food := dataTypesFood.New()
+			food.Time = pointer.FromString("2020-03-05T08:00:00Z")
+			datums = append(datums, offset, named, food, NewContinuous("2020-02-29T08:00:00Z", "mmol/L", 20))
+
+			report := summary.CalculateAGP(datums, startTime, endTime)
+			Expect(report.Buckets).To(HaveLen(96))
+
+			bucket := report.Buckets[32]
+			Expect(bucket.Time).To(Equal("08:00"))
+			Expect(bucket.Count).To(Equal(14))
+			Expect(*bucket.Percentile5).To(BeNumerically("~", 1.65, 0.0001))
+			Expect(*bucket.Percentile25).To(BeNumerically("~", 4.25, 0.0001))
+			Expect(*bucket.Percentile50).To(BeNumerically("~", 7.5, 0.0001))
+			Expect(*bucket.Percentile75).To(BeNumerically("~", 10.75, 0.0001))
+			Expect(*bucket.Percentile95).To(BeNumerically("~", 13.35, 0.0001))
+
+			bucket = report.Buckets[33]
+			Expect(bucket.Time).To(Equal("08:15"))
+			Expect(bucket.Count).To(Equal(1))
+			Expect(*bucket.Percentile50).To(BeNumerically("~", 10.0, 0.0001))
+
+			bucket = report.Buckets[53]
+			Expect(bucket.Time).To(Equal("13:15"))
+			Expect(bucket.Count).To(Equal(1))
+			Expect(*bucket.Percentile5).To(Equal(12.0))
+			Expect(*bucket.Percentile95).To(Equal(12.0))
+
+			Expect(report.Buckets[48]).To(Equal(&summary.AGPBucket{Time: "12:00"}))
+		})
+	})
+})
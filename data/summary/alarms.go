@@ -0,0 +1,371 @@
+package summary
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tidepool-org/platform/data"
+	dataBloodGlucose "github.com/tidepool-org/platform/data/blood/glucose"
+	dataTypesDeviceAlarm "github.com/tidepool-org/platform/data/types/device/alarm"
+	dataTypesSettingsCgm "github.com/tidepool-org/platform/data/types/settings/cgm"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/structure"
+)
+
+const (
+	AlarmBurstCountMinimum = 3
+	AlarmBurstGapMaximum   = 30 * time.Minute
+	AlarmsRangeDefault     = 14 * 24 * time.Hour
+
+	AlarmAlertHigh      = "high"
+	AlarmAlertLow       = "low"
+	AlarmAlertUrgentLow = "urgentLow"
+)
+
+// AlarmsOptions are the alarm codes of the CGM level alerts, as sent by the devices, to correlate the alarms with the
+// level alerts of the CGM settings
+type AlarmsOptions struct {
+	HighAlarmCodes      *[]string `json:"highAlarmCode,omitempty"`
+	LowAlarmCodes       *[]string `json:"lowAlarmCode,omitempty"`
+	UrgentLowAlarmCodes *[]string `json:"urgentLowAlarmCode,omitempty"`
+}
+
+func NewAlarmsOptions() *AlarmsOptions {
+	return &AlarmsOptions{}
+}
+
+func (a *AlarmsOptions) Parse(parser structure.ObjectParser) {
+	a.HighAlarmCodes = parser.StringArray("highAlarmCode")
+	a.LowAlarmCodes = parser.StringArray("lowAlarmCode")
+	a.UrgentLowAlarmCodes = parser.StringArray("urgentLowAlarmCode")
+}
+
+func (a *AlarmsOptions) Validate(validator structure.Validator) {
+	validator.StringArray("highAlarmCode", a.HighAlarmCodes).NotEmpty().EachNotEmpty().EachUnique()
+	validator.StringArray("lowAlarmCode", a.LowAlarmCodes).NotEmpty().EachNotEmpty().EachUnique()
+	validator.StringArray("urgentLowAlarmCode", a.UrgentLowAlarmCodes).NotEmpty().EachNotEmpty().EachUnique()
+}
+
+// alert returns the CGM level alert of the alarm code, if any
+func (a *AlarmsOptions) alert(alarmCode *string) string {
+	if a == nil || alarmCode == nil {
+		return ""
+	}
+	switch {
+	case containsAlarmCode(a.UrgentLowAlarmCodes, *alarmCode):
+		return AlarmAlertUrgentLow
+	case containsAlarmCode(a.LowAlarmCodes, *alarmCode):
+		return AlarmAlertLow
+	case containsAlarmCode(a.HighAlarmCodes, *alarmCode):
+		return AlarmAlertHigh
+	}
+	return ""
+}
+
+func containsAlarmCode(alarmCodes *[]string, alarmCode string) bool {
+	if alarmCodes != nil {
+		for _, code := range *alarmCodes {
+			if code == alarmCode {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AlarmDailyCount holds the number of alarms, in total and by alarm type, of one local day
+type AlarmDailyCount struct {
+	Date  string         `json:"date"`
+	Count int            `json:"count"`
+	Types map[string]int `json:"types"`
+}
+
+// AlarmHourlyCount holds the number of alarms, in total and by alarm type, of one local hour of the day over the range
+type AlarmHourlyCount struct {
+	Hour  int            `json:"hour"`
+	Count int            `json:"count"`
+	Types map[string]int `json:"types"`
+}
+
+// AlarmBurst is a series of at least AlarmBurstCountMinimum alarms, each within AlarmBurstGapMaximum of the previous
+type AlarmBurst struct {
+	StartTime string         `json:"startTime"`
+	EndTime   string         `json:"endTime"`
+	Count     int            `json:"count"`
+	Types     map[string]int `json:"types"`
+}
+
+// AlarmAlertConfiguration is the level alert configuration, in the CGM settings active at the time, of a number of
+// alarms with the alarm code of the level alert. The schedule is the name of the scheduled alerts active at the time, if any, otherwise the
+// default alerts apply. The level is in mmol/L and the snooze in minutes.
+type AlarmAlertConfiguration struct {
+	Alert           string   `json:"alert"`
+	AlarmCode       string   `json:"alarmCode"`
+	CGMSettingsID   *string  `json:"cgmSettingsId,omitempty"`
+	CGMSettingsTime *string  `json:"cgmSettingsTime,omitempty"`
+	Schedule        *string  `json:"schedule,omitempty"`
+	Enabled         *bool    `json:"enabled,omitempty"`
+	Level           *float64 `json:"level,omitempty"`
+	Snooze          *float64 `json:"snooze,omitempty"`
+	Count           int      `json:"count"`
+}
+
+// AlarmsReport holds the alarm counts, in total and by alarm type, per local day, ordered by date, and per local
+// hour of the day, the alarm bursts, ordered by start time, and the alert configurations of the alarms of the CGM level
+// alerts, ordered by alert and then by first alarm
+type AlarmsReport struct {
+	StartTime           string                     `json:"startTime"`
+	EndTime             string                     `json:"endTime"`
+	Units               string                     `json:"units"`
+	Count               int                        `json:"count"`
+	Types               map[string]int             `json:"types"`
+	Daily               []*AlarmDailyCount         `json:"daily"`
+	Hourly              []*AlarmHourlyCount        `json:"hourly"`
+	Bursts              []*AlarmBurst              `json:"bursts"`
+	AlertConfigurations []*AlarmAlertConfiguration `json:"alertConfigurations"`
+}
+
+type alarmOccurrence struct {
+	time      time.Time
+	alarmType string
+	alarmCode string
+	alert     string
+}
+
+type cgmSettingsOccurrence struct {
+	time        time.Time
+	cgmSettings *dataTypesSettingsCgm.CGM
+}
+
+// CalculateAlarms calculates the alarms report of the device alarm datums between the start and end times, using
+// the local time of the alarms. An alarm with one of the alarm codes of the options is one of the CGM level alert. Its
+// alert configuration is that of the latest CGM settings datum at the time of the alarm, so the datums should include
+// the CGM settings before the start time. Any other datum is ignored.
+func CalculateAlarms(datums data.Data, options *AlarmsOptions, startTime time.Time, endTime time.Time) *AlarmsReport {
+	alarms := []*alarmOccurrence{}
+	cgmSettingsOccurrences := []*cgmSettingsOccurrence{}
+	for _, datum := range datums {
+		switch typed := datum.(type) {
+		case *dataTypesDeviceAlarm.Alarm:
+			if typed.AlarmType == nil {
+				continue
+			}
+			tm, ok := LocalTime(&typed.Base)
+			if !ok || tm.Before(startTime) || tm.After(endTime) {
+				continue
+			}
+			alarms = append(alarms, &alarmOccurrence{time: tm, alarmType: *typed.AlarmType, alarmCode: pointer.ToString(typed.AlarmCode), alert: options.alert(typed.AlarmCode)})
+		case *dataTypesSettingsCgm.CGM:
+			tm, ok := LocalTime(&typed.Base)
+			if !ok || tm.After(endTime) {
+				continue
+			}
+			cgmSettingsOccurrences = append(cgmSettingsOccurrences, &cgmSettingsOccurrence{time: tm, cgmSettings: typed})
+		}
+	}
+	sort.SliceStable(alarms, func(i int, j int) bool { return alarms[i].time.Before(alarms[j].time) })
+	sort.SliceStable(cgmSettingsOccurrences, func(i int, j int) bool {
+		return cgmSettingsOccurrences[i].time.Before(cgmSettingsOccurrences[j].time)
+	})
+
+	report := &AlarmsReport{
+		StartTime:           startTime.Format(time.RFC3339Nano),
+		EndTime:             endTime.Format(time.RFC3339Nano),
+		Units:               dataBloodGlucose.MmolL,
+		Count:               len(alarms),
+		Types:               map[string]int{},
+		Daily:               []*AlarmDailyCount{},
+		Hourly:              make([]*AlarmHourlyCount, 24),
+		Bursts:              []*AlarmBurst{},
+		AlertConfigurations: []*AlarmAlertConfiguration{},
+	}
+	for hour := range report.Hourly {
+		report.Hourly[hour] = &AlarmHourlyCount{Hour: hour, Types: map[string]int{}}
+	}
+
+	daily := map[string]*AlarmDailyCount{}
+	alertConfigurations := map[string]*AlarmAlertConfiguration{}
+	var burst []*alarmOccurrence
+	for index, alarm := range alarms {
+		report.Types[alarm.alarmType]++
+
+		date := alarm.time.Format(DateFormat)
+		dailyCount, ok := daily[date]
+		if !ok {
+			dailyCount = &AlarmDailyCount{Date: date, Types: map[string]int{}}
+			daily[date] = dailyCount
+			report.Daily = append(report.Daily, dailyCount)
+		}
+		dailyCount.Count++
+		dailyCount.Types[alarm.alarmType]++
+
+		hourlyCount := report.Hourly[alarm.time.Hour()]
+		hourlyCount.Count++
+		hourlyCount.Types[alarm.alarmType]++
+
+		if index > 0 && alarm.time.Sub(alarms[index-1].time) > AlarmBurstGapMaximum {
+			report.Bursts = appendAlarmBurst(report.Bursts, burst)
+			burst = nil
+		}
+		burst = append(burst, alarm)
+
+		if alarm.alert != "" {
+			alertConfiguration := alarmAlertConfiguration(alarm, latestCGMSettings(cgmSettingsOccurrences, alarm.time))
+			key := alarmAlertConfigurationKey(alertConfiguration)
+			if existing, ok := alertConfigurations[key]; ok {
+				alertConfiguration = existing
+			} else {
+				alertConfigurations[key] = alertConfiguration
+				report.AlertConfigurations = append(report.AlertConfigurations, alertConfiguration)
+			}
+			alertConfiguration.Count++
+		}
+	}
+	report.Bursts = appendAlarmBurst(report.Bursts, burst)
+
+	sort.Slice(report.Daily, func(i int, j int) bool { return report.Daily[i].Date < report.Daily[j].Date })
+	sort.SliceStable(report.AlertConfigurations, func(i int, j int) bool {
+		return report.AlertConfigurations[i].Alert < report.AlertConfigurations[j].Alert
+	})
+	return report
+}
+
+func appendAlarmBurst(bursts []*AlarmBurst, alarms []*alarmOccurrence) []*AlarmBurst {
+	if len(alarms) < AlarmBurstCountMinimum {
+		return bursts
+	}
+	burst := &AlarmBurst{
+		StartTime: alarms[0].time.Format(time.RFC3339Nano),
+		EndTime:   alarms[len(alarms)-1].time.Format(time.RFC3339Nano),
+		Count:     len(alarms),
+		Types:     map[string]int{},
+	}
+	for _, alarm := range alarms {
+		burst.Types[alarm.alarmType]++
+	}
+	return append(bursts, burst)
+}
+
+func latestCGMSettings(cgmSettingsOccurrences []*cgmSettingsOccurrence, tm time.Time) *cgmSettingsOccurrence {
+	index := sort.Search(len(cgmSettingsOccurrences), func(index int) bool { return cgmSettingsOccurrences[index].time.After(tm) })
+	if index == 0 {
+		return nil
+	}
+	return cgmSettingsOccurrences[index-1]
+}
+
+func alarmAlertConfigurationKey(alertConfiguration *AlarmAlertConfiguration) string {
+	return strings.Join([]string{alertConfiguration.Alert, alertConfiguration.AlarmCode, pointer.ToString(alertConfiguration.CGMSettingsTime), pointer.ToString(alertConfiguration.CGMSettingsID), pointer.ToString(alertConfiguration.Schedule)}, "|")
+}
+
+// alarmAlertConfiguration returns the configuration of the level alert of the alarm in the CGM settings, if
+// any. The alerts of the first scheduled alerts active at the local time of the alarm apply, otherwise the default
+// alerts, otherwise the deprecated level alerts.
+func alarmAlertConfiguration(alarm *alarmOccurrence, occurrence *cgmSettingsOccurrence) *AlarmAlertConfiguration {
+	alertConfiguration := &AlarmAlertConfiguration{Alert: alarm.alert, AlarmCode: alarm.alarmCode}
+	if occurrence == nil {
+		return alertConfiguration
+	}
+	cgmSettings := occurrence.cgmSettings
+	alertConfiguration.CGMSettingsID = cgmSettings.ID
+	alertConfiguration.CGMSettingsTime = cgmSettings.Time
+
+	alerts := cgmSettings.DefaultAlerts
+	if scheduledAlert := activeScheduledAlert(cgmSettings.ScheduledAlerts, alarm.time); scheduledAlert != nil {
+		alertConfiguration.Schedule = scheduledAlert.Name
+		alerts = scheduledAlert.Alerts
+	}
+
+	if alerts != nil {
+		var levelAlert *dataTypesSettingsCgm.LevelAlert
+		switch alarm.alert {
+		case AlarmAlertHigh:
+			if alerts.High != nil {
+				levelAlert = &alerts.High.LevelAlert
+			}
+		case AlarmAlertLow:
+			if alerts.Low != nil {
+				levelAlert = &alerts.Low.LevelAlert
+			}
+		case AlarmAlertUrgentLow:
+			if alerts.UrgentLow != nil {
+				levelAlert = &alerts.UrgentLow.LevelAlert
+			}
+		}
+		if levelAlert != nil {
+			alertConfiguration.Enabled = pointer.FromBool(pointer.ToBool(alerts.Enabled) && pointer.ToBool(levelAlert.Enabled))
+			alertConfiguration.Level = dataBloodGlucose.NormalizeValueForUnits(levelAlert.Level, levelAlert.Units)
+			alertConfiguration.Snooze = snoozeMinutes(levelAlert.Snooze)
+		}
+		return alertConfiguration
+	}
+
+	var levelAlertDEPRECATED *dataTypesSettingsCgm.LevelAlertDEPRECATED
+	switch alarm.alert {
+	case AlarmAlertHigh:
+		if cgmSettings.HighLevelAlert != nil {
+			levelAlertDEPRECATED = &cgmSettings.HighLevelAlert.LevelAlertDEPRECATED
+		}
+	case AlarmAlertLow:
+		if cgmSettings.LowLevelAlert != nil {
+			levelAlertDEPRECATED = &cgmSettings.LowLevelAlert.LevelAlertDEPRECATED
+		}
+	}
+	if levelAlertDEPRECATED != nil {
+		alertConfiguration.Enabled = pointer.CloneBool(levelAlertDEPRECATED.Enabled)
+		alertConfiguration.Level = dataBloodGlucose.NormalizeValueForUnits(levelAlertDEPRECATED.Level, cgmSettings.Units)
+		if levelAlertDEPRECATED.Snooze != nil {
+			alertConfiguration.Snooze = pointer.FromFloat64(float64(time.Duration(*levelAlertDEPRECATED.Snooze)*time.Millisecond) / float64(time.Minute))
+		}
+	}
+	return alertConfiguration
+}
+
+// activeScheduledAlert returns the first scheduled alerts active at the local time, the start and end being the
+// milliseconds since the local midnight of the days. A schedule ending before it starts ends the following day.
+func activeScheduledAlert(scheduledAlerts *dataTypesSettingsCgm.ScheduledAlerts, tm time.Time) *dataTypesSettingsCgm.ScheduledAlert {
+	if scheduledAlerts == nil {
+		return nil
+	}
+	milliseconds := int(tm.Sub(time.Date(tm.Year(), tm.Month(), tm.Day(), 0, 0, 0, 0, tm.Location())) / time.Millisecond)
+	for _, scheduledAlert := range *scheduledAlerts {
+		if scheduledAlert == nil || scheduledAlert.Days == nil || scheduledAlert.Start == nil || scheduledAlert.End == nil {
+			continue
+		}
+		start := *scheduledAlert.Start
+		end := *scheduledAlert.End
+		var day time.Weekday
+		switch {
+		case start <= end && milliseconds >= start && milliseconds < end:
+			day = tm.Weekday()
+		case start > end && milliseconds >= start:
+			day = tm.Weekday()
+		case start > end && milliseconds < end:
+			day = tm.AddDate(0, 0, -1).Weekday()
+		default:
+			continue
+		}
+		for _, scheduledAlertDay := range *scheduledAlert.Days {
+			if scheduledAlertDay == strings.ToLower(day.String()) {
+				return scheduledAlert
+			}
+		}
+	}
+	return nil
+}
+
+func snoozeMinutes(snooze *dataTypesSettingsCgm.Snooze) *float64 {
+	if snooze == nil || snooze.Duration == nil || snooze.Units == nil {
+		return nil
+	}
+	switch *snooze.Units {
+	case dataTypesSettingsCgm.SnoozeUnitsHours:
+		return pointer.FromFloat64(*snooze.Duration * 60)
+	case dataTypesSettingsCgm.SnoozeUnitsMinutes:
+		return pointer.FromFloat64(*snooze.Duration)
+	case dataTypesSettingsCgm.SnoozeUnitsSeconds:
+		return pointer.FromFloat64(*snooze.Duration / 60)
+	}
+	return nil
+}
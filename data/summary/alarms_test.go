@@ -0,0 +1,188 @@
+package summary_test
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	dataBloodGlucose "github.com/tidepool-org/platform/data/blood/glucose"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesDeviceAlarm "github.com/tidepool-org/platform/data/types/device/alarm"
+	dataTypesSettingsCgm "github.com/tidepool-org/platform/data/types/settings/cgm"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/request"
+)
+
+func NewAlarm(tm string, alarmType string) *dataTypesDeviceAlarm.Alarm {
+	datum := dataTypesDeviceAlarm.New()
+	datum.Time = pointer.FromString(tm)
+	datum.AlarmType = pointer.FromString(alarmType)
+	return datum
+}
+
+func NewHandsetAlarm(tm string, alarmCode string) *dataTypesDeviceAlarm.Alarm {
+	datum := NewAlarm(tm, dataTypesDeviceAlarm.AlarmTypeHandset)
+	datum.AlarmCode = pointer.FromString(alarmCode)
+	return datum
+}
+
+func NewLowAlerts(enabled bool, level float64, units string) *dataTypesSettingsCgm.Alerts {
+	return &dataTypesSettingsCgm.Alerts{
+		Enabled: pointer.FromBool(true),
+		Low: &dataTypesSettingsCgm.LowAlert{LevelAlert: dataTypesSettingsCgm.LevelAlert{
+			Alert: dataTypesSettingsCgm.Alert{
+				Enabled: pointer.FromBool(enabled),
+				Snooze:  &dataTypesSettingsCgm.Snooze{Duration: pointer.FromFloat64(1), Units: pointer.FromString(dataTypesSettingsCgm.SnoozeUnitsHours)},
+			},
+			Level: pointer.FromFloat64(level),
+			Units: pointer.FromString(units),
+		}},
+	}
+}
+
+var _ = Describe("Alarms", func() {
+	var options *summary.AlarmsOptions
+	var startTime time.Time
+	var endTime time.Time
+
+	BeforeEach(func() {
+		options = summary.NewAlarmsOptions()
+		options.HighAlarmCodes = pointer.FromStringArray([]string{"20100"})
+		options.LowAlarmCodes = pointer.FromStringArray([]string{"10112", "10113"})
+		options.UrgentLowAlarmCodes = pointer.FromStringArray([]string{"10100"})
+		startTime = time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+		endTime = time.Date(2020, 3, 8, 0, 0, 0, 0, time.UTC)
+	})
+
+	Context("AlarmsOptions", func() {
+		decode := func(query string) (*summary.AlarmsOptions, error) {
+			options := summary.NewAlarmsOptions()
+			return options, request.DecodeRequestQuery(&http.Request{URL: &url.URL{RawQuery: query}}, options)
+		}
+
+		It("decodes the comma-separated alarm codes", func() {
+			options, err := decode("lowAlarmCode=10112,10113&highAlarmCode=20100")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(options.LowAlarmCodes).To(Equal(pointer.FromStringArray([]string{"10112", "10113"})))
+			Expect(options.HighAlarmCodes).To(Equal(pointer.FromStringArray([]string{"20100"})))
+			Expect(options.UrgentLowAlarmCodes).To(BeNil())
+		})
+
+		It("rejects duplicate alarm codes", func() {
+			_, err := decode("urgentLowAlarmCode=10100,10100")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	It("returns an empty report if there is no data", func() {
+		report := summary.CalculateAlarms(nil, options, startTime, endTime)
+		Expect(report.StartTime).To(Equal("2020-03-01T00:00:00Z"))
+		Expect(report.Units).To(Equal("mmol/L"))
+		Expect(report.Count).To(Equal(0))
+		Expect(report.Daily).To(BeEmpty())
+		Expect(report.Hourly).To(HaveLen(24))
+		Expect(report.Bursts).To(BeEmpty())
+		Expect(report.AlertConfigurations).To(BeEmpty())
+	})
+
+	It("counts the alarms by type per local day and per local hour of the day, ignoring those outside the range", func() {
+		datums := data.Data{
+			NewAlarm("2020-03-02T08:10:00Z", dataTypesDeviceAlarm.AlarmTypeOcclusion),
+			NewAlarm("2020-03-01T08:40:00Z", dataTypesDeviceAlarm.AlarmTypeLowInsulin),
+			NewAlarm("2020-03-01T20:00:00Z", dataTypesDeviceAlarm.AlarmTypeLowInsulin),
+			NewAlarm("2020-02-29T08:00:00Z", dataTypesDeviceAlarm.AlarmTypeOcclusion),
+		}
+		report := summary.CalculateAlarms(datums, options, startTime, endTime)
+		Expect(report.Count).To(Equal(3))
+		Expect(report.Types).To(Equal(map[string]int{"low_insulin": 2, "occlusion": 1}))
+		Expect(report.Daily).To(Equal([]*summary.AlarmDailyCount{
+			{Date: "2020-03-01", Count: 2, Types: map[string]int{"low_insulin": 2}},
+			{Date: "2020-03-02", Count: 1, Types: map[string]int{"occlusion": 1}},
+		}))
+		Expect(report.Hourly[8]).To(Equal(&summary.AlarmHourlyCount{Hour: 8, Count: 2, Types: map[string]int{"low_insulin": 1, "occlusion": 1}}))
+		Expect(report.Hourly[20].Count).To(Equal(1))
+		Expect(report.Hourly[0].Count).To(Equal(0))
+	})
+
+	It("finds the bursts of alarms each within the maximum gap of the previous", func() {
+		datums := data.Data{
+			NewAlarm("2020-03-01T01:00:00Z", dataTypesDeviceAlarm.AlarmTypeOcclusion),
+			NewAlarm("2020-03-01T01:25:00Z", dataTypesDeviceAlarm.AlarmTypeOcclusion),
+			NewHandsetAlarm("2020-03-01T01:50:00Z", "10100"),
+			NewAlarm("2020-03-01T03:00:00Z", dataTypesDeviceAlarm.AlarmTypeNoDelivery),
+			NewAlarm("2020-03-01T03:20:00Z", dataTypesDeviceAlarm.AlarmTypeNoDelivery),
+			NewAlarm("2020-03-01T04:00:00Z", dataTypesDeviceAlarm.AlarmTypeNoDelivery),
+		}
+		Expect(summary.CalculateAlarms(datums, options, startTime, endTime).Bursts).To(Equal([]*summary.AlarmBurst{
+			{StartTime: "2020-03-01T01:00:00Z", EndTime: "2020-03-01T01:50:00Z", Count: 3, Types: map[string]int{"occlusion": 2, "handset": 1}},
+		}))
+	})
+
+	Context("alert configurations", func() {
+		var older *dataTypesSettingsCgm.CGM
+		var latest *dataTypesSettingsCgm.CGM
+
+		BeforeEach(func() {
+			older = dataTypesSettingsCgm.New()
+			older.ID = pointer.FromString("older")
+			older.Time = pointer.FromString("2020-02-01T00:00:00Z")
+			older.Units = pointer.FromString(dataBloodGlucose.MmolL)
+			older.LowLevelAlert = &dataTypesSettingsCgm.LowLevelAlertDEPRECATED{LevelAlertDEPRECATED: dataTypesSettingsCgm.LevelAlertDEPRECATED{Enabled: pointer.FromBool(true), Level: pointer.FromFloat64(3.5), Snooze: pointer.FromInt(900000)}}
+			latest = dataTypesSettingsCgm.New()
+			latest.ID = pointer.FromString("latest")
+			latest.Time = pointer.FromString("2020-03-03T00:00:00Z")
+			latest.DefaultAlerts = NewLowAlerts(true, 70, dataTypesSettingsCgm.LevelAlertUnitsMgdL)
+			latest.ScheduledAlerts = &dataTypesSettingsCgm.ScheduledAlerts{
+				{
+					Name:   pointer.FromString("night"),
+					Days:   &[]string{"tuesday"},
+					Start:  pointer.FromInt(int(22 * time.Hour / time.Millisecond)),
+					End:    pointer.FromInt(int(6 * time.Hour / time.Millisecond)),
+					Alerts: NewLowAlerts(false, 3.0, dataTypesSettingsCgm.LevelAlertUnitsMmolL),
+				},
+			}
+		})
+
+		It("correlates the alarms with the alarm codes of the level alerts with the CGM settings active at the time", func() {
+			datums := data.Data{
+				latest,
+				older,
+				NewHandsetAlarm("2020-03-02T12:00:00Z", "10112"),
+				NewHandsetAlarm("2020-03-02T15:00:00Z", "10112"),
+				NewHandsetAlarm("2020-03-04T02:00:00Z", "10112"),
+				NewHandsetAlarm("2020-03-04T12:00:00Z", "10112"),
+				NewHandsetAlarm("2020-03-04T12:30:00Z", "30000"),
+				NewAlarm("2020-03-04T13:00:00Z", dataTypesDeviceAlarm.AlarmTypeOcclusion),
+			}
+			Expect(summary.CalculateAlarms(datums, options, startTime, endTime).AlertConfigurations).To(Equal([]*summary.AlarmAlertConfiguration{
+				{Alert: "low", AlarmCode: "10112", CGMSettingsID: pointer.FromString("older"), CGMSettingsTime: pointer.FromString("2020-02-01T00:00:00Z"), Enabled: pointer.FromBool(true), Level: pointer.FromFloat64(3.5), Snooze: pointer.FromFloat64(15), Count: 2},
+				{Alert: "low", AlarmCode: "10112", CGMSettingsID: pointer.FromString("latest"), CGMSettingsTime: pointer.FromString("2020-03-03T00:00:00Z"), Schedule: pointer.FromString("night"), Enabled: pointer.FromBool(false), Level: pointer.FromFloat64(3.0), Snooze: pointer.FromFloat64(60), Count: 1},
+				{Alert: "low", AlarmCode: "10112", CGMSettingsID: pointer.FromString("latest"), CGMSettingsTime: pointer.FromString("2020-03-03T00:00:00Z"), Enabled: pointer.FromBool(true), Level: dataBloodGlucose.NormalizeValueForUnits(pointer.FromFloat64(70), pointer.FromString(dataBloodGlucose.MgdL)), Snooze: pointer.FromFloat64(60), Count: 1},
+			}))
+		})
+
+		It("returns the alert alone if there are no CGM settings at the time", func() {
+			datums := data.Data{latest, NewHandsetAlarm("2020-03-02T12:00:00Z", "20100")}
+			Expect(summary.CalculateAlarms(datums, options, startTime, endTime).AlertConfigurations).To(Equal([]*summary.AlarmAlertConfiguration{
+				{Alert: "high", AlarmCode: "20100", Count: 1},
+			}))
+		})
+
+		It("converts the alert levels", func() {
+			datums := data.Data{latest, NewHandsetAlarm("2020-03-04T12:00:00Z", "10113")}
+			report := summary.CalculateAlarms(datums, options, startTime, endTime)
+			report.ConvertGlucoseUnits("mg/dL")
+			Expect(report.Units).To(Equal("mg/dL"))
+			Expect(*report.AlertConfigurations[0].Level).To(Equal(70.0))
+		})
+
+		It("correlates no alarm without the alarm codes of the level alerts", func() {
+			datums := data.Data{latest, NewHandsetAlarm("2020-03-04T12:00:00Z", "10112")}
+			Expect(summary.CalculateAlarms(datums, nil, startTime, endTime).AlertConfigurations).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,278 @@
+package summary
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/tidepool-org/platform/data"
+	dataBloodGlucose "github.com/tidepool-org/platform/data/blood/glucose"
+	dataTypesActivityPhysical "github.com/tidepool-org/platform/data/types/activity/physical"
+	dataTypesCalculator "github.com/tidepool-org/platform/data/types/calculator"
+	dataTypesCommon "github.com/tidepool-org/platform/data/types/common"
+	dataTypesDeviceAlarm "github.com/tidepool-org/platform/data/types/device/alarm"
+	dataTypesFood "github.com/tidepool-org/platform/data/types/food"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/request"
+	"github.com/tidepool-org/platform/structure"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
+)
+
+// The local time of a datum is at most 14 hours ahead of and 12 hours behind its UTC time
+const (
+	LocalTimeOffsetMaximum = 14 * time.Hour
+	LocalTimeOffsetMinimum = -12 * time.Hour
+
+	CarbohydrateGramsPerExchange = 15.0
+
+	// DailySummaryWindowDays is the most local dates whose daily summaries are calculated from the data read at once
+	DailySummaryWindowDays = 31
+)
+
+// DailySummary is the rollup of the data of a user for one local day. The carbohydrate, in grams, is the sum of the
+// food net carbohydrate and the bolus calculator carbohydrate input. The glucose stats use the default targets.
+type DailySummary struct {
+	UserID                  string             `json:"userId" bson:"userId"`
+	Date                    string             `json:"date" bson:"date"`
+	Glucose                 *GlucoseStats      `json:"glucose,omitempty" bson:"glucose,omitempty"`
+	Carbohydrate            float64            `json:"carbohydrate" bson:"carbohydrate"`
+	Insulin                 *InsulinDailyTotal `json:"insulin,omitempty" bson:"insulin,omitempty"`
+	PhysicalActivityMinutes float64            `json:"physicalActivityMinutes" bson:"physicalActivityMinutes"`
+	AlarmCount              int                `json:"alarmCount" bson:"alarmCount"`
+	UpdatedTime             string             `json:"updatedTime,omitempty" bson:"updatedTime,omitempty"`
+}
+
+// DailySummaryFilter selects the daily summaries of the local dates, formatted with DateFormat, between the start and
+// end dates, inclusive
+type DailySummaryFilter struct {
+	StartDate *string `json:"startDate,omitempty"`
+	EndDate   *string `json:"endDate,omitempty"`
+}
+
+func NewDailySummaryFilter() *DailySummaryFilter {
+	return &DailySummaryFilter{}
+}
+
+func (d *DailySummaryFilter) Parse(parser structure.ObjectParser) {
+	d.StartDate = parser.String("startDate")
+	d.EndDate = parser.String("endDate")
+}
+
+func (d *DailySummaryFilter) Validate(validator structure.Validator) {
+	validator.String("startDate", d.StartDate).AsTime(DateFormat)
+	validator.String("endDate", d.EndDate).AsTime(DateFormat)
+	if d.StartDate != nil && d.EndDate != nil && *d.EndDate < *d.StartDate {
+		validator.WithReference("endDate").ReportError(structureValidator.ErrorValueNotGreaterThanOrEqualTo(*d.EndDate, *d.StartDate))
+	}
+}
+
+// DailySummaryRebuildFilter selects the pending rebuilds of daily summaries requested before the time
+type DailySummaryRebuildFilter struct {
+	RequestedBefore *time.Time
+}
+
+func NewDailySummaryRebuildFilter() *DailySummaryRebuildFilter {
+	return &DailySummaryRebuildFilter{}
+}
+
+func (d *DailySummaryRebuildFilter) Parse(parser structure.ObjectParser) {
+	d.RequestedBefore = parser.Time("requestedBefore", time.RFC3339Nano)
+}
+
+func (d *DailySummaryRebuildFilter) Validate(validator structure.Validator) {
+	validator.Time("requestedBefore", d.RequestedBefore).NotZero()
+}
+
+func (d *DailySummaryRebuildFilter) MutateRequest(req *http.Request) error {
+	parameters := map[string]string{}
+	if d.RequestedBefore != nil {
+		parameters["requestedBefore"] = d.RequestedBefore.Format(time.RFC3339Nano)
+	}
+	return request.NewParametersMutator(parameters).MutateRequest(req)
+}
+
+// DailySummaryRebuildResult is the count of the pending rebuilds of daily summaries that were rebuilt or failed
+type DailySummaryRebuildResult struct {
+	Rebuilt int `json:"rebuilt"`
+	Failed  int `json:"failed"`
+}
+
+// DailySummaryDifference is a daily summary that is missing, unexpected or different when comparing the stored daily
+// summaries with the ones calculated from the data
+type DailySummaryDifference struct {
+	Date     string        `json:"date"`
+	Expected *DailySummary `json:"expected,omitempty"`
+	Actual   *DailySummary `json:"actual,omitempty"`
+}
+
+// DefaultGlucoseStatsOptions returns the normalized default glucose stats options
+func DefaultGlucoseStatsOptions() *GlucoseStatsOptions {
+	return &GlucoseStatsOptions{
+		Units:          pointer.FromString(dataBloodGlucose.MmolL),
+		VeryLow:        pointer.FromFloat64(GlucoseVeryLowDefault),
+		Low:            pointer.FromFloat64(GlucoseLowDefault),
+		High:           pointer.FromFloat64(GlucoseHighDefault),
+		VeryHigh:       pointer.FromFloat64(GlucoseVeryHighDefault),
+		SampleInterval: pointer.FromInt(GlucoseSampleIntervalDefault),
+	}
+}
+
+// DailySummaryDates returns the first and last local dates of the data with a UTC time between the start and end times
+func DailySummaryDates(startTime time.Time, endTime time.Time) (string, string) {
+	return startTime.UTC().Add(LocalTimeOffsetMinimum).Format(DateFormat), endTime.UTC().Add(LocalTimeOffsetMaximum).Format(DateFormat)
+}
+
+// DailySummaryTimeRange returns the range of UTC times of the data that may fall on the local dates between the start
+// and end dates, inclusive
+func DailySummaryTimeRange(startDate string, endDate string) (time.Time, time.Time, error) {
+	startTime, err := time.Parse(DateFormat, startDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	endTime, err := time.Parse(DateFormat, endDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return startTime.Add(-LocalTimeOffsetMaximum), endTime.AddDate(0, 0, 1).Add(-LocalTimeOffsetMinimum), nil
+}
+
+// DailySummaryWindow is a range of local dates, inclusive
+type DailySummaryWindow struct {
+	StartDate string
+	EndDate   string
+}
+
+// DailySummaryWindows splits the local dates between the start and end dates, inclusive, into consecutive windows of
+// at most DailySummaryWindowDays dates, so that the data of a long range is not read at once
+func DailySummaryWindows(startDate string, endDate string) ([]DailySummaryWindow, error) {
+	startTime, err := time.Parse(DateFormat, startDate)
+	if err != nil {
+		return nil, err
+	}
+	endTime, err := time.Parse(DateFormat, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	windows := []DailySummaryWindow{}
+	for windowStartTime := startTime; !windowStartTime.After(endTime); windowStartTime = windowStartTime.AddDate(0, 0, DailySummaryWindowDays) {
+		windowEndTime := windowStartTime.AddDate(0, 0, DailySummaryWindowDays-1)
+		if windowEndTime.After(endTime) {
+			windowEndTime = endTime
+		}
+		windows = append(windows, DailySummaryWindow{StartDate: windowStartTime.Format(DateFormat), EndDate: windowEndTime.Format(DateFormat)})
+	}
+	return windows, nil
+}
+
+// CalculateDailySummaries returns the daily summaries of the user for each local date between the start and end dates,
+// inclusive, with data, ordered by date. The datums must include all data within DailySummaryTimeRange of the dates.
+func CalculateDailySummaries(userID string, datums data.Data, startDate string, endDate string) []*DailySummary {
+	startTime, endTime, err := DailySummaryTimeRange(startDate, endDate)
+	if err != nil {
+		return []*DailySummary{}
+	}
+
+	summaries := map[string]*DailySummary{}
+	summary := func(date string) *DailySummary {
+		result, ok := summaries[date]
+		if !ok {
+			result = &DailySummary{UserID: userID, Date: date}
+			summaries[date] = result
+		}
+		return result
+	}
+
+	for _, glucoseStats := range CalculateGlucoseStats(datums, DefaultGlucoseStatsOptions(), startTime, endTime).Daily {
+		summary(glucoseStats.Date).Glucose = glucoseStats
+	}
+	for _, insulinDailyTotal := range CalculateInsulinDailyTotals(datums, startTime, endTime) {
+		summary(insulinDailyTotal.Date).Insulin = insulinDailyTotal
+	}
+	for _, datum := range datums {
+		switch typed := datum.(type) {
+		case *dataTypesFood.Food:
+			if date, ok := LocalDate(&typed.Base); ok && typed.Nutrition != nil && typed.Nutrition.Carbohydrate != nil && typed.Nutrition.Carbohydrate.Net != nil {
+				summary(date).Carbohydrate += *typed.Nutrition.Carbohydrate.Net
+			}
+		case *dataTypesCalculator.Calculator:
+			if date, ok := LocalDate(&typed.Base); ok && typed.CarbohydrateInput != nil {
+				if typed.CarbUnits != nil && *typed.CarbUnits == dataTypesCalculator.Exchanges {
+					summary(date).Carbohydrate += *typed.CarbohydrateInput * CarbohydrateGramsPerExchange
+				} else {
+					summary(date).Carbohydrate += *typed.CarbohydrateInput
+				}
+			}
+		case *dataTypesActivityPhysical.Physical:
+			if date, ok := LocalDate(&typed.Base); ok && typed.Duration != nil {
+				if minutes := durationInMinutes(typed.Duration); minutes != nil {
+					summary(date).PhysicalActivityMinutes += *minutes
+				}
+			}
+		case *dataTypesDeviceAlarm.Alarm:
+			if date, ok := LocalDate(&typed.Base); ok {
+				summary(date).AlarmCount++
+			}
+		}
+	}
+
+	result := []*DailySummary{}
+	for date, dailySummary := range summaries {
+		if date >= startDate && date <= endDate {
+			result = append(result, dailySummary)
+		}
+	}
+	sort.Slice(result, func(i int, j int) bool { return result[i].Date < result[j].Date })
+	return result
+}
+
+// CompareDailySummaries returns the differences between the expected and actual daily summaries, ignoring the
+// updated time, ordered by date
+func CompareDailySummaries(expected []*DailySummary, actual []*DailySummary) []*DailySummaryDifference {
+	expectedByDate := map[string]*DailySummary{}
+	for _, dailySummary := range expected {
+		expectedByDate[dailySummary.Date] = dailySummary
+	}
+	actualByDate := map[string]*DailySummary{}
+	for _, dailySummary := range actual {
+		actualByDate[dailySummary.Date] = dailySummary
+	}
+
+	differences := []*DailySummaryDifference{}
+	for date, expectedSummary := range expectedByDate {
+		if actualSummary, ok := actualByDate[date]; !ok || !equalDailySummaries(expectedSummary, actualSummary) {
+			differences = append(differences, &DailySummaryDifference{Date: date, Expected: expectedSummary, Actual: actualSummary})
+		}
+	}
+	for date, actualSummary := range actualByDate {
+		if _, ok := expectedByDate[date]; !ok {
+			differences = append(differences, &DailySummaryDifference{Date: date, Actual: actualSummary})
+		}
+	}
+	sort.Slice(differences, func(i int, j int) bool { return differences[i].Date < differences[j].Date })
+	return differences
+}
+
+func equalDailySummaries(expected *DailySummary, actual *DailySummary) bool {
+	expectedCopy := *expected
+	actualCopy := *actual
+	expectedCopy.UpdatedTime = ""
+	actualCopy.UpdatedTime = ""
+	return reflect.DeepEqual(&expectedCopy, &actualCopy)
+}
+
+func durationInMinutes(duration *dataTypesCommon.Duration) *float64 {
+	if duration.Value == nil || duration.Units == nil {
+		return nil
+	}
+	switch *duration.Units {
+	case dataTypesCommon.DurationUnitsHours:
+		return pointer.FromFloat64(*duration.Value * 60)
+	case dataTypesCommon.DurationUnitsMinutes:
+		return pointer.FromFloat64(*duration.Value)
+	case dataTypesCommon.DurationUnitsSeconds:
+		return pointer.FromFloat64(*duration.Value / 60)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package summary_test
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesActivityPhysical "github.com/tidepool-org/platform/data/types/activity/physical"
+	dataTypesBolusNormal "github.com/tidepool-org/platform/data/types/bolus/normal"
+	dataTypesCalculator "github.com/tidepool-org/platform/data/types/calculator"
+	dataTypesCommon "github.com/tidepool-org/platform/data/types/common"
+	dataTypesDeviceAlarm "github.com/tidepool-org/platform/data/types/device/alarm"
+	dataTypesFood "github.com/tidepool-org/platform/data/types/food"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/request"
+)
+
+func DecodeDailySummaryFilter(query string) (*summary.DailySummaryFilter, error) {
+	filter := summary.NewDailySummaryFilter()
+	req := &http.Request{URL: &url.URL{RawQuery: query}}
+	return filter, request.DecodeRequestQuery(req, filter)
+}
+
+var _ = Describe("Daily", func() {
+	Context("DailySummaryFilter", func() {
+		It("accepts the start and end dates", func() {
+			filter, err := DecodeDailySummaryFilter("startDate=2020-03-01&endDate=2020-03-31")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*filter.StartDate).To(Equal("2020-03-01"))
+			Expect(*filter.EndDate).To(Equal("2020-03-31"))
+		})
+
+		It("rejects a malformed date", func() {
+			_, err := DecodeDailySummaryFilter("startDate=2020-03-01T00:00:00Z")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("rejects an end date before the start date", func() {
+			_, err := DecodeDailySummaryFilter("startDate=2020-03-02&endDate=2020-03-01")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("DailySummaryRebuildFilter", func() {
+		It("accepts the requested before time", func() {
+			filter := summary.NewDailySummaryRebuildFilter()
+			req := &http.Request{URL: &url.URL{RawQuery: "requestedBefore=2020-03-01T08:00:00Z"}}
+			Expect(request.DecodeRequestQuery(req, filter)).To(Succeed())
+			Expect(*filter.RequestedBefore).To(Equal(time.Date(2020, 3, 1, 8, 0, 0, 0, time.UTC)))
+		})
+
+		It("mutates the request with the requested before time", func() {
+			filter := summary.NewDailySummaryRebuildFilter()
+			filter.RequestedBefore = pointer.FromTime(time.Date(2020, 3, 1, 8, 0, 0, 0, time.UTC))
+			req := &http.Request{URL: &url.URL{}}
+			Expect(filter.MutateRequest(req)).To(Succeed())
+			Expect(req.URL.RawQuery).To(Equal("requestedBefore=2020-03-01T08%3A00%3A00Z"))
+		})
+	})
+
+	Context("DailySummaryDates", func() {
+		It("returns the local dates of the times in any time zone", func() {
+			startDate, endDate := summary.DailySummaryDates(time.Date(2020, 3, 1, 8, 0, 0, 0, time.UTC), time.Date(2020, 3, 2, 9, 0, 0, 0, time.UTC))
+			Expect(startDate).To(Equal("2020-02-29"))
+			Expect(endDate).To(Equal("2020-03-02"))
+		})
+	})
+
+	Context("DailySummaryTimeRange", func() {
+		It("returns the times of the local dates in any time zone", func() {
+			startTime, endTime, err := summary.DailySummaryTimeRange("2020-03-01", "2020-03-02")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(startTime).To(Equal(time.Date(2020, 2, 29, 10, 0, 0, 0, time.UTC)))
+			Expect(endTime).To(Equal(time.Date(2020, 3, 3, 12, 0, 0, 0, time.UTC)))
+		})
+
+		It("returns an error if a date is malformed", func() {
+			_, _, err := summary.DailySummaryTimeRange("2020-03-01", "invalid")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("DailySummaryWindows", func() {
+		It("returns a single window for a short range", func() {
+			Expect(summary.DailySummaryWindows("2020-03-01", "2020-03-02")).To(Equal([]summary.DailySummaryWindow{
+				{StartDate: "2020-03-01", EndDate: "2020-03-02"},
+			}))
+		})
+
+		It("splits a long range into consecutive windows", func() {
+			Expect(summary.DailySummaryWindows("2020-01-01", "2020-03-05")).To(Equal([]summary.DailySummaryWindow{
+				{StartDate: "2020-01-01", EndDate: "2020-01-31"},
+				{StartDate: "2020-02-01", EndDate: "2020-03-02"},
+				{StartDate: "2020-03-03", EndDate: "2020-03-05"},
+			}))
+		})
+
+		It("returns no windows if the end date is before the start date", func() {
+			Expect(summary.DailySummaryWindows("2020-03-02", "2020-03-01")).To(BeEmpty())
+		})
+
+		It("returns an error if a date is malformed", func() {
+			_, err := summary.DailySummaryWindows("invalid", "2020-03-01")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("CalculateDailySummaries", func() {
+		It("returns no daily summaries if there is no data", func() {
+			Expect(summary.CalculateDailySummaries("user", nil, "2020-03-01", "2020-03-02")).To(BeEmpty())
+		})
+
+		It("returns the daily summaries of the dates with data, ordered by date", func() {
+			food := dataTypesFood.New()
+			food.Time = pointer.FromString("2020-03-02T12:00:00Z")
+			food.Nutrition = &dataTypesFood.Nutrition{Carbohydrate: &dataTypesFood.Carbohydrate{Net: pointer.FromFloat64(30)}}
+			calculator := dataTypesCalculator.New()
+			calculator.Time = pointer.FromString("2020-03-02T18:00:00Z")
+			calculator.CarbohydrateInput = pointer.FromFloat64(2)
+			calculator.CarbUnits = pointer.FromString(dataTypesCalculator.Exchanges)
+			physical := dataTypesActivityPhysical.New()
+			physical.Time = pointer.FromString("2020-03-01T10:00:00Z")
+			physical.Duration = &dataTypesCommon.Duration{Units: pointer.FromString(dataTypesCommon.DurationUnitsHours), Value: pointer.FromFloat64(1.5)}
+			alarm := dataTypesDeviceAlarm.New()
+			alarm.Time = pointer.FromString("2020-03-02T03:00:00Z")
+			alarm.TimeZoneOffset = pointer.FromInt(-300)
+			normal := dataTypesBolusNormal.New()
+			normal.Time = pointer.FromString("2020-03-02T08:00:00Z")
+			normal.Normal = pointer.FromFloat64(3)
+			outside := dataTypesDeviceAlarm.New()
+			outside.Time = pointer.FromString("2020-03-03T08:00:00Z")
+			datums := data.Data{
+				food, calculator, physical, alarm, normal, outside,
+				NewContinuous("2020-03-01T09:00:00Z", "mmol/L", 5),
+			}
+
+			dailySummaries := summary.CalculateDailySummaries("user", datums, "2020-03-01", "2020-03-02")
+			Expect(dailySummaries).To(HaveLen(2))
+
+			first := dailySummaries[0]
+			Expect(first.UserID).To(Equal("user"))
+			Expect(first.Date).To(Equal("2020-03-01"))
+			Expect(first.Glucose).ToNot(BeNil())
+			Expect(first.Glucose.Count).To(Equal(1))
+			Expect(first.Carbohydrate).To(Equal(0.0))
+			Expect(first.PhysicalActivityMinutes).To(Equal(90.0))
+			Expect(first.AlarmCount).To(Equal(1))
+			Expect(first.Insulin).To(BeNil())
+
+			second := dailySummaries[1]
+			Expect(second.Date).To(Equal("2020-03-02"))
+			Expect(second.Glucose).To(BeNil())
+			Expect(second.Carbohydrate).To(Equal(60.0))
+			Expect(second.AlarmCount).To(Equal(0))
+			Expect(second.Insulin).ToNot(BeNil())
+			Expect(second.Insulin.Bolus).To(Equal(3.0))
+		})
+	})
+
+	Context("CompareDailySummaries", func() {
+		It("returns no differences if the daily summaries are equal, ignoring the updated time", func() {
+			expected := []*summary.DailySummary{{UserID: "user", Date: "2020-03-01", AlarmCount: 1}}
+			actual := []*summary.DailySummary{{UserID: "user", Date: "2020-03-01", AlarmCount: 1, UpdatedTime: "2020-03-02T00:00:00Z"}}
+			Expect(summary.CompareDailySummaries(expected, actual)).To(BeEmpty())
+		})
+
+		It("returns the missing, unexpected and different daily summaries, ordered by date", func() {
+			missing := &summary.DailySummary{UserID: "user", Date: "2020-03-03"}
+			unexpected := &summary.DailySummary{UserID: "user", Date: "2020-03-01"}
+			differentExpected := &summary.DailySummary{UserID: "user", Date: "2020-03-02", Carbohydrate: 30}
+			differentActual := &summary.DailySummary{UserID: "user", Date: "2020-03-02", Carbohydrate: 20}
+			Expect(summary.CompareDailySummaries([]*summary.DailySummary{differentExpected, missing}, []*summary.DailySummary{unexpected, differentActual})).To(Equal([]*summary.DailySummaryDifference{
+				{Date: "2020-03-01", Actual: unexpected},
+				{Date: "2020-03-02", Expected: differentExpected, Actual: differentActual},
+				{Date: "2020-03-03", Expected: missing},
+			}))
+		})
+	})
+})
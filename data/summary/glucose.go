@@ -0,0 +1,254 @@
+package summary
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/tidepool-org/platform/data"
+	dataBloodGlucose "github.com/tidepool-org/platform/data/blood/glucose"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/structure"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
+)
+
+// Default glucose targets, in mmol/L, of the international consensus on time in range
+const (
+	GlucoseVeryLowDefault  = 3.0
+	GlucoseLowDefault      = 3.9
+	GlucoseHighDefault     = 10.0
+	GlucoseVeryHighDefault = 13.9
+
+	GlucoseSampleIntervalDefault = 5
+	GlucoseSampleIntervalMaximum = 60
+	GlucoseSampleIntervalMinimum = 1
+)
+
+// GlucoseStatsOptions are the glucose targets and the expected interval between two continuous glucose readings,
+// in minutes, used to calculate the glucose stats. Once normalized, the targets are in mmol/L and all options are set.
+type GlucoseStatsOptions struct {
+	Units          *string  `json:"units,omitempty"`
+	VeryLow        *float64 `json:"veryLow,omitempty"`
+	Low            *float64 `json:"low,omitempty"`
+	High           *float64 `json:"high,omitempty"`
+	VeryHigh       *float64 `json:"veryHigh,omitempty"`
+	SampleInterval *int     `json:"sampleInterval,omitempty"`
+}
+
+func NewGlucoseStatsOptions() *GlucoseStatsOptions {
+	return &GlucoseStatsOptions{}
+}
+
+func (g *GlucoseStatsOptions) Parse(parser structure.ObjectParser) {
+	g.Units = parser.String("units")
+	g.VeryLow = parser.Float64("veryLow")
+	g.Low = parser.Float64("low")
+	g.High = parser.Float64("high")
+	g.VeryHigh = parser.Float64("veryHigh")
+	g.SampleInterval = parser.Int("sampleInterval")
+}
+
+func (g *GlucoseStatsOptions) Validate(validator structure.Validator) {
+	if g.VeryLow != nil || g.Low != nil || g.High != nil || g.VeryHigh != nil {
+		validator.String("units", g.Units).Exists().OneOf(dataBloodGlucose.Units()...)
+	} else if g.Units != nil {
+		validator.String("units", g.Units).OneOf(dataBloodGlucose.Units()...)
+	}
+	minimum, maximum := dataBloodGlucose.ValueRangeForUnits(g.Units)
+	validator.Float64("veryLow", g.VeryLow).InRange(minimum, maximum)
+	validator.Float64("low", g.Low).InRange(minimum, maximum)
+	validator.Float64("high", g.High).InRange(minimum, maximum)
+	validator.Float64("veryHigh", g.VeryHigh).InRange(minimum, maximum)
+	validator.Int("sampleInterval", g.SampleInterval).InRange(GlucoseSampleIntervalMinimum, GlucoseSampleIntervalMaximum)
+}
+
+// Normalize converts the targets to mmol/L, defaults any missing option, and then ensures the targets are ordered
+func (g *GlucoseStatsOptions) Normalize(normalizer structure.Normalizer) {
+	g.VeryLow = normalizeGlucoseTarget(g.VeryLow, g.Units, GlucoseVeryLowDefault)
+	g.Low = normalizeGlucoseTarget(g.Low, g.Units, GlucoseLowDefault)
+	g.High = normalizeGlucoseTarget(g.High, g.Units, GlucoseHighDefault)
+	g.VeryHigh = normalizeGlucoseTarget(g.VeryHigh, g.Units, GlucoseVeryHighDefault)
+	g.Units = pointer.FromString(dataBloodGlucose.MmolL)
+	if g.SampleInterval == nil {
+		g.SampleInterval = pointer.FromInt(GlucoseSampleIntervalDefault)
+	}
+
+	if *g.Low < *g.VeryLow {
+		normalizer.WithReference("low").ReportError(structureValidator.ErrorValueNotGreaterThanOrEqualTo(*g.Low, *g.VeryLow))
+	}
+	if *g.High <= *g.Low {
+		normalizer.WithReference("high").ReportError(structureValidator.ErrorValueNotGreaterThan(*g.High, *g.Low))
+	}
+	if *g.VeryHigh < *g.High {
+		normalizer.WithReference("veryHigh").ReportError(structureValidator.ErrorValueNotGreaterThanOrEqualTo(*g.VeryHigh, *g.High))
+	}
+}
+
+func normalizeGlucoseTarget(value *float64, units *string, defaultValue float64) *float64 {
+	if value == nil {
+		return pointer.FromFloat64(defaultValue)
+	}
+	return dataBloodGlucose.NormalizeValueForUnits(value, units)
+}
+
+// GlucoseStats are the continuous glucose metrics of a period, with glucose values in mmol/L and times as a
+// percentage of the readings. The time below range includes the time very low and the time above range includes
+// the time very high. The sensor wear is the percentage of the expected readings in the period actually received.
+type GlucoseStats struct {
+	Date           string   `json:"date,omitempty" bson:"date,omitempty"`
+	Count          int      `json:"count" bson:"count"`
+	Mean           *float64 `json:"mean,omitempty" bson:"mean,omitempty"`
+	GMI            *float64 `json:"gmi,omitempty" bson:"gmi,omitempty"`
+	CV             *float64 `json:"cv,omitempty" bson:"cv,omitempty"`
+	TimeVeryLow    *float64 `json:"timeVeryLow,omitempty" bson:"timeVeryLow,omitempty"`
+	TimeBelowRange *float64 `json:"timeBelowRange,omitempty" bson:"timeBelowRange,omitempty"`
+	TimeInRange    *float64 `json:"timeInRange,omitempty" bson:"timeInRange,omitempty"`
+	TimeAboveRange *float64 `json:"timeAboveRange,omitempty" bson:"timeAboveRange,omitempty"`
+	TimeVeryHigh   *float64 `json:"timeVeryHigh,omitempty" bson:"timeVeryHigh,omitempty"`
+	SensorWear     float64  `json:"sensorWear" bson:"sensorWear"`
+}
+
+// GlucoseStatsReport holds the glucose stats for the whole range and for each local day of the range with readings,
+// and optionally for each segment of the local days
+type GlucoseStatsReport struct {
+	StartTime string                 `json:"startTime"`
+	EndTime   string                 `json:"endTime"`
+	Units     string                 `json:"units"`
+	Options   *GlucoseStatsOptions   `json:"options"`
+	Range     *GlucoseStats          `json:"range"`
+	Daily     []*GlucoseStats        `json:"daily"`
+	Segments  []*GlucoseStatsSegment `json:"segments,omitempty"`
+}
+
+type glucoseStatsAccumulator struct {
+	date     string
+	dayStart time.Time
+	values   []float64
+}
+
+// CalculateGlucoseStats calculates the glucose stats of the continuous glucose datums between the start and end
+// times, ignoring any other datums. The options must be normalized.
+func CalculateGlucoseStats(datums data.Data, options *GlucoseStatsOptions, startTime time.Time, endTime time.Time) *GlucoseStatsReport {
+	values, accumulators := accumulateGlucoseStats(datums, startTime, endTime)
+
+	sampleInterval := time.Duration(*options.SampleInterval) * time.Minute
+
+	daily := make([]*GlucoseStats, 0, len(accumulators))
+	for _, accumulator := range accumulators {
+		dayStartTime := maximumTime(accumulator.dayStart, startTime)
+		dayEndTime := minimumTime(accumulator.dayStart.AddDate(0, 0, 1), endTime)
+		stats := calculateGlucoseStats(accumulator.values, options, dayEndTime.Sub(dayStartTime), sampleInterval)
+		stats.Date = accumulator.date
+		daily = append(daily, stats)
+	}
+	sort.Slice(daily, func(i int, j int) bool { return daily[i].Date < daily[j].Date })
+
+	return &GlucoseStatsReport{
+		StartTime: startTime.Format(time.RFC3339Nano),
+		EndTime:   endTime.Format(time.RFC3339Nano),
+		Units:     dataBloodGlucose.MmolL,
+		Options:   options,
+		Range:     calculateGlucoseStats(values, options, endTime.Sub(startTime), sampleInterval),
+		Daily:     daily,
+	}
+}
+
+func accumulateGlucoseStats(datums data.Data, startTime time.Time, endTime time.Time) ([]float64, map[string]*glucoseStatsAccumulator) {
+	values := []float64{}
+	accumulators := map[string]*glucoseStatsAccumulator{}
+	for _, datum := range datums {
+		continuous, ok := datum.(*dataTypesBloodGlucoseContinuous.Continuous)
+		if !ok {
+			continue
+		}
+		value := dataBloodGlucose.NormalizeValueForUnits(continuous.Value, continuous.Units)
+		if value == nil {
+			continue
+		}
+		tm, ok := LocalTime(&continuous.Base)
+		if !ok || tm.Before(startTime) || tm.After(endTime) {
+			continue
+		}
+
+		date := tm.Format(DateFormat)
+		accumulator, ok := accumulators[date]
+		if !ok {
+			accumulator = &glucoseStatsAccumulator{
+				date:     date,
+				dayStart: time.Date(tm.Year(), tm.Month(), tm.Day(), 0, 0, 0, 0, tm.Location()),
+			}
+			accumulators[date] = accumulator
+		}
+		accumulator.values = append(accumulator.values, *value)
+		values = append(values, *value)
+	}
+	return values, accumulators
+}
+
+func calculateGlucoseStats(values []float64, options *GlucoseStatsOptions, duration time.Duration, sampleInterval time.Duration) *GlucoseStats {
+	stats := &GlucoseStats{Count: len(values)}
+	if stats.Count == 0 {
+		return stats
+	}
+
+	var sum float64
+	var veryLow, belowRange, inRange, aboveRange, veryHigh int
+	for _, value := range values {
+		sum += value
+		switch {
+		case value < *options.Low:
+			belowRange++
+			if value < *options.VeryLow {
+				veryLow++
+			}
+		case value > *options.High:
+			aboveRange++
+			if value > *options.VeryHigh {
+				veryHigh++
+			}
+		default:
+			inRange++
+		}
+	}
+	count := float64(stats.Count)
+	mean := sum / count
+
+	var squares float64
+	for _, value := range values {
+		squares += (value - mean) * (value - mean)
+	}
+	standardDeviation := math.Sqrt(squares / count)
+
+	stats.Mean = pointer.FromFloat64(mean)
+	stats.GMI = pointer.FromFloat64(GlucoseManagementIndicator(mean))
+	stats.CV = pointer.FromFloat64(standardDeviation / mean * 100)
+	stats.TimeVeryLow = pointer.FromFloat64(float64(veryLow) / count * 100)
+	stats.TimeBelowRange = pointer.FromFloat64(float64(belowRange) / count * 100)
+	stats.TimeInRange = pointer.FromFloat64(float64(inRange) / count * 100)
+	stats.TimeAboveRange = pointer.FromFloat64(float64(aboveRange) / count * 100)
+	stats.TimeVeryHigh = pointer.FromFloat64(float64(veryHigh) / count * 100)
+	if expected := float64(duration) / float64(sampleInterval); expected > 0 {
+		stats.SensorWear = math.Min(count/expected*100, 100)
+	}
+	return stats
+}
+
+// GlucoseManagementIndicator returns the GMI, as a percentage, of the mean glucose in mmol/L
+func GlucoseManagementIndicator(mean float64) float64 {
+	return 3.31 + 0.43056*mean
+}
+
+func minimumTime(a time.Time, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maximumTime(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
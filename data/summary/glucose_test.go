@@ -0,0 +1,176 @@
+package summary_test
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	dataTypesFood "github.com/tidepool-org/platform/data/types/food"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/request"
+)
+
+func NewContinuous(tm string, units string, value float64) *dataTypesBloodGlucoseContinuous.Continuous {
+	datum := dataTypesBloodGlucoseContinuous.New()
+	datum.Time = pointer.FromString(tm)
+	datum.Units = pointer.FromString(units)
+	datum.Value = pointer.FromFloat64(value)
+	return datum
+}
+
+func DecodeGlucoseStatsOptions(query string) (*summary.GlucoseStatsOptions, error) {
+	options := summary.NewGlucoseStatsOptions()
+	req := &http.Request{URL: &url.URL{RawQuery: query}}
+	return options, request.DecodeRequestQuery(req, options)
+}
+
+var _ = Describe("Glucose", func() {
+	It("GlucoseManagementIndicator returns the expected value", func() {
+		Expect(summary.GlucoseManagementIndicator(8.6)).To(BeNumerically("~", 7.01, 0.01))
+	})
+
+	Context("GlucoseStatsOptions", func() {
+		It("defaults the options in mmol/L", func() {
+			options, err := DecodeGlucoseStatsOptions("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(options).To(Equal(&summary.GlucoseStatsOptions{
+				Units:          pointer.FromString("mmol/L"),
+				VeryLow:        pointer.FromFloat64(3.0),
+				Low:            pointer.FromFloat64(3.9),
+				High:           pointer.FromFloat64(10.0),
+				VeryHigh:       pointer.FromFloat64(13.9),
+				SampleInterval: pointer.FromInt(5),
+			}))
+		})
+
+		It("normalizes the targets to mmol/L", func() {
+			options, err := DecodeGlucoseStatsOptions("units=mg/dL&low=70&high=180&sampleInterval=15")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*options.Units).To(Equal("mmol/L"))
+			Expect(*options.VeryLow).To(Equal(3.0))
+			Expect(*options.Low).To(BeNumerically("~", 3.8855, 0.0001))
+			Expect(*options.High).To(BeNumerically("~", 9.9913, 0.0001))
+			Expect(*options.VeryHigh).To(Equal(13.9))
+			Expect(*options.SampleInterval).To(Equal(15))
+		})
+
+		It("returns an error if a target is specified without units", func() {
+			_, err := DecodeGlucoseStatsOptions("low=4")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns an error if the units are invalid", func() {
+			_, err := DecodeGlucoseStatsOptions("units=invalid")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns an error if a target is out of range for the units", func() {
+			_, err := DecodeGlucoseStatsOptions("units=mmol/L&high=56")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns an error if the sample interval is out of range", func() {
+			_, err := DecodeGlucoseStatsOptions("sampleInterval=0")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns an error if the targets are not ordered once defaulted", func() {
+			_, err := DecodeGlucoseStatsOptions("units=mmol/L&low=11")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns an error if an unknown parameter is specified", func() {
+			_, err := DecodeGlucoseStatsOptions("unknown=1")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("CalculateGlucoseStats", func() {
+		var options *summary.GlucoseStatsOptions
+		var startTime time.Time
+		var endTime time.Time
+
+		BeforeEach(func() {
+			var err error
+			options, err = DecodeGlucoseStatsOptions("sampleInterval=60")
+			Expect(err).ToNot(HaveOccurred())
+			startTime = time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+			endTime = time.Date(2020, 3, 3, 0, 0, 0, 0, time.UTC)
+		})
+
+		It("returns empty stats if there is no data", func() {
+			report := summary.CalculateGlucoseStats(nil, options, startTime, endTime)
+			Expect(report).To(Equal(&summary.GlucoseStatsReport{
+				StartTime: "2020-03-01T12:00:00Z",
+				EndTime:   "2020-03-03T00:00:00Z",
+				Units:     "mmol/L",
+				Options:   options,
+				Range:     &summary.GlucoseStats{},
+				Daily:     []*summary.GlucoseStats{},
+			}))
+		})
+
+		It("returns the stats for the range and each local day", func() {
+			local := NewContinuous("2020-03-02T02:00:00Z", "mmol/L", 5)
+			local.TimeZoneOffset = pointer.FromInt(-240)
+			food := dataTypesFood.New()
+			food.Time = pointer.FromString("2020-03-01T13:00:00Z")
+			datums := data.Data{
+				NewContinuous("2020-03-01T11:00:00Z", "mmol/L", 20),
+				NewContinuous("2020-03-01T13:00:00Z", "mmol/L", 2),
+				NewContinuous("2020-03-01T14:00:00Z", "mmol/L", 3.5),
+				food,
+				NewContinuous("2020-03-01T15:00:00Z", "mg/dL", 108.09354),
+				local,
+				NewContinuous("2020-03-02T12:00:00Z", "mmol/L", 12),
+				NewContinuous("2020-03-02T13:00:00Z", "mmol/L", 15),
+			}
+
+			report := summary.CalculateGlucoseStats(datums, options, startTime, endTime)
+			Expect(report.Daily).To(HaveLen(2))
+
+			first := report.Daily[0]
+			Expect(first.Date).To(Equal("2020-03-01"))
+			Expect(first.Count).To(Equal(4))
+			Expect(*first.Mean).To(BeNumerically("~", 4.125, 0.0001))
+			Expect(*first.TimeVeryLow).To(Equal(25.0))
+			Expect(*first.TimeBelowRange).To(Equal(50.0))
+			Expect(*first.TimeInRange).To(Equal(50.0))
+			Expect(*first.TimeAboveRange).To(Equal(0.0))
+			Expect(*first.TimeVeryHigh).To(Equal(0.0))
+			Expect(first.SensorWear).To(BeNumerically("~", 4.0/12.0*100, 0.0001))
+
+			second := report.Daily[1]
+			Expect(second.Date).To(Equal("2020-03-02"))
+			Expect(second.Count).To(Equal(2))
+			Expect(*second.Mean).To(Equal(13.5))
+			Expect(*second.CV).To(BeNumerically("~", 1.5/13.5*100, 0.0001))
+			Expect(*second.GMI).To(BeNumerically("~", summary.GlucoseManagementIndicator(13.5), 0.0001))
+			Expect(*second.TimeAboveRange).To(Equal(100.0))
+			Expect(*second.TimeVeryHigh).To(Equal(50.0))
+			Expect(second.SensorWear).To(BeNumerically("~", 2.0/24.0*100, 0.0001))
+
+			Expect(report.Range.Date).To(BeEmpty())
+			Expect(report.Range.Count).To(Equal(6))
+			Expect(*report.Range.Mean).To(BeNumerically("~", 7.25, 0.0001))
+			Expect(*report.Range.TimeBelowRange + *report.Range.TimeInRange + *report.Range.TimeAboveRange).To(BeNumerically("~", 100, 0.0001))
+			Expect(report.Range.SensorWear).To(BeNumerically("~", 6.0/36.0*100, 0.0001))
+		})
+
+		It("limits the sensor wear to 100 percent", func() {
+			options.SampleInterval = pointer.FromInt(1)
+			datums := data.Data{}
+			for minute := 0; minute < 5; minute++ {
+				datums = append(datums, NewContinuous(startTime.Add(time.Duration(minute)*30*time.Second).Format(time.RFC3339Nano), "mmol/L", 5))
+			}
+			report := summary.CalculateGlucoseStats(datums, options, startTime, startTime.Add(2*time.Minute))
+			Expect(report.Range.SensorWear).To(Equal(100.0))
+		})
+	})
+})
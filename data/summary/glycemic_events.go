@@ -0,0 +1,314 @@
+package summary
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tidepool-org/platform/data"
+	dataBloodGlucose "github.com/tidepool-org/platform/data/blood/glucose"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	dataTypesSettingsCgm "github.com/tidepool-org/platform/data/types/settings/cgm"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/structure"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
+)
+
+// Default glycemic event thresholds, in mmol/L, and minimum durations, in minutes, of the international consensus on
+// the CGM metrics: a level 1 or 2 low lasts at least 15 minutes, and a prolonged high at least 120 minutes
+const (
+	GlycemicEventLowDurationDefault  = 15
+	GlycemicEventHighDurationDefault = 120
+	GlycemicEventDurationMaximum     = 24 * 60
+	GlycemicEventDurationMinimum     = 5
+
+	GlycemicEventsRangeDefault = 14 * 24 * time.Hour
+
+	// GlycemicEventRecoveryDuration is how long the readings must be back across the threshold for an event to end
+	GlycemicEventRecoveryDuration = 15 * time.Minute
+	// GlycemicEventGapMaximum is the longest interval between two readings within an event, a longer one ending it
+	GlycemicEventGapMaximum = 30 * time.Minute
+
+	GlycemicEventTypeLow     = "low"
+	GlycemicEventTypeVeryLow = "veryLow"
+	GlycemicEventTypeHigh    = "high"
+)
+
+func GlycemicEventTypes() []string {
+	return []string{
+		GlycemicEventTypeLow,
+		GlycemicEventTypeVeryLow,
+		GlycemicEventTypeHigh,
+	}
+}
+
+// GlycemicEventsOptions are the thresholds and minimum durations, in minutes, of the glycemic events. A threshold not
+// set is that of the enabled level alert of the latest CGM settings, if any, or else the consensus default. Once
+// normalized, the thresholds set are in mmol/L.
+type GlycemicEventsOptions struct {
+	Units        *string  `json:"units,omitempty"`
+	Low          *float64 `json:"low,omitempty"`
+	VeryLow      *float64 `json:"veryLow,omitempty"`
+	High         *float64 `json:"high,omitempty"`
+	LowDuration  *int     `json:"lowDuration,omitempty"`
+	HighDuration *int     `json:"highDuration,omitempty"`
+}
+
+func NewGlycemicEventsOptions() *GlycemicEventsOptions {
+	return &GlycemicEventsOptions{}
+}
+
+func (g *GlycemicEventsOptions) Parse(parser structure.ObjectParser) {
+	g.Units = parser.String("units")
+	g.Low = parser.Float64("low")
+	g.VeryLow = parser.Float64("veryLow")
+	g.High = parser.Float64("high")
+	g.LowDuration = parser.Int("lowDuration")
+	g.HighDuration = parser.Int("highDuration")
+}
+
+func (g *GlycemicEventsOptions) Validate(validator structure.Validator) {
+	if g.Low != nil || g.VeryLow != nil || g.High != nil {
+		validator.String("units", g.Units).Exists().OneOf(dataBloodGlucose.Units()...)
+	} else if g.Units != nil {
+		validator.String("units", g.Units).OneOf(dataBloodGlucose.Units()...)
+	}
+	minimum, maximum := dataBloodGlucose.ValueRangeForUnits(g.Units)
+	validator.Float64("low", g.Low).InRange(minimum, maximum)
+	validator.Float64("veryLow", g.VeryLow).InRange(minimum, maximum)
+	validator.Float64("high", g.High).InRange(minimum, maximum)
+	validator.Int("lowDuration", g.LowDuration).InRange(GlycemicEventDurationMinimum, GlycemicEventDurationMaximum)
+	validator.Int("highDuration", g.HighDuration).InRange(GlycemicEventDurationMinimum, GlycemicEventDurationMaximum)
+}
+
+// Normalize converts the thresholds set to mmol/L
+func (g *GlycemicEventsOptions) Normalize(normalizer structure.Normalizer) {
+	g.Low = dataBloodGlucose.NormalizeValueForUnits(g.Low, g.Units)
+	g.VeryLow = dataBloodGlucose.NormalizeValueForUnits(g.VeryLow, g.Units)
+	g.High = dataBloodGlucose.NormalizeValueForUnits(g.High, g.Units)
+	g.Units = pointer.FromString(dataBloodGlucose.MmolL)
+
+	if g.Low != nil && g.VeryLow != nil && *g.Low < *g.VeryLow {
+		normalizer.WithReference("low").ReportError(structureValidator.ErrorValueNotGreaterThanOrEqualTo(*g.Low, *g.VeryLow))
+	}
+	if g.High != nil && g.Low != nil && *g.High <= *g.Low {
+		normalizer.WithReference("high").ReportError(structureValidator.ErrorValueNotGreaterThan(*g.High, *g.Low))
+	}
+}
+
+// GlycemicEventReading is the nadir of a low or the peak of a high, in mmol/L
+type GlycemicEventReading struct {
+	Time  string  `json:"time"`
+	Value float64 `json:"value"`
+}
+
+// GlycemicEvent is a period of continuous glucose readings below the low threshold, or above the high threshold, for
+// at least the minimum duration. The event ends at the first reading back across the threshold for the recovery
+// duration or, without it, at the last reading of the event. The duration is in minutes.
+type GlycemicEvent struct {
+	Type      string                `json:"type"`
+	StartTime string                `json:"startTime"`
+	EndTime   string                `json:"endTime"`
+	Duration  float64               `json:"duration"`
+	Nadir     *GlycemicEventReading `json:"nadir,omitempty"`
+	Peak      *GlycemicEventReading `json:"peak,omitempty"`
+}
+
+// GlycemicEventsReport holds the glycemic events, ordered by start time, with the options actually used. A very low
+// event is also part of a low event.
+type GlycemicEventsReport struct {
+	StartTime string                 `json:"startTime"`
+	EndTime   string                 `json:"endTime"`
+	Options   *GlycemicEventsOptions `json:"options"`
+	Events    []*GlycemicEvent       `json:"events"`
+}
+
+// CalculateGlycemicEvents detects the glycemic events of the continuous glucose datums between the start and end
+// times, taking any threshold not set in the options from the latest CGM settings datum. The options must be
+// normalized.
+func CalculateGlycemicEvents(datums data.Data, options *GlycemicEventsOptions, startTime time.Time, endTime time.Time) *GlycemicEventsReport {
+	readings := []*glycemicEventReading{}
+	var cgmSettings *dataTypesSettingsCgm.CGM
+	var cgmSettingsTime time.Time
+	for _, datum := range datums {
+		switch typed := datum.(type) {
+		case *dataTypesBloodGlucoseContinuous.Continuous:
+			value := dataBloodGlucose.NormalizeValueForUnits(typed.Value, typed.Units)
+			if value == nil || typed.Time == nil {
+				continue
+			}
+			tm, err := time.Parse(data.TimeFormat, *typed.Time)
+			if err != nil || tm.Before(startTime) || tm.After(endTime) {
+				continue
+			}
+			readings = append(readings, &glycemicEventReading{time: tm, value: *value})
+		case *dataTypesSettingsCgm.CGM:
+			if typed.Time == nil {
+				continue
+			}
+			tm, err := time.Parse(data.TimeFormat, *typed.Time)
+			if err != nil || tm.After(endTime) || (cgmSettings != nil && tm.Before(cgmSettingsTime)) {
+				continue
+			}
+			cgmSettings = typed
+			cgmSettingsTime = tm
+		}
+	}
+	sort.SliceStable(readings, func(i int, j int) bool { return readings[i].time.Before(readings[j].time) })
+
+	options = resolveGlycemicEventsOptions(options, cgmSettings)
+	lowDuration := time.Duration(*options.LowDuration) * time.Minute
+	highDuration := time.Duration(*options.HighDuration) * time.Minute
+
+	events := []*GlycemicEvent{}
+	events = append(events, detectGlycemicEvents(readings, GlycemicEventTypeLow, func(value float64) bool { return value < *options.Low }, lowDuration)...)
+	events = append(events, detectGlycemicEvents(readings, GlycemicEventTypeVeryLow, func(value float64) bool { return value < *options.VeryLow }, lowDuration)...)
+	events = append(events, detectGlycemicEvents(readings, GlycemicEventTypeHigh, func(value float64) bool { return value > *options.High }, highDuration)...)
+	sort.SliceStable(events, func(i int, j int) bool { return events[i].StartTime < events[j].StartTime })
+
+	return &GlycemicEventsReport{
+		StartTime: startTime.Format(time.RFC3339Nano),
+		EndTime:   endTime.Format(time.RFC3339Nano),
+		Options:   options,
+		Events:    events,
+	}
+}
+
+// CGMSettingsLevelAlertThresholds returns the levels, in mmol/L, of the enabled low, urgent low and high alerts of the
+// CGM settings, preferring the default alerts to the deprecated ones
+func CGMSettingsLevelAlertThresholds(cgmSettings *dataTypesSettingsCgm.CGM) (low *float64, veryLow *float64, high *float64) {
+	if cgmSettings == nil {
+		return nil, nil, nil
+	}
+	if alerts := cgmSettings.DefaultAlerts; alerts != nil && pointer.ToBool(alerts.Enabled) {
+		if alerts.Low != nil {
+			low = levelAlertThreshold(&alerts.Low.LevelAlert)
+		}
+		if alerts.UrgentLow != nil {
+			veryLow = levelAlertThreshold(&alerts.UrgentLow.LevelAlert)
+		}
+		if alerts.High != nil {
+			high = levelAlertThreshold(&alerts.High.LevelAlert)
+		}
+	}
+	if low == nil && cgmSettings.LowLevelAlert != nil && pointer.ToBool(cgmSettings.LowLevelAlert.Enabled) {
+		low = dataBloodGlucose.NormalizeValueForUnits(cgmSettings.LowLevelAlert.Level, cgmSettings.Units)
+	}
+	if high == nil && cgmSettings.HighLevelAlert != nil && pointer.ToBool(cgmSettings.HighLevelAlert.Enabled) {
+		high = dataBloodGlucose.NormalizeValueForUnits(cgmSettings.HighLevelAlert.Level, cgmSettings.Units)
+	}
+	return low, veryLow, high
+}
+
+func levelAlertThreshold(levelAlert *dataTypesSettingsCgm.LevelAlert) *float64 {
+	if !pointer.ToBool(levelAlert.Enabled) {
+		return nil
+	}
+	return dataBloodGlucose.NormalizeValueForUnits(levelAlert.Level, levelAlert.Units)
+}
+
+func resolveGlycemicEventsOptions(options *GlycemicEventsOptions, cgmSettings *dataTypesSettingsCgm.CGM) *GlycemicEventsOptions {
+	low, veryLow, high := CGMSettingsLevelAlertThresholds(cgmSettings)
+	resolved := &GlycemicEventsOptions{
+		Units:        pointer.FromString(dataBloodGlucose.MmolL),
+		Low:          firstFloat64(options.Low, low, pointer.FromFloat64(GlucoseLowDefault)),
+		VeryLow:      firstFloat64(options.VeryLow, veryLow, pointer.FromFloat64(GlucoseVeryLowDefault)),
+		High:         firstFloat64(options.High, high, pointer.FromFloat64(GlucoseVeryHighDefault)),
+		LowDuration:  options.LowDuration,
+		HighDuration: options.HighDuration,
+	}
+	if resolved.LowDuration == nil {
+		resolved.LowDuration = pointer.FromInt(GlycemicEventLowDurationDefault)
+	}
+	if resolved.HighDuration == nil {
+		resolved.HighDuration = pointer.FromInt(GlycemicEventHighDurationDefault)
+	}
+	return resolved
+}
+
+func firstFloat64(values ...*float64) *float64 {
+	for _, value := range values {
+		if value != nil {
+			return pointer.CloneFloat64(value)
+		}
+	}
+	return nil
+}
+
+type glycemicEventReading struct {
+	time  time.Time
+	value float64
+}
+
+type glycemicEventCandidate struct {
+	start         *glycemicEventReading
+	last          *glycemicEventReading
+	extreme       *glycemicEventReading
+	recoveryStart *glycemicEventReading
+}
+
+// detectGlycemicEvents returns the events of the ordered readings within the condition for at least the duration
+func detectGlycemicEvents(readings []*glycemicEventReading, eventType string, within func(value float64) bool, duration time.Duration) []*GlycemicEvent {
+	events := []*GlycemicEvent{}
+	var candidate *glycemicEventCandidate
+	var previous *glycemicEventReading
+
+	closeCandidate := func() {
+		if candidate == nil {
+			return
+		}
+		end := candidate.last
+		if candidate.recoveryStart != nil {
+			end = candidate.recoveryStart
+		}
+		if end.time.Sub(candidate.start.time) >= duration {
+			event := &GlycemicEvent{
+				Type:      eventType,
+				StartTime: candidate.start.time.Format(time.RFC3339Nano),
+				EndTime:   end.time.Format(time.RFC3339Nano),
+				Duration:  end.time.Sub(candidate.start.time).Minutes(),
+			}
+			extreme := &GlycemicEventReading{Time: candidate.extreme.time.Format(time.RFC3339Nano), Value: candidate.extreme.value}
+			if eventType == GlycemicEventTypeHigh {
+				event.Peak = extreme
+			} else {
+				event.Nadir = extreme
+			}
+			events = append(events, event)
+		}
+		candidate = nil
+	}
+
+	for _, reading := range readings {
+		if candidate != nil && previous != nil && reading.time.Sub(previous.time) > GlycemicEventGapMaximum {
+			closeCandidate()
+		}
+		previous = reading
+
+		if within(reading.value) {
+			if candidate == nil {
+				candidate = &glycemicEventCandidate{start: reading, extreme: reading}
+			} else if isMoreExtreme(eventType, reading.value, candidate.extreme.value) {
+				candidate.extreme = reading
+			}
+			candidate.last = reading
+			candidate.recoveryStart = nil
+		} else if candidate != nil {
+			if candidate.recoveryStart == nil {
+				candidate.recoveryStart = reading
+			}
+			if reading.time.Sub(candidate.recoveryStart.time) >= GlycemicEventRecoveryDuration {
+				closeCandidate()
+			}
+		}
+	}
+	closeCandidate()
+
+	return events
+}
+
+func isMoreExtreme(eventType string, value float64, extreme float64) bool {
+	if eventType == GlycemicEventTypeHigh {
+		return value > extreme
+	}
+	return value < extreme
+}
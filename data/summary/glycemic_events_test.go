@@ -0,0 +1,170 @@
+package summary_test
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	dataBloodGlucose "github.com/tidepool-org/platform/data/blood/glucose"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesSettingsCgm "github.com/tidepool-org/platform/data/types/settings/cgm"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/request"
+)
+
+func NewContinuousSeries(startTime time.Time, values ...float64) data.Data {
+	datums := data.Data{}
+	for index, value := range values {
+		tm := startTime.Add(time.Duration(index) * 5 * time.Minute).Format(time.RFC3339Nano)
+		datum := NewContinuous(tm, "mmol/L", value)
+		datum.ID = pointer.FromString(tm)
+		datums = append(datums, datum)
+	}
+	return datums
+}
+
+func DecodeGlycemicEventsOptions(query string) (*summary.GlycemicEventsOptions, error) {
+	options := summary.NewGlycemicEventsOptions()
+	req := &http.Request{URL: &url.URL{RawQuery: query}}
+	return options, request.DecodeRequestQuery(req, options)
+}
+
+var _ = Describe("GlycemicEvents", func() {
+	Context("GlycemicEventsOptions", func() {
+		It("leaves the options not set unset", func() {
+			options, err := DecodeGlycemicEventsOptions("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(options.Low).To(BeNil())
+			Expect(options.High).To(BeNil())
+			Expect(options.LowDuration).To(BeNil())
+		})
+
+		It("converts the thresholds to mmol/L", func() {
+			options, err := DecodeGlycemicEventsOptions("units=mg/dL&low=72&high=180&lowDuration=20")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*options.Units).To(Equal("mmol/L"))
+			Expect(*options.Low).To(BeNumerically("~", 4.0, 0.01))
+			Expect(*options.High).To(BeNumerically("~", 10.0, 0.01))
+			Expect(*options.LowDuration).To(Equal(20))
+		})
+
+		It("rejects thresholds without units", func() {
+			_, err := DecodeGlycemicEventsOptions("low=4")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("rejects a low threshold below the very low threshold", func() {
+			_, err := DecodeGlycemicEventsOptions("units=mmol/L&low=3&veryLow=3.5")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("rejects a duration out of range", func() {
+			_, err := DecodeGlycemicEventsOptions("highDuration=2000")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("CalculateGlycemicEvents", func() {
+		var startTime time.Time
+		var endTime time.Time
+
+		BeforeEach(func() {
+			startTime = time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+			endTime = time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC)
+		})
+
+		It("returns no events and the default options if there is no data", func() {
+			report := summary.CalculateGlycemicEvents(nil, summary.NewGlycemicEventsOptions(), startTime, endTime)
+			Expect(report.StartTime).To(Equal("2020-03-01T00:00:00Z"))
+			Expect(report.EndTime).To(Equal("2020-03-02T00:00:00Z"))
+			Expect(report.Events).To(BeEmpty())
+			Expect(report.Options).To(Equal(&summary.GlycemicEventsOptions{
+				Units:        pointer.FromString("mmol/L"),
+				Low:          pointer.FromFloat64(3.9),
+				VeryLow:      pointer.FromFloat64(3.0),
+				High:         pointer.FromFloat64(13.9),
+				LowDuration:  pointer.FromInt(15),
+				HighDuration: pointer.FromInt(120),
+			}))
+		})
+
+		It("returns a low ending at the first reading of the recovery, with its nadir", func() {
+			datums := NewContinuousSeries(startTime.Add(time.Hour), 5, 3.5, 3.4, 3.2, 3.6, 3.7, 4.5, 4.6, 4.7, 4.8, 5)
+			report := summary.CalculateGlycemicEvents(datums, summary.NewGlycemicEventsOptions(), startTime, endTime)
+			Expect(report.Events).To(Equal([]*summary.GlycemicEvent{{
+				Type:      "low",
+				StartTime: "2020-03-01T01:05:00Z",
+				EndTime:   "2020-03-01T01:30:00Z",
+				Duration:  25,
+				Nadir:     &summary.GlycemicEventReading{Time: "2020-03-01T01:15:00Z", Value: 3.2},
+			}}))
+		})
+
+		It("ignores a low shorter than the minimum duration", func() {
+			datums := NewContinuousSeries(startTime.Add(time.Hour), 5, 3.5, 3.4, 5, 5, 5, 5)
+			Expect(summary.CalculateGlycemicEvents(datums, summary.NewGlycemicEventsOptions(), startTime, endTime).Events).To(BeEmpty())
+		})
+
+		It("merges the lows separated by a recovery shorter than the recovery duration", func() {
+			datums := NewContinuousSeries(startTime.Add(time.Hour), 3.5, 3.5, 4.5, 4.5, 3.5, 3.5, 4.5, 4.5, 4.5, 4.5)
+			events := summary.CalculateGlycemicEvents(datums, summary.NewGlycemicEventsOptions(), startTime, endTime).Events
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].StartTime).To(Equal("2020-03-01T01:00:00Z"))
+			Expect(events[0].EndTime).To(Equal("2020-03-01T01:30:00Z"))
+		})
+
+		It("ends an event at a gap in the readings", func() {
+			datums := append(NewContinuousSeries(startTime.Add(time.Hour), 3.5, 3.5, 3.5, 3.5), NewContinuousSeries(startTime.Add(2*time.Hour), 3.5, 3.5)...)
+			events := summary.CalculateGlycemicEvents(datums, summary.NewGlycemicEventsOptions(), startTime, endTime).Events
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].EndTime).To(Equal("2020-03-01T01:15:00Z"))
+		})
+
+		It("returns a very low within a low, and a prolonged high with its peak, ordered by start time", func() {
+			highs := make([]float64, 26)
+			for index := range highs {
+				highs[index] = 15
+			}
+			highs[10] = 18
+			datums := append(NewContinuousSeries(startTime.Add(6*time.Hour), highs...), NewContinuousSeries(startTime.Add(time.Hour), 3.5, 2.8, 2.7, 2.9, 2.9, 3.5, 5, 5, 5, 5)...)
+			events := summary.CalculateGlycemicEvents(datums, summary.NewGlycemicEventsOptions(), startTime, endTime).Events
+			Expect(events).To(HaveLen(3))
+			Expect(events[0].Type).To(Equal("low"))
+			Expect(events[0].Duration).To(Equal(30.0))
+			Expect(events[1].Type).To(Equal("veryLow"))
+			Expect(events[1].StartTime).To(Equal("2020-03-01T01:05:00Z"))
+			Expect(events[1].EndTime).To(Equal("2020-03-01T01:25:00Z"))
+			Expect(events[1].Nadir.Value).To(Equal(2.7))
+			Expect(events[2].Type).To(Equal("high"))
+			Expect(events[2].Duration).To(Equal(125.0))
+			Expect(events[2].Nadir).To(BeNil())
+			Expect(events[2].Peak).To(Equal(&summary.GlycemicEventReading{Time: "2020-03-01T06:50:00Z", Value: 18}))
+		})
+
+		It("uses the thresholds of the enabled level alerts of the latest CGM settings, unless in the options", func() {
+			older := dataTypesSettingsCgm.New()
+			older.Time = pointer.FromString("2020-02-01T00:00:00Z")
+			older.LowLevelAlert = &dataTypesSettingsCgm.LowLevelAlertDEPRECATED{LevelAlertDEPRECATED: dataTypesSettingsCgm.LevelAlertDEPRECATED{Enabled: pointer.FromBool(true), Level: pointer.FromFloat64(60)}}
+			older.Units = pointer.FromString(dataBloodGlucose.MgdL)
+			latest := dataTypesSettingsCgm.New()
+			latest.Time = pointer.FromString("2020-02-15T00:00:00Z")
+			latest.DefaultAlerts = &dataTypesSettingsCgm.Alerts{
+				Enabled:   pointer.FromBool(true),
+				Low:       &dataTypesSettingsCgm.LowAlert{LevelAlert: dataTypesSettingsCgm.LevelAlert{Alert: dataTypesSettingsCgm.Alert{Enabled: pointer.FromBool(true)}, Level: pointer.FromFloat64(80), Units: pointer.FromString("mg/dL")}},
+				UrgentLow: &dataTypesSettingsCgm.UrgentLowAlert{LevelAlert: dataTypesSettingsCgm.LevelAlert{Alert: dataTypesSettingsCgm.Alert{Enabled: pointer.FromBool(false)}, Level: pointer.FromFloat64(50), Units: pointer.FromString("mg/dL")}},
+				High:      &dataTypesSettingsCgm.HighAlert{LevelAlert: dataTypesSettingsCgm.LevelAlert{Alert: dataTypesSettingsCgm.Alert{Enabled: pointer.FromBool(true)}, Level: pointer.FromFloat64(11), Units: pointer.FromString("mmol/L")}},
+			}
+			options := summary.NewGlycemicEventsOptions()
+			options.High = pointer.FromFloat64(12)
+
+			report := summary.CalculateGlycemicEvents(data.Data{latest, older}, options, startTime, endTime)
+			Expect(*report.Options.Low).To(BeNumerically("~", 4.44, 0.01))
+			Expect(*report.Options.VeryLow).To(Equal(3.0))
+			Expect(*report.Options.High).To(Equal(12.0))
+		})
+	})
+})
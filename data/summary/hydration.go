@@ -0,0 +1,50 @@
+package summary
+
+import (
+	"sort"
+
+	"github.com/tidepool-org/platform/data"
+	dataTypesWater "github.com/tidepool-org/platform/data/types/water"
+)
+
+// HydrationDailyTotal is the total water intake, in milliliters, of one local day
+type HydrationDailyTotal struct {
+	Date  string  `json:"date"`
+	Count int     `json:"count"`
+	Total float64 `json:"total"`
+	Units string  `json:"units"`
+}
+
+// HydrationDailyTotals sums the water datums per local day, ignoring any other datums, ordered by date
+func HydrationDailyTotals(datums data.Data) []*HydrationDailyTotal {
+	totals := map[string]*HydrationDailyTotal{}
+	for _, datum := range datums {
+		water, ok := datum.(*dataTypesWater.Water)
+		if !ok || water.Amount == nil {
+			continue
+		}
+		value := water.Amount.ValueInMilliliters()
+		if value == nil {
+			continue
+		}
+		date, ok := LocalDate(&water.Base)
+		if !ok {
+			continue
+		}
+
+		total, ok := totals[date]
+		if !ok {
+			total = &HydrationDailyTotal{Date: date, Units: dataTypesWater.AmountUnitsMilliliters}
+			totals[date] = total
+		}
+		total.Count++
+		total.Total += *value
+	}
+
+	result := make([]*HydrationDailyTotal, 0, len(totals))
+	for _, total := range totals {
+		result = append(result, total)
+	}
+	sort.Slice(result, func(i int, j int) bool { return result[i].Date < result[j].Date })
+	return result
+}
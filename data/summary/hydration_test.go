@@ -0,0 +1,51 @@
+package summary_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesFood "github.com/tidepool-org/platform/data/types/food"
+	dataTypesWater "github.com/tidepool-org/platform/data/types/water"
+	"github.com/tidepool-org/platform/pointer"
+)
+
+func NewWater(tm string, units string, value float64) *dataTypesWater.Water {
+	datum := dataTypesWater.New()
+	datum.Time = pointer.FromString(tm)
+	datum.Amount = dataTypesWater.NewAmount()
+	datum.Amount.Units = pointer.FromString(units)
+	datum.Amount.Value = pointer.FromFloat64(value)
+	return datum
+}
+
+var _ = Describe("Hydration", func() {
+	Context("HydrationDailyTotals", func() {
+		It("returns an empty array if there is no data", func() {
+			Expect(summary.HydrationDailyTotals(nil)).To(BeEmpty())
+		})
+
+		It("returns the daily totals ordered by date", func() {
+			food := dataTypesFood.New()
+			food.Time = pointer.FromString("2020-03-01T10:00:00Z")
+			local := NewWater("2020-03-02T02:00:00Z", "milliliters", 100)
+			local.TimeZoneOffset = pointer.FromInt(-240)
+			withoutAmount := NewWater("2020-03-01T11:00:00Z", "milliliters", 100)
+			withoutAmount.Amount = nil
+			datums := data.Data{
+				NewWater("2020-03-02T12:00:00Z", "liters", 0.5),
+				NewWater("2020-03-01T08:00:00Z", "milliliters", 250),
+				food,
+				NewWater("2020-03-01T09:00:00Z", "liters", 1),
+				local,
+				withoutAmount,
+				NewWater("2020-03-01T10:00:00Z", "invalid", 1),
+			}
+			Expect(summary.HydrationDailyTotals(datums)).To(Equal([]*summary.HydrationDailyTotal{
+				{Date: "2020-03-01", Count: 3, Total: 1350, Units: "milliliters"},
+				{Date: "2020-03-02", Count: 1, Total: 500, Units: "milliliters"},
+			}))
+		})
+	})
+})
@@ -0,0 +1,301 @@
+package summary
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tidepool-org/platform/data"
+	"github.com/tidepool-org/platform/data/types"
+	dataTypesBasalAutomated "github.com/tidepool-org/platform/data/types/basal/automated"
+	dataTypesBasalScheduled "github.com/tidepool-org/platform/data/types/basal/scheduled"
+	dataTypesBasalSuspend "github.com/tidepool-org/platform/data/types/basal/suspend"
+	dataTypesBasalTemporary "github.com/tidepool-org/platform/data/types/basal/temporary"
+	dataTypesBolusBiphasic "github.com/tidepool-org/platform/data/types/bolus/biphasic"
+	dataTypesBolusCombination "github.com/tidepool-org/platform/data/types/bolus/combination"
+	dataTypesBolusExtended "github.com/tidepool-org/platform/data/types/bolus/extended"
+	dataTypesBolusNormal "github.com/tidepool-org/platform/data/types/bolus/normal"
+	dataTypesBolusPen "github.com/tidepool-org/platform/data/types/bolus/pen"
+	dataTypesSettingsPump "github.com/tidepool-org/platform/data/types/settings/pump"
+	"github.com/tidepool-org/platform/pointer"
+)
+
+const (
+	InsulinActionDurationDefault = 4 * time.Hour
+	InsulinUnits                 = "Units"
+	InsulinOnBoardInterval       = 15 * time.Minute
+	InsulinRangeDefault          = 14 * 24 * time.Hour
+)
+
+// InsulinDailyTotal is the insulin, in units, delivered during one local day. The basal insulin is the integral of
+// the rate of the basal segments over their duration, a suspend delivering no insulin. The expected bolus insulin is
+// the amount programmed, which is greater than the delivered amount when the bolus was interrupted.
+type InsulinDailyTotal struct {
+	Date           string   `json:"date" bson:"date"`
+	Total          float64  `json:"total" bson:"total"`
+	Basal          float64  `json:"basal" bson:"basal"`
+	BasalScheduled float64  `json:"basalScheduled" bson:"basalScheduled"`
+	BasalTemporary float64  `json:"basalTemporary" bson:"basalTemporary"`
+	BasalAutomated float64  `json:"basalAutomated" bson:"basalAutomated"`
+	BasalSuspended int      `json:"basalSuspended" bson:"basalSuspended"`
+	Bolus          float64  `json:"bolus" bson:"bolus"`
+	BolusExpected  float64  `json:"bolusExpected" bson:"bolusExpected"`
+	BolusCount     int      `json:"bolusCount" bson:"bolusCount"`
+	BolusNormal    float64  `json:"bolusNormal" bson:"bolusNormal"`
+	BolusExtended  float64  `json:"bolusExtended" bson:"bolusExtended"`
+	BasalPercent   *float64 `json:"basalPercent,omitempty" bson:"basalPercent,omitempty"`
+	BolusPercent   *float64 `json:"bolusPercent,omitempty" bson:"bolusPercent,omitempty"`
+}
+
+// InsulinOnBoard is the insulin, in units, still active at the time
+type InsulinOnBoard struct {
+	Time  string  `json:"time"`
+	Value float64 `json:"value"`
+}
+
+// InsulinReport holds the insulin daily totals, ordered by date, and the insulin on board series, every 15 minutes
+// of the range, and optionally the mean daily totals for each segment of the local days
+type InsulinReport struct {
+	StartTime             string               `json:"startTime"`
+	EndTime               string               `json:"endTime"`
+	Units                 string               `json:"units"`
+	InsulinActionDuration int                  `json:"insulinActionDuration"`
+	Daily                 []*InsulinDailyTotal `json:"daily"`
+	InsulinOnBoard        []*InsulinOnBoard    `json:"insulinOnBoard"`
+	Segments              []*InsulinSegment    `json:"segments,omitempty"`
+}
+
+// InsulinDelivery is an amount of insulin, in units, delivered at the time
+type InsulinDelivery struct {
+	Time   time.Time
+	Amount float64
+}
+
+// CalculateInsulin aggregates the basal and bolus datums between the start and end times per local day, ignoring
+// any other datums. Basal segments crossing the local midnight or the range bounds are split accordingly. The insulin
+// on board is derived from the boluses, including those delivered before the start time within the insulin action
+// duration of the latest pump settings at or before the end time.
+func CalculateInsulin(datums data.Data, startTime time.Time, endTime time.Time) *InsulinReport {
+	daily, deliveries, insulinActionDuration := aggregateInsulin(datums, startTime, endTime)
+	return &InsulinReport{
+		StartTime:             startTime.Format(time.RFC3339Nano),
+		EndTime:               endTime.Format(time.RFC3339Nano),
+		Units:                 InsulinUnits,
+		InsulinActionDuration: int(insulinActionDuration / time.Minute),
+		Daily:                 daily,
+		InsulinOnBoard:        CalculateInsulinOnBoard(deliveries, insulinActionDuration, startTime, endTime),
+	}
+}
+
+// CalculateInsulinDailyTotals aggregates the basal and bolus datums between the start and end times per local day,
+// as CalculateInsulin does, without the insulin on board
+func CalculateInsulinDailyTotals(datums data.Data, startTime time.Time, endTime time.Time) []*InsulinDailyTotal {
+	daily, _, _ := aggregateInsulin(datums, startTime, endTime)
+	return daily
+}
+
+func aggregateInsulin(datums data.Data, startTime time.Time, endTime time.Time) ([]*InsulinDailyTotal, []InsulinDelivery, time.Duration) {
+	totals := map[string]*InsulinDailyTotal{}
+	total := func(date string) *InsulinDailyTotal {
+		result, ok := totals[date]
+		if !ok {
+			result = &InsulinDailyTotal{Date: date}
+			totals[date] = result
+		}
+		return result
+	}
+
+	// The boluses delivered before the range, within the insulin action duration, are still on board during the range
+	insulinActionDuration := InsulinActionDuration(datums, endTime)
+	deliveriesStartTime := startTime.Add(-insulinActionDuration)
+
+	var deliveries []InsulinDelivery
+	bolus := func(base *types.Base, normal *float64, normalExpected *float64, extended *float64, extendedExpected *float64, duration *int) {
+		tm, ok := LocalTime(base)
+		if !ok || tm.Before(deliveriesStartTime) || tm.After(endTime) {
+			return
+		}
+		if !tm.Before(startTime) {
+			addBolus(total(tm.Format(DateFormat)), normal, normalExpected, extended, extendedExpected)
+		}
+		deliveries = appendInsulinDelivery(deliveries, tm, normal, extended, duration)
+	}
+
+	for _, datum := range datums {
+		switch typed := datum.(type) {
+		case *dataTypesBasalScheduled.Scheduled:
+			integrateBasal(&typed.Basal.Base, typed.Rate, typed.Duration, startTime, endTime, func(date string, amount float64) {
+				total(date).BasalScheduled += amount
+			})
+		case *dataTypesBasalTemporary.Temporary:
+			integrateBasal(&typed.Basal.Base, typed.Rate, typed.Duration, startTime, endTime, func(date string, amount float64) {
+				total(date).BasalTemporary += amount
+			})
+		case *dataTypesBasalAutomated.Automated:
+			integrateBasal(&typed.Basal.Base, typed.Rate, typed.Duration, startTime, endTime, func(date string, amount float64) {
+				total(date).BasalAutomated += amount
+			})
+		case *dataTypesBasalSuspend.Suspend:
+			if tm, ok := LocalTime(&typed.Basal.Base); ok && !tm.Before(startTime) && !tm.After(endTime) {
+				total(tm.Format(DateFormat)).BasalSuspended++
+			}
+		case *dataTypesBolusNormal.Normal:
+			bolus(&typed.Bolus.Base, typed.Normal, typed.NormalExpected, nil, nil, nil)
+		case *dataTypesBolusBiphasic.Biphasic:
+			bolus(&typed.Bolus.Base, typed.Normal.Normal, typed.NormalExpected, nil, nil, nil)
+		case *dataTypesBolusPen.Pen:
+			bolus(&typed.Bolus.Base, typed.Normal, nil, nil, nil, nil)
+		case *dataTypesBolusExtended.Extended:
+			bolus(&typed.Bolus.Base, nil, nil, typed.Extended, typed.ExtendedExpected, typed.Duration)
+		case *dataTypesBolusCombination.Combination:
+			bolus(&typed.Bolus.Base, typed.Normal, typed.NormalExpected, typed.Extended, typed.ExtendedExpected, typed.Duration)
+		}
+	}
+
+	daily := make([]*InsulinDailyTotal, 0, len(totals))
+	for _, result := range totals {
+		result.Basal = result.BasalScheduled + result.BasalTemporary + result.BasalAutomated
+		result.Bolus = result.BolusNormal + result.BolusExtended
+		result.Total = result.Basal + result.Bolus
+		if result.Total > 0 {
+			result.BasalPercent = pointer.FromFloat64(result.Basal / result.Total * 100)
+			result.BolusPercent = pointer.FromFloat64(result.Bolus / result.Total * 100)
+		}
+		daily = append(daily, result)
+	}
+	sort.Slice(daily, func(i int, j int) bool { return daily[i].Date < daily[j].Date })
+
+	return daily, deliveries, insulinActionDuration
+}
+
+// InsulinActionDuration returns the insulin action duration of the latest pump settings datum at or before the end
+// time, if any, otherwise the default
+func InsulinActionDuration(datums data.Data, endTime time.Time) time.Duration {
+	var pumpSettings *dataTypesSettingsPump.Pump
+	var pumpSettingsTime time.Time
+	for _, datum := range datums {
+		typed, ok := datum.(*dataTypesSettingsPump.Pump)
+		if !ok || typed.Time == nil {
+			continue
+		}
+		tm, err := time.Parse(data.TimeFormat, *typed.Time)
+		if err != nil || tm.After(endTime) || (pumpSettings != nil && tm.Before(pumpSettingsTime)) {
+			continue
+		}
+		pumpSettings = typed
+		pumpSettingsTime = tm
+	}
+
+	if pumpSettings != nil {
+		if duration := PumpInsulinActionDuration(pumpSettings); duration != nil {
+			return *duration
+		}
+	}
+	return InsulinActionDurationDefault
+}
+
+// PumpInsulinActionDuration returns the insulin action duration of the bolus calculator of the pump settings, if any
+func PumpInsulinActionDuration(pump *dataTypesSettingsPump.Pump) *time.Duration {
+	if pump.Bolus == nil || pump.Bolus.Calculator == nil || pump.Bolus.Calculator.Insulin == nil {
+		return nil
+	}
+	insulin := pump.Bolus.Calculator.Insulin
+	if insulin.Duration == nil || *insulin.Duration <= 0 || insulin.Units == nil {
+		return nil
+	}
+
+	var unit time.Duration
+	switch *insulin.Units {
+	case dataTypesSettingsPump.BolusCalculatorInsulinUnitsHours:
+		unit = time.Hour
+	case dataTypesSettingsPump.BolusCalculatorInsulinUnitsMinutes:
+		unit = time.Minute
+	case dataTypesSettingsPump.BolusCalculatorInsulinUnitsSeconds:
+		unit = time.Second
+	default:
+		return nil
+	}
+	duration := time.Duration(*insulin.Duration * float64(unit))
+	return &duration
+}
+
+// CalculateInsulinOnBoard returns the insulin on board every 15 minutes of the range, decreasing linearly each
+// delivery over the insulin action duration
+func CalculateInsulinOnBoard(deliveries []InsulinDelivery, insulinActionDuration time.Duration, startTime time.Time, endTime time.Time) []*InsulinOnBoard {
+	series := []*InsulinOnBoard{}
+	for tm := startTime; !tm.After(endTime); tm = tm.Add(InsulinOnBoardInterval) {
+		value := 0.0
+		for _, delivery := range deliveries {
+			elapsed := tm.Sub(delivery.Time)
+			if elapsed < 0 || elapsed >= insulinActionDuration {
+				continue
+			}
+			value += delivery.Amount * (1 - float64(elapsed)/float64(insulinActionDuration))
+		}
+		series = append(series, &InsulinOnBoard{Time: tm.UTC().Format(time.RFC3339Nano), Value: value})
+	}
+	return series
+}
+
+// integrateBasal adds the insulin delivered by the basal segment within the range to each local day it spans
+func integrateBasal(base *types.Base, rate *float64, duration *int, startTime time.Time, endTime time.Time, add func(date string, amount float64)) {
+	if rate == nil || duration == nil {
+		return
+	}
+	segmentStartTime, ok := LocalTime(base)
+	if !ok {
+		return
+	}
+	segmentEndTime := segmentStartTime.Add(time.Duration(*duration) * time.Millisecond)
+	segmentStartTime = maximumTime(segmentStartTime, startTime.In(segmentStartTime.Location()))
+	segmentEndTime = minimumTime(segmentEndTime, endTime.In(segmentStartTime.Location()))
+
+	for segmentStartTime.Before(segmentEndTime) {
+		year, month, day := segmentStartTime.Date()
+		midnight := time.Date(year, month, day+1, 0, 0, 0, 0, segmentStartTime.Location())
+		pieceEndTime := minimumTime(midnight, segmentEndTime)
+		add(segmentStartTime.Format(DateFormat), *rate*pieceEndTime.Sub(segmentStartTime).Hours())
+		segmentStartTime = pieceEndTime
+	}
+}
+
+// addBolus adds the delivered normal and extended parts of the bolus to the total, and the expected amounts,
+// defaulting to the delivered amounts, to the expected total
+func addBolus(total *InsulinDailyTotal, normal *float64, normalExpected *float64, extended *float64, extendedExpected *float64) {
+	total.BolusCount++
+	if normal != nil {
+		total.BolusNormal += *normal
+	}
+	if extended != nil {
+		total.BolusExtended += *extended
+	}
+	total.BolusExpected += expectedAmount(normal, normalExpected) + expectedAmount(extended, extendedExpected)
+}
+
+func expectedAmount(delivered *float64, expected *float64) float64 {
+	if expected != nil {
+		return *expected
+	} else if delivered != nil {
+		return *delivered
+	}
+	return 0
+}
+
+// appendInsulinDelivery appends the normal part of the bolus delivered at the time, then the extended part delivered
+// evenly over its duration, one delivery every 15 minutes
+func appendInsulinDelivery(deliveries []InsulinDelivery, tm time.Time, normal *float64, extended *float64, duration *int) []InsulinDelivery {
+	if normal != nil && *normal > 0 {
+		deliveries = append(deliveries, InsulinDelivery{Time: tm, Amount: *normal})
+	}
+	if extended != nil && *extended > 0 {
+		count := 1
+		if duration != nil {
+			if durationCount := int(time.Duration(*duration) * time.Millisecond / InsulinOnBoardInterval); durationCount > 1 {
+				count = durationCount
+			}
+		}
+		for index := 0; index < count; index++ {
+			deliveries = append(deliveries, InsulinDelivery{Time: tm.Add(time.Duration(index) * InsulinOnBoardInterval), Amount: *extended / float64(count)})
+		}
+	}
+	return deliveries
+}
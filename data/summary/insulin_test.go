@@ -0,0 +1,228 @@
+package summary_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesBasalAutomated "github.com/tidepool-org/platform/data/types/basal/automated"
+	dataTypesBasalScheduled "github.com/tidepool-org/platform/data/types/basal/scheduled"
+	dataTypesBasalSuspend "github.com/tidepool-org/platform/data/types/basal/suspend"
+	dataTypesBasalTemporary "github.com/tidepool-org/platform/data/types/basal/temporary"
+	dataTypesBolusBiphasic "github.com/tidepool-org/platform/data/types/bolus/biphasic"
+	dataTypesBolusCombination "github.com/tidepool-org/platform/data/types/bolus/combination"
+	dataTypesBolusExtended "github.com/tidepool-org/platform/data/types/bolus/extended"
+	dataTypesBolusNormal "github.com/tidepool-org/platform/data/types/bolus/normal"
+	dataTypesBolusPen "github.com/tidepool-org/platform/data/types/bolus/pen"
+	dataTypesSettingsPump "github.com/tidepool-org/platform/data/types/settings/pump"
+	"github.com/tidepool-org/platform/pointer"
+)
+
+func NewPumpWithInsulinDuration(duration float64, units string) *dataTypesSettingsPump.Pump {
+	datum := dataTypesSettingsPump.New()
+	datum.Time = pointer.FromString("2020-03-01T00:30:00Z")
+	datum.Bolus = &dataTypesSettingsPump.Bolus{
+		Calculator: &dataTypesSettingsPump.BolusCalculator{
+			Enabled: pointer.FromBool(true),
+			Insulin: &dataTypesSettingsPump.BolusCalculatorInsulin{Duration: pointer.FromFloat64(duration), Units: pointer.FromString(units)},
+		},
+	}
+	return datum
+}
+
+var _ = Describe("Insulin", func() {
+	Context("PumpInsulinActionDuration", func() {
+		It("returns nil if the pump settings have no bolus calculator", func() {
+			Expect(summary.PumpInsulinActionDuration(dataTypesSettingsPump.New())).To(BeNil())
+		})
+
+		It("returns nil if the units are unknown", func() {
+			Expect(summary.PumpInsulinActionDuration(NewPumpWithInsulinDuration(3, "days"))).To(BeNil())
+		})
+
+		It("returns the duration in the units", func() {
+			Expect(*summary.PumpInsulinActionDuration(NewPumpWithInsulinDuration(3, "hours"))).To(Equal(3 * time.Hour))
+			Expect(*summary.PumpInsulinActionDuration(NewPumpWithInsulinDuration(150, "minutes"))).To(Equal(150 * time.Minute))
+			Expect(*summary.PumpInsulinActionDuration(NewPumpWithInsulinDuration(7200, "seconds"))).To(Equal(2 * time.Hour))
+		})
+	})
+
+	Context("InsulinActionDuration", func() {
+		It("returns the default without pump settings", func() {
+			Expect(summary.InsulinActionDuration(nil, time.Now())).To(Equal(summary.InsulinActionDurationDefault))
+		})
+	})
+
+	Context("CalculateInsulinOnBoard", func() {
+		It("decreases each delivery linearly over the insulin action duration", func() {
+			startTime := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+			deliveries := []summary.InsulinDelivery{
+				{Time: startTime, Amount: 4},
+				{Time: startTime.Add(time.Hour), Amount: 2},
+			}
+			series := summary.CalculateInsulinOnBoard(deliveries, 2*time.Hour, startTime, startTime.Add(3*time.Hour))
+			Expect(series).To(HaveLen(13))
+			Expect(series[0]).To(Equal(&summary.InsulinOnBoard{Time: "2020-03-01T00:00:00Z", Value: 4}))
+			Expect(series[2].Value).To(Equal(3.0))
+			Expect(series[4].Value).To(Equal(4.0))
+			Expect(series[8].Value).To(Equal(1.0))
+			Expect(series[12]).To(Equal(&summary.InsulinOnBoard{Time: "2020-03-01T03:00:00Z", Value: 0}))
+		})
+	})
+
+	Context("CalculateInsulin", func() {
+		var startTime time.Time
+		var endTime time.Time
+
+		BeforeEach(func() {
+			startTime = time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+			endTime = time.Date(2020, 3, 3, 0, 0, 0, 0, time.UTC)
+		})
+
+		It("returns no totals and the default insulin action duration if there is no data", func() {
+			report := summary.CalculateInsulin(nil, startTime, endTime)
+			Expect(report.StartTime).To(Equal("2020-03-01T00:00:00Z"))
+			Expect(report.EndTime).To(Equal("2020-03-03T00:00:00Z"))
+			Expect(report.Units).To(Equal("Units"))
+			Expect(report.InsulinActionDuration).To(Equal(240))
+			Expect(report.Daily).To(BeEmpty())
+			Expect(report.InsulinOnBoard).To(HaveLen(193))
+			Expect(report.InsulinOnBoard[192]).To(Equal(&summary.InsulinOnBoard{Time: "2020-03-03T00:00:00Z", Value: 0}))
+		})
+
+		It("uses the insulin action duration of the latest pump settings at or before the end time", func() {
+			older := NewPumpWithInsulinDuration(2, "hours")
+			older.Time = pointer.FromString("2020-02-01T00:00:00Z")
+			latest := NewPumpWithInsulinDuration(3, "hours")
+			latest.Time = pointer.FromString("2020-02-15T00:00:00Z")
+			after := NewPumpWithInsulinDuration(5, "hours")
+			after.Time = pointer.FromString("2020-03-04T00:00:00Z")
+			report := summary.CalculateInsulin(data.Data{latest, after, older}, startTime, endTime)
+			Expect(report.InsulinActionDuration).To(Equal(180))
+		})
+
+		It("includes in the insulin on board the boluses before the start time within the insulin action duration", func() {
+			within := dataTypesBolusNormal.New()
+			within.Time = pointer.FromString("2020-02-29T23:00:00Z")
+			within.Normal = pointer.FromFloat64(6)
+			outside := dataTypesBolusNormal.New()
+			outside.Time = pointer.FromString("2020-02-29T20:00:00Z")
+			outside.Normal = pointer.FromFloat64(10)
+			pumpSettings := NewPumpWithInsulinDuration(3, "hours")
+			pumpSettings.Time = pointer.FromString("2020-02-01T00:00:00Z")
+			report := summary.CalculateInsulin(data.Data{pumpSettings, within, outside}, startTime, endTime)
+			Expect(report.Daily).To(BeEmpty())
+			Expect(report.InsulinOnBoard[0].Value).To(BeNumerically("~", 4, 0.0001))
+			Expect(report.InsulinOnBoard[8].Value).To(Equal(0.0))
+		})
+
+		It("returns the totals for each local day and the insulin on board", func() {
+			scheduled := dataTypesBasalScheduled.New()
+			scheduled.Time = pointer.FromString("2020-03-01T22:00:00Z")
+			scheduled.Rate = pointer.FromFloat64(1)
+			scheduled.Duration = pointer.FromInt(int(4 * time.Hour / time.Millisecond))
+			scheduledBefore := dataTypesBasalScheduled.New()
+			scheduledBefore.Time = pointer.FromString("2020-02-29T23:00:00Z")
+			scheduledBefore.Rate = pointer.FromFloat64(1)
+			scheduledBefore.Duration = pointer.FromInt(int(2 * time.Hour / time.Millisecond))
+			temporary := dataTypesBasalTemporary.New()
+			temporary.Time = pointer.FromString("2020-03-01T10:00:00Z")
+			temporary.Rate = pointer.FromFloat64(0.5)
+			temporary.Duration = pointer.FromInt(int(2 * time.Hour / time.Millisecond))
+			automated := dataTypesBasalAutomated.New()
+			automated.Time = pointer.FromString("2020-03-02T06:00:00Z")
+			automated.Rate = pointer.FromFloat64(2)
+			automated.Duration = pointer.FromInt(int(30 * time.Minute / time.Millisecond))
+			suspend := dataTypesBasalSuspend.New()
+			suspend.Time = pointer.FromString("2020-03-02T07:00:00Z")
+			suspend.Duration = pointer.FromInt(int(time.Hour / time.Millisecond))
+
+			normal := dataTypesBolusNormal.New()
+			normal.Time = pointer.FromString("2020-03-01T08:00:00Z")
+			normal.Normal = pointer.FromFloat64(3)
+			normal.NormalExpected = pointer.FromFloat64(4)
+			normalBefore := dataTypesBolusNormal.New()
+			normalBefore.Time = pointer.FromString("2020-02-29T20:00:00Z")
+			normalBefore.Normal = pointer.FromFloat64(10)
+			extended := dataTypesBolusExtended.New()
+			extended.Time = pointer.FromString("2020-03-01T12:00:00Z")
+			extended.Extended = pointer.FromFloat64(2)
+			extended.Duration = pointer.FromInt(int(time.Hour / time.Millisecond))
+			combination := dataTypesBolusCombination.New()
+			combination.Time = pointer.FromString("2020-03-02T12:00:00Z")
+			combination.Normal = pointer.FromFloat64(1)
+			combination.Extended = pointer.FromFloat64(1)
+			combination.ExtendedExpected = pointer.FromFloat64(2)
+			combination.Duration = pointer.FromInt(int(30 * time.Minute / time.Millisecond))
+			biphasic := dataTypesBolusBiphasic.New()
+			biphasic.Time = pointer.FromString("2020-03-02T18:00:00Z")
+			biphasic.Normal.Normal = pointer.FromFloat64(1.5)
+			pen := dataTypesBolusPen.New()
+			pen.Time = pointer.FromString("2020-03-02T20:00:00Z")
+			pen.Normal = pointer.FromFloat64(2)
+
+			datums := data.Data{
+				NewPumpWithInsulinDuration(3, "hours"),
+				scheduledBefore, normalBefore, normal, temporary, extended, scheduled,
+				automated, suspend, combination, biphasic, pen,
+				NewContinuous("2020-03-01T09:00:00Z", "mmol/L", 5),
+			}
+
+			report := summary.CalculateInsulin(datums, startTime, endTime)
+			Expect(report.InsulinActionDuration).To(Equal(180))
+			Expect(report.Daily).To(HaveLen(2))
+
+			first := report.Daily[0]
+			Expect(first.Date).To(Equal("2020-03-01"))
+			Expect(first.BasalScheduled).To(BeNumerically("~", 3, 0.0001))
+			Expect(first.BasalTemporary).To(BeNumerically("~", 1, 0.0001))
+			Expect(first.BasalAutomated).To(Equal(0.0))
+			Expect(first.Basal).To(BeNumerically("~", 4, 0.0001))
+			Expect(first.BolusNormal).To(Equal(3.0))
+			Expect(first.BolusExtended).To(Equal(2.0))
+			Expect(first.Bolus).To(Equal(5.0))
+			Expect(first.BolusExpected).To(Equal(6.0))
+			Expect(first.BolusCount).To(Equal(2))
+			Expect(first.Total).To(BeNumerically("~", 9, 0.0001))
+			Expect(*first.BasalPercent).To(BeNumerically("~", 4.0/9.0*100, 0.0001))
+			Expect(*first.BolusPercent).To(BeNumerically("~", 5.0/9.0*100, 0.0001))
+
+			second := report.Daily[1]
+			Expect(second.Date).To(Equal("2020-03-02"))
+			Expect(second.BasalScheduled).To(BeNumerically("~", 2, 0.0001))
+			Expect(second.BasalAutomated).To(BeNumerically("~", 1, 0.0001))
+			Expect(second.BasalSuspended).To(Equal(1))
+			Expect(second.Basal).To(BeNumerically("~", 3, 0.0001))
+			Expect(second.BolusNormal).To(Equal(4.5))
+			Expect(second.BolusExtended).To(Equal(1.0))
+			Expect(second.BolusExpected).To(Equal(6.5))
+			Expect(second.BolusCount).To(Equal(3))
+			Expect(second.Total).To(BeNumerically("~", 8.5, 0.0001))
+
+			Expect(report.InsulinOnBoard).To(HaveLen(193))
+			Expect(report.InsulinOnBoard[32]).To(Equal(&summary.InsulinOnBoard{Time: "2020-03-01T08:00:00Z", Value: 3}))
+			Expect(report.InsulinOnBoard[33].Value).To(BeNumerically("~", 2.75, 0.0001))
+			Expect(report.InsulinOnBoard[38].Value).To(BeNumerically("~", 1.5, 0.0001))
+			Expect(report.InsulinOnBoard[44].Value).To(Equal(0.0))
+			Expect(report.InsulinOnBoard[51].Value).To(BeNumerically("~", 1.75, 0.0001))
+		})
+
+		It("splits the basal segments at the local midnight", func() {
+			scheduled := dataTypesBasalScheduled.New()
+			scheduled.Time = pointer.FromString("2020-03-02T03:00:00Z")
+			scheduled.TimeZoneOffset = pointer.FromInt(-300)
+			scheduled.Rate = pointer.FromFloat64(1)
+			scheduled.Duration = pointer.FromInt(int(3 * time.Hour / time.Millisecond))
+
+			report := summary.CalculateInsulin(data.Data{scheduled}, startTime, endTime)
+			Expect(report.Daily).To(HaveLen(2))
+			Expect(report.Daily[0].Date).To(Equal("2020-03-01"))
+			Expect(report.Daily[0].Basal).To(BeNumerically("~", 2, 0.0001))
+			Expect(report.Daily[1].Date).To(Equal("2020-03-02"))
+			Expect(report.Daily[1].Basal).To(BeNumerically("~", 1, 0.0001))
+		})
+	})
+})
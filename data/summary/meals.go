@@ -0,0 +1,399 @@
+package summary
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/tidepool-org/platform/association"
+	"github.com/tidepool-org/platform/data"
+	dataBloodGlucose "github.com/tidepool-org/platform/data/blood/glucose"
+	dataTypesBloodGlucoseContinuous "github.com/tidepool-org/platform/data/types/blood/glucose/continuous"
+	dataTypesBolus "github.com/tidepool-org/platform/data/types/bolus"
+	dataTypesBolusBiphasic "github.com/tidepool-org/platform/data/types/bolus/biphasic"
+	dataTypesBolusCombination "github.com/tidepool-org/platform/data/types/bolus/combination"
+	dataTypesBolusExtended "github.com/tidepool-org/platform/data/types/bolus/extended"
+	dataTypesBolusNormal "github.com/tidepool-org/platform/data/types/bolus/normal"
+	dataTypesBolusPen "github.com/tidepool-org/platform/data/types/bolus/pen"
+	dataTypesCalculator "github.com/tidepool-org/platform/data/types/calculator"
+	dataTypesFood "github.com/tidepool-org/platform/data/types/food"
+	"github.com/tidepool-org/platform/pointer"
+)
+
+const (
+	MealsRangeDefault = 14 * 24 * time.Hour
+
+	// MealBolusWindow is the longest interval between a meal and a bolus not explicitly related to it
+	MealBolusWindow = 30 * time.Minute
+	// MealPreMealGlucoseWindow is the longest interval between the pre-meal glucose reading and the meal
+	MealPreMealGlucoseWindow = 30 * time.Minute
+	// MealPostPrandialDuration is the interval after the meal within which the post-prandial peak is searched
+	MealPostPrandialDuration = 2 * time.Hour
+	// MealReturnToRangeWindow is the interval after the meal within which the return to range is searched
+	MealReturnToRangeWindow = 6 * time.Hour
+
+	MealAssociationReasonBolus            = "bolus"
+	MealAssociationReasonPreMealGlucose   = "preMealGlucose"
+	MealAssociationReasonPostPrandialPeak = "postPrandialPeak"
+)
+
+func MealAssociationReasons() []string {
+	return []string{
+		MealAssociationReasonBolus,
+		MealAssociationReasonPreMealGlucose,
+		MealAssociationReasonPostPrandialPeak,
+	}
+}
+
+// MealBolus is the bolus associated with a meal, with the insulin in Units
+type MealBolus struct {
+	ID          string   `json:"id,omitempty"`
+	Time        string   `json:"time"`
+	Normal      *float64 `json:"normal,omitempty"`
+	Extended    *float64 `json:"extended,omitempty"`
+	Recommended *float64 `json:"recommended,omitempty"`
+	Prescriptor *string  `json:"prescriptor,omitempty"`
+}
+
+// MealGlucoseReading is a continuous glucose reading, in mmol/L
+type MealGlucoseReading struct {
+	ID    string  `json:"id,omitempty"`
+	Time  string  `json:"time"`
+	Value float64 `json:"value"`
+}
+
+// MealCorrelation relates a meal, a food or bolus calculator datum with carbohydrate, to the associated bolus and the
+// resulting glucose. The time of a bolus calculator meal is its input time, if any. The post-prandial peak is the
+// highest reading within 2 hours after the meal and the delta is relative to the pre-meal glucose. The time to return
+// to range, in minutes since the meal, is that of the first reading in range after the peak, zero if the peak is in
+// range, and missing if the glucose does not return to range within 6 hours. The associations reference the datums
+// related to the meal.
+type MealCorrelation struct {
+	ID                  string                        `json:"id,omitempty"`
+	Type                string                        `json:"type"`
+	Time                string                        `json:"time"`
+	Carbohydrate        float64                       `json:"carbohydrate"`
+	Meal                *string                       `json:"meal,omitempty"`
+	Bolus               *MealBolus                    `json:"bolus,omitempty"`
+	PreMealGlucose      *MealGlucoseReading           `json:"preMealGlucose,omitempty"`
+	PostPrandialPeak    *MealGlucoseReading           `json:"postPrandialPeak,omitempty"`
+	PostPrandialDelta   *float64                      `json:"postPrandialDelta,omitempty"`
+	TimeToReturnToRange *float64                      `json:"timeToReturnToRange,omitempty"`
+	Associations        *association.AssociationArray `json:"associations,omitempty"`
+}
+
+// MealsReport holds the meal correlations, ordered by time, with glucose values in mmol/L
+type MealsReport struct {
+	StartTime string             `json:"startTime"`
+	EndTime   string             `json:"endTime"`
+	Units     string             `json:"units"`
+	Meals     []*MealCorrelation `json:"meals"`
+}
+
+// MealsDataTypes returns the types of the data CalculateMeals correlates
+func MealsDataTypes() []string {
+	return []string{dataTypesFood.Type, dataTypesCalculator.Type, dataTypesBolus.Type, dataTypesBloodGlucoseContinuous.Type}
+}
+
+// MealsDataTimeRange returns the range of times of the data CalculateMeals correlates with the meals between the start
+// and end times, as the boluses and readings related to a meal may be before or after it
+func MealsDataTimeRange(startTime time.Time, endTime time.Time) (time.Time, time.Time) {
+	return startTime.Add(-MealBolusWindow), endTime.Add(MealReturnToRangeWindow)
+}
+
+// MealsTimeRangeForData returns the range of times of the meals whose correlations may change with the data between
+// the start and end times, the reverse of MealsDataTimeRange
+func MealsTimeRangeForData(startTime time.Time, endTime time.Time) (time.Time, time.Time) {
+	return startTime.Add(-MealReturnToRangeWindow), endTime.Add(MealBolusWindow)
+}
+
+// CalculateMeals correlates the meals between the start and end times with the boluses and the continuous glucose
+// readings. The datums must include the readings up to 6 hours after the end time. A bolus calculator meal is
+// associated with its bolus, any other meal with the bolus it references by a datum association or, without it, the
+// nearest bolus within 30 minutes. A food datum for rescue carbs is not a meal.
+func CalculateMeals(datums data.Data, startTime time.Time, endTime time.Time) *MealsReport {
+	meals := []*MealCorrelation{}
+	mealTimes := map[*MealCorrelation]time.Time{}
+	mealAssociations := map[*MealCorrelation]*association.AssociationArray{}
+	mealBolusIDs := map[*MealCorrelation]*string{}
+	boluses := []*MealBolus{}
+	readings := []*mealGlucoseReading{}
+	recommendedByBolusID := map[string]*float64{}
+
+	for _, datum := range datums {
+		switch typed := datum.(type) {
+		case *dataTypesCalculator.Calculator:
+			if typed.BolusID != nil && typed.Recommended != nil {
+				recommendedByBolusID[*typed.BolusID] = typed.Recommended.Net
+			}
+			if typed.CarbohydrateInput == nil || *typed.CarbohydrateInput <= 0 {
+				continue
+			}
+			tm, ok := datumTime(typed.Time)
+			if typed.InputTime != nil {
+				if inputTime, inputOK := datumTime(typed.InputTime.InputTime); inputOK {
+					tm, ok = inputTime, true
+				}
+			}
+			if !ok || tm.Before(startTime) || tm.After(endTime) {
+				continue
+			}
+			carbohydrate := *typed.CarbohydrateInput
+			if typed.CarbUnits != nil && *typed.CarbUnits == dataTypesCalculator.Exchanges {
+				carbohydrate *= CarbohydrateGramsPerExchange
+			}
+			meal := &MealCorrelation{ID: pointer.ToString(typed.ID), Type: dataTypesCalculator.Type, Time: tm.Format(time.RFC3339Nano), Carbohydrate: carbohydrate}
+			if typed.InputMeal != nil {
+				meal.Meal = typed.InputMeal.Meal
+			}
+			meals = append(meals, meal)
+			mealTimes[meal] = tm
+			mealAssociations[meal] = typed.Associations
+			mealBolusIDs[meal] = typed.BolusID
+		case *dataTypesFood.Food:
+			if typed.Meal != nil && *typed.Meal == dataTypesFood.MealRescueCarbs {
+				continue
+			}
+			if typed.Nutrition == nil || typed.Nutrition.Carbohydrate == nil || typed.Nutrition.Carbohydrate.Net == nil || *typed.Nutrition.Carbohydrate.Net <= 0 {
+				continue
+			}
+			tm, ok := datumTime(typed.Time)
+			if !ok || tm.Before(startTime) || tm.After(endTime) {
+				continue
+			}
+			meal := &MealCorrelation{ID: pointer.ToString(typed.ID), Type: dataTypesFood.Type, Time: tm.Format(time.RFC3339Nano), Carbohydrate: *typed.Nutrition.Carbohydrate.Net, Meal: typed.Meal}
+			meals = append(meals, meal)
+			mealTimes[meal] = tm
+			mealAssociations[meal] = typed.Associations
+		case *dataTypesBloodGlucoseContinuous.Continuous:
+			value := dataBloodGlucose.NormalizeValueForUnits(typed.Value, typed.Units)
+			if tm, ok := datumTime(typed.Time); ok && value != nil {
+				readings = append(readings, &mealGlucoseReading{id: pointer.ToString(typed.ID), time: tm, value: *value})
+			}
+		case *dataTypesBolusNormal.Normal:
+			boluses = appendMealBolus(boluses, &typed.Bolus, typed.Normal, nil)
+		case *dataTypesBolusBiphasic.Biphasic:
+			boluses = appendMealBolus(boluses, &typed.Bolus, typed.Normal.Normal, nil)
+		case *dataTypesBolusPen.Pen:
+			boluses = appendMealBolus(boluses, &typed.Bolus, typed.Normal, nil)
+		case *dataTypesBolusExtended.Extended:
+			boluses = appendMealBolus(boluses, &typed.Bolus, nil, typed.Extended)
+		case *dataTypesBolusCombination.Combination:
+			boluses = appendMealBolus(boluses, &typed.Bolus, typed.Normal, typed.Extended)
+		}
+	}
+	sort.SliceStable(meals, func(i int, j int) bool { return mealTimes[meals[i]].Before(mealTimes[meals[j]]) })
+	sort.SliceStable(readings, func(i int, j int) bool { return readings[i].time.Before(readings[j].time) })
+
+	bolusesByID := map[string]*MealBolus{}
+	for _, bolus := range boluses {
+		if bolus.ID != "" {
+			bolusesByID[bolus.ID] = bolus
+			bolus.Recommended = recommendedByBolusID[bolus.ID]
+		}
+	}
+
+	for _, meal := range meals {
+		mealTime := mealTimes[meal]
+		meal.Bolus = findMealBolus(mealTime, mealBolusIDs[meal], mealAssociations[meal], boluses, bolusesByID)
+		correlateMealGlucose(meal, mealTime, readings)
+
+		associations := association.AssociationArray{}
+		if meal.Bolus != nil && meal.Bolus.ID != "" {
+			associations = append(associations, newMealAssociation(meal.Bolus.ID, MealAssociationReasonBolus))
+		}
+		if meal.PreMealGlucose != nil && meal.PreMealGlucose.ID != "" {
+			associations = append(associations, newMealAssociation(meal.PreMealGlucose.ID, MealAssociationReasonPreMealGlucose))
+		}
+		if meal.PostPrandialPeak != nil && meal.PostPrandialPeak.ID != "" {
+			associations = append(associations, newMealAssociation(meal.PostPrandialPeak.ID, MealAssociationReasonPostPrandialPeak))
+		}
+		if len(associations) > 0 {
+			meal.Associations = &associations
+		}
+	}
+
+	return &MealsReport{
+		StartTime: startTime.Format(time.RFC3339Nano),
+		EndTime:   endTime.Format(time.RFC3339Nano),
+		Units:     dataBloodGlucose.MmolL,
+		Meals:     meals,
+	}
+}
+
+type mealGlucoseReading struct {
+	id    string
+	time  time.Time
+	value float64
+}
+
+func (m *mealGlucoseReading) reading() *MealGlucoseReading {
+	return &MealGlucoseReading{ID: m.id, Time: m.time.Format(time.RFC3339Nano), Value: m.value}
+}
+
+func datumTime(value *string) (time.Time, bool) {
+	if value == nil {
+		return time.Time{}, false
+	}
+	tm, err := time.Parse(data.TimeFormat, *value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return tm.UTC(), true
+}
+
+func appendMealBolus(boluses []*MealBolus, bolus *dataTypesBolus.Bolus, normal *float64, extended *float64) []*MealBolus {
+	tm, ok := datumTime(bolus.Time)
+	if !ok {
+		return boluses
+	}
+	mealBolus := &MealBolus{
+		ID:       pointer.ToString(bolus.ID),
+		Time:     tm.Format(time.RFC3339Nano),
+		Normal:   normal,
+		Extended: extended,
+	}
+	if bolus.Prescriptor != nil {
+		mealBolus.Prescriptor = bolus.Prescriptor.Prescriptor
+	}
+	return append(boluses, mealBolus)
+}
+
+func findMealBolus(mealTime time.Time, bolusID *string, associations *association.AssociationArray, boluses []*MealBolus, bolusesByID map[string]*MealBolus) *MealBolus {
+	if bolusID != nil {
+		if bolus, ok := bolusesByID[*bolusID]; ok {
+			return bolus
+		}
+	}
+	if associations != nil {
+		for _, datumAssociation := range *associations {
+			if datumAssociation != nil && pointer.ToString(datumAssociation.Type) == association.TypeDatum && datumAssociation.ID != nil {
+				if bolus, ok := bolusesByID[*datumAssociation.ID]; ok {
+					return bolus
+				}
+			}
+		}
+	}
+
+	var nearest *MealBolus
+	nearestInterval := MealBolusWindow
+	for _, bolus := range boluses {
+		bolusTime, err := time.Parse(time.RFC3339Nano, bolus.Time)
+		if err != nil {
+			continue
+		}
+		if interval := time.Duration(math.Abs(float64(bolusTime.Sub(mealTime)))); interval <= nearestInterval {
+			nearest = bolus
+			nearestInterval = interval
+		}
+	}
+	return nearest
+}
+
+func correlateMealGlucose(meal *MealCorrelation, mealTime time.Time, readings []*mealGlucoseReading) {
+	var preMeal *mealGlucoseReading
+	var peak *mealGlucoseReading
+	for _, reading := range readings {
+		if !reading.time.After(mealTime) && mealTime.Sub(reading.time) <= MealPreMealGlucoseWindow {
+			preMeal = reading
+		} else if reading.time.After(mealTime) && reading.time.Sub(mealTime) <= MealPostPrandialDuration {
+			if peak == nil || reading.value > peak.value {
+				peak = reading
+			}
+		}
+	}
+
+	if preMeal != nil {
+		meal.PreMealGlucose = preMeal.reading()
+	}
+	if peak == nil {
+		return
+	}
+	meal.PostPrandialPeak = peak.reading()
+	if preMeal != nil {
+		meal.PostPrandialDelta = pointer.FromFloat64(peak.value - preMeal.value)
+	}
+
+	if peak.value <= GlucoseHighDefault {
+		meal.TimeToReturnToRange = pointer.FromFloat64(0)
+		return
+	}
+	for _, reading := range readings {
+		if reading.time.After(peak.time) && reading.time.Sub(mealTime) <= MealReturnToRangeWindow && reading.value >= GlucoseLowDefault && reading.value <= GlucoseHighDefault {
+			meal.TimeToReturnToRange = pointer.FromFloat64(reading.time.Sub(mealTime).Minutes())
+			return
+		}
+	}
+}
+
+func newMealAssociation(id string, reason string) *association.Association {
+	return &association.Association{
+		ID:     pointer.FromString(id),
+		Reason: pointer.FromString(reason),
+		Type:   pointer.FromString(association.TypeDatum),
+	}
+}
+
+// MealDataAssociations returns, by datum id, the associations of the meal datums with the datum associations of the
+// report meals in place of those recorded before, the other associations kept, and nil if none is left. Only the meal
+// datums whose associations change are returned.
+func MealDataAssociations(datums data.Data, report *MealsReport) map[string]*association.AssociationArray {
+	mealsByID := map[string]*MealCorrelation{}
+	for _, meal := range report.Meals {
+		if meal.ID != "" {
+			mealsByID[meal.ID] = meal
+		}
+	}
+
+	result := map[string]*association.AssociationArray{}
+	for _, datum := range datums {
+		var id *string
+		var existing *association.AssociationArray
+		switch typed := datum.(type) {
+		case *dataTypesCalculator.Calculator:
+			id, existing = typed.ID, typed.Associations
+		case *dataTypesFood.Food:
+			id, existing = typed.ID, typed.Associations
+		default:
+			continue
+		}
+		meal, ok := mealsByID[pointer.ToString(id)]
+		if !ok {
+			continue
+		}
+
+		associations := association.AssociationArray{}
+		if existing != nil {
+			for _, datumAssociation := range *existing {
+				if !isMealAssociation(datumAssociation) {
+					associations = append(associations, datumAssociation)
+				}
+			}
+		}
+		if meal.Associations != nil {
+			associations = append(associations, *meal.Associations...)
+		}
+
+		var updated *association.AssociationArray
+		if len(associations) > 0 {
+			updated = &associations
+		}
+		if !reflect.DeepEqual(existing, updated) {
+			result[*id] = updated
+		}
+	}
+	return result
+}
+
+func isMealAssociation(datumAssociation *association.Association) bool {
+	if datumAssociation == nil || pointer.ToString(datumAssociation.Type) != association.TypeDatum || datumAssociation.Reason == nil {
+		return false
+	}
+	for _, reason := range MealAssociationReasons() {
+		if *datumAssociation.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package summary_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/association"
+	"github.com/tidepool-org/platform/data"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesBolusNormal "github.com/tidepool-org/platform/data/types/bolus/normal"
+	dataTypesCalculator "github.com/tidepool-org/platform/data/types/calculator"
+	dataTypesCommon "github.com/tidepool-org/platform/data/types/common"
+	dataTypesFood "github.com/tidepool-org/platform/data/types/food"
+	"github.com/tidepool-org/platform/pointer"
+)
+
+func NewNormalBolus(id string, tm string, normal float64) *dataTypesBolusNormal.Normal {
+	datum := dataTypesBolusNormal.New()
+	datum.ID = pointer.FromString(id)
+	datum.Time = pointer.FromString(tm)
+	datum.Normal = pointer.FromFloat64(normal)
+	return datum
+}
+
+func NewFood(id string, tm string, carbohydrate float64) *dataTypesFood.Food {
+	datum := dataTypesFood.New()
+	datum.ID = pointer.FromString(id)
+	datum.Time = pointer.FromString(tm)
+	datum.Nutrition = &dataTypesFood.Nutrition{Carbohydrate: &dataTypesFood.Carbohydrate{Net: pointer.FromFloat64(carbohydrate)}}
+	return datum
+}
+
+var _ = Describe("Meals", func() {
+	var startTime time.Time
+	var endTime time.Time
+
+	BeforeEach(func() {
+		startTime = time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+		endTime = time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC)
+	})
+
+	It("returns no meals if there is no data", func() {
+		report := summary.CalculateMeals(nil, startTime, endTime)
+		Expect(report.StartTime).To(Equal("2020-03-01T00:00:00Z"))
+		Expect(report.Units).To(Equal("mmol/L"))
+		Expect(report.Meals).To(BeEmpty())
+	})
+
+	It("correlates a bolus calculator meal with its bolus and the resulting glucose", func() {
+		calculator := dataTypesCalculator.New()
+		calculator.ID = pointer.FromString("calculator")
+		calculator.Time = pointer.FromString("2020-03-01T08:05:00Z")
+		calculator.InputTime = &dataTypesCommon.InputTime{InputTime: pointer.FromString("2020-03-01T08:00:00Z")}
+		calculator.InputMeal = &dataTypesFood.Meal{Meal: pointer.FromString("medium")}
+		calculator.CarbohydrateInput = pointer.FromFloat64(45)
+		calculator.BolusID = pointer.FromString("bolus")
+		calculator.Recommended = &dataTypesCalculator.Recommended{Net: pointer.FromFloat64(5)}
+		bolus := NewNormalBolus("bolus", "2020-03-01T08:20:00Z", 4.5)
+		bolus.Prescriptor.Prescriptor = pointer.FromString("hybrid")
+		readings := NewContinuousSeries(time.Date(2020, 3, 1, 7, 30, 0, 0, time.UTC), 6, 6, 6, 6, 6, 6, 6, 8, 10, 12, 11.5, 11, 10.5, 10, 9)
+		datums := append(data.Data{NewNormalBolus("other", "2020-03-01T08:01:00Z", 1), calculator, bolus}, readings...)
+
+		meals := summary.CalculateMeals(datums, startTime, endTime).Meals
+		Expect(meals).To(HaveLen(1))
+		meal := meals[0]
+		Expect(meal.ID).To(Equal("calculator"))
+		Expect(meal.Type).To(Equal("wizard"))
+		Expect(meal.Time).To(Equal("2020-03-01T08:00:00Z"))
+		Expect(meal.Carbohydrate).To(Equal(45.0))
+		Expect(*meal.Meal).To(Equal("medium"))
+		Expect(meal.Bolus).To(Equal(&summary.MealBolus{
+			ID:          "bolus",
+			Time:        "2020-03-01T08:20:00Z",
+			Normal:      pointer.FromFloat64(4.5),
+			Recommended: pointer.FromFloat64(5),
+			Prescriptor: pointer.FromString("hybrid"),
+		}))
+		Expect(meal.PreMealGlucose.Time).To(Equal("2020-03-01T08:00:00Z"))
+		Expect(meal.PostPrandialPeak.Time).To(Equal("2020-03-01T08:15:00Z"))
+		Expect(meal.PostPrandialPeak.Value).To(Equal(12.0))
+		Expect(*meal.PostPrandialDelta).To(Equal(6.0))
+		Expect(*meal.TimeToReturnToRange).To(Equal(35.0))
+		Expect(meal.Associations).To(Equal(&association.AssociationArray{
+			{ID: pointer.FromString("bolus"), Reason: pointer.FromString("bolus"), Type: pointer.FromString("datum")},
+			{ID: pointer.FromString("2020-03-01T08:00:00Z"), Reason: pointer.FromString("preMealGlucose"), Type: pointer.FromString("datum")},
+			{ID: pointer.FromString("2020-03-01T08:15:00Z"), Reason: pointer.FromString("postPrandialPeak"), Type: pointer.FromString("datum")},
+		}))
+	})
+
+	It("associates a food meal with the bolus it references, otherwise the nearest bolus", func() {
+		referencing := NewFood("referencing", "2020-03-01T12:00:00Z", 30)
+		referencing.Associations = &association.AssociationArray{{ID: pointer.FromString("referenced"), Type: pointer.FromString("datum")}}
+		datums := data.Data{
+			referencing,
+			NewNormalBolus("nearby", "2020-03-01T12:05:00Z", 2),
+			NewNormalBolus("referenced", "2020-03-01T12:40:00Z", 3),
+			NewFood("nearest", "2020-03-01T18:00:00Z", 20),
+			NewNormalBolus("far", "2020-03-01T17:20:00Z", 1),
+			NewNormalBolus("near", "2020-03-01T18:10:00Z", 1.5),
+			NewFood("alone", "2020-03-01T21:00:00Z", 10),
+		}
+
+		meals := summary.CalculateMeals(datums, startTime, endTime).Meals
+		Expect(meals).To(HaveLen(3))
+		Expect(meals[0].Bolus.ID).To(Equal("referenced"))
+		Expect(meals[1].Bolus.ID).To(Equal("near"))
+		Expect(meals[2].Bolus).To(BeNil())
+		Expect(meals[2].PostPrandialPeak).To(BeNil())
+		Expect(meals[2].TimeToReturnToRange).To(BeNil())
+		Expect(meals[2].Associations).To(BeNil())
+	})
+
+	It("ignores rescue carbs and the meals outside of the range", func() {
+		rescue := NewFood("rescue", "2020-03-01T12:00:00Z", 15)
+		rescue.Meal = pointer.FromString(dataTypesFood.MealRescueCarbs)
+		datums := data.Data{rescue, NewFood("before", "2020-02-29T23:00:00Z", 30)}
+		Expect(summary.CalculateMeals(datums, startTime, endTime).Meals).To(BeEmpty())
+	})
+
+	It("orders the meals by time, whatever the precision of their times", func() {
+		datums := data.Data{NewFood("later", "2020-03-01T12:00:00.5Z", 30), NewFood("earlier", "2020-03-01T12:00:00Z", 20)}
+		meals := summary.CalculateMeals(datums, startTime, endTime).Meals
+		Expect(meals).To(HaveLen(2))
+		Expect(meals[0].ID).To(Equal("earlier"))
+		Expect(meals[1].ID).To(Equal("later"))
+	})
+
+	It("returns the range of the data correlated with the meals, and the reverse", func() {
+		dataStartTime, dataEndTime := summary.MealsDataTimeRange(startTime, endTime)
+		Expect(dataStartTime).To(Equal(startTime.Add(-30 * time.Minute)))
+		Expect(dataEndTime).To(Equal(endTime.Add(6 * time.Hour)))
+		mealsStartTime, mealsEndTime := summary.MealsTimeRangeForData(startTime, endTime)
+		Expect(mealsStartTime).To(Equal(startTime.Add(-6 * time.Hour)))
+		Expect(mealsEndTime).To(Equal(endTime.Add(30 * time.Minute)))
+	})
+
+	It("returns no time to return to range if the glucose stays high", func() {
+		datums := append(data.Data{NewFood("food", "2020-03-01T12:00:00Z", 30)}, NewContinuousSeries(time.Date(2020, 3, 1, 12, 5, 0, 0, time.UTC), 11, 12, 13)...)
+		meals := summary.CalculateMeals(datums, startTime, endTime).Meals
+		Expect(meals[0].PreMealGlucose).To(BeNil())
+		Expect(meals[0].PostPrandialDelta).To(BeNil())
+		Expect(meals[0].PostPrandialPeak.Value).To(Equal(13.0))
+		Expect(meals[0].TimeToReturnToRange).To(BeNil())
+	})
+
+	Context("MealDataAssociations", func() {
+		It("replaces the meal associations recorded before, keeping the others, and returns only the changed meal datums", func() {
+			other := &association.Association{ID: pointer.FromString("image"), Reason: pointer.FromString("photo"), Type: pointer.FromString(association.TypeImage)}
+			recorded := NewFood("recorded", "2020-03-01T12:00:00Z", 30)
+			recorded.Associations = &association.AssociationArray{
+				other,
+				{ID: pointer.FromString("stale"), Reason: pointer.FromString(summary.MealAssociationReasonBolus), Type: pointer.FromString(association.TypeDatum)},
+			}
+			unchanged := NewFood("unchanged", "2020-03-01T18:00:00Z", 20)
+			unchanged.Associations = &association.AssociationArray{{ID: pointer.FromString("evening"), Reason: pointer.FromString(summary.MealAssociationReasonBolus), Type: pointer.FromString(association.TypeDatum)}}
+			alone := NewFood("alone", "2020-03-01T21:00:00Z", 10)
+			alone.Associations = &association.AssociationArray{{ID: pointer.FromString("gone"), Reason: pointer.FromString(summary.MealAssociationReasonBolus), Type: pointer.FromString(association.TypeDatum)}}
+			datums := data.Data{
+				recorded,
+				NewNormalBolus("noon", "2020-03-01T12:05:00Z", 2),
+				unchanged,
+				NewNormalBolus("evening", "2020-03-01T18:10:00Z", 1.5),
+				alone,
+				NewFood("none", "2020-03-01T23:00:00Z", 10),
+			}
+			associations := summary.MealDataAssociations(datums, summary.CalculateMeals(datums, startTime, endTime))
+			Expect(associations).To(HaveLen(2))
+			Expect(associations).To(HaveKeyWithValue("recorded", &association.AssociationArray{
+				other,
+				{ID: pointer.FromString("noon"), Reason: pointer.FromString(summary.MealAssociationReasonBolus), Type: pointer.FromString(association.TypeDatum)},
+			}))
+			Expect(associations).To(HaveKeyWithValue("alone", BeNil()))
+		})
+	})
+})
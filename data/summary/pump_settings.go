@@ -0,0 +1,275 @@
+package summary
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/tidepool-org/platform/data"
+	dataTypesSettingsPump "github.com/tidepool-org/platform/data/types/settings/pump"
+	"github.com/tidepool-org/platform/pointer"
+)
+
+const (
+	PumpSettingsHistoryRangeDefault = 90 * 24 * time.Hour
+
+	PumpSettingsScheduleDefaultName = "default"
+
+	PumpSettingsScheduleBasal              = "basal"
+	PumpSettingsScheduleBloodGlucoseTarget = "bgTarget"
+	PumpSettingsScheduleCarbohydrateRatio  = "carbRatio"
+	PumpSettingsScheduleInsulinSensitivity = "insulinSensitivity"
+
+	PumpSettingsChangeAdded   = "added"
+	PumpSettingsChangeChanged = "changed"
+	PumpSettingsChangeRemoved = "removed"
+)
+
+// PumpSettingsScheduleChange is a schedule entry, identified by the schedule, its name and the start of the entry in
+// milliseconds since midnight, added, changed or removed between two snapshots. A schedule without a name, the one
+// of older pumps, is named default.
+type PumpSettingsScheduleChange struct {
+	Schedule string      `json:"schedule"`
+	Name     string      `json:"name"`
+	Start    int         `json:"start"`
+	Change   string      `json:"change"`
+	Previous interface{} `json:"previous,omitempty"`
+	Current  interface{} `json:"current,omitempty"`
+}
+
+// PumpSettingsActiveScheduleChange is the switch of the active schedule between two snapshots
+type PumpSettingsActiveScheduleChange struct {
+	Previous *string `json:"previous,omitempty"`
+	Current  *string `json:"current,omitempty"`
+}
+
+// PumpSettingsSnapshot is a pump settings datum of the timeline with the changes since the previous snapshot of the
+// same device. The first snapshot of each device holds the full settings, the changes being relative to it.
+type PumpSettingsSnapshot struct {
+	ID                   string                            `json:"id,omitempty"`
+	DeviceID             string                            `json:"deviceId,omitempty"`
+	UploadID             string                            `json:"uploadId,omitempty"`
+	Time                 string                            `json:"time"`
+	ActiveScheduleName   *string                           `json:"activeSchedule,omitempty"`
+	ActiveScheduleChange *PumpSettingsActiveScheduleChange `json:"activeScheduleChange,omitempty"`
+	Changes              []*PumpSettingsScheduleChange     `json:"changes"`
+	Settings             *dataTypesSettingsPump.Pump       `json:"settings,omitempty"`
+}
+
+// PumpSettingsHistory is the timeline of the pump settings of all the devices, ordered by time
+type PumpSettingsHistory struct {
+	StartTime string                  `json:"startTime"`
+	EndTime   string                  `json:"endTime"`
+	Snapshots []*PumpSettingsSnapshot `json:"snapshots"`
+}
+
+// CalculatePumpSettingsHistory returns the timeline of the pump settings datums between the start and end times,
+// ignoring any other datums. Each snapshot is compared to the previous one of the same device, so the settings of
+// several pumps are not diffed against each other. A snapshot identical to the previous one, as stored by each
+// upload, is omitted.
+func CalculatePumpSettingsHistory(datums data.Data, startTime time.Time, endTime time.Time) *PumpSettingsHistory {
+	pumps := []*dataTypesSettingsPump.Pump{}
+	for _, datum := range datums {
+		pump, ok := datum.(*dataTypesSettingsPump.Pump)
+		if !ok || pump.Time == nil {
+			continue
+		}
+		tm, err := time.Parse(data.TimeFormat, *pump.Time)
+		if err != nil || tm.Before(startTime) || tm.After(endTime) {
+			continue
+		}
+		pumps = append(pumps, pump)
+	}
+	sort.SliceStable(pumps, func(i int, j int) bool { return *pumps[i].Time < *pumps[j].Time })
+
+	snapshots := []*PumpSettingsSnapshot{}
+	previousByDeviceID := map[string]*dataTypesSettingsPump.Pump{}
+	for _, pump := range pumps {
+		deviceID := pointer.ToString(pump.DeviceID)
+		previous := previousByDeviceID[deviceID]
+		snapshot := &PumpSettingsSnapshot{
+			ID:                 pointer.ToString(pump.ID),
+			DeviceID:           deviceID,
+			UploadID:           pointer.ToString(pump.UploadID),
+			Time:               *pump.Time,
+			ActiveScheduleName: pump.ActiveScheduleName,
+			Changes:            []*PumpSettingsScheduleChange{},
+		}
+		if previous == nil {
+			snapshot.Settings = pump
+		} else {
+			snapshot.Changes = DiffPumpSettingsSchedules(previous, pump)
+			if !pointer.EqualString(previous.ActiveScheduleName, pump.ActiveScheduleName) {
+				snapshot.ActiveScheduleChange = &PumpSettingsActiveScheduleChange{Previous: previous.ActiveScheduleName, Current: pump.ActiveScheduleName}
+			}
+			if len(snapshot.Changes) == 0 && snapshot.ActiveScheduleChange == nil {
+				continue
+			}
+		}
+		snapshots = append(snapshots, snapshot)
+		previousByDeviceID[deviceID] = pump
+	}
+
+	return &PumpSettingsHistory{
+		StartTime: startTime.Format(time.RFC3339Nano),
+		EndTime:   endTime.Format(time.RFC3339Nano),
+		Snapshots: snapshots,
+	}
+}
+
+// DiffPumpSettingsSchedules returns the schedule entries added, changed or removed between the previous and current
+// pump settings, ordered by schedule, name and start
+func DiffPumpSettingsSchedules(previous *dataTypesSettingsPump.Pump, current *dataTypesSettingsPump.Pump) []*PumpSettingsScheduleChange {
+	changes := []*PumpSettingsScheduleChange{}
+	changes = append(changes, diffPumpSettingsSchedule(PumpSettingsScheduleBasal, basalRateSchedules(previous), basalRateSchedules(current))...)
+	changes = append(changes, diffPumpSettingsSchedule(PumpSettingsScheduleBloodGlucoseTarget, bloodGlucoseTargetSchedules(previous), bloodGlucoseTargetSchedules(current))...)
+	changes = append(changes, diffPumpSettingsSchedule(PumpSettingsScheduleCarbohydrateRatio, carbohydrateRatioSchedules(previous), carbohydrateRatioSchedules(current))...)
+	changes = append(changes, diffPumpSettingsSchedule(PumpSettingsScheduleInsulinSensitivity, insulinSensitivitySchedules(previous), insulinSensitivitySchedules(current))...)
+	return changes
+}
+
+// pumpSettingsSchedules holds the entries of each named schedule by start
+type pumpSettingsSchedules map[string]map[int]interface{}
+
+func (p pumpSettingsSchedules) set(name string, start *int, entry interface{}) {
+	if start == nil {
+		return
+	}
+	if p[name] == nil {
+		p[name] = map[int]interface{}{}
+	}
+	p[name][*start] = entry
+}
+
+func diffPumpSettingsSchedule(schedule string, previous pumpSettingsSchedules, current pumpSettingsSchedules) []*PumpSettingsScheduleChange {
+	names := map[string]bool{}
+	for name := range previous {
+		names[name] = true
+	}
+	for name := range current {
+		names[name] = true
+	}
+	sortedNames := make([]string, 0, len(names))
+	for name := range names {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	changes := []*PumpSettingsScheduleChange{}
+	for _, name := range sortedNames {
+		starts := map[int]bool{}
+		for start := range previous[name] {
+			starts[start] = true
+		}
+		for start := range current[name] {
+			starts[start] = true
+		}
+		sortedStarts := make([]int, 0, len(starts))
+		for start := range starts {
+			sortedStarts = append(sortedStarts, start)
+		}
+		sort.Ints(sortedStarts)
+
+		for _, start := range sortedStarts {
+			previousEntry, previousOK := previous[name][start]
+			currentEntry, currentOK := current[name][start]
+			change := &PumpSettingsScheduleChange{Schedule: schedule, Name: name, Start: start}
+			switch {
+			case !previousOK:
+				change.Change = PumpSettingsChangeAdded
+				change.Current = currentEntry
+			case !currentOK:
+				change.Change = PumpSettingsChangeRemoved
+				change.Previous = previousEntry
+			case !reflect.DeepEqual(previousEntry, currentEntry):
+				change.Change = PumpSettingsChangeChanged
+				change.Previous = previousEntry
+				change.Current = currentEntry
+			default:
+				continue
+			}
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+func basalRateSchedules(pump *dataTypesSettingsPump.Pump) pumpSettingsSchedules {
+	schedules := pumpSettingsSchedules{}
+	addBasalRateStartArray := func(name string, array *dataTypesSettingsPump.BasalRateStartArray) {
+		if array != nil {
+			for _, entry := range *array {
+				if entry != nil {
+					schedules.set(name, entry.Start, entry)
+				}
+			}
+		}
+	}
+	addBasalRateStartArray(PumpSettingsScheduleDefaultName, pump.BasalRateSchedule)
+	if pump.BasalRateSchedules != nil {
+		for name, array := range *pump.BasalRateSchedules {
+			addBasalRateStartArray(name, array)
+		}
+	}
+	return schedules
+}
+
+func bloodGlucoseTargetSchedules(pump *dataTypesSettingsPump.Pump) pumpSettingsSchedules {
+	schedules := pumpSettingsSchedules{}
+	addBloodGlucoseTargetStartArray := func(name string, array *dataTypesSettingsPump.BloodGlucoseTargetStartArray) {
+		if array != nil {
+			for _, entry := range *array {
+				if entry != nil {
+					schedules.set(name, entry.Start, entry)
+				}
+			}
+		}
+	}
+	addBloodGlucoseTargetStartArray(PumpSettingsScheduleDefaultName, pump.BloodGlucoseTargetSchedule)
+	if pump.BloodGlucoseTargetSchedules != nil {
+		for name, array := range *pump.BloodGlucoseTargetSchedules {
+			addBloodGlucoseTargetStartArray(name, array)
+		}
+	}
+	return schedules
+}
+
+func carbohydrateRatioSchedules(pump *dataTypesSettingsPump.Pump) pumpSettingsSchedules {
+	schedules := pumpSettingsSchedules{}
+	addCarbohydrateRatioStartArray := func(name string, array *dataTypesSettingsPump.CarbohydrateRatioStartArray) {
+		if array != nil {
+			for _, entry := range *array {
+				if entry != nil {
+					schedules.set(name, entry.Start, entry)
+				}
+			}
+		}
+	}
+	addCarbohydrateRatioStartArray(PumpSettingsScheduleDefaultName, pump.CarbohydrateRatioSchedule)
+	if pump.CarbohydrateRatioSchedules != nil {
+		for name, array := range *pump.CarbohydrateRatioSchedules {
+			addCarbohydrateRatioStartArray(name, array)
+		}
+	}
+	return schedules
+}
+
+func insulinSensitivitySchedules(pump *dataTypesSettingsPump.Pump) pumpSettingsSchedules {
+	schedules := pumpSettingsSchedules{}
+	addInsulinSensitivityStartArray := func(name string, array *dataTypesSettingsPump.InsulinSensitivityStartArray) {
+		if array != nil {
+			for _, entry := range *array {
+				if entry != nil {
+					schedules.set(name, entry.Start, entry)
+				}
+			}
+		}
+	}
+	addInsulinSensitivityStartArray(PumpSettingsScheduleDefaultName, pump.InsulinSensitivitySchedule)
+	if pump.InsulinSensitivitySchedules != nil {
+		for name, array := range *pump.InsulinSensitivitySchedules {
+			addInsulinSensitivityStartArray(name, array)
+		}
+	}
+	return schedules
+}
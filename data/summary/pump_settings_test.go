@@ -0,0 +1,165 @@
+package summary_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	dataBloodGlucose "github.com/tidepool-org/platform/data/blood/glucose"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesSettingsPump "github.com/tidepool-org/platform/data/types/settings/pump"
+	"github.com/tidepool-org/platform/pointer"
+)
+
+func NewPumpSettings(tm string, activeScheduleName string, standardRate float64) *dataTypesSettingsPump.Pump {
+	datum := dataTypesSettingsPump.New()
+	datum.ID = pointer.FromString(tm)
+	datum.Time = pointer.FromString(tm)
+	datum.ActiveScheduleName = pointer.FromString(activeScheduleName)
+	datum.BasalRateSchedules = &dataTypesSettingsPump.BasalRateStartArrayMap{
+		"Standard": &dataTypesSettingsPump.BasalRateStartArray{
+			{Start: pointer.FromInt(0), Rate: pointer.FromFloat64(standardRate)},
+			{Start: pointer.FromInt(21600000), Rate: pointer.FromFloat64(1.0)},
+		},
+		"Weekend": &dataTypesSettingsPump.BasalRateStartArray{
+			{Start: pointer.FromInt(0), Rate: pointer.FromFloat64(0.7)},
+		},
+	}
+	datum.CarbohydrateRatioSchedule = &dataTypesSettingsPump.CarbohydrateRatioStartArray{
+		{Start: pointer.FromInt(0), Amount: pointer.FromFloat64(10)},
+	}
+	datum.BloodGlucoseTargetSchedule = &dataTypesSettingsPump.BloodGlucoseTargetStartArray{
+		{Start: pointer.FromInt(0), Target: dataBloodGlucose.Target{Low: pointer.FromFloat64(5), High: pointer.FromFloat64(6)}},
+	}
+	return datum
+}
+
+var _ = Describe("PumpSettings", func() {
+	Context("DiffPumpSettingsSchedules", func() {
+		It("returns no changes for identical schedules", func() {
+			Expect(summary.DiffPumpSettingsSchedules(NewPumpSettings("2020-03-01T00:00:00Z", "Standard", 0.8), NewPumpSettings("2020-03-02T00:00:00Z", "Standard", 0.8))).To(BeEmpty())
+		})
+
+		It("returns the added, changed and removed entries", func() {
+			previous := NewPumpSettings("2020-03-01T00:00:00Z", "Standard", 0.8)
+			current := NewPumpSettings("2020-03-02T00:00:00Z", "Standard", 0.9)
+			delete(*current.BasalRateSchedules, "Weekend")
+			*current.CarbohydrateRatioSchedule = append(*current.CarbohydrateRatioSchedule, &dataTypesSettingsPump.CarbohydrateRatioStart{Start: pointer.FromInt(43200000), Amount: pointer.FromFloat64(12)})
+			(*current.BloodGlucoseTargetSchedule)[0].High = pointer.FromFloat64(7)
+			current.InsulinSensitivitySchedule = &dataTypesSettingsPump.InsulinSensitivityStartArray{
+				{Start: pointer.FromInt(0), Amount: pointer.FromFloat64(2.5)},
+			}
+
+			Expect(summary.DiffPumpSettingsSchedules(previous, current)).To(Equal([]*summary.PumpSettingsScheduleChange{
+				{
+					Schedule: "basal", Name: "Standard", Start: 0, Change: "changed",
+					Previous: (*previous.BasalRateSchedules)["Standard"].First(), Current: (*current.BasalRateSchedules)["Standard"].First(),
+				},
+				{
+					Schedule: "basal", Name: "Weekend", Start: 0, Change: "removed",
+					Previous: (*previous.BasalRateSchedules)["Weekend"].First(),
+				},
+				{
+					Schedule: "bgTarget", Name: "default", Start: 0, Change: "changed",
+					Previous: (*previous.BloodGlucoseTargetSchedule)[0], Current: (*current.BloodGlucoseTargetSchedule)[0],
+				},
+				{
+					Schedule: "carbRatio", Name: "default", Start: 43200000, Change: "added",
+					Current: (*current.CarbohydrateRatioSchedule)[1],
+				},
+				{
+					Schedule: "insulinSensitivity", Name: "default", Start: 0, Change: "added",
+					Current: (*current.InsulinSensitivitySchedule)[0],
+				},
+			}))
+		})
+	})
+
+	Context("CalculatePumpSettingsHistory", func() {
+		var startTime time.Time
+		var endTime time.Time
+
+		BeforeEach(func() {
+			startTime = time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+			endTime = time.Date(2020, 3, 31, 0, 0, 0, 0, time.UTC)
+		})
+
+		It("returns no snapshots if there is no data", func() {
+			Expect(summary.CalculatePumpSettingsHistory(nil, startTime, endTime)).To(Equal(&summary.PumpSettingsHistory{
+				StartTime: "2020-03-01T00:00:00Z",
+				EndTime:   "2020-03-31T00:00:00Z",
+				Snapshots: []*summary.PumpSettingsSnapshot{},
+			}))
+		})
+
+		It("returns the snapshots with changes, ordered by time", func() {
+			first := NewPumpSettings("2020-03-02T00:00:00Z", "Standard", 0.8)
+			changed := NewPumpSettings("2020-03-06T00:00:00Z", "Standard", 0.9)
+			switched := NewPumpSettings("2020-03-08T00:00:00Z", "Weekend", 0.9)
+			datums := data.Data{
+				switched,
+				NewPumpSettings("2020-03-04T00:00:00Z", "Standard", 0.8),
+				first,
+				NewContinuous("2020-03-05T00:00:00Z", "mmol/L", 5),
+				changed,
+				NewPumpSettings("2020-03-07T00:00:00Z", "Standard", 0.9),
+				NewPumpSettings("2020-02-28T00:00:00Z", "Standard", 0.5),
+			}
+
+			history := summary.CalculatePumpSettingsHistory(datums, startTime, endTime)
+			Expect(history.Snapshots).To(HaveLen(3))
+
+			Expect(history.Snapshots[0].ID).To(Equal("2020-03-02T00:00:00Z"))
+			Expect(history.Snapshots[0].Settings).To(Equal(first))
+			Expect(history.Snapshots[0].Changes).To(BeEmpty())
+			Expect(history.Snapshots[0].ActiveScheduleChange).To(BeNil())
+
+			Expect(history.Snapshots[1].Time).To(Equal("2020-03-06T00:00:00Z"))
+			Expect(history.Snapshots[1].Settings).To(BeNil())
+			Expect(history.Snapshots[1].Changes).To(HaveLen(1))
+			Expect(history.Snapshots[1].Changes[0].Change).To(Equal("changed"))
+			Expect(history.Snapshots[1].ActiveScheduleChange).To(BeNil())
+
+			Expect(history.Snapshots[2].Time).To(Equal("2020-03-08T00:00:00Z"))
+			Expect(*history.Snapshots[2].ActiveScheduleName).To(Equal("Weekend"))
+			Expect(history.Snapshots[2].Changes).To(BeEmpty())
+			Expect(history.Snapshots[2].ActiveScheduleChange).To(Equal(&summary.PumpSettingsActiveScheduleChange{
+				Previous: pointer.FromString("Standard"),
+				Current:  pointer.FromString("Weekend"),
+			}))
+		})
+
+		It("compares each snapshot to the previous one of the same device", func() {
+			first := NewPumpSettings("2020-03-02T00:00:00Z", "Standard", 0.8)
+			first.DeviceID = pointer.FromString("pump-a")
+			other := NewPumpSettings("2020-03-03T00:00:00Z", "Weekend", 1.2)
+			other.DeviceID = pointer.FromString("pump-b")
+			same := NewPumpSettings("2020-03-04T00:00:00Z", "Standard", 0.8)
+			same.DeviceID = pointer.FromString("pump-a")
+			changed := NewPumpSettings("2020-03-05T00:00:00Z", "Weekend", 1.2)
+			changed.DeviceID = pointer.FromString("pump-b")
+			changed.CarbohydrateRatioSchedule = &dataTypesSettingsPump.CarbohydrateRatioStartArray{
+				{Start: pointer.FromInt(0), Amount: pointer.FromFloat64(12)},
+			}
+
+			history := summary.CalculatePumpSettingsHistory(data.Data{first, other, same, changed}, startTime, endTime)
+			Expect(history.Snapshots).To(HaveLen(3))
+
+			Expect(history.Snapshots[0].DeviceID).To(Equal("pump-a"))
+			Expect(history.Snapshots[0].Settings).To(Equal(first))
+
+			Expect(history.Snapshots[1].DeviceID).To(Equal("pump-b"))
+			Expect(history.Snapshots[1].Settings).To(Equal(other))
+			Expect(history.Snapshots[1].Changes).To(BeEmpty())
+			Expect(history.Snapshots[1].ActiveScheduleChange).To(BeNil())
+
+			Expect(history.Snapshots[2].DeviceID).To(Equal("pump-b"))
+			Expect(history.Snapshots[2].Settings).To(BeNil())
+			Expect(history.Snapshots[2].Changes).To(HaveLen(1))
+			Expect(history.Snapshots[2].Changes[0].Schedule).To(Equal(summary.PumpSettingsScheduleCarbohydrateRatio))
+			Expect(history.Snapshots[2].ActiveScheduleChange).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,78 @@
+package rebuild
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/tidepool-org/platform/config"
+	"github.com/tidepool-org/platform/errors"
+	"github.com/tidepool-org/platform/page"
+)
+
+const (
+	BatchSizeDefault  = 100
+	BatchDelayDefault = time.Second
+	IntervalDefault   = 5 * time.Minute
+)
+
+// Config of the rebuild of the pending daily summaries. The interval bounds the time the daily summaries lag the data.
+type Config struct {
+	BatchSize  int
+	BatchDelay time.Duration
+	Interval   time.Duration
+}
+
+func NewConfig() *Config {
+	return &Config{
+		BatchSize:  BatchSizeDefault,
+		BatchDelay: BatchDelayDefault,
+		Interval:   IntervalDefault,
+	}
+}
+
+func (c *Config) Load(configReporter config.Reporter) error {
+	if configReporter == nil {
+		return errors.New("config reporter is missing")
+	}
+
+	if batchSizeString, err := configReporter.Get("batch_size"); err == nil {
+		var batchSize int64
+		batchSize, err = strconv.ParseInt(batchSizeString, 10, 0)
+		if err != nil {
+			return errors.New("batch size is invalid")
+		}
+		c.BatchSize = int(batchSize)
+	}
+	if batchDelayString, err := configReporter.Get("batch_delay"); err == nil {
+		var batchDelay int64
+		batchDelay, err = strconv.ParseInt(batchDelayString, 10, 0)
+		if err != nil {
+			return errors.New("batch delay is invalid")
+		}
+		c.BatchDelay = time.Duration(batchDelay) * time.Second
+	}
+	if intervalString, err := configReporter.Get("interval"); err == nil {
+		var interval int64
+		interval, err = strconv.ParseInt(intervalString, 10, 0)
+		if err != nil {
+			return errors.New("interval is invalid")
+		}
+		c.Interval = time.Duration(interval) * time.Second
+	}
+
+	return nil
+}
+
+func (c *Config) Validate() error {
+	if c.BatchSize < page.PaginationSizeMinimum || c.BatchSize > page.PaginationSizeMaximum {
+		return errors.New("batch size is invalid")
+	}
+	if c.BatchDelay < 0 {
+		return errors.New("batch delay is invalid")
+	}
+	if c.Interval <= 0 {
+		return errors.New("interval is invalid")
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package rebuild_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	configTest "github.com/tidepool-org/platform/config/test"
+	dataSummaryRebuild "github.com/tidepool-org/platform/data/summary/rebuild"
+)
+
+var _ = Describe("Config", func() {
+	Context("NewConfig", func() {
+		It("returns the defaults", func() {
+			cfg := dataSummaryRebuild.NewConfig()
+			Expect(cfg).ToNot(BeNil())
+			Expect(cfg.BatchSize).To(Equal(dataSummaryRebuild.BatchSizeDefault))
+			Expect(cfg.BatchDelay).To(Equal(dataSummaryRebuild.BatchDelayDefault))
+			Expect(cfg.Interval).To(Equal(dataSummaryRebuild.IntervalDefault))
+			Expect(cfg.Validate()).To(Succeed())
+		})
+	})
+
+	Context("with new config", func() {
+		var configReporter *configTest.Reporter
+		var cfg *dataSummaryRebuild.Config
+
+		BeforeEach(func() {
+			configReporter = configTest.NewReporter()
+			cfg = dataSummaryRebuild.NewConfig()
+		})
+
+		Context("Load", func() {
+			It("returns an error if the config reporter is missing", func() {
+				Expect(cfg.Load(nil)).To(MatchError("config reporter is missing"))
+			})
+
+			It("keeps the defaults if not configured", func() {
+				Expect(cfg.Load(configReporter)).To(Succeed())
+				Expect(cfg).To(Equal(dataSummaryRebuild.NewConfig()))
+			})
+
+			It("returns an error if the batch size is invalid", func() {
+				configReporter.Config["batch_size"] = "abc"
+				Expect(cfg.Load(configReporter)).To(MatchError("batch size is invalid"))
+			})
+
+			It("returns an error if the batch delay is invalid", func() {
+				configReporter.Config["batch_delay"] = "abc"
+				Expect(cfg.Load(configReporter)).To(MatchError("batch delay is invalid"))
+			})
+
+			It("returns an error if the interval is invalid", func() {
+				configReporter.Config["interval"] = "abc"
+				Expect(cfg.Load(configReporter)).To(MatchError("interval is invalid"))
+			})
+
+			It("loads the configuration", func() {
+				configReporter.Config["batch_size"] = "50"
+				configReporter.Config["batch_delay"] = "2"
+				configReporter.Config["interval"] = "60"
+				Expect(cfg.Load(configReporter)).To(Succeed())
+				Expect(cfg.BatchSize).To(Equal(50))
+				Expect(cfg.BatchDelay).To(Equal(2 * time.Second))
+				Expect(cfg.Interval).To(Equal(time.Minute))
+			})
+		})
+
+		Context("Validate", func() {
+			It("returns an error if the batch size is less than the minimum", func() {
+				cfg.BatchSize = 0
+				Expect(cfg.Validate()).To(MatchError("batch size is invalid"))
+			})
+
+			It("returns an error if the batch size is greater than the maximum", func() {
+				cfg.BatchSize = 1001
+				Expect(cfg.Validate()).To(MatchError("batch size is invalid"))
+			})
+
+			It("returns an error if the batch delay is negative", func() {
+				cfg.BatchDelay = -time.Second
+				Expect(cfg.Validate()).To(MatchError("batch delay is invalid"))
+			})
+
+			It("returns an error if the interval is not positive", func() {
+				cfg.Interval = 0
+				Expect(cfg.Validate()).To(MatchError("interval is invalid"))
+			})
+		})
+	})
+})
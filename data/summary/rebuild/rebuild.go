@@ -0,0 +1,3 @@
+package rebuild
+
+const Type = "org.tidepool.data.summary.rebuild"
@@ -0,0 +1,14 @@
+package rebuild_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	dataSummaryRebuild "github.com/tidepool-org/platform/data/summary/rebuild"
+)
+
+var _ = Describe("Rebuild", func() {
+	It("Type is expected", func() {
+		Expect(dataSummaryRebuild.Type).To(Equal("org.tidepool.data.summary.rebuild"))
+	})
+})
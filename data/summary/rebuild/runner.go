@@ -0,0 +1,196 @@
+package rebuild
+
+import (
+	"context"
+	"time"
+
+	"github.com/tidepool-org/platform/auth"
+	dataClient "github.com/tidepool-org/platform/data/client"
+	"github.com/tidepool-org/platform/data/summary"
+	"github.com/tidepool-org/platform/errors"
+	"github.com/tidepool-org/platform/log"
+	"github.com/tidepool-org/platform/page"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/task"
+)
+
+const TaskDurationMaximum = 30 * time.Minute
+
+type Runner struct {
+	config     *Config
+	logger     log.Logger
+	authClient auth.Client
+	dataClient dataClient.Client
+}
+
+func NewRunner(cfg *Config, logger log.Logger, authClient auth.Client, dataClient dataClient.Client) (*Runner, error) {
+	if cfg == nil {
+		return nil, errors.New("config is missing")
+	} else if err := cfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "config is invalid")
+	}
+	if logger == nil {
+		return nil, errors.New("logger is missing")
+	}
+	if authClient == nil {
+		return nil, errors.New("auth client is missing")
+	}
+	if dataClient == nil {
+		return nil, errors.New("data client is missing")
+	}
+
+	return &Runner{
+		config:     cfg,
+		logger:     logger,
+		authClient: authClient,
+		dataClient: dataClient,
+	}, nil
+}
+
+func (r *Runner) Config() *Config {
+	return r.config
+}
+
+func (r *Runner) Logger() log.Logger {
+	return r.logger
+}
+
+func (r *Runner) AuthClient() auth.Client {
+	return r.authClient
+}
+
+func (r *Runner) DataClient() dataClient.Client {
+	return r.dataClient
+}
+
+func (r *Runner) CanRunTask(tsk *task.Task) bool {
+	return tsk != nil && tsk.Type == Type
+}
+
+func (r *Runner) Run(ctx context.Context, tsk *task.Task) {
+	now := time.Now()
+
+	ctx = log.NewContextWithLogger(ctx, r.Logger())
+
+	tsk.ClearError()
+
+	summary := NewSummary(now)
+
+	if serverSessionToken, sErr := r.AuthClient().ServerSessionToken(); sErr != nil {
+		tsk.AppendError(errors.Wrap(sErr, "unable to get server session token"))
+	} else {
+		ctx = auth.NewContextWithServerSessionToken(ctx, serverSessionToken)
+
+		if taskRunner, tErr := NewTaskRunner(r, tsk); tErr != nil {
+			tsk.AppendError(errors.Wrap(tErr, "unable to create task runner"))
+		} else if tErr = taskRunner.Run(ctx, summary); tErr != nil {
+			tsk.AppendError(errors.Wrap(tErr, "unable to run task runner"))
+		}
+	}
+
+	summary.Duration = time.Since(now)
+
+	if tsk.Data == nil {
+		tsk.Data = map[string]interface{}{}
+	}
+	tsk.Data["lastRun"] = summary.AsMap()
+
+	if !tsk.IsFailed() {
+		tsk.RepeatAvailableAfter(r.Config().Interval)
+	}
+
+	r.Logger().WithField("summary", summary).Info("Rebuilt pending daily summaries")
+
+	if summary.Duration > TaskDurationMaximum+r.Config().BatchDelay {
+		r.Logger().WithField("taskDuration", summary.Duration.Truncate(time.Millisecond).Seconds()).Warn("Task duration exceeds maximum")
+	}
+}
+
+// Summary of a single run of the rebuild task, recorded in the task data
+type Summary struct {
+	StartTime time.Time     `json:"startTime"`
+	Duration  time.Duration `json:"duration"`
+	Batches   int           `json:"batches"`
+	Rebuilt   int           `json:"rebuilt"`
+	Failed    int           `json:"failed"`
+	Complete  bool          `json:"complete"`
+}
+
+func NewSummary(startTime time.Time) *Summary {
+	return &Summary{
+		StartTime: startTime,
+	}
+}
+
+func (s *Summary) AsMap() map[string]interface{} {
+	return map[string]interface{}{
+		"startTime": s.StartTime.Truncate(time.Millisecond).Format(time.RFC3339Nano),
+		"duration":  s.Duration.Truncate(time.Millisecond).Seconds(),
+		"batches":   s.Batches,
+		"rebuilt":   s.Rebuilt,
+		"failed":    s.Failed,
+		"complete":  s.Complete,
+	}
+}
+
+type TaskRunner struct {
+	*Runner
+	task *task.Task
+}
+
+func NewTaskRunner(rnnr *Runner, tsk *task.Task) (*TaskRunner, error) {
+	if rnnr == nil {
+		return nil, errors.New("runner is missing")
+	}
+	if tsk == nil {
+		return nil, errors.New("task is missing")
+	}
+
+	return &TaskRunner{
+		Runner: rnnr,
+		task:   tsk,
+	}, nil
+}
+
+// Run rebuilds the daily summaries pending since before the start of the run in batches, until none remain or the
+// task duration maximum is reached. A pending rebuild is not selected again once run, whether rebuilt or failed, so
+// each batch is always the first page and each is counted once. A failed rebuild is run again by the next run.
+func (t *TaskRunner) Run(ctx context.Context, smmry *Summary) error {
+	if ctx == nil {
+		return errors.New("context is missing")
+	}
+	if smmry == nil {
+		return errors.New("summary is missing")
+	}
+
+	filter := summary.NewDailySummaryRebuildFilter()
+	filter.RequestedBefore = pointer.FromTime(smmry.StartTime)
+	pagination := page.NewPagination()
+	pagination.Size = t.Config().BatchSize
+
+	for time.Since(smmry.StartTime) < TaskDurationMaximum {
+		result, err := t.DataClient().RebuildPendingDailySummaries(ctx, filter, pagination)
+		if err != nil {
+			return errors.Wrap(err, "unable to rebuild pending daily summaries")
+		}
+
+		count := result.Rebuilt + result.Failed
+		if count > 0 {
+			smmry.Batches++
+			smmry.Rebuilt += result.Rebuilt
+			smmry.Failed += result.Failed
+		}
+		if count < pagination.Size {
+			smmry.Complete = true
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(t.Config().BatchDelay):
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package rebuild_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/auth"
+	authTest "github.com/tidepool-org/platform/auth/test"
+	dataClientTest "github.com/tidepool-org/platform/data/client/test"
+	"github.com/tidepool-org/platform/data/summary"
+	dataSummaryRebuild "github.com/tidepool-org/platform/data/summary/rebuild"
+	dataTest "github.com/tidepool-org/platform/data/test"
+	"github.com/tidepool-org/platform/errors"
+	logTest "github.com/tidepool-org/platform/log/test"
+	"github.com/tidepool-org/platform/task"
+)
+
+var _ = Describe("Runner", func() {
+	var cfg *dataSummaryRebuild.Config
+	var logger *logTest.Logger
+	var authClient *authTest.Client
+	var dataClient *dataClientTest.Client
+
+	BeforeEach(func() {
+		cfg = dataSummaryRebuild.NewConfig()
+		cfg.BatchSize = 2
+		cfg.BatchDelay = 0
+		logger = logTest.NewLogger()
+		authClient = authTest.NewClient()
+		dataClient = dataClientTest.NewClient()
+	})
+
+	AfterEach(func() {
+		dataClient.AssertOutputsEmpty()
+		authClient.AssertOutputsEmpty()
+	})
+
+	Context("NewRunner", func() {
+		It("returns an error if the config is missing", func() {
+			rnnr, err := dataSummaryRebuild.NewRunner(nil, logger, authClient, dataClient)
+			Expect(err).To(MatchError("config is missing"))
+			Expect(rnnr).To(BeNil())
+		})
+
+		It("returns an error if the config is invalid", func() {
+			cfg.BatchSize = 0
+			rnnr, err := dataSummaryRebuild.NewRunner(cfg, logger, authClient, dataClient)
+			Expect(err).To(MatchError("config is invalid; batch size is invalid"))
+			Expect(rnnr).To(BeNil())
+		})
+
+		It("returns an error if the logger is missing", func() {
+			rnnr, err := dataSummaryRebuild.NewRunner(cfg, nil, authClient, dataClient)
+			Expect(err).To(MatchError("logger is missing"))
+			Expect(rnnr).To(BeNil())
+		})
+
+		It("returns an error if the auth client is missing", func() {
+			rnnr, err := dataSummaryRebuild.NewRunner(cfg, logger, nil, dataClient)
+			Expect(err).To(MatchError("auth client is missing"))
+			Expect(rnnr).To(BeNil())
+		})
+
+		It("returns an error if the data client is missing", func() {
+			rnnr, err := dataSummaryRebuild.NewRunner(cfg, logger, authClient, nil)
+			Expect(err).To(MatchError("data client is missing"))
+			Expect(rnnr).To(BeNil())
+		})
+
+		It("returns successfully", func() {
+			rnnr, err := dataSummaryRebuild.NewRunner(cfg, logger, authClient, dataClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rnnr).ToNot(BeNil())
+			Expect(rnnr.Config()).To(Equal(cfg))
+			Expect(rnnr.Logger()).To(Equal(logger))
+			Expect(rnnr.AuthClient()).To(Equal(authClient))
+			Expect(rnnr.DataClient()).To(Equal(dataClient))
+		})
+	})
+
+	Context("with new runner", func() {
+		var rnnr *dataSummaryRebuild.Runner
+		var tsk *task.Task
+
+		BeforeEach(func() {
+			var err error
+			rnnr, err = dataSummaryRebuild.NewRunner(cfg, logger, authClient, dataClient)
+			Expect(err).ToNot(HaveOccurred())
+			tsk, err = task.NewTask(dataSummaryRebuild.NewTaskCreate())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Context("CanRunTask", func() {
+			It("returns false if the task is missing", func() {
+				Expect(rnnr.CanRunTask(nil)).To(BeFalse())
+			})
+
+			It("returns false if the task type is different", func() {
+				tsk.Type = "org.tidepool.other"
+				Expect(rnnr.CanRunTask(tsk)).To(BeFalse())
+			})
+
+			It("returns true if the task type is rebuild", func() {
+				Expect(rnnr.CanRunTask(tsk)).To(BeTrue())
+			})
+		})
+
+		Context("Run", func() {
+			var ctx context.Context
+			var token string
+
+			BeforeEach(func() {
+				ctx = context.Background()
+				token = dataTest.NewSessionToken()
+			})
+
+			It("records the error if the server session token cannot be obtained", func() {
+				authClient.ServerSessionTokenOutputs = []authTest.ServerSessionTokenOutput{{Error: errors.New("test error")}}
+				rnnr.Run(ctx, tsk)
+				Expect(tsk.HasError()).To(BeTrue())
+				Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("rebuilt", 0))
+				Expect(tsk.AvailableTime).ToNot(BeNil())
+			})
+
+			Context("with server session token", func() {
+				BeforeEach(func() {
+					authClient.ServerSessionTokenOutputs = []authTest.ServerSessionTokenOutput{{Token: token}}
+				})
+
+				It("rebuilds the daily summaries requested before the run with the server session token", func() {
+					dataClient.RebuildPendingDailySummariesOutputs = []dataClientTest.RebuildPendingDailySummariesOutput{{Result: &summary.DailySummaryRebuildResult{}}}
+					startTime := time.Now()
+					rnnr.Run(ctx, tsk)
+					Expect(tsk.HasError()).To(BeFalse())
+					Expect(dataClient.RebuildPendingDailySummariesInputs).To(HaveLen(1))
+					input := dataClient.RebuildPendingDailySummariesInputs[0]
+					Expect(auth.ServerSessionTokenFromContext(input.Context)).To(Equal(token))
+					Expect(*input.Filter.RequestedBefore).To(BeTemporally("~", startTime, time.Second))
+					Expect(input.Pagination.Page).To(Equal(0))
+					Expect(input.Pagination.Size).To(Equal(cfg.BatchSize))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("complete", true))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("batches", 0))
+					Expect(*tsk.AvailableTime).To(BeTemporally("~", startTime.Add(cfg.Interval), time.Second))
+				})
+
+				It("rebuilds in batches until none remain, counting each failure once", func() {
+					dataClient.RebuildPendingDailySummariesOutputs = []dataClientTest.RebuildPendingDailySummariesOutput{
+						{Result: &summary.DailySummaryRebuildResult{Rebuilt: 1, Failed: 1}},
+						{Result: &summary.DailySummaryRebuildResult{Rebuilt: 1}},
+					}
+					rnnr.Run(ctx, tsk)
+					Expect(tsk.HasError()).To(BeFalse())
+					Expect(dataClient.RebuildPendingDailySummariesInputs).To(HaveLen(2))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("batches", 2))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("rebuilt", 2))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("failed", 1))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("complete", true))
+				})
+
+				It("stops and records the error if the pending daily summaries cannot be rebuilt", func() {
+					dataClient.RebuildPendingDailySummariesOutputs = []dataClientTest.RebuildPendingDailySummariesOutput{{Error: errors.New("test error")}}
+					rnnr.Run(ctx, tsk)
+					Expect(tsk.HasError()).To(BeTrue())
+					Expect(tsk.IsFailed()).To(BeFalse())
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("complete", false))
+					Expect(tsk.AvailableTime).ToNot(BeNil())
+				})
+
+				It("stops between batches when the context is done", func() {
+					cfg.BatchDelay = time.Hour
+					cancelCtx, cancel := context.WithCancel(ctx)
+					cancel()
+					dataClient.RebuildPendingDailySummariesOutputs = []dataClientTest.RebuildPendingDailySummariesOutput{{Result: &summary.DailySummaryRebuildResult{Rebuilt: 2}}}
+					rnnr.Run(cancelCtx, tsk)
+					Expect(tsk.HasError()).To(BeFalse())
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("rebuilt", 2))
+					Expect(tsk.Data["lastRun"]).To(HaveKeyWithValue("complete", false))
+				})
+			})
+		})
+	})
+})
@@ -0,0 +1,23 @@
+package rebuild_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	dataSummaryRebuild "github.com/tidepool-org/platform/data/summary/rebuild"
+	"github.com/tidepool-org/platform/pointer"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
+)
+
+var _ = Describe("Task", func() {
+	Context("NewTaskCreate", func() {
+		It("returns a valid task create", func() {
+			taskCreate := dataSummaryRebuild.NewTaskCreate()
+			Expect(taskCreate).ToNot(BeNil())
+			Expect(taskCreate.Name).To(Equal(pointer.FromString(dataSummaryRebuild.TaskName)))
+			Expect(taskCreate.Type).To(Equal(dataSummaryRebuild.Type))
+			Expect(taskCreate.Data).To(BeEmpty())
+			Expect(structureValidator.New().Validate(taskCreate)).To(Succeed())
+		})
+	})
+})
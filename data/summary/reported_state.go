@@ -0,0 +1,307 @@
+package summary
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tidepool-org/platform/data"
+	dataTypesStateReported "github.com/tidepool-org/platform/data/types/state/reported"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/structure"
+)
+
+const (
+	ReportedStateDuration     = 24 * time.Hour
+	ReportedStateNone         = "none"
+	ReportedStateRangeDefault = 14 * 24 * time.Hour
+
+	SegmentByReportedState = "reportedState"
+)
+
+func SegmentBys() []string {
+	return []string{
+		SegmentByReportedState,
+	}
+}
+
+// SegmentOptions select how an analytics report is segmented, in addition to the whole range and the local days
+type SegmentOptions struct {
+	SegmentBy *string `json:"segmentBy,omitempty"`
+}
+
+func NewSegmentOptions() *SegmentOptions {
+	return &SegmentOptions{}
+}
+
+func (s *SegmentOptions) Parse(parser structure.ObjectParser) {
+	s.SegmentBy = parser.String("segmentBy")
+}
+
+func (s *SegmentOptions) Validate(validator structure.Validator) {
+	validator.String("segmentBy", s.SegmentBy).OneOf(SegmentBys()...)
+}
+
+func (s *SegmentOptions) Normalize(normalizer structure.Normalizer) {}
+
+func (s *SegmentOptions) SegmentByReportedState() bool {
+	return s != nil && pointer.ToString(s.SegmentBy) == SegmentByReportedState
+}
+
+// ReportedStatePeriod is a period during which a reported state was active, with the highest severity reported. A
+// state is active from the time it is reported until the next report not including it, and for at most
+// ReportedStateDuration after the last report including it.
+type ReportedStatePeriod struct {
+	State      string  `json:"state"`
+	StateOther *string `json:"stateOther,omitempty"`
+	Severity   *int    `json:"severity,omitempty"`
+	StartTime  string  `json:"startTime"`
+	EndTime    string  `json:"endTime"`
+	Reports    int     `json:"reports"`
+
+	startTime time.Time
+	endTime   time.Time
+}
+
+// ReportedStatePeriodsReport holds the reported state periods overlapping the range, ordered by start time
+type ReportedStatePeriodsReport struct {
+	StartTime string                 `json:"startTime"`
+	EndTime   string                 `json:"endTime"`
+	Periods   []*ReportedStatePeriod `json:"periods"`
+}
+
+// CalculateReportedStatePeriods returns the periods of the reported state datums overlapping the start and end
+// times, ignoring any other datums. The datums should include the reported states up to ReportedStateDuration before
+// the start time, since they may still be active at the start time.
+func CalculateReportedStatePeriods(datums data.Data, startTime time.Time, endTime time.Time) *ReportedStatePeriodsReport {
+	return &ReportedStatePeriodsReport{
+		StartTime: startTime.Format(time.RFC3339Nano),
+		EndTime:   endTime.Format(time.RFC3339Nano),
+		Periods:   reportedStatePeriods(datums, startTime, endTime),
+	}
+}
+
+func reportedStatePeriods(datums data.Data, startTime time.Time, endTime time.Time) []*ReportedStatePeriod {
+	type report struct {
+		time   time.Time
+		states []*dataTypesStateReported.State
+	}
+
+	reports := []report{}
+	for _, datum := range datums {
+		reported, ok := datum.(*dataTypesStateReported.Reported)
+		if !ok {
+			continue
+		}
+		tm, ok := LocalTime(&reported.Base)
+		if !ok {
+			continue
+		}
+		var states []*dataTypesStateReported.State
+		if reported.States != nil {
+			for _, state := range *reported.States {
+				if state != nil && state.State != nil {
+					states = append(states, state)
+				}
+			}
+		}
+		reports = append(reports, report{time: tm, states: states})
+	}
+	sort.SliceStable(reports, func(i int, j int) bool { return reports[i].time.Before(reports[j].time) })
+
+	periods := []*ReportedStatePeriod{}
+	active := map[string]*ReportedStatePeriod{}
+	end := func(key string, tm time.Time) {
+		period := active[key]
+		period.endTime = minimumTime(period.endTime, tm)
+		periods = append(periods, period)
+		delete(active, key)
+	}
+
+	for _, report := range reports {
+		reported := map[string]*dataTypesStateReported.State{}
+		for _, state := range report.states {
+			reported[reportedStateKey(state)] = state
+		}
+		for key, period := range active {
+			if _, ok := reported[key]; !ok || !period.endTime.After(report.time) {
+				end(key, report.time)
+			}
+		}
+		for key, state := range reported {
+			period, ok := active[key]
+			if !ok {
+				period = &ReportedStatePeriod{
+					State:      *state.State,
+					StateOther: pointer.CloneString(state.StateOther),
+					startTime:  report.time,
+				}
+				active[key] = period
+			}
+			period.endTime = report.time.Add(ReportedStateDuration)
+			period.Reports++
+			if state.Severity != nil && (period.Severity == nil || *state.Severity > *period.Severity) {
+				period.Severity = pointer.CloneInt(state.Severity)
+			}
+		}
+	}
+	for key, period := range active {
+		end(key, period.endTime)
+	}
+
+	result := []*ReportedStatePeriod{}
+	for _, period := range periods {
+		if period.endTime.Before(startTime) || period.startTime.After(endTime) {
+			continue
+		}
+		period.StartTime = period.startTime.Format(time.RFC3339Nano)
+		period.EndTime = period.endTime.Format(time.RFC3339Nano)
+		result = append(result, period)
+	}
+	sort.Slice(result, func(i int, j int) bool {
+		if !result[i].startTime.Equal(result[j].startTime) {
+			return result[i].startTime.Before(result[j].startTime)
+		}
+		return result[i].State < result[j].State
+	})
+	return result
+}
+
+func reportedStateKey(state *dataTypesStateReported.State) string {
+	if state.StateOther != nil {
+		return *state.State + "/" + *state.StateOther
+	}
+	return *state.State
+}
+
+// ReportedStateDates returns the states active during each local date, in the local time of the report
+func ReportedStateDates(periods []*ReportedStatePeriod) map[string][]string {
+	dates := map[string]map[string]bool{}
+	for _, period := range periods {
+		lastDate := period.endTime.Add(-time.Nanosecond).Format(DateFormat)
+		for tm := period.startTime; ; tm = tm.AddDate(0, 0, 1) {
+			date := tm.Format(DateFormat)
+			if date > lastDate {
+				break
+			}
+			if dates[date] == nil {
+				dates[date] = map[string]bool{}
+			}
+			dates[date][period.State] = true
+			if date == lastDate {
+				break
+			}
+		}
+	}
+
+	result := map[string][]string{}
+	for date, states := range dates {
+		for state := range states {
+			result[date] = append(result[date], state)
+		}
+		sort.Strings(result[date])
+	}
+	return result
+}
+
+// reportedStateSegments returns the segments of each local date, being the states active during the date, or
+// ReportedStateNone if no state was active
+func reportedStateSegments(stateDates map[string][]string, date string) []string {
+	if states, ok := stateDates[date]; ok {
+		return states
+	}
+	return []string{ReportedStateNone}
+}
+
+// GlucoseStatsSegment holds the glucose stats of the local days, with readings, during which the state was active
+type GlucoseStatsSegment struct {
+	State string        `json:"state"`
+	Dates []string      `json:"dates"`
+	Stats *GlucoseStats `json:"stats"`
+}
+
+// CalculateGlucoseStatsSegments segments the glucose stats calculated by CalculateGlucoseStats by the reported state
+// periods. A local day belongs to the segment of each state active during the day, or to the ReportedStateNone segment
+// if no state was active. The segments are ordered by state.
+func CalculateGlucoseStatsSegments(datums data.Data, options *GlucoseStatsOptions, periods []*ReportedStatePeriod, startTime time.Time, endTime time.Time) []*GlucoseStatsSegment {
+	type segmentAccumulator struct {
+		dates    []string
+		values   []float64
+		duration time.Duration
+	}
+
+	stateDates := ReportedStateDates(periods)
+	_, accumulators := accumulateGlucoseStats(datums, startTime, endTime)
+
+	segmentAccumulators := map[string]*segmentAccumulator{}
+	for _, accumulator := range accumulators {
+		dayStartTime := maximumTime(accumulator.dayStart, startTime)
+		dayEndTime := minimumTime(accumulator.dayStart.AddDate(0, 0, 1), endTime)
+		for _, state := range reportedStateSegments(stateDates, accumulator.date) {
+			segment, ok := segmentAccumulators[state]
+			if !ok {
+				segment = &segmentAccumulator{}
+				segmentAccumulators[state] = segment
+			}
+			segment.dates = append(segment.dates, accumulator.date)
+			segment.values = append(segment.values, accumulator.values...)
+			segment.duration += dayEndTime.Sub(dayStartTime)
+		}
+	}
+
+	sampleInterval := time.Duration(*options.SampleInterval) * time.Minute
+
+	segments := make([]*GlucoseStatsSegment, 0, len(segmentAccumulators))
+	for state, segment := range segmentAccumulators {
+		sort.Strings(segment.dates)
+		segments = append(segments, &GlucoseStatsSegment{
+			State: state,
+			Dates: segment.dates,
+			Stats: calculateGlucoseStats(segment.values, options, segment.duration, sampleInterval),
+		})
+	}
+	sort.Slice(segments, func(i int, j int) bool { return segments[i].State < segments[j].State })
+	return segments
+}
+
+// InsulinSegment holds the mean daily insulin, in units, of the local days, with insulin, during which the state
+// was active
+type InsulinSegment struct {
+	State string   `json:"state"`
+	Dates []string `json:"dates"`
+	Total float64  `json:"total"`
+	Basal float64  `json:"basal"`
+	Bolus float64  `json:"bolus"`
+}
+
+// CalculateInsulinSegments segments the insulin daily totals by the reported state periods, as
+// CalculateGlucoseStatsSegments does. The segments are ordered by state.
+func CalculateInsulinSegments(daily []*InsulinDailyTotal, periods []*ReportedStatePeriod) []*InsulinSegment {
+	stateDates := ReportedStateDates(periods)
+
+	segmentsByState := map[string]*InsulinSegment{}
+	for _, total := range daily {
+		for _, state := range reportedStateSegments(stateDates, total.Date) {
+			segment, ok := segmentsByState[state]
+			if !ok {
+				segment = &InsulinSegment{State: state}
+				segmentsByState[state] = segment
+			}
+			segment.Dates = append(segment.Dates, total.Date)
+			segment.Total += total.Total
+			segment.Basal += total.Basal
+			segment.Bolus += total.Bolus
+		}
+	}
+
+	segments := make([]*InsulinSegment, 0, len(segmentsByState))
+	for _, segment := range segmentsByState {
+		days := float64(len(segment.Dates))
+		segment.Total /= days
+		segment.Basal /= days
+		segment.Bolus /= days
+		sort.Strings(segment.Dates)
+		segments = append(segments, segment)
+	}
+	sort.Slice(segments, func(i int, j int) bool { return segments[i].State < segments[j].State })
+	return segments
+}
@@ -0,0 +1,158 @@
+package summary_test
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	gomegaTypes "github.com/onsi/gomega/types"
+
+	"github.com/tidepool-org/platform/data"
+	"github.com/tidepool-org/platform/data/summary"
+	dataTypesStateReported "github.com/tidepool-org/platform/data/types/state/reported"
+	"github.com/tidepool-org/platform/pointer"
+	"github.com/tidepool-org/platform/request"
+)
+
+func NewReported(tm string, states ...*dataTypesStateReported.State) *dataTypesStateReported.Reported {
+	datum := dataTypesStateReported.New()
+	datum.Time = pointer.FromString(tm)
+	stateArray := dataTypesStateReported.StateArray(states)
+	datum.States = &stateArray
+	return datum
+}
+
+func NewState(state string, severity int) *dataTypesStateReported.State {
+	return &dataTypesStateReported.State{State: pointer.FromString(state), Severity: pointer.FromInt(severity)}
+}
+
+func DecodeSegmentOptions(query string) (*summary.SegmentOptions, error) {
+	options := summary.NewSegmentOptions()
+	req := &http.Request{URL: &url.URL{RawQuery: query}}
+	return options, request.DecodeRequestQuery(req, options)
+}
+
+var _ = Describe("ReportedState", func() {
+	var startTime time.Time
+	var endTime time.Time
+
+	BeforeEach(func() {
+		startTime = time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+		endTime = time.Date(2020, 3, 5, 0, 0, 0, 0, time.UTC)
+	})
+
+	Context("SegmentOptions", func() {
+		It("segments by reported state", func() {
+			options, err := DecodeSegmentOptions("segmentBy=reportedState")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(options.SegmentByReportedState()).To(BeTrue())
+		})
+
+		It("does not segment by default", func() {
+			options, err := DecodeSegmentOptions("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(options.SegmentByReportedState()).To(BeFalse())
+		})
+
+		It("rejects an unknown segment", func() {
+			_, err := DecodeSegmentOptions("segmentBy=weekday")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("CalculateReportedStatePeriods", func() {
+		It("returns no periods if there is no data", func() {
+			report := summary.CalculateReportedStatePeriods(nil, startTime, endTime)
+			Expect(report.StartTime).To(Equal("2020-03-01T00:00:00Z"))
+			Expect(report.Periods).To(BeEmpty())
+		})
+
+		It("extends a state reported again, ends it when no longer reported, and keeps the highest severity", func() {
+			datums := data.Data{
+				NewReported("2020-03-01T08:00:00Z", NewState("illness", 3), NewState("stress", 5)),
+				NewReported("2020-03-01T20:00:00Z", NewState("illness", 7)),
+				NewReported("2020-03-02T10:00:00Z", NewState("illness", 2)),
+			}
+			Expect(summary.CalculateReportedStatePeriods(datums, startTime, endTime).Periods).To(ConsistOf(
+				PointTo(MatchReportedStatePeriod("illness", "2020-03-01T08:00:00Z", "2020-03-03T10:00:00Z", 7, 3)),
+				PointTo(MatchReportedStatePeriod("stress", "2020-03-01T08:00:00Z", "2020-03-01T20:00:00Z", 5, 1)),
+			))
+		})
+
+		It("ends a state after the duration without being reported again", func() {
+			datums := data.Data{
+				NewReported("2020-03-01T08:00:00Z", NewState("alcohol", 1)),
+				NewReported("2020-03-03T08:00:00Z", NewState("alcohol", 1)),
+			}
+			periods := summary.CalculateReportedStatePeriods(datums, startTime, endTime).Periods
+			Expect(periods).To(HaveLen(2))
+			Expect(periods[0].EndTime).To(Equal("2020-03-02T08:00:00Z"))
+			Expect(periods[1].StartTime).To(Equal("2020-03-03T08:00:00Z"))
+		})
+
+		It("returns a period started before the range and still active at its start", func() {
+			datums := data.Data{
+				NewReported("2020-02-29T12:00:00Z", NewState("cycle", 4)),
+				NewReported("2020-02-27T12:00:00Z", NewState("illness", 4)),
+			}
+			periods := summary.CalculateReportedStatePeriods(datums, startTime, endTime).Periods
+			Expect(periods).To(HaveLen(1))
+			Expect(periods[0].State).To(Equal("cycle"))
+			Expect(periods[0].StartTime).To(Equal("2020-02-29T12:00:00Z"))
+		})
+	})
+
+	Context("segments", func() {
+		var periods []*summary.ReportedStatePeriod
+
+		BeforeEach(func() {
+			periods = summary.CalculateReportedStatePeriods(data.Data{
+				NewReported("2020-03-02T08:00:00Z", NewState("illness", 3)),
+				NewReported("2020-03-02T20:00:00Z"),
+			}, startTime, endTime).Periods
+		})
+
+		It("returns the states active during each local date", func() {
+			Expect(summary.ReportedStateDates(periods)).To(Equal(map[string][]string{"2020-03-02": {"illness"}}))
+		})
+
+		It("segments the glucose stats by the reported states of the local days", func() {
+			datums := append(NewContinuousSeries(time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC), 5, 7), NewContinuousSeries(time.Date(2020, 3, 2, 12, 0, 0, 0, time.UTC), 12, 14)...)
+			options, err := DecodeGlucoseStatsOptions("")
+			Expect(err).ToNot(HaveOccurred())
+			segments := summary.CalculateGlucoseStatsSegments(datums, options, periods, startTime, endTime)
+			Expect(segments).To(HaveLen(2))
+			Expect(segments[0].State).To(Equal("illness"))
+			Expect(segments[0].Dates).To(Equal([]string{"2020-03-02"}))
+			Expect(*segments[0].Stats.Mean).To(Equal(13.0))
+			Expect(segments[1].State).To(Equal("none"))
+			Expect(segments[1].Dates).To(Equal([]string{"2020-03-01"}))
+			Expect(*segments[1].Stats.Mean).To(Equal(6.0))
+		})
+
+		It("segments the insulin daily totals by the reported states of the local days", func() {
+			daily := []*summary.InsulinDailyTotal{
+				{Date: "2020-03-01", Total: 30, Basal: 15, Bolus: 15},
+				{Date: "2020-03-02", Total: 40, Basal: 20, Bolus: 20},
+				{Date: "2020-03-03", Total: 20, Basal: 15, Bolus: 5},
+			}
+			Expect(summary.CalculateInsulinSegments(daily, periods)).To(Equal([]*summary.InsulinSegment{
+				{State: "illness", Dates: []string{"2020-03-02"}, Total: 40, Basal: 20, Bolus: 20},
+				{State: "none", Dates: []string{"2020-03-01", "2020-03-03"}, Total: 25, Basal: 15, Bolus: 10},
+			}))
+		})
+	})
+})
+
+func MatchReportedStatePeriod(state string, startTime string, endTime string, severity int, reports int) gomegaTypes.GomegaMatcher {
+	return MatchFields(IgnoreExtras, Fields{
+		"State":     Equal(state),
+		"StartTime": Equal(startTime),
+		"EndTime":   Equal(endTime),
+		"Severity":  Equal(pointer.FromInt(severity)),
+		"Reports":   Equal(reports),
+	})
+}
@@ -0,0 +1,62 @@
+package summary
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tidepool-org/platform/data"
+	"github.com/tidepool-org/platform/data/types"
+)
+
+const DateFormat = "2006-01-02"
+
+// locations caches the locations by time zone name, nil if the name is not valid, since loading a location reads the
+// time zone database each time
+var (
+	locations      = map[string]*time.Location{}
+	locationsMutex sync.Mutex
+)
+
+func loadLocation(name string) *time.Location {
+	locationsMutex.Lock()
+	defer locationsMutex.Unlock()
+
+	location, ok := locations[name]
+	if !ok {
+		location, _ = time.LoadLocation(name)
+		locations[name] = location
+	}
+	return location
+}
+
+// LocalTime returns the time of the datum in the local time of the device, using the time zone name when
+// known, otherwise the time zone offset, otherwise UTC
+func LocalTime(datum *types.Base) (time.Time, bool) {
+	if datum == nil || datum.Time == nil {
+		return time.Time{}, false
+	}
+
+	tm, err := time.Parse(data.TimeFormat, *datum.Time)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	if datum.TimeZoneName != nil {
+		if location := loadLocation(*datum.TimeZoneName); location != nil {
+			return tm.In(location), true
+		}
+	}
+	if datum.TimeZoneOffset != nil {
+		return tm.In(time.FixedZone("", *datum.TimeZoneOffset*60)), true
+	}
+	return tm.UTC(), true
+}
+
+// LocalDate returns the local date of the datum formatted with DateFormat
+func LocalDate(datum *types.Base) (string, bool) {
+	tm, ok := LocalTime(datum)
+	if !ok {
+		return "", false
+	}
+	return tm.Format(DateFormat), true
+}
@@ -0,0 +1,104 @@
+package summary_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data/summary"
+	"github.com/tidepool-org/platform/data/types"
+	"github.com/tidepool-org/platform/pointer"
+)
+
+var _ = Describe("Summary", func() {
+	It("DateFormat is expected", func() {
+		Expect(summary.DateFormat).To(Equal("2006-01-02"))
+	})
+
+	Context("LocalTime", func() {
+		var datum *types.Base
+
+		BeforeEach(func() {
+			base := types.New("water")
+			datum = &base
+			datum.Time = pointer.FromString("2020-03-01T03:30:00Z")
+		})
+
+		It("returns false if the datum is missing", func() {
+			_, ok := summary.LocalTime(nil)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("returns false if the time is missing", func() {
+			datum.Time = nil
+			_, ok := summary.LocalTime(datum)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("returns false if the time is invalid", func() {
+			datum.Time = pointer.FromString("invalid")
+			_, ok := summary.LocalTime(datum)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("returns the time in UTC without time zone", func() {
+			tm, ok := summary.LocalTime(datum)
+			Expect(ok).To(BeTrue())
+			Expect(tm.Format(time.RFC3339)).To(Equal("2020-03-01T03:30:00Z"))
+		})
+
+		It("returns the time using the time zone offset", func() {
+			datum.TimeZoneOffset = pointer.FromInt(-300)
+			tm, ok := summary.LocalTime(datum)
+			Expect(ok).To(BeTrue())
+			Expect(tm.Format(time.RFC3339)).To(Equal("2020-02-29T22:30:00-05:00"))
+		})
+
+		It("returns the time using the time zone name before the time zone offset", func() {
+			datum.TimeZoneName = pointer.FromString("Europe/Paris")
+			datum.TimeZoneOffset = pointer.FromInt(-300)
+			tm, ok := summary.LocalTime(datum)
+			Expect(ok).To(BeTrue())
+			Expect(tm.Format(time.RFC3339)).To(Equal("2020-03-01T04:30:00+01:00"))
+		})
+
+		It("returns the time using the time zone offset if the time zone name is invalid", func() {
+			datum.TimeZoneName = pointer.FromString("Invalid/Zone")
+			datum.TimeZoneOffset = pointer.FromInt(60)
+			tm, ok := summary.LocalTime(datum)
+			Expect(ok).To(BeTrue())
+			Expect(tm.Format(time.RFC3339)).To(Equal("2020-03-01T04:30:00+01:00"))
+		})
+
+		It("returns the same time for a time zone name already loaded, whether valid or not", func() {
+			for _, timeZoneName := range []string{"America/New_York", "Invalid/Zone"} {
+				datum.TimeZoneName = pointer.FromString(timeZoneName)
+				datum.TimeZoneOffset = pointer.FromInt(60)
+				first, ok := summary.LocalTime(datum)
+				Expect(ok).To(BeTrue())
+				second, ok := summary.LocalTime(datum)
+				Expect(ok).To(BeTrue())
+				Expect(second.Format(time.RFC3339)).To(Equal(first.Format(time.RFC3339)))
+				Expect(second.Location()).To(Equal(first.Location()))
+			}
+		})
+	})
+
+	Context("LocalDate", func() {
+		It("returns false if the time is missing", func() {
+			base := types.New("water")
+			_, ok := summary.LocalDate(&base)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("returns the local date", func() {
+			base := types.New("water")
+			base.Time = pointer.FromString("2020-03-01T03:30:00Z")
+			base.TimeZoneOffset = pointer.FromInt(-300)
+			date, ok := summary.LocalDate(&base)
+			Expect(ok).To(BeTrue())
+			Expect(date).To(Equal("2020-02-29"))
+		})
+	})
+})
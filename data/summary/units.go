@@ -0,0 +1,123 @@
+package summary
+
+import (
+	dataBloodGlucose "github.com/tidepool-org/platform/data/blood/glucose"
+	"github.com/tidepool-org/platform/pointer"
+)
+
+// ConvertGlucoseUnits converts the glucose values of the report, calculated in mmol/L, including the glucose targets of
+// its options, to the units, rounded to the precision displayed by devices (see dataBloodGlucose.ConvertValueToUnits).
+// Percentages and durations are unchanged. The report is unchanged if the units are unknown.
+func (g *GlucoseStatsReport) ConvertGlucoseUnits(units string) {
+	if !isGlucoseUnits(&units) {
+		return
+	}
+	g.Units = *dataBloodGlucose.CanonicalUnits(&units)
+	if g.Options != nil {
+		g.Options.VeryLow = dataBloodGlucose.ConvertValueToUnits(g.Options.VeryLow, &units)
+		g.Options.Low = dataBloodGlucose.ConvertValueToUnits(g.Options.Low, &units)
+		g.Options.High = dataBloodGlucose.ConvertValueToUnits(g.Options.High, &units)
+		g.Options.VeryHigh = dataBloodGlucose.ConvertValueToUnits(g.Options.VeryHigh, &units)
+		g.Options.Units = dataBloodGlucose.CanonicalUnits(&units)
+	}
+	g.Range.convertGlucoseUnits(units)
+	for _, stats := range g.Daily {
+		stats.convertGlucoseUnits(units)
+	}
+	for _, segment := range g.Segments {
+		segment.Stats.convertGlucoseUnits(units)
+	}
+}
+
+func (g *GlucoseStats) convertGlucoseUnits(units string) {
+	if g != nil {
+		g.Mean = dataBloodGlucose.ConvertValueToUnits(g.Mean, &units)
+	}
+}
+
+// ConvertGlucoseUnits converts the glucose percentiles of the report to the units, as for the glucose stats report
+func (a *AGPReport) ConvertGlucoseUnits(units string) {
+	if !isGlucoseUnits(&units) {
+		return
+	}
+	a.Units = *dataBloodGlucose.CanonicalUnits(&units)
+	for _, bucket := range a.Buckets {
+		bucket.Percentile5 = dataBloodGlucose.ConvertValueToUnits(bucket.Percentile5, &units)
+		bucket.Percentile25 = dataBloodGlucose.ConvertValueToUnits(bucket.Percentile25, &units)
+		bucket.Percentile50 = dataBloodGlucose.ConvertValueToUnits(bucket.Percentile50, &units)
+		bucket.Percentile75 = dataBloodGlucose.ConvertValueToUnits(bucket.Percentile75, &units)
+		bucket.Percentile95 = dataBloodGlucose.ConvertValueToUnits(bucket.Percentile95, &units)
+	}
+}
+
+// ConvertGlucoseUnits converts the glucose thresholds, nadirs and peaks of the report to the units, as for the glucose
+// stats report
+func (g *GlycemicEventsReport) ConvertGlucoseUnits(units string) {
+	if !isGlucoseUnits(&units) {
+		return
+	}
+	if g.Options != nil {
+		g.Options.Low = dataBloodGlucose.ConvertValueToUnits(g.Options.Low, &units)
+		g.Options.VeryLow = dataBloodGlucose.ConvertValueToUnits(g.Options.VeryLow, &units)
+		g.Options.High = dataBloodGlucose.ConvertValueToUnits(g.Options.High, &units)
+		g.Options.Units = dataBloodGlucose.CanonicalUnits(&units)
+	}
+	for _, event := range g.Events {
+		if event.Nadir != nil {
+			event.Nadir.Value = *dataBloodGlucose.ConvertValueToUnits(&event.Nadir.Value, &units)
+		}
+		if event.Peak != nil {
+			event.Peak.Value = *dataBloodGlucose.ConvertValueToUnits(&event.Peak.Value, &units)
+		}
+	}
+}
+
+// ConvertGlucoseUnits converts the glucose readings and deltas of the report to the units, as for the glucose stats
+// report
+func (m *MealsReport) ConvertGlucoseUnits(units string) {
+	if !isGlucoseUnits(&units) {
+		return
+	}
+	m.Units = *dataBloodGlucose.CanonicalUnits(&units)
+	for _, meal := range m.Meals {
+		if meal.PreMealGlucose != nil {
+			meal.PreMealGlucose.Value = *dataBloodGlucose.ConvertValueToUnits(&meal.PreMealGlucose.Value, &units)
+		}
+		if meal.PostPrandialPeak != nil {
+			meal.PostPrandialPeak.Value = *dataBloodGlucose.ConvertValueToUnits(&meal.PostPrandialPeak.Value, &units)
+		}
+		// The delta is that of the converted values, as displayed
+		if meal.PreMealGlucose != nil && meal.PostPrandialPeak != nil {
+			meal.PostPrandialDelta = pointer.FromFloat64(dataBloodGlucose.RoundValueForUnits(meal.PostPrandialPeak.Value-meal.PreMealGlucose.Value, &units))
+		} else {
+			meal.PostPrandialDelta = dataBloodGlucose.ConvertValueToUnits(meal.PostPrandialDelta, &units)
+		}
+	}
+}
+
+// ConvertGlucoseUnits converts the alert levels of the report to the units, as for the glucose stats report
+func (a *AlarmsReport) ConvertGlucoseUnits(units string) {
+	if !isGlucoseUnits(&units) {
+		return
+	}
+	a.Units = *dataBloodGlucose.CanonicalUnits(&units)
+	for _, alertConfiguration := range a.AlertConfigurations {
+		alertConfiguration.Level = dataBloodGlucose.ConvertValueToUnits(alertConfiguration.Level, &units)
+	}
+}
+
+// ConvertGlucoseUnits converts the mean glucose of the daily summary to the units, as for the glucose stats report
+func (d *DailySummary) ConvertGlucoseUnits(units string) {
+	if !isGlucoseUnits(&units) {
+		return
+	}
+	d.Glucose.convertGlucoseUnits(units)
+}
+
+func isGlucoseUnits(units *string) bool {
+	switch *dataBloodGlucose.CanonicalUnits(units) {
+	case dataBloodGlucose.MmolL, dataBloodGlucose.MgdL:
+		return true
+	}
+	return false
+}
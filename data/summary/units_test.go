@@ -0,0 +1,98 @@
+package summary_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data"
+	"github.com/tidepool-org/platform/data/summary"
+	"github.com/tidepool-org/platform/pointer"
+)
+
+var _ = Describe("Units", func() {
+	var startTime time.Time
+	var endTime time.Time
+
+	BeforeEach(func() {
+		startTime = time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+		endTime = time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC)
+	})
+
+	It("converts the mean glucose and targets of the glucose stats, leaving the percentages unchanged", func() {
+		options, err := DecodeGlucoseStatsOptions("units=mg/dL&low=70&high=180")
+		Expect(err).ToNot(HaveOccurred())
+		report := summary.CalculateGlucoseStats(NewContinuousSeries(startTime, 5.55075, 6.66090), options, startTime, endTime)
+		timeInRange := *report.Range.TimeInRange
+		report.ConvertGlucoseUnits("mg/dl")
+		Expect(report.Units).To(Equal("mg/dL"))
+		Expect(*report.Options.Units).To(Equal("mg/dL"))
+		Expect(*report.Options.Low).To(Equal(70.0))
+		Expect(*report.Options.High).To(Equal(180.0))
+		Expect(*report.Options.VeryLow).To(Equal(54.0))
+		Expect(*report.Range.Mean).To(Equal(110.0))
+		Expect(*report.Daily[0].Mean).To(Equal(110.0))
+		Expect(*report.Range.TimeInRange).To(Equal(timeInRange))
+	})
+
+	It("rounds the glucose stats to one decimal in mmol/L", func() {
+		options, err := DecodeGlucoseStatsOptions("")
+		Expect(err).ToNot(HaveOccurred())
+		report := summary.CalculateGlucoseStats(NewContinuousSeries(startTime, 5.55075, 6.66090), options, startTime, endTime)
+		report.ConvertGlucoseUnits("mmol/L")
+		Expect(*report.Range.Mean).To(Equal(6.1))
+	})
+
+	It("leaves the report unchanged for unknown units", func() {
+		options, err := DecodeGlucoseStatsOptions("")
+		Expect(err).ToNot(HaveOccurred())
+		report := summary.CalculateGlucoseStats(NewContinuousSeries(startTime, 5.55075), options, startTime, endTime)
+		report.ConvertGlucoseUnits("unknown")
+		Expect(report.Units).To(Equal("mmol/L"))
+		Expect(*report.Range.Mean).To(Equal(5.55075))
+	})
+
+	It("converts the percentiles of the AGP", func() {
+		report := summary.CalculateAGP(NewContinuousSeries(startTime, 5.55075), startTime, endTime)
+		report.ConvertGlucoseUnits("mg/dL")
+		Expect(report.Units).To(Equal("mg/dL"))
+		Expect(*report.Buckets[0].Percentile50).To(Equal(100.0))
+		Expect(report.Buckets[1].Percentile50).To(BeNil())
+	})
+
+	It("converts the thresholds, nadirs and peaks of the glycemic events", func() {
+		datums := NewContinuousSeries(startTime.Add(time.Hour), 5, 3.5, 3.4, 3.2, 3.6, 3.7, 4.5, 4.6, 4.7, 4.8, 5)
+		report := summary.CalculateGlycemicEvents(datums, summary.NewGlycemicEventsOptions(), startTime, endTime)
+		report.ConvertGlucoseUnits("mg/dL")
+		Expect(*report.Options.Units).To(Equal("mg/dL"))
+		Expect(*report.Options.Low).To(Equal(70.0))
+		Expect(*report.Options.High).To(Equal(250.0))
+		Expect(report.Options.LowDuration).To(Equal(pointer.FromInt(15)))
+		Expect(report.Events[0].Nadir.Value).To(Equal(58.0))
+		Expect(report.Events[0].Duration).To(Equal(25.0))
+	})
+
+	It("converts the readings of the meals, with the delta of the converted readings", func() {
+		readings := NewContinuousSeries(time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC), 5.48, 7, 9.74)
+		report := summary.CalculateMeals(append(data.Data{NewFood("food", "2020-03-01T12:00:00Z", 30)}, readings...), startTime, endTime)
+		report.ConvertGlucoseUnits("mg/dL")
+		Expect(report.Units).To(Equal("mg/dL"))
+		Expect(report.Meals[0].PreMealGlucose.Value).To(Equal(99.0))
+		Expect(report.Meals[0].PostPrandialPeak.Value).To(Equal(175.0))
+		Expect(*report.Meals[0].PostPrandialDelta).To(Equal(76.0))
+	})
+
+	It("converts the mean glucose of the daily summary", func() {
+		dailySummary := &summary.DailySummary{Date: "2020-03-01", Glucose: &summary.GlucoseStats{Count: 1, Mean: pointer.FromFloat64(9.99135)}, Carbohydrate: 30}
+		dailySummary.ConvertGlucoseUnits("mg/dL")
+		Expect(*dailySummary.Glucose.Mean).To(Equal(180.0))
+		Expect(dailySummary.Carbohydrate).To(Equal(30.0))
+	})
+
+	It("converts a daily summary without glucose", func() {
+		dailySummary := &summary.DailySummary{Date: "2020-03-01"}
+		dailySummary.ConvertGlucoseUnits("mg/dL")
+		Expect(dailySummary.Glucose).To(BeNil())
+	})
+})
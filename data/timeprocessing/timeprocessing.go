@@ -0,0 +1,249 @@
+package timeprocessing
+
+import (
+	"time"
+
+	dataTypes "github.com/tidepool-org/platform/data/types"
+	dataTypesDevice "github.com/tidepool-org/platform/data/types/device"
+	dataTypesDeviceTimechange "github.com/tidepool-org/platform/data/types/device/timechange"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	"github.com/tidepool-org/platform/errors"
+	structureParser "github.com/tidepool-org/platform/structure/parser"
+	timeZone "github.com/tidepool-org/platform/time/zone"
+)
+
+// Process computes the time, time zone offset and conversion offset of the raw data with a device time but without a
+// time, as the uploaders used to, following the time processing of the data set:
+//
+// - across-the-board-timezone: the device time is the local time in the time zone of the data set
+//
+// - utc-bootstrapping: at the end of the data, the device clock is assumed to be off the local time in the time zone of
+// the data set by the difference between the device time and the computer time of the data set, as recorded by the
+// uploader, if both present, otherwise to be set to the local time. Before each time change, the device clock is
+// assumed to have run accurately up to the change, whose time is that of its to time after the change. The data
+// before the change are local to its from time zone name, if any, otherwise to the time zone after the change.
+//
+// The data are in the order recorded by the device, as sent by the uploaders, so that the time changes apply to the
+// data preceding them, not to the data following them. A time change is recorded with the clock before the change.
+// The conversion offset is the difference, in milliseconds, between the device time recorded by the device and the
+// local time, to which the device time is then normalized, as for any datum. The daylight saving time transitions are
+// those of the time zone. The raw data that cannot be processed are left unchanged, to be reported by validation. An
+// error is returned only if the time zone name of the data set is missing or not valid while any raw datum must be
+// processed.
+//
+// Process only processes the raw data given, as if they were the end of the data. With utc-bootstrapping, a time
+// change only corrects the data preceding it in the data sent in multiple batches if all the batches are processed
+// by the same Processor, from the last to the first, as done when the data set is closed (see Deferred).
+func Process(dataSet *dataTypesUpload.Upload, rawData []interface{}) error {
+	if processor := NewProcessor(dataSet); processor != nil {
+		return processor.Process(rawData)
+	}
+	return nil
+}
+
+// Deferred returns true if the processing of the raw data must be deferred until the data set is closed, so that all
+// its batches are processed together; that is, if any raw datum must be processed with utc-bootstrapping and the data
+// set is closed once all its data are sent. The data of a continuous data set, which is never closed, are processed
+// batch by batch.
+func Deferred(dataSet *dataTypesUpload.Upload, rawData []interface{}) bool {
+	if dataSet == nil || dataSet.TimeProcessing == nil || *dataSet.TimeProcessing != dataTypesUpload.TimeProcessingUTCBootstrapping {
+		return false
+	}
+	if dataSet.DataSetType != nil && *dataSet.DataSetType == dataTypesUpload.DataSetTypeContinuous {
+		return false
+	}
+	for _, rawDatum := range rawData {
+		if object, ok := rawDatum.(map[string]interface{}); ok {
+			if _, ok = parseDeviceTime(object); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Processor processes the raw data of a data set sent in multiple batches, keeping the device clock from one batch to
+// the previous one, so that the batches must be processed from the last to the first
+type Processor struct {
+	dataSet       *dataTypesUpload.Upload
+	bootstrapping bool
+	clock         *clock
+}
+
+// NewProcessor returns the processor of the data set, or nil if the data set has no time processing to do
+func NewProcessor(dataSet *dataTypesUpload.Upload) *Processor {
+	if dataSet == nil || dataSet.TimeProcessing == nil {
+		return nil
+	}
+
+	switch *dataSet.TimeProcessing {
+	case dataTypesUpload.TimeProcessingAcrossTheBoardTimeZone:
+		return &Processor{dataSet: dataSet}
+	case dataTypesUpload.TimeProcessingUTCBootstrapping:
+		return &Processor{dataSet: dataSet, bootstrapping: true}
+	}
+	return nil
+}
+
+// Process processes the raw data of a batch, which precedes the batches already processed, if any (see Process)
+func (p *Processor) Process(rawData []interface{}) error {
+	objects := []map[string]interface{}{}
+	deviceTimes := map[int]time.Time{}
+	for index, rawDatum := range rawData {
+		object, ok := rawDatum.(map[string]interface{})
+		if !ok {
+			object = nil
+		} else if deviceTime, ok := parseDeviceTime(object); ok {
+			deviceTimes[index] = deviceTime
+		}
+		objects = append(objects, object)
+	}
+	if len(deviceTimes) == 0 && (!p.bootstrapping || p.clock != nil) {
+		return nil
+	}
+
+	if p.clock == nil {
+		deviceClock, err := newClock(p.dataSet, p.bootstrapping)
+		if err != nil {
+			if len(deviceTimes) == 0 {
+				return nil
+			}
+			return err
+		}
+		p.clock = deviceClock
+	}
+
+	for index := len(objects) - 1; index >= 0; index-- {
+		object := objects[index]
+		if object == nil {
+			continue
+		}
+
+		if p.bootstrapping && isTimeChange(object) {
+			p.clock = p.clock.Before(object)
+		}
+
+		deviceTime, ok := deviceTimes[index]
+		if !ok {
+			continue
+		}
+
+		datumTime := p.clock.Time(deviceTime)
+		localTime := datumTime.In(p.clock.location)
+		_, offset := localTime.Zone()
+		localDeviceTime := time.Date(localTime.Year(), localTime.Month(), localTime.Day(), localTime.Hour(), localTime.Minute(), localTime.Second(), localTime.Nanosecond(), time.UTC)
+
+		object["time"] = datumTime.UTC().Format(dataTypes.TimeFormat)
+		object["timezoneOffset"] = offset / 60
+		if _, ok := object["timezone"]; !ok {
+			object["timezone"] = p.clock.location.String()
+		}
+		object["conversionOffset"] = int(deviceTime.Sub(localDeviceTime) / time.Millisecond)
+	}
+
+	return nil
+}
+
+// newClock returns the device clock at the end of the data of the data set, off the local time by the difference
+// between the device time and the computer time of the data set, if bootstrapping
+func newClock(dataSet *dataTypesUpload.Upload, bootstrapping bool) (*clock, error) {
+	if dataSet.TimeZoneName == nil || *dataSet.TimeZoneName == "" {
+		return nil, errors.New("time zone name is missing")
+	} else if !timeZone.IsValidName(*dataSet.TimeZoneName) {
+		return nil, errors.Newf("time zone name %q is not valid", *dataSet.TimeZoneName)
+	}
+	location, err := time.LoadLocation(*dataSet.TimeZoneName)
+	if err != nil {
+		return nil, errors.Newf("time zone name %q is not valid", *dataSet.TimeZoneName)
+	}
+
+	deviceClock := &clock{location: location}
+	if bootstrapping && dataSet.DeviceTime != nil && dataSet.ComputerTime != nil {
+		deviceTime, deviceTimeErr := time.Parse(dataTypes.DeviceTimeFormat, *dataSet.DeviceTime)
+		computerTime, computerTimeErr := time.Parse(dataTypesUpload.ComputerTimeFormat, *dataSet.ComputerTime)
+		if deviceTimeErr == nil && computerTimeErr == nil {
+			deviceClock.drift = computerTime.Sub(deviceTime)
+		}
+	}
+	return deviceClock, nil
+}
+
+// clock converts the device times, as recorded by the device clock, to times. If the offset is not known, the device
+// clock is off the local time in the location by the drift.
+type clock struct {
+	location *time.Location
+	drift    time.Duration
+	offset   *time.Duration
+}
+
+func (c *clock) Time(deviceTime time.Time) time.Time {
+	if c.offset != nil {
+		return deviceTime.Add(*c.offset)
+	}
+	localTime := deviceTime.Add(c.drift)
+	return time.Date(localTime.Year(), localTime.Month(), localTime.Day(), localTime.Hour(), localTime.Minute(), localTime.Second(), localTime.Nanosecond(), c.location)
+}
+
+// Before returns the clock before the time change, or the clock itself if the time change is not valid
+func (c *clock) Before(object map[string]interface{}) *clock {
+	from, to := parseTimeChange(object)
+	if from == nil || from.Time == nil || to == nil || to.Time == nil {
+		return c
+	}
+
+	offset := c.Time(*to.Time).Sub(*from.Time)
+	before := &clock{location: c.location, offset: &offset}
+	if from.TimeZoneName != nil && timeZone.IsValidName(*from.TimeZoneName) {
+		if location, err := time.LoadLocation(*from.TimeZoneName); err == nil {
+			before.location = location
+		}
+	}
+	return before
+}
+
+// parseDeviceTime returns the device time of the raw datum, if it must be processed
+func parseDeviceTime(object map[string]interface{}) (time.Time, bool) {
+	if _, ok := object["time"]; ok {
+		return time.Time{}, false
+	}
+	deviceTimeString, ok := object["deviceTime"].(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	deviceTime, err := time.Parse(dataTypes.DeviceTimeFormat, deviceTimeString)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return deviceTime, true
+}
+
+func isTimeChange(object map[string]interface{}) bool {
+	return object["type"] == dataTypesDevice.Type && object["subType"] == dataTypesDeviceTimechange.SubType
+}
+
+// parseTimeChange returns the from and to of the time change, including those of the deprecated change, if valid
+func parseTimeChange(object map[string]interface{}) (*dataTypesDeviceTimechange.Info, *dataTypesDeviceTimechange.Info) {
+	parser := structureParser.NewObject(&object)
+	from := dataTypesDeviceTimechange.ParseInfo(parser.WithReferenceObjectParser("from"))
+	to := dataTypesDeviceTimechange.ParseInfo(parser.WithReferenceObjectParser("to"))
+	if from == nil && to == nil {
+		if change := dataTypesDeviceTimechange.ParseChange(parser.WithReferenceObjectParser("change")); change != nil {
+			from = parseChangeTime(change.From, dataTypesDeviceTimechange.FromTimeFormat)
+			to = parseChangeTime(change.To, dataTypesDeviceTimechange.ToTimeFormat)
+		}
+	}
+	if parser.Error() != nil {
+		return nil, nil
+	}
+	return from, to
+}
+
+func parseChangeTime(value *string, layout string) *dataTypesDeviceTimechange.Info {
+	if value == nil {
+		return nil
+	}
+	if changeTime, err := time.Parse(layout, *value); err == nil {
+		return &dataTypesDeviceTimechange.Info{Time: &changeTime}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package timeprocessing_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/data/timeprocessing"
+	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	"github.com/tidepool-org/platform/pointer"
+)
+
+func NewDataSet(timeProcessing string, timeZoneName *string) *dataTypesUpload.Upload {
+	dataSet := dataTypesUpload.New()
+	dataSet.TimeProcessing = pointer.FromString(timeProcessing)
+	dataSet.TimeZoneName = timeZoneName
+	return dataSet
+}
+
+func NewRawDatum(deviceTime string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "smbg",
+		"deviceTime": deviceTime,
+	}
+}
+
+func NewRawTimeChange(deviceTime string, from map[string]interface{}, to map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "deviceEvent",
+		"subType":    "timeChange",
+		"deviceTime": deviceTime,
+		"from":       from,
+		"to":         to,
+		"method":     "manual",
+	}
+}
+
+func ExpectTimes(rawDatum interface{}, time string, timeZoneOffset int, timeZoneName string, conversionOffset int) {
+	object := rawDatum.(map[string]interface{})
+	Expect(object["time"]).To(Equal(time))
+	Expect(object["timezoneOffset"]).To(Equal(timeZoneOffset))
+	Expect(object["timezone"]).To(Equal(timeZoneName))
+	Expect(object["conversionOffset"]).To(Equal(conversionOffset))
+}
+
+var _ = Describe("Process", func() {
+	It("does not process the data if the data set time processing is none", func() {
+		rawData := []interface{}{NewRawDatum("2021-03-14T01:30:00")}
+		Expect(timeprocessing.Process(NewDataSet(dataTypesUpload.TimeProcessingNone, nil), rawData)).To(Succeed())
+		Expect(rawData).To(Equal([]interface{}{NewRawDatum("2021-03-14T01:30:00")}))
+	})
+
+	It("returns an error if the data set time zone name is missing and any datum must be processed", func() {
+		Expect(timeprocessing.Process(NewDataSet(dataTypesUpload.TimeProcessingAcrossTheBoardTimeZone, nil), []interface{}{NewRawDatum("2021-03-14T01:30:00")})).To(MatchError("time zone name is missing"))
+	})
+
+	It("returns an error if the data set time zone name is not valid and any datum must be processed", func() {
+		Expect(timeprocessing.Process(NewDataSet(dataTypesUpload.TimeProcessingUTCBootstrapping, pointer.FromString("Mars/Olympus_Mons")), []interface{}{NewRawDatum("2021-03-14T01:30:00")})).To(MatchError(`time zone name "Mars/Olympus_Mons" is not valid`))
+	})
+
+	It("does not return an error if the data set time zone name is missing and no datum must be processed", func() {
+		rawData := []interface{}{map[string]interface{}{"type": "smbg", "time": "2021-03-14T06:30:00Z", "deviceTime": "2021-03-14T01:30:00"}, "datum"}
+		Expect(timeprocessing.Process(NewDataSet(dataTypesUpload.TimeProcessingAcrossTheBoardTimeZone, nil), rawData)).To(Succeed())
+		Expect(rawData[0]).ToNot(HaveKey("conversionOffset"))
+	})
+
+	Context("with across-the-board-timezone", func() {
+		It("converts the device times in the time zone of the data set, across the daylight saving time transition", func() {
+			rawData := []interface{}{
+				NewRawDatum("2021-03-14T01:30:00"),
+				NewRawDatum("2021-03-14T03:30:00"),
+				NewRawDatum("2021-03-14 04:30"),
+			}
+			Expect(timeprocessing.Process(NewDataSet(dataTypesUpload.TimeProcessingAcrossTheBoardTimeZone, pointer.FromString("America/New_York")), rawData)).To(Succeed())
+			ExpectTimes(rawData[0], "2021-03-14T06:30:00Z", -300, "America/New_York", 0)
+			ExpectTimes(rawData[1], "2021-03-14T07:30:00Z", -240, "America/New_York", 0)
+			Expect(rawData[2]).ToNot(HaveKey("time"))
+		})
+	})
+
+	Context("with utc-bootstrapping", func() {
+		It("corrects the device times before a clock change", func() {
+			rawData := []interface{}{
+				NewRawDatum("2021-06-01T10:00:00"),
+				NewRawTimeChange("2021-06-01T12:00:00", map[string]interface{}{"time": "2021-06-01T12:00:00"}, map[string]interface{}{"time": "2021-06-01T13:00:00"}),
+				NewRawDatum("2021-06-01T14:00:00"),
+			}
+			Expect(timeprocessing.Process(NewDataSet(dataTypesUpload.TimeProcessingUTCBootstrapping, pointer.FromString("Europe/Paris")), rawData)).To(Succeed())
+			ExpectTimes(rawData[0], "2021-06-01T09:00:00Z", 120, "Europe/Paris", -3600000)
+			ExpectTimes(rawData[1], "2021-06-01T11:00:00Z", 120, "Europe/Paris", -3600000)
+			ExpectTimes(rawData[2], "2021-06-01T12:00:00Z", 120, "Europe/Paris", 0)
+		})
+
+		It("converts the device times before a time change with time zone in its from time zone", func() {
+			rawData := []interface{}{
+				NewRawDatum("2021-06-01T08:00:00"),
+				NewRawTimeChange("2021-06-01T09:00:00",
+					map[string]interface{}{"time": "2021-06-01T09:00:00", "timeZoneName": "America/New_York"},
+					map[string]interface{}{"time": "2021-06-01T15:00:00", "timeZoneName": "Europe/Paris"}),
+				NewRawDatum("2021-06-01T16:00:00"),
+			}
+			Expect(timeprocessing.Process(NewDataSet(dataTypesUpload.TimeProcessingUTCBootstrapping, pointer.FromString("Europe/Paris")), rawData)).To(Succeed())
+			ExpectTimes(rawData[0], "2021-06-01T12:00:00Z", -240, "America/New_York", 0)
+			ExpectTimes(rawData[1], "2021-06-01T13:00:00Z", -240, "America/New_York", 0)
+			ExpectTimes(rawData[2], "2021-06-01T14:00:00Z", 120, "Europe/Paris", 0)
+		})
+
+		It("corrects the device times before a deprecated change, and ignores an invalid time change", func() {
+			rawData := []interface{}{
+				NewRawDatum("2021-11-01T10:00:00"),
+				map[string]interface{}{"type": "deviceEvent", "subType": "timeChange", "time": "2021-11-01T11:00:00Z", "change": map[string]interface{}{"agent": "manual", "from": "2021-11-01T12:30:00", "to": "2021-11-01T12:00:00"}},
+				NewRawDatum("2021-11-01T13:00:00"),
+				NewRawTimeChange("2021-11-01T14:00:00", map[string]interface{}{"time": "invalid"}, map[string]interface{}{"time": "2021-11-01T15:00:00"}),
+				NewRawDatum("2021-11-01T15:00:00"),
+			}
+			Expect(timeprocessing.Process(NewDataSet(dataTypesUpload.TimeProcessingUTCBootstrapping, pointer.FromString("UTC")), rawData)).To(Succeed())
+			ExpectTimes(rawData[0], "2021-11-01T09:30:00Z", 0, "UTC", 1800000)
+			Expect(rawData[1]).ToNot(HaveKey("conversionOffset"))
+			ExpectTimes(rawData[2], "2021-11-01T13:00:00Z", 0, "UTC", 0)
+			ExpectTimes(rawData[3], "2021-11-01T14:00:00Z", 0, "UTC", 0)
+			ExpectTimes(rawData[4], "2021-11-01T15:00:00Z", 0, "UTC", 0)
+		})
+
+		It("converts the device times off the local time by the difference between the device and computer times of the data set", func() {
+			dataSet := NewDataSet(dataTypesUpload.TimeProcessingUTCBootstrapping, pointer.FromString("Europe/Paris"))
+			dataSet.DeviceTime = pointer.FromString("2021-06-01T18:00:00")
+			dataSet.ComputerTime = pointer.FromString("2021-06-01T18:10:00")
+			rawData := []interface{}{NewRawDatum("2021-06-01T10:00:00")}
+			Expect(timeprocessing.Process(dataSet, rawData)).To(Succeed())
+			ExpectTimes(rawData[0], "2021-06-01T08:10:00Z", 120, "Europe/Paris", -600000)
+		})
+	})
+})
+
+var _ = Describe("Deferred", func() {
+	It("returns false if the data set time processing is not utc-bootstrapping", func() {
+		Expect(timeprocessing.Deferred(NewDataSet(dataTypesUpload.TimeProcessingAcrossTheBoardTimeZone, pointer.FromString("UTC")), []interface{}{NewRawDatum("2021-06-01T10:00:00")})).To(BeFalse())
+	})
+
+	It("returns false if the data set is continuous", func() {
+		dataSet := NewDataSet(dataTypesUpload.TimeProcessingUTCBootstrapping, pointer.FromString("UTC"))
+		dataSet.DataSetType = pointer.FromString(dataTypesUpload.DataSetTypeContinuous)
+		Expect(timeprocessing.Deferred(dataSet, []interface{}{NewRawDatum("2021-06-01T10:00:00")})).To(BeFalse())
+	})
+
+	It("returns false if no datum must be processed", func() {
+		rawData := []interface{}{map[string]interface{}{"type": "smbg", "time": "2021-06-01T10:00:00Z", "deviceTime": "2021-06-01T10:00:00"}}
+		Expect(timeprocessing.Deferred(NewDataSet(dataTypesUpload.TimeProcessingUTCBootstrapping, pointer.FromString("UTC")), rawData)).To(BeFalse())
+	})
+
+	It("returns true if any datum must be processed with utc-bootstrapping", func() {
+		Expect(timeprocessing.Deferred(NewDataSet(dataTypesUpload.TimeProcessingUTCBootstrapping, pointer.FromString("UTC")), []interface{}{NewRawDatum("2021-06-01T10:00:00")})).To(BeTrue())
+	})
+})
+
+var _ = Describe("Processor", func() {
+	It("returns nil if the data set has no time processing", func() {
+		Expect(timeprocessing.NewProcessor(NewDataSet(dataTypesUpload.TimeProcessingNone, nil))).To(BeNil())
+	})
+
+	It("corrects the device times of a previous batch before a clock change in a later batch", func() {
+		processor := timeprocessing.NewProcessor(NewDataSet(dataTypesUpload.TimeProcessingUTCBootstrapping, pointer.FromString("Europe/Paris")))
+		Expect(processor).ToNot(BeNil())
+		firstRawData := []interface{}{NewRawDatum("2021-06-01T10:00:00")}
+		lastRawData := []interface{}{
+			NewRawTimeChange("2021-06-01T12:00:00", map[string]interface{}{"time": "2021-06-01T12:00:00"}, map[string]interface{}{"time": "2021-06-01T13:00:00"}),
+			NewRawDatum("2021-06-01T14:00:00"),
+		}
+		Expect(processor.Process(lastRawData)).To(Succeed())
+		Expect(processor.Process(firstRawData)).To(Succeed())
+		ExpectTimes(firstRawData[0], "2021-06-01T09:00:00Z", 120, "Europe/Paris", -3600000)
+		ExpectTimes(lastRawData[0], "2021-06-01T11:00:00Z", 120, "Europe/Paris", -3600000)
+		ExpectTimes(lastRawData[1], "2021-06-01T12:00:00Z", 120, "Europe/Paris", 0)
+	})
+})
@@ -27,7 +27,7 @@ const (
 	NoteLengthMaximum       = 1000
 	NotesLengthMaximum      = 100
 	SchemaVersionCurrent    = SchemaVersionMaximum
-	SchemaVersionMaximum    = 3
+	SchemaVersionMaximum    = 4
 	SchemaVersionMinimum    = 1
 	TagLengthMaximum        = 100
 	TagsLengthMaximum       = 100
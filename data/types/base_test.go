@@ -596,20 +596,20 @@ var _ = Describe("Base", func() {
 				Entry("schema version; out of range (lower)",
 					func(datum *types.Base) { datum.SchemaVersion = 0 },
 					[]structure.Origin{structure.OriginStore},
-					errorsTest.WithPointerSource(structureValidator.ErrorValueNotInRange(0, 1, 3), "/_schemaVersion"),
+					errorsTest.WithPointerSource(structureValidator.ErrorValueNotInRange(0, 1, 4), "/_schemaVersion"),
 				),
 				Entry("schema version; in range (lower)",
 					func(datum *types.Base) { datum.SchemaVersion = 1 },
 					structure.Origins(),
 				),
 				Entry("schema version; in range (upper)",
-					func(datum *types.Base) { datum.SchemaVersion = 3 },
+					func(datum *types.Base) { datum.SchemaVersion = 4 },
 					structure.Origins(),
 				),
 				Entry("schema version; out of range (upper)",
-					func(datum *types.Base) { datum.SchemaVersion = 4 },
+					func(datum *types.Base) { datum.SchemaVersion = 5 },
 					[]structure.Origin{structure.OriginStore},
-					errorsTest.WithPointerSource(structureValidator.ErrorValueNotInRange(4, 1, 3), "/_schemaVersion"),
+					errorsTest.WithPointerSource(structureValidator.ErrorValueNotInRange(5, 1, 4), "/_schemaVersion"),
 				),
 				Entry("source missing",
 					func(datum *types.Base) { datum.Source = nil },
@@ -789,7 +789,7 @@ var _ = Describe("Base", func() {
 						datum.Version = -1
 					},
 					[]structure.Origin{structure.OriginStore},
-					errorsTest.WithPointerSource(structureValidator.ErrorValueNotInRange(0, 1, 3), "/_schemaVersion"),
+					errorsTest.WithPointerSource(structureValidator.ErrorValueNotInRange(0, 1, 4), "/_schemaVersion"),
 					errorsTest.WithPointerSource(structureValidator.ErrorValueNotExists(), "/_userId"),
 					errorsTest.WithPointerSource(structureValidator.ErrorValueNotGreaterThanOrEqualTo(-1, 0), "/_version"),
 				),
@@ -908,7 +908,7 @@ var _ = Describe("Base", func() {
 				Entry("default schema version",
 					func(datum *types.Base) { datum.SchemaVersion = 0 },
 					func(datum *types.Base, expectedDatum *types.Base) {
-						Expect(datum.SchemaVersion).To(Equal(3))
+						Expect(datum.SchemaVersion).To(Equal(4))
 						expectedDatum.SchemaVersion = datum.SchemaVersion
 						sort.Strings(*expectedDatum.Tags)
 					},
@@ -920,7 +920,7 @@ var _ = Describe("Base", func() {
 					func(datum *types.Base, expectedDatum *types.Base) {
 						Expect(datum.ID).ToNot(BeNil())
 						Expect(datum.ID).ToNot(Equal(expectedDatum.ID))
-						Expect(datum.SchemaVersion).To(Equal(3))
+						Expect(datum.SchemaVersion).To(Equal(4))
 						expectedDatum.ID = datum.ID
 						expectedDatum.SchemaVersion = datum.SchemaVersion
 					},
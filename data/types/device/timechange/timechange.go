@@ -11,6 +11,8 @@ const (
 	MethodAutomatic = "automatic"
 	MethodManual    = "manual"
 	SubType         = "timeChange" // TODO: Rename Type to "device/timeChange"; remove SubType
+
+	SchemaVersionChangeDEPRECATED = 3 // Last schema version with deprecated change
 )
 
 func Methods() []string {
@@ -115,5 +117,9 @@ func (t *TimeChange) Normalize(normalizer data.Normalizer) {
 
 	if t.Change != nil {
 		t.Change.Normalize(normalizer.WithReference("change"))
+
+		if normalizer.Origin() == structure.OriginExternal && t.SchemaVersion > SchemaVersionChangeDEPRECATED {
+			t.SchemaVersion = SchemaVersionChangeDEPRECATED
+		}
 	}
 }
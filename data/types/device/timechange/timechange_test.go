@@ -8,6 +8,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	dataNormalizer "github.com/tidepool-org/platform/data/normalizer"
+	dataTypes "github.com/tidepool-org/platform/data/types"
 	dataTypesDevice "github.com/tidepool-org/platform/data/types/device"
 	dataTypesDeviceTimechange "github.com/tidepool-org/platform/data/types/device/timechange"
 	dataTypesDeviceTimechangeTest "github.com/tidepool-org/platform/data/types/device/timechange/test"
@@ -245,6 +246,20 @@ var _ = Describe("TimeChange", func() {
 			Context("deprecated", func() {
 				normalizeValidations(true)
 			})
+
+			It("keeps the schema version of the deprecated change with origin external", func() {
+				datum := dataTypesDeviceTimechangeTest.RandomTimeChange(true)
+				datum.SchemaVersion = 0
+				datum.Normalize(dataNormalizer.New().WithOrigin(structure.OriginExternal))
+				Expect(datum.SchemaVersion).To(Equal(dataTypesDeviceTimechange.SchemaVersionChangeDEPRECATED))
+			})
+
+			It("sets the current schema version without deprecated change with origin external", func() {
+				datum := dataTypesDeviceTimechangeTest.RandomTimeChange(false)
+				datum.SchemaVersion = 0
+				datum.Normalize(dataNormalizer.New().WithOrigin(structure.OriginExternal))
+				Expect(datum.SchemaVersion).To(Equal(dataTypes.SchemaVersionCurrent))
+			})
 		})
 	})
 })
@@ -19,6 +19,7 @@ import (
 	dataTypesSettingsPump "github.com/tidepool-org/platform/data/types/settings/pump"
 	dataTypesStateReported "github.com/tidepool-org/platform/data/types/state/reported"
 	dataTypesUpload "github.com/tidepool-org/platform/data/types/upload"
+	dataTypesWater "github.com/tidepool-org/platform/data/types/water"
 	"github.com/tidepool-org/platform/structure"
 	structureValidator "github.com/tidepool-org/platform/structure/validator"
 )
@@ -38,6 +39,7 @@ var types = []string{
 	dataTypesSettingsPump.Type,
 	dataTypesStateReported.Type,
 	dataTypesUpload.Type,
+	dataTypesWater.Type,
 }
 
 func NewDatum(parser structure.ObjectParser) data.Datum {
@@ -80,6 +82,8 @@ func NewDatum(parser structure.ObjectParser) data.Datum {
 		return dataTypesStateReported.New()
 	case dataTypesUpload.Type:
 		return dataTypesUpload.New()
+	case dataTypesWater.Type:
+		return dataTypesWater.New()
 	}
 
 	parser.WithReferenceErrorReporter("type").ReportError(structureValidator.ErrorValueStringNotOneOf(*value, types))
@@ -0,0 +1,121 @@
+package schema
+
+import (
+	"sort"
+
+	dataTypes "github.com/tidepool-org/platform/data/types"
+	"github.com/tidepool-org/platform/errors"
+)
+
+// The stored datums are upgraded to the current shape on read, so that the readers do not have to handle the
+// historical shapes. An upgrade of a type at a schema version turns a stored document of the type at that version into
+// the shape of the next version. A document is upgraded by applying, in order, the upgrades of its type from its
+// schema version to the current one. The datums written in a deprecated shape keep the schema version of that shape, so
+// that they are upgraded on read as well. The upgrades work on the stored document, rather than the datum, so that any
+// field, whether known to the datum or not, is preserved. An upgrade must leave a document already in the shape of the
+// next version unchanged.
+
+const SchemaVersionField = "_schemaVersion"
+
+// Upgrade turns the document into the shape of the next schema version, and returns true if changed
+type Upgrade func(document map[string]interface{}) (bool, error)
+
+type upgrade struct {
+	version int
+	upgrade Upgrade
+}
+
+var upgrades = map[string][]upgrade{}
+
+// Register registers the upgrade of the documents of the type at the schema version
+func Register(typ string, version int, upgradeFunc Upgrade) error {
+	if typ == "" {
+		return errors.New("type is missing")
+	} else if version < dataTypes.SchemaVersionMinimum || version >= dataTypes.SchemaVersionCurrent {
+		return errors.Newf("version %d is invalid", version)
+	} else if upgradeFunc == nil {
+		return errors.New("upgrade is missing")
+	}
+
+	for _, typeUpgrade := range upgrades[typ] {
+		if typeUpgrade.version == version {
+			return errors.Newf("upgrade of type %q at version %d already registered", typ, version)
+		}
+	}
+
+	typeUpgrades := append(upgrades[typ], upgrade{version: version, upgrade: upgradeFunc})
+	sort.Slice(typeUpgrades, func(i int, j int) bool { return typeUpgrades[i].version < typeUpgrades[j].version })
+	upgrades[typ] = typeUpgrades
+	return nil
+}
+
+// Types returns the types with registered upgrades
+func Types() []string {
+	types := []string{}
+	for typ := range upgrades {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// HasUpgrades returns true if the type has registered upgrades
+func HasUpgrades(typ string) bool {
+	return len(upgrades[typ]) > 0
+}
+
+// Version returns the schema version of the document, or the minimum schema version if missing
+func Version(document map[string]interface{}) (int, error) {
+	switch version := document[SchemaVersionField].(type) {
+	case nil:
+		return dataTypes.SchemaVersionMinimum, nil
+	case int:
+		return version, nil
+	case int32:
+		return int(version), nil
+	case int64:
+		return int(version), nil
+	case float64:
+		if version == float64(int(version)) {
+			return int(version), nil
+		}
+	}
+	return 0, errors.Newf("schema version %v is invalid", document[SchemaVersionField])
+}
+
+// UpgradeDocument upgrades the document from its schema version to the current one, and returns true if upgraded. The
+// document is left unchanged, including its schema version, if its type has no upgrades, or if none of the upgrades
+// changed it, so that it does not need to be encoded again.
+func UpgradeDocument(document map[string]interface{}) (bool, error) {
+	if document == nil {
+		return false, errors.New("document is missing")
+	}
+
+	typ, _ := document["type"].(string)
+	if !HasUpgrades(typ) {
+		return false, nil
+	}
+
+	version, err := Version(document)
+	if err != nil {
+		return false, err
+	} else if version >= dataTypes.SchemaVersionCurrent {
+		return false, nil
+	}
+
+	var upgraded bool
+	for _, typeUpgrade := range upgrades[typ] {
+		if typeUpgrade.version >= version {
+			changed, err := typeUpgrade.upgrade(document)
+			if err != nil {
+				return false, errors.Wrapf(err, "unable to upgrade document of type %q at version %d", typ, typeUpgrade.version)
+			}
+			upgraded = upgraded || changed
+		}
+	}
+
+	if upgraded {
+		document[SchemaVersionField] = dataTypes.SchemaVersionCurrent
+	}
+	return upgraded, nil
+}
@@ -0,0 +1,132 @@
+package schema_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/tidepool-org/platform/errors"
+
+	dataTypesSchema "github.com/tidepool-org/platform/data/types/schema"
+)
+
+var _ = Describe("Schema", func() {
+	It("registers the upgrades of the CGM settings and device events", func() {
+		Expect(dataTypesSchema.Types()).To(ContainElement("cgmSettings"))
+		Expect(dataTypesSchema.Types()).To(ContainElement("deviceEvent"))
+	})
+
+	Context("HasUpgrades", func() {
+		It("returns true if the type has registered upgrades", func() {
+			Expect(dataTypesSchema.HasUpgrades("cgmSettings")).To(BeTrue())
+		})
+
+		It("returns false if the type has no registered upgrades", func() {
+			Expect(dataTypesSchema.HasUpgrades("smbg")).To(BeFalse())
+		})
+	})
+
+	Context("Register", func() {
+		It("returns an error if the type is missing", func() {
+			Expect(dataTypesSchema.Register("", 3, func(document map[string]interface{}) (bool, error) { return true, nil })).To(MatchError("type is missing"))
+		})
+
+		It("returns an error if the version is below the minimum", func() {
+			Expect(dataTypesSchema.Register("register", 0, func(document map[string]interface{}) (bool, error) { return true, nil })).To(MatchError("version 0 is invalid"))
+		})
+
+		It("returns an error if the version is current", func() {
+			Expect(dataTypesSchema.Register("register", 4, func(document map[string]interface{}) (bool, error) { return true, nil })).To(MatchError("version 4 is invalid"))
+		})
+
+		It("returns an error if the upgrade is missing", func() {
+			Expect(dataTypesSchema.Register("register", 3, nil)).To(MatchError("upgrade is missing"))
+		})
+
+		It("returns an error if the upgrade is already registered", func() {
+			Expect(dataTypesSchema.Register("cgmSettings", 3, func(document map[string]interface{}) (bool, error) { return true, nil })).To(MatchError(`upgrade of type "cgmSettings" at version 3 already registered`))
+		})
+	})
+
+	Context("Version", func() {
+		DescribeTable("returns the version of the document",
+			func(value interface{}, expectedVersion int) {
+				Expect(dataTypesSchema.Version(map[string]interface{}{"_schemaVersion": value})).To(Equal(expectedVersion))
+			},
+			Entry("is missing", nil, 1),
+			Entry("is int", 2, 2),
+			Entry("is int32", int32(3), 3),
+			Entry("is int64", int64(3), 3),
+			Entry("is float64", 2.0, 2),
+		)
+
+		It("returns an error if the version is invalid", func() {
+			_, err := dataTypesSchema.Version(map[string]interface{}{"_schemaVersion": "3"})
+			Expect(err).To(MatchError("schema version 3 is invalid"))
+		})
+	})
+
+	Context("UpgradeDocument", func() {
+		var versions []int
+
+		BeforeEach(func() {
+			versions = nil
+		})
+
+		registerUpgrade := func(typ string, version int, changed bool) {
+			Expect(dataTypesSchema.Register(typ, version, func(document map[string]interface{}) (bool, error) {
+				versions = append(versions, version)
+				return changed, nil
+			})).To(Succeed())
+		}
+
+		It("returns an error if the document is missing", func() {
+			_, err := dataTypesSchema.UpgradeDocument(nil)
+			Expect(err).To(MatchError("document is missing"))
+		})
+
+		It("returns an error if the version is invalid", func() {
+			_, err := dataTypesSchema.UpgradeDocument(map[string]interface{}{"type": "cgmSettings", "_schemaVersion": 2.5})
+			Expect(err).To(MatchError("schema version 2.5 is invalid"))
+		})
+
+		It("does not upgrade the document at the current version", func() {
+			registerUpgrade("current", 3, true)
+			document := map[string]interface{}{"type": "current", "_schemaVersion": 4}
+			Expect(dataTypesSchema.UpgradeDocument(document)).To(BeFalse())
+			Expect(document).To(Equal(map[string]interface{}{"type": "current", "_schemaVersion": 4}))
+			Expect(versions).To(BeEmpty())
+		})
+
+		It("applies the upgrades from the version of the document in order", func() {
+			registerUpgrade("ordered", 3, false)
+			registerUpgrade("ordered", 1, true)
+			registerUpgrade("ordered", 2, true)
+			document := map[string]interface{}{"type": "ordered", "_schemaVersion": 2}
+			Expect(dataTypesSchema.UpgradeDocument(document)).To(BeTrue())
+			Expect(document).To(Equal(map[string]interface{}{"type": "ordered", "_schemaVersion": 4}))
+			Expect(versions).To(Equal([]int{2, 3}))
+		})
+
+		It("does not upgrade the document without upgrades of its type", func() {
+			document := map[string]interface{}{"type": "none", "_schemaVersion": 3}
+			Expect(dataTypesSchema.UpgradeDocument(document)).To(BeFalse())
+			Expect(document).To(Equal(map[string]interface{}{"type": "none", "_schemaVersion": 3}))
+		})
+
+		It("does not upgrade the document if no upgrade changed it", func() {
+			registerUpgrade("unchanged", 2, false)
+			registerUpgrade("unchanged", 3, false)
+			document := map[string]interface{}{"type": "unchanged", "_schemaVersion": 2}
+			Expect(dataTypesSchema.UpgradeDocument(document)).To(BeFalse())
+			Expect(document).To(Equal(map[string]interface{}{"type": "unchanged", "_schemaVersion": 2}))
+			Expect(versions).To(Equal([]int{2, 3}))
+		})
+
+		It("returns an error if an upgrade fails", func() {
+			Expect(dataTypesSchema.Register("failing", 3, func(document map[string]interface{}) (bool, error) { return false, errors.New("test error") })).To(Succeed())
+			_, err := dataTypesSchema.UpgradeDocument(map[string]interface{}{"type": "failing", "_schemaVersion": 3})
+			Expect(err).To(MatchError(`unable to upgrade document of type "failing" at version 3; test error`))
+		})
+	})
+})
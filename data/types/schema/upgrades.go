@@ -0,0 +1,199 @@
+package schema
+
+import (
+	"math"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+
+	dataBloodGlucose "github.com/tidepool-org/platform/data/blood/glucose"
+	dataTypesDevice "github.com/tidepool-org/platform/data/types/device"
+	dataTypesDeviceTimechange "github.com/tidepool-org/platform/data/types/device/timechange"
+	dataTypesSettingsCgm "github.com/tidepool-org/platform/data/types/settings/cgm"
+)
+
+// UpgradeCGMSettingsAlertsDEPRECATED moves the deprecated high, low, out of range and rate of change alerts of the CGM
+// settings to the corresponding default alerts, unless already present. The deprecated levels and rates are in the
+// units of the CGM settings, the deprecated snoozes and out of range thresholds in milliseconds, and the deprecated fall
+// rates are negative.
+func UpgradeCGMSettingsAlertsDEPRECATED(document map[string]interface{}) (bool, error) {
+	highAlerts, _ := objectValue(document["highAlerts"])
+	lowAlerts, _ := objectValue(document["lowAlerts"])
+	outOfRangeAlerts, _ := objectValue(document["outOfRangeAlerts"])
+	rateOfChangeAlert, _ := objectValue(document["rateOfChangeAlert"])
+	if highAlerts == nil && lowAlerts == nil && outOfRangeAlerts == nil && rateOfChangeAlert == nil {
+		return false, nil
+	}
+
+	var levelUnits string
+	var rateUnits string
+	if units, ok := document["units"].(string); ok {
+		switch units {
+		case dataBloodGlucose.MgdL, dataBloodGlucose.Mgdl:
+			levelUnits = dataTypesSettingsCgm.LevelAlertUnitsMgdL
+			rateUnits = dataTypesSettingsCgm.RateAlertUnitsMgdLMinute
+		case dataBloodGlucose.MmolL, dataBloodGlucose.Mmoll:
+			levelUnits = dataTypesSettingsCgm.LevelAlertUnitsMmolL
+			rateUnits = dataTypesSettingsCgm.RateAlertUnitsMmolLMinute
+		}
+	}
+
+	defaultAlerts, _ := objectValue(document["defaultAlerts"])
+	if defaultAlerts == nil {
+		defaultAlerts = map[string]interface{}{"enabled": true}
+	}
+
+	if highAlerts != nil {
+		setIfMissing(defaultAlerts, "high", upgradeLevelAlertDEPRECATED(highAlerts, levelUnits))
+	}
+	if lowAlerts != nil {
+		setIfMissing(defaultAlerts, "low", upgradeLevelAlertDEPRECATED(lowAlerts, levelUnits))
+	}
+	if outOfRangeAlerts != nil {
+		setIfMissing(defaultAlerts, "outOfRange", upgradeOutOfRangeAlertDEPRECATED(outOfRangeAlerts))
+	}
+	if rateOfChangeAlert != nil {
+		if fallRate, ok := objectValue(rateOfChangeAlert["fallRate"]); ok {
+			setIfMissing(defaultAlerts, "fall", upgradeRateAlertDEPRECATED(fallRate, rateUnits))
+		}
+		if riseRate, ok := objectValue(rateOfChangeAlert["riseRate"]); ok {
+			setIfMissing(defaultAlerts, "rise", upgradeRateAlertDEPRECATED(riseRate, rateUnits))
+		}
+	}
+
+	document["defaultAlerts"] = defaultAlerts
+	delete(document, "highAlerts")
+	delete(document, "lowAlerts")
+	delete(document, "outOfRangeAlerts")
+	delete(document, "rateOfChangeAlert")
+	return true, nil
+}
+
+func upgradeLevelAlertDEPRECATED(levelAlert map[string]interface{}, units string) map[string]interface{} {
+	alert := upgradeAlertDEPRECATED(levelAlert)
+	if snooze, ok := numberValue(levelAlert["snooze"]); ok {
+		alert["snooze"] = map[string]interface{}{
+			"duration": snooze / 1000,
+			"units":    dataTypesSettingsCgm.SnoozeUnitsSeconds,
+		}
+	}
+	if level, ok := numberValue(levelAlert["level"]); ok && units != "" {
+		alert["level"] = level
+		alert["units"] = units
+	}
+	return alert
+}
+
+func upgradeOutOfRangeAlertDEPRECATED(outOfRangeAlert map[string]interface{}) map[string]interface{} {
+	alert := upgradeAlertDEPRECATED(outOfRangeAlert)
+	if threshold, ok := numberValue(outOfRangeAlert["snooze"]); ok {
+		alert["duration"] = threshold / 1000
+		alert["units"] = dataTypesSettingsCgm.DurationAlertUnitsSeconds
+	}
+	return alert
+}
+
+func upgradeRateAlertDEPRECATED(rateAlert map[string]interface{}, units string) map[string]interface{} {
+	alert := upgradeAlertDEPRECATED(rateAlert)
+	if rate, ok := numberValue(rateAlert["rate"]); ok && units != "" {
+		alert["rate"] = math.Abs(rate)
+		alert["units"] = units
+	}
+	return alert
+}
+
+func upgradeAlertDEPRECATED(alertDEPRECATED map[string]interface{}) map[string]interface{} {
+	alert := map[string]interface{}{}
+	if enabled, ok := alertDEPRECATED["enabled"].(bool); ok {
+		alert["enabled"] = enabled
+	}
+	return alert
+}
+
+// UpgradeTimeChangeChangeDEPRECATED replaces the deprecated change of the time change with the from and to times, and
+// the method, unless any is already present. The deprecated change is left unchanged if its times cannot be parsed.
+func UpgradeTimeChangeChangeDEPRECATED(document map[string]interface{}) (bool, error) {
+	if document["subType"] != dataTypesDeviceTimechange.SubType {
+		return false, nil
+	}
+
+	change, ok := objectValue(document["change"])
+	if !ok {
+		return false, nil
+	}
+
+	if _, ok := document["from"]; !ok {
+		if _, ok := document["to"]; !ok {
+			if _, ok := document["method"]; !ok {
+				fromTime, fromOK := changeTime(change["from"], dataTypesDeviceTimechange.FromTimeFormat)
+				toTime, toOK := changeTime(change["to"], dataTypesDeviceTimechange.ToTimeFormat)
+				if !fromOK || !toOK {
+					return false, nil
+				}
+
+				document["from"] = map[string]interface{}{"time": fromTime}
+				document["to"] = map[string]interface{}{"time": toTime}
+				if agent, ok := change["agent"].(string); ok {
+					switch agent {
+					case dataTypesDeviceTimechange.AgentAutomatic:
+						document["method"] = dataTypesDeviceTimechange.MethodAutomatic
+					case dataTypesDeviceTimechange.AgentManual:
+						document["method"] = dataTypesDeviceTimechange.MethodManual
+					}
+				}
+			}
+		}
+	}
+
+	delete(document, "change")
+	return true, nil
+}
+
+func changeTime(value interface{}, layout string) (time.Time, bool) {
+	if stringValue, ok := value.(string); ok {
+		if timeValue, err := time.Parse(layout, stringValue); err == nil {
+			return timeValue, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func setIfMissing(object map[string]interface{}, key string, value interface{}) {
+	if _, ok := object[key]; !ok {
+		object[key] = value
+	}
+}
+
+// objectValue returns the value as an object, whether decoded from JSON or BSON
+func objectValue(value interface{}) (map[string]interface{}, bool) {
+	switch object := value.(type) {
+	case map[string]interface{}:
+		return object, true
+	case bson.M:
+		return object, true
+	}
+	return nil, false
+}
+
+func numberValue(value interface{}) (float64, bool) {
+	switch number := value.(type) {
+	case int:
+		return float64(number), true
+	case int32:
+		return float64(number), true
+	case int64:
+		return float64(number), true
+	case float64:
+		return number, true
+	}
+	return 0, false
+}
+
+func init() {
+	if err := Register(dataTypesSettingsCgm.Type, dataTypesSettingsCgm.SchemaVersionAlertsDEPRECATED, UpgradeCGMSettingsAlertsDEPRECATED); err != nil {
+		panic(err)
+	}
+	if err := Register(dataTypesDevice.Type, dataTypesDeviceTimechange.SchemaVersionChangeDEPRECATED, UpgradeTimeChangeChangeDEPRECATED); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,187 @@
+package schema_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/globalsign/mgo/bson"
+
+	dataTypesSchema "github.com/tidepool-org/platform/data/types/schema"
+	dataTypesSettingsCgm "github.com/tidepool-org/platform/data/types/settings/cgm"
+	structureParser "github.com/tidepool-org/platform/structure/parser"
+	structureValidator "github.com/tidepool-org/platform/structure/validator"
+)
+
+var _ = Describe("Upgrades", func() {
+	Context("UpgradeCGMSettingsAlertsDEPRECATED", func() {
+		var document map[string]interface{}
+
+		BeforeEach(func() {
+			document = map[string]interface{}{
+				"type":  "cgmSettings",
+				"units": "mmol/L",
+				"highAlerts": map[string]interface{}{
+					"enabled": true,
+					"level":   13.87699,
+					"snooze":  int64(7200000),
+				},
+				"lowAlerts": map[string]interface{}{
+					"enabled": false,
+					"level":   3.88552,
+					"snooze":  900000,
+				},
+				"outOfRangeAlerts": map[string]interface{}{
+					"enabled": true,
+					"snooze":  int32(1800000),
+				},
+				"rateOfChangeAlert": map[string]interface{}{
+					"fallRate": map[string]interface{}{"enabled": true, "rate": -0.16652243973136602},
+					"riseRate": map[string]interface{}{"enabled": false, "rate": 0.11101495982091067},
+				},
+				"_schemaVersion": 3,
+			}
+		})
+
+		It("moves the deprecated alerts to the default alerts", func() {
+			Expect(dataTypesSchema.UpgradeDocument(document)).To(BeTrue())
+			Expect(document).To(Equal(map[string]interface{}{
+				"type":  "cgmSettings",
+				"units": "mmol/L",
+				"defaultAlerts": map[string]interface{}{
+					"enabled": true,
+					"high": map[string]interface{}{
+						"enabled": true,
+						"level":   13.87699,
+						"units":   "mmol/L",
+						"snooze":  map[string]interface{}{"duration": 7200.0, "units": "seconds"},
+					},
+					"low": map[string]interface{}{
+						"enabled": false,
+						"level":   3.88552,
+						"units":   "mmol/L",
+						"snooze":  map[string]interface{}{"duration": 900.0, "units": "seconds"},
+					},
+					"outOfRange": map[string]interface{}{
+						"enabled":  true,
+						"duration": 1800.0,
+						"units":    "seconds",
+					},
+					"fall": map[string]interface{}{"enabled": true, "rate": 0.16652243973136602, "units": "mmol/L/minute"},
+					"rise": map[string]interface{}{"enabled": false, "rate": 0.11101495982091067, "units": "mmol/L/minute"},
+				},
+				"_schemaVersion": 4,
+			}))
+		})
+
+		It("upgrades to valid default alerts", func() {
+			Expect(dataTypesSchema.UpgradeDocument(document)).To(BeTrue())
+			defaultAlerts := document["defaultAlerts"].(map[string]interface{})
+			alerts := dataTypesSettingsCgm.ParseAlerts(structureParser.NewObject(&defaultAlerts))
+			Expect(alerts).ToNot(BeNil())
+			Expect(structureValidator.New().Validate(alerts)).To(Succeed())
+		})
+
+		It("uses the units in mg/dL", func() {
+			document["units"] = "mg/dL"
+			document["highAlerts"].(map[string]interface{})["level"] = 250.0
+			Expect(dataTypesSchema.UpgradeDocument(document)).To(BeTrue())
+			defaultAlerts := document["defaultAlerts"].(map[string]interface{})
+			Expect(defaultAlerts["high"]).To(HaveKeyWithValue("units", "mg/dL"))
+			Expect(defaultAlerts["high"]).To(HaveKeyWithValue("level", 250.0))
+			Expect(defaultAlerts["fall"]).To(HaveKeyWithValue("units", "mg/dL/minute"))
+		})
+
+		It("omits the levels and rates if the units are unknown", func() {
+			delete(document, "units")
+			Expect(dataTypesSchema.UpgradeDocument(document)).To(BeTrue())
+			defaultAlerts := document["defaultAlerts"].(map[string]interface{})
+			Expect(defaultAlerts["high"]).ToNot(HaveKey("level"))
+			Expect(defaultAlerts["high"]).ToNot(HaveKey("units"))
+			Expect(defaultAlerts["fall"]).ToNot(HaveKey("rate"))
+			Expect(defaultAlerts["fall"]).ToNot(HaveKey("units"))
+		})
+
+		It("keeps the default alerts already present", func() {
+			high := map[string]interface{}{"enabled": false}
+			document["defaultAlerts"] = map[string]interface{}{"enabled": false, "high": high}
+			Expect(dataTypesSchema.UpgradeDocument(document)).To(BeTrue())
+			defaultAlerts := document["defaultAlerts"].(map[string]interface{})
+			Expect(defaultAlerts).To(HaveKeyWithValue("enabled", false))
+			Expect(defaultAlerts).To(HaveKeyWithValue("high", high))
+			Expect(defaultAlerts).To(HaveKey("low"))
+			Expect(document).ToNot(HaveKey("highAlerts"))
+		})
+
+		It("moves the deprecated alerts decoded from BSON", func() {
+			document["highAlerts"] = bson.M{"enabled": true, "level": 13.87699, "snooze": 0}
+			Expect(dataTypesSchema.UpgradeDocument(document)).To(BeTrue())
+			defaultAlerts := document["defaultAlerts"].(map[string]interface{})
+			Expect(defaultAlerts).To(HaveKeyWithValue("high", map[string]interface{}{
+				"enabled": true,
+				"level":   13.87699,
+				"units":   "mmol/L",
+				"snooze":  map[string]interface{}{"duration": 0.0, "units": "seconds"},
+			}))
+			Expect(document).ToNot(HaveKey("highAlerts"))
+		})
+
+		It("leaves the document without deprecated alerts unchanged", func() {
+			document = map[string]interface{}{"type": "cgmSettings", "units": "mmol/L"}
+			Expect(dataTypesSchema.UpgradeCGMSettingsAlertsDEPRECATED(document)).To(BeFalse())
+			Expect(document).To(Equal(map[string]interface{}{"type": "cgmSettings", "units": "mmol/L"}))
+		})
+	})
+
+	Context("UpgradeTimeChangeChangeDEPRECATED", func() {
+		var document map[string]interface{}
+
+		BeforeEach(func() {
+			document = map[string]interface{}{
+				"type":           "deviceEvent",
+				"subType":        "timeChange",
+				"change":         map[string]interface{}{"agent": "automatic", "from": "2020-03-08T01:59:30", "to": "2020-03-08T03:00:00"},
+				"_schemaVersion": 2,
+			}
+		})
+
+		It("replaces the deprecated change with the from and to times, and the method", func() {
+			Expect(dataTypesSchema.UpgradeDocument(document)).To(BeTrue())
+			Expect(document).To(Equal(map[string]interface{}{
+				"type":           "deviceEvent",
+				"subType":        "timeChange",
+				"from":           map[string]interface{}{"time": time.Date(2020, 3, 8, 1, 59, 30, 0, time.UTC)},
+				"to":             map[string]interface{}{"time": time.Date(2020, 3, 8, 3, 0, 0, 0, time.UTC)},
+				"method":         "automatic",
+				"_schemaVersion": 4,
+			}))
+		})
+
+		It("removes the deprecated change if the from or to is already present", func() {
+			to := map[string]interface{}{"time": time.Date(2020, 3, 8, 3, 0, 0, 0, time.UTC)}
+			document["to"] = to
+			Expect(dataTypesSchema.UpgradeDocument(document)).To(BeTrue())
+			Expect(document).ToNot(HaveKey("change"))
+			Expect(document).ToNot(HaveKey("from"))
+			Expect(document).ToNot(HaveKey("method"))
+			Expect(document).To(HaveKeyWithValue("to", to))
+		})
+
+		It("leaves the deprecated change unchanged if its times cannot be parsed", func() {
+			document["change"].(map[string]interface{})["to"] = "invalid"
+			Expect(dataTypesSchema.UpgradeDocument(document)).To(BeFalse())
+			Expect(document).To(HaveKeyWithValue("_schemaVersion", 2))
+			Expect(document).To(HaveKey("change"))
+			Expect(document).ToNot(HaveKey("from"))
+			Expect(document).ToNot(HaveKey("to"))
+		})
+
+		It("leaves the other device events unchanged", func() {
+			document["subType"] = "alarm"
+			Expect(dataTypesSchema.UpgradeDocument(document)).To(BeFalse())
+			Expect(document).To(HaveKey("change"))
+			Expect(document).ToNot(HaveKey("from"))
+		})
+	})
+})
@@ -17,6 +17,7 @@ const (
 	ManufacturerLengthMaximum     = 100
 	ManufacturersLengthMaximum    = 10
 	ModelLengthMaximum            = 100
+	SchemaVersionAlertsDEPRECATED = 3 // Last schema version with deprecated alerts
 	SerialNumberLengthMaximum     = 100
 	TransmitterIDExpressionString = "^[0-9A-Z]{5,6}$"
 )
@@ -138,6 +139,14 @@ func (c *CGM) Normalize(normalizer data.Normalizer) {
 	if c.RateAlerts != nil {
 		c.RateAlerts.Normalize(normalizer.WithReference("rateOfChangeAlerts"), units)
 	}
+
+	if normalizer.Origin() == structure.OriginExternal {
+		if c.HighLevelAlert != nil || c.LowLevelAlert != nil || c.OutOfRangeAlert != nil || c.RateAlerts != nil {
+			if c.SchemaVersion > SchemaVersionAlertsDEPRECATED {
+				c.SchemaVersion = SchemaVersionAlertsDEPRECATED
+			}
+		}
+	}
 }
 
 func IsValidTransmitterID(value string) bool {
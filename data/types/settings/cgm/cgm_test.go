@@ -471,6 +471,24 @@ var _ = Describe("CGM", func() {
 					nil,
 				),
 			)
+
+			It("keeps the schema version of the deprecated alerts with origin external", func() {
+				datum := dataTypesSettingsCgmTest.RandomCGM(pointer.FromString("mmol/L"))
+				datum.SchemaVersion = 0
+				datum.Normalize(dataNormalizer.New().WithOrigin(structure.OriginExternal))
+				Expect(datum.SchemaVersion).To(Equal(dataTypesSettingsCgm.SchemaVersionAlertsDEPRECATED))
+			})
+
+			It("sets the current schema version without deprecated alerts with origin external", func() {
+				datum := dataTypesSettingsCgmTest.RandomCGM(pointer.FromString("mmol/L"))
+				datum.SchemaVersion = 0
+				datum.HighLevelAlert = nil
+				datum.LowLevelAlert = nil
+				datum.OutOfRangeAlert = nil
+				datum.RateAlerts = nil
+				datum.Normalize(dataNormalizer.New().WithOrigin(structure.OriginExternal))
+				Expect(datum.SchemaVersion).To(Equal(dataTypes.SchemaVersionCurrent))
+			})
 		})
 	})
 
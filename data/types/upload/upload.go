@@ -73,6 +73,7 @@ type Upload struct {
 	DeviceModel         *string   `json:"deviceModel,omitempty" bson:"deviceModel,omitempty"`
 	DeviceSerialNumber  *string   `json:"deviceSerialNumber,omitempty" bson:"deviceSerialNumber,omitempty"`
 	DeviceTags          *[]string `json:"deviceTags,omitempty" bson:"deviceTags,omitempty"`
+	PurgedTime          *string   `json:"-" bson:"_purgedTime,omitempty"`
 	State               *string   `json:"-" bson:"_state,omitempty" enums:"closed,open"` // TODO: Should this be returned in JSON? I think so.
 	TimeProcessing      *string   `json:"timeProcessing,omitempty" bson:"timeProcessing,omitempty"`
 	Version             *string   `json:"version,omitempty" bson:"version,omitempty"` // TODO: Deprecate in favor of Client.Version
@@ -221,3 +222,11 @@ func (u *Upload) HasDeduplicatorName() bool {
 func (u *Upload) HasDeduplicatorNameMatch(name string) bool {
 	return u.Deduplicator != nil && u.Deduplicator.HasNameMatch(name)
 }
+
+func (u *Upload) IsDeleted() bool {
+	return u.DeletedTime != nil
+}
+
+func (u *Upload) IsPurged() bool {
+	return u.PurgedTime != nil
+}
@@ -2,6 +2,7 @@ package upload_test
 
 import (
 	"sort"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
@@ -624,6 +625,30 @@ var _ = Describe("Upload", func() {
 					Expect(datum.HasDeduplicatorNameMatch(name)).To(BeTrue())
 				})
 			})
+
+			Context("IsDeleted", func() {
+				It("returns false if the deleted time is missing", func() {
+					datum.DeletedTime = nil
+					Expect(datum.IsDeleted()).To(BeFalse())
+				})
+
+				It("returns true if the deleted time exists", func() {
+					datum.DeletedTime = pointer.FromString(test.RandomTime().Format(time.RFC3339Nano))
+					Expect(datum.IsDeleted()).To(BeTrue())
+				})
+			})
+
+			Context("IsPurged", func() {
+				It("returns false if the purged time is missing", func() {
+					datum.PurgedTime = nil
+					Expect(datum.IsPurged()).To(BeFalse())
+				})
+
+				It("returns true if the purged time exists", func() {
+					datum.PurgedTime = pointer.FromString(test.RandomTime().Format(time.RFC3339Nano))
+					Expect(datum.IsPurged()).To(BeTrue())
+				})
+			})
 		})
 	})
 })
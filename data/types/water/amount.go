@@ -63,6 +63,28 @@ func (a *Amount) Validate(validator structure.Validator) {
 
 func (a *Amount) Normalize(normalizer data.Normalizer) {}
 
+// ValueInMilliliters returns the amount converted to milliliters, or nil if the units are not known
+func (a *Amount) ValueInMilliliters() *float64 {
+	if a.Units == nil || a.Value == nil {
+		return nil
+	}
+
+	var value float64
+	switch *a.Units {
+	case AmountUnitsGallons:
+		value = *a.Value * AmountLitersPerGallon * 1000.0
+	case AmountUnitsLiters:
+		value = *a.Value * 1000.0
+	case AmountUnitsMilliliters:
+		value = *a.Value
+	case AmountUnitsOunces:
+		value = *a.Value / AmountOuncesPerGallon * AmountLitersPerGallon * 1000.0
+	default:
+		return nil
+	}
+	return &value
+}
+
 func AmountValueRangeForUnits(units *string) (float64, float64) {
 	if units != nil {
 		switch *units {
@@ -6,6 +6,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
 
 	dataNormalizer "github.com/tidepool-org/platform/data/normalizer"
 	dataTypesTest "github.com/tidepool-org/platform/data/types/test"
@@ -395,6 +396,41 @@ var _ = Describe("Amount", func() {
 		})
 	})
 
+	Context("ValueInMilliliters", func() {
+		var datum *water.Amount
+
+		BeforeEach(func() {
+			datum = NewAmount()
+		})
+
+		It("returns nil if units is missing", func() {
+			datum.Units = nil
+			Expect(datum.ValueInMilliliters()).To(BeNil())
+		})
+
+		It("returns nil if units is invalid", func() {
+			datum.Units = pointer.FromString("invalid")
+			Expect(datum.ValueInMilliliters()).To(BeNil())
+		})
+
+		It("returns nil if value is missing", func() {
+			datum.Value = nil
+			Expect(datum.ValueInMilliliters()).To(BeNil())
+		})
+
+		DescribeTable("returns the expected value",
+			func(units string, value float64, expected float64) {
+				datum.Units = pointer.FromString(units)
+				datum.Value = pointer.FromFloat64(value)
+				Expect(datum.ValueInMilliliters()).To(PointTo(BeNumerically("~", expected, 0.0001)))
+			},
+			Entry("gallons", "gallons", 2.0, 7570.8236),
+			Entry("liters", "liters", 1.5, 1500.0),
+			Entry("milliliters", "milliliters", 250.0, 250.0),
+			Entry("ounces", "ounces", 8.0, 236.5882375),
+		)
+	})
+
 	Context("AmountValueRangeForUnits", func() {
 		It("returns expected range for units missing", func() {
 			minimum, maximum := water.AmountValueRangeForUnits(nil)
@@ -1,8 +1,11 @@
 package water
 
 import (
+	"strconv"
+
 	"github.com/tidepool-org/platform/data"
 	"github.com/tidepool-org/platform/data/types"
+	"github.com/tidepool-org/platform/errors"
 	"github.com/tidepool-org/platform/structure"
 	structureValidator "github.com/tidepool-org/platform/structure/validator"
 )
@@ -51,6 +54,25 @@ func (w *Water) Validate(validator structure.Validator) {
 	}
 }
 
+func (w *Water) IdentityFields() ([]string, error) {
+	identityFields, err := w.Base.IdentityFields()
+	if err != nil {
+		return nil, err
+	}
+
+	if w.Amount == nil {
+		return nil, errors.New("amount is missing")
+	}
+	if w.Amount.Units == nil {
+		return nil, errors.New("amount units is missing")
+	}
+	if w.Amount.Value == nil {
+		return nil, errors.New("amount value is missing")
+	}
+
+	return append(identityFields, *w.Amount.Units, strconv.FormatFloat(*w.Amount.Value, 'f', -1, 64)), nil
+}
+
 func (w *Water) Normalize(normalizer data.Normalizer) {
 	if !normalizer.HasMeta() {
 		normalizer = normalizer.WithMeta(w.Meta())
@@ -1,6 +1,8 @@
 package water_test
 
 import (
+	"strconv"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
@@ -123,5 +125,47 @@ var _ = Describe("Water", func() {
 				),
 			)
 		})
+
+		Context("IdentityFields", func() {
+			var datum *water.Water
+
+			BeforeEach(func() {
+				datum = NewWater()
+			})
+
+			It("returns error if user id is missing", func() {
+				datum.UserID = nil
+				identityFields, err := datum.IdentityFields()
+				Expect(err).To(MatchError("user id is missing"))
+				Expect(identityFields).To(BeEmpty())
+			})
+
+			It("returns error if amount is missing", func() {
+				datum.Amount = nil
+				identityFields, err := datum.IdentityFields()
+				Expect(err).To(MatchError("amount is missing"))
+				Expect(identityFields).To(BeEmpty())
+			})
+
+			It("returns error if amount units is missing", func() {
+				datum.Amount.Units = nil
+				identityFields, err := datum.IdentityFields()
+				Expect(err).To(MatchError("amount units is missing"))
+				Expect(identityFields).To(BeEmpty())
+			})
+
+			It("returns error if amount value is missing", func() {
+				datum.Amount.Value = nil
+				identityFields, err := datum.IdentityFields()
+				Expect(err).To(MatchError("amount value is missing"))
+				Expect(identityFields).To(BeEmpty())
+			})
+
+			It("returns the expected identity fields", func() {
+				identityFields, err := datum.IdentityFields()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(identityFields).To(Equal([]string{*datum.UserID, *datum.DeviceID, *datum.Time, datum.Type, *datum.Amount.Units, strconv.FormatFloat(*datum.Amount.Value, 'f', -1, 64)}))
+			})
+		})
 	})
 })
@@ -7,6 +7,7 @@ export TIDEPOOL_SERVER_TLS="false"
 
 export TIDEPOOL_CONFIRMATION_STORE_DATABASE="confirm"
 export TIDEPOOL_DEPRECATED_DATA_STORE_DATABASE="data"
+export TIDEPOOL_DATA_SOURCE_STORE_DATABASE="data"
 export TIDEPOOL_MESSAGE_STORE_DATABASE="messages"
 export TIDEPOOL_PERMISSION_STORE_DATABASE="gatekeeper"
 export TIDEPOOL_PERMISSION_STORE_SECRET="This secret is used to encrypt the groupId stored in the DB for gatekeeper"
@@ -12,6 +12,7 @@ export TIDEPOOL_STORE_MAX_CONNECTION_ATTEMPTS=5
 
 export TIDEPOOL_CONFIRMATION_STORE_DATABASE="confirm_test"
 export TIDEPOOL_DATA_STORE_DATABASE="data_test"
+export TIDEPOOL_DATA_SOURCE_STORE_DATABASE="data_test"
 export TIDEPOOL_MESSAGE_STORE_DATABASE="messages_test"
 export TIDEPOOL_PERMISSION_STORE_DATABASE="gatekeeper_test"
 export TIDEPOOL_PROFILE_STORE_DATABASE="seagull_test"
@@ -1,9 +1,13 @@
 package service
 
 import (
+	"context"
+
 	"github.com/tidepool-org/platform/application"
 	"github.com/tidepool-org/platform/client"
 	dataClient "github.com/tidepool-org/platform/data/client"
+	dataPurge "github.com/tidepool-org/platform/data/purge"
+	dataSummaryRebuild "github.com/tidepool-org/platform/data/summary/rebuild"
 	dataSource "github.com/tidepool-org/platform/data/source"
 	dataSourceClient "github.com/tidepool-org/platform/data/source/client"
 	"github.com/tidepool-org/platform/dexcom"
@@ -11,7 +15,9 @@ import (
 	dexcomFetch "github.com/tidepool-org/platform/dexcom/fetch"
 	dexcomProvider "github.com/tidepool-org/platform/dexcom/provider"
 	"github.com/tidepool-org/platform/errors"
+	"github.com/tidepool-org/platform/log"
 	"github.com/tidepool-org/platform/platform"
+	"github.com/tidepool-org/platform/pointer"
 	serviceService "github.com/tidepool-org/platform/service/service"
 	storeStructuredMongo "github.com/tidepool-org/platform/store/structured/mongo"
 	"github.com/tidepool-org/platform/task"
@@ -257,6 +263,46 @@ func (s *Service) initializeTaskQueue() error {
 		taskQueue.RegisterRunner(rnnr)
 	}
 
+	s.Logger().Debug("Loading data purge config")
+
+	dataPurgeCfg := dataPurge.NewConfig()
+	if err = dataPurgeCfg.Load(s.ConfigReporter().WithScopes("data", "purge")); err != nil {
+		return errors.Wrap(err, "unable to load data purge config")
+	}
+
+	s.Logger().Debug("Creating data purge runner")
+
+	dataPurgeRnnr, err := dataPurge.NewRunner(dataPurgeCfg, s.Logger(), s.AuthClient(), s.dataClient)
+	if err != nil {
+		return errors.Wrap(err, "unable to create data purge runner")
+	}
+
+	taskQueue.RegisterRunner(dataPurgeRnnr)
+
+	if err = s.ensureDataPurgeTask(); err != nil {
+		return err
+	}
+
+	s.Logger().Debug("Loading data summary rebuild config")
+
+	dataSummaryRebuildCfg := dataSummaryRebuild.NewConfig()
+	if err = dataSummaryRebuildCfg.Load(s.ConfigReporter().WithScopes("data", "summary", "rebuild")); err != nil {
+		return errors.Wrap(err, "unable to load data summary rebuild config")
+	}
+
+	s.Logger().Debug("Creating data summary rebuild runner")
+
+	dataSummaryRebuildRnnr, err := dataSummaryRebuild.NewRunner(dataSummaryRebuildCfg, s.Logger(), s.AuthClient(), s.dataClient)
+	if err != nil {
+		return errors.Wrap(err, "unable to create data summary rebuild runner")
+	}
+
+	taskQueue.RegisterRunner(dataSummaryRebuildRnnr)
+
+	if err = s.ensureDataSummaryRebuildTask(); err != nil {
+		return err
+	}
+
 	s.Logger().Debug("Starting task queue")
 
 	s.taskQueue.Start()
@@ -264,6 +310,48 @@ func (s *Service) initializeTaskQueue() error {
 	return nil
 }
 
+func (s *Service) ensureDataPurgeTask() error {
+	s.Logger().Debug("Ensuring data purge task")
+
+	ctx := log.NewContextWithLogger(context.Background(), s.Logger())
+
+	filter := task.NewTaskFilter()
+	filter.Name = pointer.FromString(dataPurge.TaskName)
+	tasks, err := s.TaskClient().ListTasks(ctx, filter, nil)
+	if err != nil {
+		return errors.Wrap(err, "unable to list data purge tasks")
+	} else if len(tasks) > 0 {
+		return nil
+	}
+
+	if _, err = s.TaskClient().CreateTask(ctx, dataPurge.NewTaskCreate()); err != nil {
+		return errors.Wrap(err, "unable to create data purge task")
+	}
+
+	return nil
+}
+
+func (s *Service) ensureDataSummaryRebuildTask() error {
+	s.Logger().Debug("Ensuring data summary rebuild task")
+
+	ctx := log.NewContextWithLogger(context.Background(), s.Logger())
+
+	filter := task.NewTaskFilter()
+	filter.Name = pointer.FromString(dataSummaryRebuild.TaskName)
+	tasks, err := s.TaskClient().ListTasks(ctx, filter, nil)
+	if err != nil {
+		return errors.Wrap(err, "unable to list data summary rebuild tasks")
+	} else if len(tasks) > 0 {
+		return nil
+	}
+
+	if _, err = s.TaskClient().CreateTask(ctx, dataSummaryRebuild.NewTaskCreate()); err != nil {
+		return errors.Wrap(err, "unable to create data summary rebuild task")
+	}
+
+	return nil
+}
+
 func (s *Service) terminateTaskQueue() {
 	if s.taskQueue != nil {
 		s.Logger().Debug("Stopping task queue")
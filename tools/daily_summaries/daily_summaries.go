@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/tidepool-org/platform/application"
+	dataStoreDEPRECATEDMongo "github.com/tidepool-org/platform/data/storeDEPRECATED/mongo"
+	"github.com/tidepool-org/platform/data/summary"
+	"github.com/tidepool-org/platform/errors"
+	"github.com/tidepool-org/platform/log"
+	toolMongo "github.com/tidepool-org/platform/tool/mongo"
+)
+
+const (
+	StartDateFlag = "start-date"
+	EndDateFlag   = "end-date"
+	CompareFlag   = "compare"
+	OutputFlag    = "output"
+
+	ChunkDays = 30
+)
+
+func main() {
+	application.RunAndExit(NewTool())
+}
+
+// Difference is a daily summary difference of a user, as output when comparing
+type Difference struct {
+	UserID string `json:"userId"`
+	*summary.DailySummaryDifference
+}
+
+type Tool struct {
+	*toolMongo.Tool
+	dataStore *dataStoreDEPRECATEDMongo.Store
+	userIDs   []string
+	startDate string
+	endDate   string
+	compare   bool
+	output    string
+}
+
+func NewTool() *Tool {
+	return &Tool{
+		Tool: toolMongo.NewTool(),
+	}
+}
+
+func (t *Tool) Initialize(provider application.Provider) error {
+	if err := t.Tool.Initialize(provider); err != nil {
+		return err
+	}
+
+	t.CLI().Usage = "Rebuild, or compare with the data, the daily summaries of the users given as arguments"
+	t.CLI().Flags = append(t.CLI().Flags,
+		cli.StringFlag{
+			Name:  StartDateFlag,
+			Usage: "first local date, formatted as YYYY-MM-DD",
+		},
+		cli.StringFlag{
+			Name:  EndDateFlag,
+			Usage: "last local date, formatted as YYYY-MM-DD, defaults to today",
+		},
+		cli.BoolFlag{
+			Name:  CompareFlag,
+			Usage: "compare the stored daily summaries with the ones calculated from the data, without rebuilding them",
+		},
+		cli.StringFlag{
+			Name:  fmt.Sprintf("%s,%s", OutputFlag, "o"),
+			Usage: "output file for the differences",
+		},
+	)
+	t.CLI().Action = func(ctx *cli.Context) error {
+		if !t.ParseContext(ctx) {
+			return nil
+		}
+		return t.execute()
+	}
+
+	return nil
+}
+
+func (t *Tool) Terminate() {
+	t.terminateDataStore()
+
+	t.Tool.Terminate()
+}
+
+func (t *Tool) ParseContext(ctx *cli.Context) bool {
+	if parsed := t.Tool.ParseContext(ctx); !parsed {
+		return parsed
+	}
+
+	t.userIDs = ctx.Args()
+	t.startDate = ctx.String(StartDateFlag)
+	t.endDate = ctx.String(EndDateFlag)
+	t.compare = ctx.Bool(CompareFlag)
+	t.output = ctx.String(OutputFlag)
+
+	return true
+}
+
+func (t *Tool) initializeDataStore() error {
+	t.Logger().Debug("Creating data store")
+
+	config := t.NewMongoConfig()
+	config.Database = "data"
+	store, err := dataStoreDEPRECATEDMongo.NewStore(config, t.Logger())
+	if err != nil {
+		return errors.Wrap(err, "unable to create data store")
+	}
+	t.dataStore = store
+
+	return nil
+}
+
+func (t *Tool) terminateDataStore() {
+	if t.dataStore != nil {
+		t.Logger().Debug("Destroying data store")
+		t.dataStore.Close()
+		t.dataStore = nil
+	}
+}
+
+func (t *Tool) execute() error {
+	if len(t.userIDs) == 0 {
+		return errors.New("user ids are missing")
+	}
+	if t.endDate == "" {
+		t.endDate = time.Now().UTC().Format(summary.DateFormat)
+	}
+	startTime, err := time.Parse(summary.DateFormat, t.startDate)
+	if err != nil {
+		return errors.Wrap(err, "start date is invalid")
+	}
+	endTime, err := time.Parse(summary.DateFormat, t.endDate)
+	if err != nil {
+		return errors.Wrap(err, "end date is invalid")
+	} else if endTime.Before(startTime) {
+		return errors.New("end date is before start date")
+	}
+
+	var outputWriter io.Writer
+	if t.output != "" {
+		outputFile, err := os.Create(t.output)
+		if err != nil {
+			return errors.Wrap(err, "unable to create output file")
+		}
+		defer outputFile.Close()
+		outputWriter = outputFile
+	} else {
+		outputWriter = os.Stdout
+	}
+
+	if err = t.initializeDataStore(); err != nil {
+		return err
+	}
+
+	ctx := log.NewContextWithLogger(context.Background(), t.Logger())
+	for _, userID := range t.userIDs {
+		if err = t.executeUser(ctx, userID, startTime, endTime, json.NewEncoder(outputWriter)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// executeUser rebuilds or compares the daily summaries of the user in chunks of days, to limit the data held in memory
+func (t *Tool) executeUser(ctx context.Context, userID string, startTime time.Time, endTime time.Time, encoder *json.Encoder) error {
+	logger := t.Logger().WithField("userId", userID)
+
+	session := t.dataStore.NewDataSession()
+	defer session.Close()
+
+	differenceCount := 0
+	for chunkStartTime := startTime; !chunkStartTime.After(endTime); chunkStartTime = chunkStartTime.AddDate(0, 0, ChunkDays) {
+		chunkEndTime := chunkStartTime.AddDate(0, 0, ChunkDays-1)
+		if chunkEndTime.After(endTime) {
+			chunkEndTime = endTime
+		}
+		startDate := chunkStartTime.Format(summary.DateFormat)
+		endDate := chunkEndTime.Format(summary.DateFormat)
+
+		logger.WithFields(log.Fields{"startDate": startDate, "endDate": endDate}).Debug("Executing daily summaries chunk")
+
+		if !t.compare {
+			if err := session.RebuildDailySummaries(ctx, userID, startDate, endDate); err != nil {
+				return errors.Wrapf(err, "unable to rebuild daily summaries for user %q", userID)
+			}
+			continue
+		}
+
+		expected, err := session.CalculateDailySummaries(ctx, userID, startDate, endDate)
+		if err != nil {
+			return errors.Wrapf(err, "unable to calculate daily summaries for user %q", userID)
+		}
+		actual, err := session.GetDailySummaries(ctx, userID, &summary.DailySummaryFilter{StartDate: &startDate, EndDate: &endDate})
+		if err != nil {
+			return errors.Wrapf(err, "unable to get daily summaries for user %q", userID)
+		}
+		for _, difference := range summary.CompareDailySummaries(expected, actual) {
+			if err = encoder.Encode(&Difference{UserID: userID, DailySummaryDifference: difference}); err != nil {
+				return errors.Wrap(err, "unable to write difference")
+			}
+			differenceCount++
+		}
+	}
+
+	if t.compare {
+		logger.WithField("differenceCount", differenceCount).Info("Compared daily summaries")
+	} else {
+		logger.Info("Rebuilt daily summaries")
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/urfave/cli"
+
+	"github.com/tidepool-org/platform/application"
+	dataStoreDEPRECATEDMongo "github.com/tidepool-org/platform/data/storeDEPRECATED/mongo"
+	"github.com/tidepool-org/platform/errors"
+	"github.com/tidepool-org/platform/log"
+	toolMongo "github.com/tidepool-org/platform/tool/mongo"
+)
+
+const (
+	LimitFlag  = "limit"
+	DryRunFlag = "dry-run"
+	StatusFlag = "status"
+)
+
+func main() {
+	application.RunAndExit(NewTool())
+}
+
+type Tool struct {
+	*toolMongo.Tool
+	dataStore *dataStoreDEPRECATEDMongo.Store
+	limit     int
+	dryRun    bool
+	status    bool
+}
+
+func NewTool() *Tool {
+	return &Tool{
+		Tool: toolMongo.NewTool(),
+	}
+}
+
+func (t *Tool) Initialize(provider application.Provider) error {
+	if err := t.Tool.Initialize(provider); err != nil {
+		return err
+	}
+
+	t.CLI().Usage = "Upgrade the stored data to the current schema version, resuming any interrupted upgrade"
+	t.CLI().Flags = append(t.CLI().Flags,
+		cli.IntFlag{
+			Name:  LimitFlag,
+			Usage: "maximum number of data to upgrade, all if not positive",
+		},
+		cli.BoolFlag{
+			Name:  DryRunFlag,
+			Usage: "count the data to upgrade, without upgrading them",
+		},
+		cli.BoolFlag{
+			Name:  StatusFlag,
+			Usage: "output the progress of the upgrade, without upgrading",
+		},
+	)
+	t.CLI().Action = func(ctx *cli.Context) error {
+		if !t.ParseContext(ctx) {
+			return nil
+		}
+		return t.execute()
+	}
+
+	return nil
+}
+
+func (t *Tool) Terminate() {
+	t.terminateDataStore()
+
+	t.Tool.Terminate()
+}
+
+func (t *Tool) ParseContext(ctx *cli.Context) bool {
+	if parsed := t.Tool.ParseContext(ctx); !parsed {
+		return parsed
+	}
+
+	t.limit = ctx.Int(LimitFlag)
+	t.dryRun = ctx.Bool(DryRunFlag)
+	t.status = ctx.Bool(StatusFlag)
+
+	return true
+}
+
+func (t *Tool) initializeDataStore() error {
+	t.Logger().Debug("Creating data store")
+
+	config := t.NewMongoConfig()
+	config.Database = "data"
+	store, err := dataStoreDEPRECATEDMongo.NewStore(config, t.Logger())
+	if err != nil {
+		return errors.Wrap(err, "unable to create data store")
+	}
+	t.dataStore = store
+
+	return nil
+}
+
+func (t *Tool) terminateDataStore() {
+	if t.dataStore != nil {
+		t.Logger().Debug("Destroying data store")
+		t.dataStore.Close()
+		t.dataStore = nil
+	}
+}
+
+func (t *Tool) execute() error {
+	if err := t.initializeDataStore(); err != nil {
+		return err
+	}
+
+	session := t.dataStore.NewSchemaUpgradeSession()
+	defer session.Close()
+
+	ctx := log.NewContextWithLogger(context.Background(), t.Logger())
+
+	var progress *dataStoreDEPRECATEDMongo.SchemaUpgradeProgress
+	var err error
+	if t.status {
+		if progress, err = session.GetProgress(ctx); err != nil {
+			return errors.Wrap(err, "unable to get progress")
+		}
+	} else {
+		if progress, err = session.Upgrade(ctx, t.limit, t.dryRun); err != nil {
+			return errors.Wrap(err, "unable to upgrade")
+		}
+		t.Logger().WithFields(log.Fields{"upgraded": progress.Upgraded, "failed": progress.Failed, "completed": progress.CompletedTime != nil, "dryRun": t.dryRun}).Info("Upgraded data")
+	}
+
+	if err = json.NewEncoder(os.Stdout).Encode(progress); err != nil {
+		return errors.Wrap(err, "unable to write progress")
+	}
+	return nil
+}